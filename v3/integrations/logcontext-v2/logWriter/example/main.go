@@ -5,8 +5,8 @@ import (
 	"os"
 	"time"
 
-	"github.com/newrelic/go-agent/v3/integrations/logcontext-v2/logWriter"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/integrations/logcontext-v2/logWriter"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 func main() {
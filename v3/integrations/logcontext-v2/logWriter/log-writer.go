@@ -4,9 +4,9 @@ import (
 	"context"
 	"io"
 
-	"github.com/newrelic/go-agent/v3/integrations/logcontext-v2/nrwriter"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/integrations/logcontext-v2/nrwriter"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 type LogWriter struct {
@@ -28,6 +28,14 @@ func New(output io.Writer, app *newrelic.Application) LogWriter {
 // will fail silently. Enabling debug logging will print error messages on a new line after your log message.
 func (lw *LogWriter) DebugLogging(enabled bool) { lw.w.DebugLogging(enabled) }
 
+// BatchSize sets the number of bytes of log lines buffered before they are written to the output
+// io.Writer. By default, the batch size is zero and each line is written immediately. Buffered lines
+// are written by Flush, which should be called before the application exits.
+func (lw *LogWriter) BatchSize(size int) { lw.w.BatchSize(size) }
+
+// Flush writes the buffered log lines to the output io.Writer.
+func (lw LogWriter) Flush() error { return lw.w.Flush() }
+
 // WithTransaction creates a new LogWriter for a specific transactions
 func (lw *LogWriter) WithTransaction(txn *newrelic.Transaction) LogWriter {
 	return LogWriter{w: lw.w.WithTransaction(txn)}
@@ -40,6 +48,5 @@ func (lw *LogWriter) WithContext(ctx context.Context) LogWriter {
 
 // Write is a valid io.Writer method that will write the content of an enriched log to the output io.Writer
 func (lw LogWriter) Write(p []byte) (n int, err error) {
-	enrichedLog := lw.w.EnrichLog(newrelic.LogData{Message: string(p)}, p)
-	return lw.w.Write(enrichedLog)
+	return lw.w.WriteLines(p, nil)
 }
@@ -6,8 +6,8 @@ import (
 	"os"
 	"time"
 
-	"github.com/newrelic/go-agent/v3/integrations/logcontext-v2/nrlogrus"
-	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/integrations/logcontext-v2/nrlogrus"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
 	"github.com/sirupsen/logrus"
 )
 
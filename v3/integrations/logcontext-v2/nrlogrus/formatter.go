@@ -6,8 +6,8 @@ package nrlogrus
 import (
 	"bytes"
 
-	"github.com/newrelic/go-agent/v3/internal"
-	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
 	"github.com/sirupsen/logrus"
 )
 
@@ -41,12 +41,7 @@ func (f ContextFormatter) Format(e *logrus.Entry) ([]byte, error) {
 	logBytes = bytes.TrimRight(logBytes, "\n")
 	b := bytes.NewBuffer(logBytes)
 
-	ctx := e.Context
-	var txn *newrelic.Transaction
-	if ctx != nil {
-		txn = newrelic.FromContext(ctx)
-	}
-	if txn != nil {
+	if txn := transactionFromEntry(e); txn != nil {
 		txn.RecordLog(logData)
 		err := newrelic.EnrichLog(b, newrelic.FromTxn(txn))
 		if err != nil {
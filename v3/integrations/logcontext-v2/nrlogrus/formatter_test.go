@@ -6,11 +6,11 @@ import (
 	"io"
 	"testing"
 
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
-	"github.com/newrelic/go-agent/v3/internal/logcontext"
-	"github.com/newrelic/go-agent/v3/internal/sysinfo"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/internal/logcontext"
+	"github.com/rainforestpay/go-agent/v3/internal/sysinfo"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 	"github.com/sirupsen/logrus"
 )
 
@@ -0,0 +1,127 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrlogrus
+
+import (
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+	"github.com/sirupsen/logrus"
+)
+
+// The names of the fields added by FieldsFormatter.
+const (
+	KeyTraceID    = "trace.id"
+	KeySpanID     = "span.id"
+	KeyEntityName = "entity.name"
+	KeyEntityType = "entity.type"
+	KeyEntityGUID = "entity.guid"
+	KeyHostname   = "hostname"
+)
+
+// transactionFromEntry returns the transaction of the entry's context, or nil.
+func transactionFromEntry(e *logrus.Entry) *newrelic.Transaction {
+	if ctx := e.Context; ctx != nil {
+		return newrelic.FromContext(ctx)
+	}
+	return nil
+}
+
+// Hook is a `logrus.Hook` that forwards logs to New Relic through the agent,
+// leaving their output unchanged.  The entry's fields listed in
+// Config.ApplicationLogging.Forwarding.AttributeAllowList are recorded as
+// attributes of the logs.  Logs of an entry whose context contains a
+// transaction, see `logrus.Entry.WithContext`, are recorded by the transaction
+// so that they are linked to its trace.  Other logs are recorded by the
+// application.
+type Hook struct {
+	app    *newrelic.Application
+	levels []logrus.Level
+}
+
+// NewHook creates a Hook which forwards the logs of every level.
+func NewHook(app *newrelic.Application) Hook {
+	return NewLevelHook(app, logrus.AllLevels)
+}
+
+// NewLevelHook creates a Hook which forwards the logs of the given levels.
+func NewLevelHook(app *newrelic.Application, levels []logrus.Level) Hook {
+	return Hook{
+		app:    app,
+		levels: levels,
+	}
+}
+
+// Levels implements `logrus.Hook`.
+func (h Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire implements `logrus.Hook`.
+func (h Hook) Fire(e *logrus.Entry) error {
+	logData := newrelic.LogData{
+		Timestamp: e.Time.UnixNano() / int64(1000*1000),
+		Severity:  e.Level.String(),
+		Message:   e.Message,
+
+		Attributes: e.Data,
+	}
+	if txn := transactionFromEntry(e); txn != nil {
+		txn.RecordLog(logData)
+	} else {
+		h.app.RecordLog(logData)
+	}
+	return nil
+}
+
+// FieldsFormatter is a `logrus.Formatter` that decorates logs locally by
+// adding the linking metadata of the transaction in the entry's context as
+// fields, and then formatting the entry with the wrapped formatter.  With a
+// `logrus.JSONFormatter`, the metadata are the JSON fields "trace.id",
+// "span.id", "entity.name", "entity.type", "entity.guid", and "hostname".
+// Entries without a transaction are formatted unchanged.  FieldsFormatter
+// does not forward logs: use it with Hook to also forward them.
+type FieldsFormatter struct {
+	formatter logrus.Formatter
+}
+
+// NewFieldsFormatter creates a FieldsFormatter which wraps the formatter.  If
+// formatter is nil, a `logrus.JSONFormatter` is used.
+func NewFieldsFormatter(formatter logrus.Formatter) FieldsFormatter {
+	if formatter == nil {
+		formatter = &logrus.JSONFormatter{}
+	}
+	return FieldsFormatter{
+		formatter: formatter,
+	}
+}
+
+// Format renders a single log entry.
+func (f FieldsFormatter) Format(e *logrus.Entry) ([]byte, error) {
+	txn := transactionFromEntry(e)
+	if txn == nil {
+		return f.formatter.Format(e)
+	}
+	md := txn.GetLinkingMetadata()
+	data := make(logrus.Fields, len(e.Data)+6)
+	for k, v := range e.Data {
+		data[k] = v
+	}
+	addField(data, KeyTraceID, md.TraceID)
+	addField(data, KeySpanID, md.SpanID)
+	addField(data, KeyEntityName, md.EntityName)
+	addField(data, KeyEntityType, md.EntityType)
+	addField(data, KeyEntityGUID, md.EntityGUID)
+	addField(data, KeyHostname, md.Hostname)
+
+	// The entry is copied so that the fields are not added to the entry
+	// seen by other hooks and formatters.
+	decorated := *e
+	decorated.Data = data
+	return f.formatter.Format(&decorated)
+}
+
+func addField(data logrus.Fields, key, value string) {
+	if value != "" {
+		data[key] = value
+	}
+}
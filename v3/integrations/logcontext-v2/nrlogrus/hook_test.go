@@ -0,0 +1,144 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrlogrus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
+	"github.com/sirupsen/logrus"
+)
+
+func newHookLogger(out *bytes.Buffer, hook Hook) *logrus.Logger {
+	l := logrus.New()
+	l.Formatter = NewFieldsFormatter(nil)
+	l.AddHook(hook)
+	l.SetOutput(out)
+	return l
+}
+
+func TestHookBackgroundLog(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn,
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+	out := bytes.NewBuffer([]byte{})
+	log := newHookLogger(out, NewHook(app.Application))
+	message := "Hello World!"
+	log.Info(message)
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &fields); err != nil {
+		t.Fatal(err, out.String())
+	}
+	if fields["msg"] != message {
+		t.Error(fields)
+	}
+	if _, ok := fields[KeyTraceID]; ok {
+		t.Error("log without a transaction decorated", fields)
+	}
+	app.ExpectLogEvents(t, []internal.WantLog{
+		{
+			Severity:  logrus.InfoLevel.String(),
+			Message:   message,
+			Timestamp: internal.MatchAnyUnixMilli,
+		},
+	})
+}
+
+func TestHookInContext(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn,
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+	out := bytes.NewBuffer([]byte{})
+	log := newHookLogger(out, NewHook(app.Application))
+	txn := app.StartTransaction("test txn")
+	md := txn.GetLinkingMetadata()
+
+	ctx := newrelic.NewContext(context.Background(), txn)
+	message := "Hello World!"
+	log.WithContext(ctx).WithField("zip", "zap").Info(message)
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &fields); err != nil {
+		t.Fatal(err, out.String())
+	}
+	for key, value := range map[string]string{
+		KeyTraceID:    md.TraceID,
+		KeySpanID:     md.SpanID,
+		KeyEntityName: integrationsupport.SampleAppName,
+		KeyEntityType: "SERVICE",
+		KeyEntityGUID: integrationsupport.TestEntityGUID,
+		"zip":         "zap",
+		"msg":         message,
+	} {
+		if fields[key] != value {
+			t.Errorf("field %s: expected %q, got %v", key, value, fields[key])
+		}
+	}
+	txn.ExpectLogEvents(t, []internal.WantLog{
+		{
+			Severity:  logrus.InfoLevel.String(),
+			Message:   message,
+			Timestamp: internal.MatchAnyUnixMilli,
+			SpanID:    md.SpanID,
+			TraceID:   md.TraceID,
+		},
+	})
+	txn.End()
+}
+
+func TestHookLevels(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn,
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+	out := bytes.NewBuffer([]byte{})
+	log := newHookLogger(out, NewLevelHook(app.Application, []logrus.Level{logrus.ErrorLevel}))
+	log.Info("not forwarded")
+	log.Error("forwarded")
+	app.ExpectLogEvents(t, []internal.WantLog{
+		{
+			Severity:  logrus.ErrorLevel.String(),
+			Message:   "forwarded",
+			Timestamp: internal.MatchAnyUnixMilli,
+		},
+	})
+}
+
+func TestHookAttributes(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn,
+		newrelic.ConfigAppLogForwardingEnabled(true),
+		newrelic.ConfigAppLogForwardingAttributeAllowList("order_id"),
+	)
+	out := bytes.NewBuffer([]byte{})
+	log := newHookLogger(out, NewHook(app.Application))
+	log.WithFields(logrus.Fields{"order_id": 1234, "zip": "zap"}).Info("order placed")
+	app.ExpectLogEvents(t, []internal.WantLog{
+		{
+			Severity:  logrus.InfoLevel.String(),
+			Message:   "order placed",
+			Timestamp: internal.MatchAnyUnixMilli,
+			Attributes: map[string]interface{}{
+				"order_id": 1234,
+			},
+		},
+	})
+}
+
+func TestFieldsFormatterEntryUnchanged(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn)
+	txn := app.StartTransaction("test txn")
+	e := logrus.New().WithContext(newrelic.NewContext(context.Background(), txn))
+	if _, err := NewFieldsFormatter(&logrus.TextFormatter{}).Format(e); err != nil {
+		t.Fatal(err)
+	}
+	if len(e.Data) != 0 {
+		t.Error(e.Data)
+	}
+	txn.End()
+}
@@ -1,7 +1,7 @@
 // nrwriter is a library of common code that handles capturing and sending New Relic logs in context data
 // from any io.Writer. This module should not be used as a standalone integration for logs in context.
 //
-// See github.com/newrelic/go-agent/v3/integrations/logcontext-v2/zerologWriter for an example of how
+// See github.com/rainforestpay/go-agent/v3/integrations/logcontext-v2/zerologWriter for an example of how
 // to use this library.
 
 package nrwriter
@@ -10,16 +10,19 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"sync"
+	"unicode/utf8"
 
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 // LogWriter is an io.Writer that captures log data for use with New Relic Logs in Context
 type LogWriter struct {
-	debug bool
-	out   io.Writer
-	app   *newrelic.Application
-	txn   *newrelic.Transaction
+	debug         bool
+	maxLineLength int
+	out           *batchWriter
+	app           *newrelic.Application
+	txn           *newrelic.Transaction
 }
 
 // New creates a new NewRelicWriter Object
@@ -27,7 +30,7 @@ type LogWriter struct {
 // app must be a vaild, non nil new relic Application
 func New(output io.Writer, app *newrelic.Application) LogWriter {
 	return LogWriter{
-		out: output,
+		out: &batchWriter{out: output},
 		app: app,
 	}
 }
@@ -38,33 +41,64 @@ func (b *LogWriter) DebugLogging(enabled bool) {
 	b.debug = enabled
 }
 
+// BatchSize sets the number of bytes of log lines buffered before they are
+// written to the output.  Buffered lines are also written by Flush.  The
+// buffer is shared by every LogWriter created from this one with
+// WithTransaction or WithContext, so that lines are written in order.  By
+// default, the batch size is zero and each line is written immediately.
+func (b *LogWriter) BatchSize(size int) {
+	b.out.setSize(size)
+}
+
+// MaxLineLength sets the maximum number of bytes of each log line recorded by
+// New Relic.  Longer lines are truncated rather than dropped.  The output is
+// not truncated.  By default, and at most, the maximum is
+// newrelic.MaxLogLength.
+func (b *LogWriter) MaxLineLength(max int) {
+	b.maxLineLength = max
+}
+
+// Flush writes the buffered log lines to the output.
+func (b LogWriter) Flush() error {
+	return b.out.flush()
+}
+
 // WithTransaction duplicates the current NewRelicWriter and sets the transaction to txn
 func (b *LogWriter) WithTransaction(txn *newrelic.Transaction) LogWriter {
-	return LogWriter{
-		out:   b.out,
-		app:   b.app,
-		debug: b.debug,
-		txn:   txn,
-	}
+	w := *b
+	w.txn = txn
+	return w
 }
 
 // WithTransaction duplicates the current NewRelicWriter and sets the transaction to the transaction parsed from ctx
 func (b *LogWriter) WithContext(ctx context.Context) LogWriter {
-	txn := newrelic.FromContext(ctx)
-	return LogWriter{
-		out:   b.out,
-		app:   b.app,
-		debug: b.debug,
-		txn:   txn,
+	return b.WithTransaction(newrelic.FromContext(ctx))
+}
+
+// truncateMessage shortens the message to at most max bytes without
+// splitting a UTF-8 encoded character.
+func truncateMessage(message string, max int) string {
+	if max <= 0 || max > newrelic.MaxLogLength {
+		max = newrelic.MaxLogLength
+	}
+	if len(message) <= max {
+		return message
 	}
+	for max > 0 && !utf8.RuneStart(message[max]) {
+		max--
+	}
+	return message[:max]
 }
 
 // EnrichLog attempts to enrich a log with New Relic linking metadata. If it fails,
 // it will return the original log line unless debug=true, otherwise it will print
 // an error on a following line.
 func (b *LogWriter) EnrichLog(data newrelic.LogData, p []byte) []byte {
+	data.Message = truncateMessage(data.Message, b.maxLineLength)
 	logLine := bytes.TrimRight(p, "\n")
-	buf := bytes.NewBuffer(logLine)
+	// The line's capacity is limited so that enriching it does not
+	// overwrite the bytes following it in p.
+	buf := bytes.NewBuffer(logLine[:len(logLine):len(logLine)])
 
 	var enrichErr error
 	if b.txn != nil {
@@ -84,7 +118,90 @@ func (b *LogWriter) EnrichLog(data newrelic.LogData, p []byte) []byte {
 	return buf.Bytes()
 }
 
+// WriteLines enriches and records each line of p, and writes the enriched
+// lines to the output.  It allows any logger which writes to an io.Writer to
+// forward its logs.  The log data of each line is returned by parse, or if
+// parse is nil, the whole line is the message.  It returns len(p) if the
+// lines were written or buffered.
+func (b LogWriter) WriteLines(p []byte, parse func(line []byte) newrelic.LogData) (n int, err error) {
+	var enriched []byte
+	for rest := p; len(rest) > 0; {
+		line := rest
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			line, rest = rest[:i+1], rest[i+1:]
+		} else {
+			rest = nil
+		}
+		trimmed := bytes.TrimRight(line, "\n")
+		if len(trimmed) == 0 {
+			continue
+		}
+		var data newrelic.LogData
+		if nil != parse {
+			data = parse(line)
+		} else {
+			data.Message = string(trimmed)
+		}
+		enriched = append(enriched, b.EnrichLog(data, line)...)
+	}
+	if len(enriched) == 0 {
+		return len(p), nil
+	}
+	if _, err := b.Write(enriched); nil != err {
+		return 0, err
+	}
+	return len(p), nil
+}
+
 // Write implements io.Write
 func (b LogWriter) Write(p []byte) (n int, err error) {
 	return b.out.Write(p)
 }
+
+// batchWriter buffers writes to the output until the batch size is reached.
+type batchWriter struct {
+	sync.Mutex
+	out  io.Writer
+	size int
+	buf  bytes.Buffer
+}
+
+func (w *batchWriter) setSize(size int) {
+	w.Lock()
+	defer w.Unlock()
+	w.size = size
+}
+
+// Write implements io.Writer.
+func (w *batchWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	if w.size <= 0 {
+		if err := w.flushLocked(); nil != err {
+			return 0, err
+		}
+		return w.out.Write(p)
+	}
+	w.buf.Write(p)
+	if w.buf.Len() >= w.size {
+		if err := w.flushLocked(); nil != err {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *batchWriter) flush() error {
+	w.Lock()
+	defer w.Unlock()
+	return w.flushLocked()
+}
+
+func (w *batchWriter) flushLocked() error {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	_, err := w.out.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
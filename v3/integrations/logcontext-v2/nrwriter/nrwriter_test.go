@@ -7,10 +7,11 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
-	"github.com/newrelic/go-agent/v3/internal/logcontext"
-	"github.com/newrelic/go-agent/v3/internal/sysinfo"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/internal/logcontext"
+	"github.com/rainforestpay/go-agent/v3/internal/sysinfo"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 var (
@@ -173,3 +174,105 @@ func TestNilContextLog(t *testing.T) {
 		EntityName: integrationsupport.SampleAppName,
 	})
 }
+
+func TestWriteLines(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn,
+		newrelic.ConfigAppLogDecoratingEnabled(true),
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+	buf := bytes.NewBuffer([]byte{})
+	a := New(buf, app.Application)
+
+	input := []byte("first line\n\nsecond line\nthird line")
+	n, err := a.WriteLines(input, nil)
+	if n != len(input) || err != nil {
+		t.Error(n, err)
+	}
+	if count := strings.Count(buf.String(), nrlinking); count != 3 {
+		t.Errorf("Expected 3 decorated lines, got %d: %s", count, buf.String())
+	}
+	app.ExpectLogEvents(t, []internal.WantLog{
+		{Severity: logcontext.LogSeverityUnknown, Message: "first line", Timestamp: internal.MatchAnyUnixMilli},
+		{Severity: logcontext.LogSeverityUnknown, Message: "second line", Timestamp: internal.MatchAnyUnixMilli},
+		{Severity: logcontext.LogSeverityUnknown, Message: "third line", Timestamp: internal.MatchAnyUnixMilli},
+	})
+}
+
+func TestWriteLinesParse(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn,
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+	a := New(io.Discard, app.Application)
+	parse := func(line []byte) newrelic.LogData {
+		return newrelic.LogData{Severity: "WARN", Message: strings.ToUpper(string(line))}
+	}
+	a.WriteLines([]byte("hello\n"), parse)
+	app.ExpectLogEvents(t, []internal.WantLog{
+		{Severity: "WARN", Message: "HELLO", Timestamp: internal.MatchAnyUnixMilli},
+	})
+}
+
+func TestBatchSize(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn)
+	buf := bytes.NewBuffer([]byte{})
+	a := New(buf, app.Application)
+	a.BatchSize(1000)
+
+	txn := app.StartTransaction("test transaction")
+	b := a.WithTransaction(txn)
+	a.WriteLines([]byte("first line\n"), nil)
+	b.WriteLines([]byte("second line\n"), nil)
+	if buf.Len() != 0 {
+		t.Errorf("Expected lines to be buffered, got: %s", buf.String())
+	}
+	if err := a.Flush(); err != nil {
+		t.Error(err)
+	}
+	if out := buf.String(); out != "first line\nsecond line\n" {
+		t.Errorf("Unexpected output: %s", out)
+	}
+
+	// Lines are written once the batch size is reached.
+	buf.Reset()
+	a.BatchSize(5)
+	a.WriteLines([]byte("third line\n"), nil)
+	if out := buf.String(); out != "third line\n" {
+		t.Errorf("Unexpected output: %s", out)
+	}
+	txn.End()
+}
+
+func TestMaxLineLength(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn,
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+	buf := bytes.NewBuffer([]byte{})
+	a := New(buf, app.Application)
+	a.MaxLineLength(6)
+
+	a.WriteLines([]byte("héllo world\n"), nil)
+	if out := buf.String(); out != "héllo world\n" {
+		t.Errorf("Output truncated: %s", out)
+	}
+	app.ExpectLogEvents(t, []internal.WantLog{
+		{Severity: logcontext.LogSeverityUnknown, Message: "héllo", Timestamp: internal.MatchAnyUnixMilli},
+	})
+}
+
+func TestTruncateMessage(t *testing.T) {
+	for _, tc := range []struct {
+		message string
+		max     int
+		expect  string
+	}{
+		{message: "hello", max: 10, expect: "hello"},
+		{message: "hello", max: 3, expect: "hel"},
+		{message: "héllo", max: 2, expect: "h"},
+		{message: "héllo", max: 3, expect: "hé"},
+		{message: strings.Repeat("a", newrelic.MaxLogLength+1), max: 0, expect: strings.Repeat("a", newrelic.MaxLogLength)},
+	} {
+		if out := truncateMessage(tc.message, tc.max); out != tc.expect {
+			t.Errorf("truncateMessage(%q, %d) = %q, expected %q", tc.message, tc.max, out, tc.expect)
+		}
+	}
+}
@@ -1,9 +1,25 @@
-module github.com/newrelic/go-agent/v3/integrations/logcontext-v2/zerologWriter
+module github.com/rainforestpay/go-agent/v3/integrations/logcontext-v2/zerologWriter
 
 go 1.17
 
 require (
-	github.com/newrelic/go-agent/v3 v3.19.1
-	github.com/newrelic/go-agent/v3/integrations/logcontext-v2/nrwriter v1.0.0
+	github.com/rainforestpay/go-agent/v3 v3.20.0
+	github.com/rainforestpay/go-agent/v3/integrations/logcontext-v2/nrwriter v1.0.0
 	github.com/rs/zerolog v1.27.0
 )
+
+require (
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/mattn/go-colorable v0.1.12 // indirect
+	github.com/mattn/go-isatty v0.0.14 // indirect
+	golang.org/x/net v0.0.0-20201021035429-f5854403a974 // indirect
+	golang.org/x/sys v0.0.0-20210927094055-39ccf1dd6fa6 // indirect
+	golang.org/x/text v0.3.3 // indirect
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013 // indirect
+	google.golang.org/grpc v1.49.0 // indirect
+	google.golang.org/protobuf v1.27.1 // indirect
+)
+
+replace github.com/rainforestpay/go-agent/v3 => ../../..
+
+replace github.com/rainforestpay/go-agent/v3/integrations/logcontext-v2/nrwriter => ../nrwriter
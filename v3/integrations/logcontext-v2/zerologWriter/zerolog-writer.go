@@ -7,9 +7,9 @@ import (
 	"time"
 	"unicode"
 
-	"github.com/newrelic/go-agent/v3/integrations/logcontext-v2/nrwriter"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/integrations/logcontext-v2/nrwriter"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 	"github.com/rs/zerolog"
 )
 
@@ -32,6 +32,14 @@ func New(output io.Writer, app *newrelic.Application) ZerologWriter {
 // will fail silently. Enabling debug logging will print error messages on a new line after your log message.
 func (zw *ZerologWriter) DebugLogging(enabled bool) { zw.w.DebugLogging(enabled) }
 
+// BatchSize sets the number of bytes of log lines buffered before they are written to the output
+// io.Writer. By default, the batch size is zero and each line is written immediately. Buffered lines
+// are written by Flush, which should be called before the application exits.
+func (zw *ZerologWriter) BatchSize(size int) { zw.w.BatchSize(size) }
+
+// Flush writes the buffered log lines to the output io.Writer.
+func (zw ZerologWriter) Flush() error { return zw.w.Flush() }
+
 // WithTransaction creates a new ZerologWriter for a specific transactions
 func (zw *ZerologWriter) WithTransaction(txn *newrelic.Transaction) ZerologWriter {
 	return ZerologWriter{w: zw.w.WithTransaction(txn)}
@@ -44,9 +52,7 @@ func (zw *ZerologWriter) WithContext(ctx context.Context) ZerologWriter {
 
 // Write is a valid io.Writer method that will write the content of an enriched log to the output io.Writer
 func (zw ZerologWriter) Write(p []byte) (n int, err error) {
-	data := parseJSONLogData(p)
-	enrichedLog := zw.w.EnrichLog(data, p)
-	return zw.w.Write(enrichedLog)
+	return zw.w.WriteLines(p, parseJSONLogData)
 }
 
 func parseJSONLogData(log []byte) newrelic.LogData {
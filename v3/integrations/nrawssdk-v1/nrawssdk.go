@@ -6,8 +6,8 @@ package nrawssdk
 
 import (
 	"github.com/aws/aws-sdk-go/aws/request"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/internal/awssupport"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/awssupport"
 )
 
 func init() { internal.TrackUsage("integration", "library", "aws-sdk-go") }
@@ -20,6 +20,13 @@ func startSegment(req *request.Request) {
 		Region:      req.ClientInfo.SigningRegion,
 		Params:      req.Params,
 	}
+	// The credentials have already been retrieved to sign the request, so
+	// this does not make a call to the credentials provider.
+	if creds := req.Config.Credentials; creds != nil {
+		if v, err := creds.Get(); err == nil {
+			input.AccountID = awssupport.GetAccessKeyAccountID(v.AccessKeyID)
+		}
+	}
 	req.HTTPRequest = awssupport.StartSegment(input)
 }
 
@@ -36,7 +43,12 @@ func endSegment(req *request.Request) {
 // `newrelic.ExternalSegment` type.
 //
 // Additional attributes will be added to Transaction Trace Segments and Span
-// Events: aws.region, aws.requestId, and aws.operation.
+// Events: aws.region, aws.requestId, aws.operation, cloud.region, and
+// cloud.account.id.  The account ID is decoded from the access key of the
+// request's credentials.  Calls to SNS, SQS, and Lambda also include
+// aws.arn when the target resource can be determined from the request
+// parameters.  These attributes are used to create relationships between
+// your application and the AWS resources it calls.
 //
 // To add instrumentation to the Session and see segments created for each
 // invocation that uses the Session, call InstrumentHandlers with the session's
@@ -33,8 +33,9 @@ import (
 	awsmiddle "github.com/aws/aws-sdk-go-v2/aws/middleware"
 	smithymiddle "github.com/aws/smithy-go/middleware"
 	smithyhttp "github.com/aws/smithy-go/transport/http"
-	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal/awssupport"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 type nrMiddleware struct {
@@ -43,6 +44,22 @@ type nrMiddleware struct {
 
 type endable interface{ End() }
 
+// paramsKey is the stack value key of the operation's input parameters.
+type paramsKey struct{}
+
+// initializeMiddleware records the operation's input parameters so that the
+// deserialize middleware can determine the ARN of the resource being called.
+func (m nrMiddleware) initializeMiddleware(stack *smithymiddle.Stack) error {
+	return stack.Initialize.Add(smithymiddle.InitializeMiddlewareFunc("NRInitializeMiddleware", func(
+		ctx context.Context, in smithymiddle.InitializeInput, next smithymiddle.InitializeHandler) (
+		smithymiddle.InitializeOutput, smithymiddle.Metadata, error) {
+
+		ctx = smithymiddle.WithStackValue(ctx, paramsKey{}, in.Parameters)
+		return next.HandleInitialize(ctx, in)
+	}),
+		smithymiddle.After)
+}
+
 // See https://aws.github.io/aws-sdk-go-v2/docs/middleware/ for a description of
 // AWS SDK V2 middleware.
 func (m nrMiddleware) deserializeMiddleware(stack *smithymiddle.Stack) error {
@@ -95,6 +112,14 @@ func (m nrMiddleware) deserializeMiddleware(stack *smithymiddle.Stack) error {
 				newrelic.SpanAttributeAWSOperation, operation)
 			integrationsupport.AddAgentSpanAttribute(txn,
 				newrelic.SpanAttributeAWSRegion, region)
+			arn := awssupport.GetARN(serviceName, region, "",
+				smithymiddle.GetStackValue(ctx, paramsKey{}))
+			integrationsupport.AddAgentSpanAttribute(txn,
+				newrelic.SpanAttributeCloudRegion, region)
+			integrationsupport.AddAgentSpanAttribute(txn,
+				newrelic.SpanAttributeCloudAccountID, awssupport.GetARNAccountID(arn))
+			integrationsupport.AddAgentSpanAttribute(txn,
+				newrelic.SpanAttributeAWSARN, arn)
 			requestID, ok := awsmiddle.GetRequestIDMetadata(metadata)
 			if ok {
 				integrationsupport.AddAgentSpanAttribute(txn,
@@ -114,8 +139,10 @@ func (m nrMiddleware) deserializeMiddleware(stack *smithymiddle.Stack) error {
 // using `newrelic.FromContext`.
 //
 // Additional attributes will be added to transaction trace segments and span
-// events: aws.region, aws.requestId, and aws.operation. In addition,
-// http.statusCode will be added to span events.
+// events: aws.region, aws.requestId, aws.operation, and cloud.region. In
+// addition, http.statusCode will be added to span events. Calls to SNS, SQS,
+// and Lambda also include aws.arn and cloud.account.id when the target
+// resource can be determined from the request parameters.
 //
 // To see segments and spans for all AWS invocations, call AppendMiddlewares
 // with the AWS Config `apiOptions` and provide nil for `txn`. For example:
@@ -140,5 +167,5 @@ func (m nrMiddleware) deserializeMiddleware(stack *smithymiddle.Stack) error {
 //  nraws.AppendMiddlewares(&awsConfig.APIOptions, txn)
 func AppendMiddlewares(apiOptions *[]func(*smithymiddle.Stack) error, txn *newrelic.Transaction) {
 	m := nrMiddleware{txn: txn}
-	*apiOptions = append(*apiOptions, m.deserializeMiddleware)
+	*apiOptions = append(*apiOptions, m.initializeMiddleware, m.deserializeMiddleware)
 }
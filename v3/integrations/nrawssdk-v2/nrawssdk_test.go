@@ -17,9 +17,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
 	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 func testApp() integrationsupport.ExpectApp {
@@ -111,6 +111,7 @@ var (
 		AgentAttributes: map[string]interface{}{
 			"aws.operation":   "Invoke",
 			"aws.region":      awsRegion,
+			"cloud.region":    awsRegion,
 			"aws.requestId":   requestID,
 			"http.method":     "POST",
 			"http.url":        "https://lambda.us-west-2.amazonaws.com/2015-03-31/functions/non-existent-function/invocations",
@@ -134,6 +135,7 @@ var (
 		AgentAttributes: map[string]interface{}{
 			"aws.operation":   "Invoke",
 			"aws.region":      awsRegion,
+			"cloud.region":    awsRegion,
 			"http.method":     "POST",
 			"http.url":        "https://lambda.us-west-2.amazonaws.com/2015-03-31/functions/non-existent-function/invocations",
 			"http.statusCode": "200",
@@ -156,6 +158,7 @@ var (
 		AgentAttributes: map[string]interface{}{
 			"aws.operation":   "DescribeTable",
 			"aws.region":      awsRegion,
+			"cloud.region":    awsRegion,
 			"aws.requestId":   requestID,
 			"db.statement":    "'DescribeTable' on 'unknown' using 'DynamoDB'",
 			"peer.address":    "dynamodb.us-west-2.amazonaws.com:unknown",
@@ -257,6 +260,42 @@ func TestInstrumentRequestExternal(t *testing.T) {
 	)
 }
 
+func TestInstrumentRequestExternalARN(t *testing.T) {
+	app := testApp()
+	txn := app.StartTransaction(txnName)
+	ctx := context.Background()
+
+	client := lambda.NewFromConfig(newConfig(ctx, txn))
+
+	functionARN := "arn:aws:lambda:us-west-2:123456789012:function:my-function"
+	input := &lambda.InvokeInput{
+		FunctionName: aws.String(functionARN),
+		Payload:      []byte("{}"),
+	}
+
+	_, err := client.Invoke(ctx, input)
+	if err != nil {
+		t.Error(err)
+	}
+
+	txn.End()
+
+	span := externalSpan
+	span.AgentAttributes = map[string]interface{}{
+		"aws.operation":    "Invoke",
+		"aws.region":       awsRegion,
+		"aws.requestId":    requestID,
+		"aws.arn":          functionARN,
+		"cloud.region":     awsRegion,
+		"cloud.account.id": "123456789012",
+		"http.method":      "POST",
+		"http.url":         internal.MatchAnything,
+		"http.statusCode":  "200",
+	}
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		span, genericSpan})
+}
+
 func TestInstrumentRequestDatastore(t *testing.T) {
 	runTestTable(t,
 		[]*testTableEntry{
@@ -401,6 +440,7 @@ func TestRetrySend(t *testing.T) {
 					AgentAttributes: map[string]interface{}{
 						"aws.operation":   "Invoke",
 						"aws.region":      awsRegion,
+						"cloud.region":    awsRegion,
 						"http.method":     "POST",
 						"http.url":        "https://lambda.us-west-2.amazonaws.com/2015-03-31/functions/non-existent-function/invocations",
 						"http.statusCode": "0",
@@ -422,6 +462,7 @@ func TestRetrySend(t *testing.T) {
 					AgentAttributes: map[string]interface{}{
 						"aws.operation":   "Invoke",
 						"aws.region":      awsRegion,
+						"cloud.region":    awsRegion,
 						"aws.requestId":   requestID,
 						"http.method":     "POST",
 						"http.url":        "https://lambda.us-west-2.amazonaws.com/2015-03-31/functions/non-existent-function/invocations",
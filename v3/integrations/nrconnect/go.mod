@@ -0,0 +1,22 @@
+module github.com/rainforestpay/go-agent/v3/integrations/nrconnect
+
+// As of Aug 2023, the connect go.mod file uses 1.19:
+// https://github.com/connectrpc/connect-go/blob/main/go.mod
+go 1.19
+
+require (
+	connectrpc.com/connect v1.11.1
+	github.com/rainforestpay/go-agent/v3 v3.20.0
+	google.golang.org/protobuf v1.31.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.2 // indirect
+	golang.org/x/net v0.0.0-20201021035429-f5854403a974 // indirect
+	golang.org/x/sys v0.0.0-20210119212857-b64e53b001e4 // indirect
+	golang.org/x/text v0.3.3 // indirect
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013 // indirect
+	google.golang.org/grpc v1.49.0 // indirect
+)
+
+replace github.com/rainforestpay/go-agent/v3 => ../..
@@ -0,0 +1,194 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrconnect instruments https://connectrpc.com/connect servers and
+// clients.
+//
+// Use NewInterceptor to create a connect.Interceptor and add it to your
+// handlers and clients using connect.WithInterceptors:
+//
+//	interceptors := connect.WithInterceptors(nrconnect.NewInterceptor(app))
+//	mux.Handle(greetv1connect.NewGreetServiceHandler(&server{}, interceptors))
+//	client := greetv1connect.NewGreetServiceClient(http.DefaultClient, url, interceptors)
+//
+// Each call handled by the server is recorded with a transaction named after
+// its procedure, eg. "greet.v1.GreetService/Greet", with the rpc.* attributes
+// added.  If the handler is also wrapped with newrelic.WrapHandle, the
+// transaction started there is used instead.  Errors returned by the handler
+// are noticed as expected errors if their code indicates a problem with the
+// request, eg. connect.CodeNotFound, and as errors otherwise.
+//
+// Each call made by a client with a transaction in its context is recorded
+// with an external segment, and distributed tracing headers are added to the
+// request.
+package nrconnect
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"connectrpc.com/connect"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+func init() { internal.TrackUsage("integration", "framework", "connect") }
+
+const rpcSystem = "connect_rpc"
+
+// Option configures the interceptor created by NewInterceptor.
+type Option func(*interceptor)
+
+// WithTransactionNameGrouping sets how calls are grouped into transaction
+// names.  By default, transactions are named after the service and method.
+func WithTransactionNameGrouping(grouping newrelic.RPCNameGrouping) Option {
+	return func(i *interceptor) {
+		i.nameGrouping = grouping
+	}
+}
+
+type interceptor struct {
+	app          *newrelic.Application
+	nameGrouping newrelic.RPCNameGrouping
+}
+
+var _ connect.Interceptor = &interceptor{}
+
+// NewInterceptor returns a connect.Interceptor which instruments the calls
+// handled by servers and made by clients.  The application is used to start
+// transactions for calls handled without a transaction in their context, and
+// may be nil if the handlers are wrapped using newrelic.WrapHandle.
+func NewInterceptor(app *newrelic.Application, options ...Option) connect.Interceptor {
+	i := &interceptor{
+		app:          app,
+		nameGrouping: newrelic.RPCGroupByMethod,
+	}
+	for _, option := range options {
+		option(i)
+	}
+	return i
+}
+
+func splitProcedure(procedure string) (service, method string) {
+	procedure = strings.TrimPrefix(procedure, "/")
+	if idx := strings.LastIndex(procedure, "/"); idx >= 0 {
+		return procedure[:idx], procedure[idx+1:]
+	}
+	return procedure, ""
+}
+
+// startTransaction returns the transaction used to record the call, the
+// context containing it, and a function which ends it if it was started here.
+func (i *interceptor) startTransaction(ctx context.Context, spec connect.Spec, req newrelic.WebRequest) (*newrelic.Transaction, context.Context, func()) {
+	name := newrelic.RPCTransactionName(spec.Procedure, i.nameGrouping)
+	end := func() {}
+	txn := newrelic.FromContext(ctx)
+	if nil != txn {
+		txn.SetName(name)
+	} else if nil != i.app {
+		txn = i.app.StartTransaction(name)
+		txn.SetWebRequest(req)
+		ctx = newrelic.NewContext(ctx, txn)
+		end = txn.End
+	} else {
+		return nil, ctx, end
+	}
+	service, method := splitProcedure(spec.Procedure)
+	integrationsupport.AddRPCAttributes(txn, rpcSystem, service, method)
+	return txn, ctx, end
+}
+
+func noticeError(txn *newrelic.Transaction, err error) {
+	if nil == err {
+		return
+	}
+	code := connect.CodeOf(err).String()
+	msg := err.Error()
+	var connectErr *connect.Error
+	if errors.As(err, &connectErr) {
+		msg = connectErr.Message()
+	}
+	integrationsupport.NoticeRPCError(txn, "Connect Error: "+code, code, msg)
+}
+
+func startClientSegment(ctx context.Context, spec connect.Spec, peer connect.Peer, hdrs http.Header) *newrelic.ExternalSegment {
+	txn := newrelic.FromContext(ctx)
+	if nil == txn {
+		return nil
+	}
+	seg := newrelic.StartExternalSegment(txn, nil)
+	seg.Host = peer.Addr
+	seg.Library = "Connect"
+	seg.Procedure = strings.TrimPrefix(spec.Procedure, "/")
+	txn.InsertDistributedTraceHeaders(hdrs)
+	return seg
+}
+
+// WrapUnary implements connect.Interceptor.
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		spec := req.Spec()
+		if spec.IsClient {
+			seg := startClientSegment(ctx, spec, req.Peer(), req.Header())
+			defer seg.End()
+			return next(ctx, req)
+		}
+		txn, ctx, end := i.startTransaction(ctx, spec, newrelic.WebRequest{
+			Header:    req.Header(),
+			URL:       &url.URL{Path: spec.Procedure},
+			Method:    req.HTTPMethod(),
+			Transport: newrelic.TransportHTTP,
+		})
+		defer end()
+		resp, err := next(ctx, req)
+		noticeError(txn, err)
+		return resp, err
+	}
+}
+
+type wrappedClientConn struct {
+	connect.StreamingClientConn
+	segment *newrelic.ExternalSegment
+}
+
+func (c wrappedClientConn) CloseResponse() error {
+	err := c.StreamingClientConn.CloseResponse()
+	c.segment.End()
+	return err
+}
+
+// WrapStreamingClient implements connect.Interceptor.
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		seg := startClientSegment(ctx, spec, conn.Peer(), conn.RequestHeader())
+		if nil == seg {
+			return conn
+		}
+		return wrappedClientConn{
+			StreamingClientConn: conn,
+			segment:             seg,
+		}
+	}
+}
+
+// WrapStreamingHandler implements connect.Interceptor.
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		spec := conn.Spec()
+		txn, ctx, end := i.startTransaction(ctx, spec, newrelic.WebRequest{
+			Header:    conn.RequestHeader(),
+			URL:       &url.URL{Path: spec.Procedure},
+			Method:    "POST",
+			Transport: newrelic.TransportHTTP,
+		})
+		defer end()
+		err := next(ctx, conn)
+		noticeError(txn, err)
+		return err
+	}
+}
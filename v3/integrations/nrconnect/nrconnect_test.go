@@ -0,0 +1,167 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrconnect
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const echoProcedure = "/test.v1.TestService/Echo"
+
+// newTestServer starts a server handling echoProcedure with the handler
+// provided.  The server must be closed when done with it.
+func newTestServer(handler func(context.Context, *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error), options ...connect.HandlerOption) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.Handle(echoProcedure, connect.NewUnaryHandler(echoProcedure, handler, options...))
+	return httptest.NewServer(mux)
+}
+
+func echo(ctx context.Context, req *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error) {
+	return connect.NewResponse(wrapperspb.String(req.Msg.GetValue())), nil
+}
+
+func callEcho(ctx context.Context, url string, options ...connect.ClientOption) error {
+	client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](http.DefaultClient, url+echoProcedure, options...)
+	_, err := client.CallUnary(ctx, connect.NewRequest(wrapperspb.String("hello")))
+	return err
+}
+
+func TestServerUnary(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	srv := newTestServer(func(ctx context.Context, req *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error) {
+		if nil == newrelic.FromContext(ctx) {
+			t.Error("transaction not found in context")
+		}
+		return echo(ctx, req)
+	}, connect.WithInterceptors(NewInterceptor(app.Application)))
+	defer srv.Close()
+
+	if err := callEcho(context.Background(), srv.URL); nil != err {
+		t.Fatal(err)
+	}
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/test.v1.TestService/Echo",
+			"guid":             internal.MatchAnything,
+			"traceId":          internal.MatchAnything,
+			"priority":         internal.MatchAnything,
+			"sampled":          internal.MatchAnything,
+			"nr.apdexPerfZone": internal.MatchAnything,
+		},
+		AgentAttributes: map[string]interface{}{
+			newrelic.AttributeRPCSystem:   "connect_rpc",
+			newrelic.AttributeRPCService:  "test.v1.TestService",
+			newrelic.AttributeRPCMethod:   "Echo",
+			"request.method":              "POST",
+			"request.uri":                 echoProcedure,
+			"request.headers.contentType": "application/proto",
+		},
+	}})
+}
+
+func TestServerUnaryNameGrouping(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	interceptor := NewInterceptor(app.Application, WithTransactionNameGrouping(newrelic.RPCGroupByService))
+	srv := newTestServer(echo, connect.WithInterceptors(interceptor))
+	defer srv.Close()
+
+	if err := callEcho(context.Background(), srv.URL); nil != err {
+		t.Fatal(err)
+	}
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "WebTransaction/Go/test.v1.TestService"},
+	})
+}
+
+func TestServerUnaryErrors(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	code := connect.CodeNotFound
+	srv := newTestServer(func(context.Context, *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error) {
+		return nil, connect.NewError(code, errors.New("oops"))
+	}, connect.WithInterceptors(NewInterceptor(app.Application)))
+	defer srv.Close()
+
+	if err := callEcho(context.Background(), srv.URL); connect.CodeNotFound != connect.CodeOf(err) {
+		t.Fatal(err)
+	}
+	code = connect.CodeInternal
+	if err := callEcho(context.Background(), srv.URL); connect.CodeInternal != connect.CodeOf(err) {
+		t.Fatal(err)
+	}
+
+	app.ExpectErrors(t, []internal.WantError{
+		{TxnName: "WebTransaction/Go/test.v1.TestService/Echo", Msg: "oops", Klass: "Connect Error: not_found"},
+		{TxnName: "WebTransaction/Go/test.v1.TestService/Echo", Msg: "oops", Klass: "Connect Error: internal"},
+	})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "ErrorsExpected/all"},
+		{Name: "Errors/all"},
+	})
+}
+
+func TestServerUnaryExistingTransaction(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	mux := http.NewServeMux()
+	mux.Handle(newrelic.WrapHandle(app.Application, echoProcedure,
+		connect.NewUnaryHandler(echoProcedure, echo, connect.WithInterceptors(NewInterceptor(nil)))))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	if err := callEcho(context.Background(), srv.URL); nil != err {
+		t.Fatal(err)
+	}
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "WebTransaction/Go/test.v1.TestService/Echo"},
+	})
+}
+
+func TestClientUnary(t *testing.T) {
+	app := integrationsupport.NewTestApp(func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+		reply.AccountID = "123"
+		reply.TrustedAccountKey = "123"
+		reply.PrimaryAppID = "456"
+	}, integrationsupport.DTEnabledCfgFn)
+	srv := newTestServer(func(ctx context.Context, req *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error) {
+		if hdr := req.Header().Get("traceparent"); "" == hdr {
+			t.Error("distributed tracing headers not found")
+		}
+		return echo(ctx, req)
+	})
+	defer srv.Close()
+
+	txn := app.StartTransaction("client")
+	ctx := newrelic.NewContext(context.Background(), txn)
+	if err := callEcho(ctx, srv.URL, connect.WithInterceptors(NewInterceptor(nil))); nil != err {
+		t.Fatal(err)
+	}
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "External/all"},
+		{Name: "External/" + srv.Listener.Addr().String() + "/Connect/test.v1.TestService/Echo", Scope: "OtherTransaction/Go/client"},
+	})
+}
+
+func TestClientUnaryNoTransaction(t *testing.T) {
+	srv := newTestServer(echo)
+	defer srv.Close()
+
+	if err := callEcho(context.Background(), srv.URL, connect.WithInterceptors(NewInterceptor(nil))); nil != err {
+		t.Fatal(err)
+	}
+}
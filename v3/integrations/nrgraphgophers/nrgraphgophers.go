@@ -5,7 +5,9 @@
 // applications.
 //
 // This package creates a graphql-go Tracer that adds adds segment
-// instrumentation to your graphql request transactions.
+// instrumentation to your graphql request transactions.  Transactions are
+// named after the GraphQL operation, eg. "query/GetUser", and the operation's
+// type and name are added as agent attributes.
 package nrgraphgophers
 
 import (
@@ -15,8 +17,9 @@ import (
 	"github.com/graph-gophers/graphql-go/errors"
 	"github.com/graph-gophers/graphql-go/introspection"
 	"github.com/graph-gophers/graphql-go/trace"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 func init() { internal.TrackUsage("integration", "framework", "graph-gophers") }
@@ -43,6 +46,14 @@ func NewTracer() trace.Tracer {
 	}
 }
 
+// WithPersistedQueryHash returns a copy of the context containing the hash of
+// the persisted query being executed.  Use it when resolving automatic
+// persisted queries to add the hash to the transaction as the
+// newrelic.AttributeGraphQLPersistedQueryHash attribute.
+func WithPersistedQueryHash(ctx context.Context, hash string) context.Context {
+	return integrationsupport.WithGraphQLPersistedQueryHash(ctx, hash)
+}
+
 func (t *tracer) newRequestID() requestID {
 	t.Lock()
 	defer t.Unlock()
@@ -81,6 +92,12 @@ func (t *tracer) TraceQuery(ctx context.Context, queryString string, operationNa
 		return ctx, func([]*errors.QueryError) {}
 	}
 
+	integrationsupport.NameGraphQLTransaction(txn, integrationsupport.GraphQLOperation{
+		Type:               integrationsupport.GraphQLOperationType(queryString, operationName),
+		Name:               operationName,
+		PersistedQueryHash: integrationsupport.GraphQLPersistedQueryHash(ctx),
+	})
+
 	// Since this https://github.com/graph-gophers/graphql-go/pull/374 was
 	// merged in Feb 2020, an empty operation name should be impossible.
 	// This conditional is left here in case someone is using an early
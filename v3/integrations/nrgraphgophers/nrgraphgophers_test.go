@@ -14,9 +14,9 @@ import (
 	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/graph-gophers/graphql-go/introspection"
 	"github.com/graph-gophers/graphql-go/relay"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 func TestFieldManagementSync(t *testing.T) {
@@ -100,18 +100,18 @@ func TestQueryWithAsyncFields(t *testing.T) {
 
 	app.ExpectMetrics(t, []internal.WantMetric{
 		{Name: "WebTransaction"},
-		{Name: "WebTransaction/Go/hello"},
+		{Name: "WebTransaction/Go/query/MyOperation"},
 		{Name: "WebTransactionTotalTime"},
-		{Name: "WebTransactionTotalTime/Go/hello"},
+		{Name: "WebTransactionTotalTime/Go/query/MyOperation"},
 		{Name: "Apdex"},
-		{Name: "Apdex/Go/hello"},
+		{Name: "Apdex/Go/query/MyOperation"},
 		{Name: "HttpDispatcher"},
 		{Name: "Custom/MyOperation"},
-		{Name: "Custom/MyOperation", Scope: "WebTransaction/Go/hello"},
+		{Name: "Custom/MyOperation", Scope: "WebTransaction/Go/query/MyOperation"},
 		{Name: "Custom/field2"},
-		{Name: "Custom/field2", Scope: "WebTransaction/Go/hello"},
+		{Name: "Custom/field2", Scope: "WebTransaction/Go/query/MyOperation"},
 		{Name: "Custom/field1"},
-		{Name: "Custom/field1", Scope: "WebTransaction/Go/hello"},
+		{Name: "Custom/field1", Scope: "WebTransaction/Go/query/MyOperation"},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Forced: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allWeb", Forced: nil},
 	})
@@ -158,16 +158,17 @@ func TestQueryRequest(t *testing.T) {
 	}
 	app.ExpectMetrics(t, []internal.WantMetric{
 		{Name: "WebTransaction"},
-		{Name: "WebTransaction/Go/POST /"},
+		{Name: "WebTransaction/Go/query/HelloOperation"},
 		{Name: "WebTransactionTotalTime"},
-		{Name: "WebTransactionTotalTime/Go/POST /"},
+		{Name: "WebTransactionTotalTime/Go/query/HelloOperation"},
 		{Name: "Apdex"},
-		{Name: "Apdex/Go/POST /"},
+		{Name: "Apdex/Go/query/HelloOperation"},
 		{Name: "HttpDispatcher"},
+		{Name: "HttpDispatcher/StatusClass/2xx"},
 		{Name: "Custom/HelloOperation"},
-		{Name: "Custom/HelloOperation", Scope: "WebTransaction/Go/POST /"},
+		{Name: "Custom/HelloOperation", Scope: "WebTransaction/Go/query/HelloOperation"},
 		{Name: "Custom/hello"},
-		{Name: "Custom/hello", Scope: "WebTransaction/Go/POST /"},
+		{Name: "Custom/hello", Scope: "WebTransaction/Go/query/HelloOperation"},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Forced: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allWeb", Forced: nil},
 	})
@@ -196,16 +197,17 @@ func TestQueryRequestUnknownOperation(t *testing.T) {
 	}
 	app.ExpectMetrics(t, []internal.WantMetric{
 		{Name: "WebTransaction"},
-		{Name: "WebTransaction/Go/POST /"},
+		{Name: "WebTransaction/Go/query/HelloOperation"},
 		{Name: "WebTransactionTotalTime"},
-		{Name: "WebTransactionTotalTime/Go/POST /"},
+		{Name: "WebTransactionTotalTime/Go/query/HelloOperation"},
 		{Name: "Apdex"},
-		{Name: "Apdex/Go/POST /"},
+		{Name: "Apdex/Go/query/HelloOperation"},
 		{Name: "HttpDispatcher"},
+		{Name: "HttpDispatcher/StatusClass/2xx"},
 		{Name: "Custom/HelloOperation"},
-		{Name: "Custom/HelloOperation", Scope: "WebTransaction/Go/POST /"},
+		{Name: "Custom/HelloOperation", Scope: "WebTransaction/Go/query/HelloOperation"},
 		{Name: "Custom/hello"},
-		{Name: "Custom/hello", Scope: "WebTransaction/Go/POST /"},
+		{Name: "Custom/hello", Scope: "WebTransaction/Go/query/HelloOperation"},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Forced: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allWeb", Forced: nil},
 	})
@@ -233,26 +235,27 @@ func TestQueryRequestError(t *testing.T) {
 	}
 	app.ExpectMetrics(t, []internal.WantMetric{
 		{Name: "WebTransaction"},
-		{Name: "WebTransaction/Go/POST /"},
+		{Name: "WebTransaction/Go/query/ProblemOperation"},
 		{Name: "WebTransactionTotalTime"},
-		{Name: "WebTransactionTotalTime/Go/POST /"},
+		{Name: "WebTransactionTotalTime/Go/query/ProblemOperation"},
 		{Name: "Apdex"},
-		{Name: "Apdex/Go/POST /"},
+		{Name: "Apdex/Go/query/ProblemOperation"},
 		{Name: "HttpDispatcher"},
+		{Name: "HttpDispatcher/StatusClass/2xx"},
 		{Name: "Custom/ProblemOperation"},
-		{Name: "Custom/ProblemOperation", Scope: "WebTransaction/Go/POST /"},
+		{Name: "Custom/ProblemOperation", Scope: "WebTransaction/Go/query/ProblemOperation"},
 		{Name: "Custom/problem"},
-		{Name: "Custom/problem", Scope: "WebTransaction/Go/POST /"},
+		{Name: "Custom/problem", Scope: "WebTransaction/Go/query/ProblemOperation"},
 		{Name: "Errors/all"},
 		{Name: "Errors/allWeb"},
-		{Name: "Errors/WebTransaction/Go/POST /"},
+		{Name: "Errors/WebTransaction/Go/query/ProblemOperation"},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Forced: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allWeb", Forced: nil},
 		{Name: "ErrorsByCaller/Unknown/Unknown/Unknown/Unknown/all"},
 		{Name: "ErrorsByCaller/Unknown/Unknown/Unknown/Unknown/allWeb"},
 	})
 	app.ExpectErrors(t, []internal.WantError{{
-		TxnName: "WebTransaction/Go/POST /",
+		TxnName: "WebTransaction/Go/query/ProblemOperation",
 		Msg:     "graphql: something went wrong",
 	}})
 }
@@ -279,19 +282,64 @@ func TestQueryRequestMultipleFields(t *testing.T) {
 	}
 	app.ExpectMetrics(t, []internal.WantMetric{
 		{Name: "WebTransaction"},
-		{Name: "WebTransaction/Go/POST /"},
+		{Name: "WebTransaction/Go/query/Multiple"},
 		{Name: "WebTransactionTotalTime"},
-		{Name: "WebTransactionTotalTime/Go/POST /"},
+		{Name: "WebTransactionTotalTime/Go/query/Multiple"},
 		{Name: "Apdex"},
-		{Name: "Apdex/Go/POST /"},
+		{Name: "Apdex/Go/query/Multiple"},
 		{Name: "HttpDispatcher"},
+		{Name: "HttpDispatcher/StatusClass/2xx"},
 		{Name: "Custom/Multiple"},
-		{Name: "Custom/Multiple", Scope: "WebTransaction/Go/POST /"},
+		{Name: "Custom/Multiple", Scope: "WebTransaction/Go/query/Multiple"},
 		{Name: "Custom/zip"},
-		{Name: "Custom/zip", Scope: "WebTransaction/Go/POST /"},
+		{Name: "Custom/zip", Scope: "WebTransaction/Go/query/Multiple"},
 		{Name: "Custom/zap"},
-		{Name: "Custom/zap", Scope: "WebTransaction/Go/POST /"},
+		{Name: "Custom/zap", Scope: "WebTransaction/Go/query/Multiple"},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Forced: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allWeb", Forced: nil},
 	})
 }
+
+func TestQueryRequestAttributes(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	opt := graphql.Tracer(NewTracer())
+	schema := graphql.MustParseSchema(querySchema, &query{}, opt)
+	handler := &relay.Handler{Schema: schema}
+	mux := http.NewServeMux()
+	mux.HandleFunc(newrelic.WrapHandleFunc(app.Application, "/", func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(WithPersistedQueryHash(r.Context(), "abc123"))
+		handler.ServeHTTP(w, r)
+	}))
+	body := `{
+			"query": "{ hello }"
+		}`
+	req, err := http.NewRequest("POST", "/", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rw := httptest.NewRecorder()
+	mux.ServeHTTP(rw, req)
+	if b := rw.Body.String(); b != `{"data":{"hello":"hello world"}}` {
+		t.Error(b)
+	}
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/query/<anonymous>",
+			"guid":             internal.MatchAnything,
+			"traceId":          internal.MatchAnything,
+			"priority":         internal.MatchAnything,
+			"sampled":          internal.MatchAnything,
+			"nr.apdexPerfZone": internal.MatchAnything,
+		},
+		AgentAttributes: map[string]interface{}{
+			newrelic.AttributeGraphQLOperationType:      "query",
+			newrelic.AttributeGraphQLOperationName:      "<anonymous>",
+			newrelic.AttributeGraphQLPersistedQueryHash: "abc123",
+			"request.method":                            "POST",
+			"request.uri":                               "/",
+			"http.statusCode":                           200,
+			"httpResponseCode":                          "200",
+			"response.headers.contentType":              "application/json",
+		},
+	}})
+}
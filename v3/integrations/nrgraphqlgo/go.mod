@@ -1,8 +1,10 @@
-module github.com/newrelic/go-agent/v3/integrations/nrgraphqlgo
+module github.com/rainforestpay/go-agent/v3/integrations/nrgraphqlgo
 
 go 1.13
 
 require (
 	github.com/graphql-go/graphql v0.7.9
-	github.com/newrelic/go-agent/v3 v3.17.0
+	github.com/rainforestpay/go-agent/v3 v3.20.0
 )
+
+replace github.com/rainforestpay/go-agent/v3 => ../..
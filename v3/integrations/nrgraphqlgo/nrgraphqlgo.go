@@ -9,16 +9,18 @@
 // (Parse, Validation, Execution, ResolveField) to your GraphQL
 // request transactions. Errors in any of these steps will
 // be noticed using NoticeError
-// (https://godoc.org/github.com/newrelic/go-agent/v3/newrelic#Transaction.NoticeError)
+// (https://godoc.org/github.com/rainforestpay/go-agent/v3/newrelic#Transaction.NoticeError)
+// Transactions are named after the GraphQL operation, eg. "query/GetUser", and
+// the operation's type and name are added as agent attributes.
 //
 // Please note that you must also instrument your web request handlers
 // and put the transaction into the context object in order to
 // utilize this instrumentation. For example, you could use
-// newrelic.WrapHandle (https://godoc.org/github.com/newrelic/go-agent/v3/newrelic#WrapHandle)
-// or newrelic.WrapHandleFunc (https://godoc.org/github.com/newrelic/go-agent/v3/newrelic#WrapHandleFunc)
+// newrelic.WrapHandle (https://godoc.org/github.com/rainforestpay/go-agent/v3/newrelic#WrapHandle)
+// or newrelic.WrapHandleFunc (https://godoc.org/github.com/rainforestpay/go-agent/v3/newrelic#WrapHandleFunc)
 // or you could use a New Relic integration for the web framework you are using
 // if it is available (for example,
-// https://godoc.org/github.com/newrelic/go-agent/v3/integrations/nrgorilla)
+// https://godoc.org/github.com/rainforestpay/go-agent/v3/integrations/nrgorilla)
 //
 // For a complete example, including instrumenting a graphql-go-handler, see:
 // https://github.com/newrelic/go-agent/tree/master/v3/integrations/nrgraphqlgo/example/main.go
@@ -29,8 +31,9 @@ import (
 
 	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/graphql/gqlerrors"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 func init() { internal.TrackUsage("integration", "framework", "graphql-go") }
@@ -41,11 +44,27 @@ type Extension struct{}
 
 var _ graphql.Extension = Extension{}
 
-// Init is used to help you initialize the extension - in this case, a noop
-func (Extension) Init(ctx context.Context, _ *graphql.Params) context.Context {
+// Init is called before the request is handled.  It names the transaction
+// after the GraphQL operation.
+func (Extension) Init(ctx context.Context, p *graphql.Params) context.Context {
+	if txn := newrelic.FromContext(ctx); nil != txn && nil != p {
+		integrationsupport.NameGraphQLTransaction(txn, integrationsupport.GraphQLOperation{
+			Type:               integrationsupport.GraphQLOperationType(p.RequestString, p.OperationName),
+			Name:               p.OperationName,
+			PersistedQueryHash: integrationsupport.GraphQLPersistedQueryHash(ctx),
+		})
+	}
 	return ctx
 }
 
+// WithPersistedQueryHash returns a copy of the context containing the hash of
+// the persisted query being executed.  Use it when resolving automatic
+// persisted queries to add the hash to the transaction as the
+// newrelic.AttributeGraphQLPersistedQueryHash attribute.
+func WithPersistedQueryHash(ctx context.Context, hash string) context.Context {
+	return integrationsupport.WithGraphQLPersistedQueryHash(ctx, hash)
+}
+
 // Name returns the name of the extension
 func (Extension) Name() string {
 	return "New Relic Extension"
@@ -10,9 +10,9 @@ import (
 	"testing"
 
 	"github.com/graphql-go/graphql"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 var schema = func() graphql.Schema {
@@ -84,17 +84,17 @@ func TestExtensionWithTransaction(t *testing.T) {
 	txn.End()
 	app.ExpectMetrics(t, []internal.WantMetric{
 		{Name: "Custom/Execution", Scope: "", Forced: false, Data: nil},
-		{Name: "Custom/Execution", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
+		{Name: "Custom/Execution", Scope: "OtherTransaction/Go/query/<anonymous>", Forced: false, Data: nil},
 		{Name: "Custom/Parse", Scope: "", Forced: false, Data: nil},
-		{Name: "Custom/Parse", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
+		{Name: "Custom/Parse", Scope: "OtherTransaction/Go/query/<anonymous>", Forced: false, Data: nil},
 		{Name: "Custom/ResolveField:hello", Scope: "", Forced: false, Data: nil},
-		{Name: "Custom/ResolveField:hello", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
+		{Name: "Custom/ResolveField:hello", Scope: "OtherTransaction/Go/query/<anonymous>", Forced: false, Data: nil},
 		{Name: "Custom/Validation", Scope: "", Forced: false, Data: nil},
-		{Name: "Custom/Validation", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
-		{Name: "OtherTransaction/Go/query", Scope: "", Forced: true, Data: nil},
+		{Name: "Custom/Validation", Scope: "OtherTransaction/Go/query/<anonymous>", Forced: false, Data: nil},
+		{Name: "OtherTransaction/Go/query/<anonymous>", Scope: "", Forced: true, Data: nil},
 		{Name: "OtherTransaction/all", Scope: "", Forced: true, Data: nil},
 		{Name: "OtherTransactionTotalTime", Scope: "", Forced: true, Data: nil},
-		{Name: "OtherTransactionTotalTime/Go/query", Scope: "", Forced: false, Data: nil},
+		{Name: "OtherTransactionTotalTime/Go/query/<anonymous>", Scope: "", Forced: false, Data: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Scope: "", Forced: false, Data: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allOther", Scope: "", Forced: false, Data: nil},
 	})
@@ -125,22 +125,22 @@ func TestExtensionResolveError(t *testing.T) {
 	txn.End()
 	app.ExpectMetrics(t, []internal.WantMetric{
 		{Name: "Custom/Execution", Scope: "", Forced: false, Data: nil},
-		{Name: "Custom/Execution", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
+		{Name: "Custom/Execution", Scope: "OtherTransaction/Go/query/<anonymous>", Forced: false, Data: nil},
 		{Name: "Custom/Parse", Scope: "", Forced: false, Data: nil},
-		{Name: "Custom/Parse", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
+		{Name: "Custom/Parse", Scope: "OtherTransaction/Go/query/<anonymous>", Forced: false, Data: nil},
 		{Name: "Custom/ResolveField:hello", Scope: "", Forced: false, Data: nil},
-		{Name: "Custom/ResolveField:hello", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
+		{Name: "Custom/ResolveField:hello", Scope: "OtherTransaction/Go/query/<anonymous>", Forced: false, Data: nil},
 		{Name: "Custom/ResolveField:errors", Scope: "", Forced: false, Data: nil},
-		{Name: "Custom/ResolveField:errors", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
+		{Name: "Custom/ResolveField:errors", Scope: "OtherTransaction/Go/query/<anonymous>", Forced: false, Data: nil},
 		{Name: "Custom/Validation", Scope: "", Forced: false, Data: nil},
-		{Name: "Custom/Validation", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
-		{Name: "Errors/OtherTransaction/Go/query", Scope: "", Forced: true, Data: nil},
+		{Name: "Custom/Validation", Scope: "OtherTransaction/Go/query/<anonymous>", Forced: false, Data: nil},
+		{Name: "Errors/OtherTransaction/Go/query/<anonymous>", Scope: "", Forced: true, Data: nil},
 		{Name: "Errors/all", Scope: "", Forced: true, Data: nil},
 		{Name: "Errors/allOther", Scope: "", Forced: true, Data: nil},
-		{Name: "OtherTransaction/Go/query", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransaction/Go/query/<anonymous>", Scope: "", Forced: true, Data: nil},
 		{Name: "OtherTransaction/all", Scope: "", Forced: true, Data: nil},
 		{Name: "OtherTransactionTotalTime", Scope: "", Forced: true, Data: nil},
-		{Name: "OtherTransactionTotalTime/Go/query", Scope: "", Forced: false, Data: nil},
+		{Name: "OtherTransactionTotalTime/Go/query/<anonymous>", Scope: "", Forced: false, Data: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Scope: "", Forced: false, Data: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allOther", Scope: "", Forced: false, Data: nil},
 		{Name: "ErrorsByCaller/Unknown/Unknown/Unknown/Unknown/all", Scope: "", Forced: false, Data: nil},
@@ -150,7 +150,7 @@ func TestExtensionResolveError(t *testing.T) {
 		Intrinsics: map[string]interface{}{
 			"error.message":   "ooooooops",
 			"error.class":     internal.MatchAnything,
-			"transactionName": "OtherTransaction/Go/query",
+			"transactionName": "OtherTransaction/Go/query/<anonymous>",
 			"sampled":         false,
 			// Note: "*" is a wildcard value
 			"guid":     "*",
@@ -179,14 +179,14 @@ func TestExtensionParseError(t *testing.T) {
 	txn.End()
 	app.ExpectMetrics(t, []internal.WantMetric{
 		{Name: "Custom/Parse", Scope: "", Forced: false, Data: nil},
-		{Name: "Custom/Parse", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
-		{Name: "Errors/OtherTransaction/Go/query", Scope: "", Forced: true, Data: nil},
+		{Name: "Custom/Parse", Scope: "OtherTransaction/Go/query/<anonymous>", Forced: false, Data: nil},
+		{Name: "Errors/OtherTransaction/Go/query/<anonymous>", Scope: "", Forced: true, Data: nil},
 		{Name: "Errors/all", Scope: "", Forced: true, Data: nil},
 		{Name: "Errors/allOther", Scope: "", Forced: true, Data: nil},
-		{Name: "OtherTransaction/Go/query", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransaction/Go/query/<anonymous>", Scope: "", Forced: true, Data: nil},
 		{Name: "OtherTransaction/all", Scope: "", Forced: true, Data: nil},
 		{Name: "OtherTransactionTotalTime", Scope: "", Forced: true, Data: nil},
-		{Name: "OtherTransactionTotalTime/Go/query", Scope: "", Forced: false, Data: nil},
+		{Name: "OtherTransactionTotalTime/Go/query/<anonymous>", Scope: "", Forced: false, Data: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Scope: "", Forced: false, Data: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allOther", Scope: "", Forced: false, Data: nil},
 		{Name: "ErrorsByCaller/Unknown/Unknown/Unknown/Unknown/all", Scope: "", Forced: false, Data: nil},
@@ -196,7 +196,7 @@ func TestExtensionParseError(t *testing.T) {
 		Intrinsics: map[string]interface{}{
 			"error.message":   internal.MatchAnything,
 			"error.class":     internal.MatchAnything,
-			"transactionName": "OtherTransaction/Go/query",
+			"transactionName": "OtherTransaction/Go/query/<anonymous>",
 			"sampled":         false,
 			"guid":            "*",
 			"traceId":         "*",
@@ -224,16 +224,16 @@ func TestExtensionValidationError(t *testing.T) {
 	txn.End()
 	app.ExpectMetrics(t, []internal.WantMetric{
 		{Name: "Custom/Parse", Scope: "", Forced: false, Data: nil},
-		{Name: "Custom/Parse", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
+		{Name: "Custom/Parse", Scope: "OtherTransaction/Go/query/<anonymous>", Forced: false, Data: nil},
 		{Name: "Custom/Validation", Scope: "", Forced: false, Data: nil},
-		{Name: "Custom/Validation", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
-		{Name: "Errors/OtherTransaction/Go/query", Scope: "", Forced: true, Data: nil},
+		{Name: "Custom/Validation", Scope: "OtherTransaction/Go/query/<anonymous>", Forced: false, Data: nil},
+		{Name: "Errors/OtherTransaction/Go/query/<anonymous>", Scope: "", Forced: true, Data: nil},
 		{Name: "Errors/all", Scope: "", Forced: true, Data: nil},
 		{Name: "Errors/allOther", Scope: "", Forced: true, Data: nil},
-		{Name: "OtherTransaction/Go/query", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransaction/Go/query/<anonymous>", Scope: "", Forced: true, Data: nil},
 		{Name: "OtherTransaction/all", Scope: "", Forced: true, Data: nil},
 		{Name: "OtherTransactionTotalTime", Scope: "", Forced: true, Data: nil},
-		{Name: "OtherTransactionTotalTime/Go/query", Scope: "", Forced: false, Data: nil},
+		{Name: "OtherTransactionTotalTime/Go/query/<anonymous>", Scope: "", Forced: false, Data: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Scope: "", Forced: false, Data: nil},
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allOther", Scope: "", Forced: false, Data: nil},
 		{Name: "ErrorsByCaller/Unknown/Unknown/Unknown/Unknown/all", Scope: "", Forced: false, Data: nil},
@@ -243,7 +243,7 @@ func TestExtensionValidationError(t *testing.T) {
 		Intrinsics: map[string]interface{}{
 			"error.message":   internal.MatchAnything,
 			"error.class":     internal.MatchAnything,
-			"transactionName": "OtherTransaction/Go/query",
+			"transactionName": "OtherTransaction/Go/query/<anonymous>",
 			"sampled":         false,
 			"guid":            "*",
 			"traceId":         "*",
@@ -251,3 +251,37 @@ func TestExtensionValidationError(t *testing.T) {
 		},
 	}})
 }
+
+func TestExtensionNamesTransaction(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	txn := app.StartTransaction("query")
+	ctx := newrelic.NewContext(context.Background(), txn)
+	ctx = WithPersistedQueryHash(ctx, "abc123")
+
+	params := graphql.Params{
+		Schema:        schema,
+		RequestString: `query GetHello { hello }`,
+		OperationName: "GetHello",
+		Context:       ctx,
+	}
+	resp := graphql.Do(params)
+	for _, err := range resp.Errors {
+		t.Error("failure to Do:", err)
+	}
+
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":     "OtherTransaction/Go/query/GetHello",
+			"guid":     internal.MatchAnything,
+			"traceId":  internal.MatchAnything,
+			"priority": internal.MatchAnything,
+			"sampled":  internal.MatchAnything,
+		},
+		AgentAttributes: map[string]interface{}{
+			newrelic.AttributeGraphQLOperationType:      "query",
+			newrelic.AttributeGraphQLOperationName:      "GetHello",
+			newrelic.AttributeGraphQLPersistedQueryHash: "abc123",
+		},
+	}})
+}
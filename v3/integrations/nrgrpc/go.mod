@@ -1,4 +1,4 @@
-module github.com/newrelic/go-agent/v3/integrations/nrgrpc
+module github.com/rainforestpay/go-agent/v3/integrations/nrgrpc
 
 // As of Dec 2019, the grpc go.mod file uses 1.11:
 // https://github.com/grpc/grpc-go/blob/master/go.mod
@@ -8,7 +8,7 @@ require (
 	// protobuf v1.3.0 is the earliest version using modules, we use v1.3.1
 	// because all dependencies were removed in this version.
 	github.com/golang/protobuf v1.5.2
-	github.com/newrelic/go-agent/v3 v3.18.2
+	github.com/rainforestpay/go-agent/v3 v3.20.0
 	// v1.15.0 is the earliest version of grpc using modules.
 	google.golang.org/grpc v1.49.0
 )
@@ -20,3 +20,5 @@ require (
 	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013 // indirect
 	google.golang.org/protobuf v1.27.1 // indirect
 )
+
+replace github.com/rainforestpay/go-agent/v3 => ../..
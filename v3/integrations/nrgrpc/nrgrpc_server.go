@@ -38,14 +38,14 @@ import (
 	"net/http"
 	"strings"
 
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
-func startTransaction(ctx context.Context, app *newrelic.Application, fullMethod string) *newrelic.Transaction {
+func startTransaction(ctx context.Context, app *newrelic.Application, fullMethod string, grouping newrelic.RPCNameGrouping) *newrelic.Transaction {
 	method := strings.TrimPrefix(fullMethod, "/")
 
 	var hdrs http.Header
@@ -67,7 +67,7 @@ func startTransaction(ctx context.Context, app *newrelic.Application, fullMethod
 		Method:    method,
 		Transport: newrelic.TransportHTTP,
 	}
-	txn := app.StartTransaction(method)
+	txn := app.StartTransaction(newrelic.RPCTransactionName(fullMethod, grouping))
 	txn.SetWebRequest(webReq)
 
 	return txn
@@ -111,11 +111,39 @@ var interceptorStatusHandlerRegistry = statusHandlerMap{
 	codes.Unauthenticated:    InfoInterceptorStatusHandler,
 }
 
+//
+// interceptorConfig holds the settings used by an interceptor.
+//
+type interceptorConfig struct {
+	handlers     statusHandlerMap
+	nameGrouping newrelic.RPCNameGrouping
+}
+
+//
+// interceptorDefaultNameGrouping is the current default transaction name
+// grouping used by each interceptor.
+//
+var interceptorDefaultNameGrouping = newrelic.RPCGroupByMethod
+
+func newInterceptorConfig(options []HandlerOption) *interceptorConfig {
+	c := &interceptorConfig{
+		handlers:     make(statusHandlerMap),
+		nameGrouping: interceptorDefaultNameGrouping,
+	}
+	for code, handler := range interceptorStatusHandlerRegistry {
+		c.handlers[code] = handler
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
 //
 // HandlerOption is the type for options passed to the interceptor
-// functions to specify gRPC status handlers.
+// functions to specify gRPC status handlers and transaction naming.
 //
-type HandlerOption func(statusHandlerMap)
+type HandlerOption func(*interceptorConfig)
 
 //
 // WithStatusHandler indicates a handler function to be used to
@@ -149,22 +177,42 @@ type HandlerOption func(statusHandlerMap)
 // to your Configure, StreamServiceInterceptor, or UnaryServiceInterceptor function.
 //
 func WithStatusHandler(c codes.Code, h ErrorHandler) HandlerOption {
-	return func(m statusHandlerMap) {
-		m[c] = h
+	return func(cfg *interceptorConfig) {
+		cfg.handlers[c] = h
 	}
 }
 
 //
-// Configure takes a list of WithStatusHandler options and sets them
-// as the new default handlers for the specified gRPC status codes, in the same
-// way as if WithStatusHandler were given to the StreamServiceInterceptor
-// or UnaryServiceInterceptor functions (q.v.); however, in this case the new handlers
-// become the default for any subsequent interceptors created by the above functions.
+// WithTransactionNameGrouping sets how calls are grouped into transaction
+// names.  By default, transactions are named after the service and method,
+// eg. "pkg.Service/Method".  Use newrelic.RPCGroupByService to name
+// transactions after the service only, eg. "pkg.Service", which limits the
+// number of transaction names created by services with many methods.  This
+// may be given to the Configure, StreamServiceInterceptor, or
+// UnaryServiceInterceptor functions.
+//
+func WithTransactionNameGrouping(grouping newrelic.RPCNameGrouping) HandlerOption {
+	return func(cfg *interceptorConfig) {
+		cfg.nameGrouping = grouping
+	}
+}
+
+//
+// Configure takes a list of WithStatusHandler and WithTransactionNameGrouping
+// options and sets them as the new defaults, in the same way as if they were
+// given to the StreamServiceInterceptor or UnaryServiceInterceptor functions
+// (q.v.); however, in this case the new settings become the default for any
+// subsequent interceptors created by the above functions.
 //
 func Configure(options ...HandlerOption) {
+	cfg := &interceptorConfig{
+		handlers:     interceptorStatusHandlerRegistry,
+		nameGrouping: interceptorDefaultNameGrouping,
+	}
 	for _, option := range options {
-		option(interceptorStatusHandlerRegistry)
+		option(cfg)
 	}
+	interceptorDefaultNameGrouping = cfg.nameGrouping
 }
 
 //
@@ -298,21 +346,15 @@ func UnaryServerInterceptor(app *newrelic.Application, options ...HandlerOption)
 		}
 	}
 
-	localHandlerMap := make(statusHandlerMap)
-	for code, handler := range interceptorStatusHandlerRegistry {
-		localHandlerMap[code] = handler
-	}
-	for _, option := range options {
-		option(localHandlerMap)
-	}
+	cfg := newInterceptorConfig(options)
 
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-		txn := startTransaction(ctx, app, info.FullMethod)
+		txn := startTransaction(ctx, app, info.FullMethod, cfg.nameGrouping)
 		defer txn.End()
 
 		ctx = newrelic.NewContext(ctx, txn)
 		resp, err = handler(ctx, req)
-		reportInterceptorStatus(ctx, txn, localHandlerMap, err)
+		reportInterceptorStatus(ctx, txn, cfg.handlers, err)
 		return
 	}
 }
@@ -351,20 +393,14 @@ func StreamServerInterceptor(app *newrelic.Application, options ...HandlerOption
 		}
 	}
 
-	localHandlerMap := make(statusHandlerMap)
-	for code, handler := range interceptorStatusHandlerRegistry {
-		localHandlerMap[code] = handler
-	}
-	for _, option := range options {
-		option(localHandlerMap)
-	}
+	cfg := newInterceptorConfig(options)
 
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		txn := startTransaction(ss.Context(), app, info.FullMethod)
+		txn := startTransaction(ss.Context(), app, info.FullMethod, cfg.nameGrouping)
 		defer txn.End()
 
 		err := handler(srv, newWrappedServerStream(ss, txn))
-		reportInterceptorStatus(ss.Context(), txn, localHandlerMap, err)
+		reportInterceptorStatus(ss.Context(), txn, cfg.handlers, err)
 		return err
 	}
 }
@@ -15,19 +15,19 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/test/bufconn"
 
-	"github.com/newrelic/go-agent/v3/integrations/nrgrpc/testapp"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/integrations/nrgrpc/testapp"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 // newTestServerAndConn creates a new *grpc.Server and *grpc.ClientConn for use
 // in testing. It adds instrumentation to both. If app is nil, then
 // instrumentation is not applied to the server. Be sure to Stop() the server
 // and Close() the connection when done with them.
-func newTestServerAndConn(t *testing.T, app *newrelic.Application) (*grpc.Server, *grpc.ClientConn) {
+func newTestServerAndConn(t *testing.T, app *newrelic.Application, options ...HandlerOption) (*grpc.Server, *grpc.ClientConn) {
 	s := grpc.NewServer(
-		grpc.UnaryInterceptor(UnaryServerInterceptor(app)),
-		grpc.StreamInterceptor(StreamServerInterceptor(app)),
+		grpc.UnaryInterceptor(UnaryServerInterceptor(app, options...)),
+		grpc.StreamInterceptor(StreamServerInterceptor(app, options...)),
 	)
 	testapp.RegisterTestApplicationServer(s, &testapp.Server{})
 	lis := bufconn.Listen(1024 * 1024)
@@ -145,6 +145,38 @@ func TestUnaryServerInterceptor(t *testing.T) {
 	})
 }
 
+func TestUnaryServerInterceptorNameGrouping(t *testing.T) {
+	app := testApp()
+
+	s, conn := newTestServerAndConn(t, app.Application, WithTransactionNameGrouping(newrelic.RPCGroupByService))
+	defer s.Stop()
+	defer conn.Close()
+
+	client := testapp.NewTestApplicationClient(conn)
+	if _, err := client.DoUnaryUnary(context.Background(), &testapp.Message{}); err != nil {
+		t.Fatal("unable to call client DoUnaryUnary", err)
+	}
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"guid":             internal.MatchAnything,
+			"name":             "WebTransaction/Go/TestApplication",
+			"nr.apdexPerfZone": internal.MatchAnything,
+			"priority":         internal.MatchAnything,
+			"sampled":          internal.MatchAnything,
+			"traceId":          internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{
+			"httpResponseCode":            0,
+			"http.statusCode":             0,
+			"request.headers.contentType": "application/grpc",
+			"request.method":              "TestApplication/DoUnaryUnary",
+			"request.uri":                 "grpc://bufnet/TestApplication/DoUnaryUnary",
+		},
+	}})
+}
+
 func TestUnaryServerInterceptorError(t *testing.T) {
 	app := testApp()
 
@@ -0,0 +1,162 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrmongo
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// maxObfuscatedCommandLength limits the length of the obfuscated command
+// recorded as the segment's query.
+const maxObfuscatedCommandLength = 2000
+
+// commandOperation returns the operation of the command and, for commands which
+// contain several sub-operations, the operation of each.  aggregate commands
+// contain their pipeline stages, eg. "$match", and insert, update, delete, and
+// bulkWrite commands of more than one document contain each write.
+// findAndModify is named after the collection method used, eg.
+// "findOneAndUpdate".
+func commandOperation(name string, command bson.Raw) (string, []string) {
+	switch name {
+	case "aggregate":
+		var stages []string
+		for _, stage := range arrayDocuments(command.Lookup("pipeline")) {
+			if elems, err := stage.Elements(); nil == err && len(elems) > 0 {
+				stages = append(stages, elems[0].Key())
+			}
+		}
+		return name, stages
+	case "findAndModify":
+		if remove, ok := command.Lookup("remove").BooleanOK(); ok && remove {
+			return "findOneAndDelete", nil
+		}
+		if update, ok := command.Lookup("update").DocumentOK(); ok && !isUpdateDocument(update) {
+			return "findOneAndReplace", nil
+		}
+		return "findOneAndUpdate", nil
+	case "insert", "update", "delete":
+		field := map[string]string{"insert": "documents", "update": "updates", "delete": "deletes"}[name]
+		if n := len(arrayDocuments(command.Lookup(field))); n > 1 {
+			ops := make([]string, n)
+			for i := range ops {
+				ops[i] = name
+			}
+			return name, ops
+		}
+		return name, nil
+	case "bulkWrite":
+		var ops []string
+		for _, op := range arrayDocuments(command.Lookup("ops")) {
+			if elems, err := op.Elements(); nil == err && len(elems) > 0 {
+				ops = append(ops, elems[0].Key())
+			}
+		}
+		return name, ops
+	}
+	return name, nil
+}
+
+// isUpdateDocument returns true if the document contains update operators,
+// and false if it is a replacement document.
+func isUpdateDocument(d bson.Raw) bool {
+	elems, err := d.Elements()
+	return nil == err && len(elems) > 0 && strings.HasPrefix(elems[0].Key(), "$")
+}
+
+func arrayDocuments(v bson.RawValue) []bson.Raw {
+	arr, ok := v.ArrayOK()
+	if !ok {
+		return nil
+	}
+	values, err := arr.Values()
+	if nil != err {
+		return nil
+	}
+	docs := make([]bson.Raw, 0, len(values))
+	for _, v := range values {
+		if d, ok := v.DocumentOK(); ok {
+			docs = append(docs, d)
+		}
+	}
+	return docs
+}
+
+// obfuscateCommand returns the command document as JSON with every value
+// replaced by "?", other than the value of the first element, which is the
+// collection name.  Like SQL queries, it is recorded as the segment's query
+// unless the collection of queries is disabled by security policies.  Fields
+// added by the driver, such as "lsid" and "$db", are omitted.
+func obfuscateCommand(command bson.Raw) string {
+	elems, err := command.Elements()
+	if nil != err || 0 == len(elems) {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	written := 0
+	for i, e := range elems {
+		key := e.Key()
+		if strings.HasPrefix(key, "$") || "lsid" == key || "txnNumber" == key {
+			continue
+		}
+		if written > 0 {
+			b.WriteByte(',')
+		}
+		written++
+		b.WriteString(strconv.Quote(key))
+		b.WriteByte(':')
+		if v := e.Value(); 0 == i && bsontype.String == v.Type {
+			b.WriteString(strconv.Quote(v.StringValue()))
+		} else {
+			obfuscateValue(&b, v)
+		}
+	}
+	b.WriteByte('}')
+	s := b.String()
+	if len(s) > maxObfuscatedCommandLength {
+		// Truncate on a rune boundary so that the query is valid UTF-8.
+		end := maxObfuscatedCommandLength
+		for end > 0 && !utf8.RuneStart(s[end]) {
+			end--
+		}
+		s = s[:end] + "..."
+	}
+	return s
+}
+
+func obfuscateValue(b *strings.Builder, v bson.RawValue) {
+	switch v.Type {
+	case bsontype.EmbeddedDocument:
+		b.WriteByte('{')
+		if elems, err := v.Document().Elements(); nil == err {
+			for i, e := range elems {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				b.WriteString(strconv.Quote(e.Key()))
+				b.WriteByte(':')
+				obfuscateValue(b, e.Value())
+			}
+		}
+		b.WriteByte('}')
+	case bsontype.Array:
+		b.WriteByte('[')
+		if values, err := v.Array().Values(); nil == err {
+			for i, e := range values {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				obfuscateValue(b, e)
+			}
+		}
+		b.WriteByte(']')
+	default:
+		b.WriteString(`"?"`)
+	}
+}
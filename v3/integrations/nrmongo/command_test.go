@@ -0,0 +1,113 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrmongo
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func mustMarshal(t *testing.T, d bson.D) bson.Raw {
+	raw, err := bson.Marshal(d)
+	if nil != err {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestCommandOperation(t *testing.T) {
+	testcases := []struct {
+		name    string
+		command bson.D
+		op      string
+		subOps  string
+	}{
+		{
+			name: "aggregate",
+			command: bson.D{{Key: "aggregate", Value: "coll"}, {Key: "pipeline", Value: bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "x", Value: 1}}}},
+				bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$x"}}}},
+			}}},
+			op:     "aggregate",
+			subOps: "$match,$group",
+		},
+		{
+			name:    "findAndModify",
+			command: bson.D{{Key: "findAndModify", Value: "coll"}, {Key: "remove", Value: true}},
+			op:      "findOneAndDelete",
+		},
+		{
+			name:    "findAndModify",
+			command: bson.D{{Key: "findAndModify", Value: "coll"}, {Key: "update", Value: bson.D{{Key: "$set", Value: bson.D{{Key: "x", Value: 1}}}}}},
+			op:      "findOneAndUpdate",
+		},
+		{
+			name:    "findAndModify",
+			command: bson.D{{Key: "findAndModify", Value: "coll"}, {Key: "update", Value: bson.D{{Key: "x", Value: 1}}}},
+			op:      "findOneAndReplace",
+		},
+		{
+			name:    "insert",
+			command: bson.D{{Key: "insert", Value: "coll"}, {Key: "documents", Value: bson.A{bson.D{{Key: "x", Value: 1}}}}},
+			op:      "insert",
+		},
+		{
+			name: "delete",
+			command: bson.D{{Key: "delete", Value: "coll"}, {Key: "deletes", Value: bson.A{
+				bson.D{{Key: "q", Value: bson.D{}}}, bson.D{{Key: "q", Value: bson.D{}}},
+			}}},
+			op:     "delete",
+			subOps: "delete,delete",
+		},
+		{
+			name: "bulkWrite",
+			command: bson.D{{Key: "bulkWrite", Value: 1}, {Key: "ops", Value: bson.A{
+				bson.D{{Key: "insert", Value: 0}}, bson.D{{Key: "update", Value: 0}},
+			}}},
+			op:     "bulkWrite",
+			subOps: "insert,update",
+		},
+		{
+			name:    "find",
+			command: bson.D{{Key: "find", Value: "coll"}},
+			op:      "find",
+		},
+	}
+	for _, tc := range testcases {
+		op, subOps := commandOperation(tc.name, mustMarshal(t, tc.command))
+		if op != tc.op || strings.Join(subOps, ",") != tc.subOps {
+			t.Errorf("%s: got %s %v", tc.name, op, subOps)
+		}
+	}
+}
+
+func TestObfuscateCommand(t *testing.T) {
+	command := mustMarshal(t, bson.D{
+		{Key: "find", Value: "coll"},
+		{Key: "filter", Value: bson.D{{Key: "name", Value: "pi"}, {Key: "value", Value: bson.D{{Key: "$gt", Value: 3}}}}},
+		{Key: "projection", Value: bson.A{"a", 1}},
+		{Key: "lsid", Value: bson.D{{Key: "id", Value: "session"}}},
+		{Key: "$db", Value: "testing"},
+	})
+	want := `{"find":"coll","filter":{"name":"?","value":{"$gt":"?"}},"projection":["?","?"]}`
+	if s := obfuscateCommand(command); s != want {
+		t.Error(s)
+	}
+	if s := obfuscateCommand(nil); s != "" {
+		t.Error(s)
+	}
+}
+
+func TestObfuscateCommandTruncatesOnRuneBoundary(t *testing.T) {
+	command := mustMarshal(t, bson.D{
+		{Key: "find", Value: strings.Repeat("é", maxObfuscatedCommandLength)},
+	})
+	s := obfuscateCommand(command)
+	if !utf8.ValidString(s) || !strings.HasSuffix(s, "...") || len(s) > maxObfuscatedCommandLength+len("...") {
+		t.Error(len(s), s[len(s)-10:])
+	}
+}
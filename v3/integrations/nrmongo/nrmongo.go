@@ -35,8 +35,8 @@ import (
 	"regexp"
 	"sync"
 
-	"github.com/newrelic/go-agent/v3/internal"
-	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
 	"go.mongodb.org/mongo-driver/event"
 )
 
@@ -59,7 +59,9 @@ var connIDPattern = regexp.MustCompile(`([^:\[]+)(?::(\d+))?\[-\d+]`)
 // provided, the original `*event.CommandMonitor` will be called as well.  The
 // returned `*event.CommandMonitor` creates `newrelic.DatastoreSegment`s
 // (https://godoc.org/github.com/newrelic/go-agent#DatastoreSegment) for each
-// database call.
+// database call.  The command document is recorded as the segment's query with
+// all values obfuscated.  The stages of aggregate commands and the writes of
+// bulk writes are counted as the segment's PipelineOperations.
 //
 //	// Use `SetMonitor` to register the CommandMonitor.
 //	client, err := mongo.Connect(ctx, options.Client().ApplyURI("mongodb://localhost:27017").SetMonitor(nrmongo.NewCommandMonitor(nil)))
@@ -96,14 +98,17 @@ func (m *mongoMonitor) started(ctx context.Context, e *event.CommandStartedEvent
 		return
 	}
 	host, port := calcHostAndPort(e.ConnectionID)
+	operation, subOperations := commandOperation(e.CommandName, e.Command)
 	sgmt := newrelic.DatastoreSegment{
-		StartTime:    txn.StartSegmentNow(),
-		Product:      newrelic.DatastoreMongoDB,
-		Collection:   collName(e),
-		Operation:    e.CommandName,
-		Host:         host,
-		PortPathOrID: port,
-		DatabaseName: e.DatabaseName,
+		StartTime:          txn.StartSegmentNow(),
+		Product:            newrelic.DatastoreMongoDB,
+		Collection:         collName(e),
+		Operation:          operation,
+		PipelineOperations: subOperations,
+		ParameterizedQuery: obfuscateCommand(e.Command),
+		Host:               host,
+		PortPathOrID:       port,
+		DatabaseName:       e.DatabaseName,
 	}
 	m.addSgmt(e, &sgmt)
 }
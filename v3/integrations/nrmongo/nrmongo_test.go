@@ -7,10 +7,10 @@ import (
 	"context"
 	"testing"
 
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
-	"github.com/newrelic/go-agent/v3/internal/sysinfo"
-	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/internal/sysinfo"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/event"
@@ -162,7 +162,7 @@ func TestMonitor(t *testing.T) {
 			AgentAttributes: map[string]interface{}{
 				"peer.address":  thisHost + ":27017",
 				"peer.hostname": thisHost,
-				"db.statement":  "'commName' on 'collName' using 'MongoDB'",
+				"db.statement":  `{"commName":"collName"}`,
 				"db.instance":   "testdb",
 				"db.collection": "collName",
 			},
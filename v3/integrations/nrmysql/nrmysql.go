@@ -23,7 +23,7 @@
 // Then change the side-effect import to this package, and open "nrmysql" instead:
 //
 //	import (
-//		_ "github.com/newrelic/go-agent/v3/integrations/nrmysql"
+//		_ "github.com/rainforestpay/go-agent/v3/integrations/nrmysql"
 //	)
 //
 //	func main() {
@@ -49,12 +49,11 @@ package nrmysql
 
 import (
 	"database/sql"
-	"net"
 
 	"github.com/go-sql-driver/mysql"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/newrelic"
-	"github.com/newrelic/go-agent/v3/newrelic/sqlparse"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/newrelic/sqlparse"
 )
 
 var (
@@ -83,23 +82,10 @@ func parseDSN(s *newrelic.DatastoreSegment, dsn string) {
 func parseConfig(s *newrelic.DatastoreSegment, cfg *mysql.Config) {
 	s.DatabaseName = cfg.DBName
 
-	var host, ppoid string
-	switch cfg.Net {
-	case "unix", "unixgram", "unixpacket":
-		host = "localhost"
-		ppoid = cfg.Addr
-	case "cloudsql":
-		host = cfg.Addr
-	default:
-		var err error
-		host, ppoid, err = net.SplitHostPort(cfg.Addr)
-		if nil != err {
-			host = cfg.Addr
-		} else if host == "" {
-			host = "localhost"
-		}
+	if cfg.Net == "cloudsql" {
+		s.Host = cfg.Addr
+		s.PortPathOrID = ""
+		return
 	}
-
-	s.Host = host
-	s.PortPathOrID = ppoid
+	s.SetInstance(cfg.Net, cfg.Addr)
 }
@@ -7,7 +7,7 @@ import (
 	"testing"
 
 	"github.com/go-sql-driver/mysql"
-	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 func TestParseDSN(t *testing.T) {
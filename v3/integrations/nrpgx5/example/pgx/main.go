@@ -8,8 +8,8 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/newrelic/go-agent/v3/integrations/nrpgx5"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/integrations/nrpgx5"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 func main() {
@@ -11,8 +11,8 @@
 // ```go
 // import (
 // 	"github.com/jackc/pgx/v5"
-// 	"github.com/newrelic/go-agent/v3/integrations/nrpgx5"
-// 	"github.com/newrelic/go-agent/v3/newrelic"
+// 	"github.com/rainforestpay/go-agent/v3/integrations/nrpgx5"
+// 	"github.com/rainforestpay/go-agent/v3/newrelic"
 // )
 //
 // func main() {
@@ -32,8 +32,8 @@
 // ```go
 // import (
 // 	"github.com/jackc/pgx/v5/pgxpool"
-// 	"github.com/newrelic/go-agent/v3/integrations/nrpgx5"
-// 	"github.com/newrelic/go-agent/v3/newrelic"
+// 	"github.com/rainforestpay/go-agent/v3/integrations/nrpgx5"
+// 	"github.com/rainforestpay/go-agent/v3/newrelic"
 // )
 //
 // func main() {
@@ -55,12 +55,13 @@ package nrpgx5
 import (
 	"context"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/newrelic/go-agent/v3/newrelic"
-	"github.com/newrelic/go-agent/v3/newrelic/sqlparse"
 	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/newrelic/sqlparse"
 )
 
 func init() {
@@ -80,11 +81,28 @@ type (
 )
 
 const (
-	querySegmentKey   nrPgxSegmentType = "nrPgx5Segment"
-	prepareSegmentKey nrPgxSegmentType = "prepareNrPgx5Segment"
-	batchSegmentKey   nrPgxSegmentType = "batchNrPgx5Segment"
+	querySegmentKey    nrPgxSegmentType = "nrPgx5Segment"
+	prepareSegmentKey  nrPgxSegmentType = "prepareNrPgx5Segment"
+	batchSegmentKey    nrPgxSegmentType = "batchNrPgx5Segment"
+	copyFromSegmentKey nrPgxSegmentType = "copyFromNrPgx5Segment"
 )
 
+const (
+	// rowCountAttribute is the segment attribute recording the number of
+	// rows affected by a batch or copied by CopyFrom.
+	rowCountAttribute = "db.rowCount"
+	// batchSizeAttribute is the segment attribute recording the number of
+	// queries in a batch.
+	batchSizeAttribute = "db.batchSize"
+)
+
+// batchSegment is the segment of a batch, along with the number of rows
+// affected by its queries.
+type batchSegment struct {
+	segment  newrelic.DatastoreSegment
+	rowCount int64
+}
+
 func NewTracer() *Tracer {
 	return &Tracer{
 		ParseQuery:          sqlparse.ParseQuery,
@@ -156,36 +174,84 @@ func (t *Tracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.T
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 
-	segment := t.BaseSegment
-	segment.StartTime = newrelic.FromContext(ctx).StartSegmentNow()
-	segment.Operation = "batch"
-	segment.Collection = ""
+	b := &batchSegment{segment: t.BaseSegment}
+	b.segment.StartTime = newrelic.FromContext(ctx).StartSegmentNow()
+	b.segment.Operation = "batch"
+	b.segment.Collection = ""
+	if nil != data.Batch {
+		b.segment.AddAttribute(batchSizeAttribute, data.Batch.Len())
+	}
 
-	return context.WithValue(ctx, batchSegmentKey, &segment)
+	return context.WithValue(ctx, batchSegmentKey, b)
 }
 
-// TraceBatchQuery implement pgx.BatchTracer. In this method we will get query and store it in segment.
+// TraceBatchQuery implement pgx.BatchTracer. In this method we will get query and store it in segment,
+// along with the number of rows it affected.
 func (t *Tracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 
-	segment, ok := ctx.Value(batchSegmentKey).(*newrelic.DatastoreSegment)
+	b, ok := ctx.Value(batchSegmentKey).(*batchSegment)
 	if !ok {
 		return
 	}
 
-	segment.ParameterizedQuery += data.SQL + "\n"
+	b.segment.ParameterizedQuery += data.SQL + "\n"
+	if nil == data.Err {
+		b.rowCount += data.CommandTag.RowsAffected()
+	}
 }
 
-// TraceBatchEnd implement pgx.BatchTracer. In this method we will get segment from context and fill it with
+// TraceBatchEnd implement pgx.BatchTracer. In this method we will get segment from context, add the
+// number of rows affected by the batch, and end it.
 func (t *Tracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
 
-	segment, ok := ctx.Value(batchSegmentKey).(*newrelic.DatastoreSegment)
+	b, ok := ctx.Value(batchSegmentKey).(*batchSegment)
+	if !ok {
+		return
+	}
+	b.segment.AddAttribute(rowCountAttribute, b.rowCount)
+	b.segment.End()
+}
+
+// TraceCopyFromStart is called at the beginning of CopyFrom calls. The returned context is used for the
+// rest of the call and will be passed to TraceCopyFromEnd. // implement pgx.CopyFromTracer
+func (t *Tracer) TraceCopyFromStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromStartData) context.Context {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	segment := t.BaseSegment
+	segment.StartTime = newrelic.FromContext(ctx).StartSegmentNow()
+	segment.Operation = "copy"
+	segment.Collection = ""
+	if n := len(data.TableName); n > 0 {
+		segment.Collection = data.TableName[n-1]
+	}
+	columns := make([]string, len(data.ColumnNames))
+	for i, c := range data.ColumnNames {
+		columns[i] = pgx.Identifier{c}.Sanitize()
+	}
+	segment.ParameterizedQuery = "COPY " + data.TableName.Sanitize() +
+		" (" + strings.Join(columns, ", ") + ") FROM STDIN BINARY"
+
+	return context.WithValue(ctx, copyFromSegmentKey, &segment)
+}
+
+// TraceCopyFromEnd implement pgx.CopyFromTracer. It will try to get segment from context, add the
+// number of rows copied, and end it.
+func (t *Tracer) TraceCopyFromEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceCopyFromEndData) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	segment, ok := ctx.Value(copyFromSegmentKey).(*newrelic.DatastoreSegment)
 	if !ok {
 		return
 	}
+	if nil == data.Err {
+		segment.AddAttribute(rowCountAttribute, data.CommandTag.RowsAffected())
+	}
 	segment.End()
 }
 
@@ -9,10 +9,11 @@ import (
 
 	"github.com/egon12/pgsnap"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -316,3 +317,86 @@ func getDBHostname() string {
 
 	return h
 }
+
+func TestTracer_copyFrom(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn, integrationsupport.DTEnabledCfgFn)
+	txn := app.StartTransaction(t.Name())
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	tracer := NewTracer()
+	tracer.BaseSegment = newrelic.DatastoreSegment{Product: newrelic.DatastorePostgres}
+	ctx = tracer.TraceCopyFromStart(ctx, nil, pgx.TraceCopyFromStartData{
+		TableName:   pgx.Identifier{"public", "events"},
+		ColumnNames: []string{"id", "name"},
+	})
+	tracer.TraceCopyFromEnd(ctx, nil, pgx.TraceCopyFromEndData{CommandTag: pgconn.NewCommandTag("COPY 3")})
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/operation/Postgres/copy"},
+		{Name: "Datastore/statement/Postgres/events/copy"},
+	})
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "Datastore/statement/Postgres/events/copy",
+				"category":  "datastore",
+				"component": "Postgres",
+				"span.kind": "client",
+				"parentId":  internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				"db.rowCount": 3,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/" + t.Name(),
+				"transaction.name": "OtherTransaction/Go/" + t.Name(),
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+		},
+	})
+}
+
+func TestTracer_batchRowCount(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn, integrationsupport.DTEnabledCfgFn)
+	txn := app.StartTransaction(t.Name())
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	tracer := NewTracer()
+	tracer.BaseSegment = newrelic.DatastoreSegment{Product: newrelic.DatastorePostgres}
+	batch := &pgx.Batch{}
+	batch.Queue("INSERT INTO mytable(name) VALUES ($1)", "name a")
+	batch.Queue("UPDATE mytable SET name = $1", "name b")
+	ctx = tracer.TraceBatchStart(ctx, nil, pgx.TraceBatchStartData{Batch: batch})
+	tracer.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: "INSERT INTO mytable(name) VALUES ($1)", CommandTag: pgconn.NewCommandTag("INSERT 0 1")})
+	tracer.TraceBatchQuery(ctx, nil, pgx.TraceBatchQueryData{SQL: "UPDATE mytable SET name = $1", CommandTag: pgconn.NewCommandTag("UPDATE 4")})
+	tracer.TraceBatchEnd(ctx, nil, pgx.TraceBatchEndData{})
+	txn.End()
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "Datastore/operation/Postgres/batch",
+				"category":  "datastore",
+				"component": "Postgres",
+				"span.kind": "client",
+				"parentId":  internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				"db.batchSize": 2,
+				"db.rowCount":  5,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/" + t.Name(),
+				"transaction.name": "OtherTransaction/Go/" + t.Name(),
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+		},
+	})
+}
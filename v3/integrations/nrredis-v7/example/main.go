@@ -10,8 +10,8 @@ import (
 	"time"
 
 	redis "github.com/go-redis/redis/v7"
-	nrredis "github.com/newrelic/go-agent/v3/integrations/nrredis-v7"
-	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	nrredis "github.com/rainforestpay/go-agent/v3/integrations/nrredis-v7"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 func main() {
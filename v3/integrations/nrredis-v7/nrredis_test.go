@@ -6,12 +6,13 @@ package nrredis
 import (
 	"context"
 	"net"
+	"strings"
 	"testing"
 
 	redis "github.com/go-redis/redis/v7"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
-	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 func emptyDialer(context.Context, string, string) (net.Conn, error) {
@@ -81,15 +82,19 @@ func TestPingWithOptionsAndAddress(t *testing.T) {
 	})
 }
 
-func TestPipelineOperation(t *testing.T) {
+func TestPipelineOperations(t *testing.T) {
 	// As of Jan 16, 2020, it is impossible to test pipeline operations using
 	// a &net.TCPConn{}, so we will have to make do with this.
-	if op := pipelineOperation(nil); op != "pipeline:" {
-		t.Error(op)
+	if op, ops := pipelineOperations(nil); op != "pipeline" || len(ops) != 0 {
+		t.Error(op, ops)
 	}
 	cmds := []redis.Cmder{redis.NewCmd("GET"), redis.NewCmd("SET")}
-	if op := pipelineOperation(cmds); op != "pipeline:get,set" {
-		t.Error(op)
+	if op, ops := pipelineOperations(cmds); op != "pipeline" || strings.Join(ops, ",") != "get,set" {
+		t.Error(op, ops)
+	}
+	cmds = []redis.Cmder{redis.NewCmd("MULTI"), redis.NewCmd("INCR"), redis.NewCmd("EXEC")}
+	if op, ops := pipelineOperations(cmds); op != "multi" || strings.Join(ops, ",") != "incr" {
+		t.Error(op, ops)
 	}
 }
 
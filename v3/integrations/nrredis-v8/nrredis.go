@@ -9,12 +9,10 @@ package nrredis
 
 import (
 	"context"
-	"net"
-	"strings"
 
 	redis "github.com/go-redis/redis/v8"
-	"github.com/newrelic/go-agent/v3/internal"
-	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 func init() { internal.TrackUsage("integration", "datastore", "redis") }
@@ -40,21 +38,12 @@ func NewHook(opts *redis.Options) redis.Hook {
 	if opts != nil {
 		// Per https://godoc.org/github.com/go-redis/redis#Options the
 		// network should either be tcp or unix, and the default is tcp.
-		if opts.Network == "unix" {
-			h.segment.Host = "localhost"
-			h.segment.PortPathOrID = opts.Addr
-		} else if host, port, err := net.SplitHostPort(opts.Addr); err == nil {
-			if "" == host {
-				host = "localhost"
-			}
-			h.segment.Host = host
-			h.segment.PortPathOrID = port
-		}
+		h.segment.SetInstance(opts.Network, opts.Addr)
 	}
 	return h
 }
 
-func (h hook) before(ctx context.Context, operation string) (context.Context, error) {
+func (h hook) before(ctx context.Context, operation string, pipeline []string) (context.Context, error) {
 	txn := newrelic.FromContext(ctx)
 	if txn == nil {
 		return ctx, nil
@@ -62,6 +51,7 @@ func (h hook) before(ctx context.Context, operation string) (context.Context, er
 	s := h.segment
 	s.StartTime = txn.StartSegmentNow()
 	s.Operation = operation
+	s.PipelineOperations = pipeline
 	ctx = context.WithValue(ctx, segmentContextKey, &s)
 	return ctx, nil
 }
@@ -73,7 +63,7 @@ func (h hook) after(ctx context.Context) {
 }
 
 func (h hook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
-	return h.before(ctx, cmd.Name())
+	return h.before(ctx, cmd.Name(), nil)
 }
 
 func (h hook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
@@ -81,16 +71,26 @@ func (h hook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
 	return nil
 }
 
-func pipelineOperation(cmds []redis.Cmder) string {
+// pipelineOperations returns the operation of a pipeline, either "pipeline" or
+// "multi" for a MULTI/EXEC transaction, and the operations of the commands it
+// contains.  A single segment is created for the pipeline rather than one for
+// each command.
+func pipelineOperations(cmds []redis.Cmder) (string, []string) {
+	operation := "pipeline"
+	if n := len(cmds); n >= 2 && cmds[0].Name() == "multi" && cmds[n-1].Name() == "exec" {
+		operation = "multi"
+		cmds = cmds[1 : n-1]
+	}
 	operations := make([]string, 0, len(cmds))
 	for _, cmd := range cmds {
 		operations = append(operations, cmd.Name())
 	}
-	return "pipeline:" + strings.Join(operations, ",")
+	return operation, operations
 }
 
 func (h hook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
-	return h.before(ctx, pipelineOperation(cmds))
+	operation, operations := pipelineOperations(cmds)
+	return h.before(ctx, operation, operations)
 }
 
 func (h hook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
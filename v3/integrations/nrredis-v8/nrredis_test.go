@@ -6,12 +6,13 @@ package nrredis
 import (
 	"context"
 	"net"
+	"strings"
 	"testing"
 
 	redis "github.com/go-redis/redis/v8"
-	"github.com/newrelic/go-agent/v3/internal"
-	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
-	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
 )
 
 func emptyDialer(context.Context, string, string) (net.Conn, error) {
@@ -38,6 +39,8 @@ func TestPing(t *testing.T) {
 		{Name: "OtherTransactionTotalTime/Go/txnName", Forced: nil},
 		{Name: "OtherTransaction/all", Forced: nil},
 		{Name: "OtherTransactionTotalTime", Forced: nil},
+		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Forced: nil},
+		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allOther", Forced: nil},
 		{Name: "Datastore/all", Forced: nil},
 		{Name: "Datastore/allOther", Forced: nil},
 		{Name: "Datastore/Redis/all", Forced: nil},
@@ -67,6 +70,8 @@ func TestPingWithOptionsAndAddress(t *testing.T) {
 		{Name: "OtherTransactionTotalTime/Go/txnName", Forced: nil},
 		{Name: "OtherTransaction/all", Forced: nil},
 		{Name: "OtherTransactionTotalTime", Forced: nil},
+		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Forced: nil},
+		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allOther", Forced: nil},
 		{Name: "Datastore/all", Forced: nil},
 		{Name: "Datastore/allOther", Forced: nil},
 		{Name: "Datastore/Redis/all", Forced: nil},
@@ -77,16 +82,20 @@ func TestPingWithOptionsAndAddress(t *testing.T) {
 	})
 }
 
-func TestPipelineOperation(t *testing.T) {
+func TestPipelineOperations(t *testing.T) {
 	// As of Jan 16, 2020, it is impossible to test pipeline operations using
 	// a &net.TCPConn{}, so we will have to make do with this.
-	if op := pipelineOperation(nil); op != "pipeline:" {
-		t.Error(op)
+	if op, ops := pipelineOperations(nil); op != "pipeline" || len(ops) != 0 {
+		t.Error(op, ops)
 	}
 	ctx := context.Background()
 	cmds := []redis.Cmder{redis.NewCmd(ctx, "GET"), redis.NewCmd(ctx, "SET")}
-	if op := pipelineOperation(cmds); op != "pipeline:get,set" {
-		t.Error(op)
+	if op, ops := pipelineOperations(cmds); op != "pipeline" || strings.Join(ops, ",") != "get,set" {
+		t.Error(op, ops)
+	}
+	cmds = []redis.Cmder{redis.NewCmd(ctx, "MULTI"), redis.NewCmd(ctx, "INCR"), redis.NewCmd(ctx, "EXEC")}
+	if op, ops := pipelineOperations(cmds); op != "multi" || strings.Join(ops, ",") != "incr" {
+		t.Error(op, ops)
 	}
 }
 
@@ -0,0 +1,21 @@
+module github.com/rainforestpay/go-agent/v3/integrations/nrtwirp
+
+go 1.17
+
+require (
+	github.com/rainforestpay/go-agent/v3 v3.20.0
+	// twirp v8 does not use modules, so it is imported as +incompatible.
+	github.com/twitchtv/twirp v8.1.3+incompatible
+)
+
+require (
+	github.com/golang/protobuf v1.5.2 // indirect
+	golang.org/x/net v0.0.0-20201021035429-f5854403a974 // indirect
+	golang.org/x/sys v0.0.0-20210119212857-b64e53b001e4 // indirect
+	golang.org/x/text v0.3.3 // indirect
+	google.golang.org/genproto v0.0.0-20200526211855-cb27e3aa2013 // indirect
+	google.golang.org/grpc v1.49.0 // indirect
+	google.golang.org/protobuf v1.27.1 // indirect
+)
+
+replace github.com/rainforestpay/go-agent/v3 => ../..
@@ -0,0 +1,132 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrtwirp instruments https://github.com/twitchtv/twirp servers and
+// clients.
+//
+// Twirp servers are instrumented by wrapping the server using
+// newrelic.WrapHandle, which starts a transaction for each request, and
+// adding the server hooks returned by ServerHooks, which name the transaction
+// after the method called, eg. "example.Haberdasher/MakeHat", and add the
+// rpc.* attributes:
+//
+//	server := example.NewHaberdasherServer(&randomHaberdasher{},
+//		twirp.WithServerHooks(nrtwirp.ServerHooks()))
+//	http.Handle(newrelic.WrapHandle(app, server.PathPrefix(), server))
+//
+// Errors returned by the server are noticed as expected errors if their code
+// indicates a problem with the request, eg. twirp.NotFound, and as errors
+// otherwise.
+//
+// Twirp clients are instrumented by wrapping their HTTP client using
+// WrapHTTPClient.  Each call made with a transaction in its context is
+// recorded with an external segment, and distributed tracing headers are added
+// to the request:
+//
+//	client := example.NewHaberdasherProtobufClient(url,
+//		nrtwirp.WrapHTTPClient(http.DefaultClient))
+package nrtwirp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
+	"github.com/twitchtv/twirp"
+)
+
+func init() { internal.TrackUsage("integration", "framework", "twirp") }
+
+const rpcSystem = "twirp"
+
+// Option configures the hooks created by ServerHooks.
+type Option func(*hooksConfig)
+
+type hooksConfig struct {
+	nameGrouping newrelic.RPCNameGrouping
+}
+
+// WithTransactionNameGrouping sets how calls are grouped into transaction
+// names.  By default, transactions are named after the service and method.
+func WithTransactionNameGrouping(grouping newrelic.RPCNameGrouping) Option {
+	return func(cfg *hooksConfig) {
+		cfg.nameGrouping = grouping
+	}
+}
+
+// serviceName returns the fully qualified name of the service handling the
+// context, eg. "example.Haberdasher".
+func serviceName(ctx context.Context) string {
+	service, _ := twirp.ServiceName(ctx)
+	if pkg, _ := twirp.PackageName(ctx); "" != pkg {
+		return pkg + "." + service
+	}
+	return service
+}
+
+// ServerHooks returns twirp.ServerHooks which name the transaction in the
+// request context after the method called, add the rpc.* attributes to it, and
+// notice the errors returned.  Use twirp.ChainHooks to combine them with your
+// own hooks.
+func ServerHooks(options ...Option) *twirp.ServerHooks {
+	cfg := &hooksConfig{nameGrouping: newrelic.RPCGroupByMethod}
+	for _, option := range options {
+		option(cfg)
+	}
+	return &twirp.ServerHooks{
+		RequestRouted: func(ctx context.Context) (context.Context, error) {
+			txn := newrelic.FromContext(ctx)
+			if nil == txn {
+				return ctx, nil
+			}
+			service := serviceName(ctx)
+			method, _ := twirp.MethodName(ctx)
+			txn.SetName(newrelic.RPCTransactionName("/"+service+"/"+method, cfg.nameGrouping))
+			integrationsupport.AddRPCAttributes(txn, rpcSystem, service, method)
+			return ctx, nil
+		},
+		Error: func(ctx context.Context, err twirp.Error) context.Context {
+			code := string(err.Code())
+			integrationsupport.NoticeRPCError(newrelic.FromContext(ctx), "Twirp Error: "+code, code, err.Msg())
+			return ctx
+		},
+	}
+}
+
+// HTTPClient is the interface used by Twirp clients to make requests.
+type HTTPClient interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+type httpClient struct {
+	client HTTPClient
+}
+
+// WrapHTTPClient returns an HTTPClient which records calls made by Twirp
+// clients with a transaction in their context using an external segment.  If
+// the client provided is nil, http.DefaultClient is used.
+func WrapHTTPClient(client HTTPClient) HTTPClient {
+	if nil == client {
+		client = http.DefaultClient
+	}
+	return &httpClient{client: client}
+}
+
+func (c *httpClient) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	txn := newrelic.FromContext(ctx)
+	if nil == txn {
+		return c.client.Do(req)
+	}
+	seg := newrelic.StartExternalSegment(txn, req)
+	seg.Library = "Twirp"
+	if method, ok := twirp.MethodName(ctx); ok {
+		seg.Procedure = serviceName(ctx) + "/" + method
+	}
+	resp, err := c.client.Do(req)
+	seg.Response = resp
+	seg.End()
+	return resp, err
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrtwirp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
+	"github.com/twitchtv/twirp"
+	"github.com/twitchtv/twirp/ctxsetters"
+)
+
+// methodContext returns a context populated as Twirp servers and clients do
+// when handling a call to example.Haberdasher/MakeHat.
+func methodContext(ctx context.Context) context.Context {
+	ctx = ctxsetters.WithPackageName(ctx, "example")
+	ctx = ctxsetters.WithServiceName(ctx, "Haberdasher")
+	return ctxsetters.WithMethodName(ctx, "MakeHat")
+}
+
+func TestServerHooks(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	hooks := ServerHooks()
+
+	txn := app.StartTransaction("POST /twirp/")
+	ctx := methodContext(newrelic.NewContext(context.Background(), txn))
+	ctx, err := hooks.RequestRouted(ctx)
+	if nil != err {
+		t.Fatal(err)
+	}
+	hooks.Error(ctx, twirp.NotFoundError("no hats"))
+	txn.End()
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":     "OtherTransaction/Go/example.Haberdasher/MakeHat",
+			"guid":     internal.MatchAnything,
+			"traceId":  internal.MatchAnything,
+			"priority": internal.MatchAnything,
+			"sampled":  internal.MatchAnything,
+		},
+		AgentAttributes: map[string]interface{}{
+			newrelic.AttributeRPCSystem:    "twirp",
+			newrelic.AttributeRPCService:   "example.Haberdasher",
+			newrelic.AttributeRPCMethod:    "MakeHat",
+			newrelic.AttributeRPCErrorCode: "not_found",
+		},
+	}})
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName: "OtherTransaction/Go/example.Haberdasher/MakeHat",
+		Msg:     "no hats",
+		Klass:   "Twirp Error: not_found",
+	}})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "ErrorsExpected/all"},
+	})
+}
+
+func TestServerHooksUnexpectedError(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	hooks := ServerHooks(WithTransactionNameGrouping(newrelic.RPCGroupByService))
+
+	txn := app.StartTransaction("POST /twirp/")
+	ctx := methodContext(newrelic.NewContext(context.Background(), txn))
+	ctx, _ = hooks.RequestRouted(ctx)
+	hooks.Error(ctx, twirp.InternalError("oops"))
+	txn.End()
+
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName: "OtherTransaction/Go/example.Haberdasher",
+		Msg:     "oops",
+		Klass:   "Twirp Error: internal",
+	}})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Errors/all"},
+	})
+}
+
+func TestServerHooksNoTransaction(t *testing.T) {
+	hooks := ServerHooks()
+	ctx := methodContext(context.Background())
+	ctx, err := hooks.RequestRouted(ctx)
+	if nil != err {
+		t.Error(err)
+	}
+	hooks.Error(ctx, twirp.InternalError("oops"))
+}
+
+func TestWrapHTTPClient(t *testing.T) {
+	app := integrationsupport.NewTestApp(func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+		reply.AccountID = "123"
+		reply.TrustedAccountKey = "123"
+		reply.PrimaryAppID = "456"
+	}, integrationsupport.DTEnabledCfgFn)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hdr := r.Header.Get("traceparent"); "" == hdr {
+			t.Error("distributed tracing headers not found")
+		}
+	}))
+	defer srv.Close()
+
+	txn := app.StartTransaction("client")
+	ctx := methodContext(newrelic.NewContext(context.Background(), txn))
+	req, err := http.NewRequestWithContext(ctx, "POST", srv.URL+"/twirp/example.Haberdasher/MakeHat", nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+	resp, err := WrapHTTPClient(nil).Do(req)
+	if nil != err {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "External/all"},
+		{Name: "External/" + srv.Listener.Addr().String() + "/Twirp/example.Haberdasher/MakeHat", Scope: "OtherTransaction/Go/client"},
+	})
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrzap
+
+import (
+	"context"
+
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// transactionKey is the key of the field created by Transaction.
+const transactionKey = "newrelic.transaction"
+
+// Transaction returns a field which links the logs written with it to the
+// transaction's trace when they are forwarded by the Core.  The field is
+// skipped by encoders, so it does not appear in the output of other cores.
+func Transaction(txn *newrelic.Transaction) zap.Field {
+	return zap.Field{Key: transactionKey, Type: zapcore.SkipType, Interface: txn}
+}
+
+// Context returns a Transaction field for the transaction of the context.
+func Context(ctx context.Context) zap.Field {
+	return Transaction(newrelic.FromContext(ctx))
+}
+
+// transactionFromFields returns the transaction of the last Transaction field,
+// or nil.
+func transactionFromFields(fields []zapcore.Field) *newrelic.Transaction {
+	var txn *newrelic.Transaction
+	for _, f := range fields {
+		if f.Key == transactionKey && f.Type == zapcore.SkipType {
+			if t, ok := f.Interface.(*newrelic.Transaction); ok {
+				txn = t
+			}
+		}
+	}
+	return txn
+}
+
+// Core is a zapcore.Core which forwards log entries to New Relic through the
+// agent.  Entries logged with a Transaction field, or by a logger created
+// with one by zap.Logger.With, are recorded by the transaction so that they
+// are linked to its trace.  Other entries are recorded by the application.
+//
+// Since it only forwards logs, the Core is combined with the core writing the
+// application's output using WrapCore or zapcore.NewTee.  Its level, and its
+// sampling with zapcore.NewSampler, are independent of that core's.
+type Core struct {
+	app    *newrelic.Application
+	txn    *newrelic.Transaction
+	level  zapcore.LevelEnabler
+	fields []zapcore.Field
+}
+
+// NewCore creates a Core which forwards the entries enabled by level.
+func NewCore(app *newrelic.Application, level zapcore.LevelEnabler) *Core {
+	return &Core{
+		app:   app,
+		level: level,
+	}
+}
+
+// WrapCore returns a core which writes entries to core and forwards those
+// enabled by level to New Relic.
+func WrapCore(core zapcore.Core, app *newrelic.Application, level zapcore.LevelEnabler) zapcore.Core {
+	return zapcore.NewTee(core, NewCore(app, level))
+}
+
+// attributes returns the fields encoded as log event attributes, or nil.
+// They are recorded if they are listed in
+// Config.ApplicationLogging.Forwarding.AttributeAllowList.
+func attributes(fields ...[]zapcore.Field) map[string]interface{} {
+	var enc *zapcore.MapObjectEncoder
+	for _, fs := range fields {
+		for _, f := range fs {
+			if f.Type == zapcore.SkipType {
+				continue
+			}
+			if enc == nil {
+				enc = zapcore.NewMapObjectEncoder()
+			}
+			f.AddTo(enc)
+		}
+	}
+	if enc == nil {
+		return nil
+	}
+	return enc.Fields
+}
+
+// Enabled implements zapcore.Core.
+func (c *Core) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+// With implements zapcore.Core.
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	if len(fields) == 0 {
+		return c
+	}
+	txn := transactionFromFields(fields)
+	if txn == nil {
+		txn = c.txn
+	}
+	return &Core{
+		app:    c.app,
+		txn:    txn,
+		level:  c.level,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+// Check implements zapcore.Core.
+func (c *Core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write implements zapcore.Core.
+func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	data := newrelic.LogData{
+		Timestamp: entry.Time.UnixNano() / int64(1000*1000),
+		Severity:  entry.Level.String(),
+		Message:   entry.Message,
+
+		Attributes: attributes(c.fields, fields),
+	}
+	txn := transactionFromFields(fields)
+	if txn == nil {
+		txn = c.txn
+	}
+	if txn != nil {
+		txn.RecordLog(data)
+	} else {
+		c.app.RecordLog(data)
+	}
+	return nil
+}
+
+// Sync implements zapcore.Core.  Logs are sent with the agent's harvests, so
+// there is nothing to flush.
+func (c *Core) Sync() error {
+	return nil
+}
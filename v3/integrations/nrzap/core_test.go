@@ -0,0 +1,132 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrzap
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func newTestLogger(buf *bytes.Buffer, app *newrelic.Application, level zapcore.Level) *zap.Logger {
+	console := zapcore.NewCore(
+		zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()),
+		zapcore.AddSync(buf),
+		zap.DebugLevel,
+	)
+	return zap.New(WrapCore(console, app, level))
+}
+
+func TestCoreBackgroundLog(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn,
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+	buf := &bytes.Buffer{}
+	logger := newTestLogger(buf, app.Application, zap.InfoLevel)
+	logger.Debug("not forwarded")
+	logger.Info("hello world")
+
+	if out := buf.String(); !strings.Contains(out, "not forwarded") || !strings.Contains(out, "hello world") {
+		t.Error(out)
+	}
+	app.ExpectLogEvents(t, []internal.WantLog{
+		{
+			Severity:  zap.InfoLevel.String(),
+			Message:   "hello world",
+			Timestamp: internal.MatchAnyUnixMilli,
+		},
+	})
+}
+
+func TestCoreTransactionField(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn,
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+	buf := &bytes.Buffer{}
+	logger := newTestLogger(buf, app.Application, zap.InfoLevel)
+	txn := app.StartTransaction("test txn")
+	md := txn.GetLinkingMetadata()
+	logger.Info("hello world", Transaction(txn))
+
+	if out := buf.String(); strings.Contains(out, transactionKey) {
+		t.Error("transaction field written", out)
+	}
+	txn.ExpectLogEvents(t, []internal.WantLog{
+		{
+			Severity:  zap.InfoLevel.String(),
+			Message:   "hello world",
+			Timestamp: internal.MatchAnyUnixMilli,
+			SpanID:    md.SpanID,
+			TraceID:   md.TraceID,
+		},
+	})
+	txn.End()
+}
+
+func TestCoreWithContext(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn,
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+	buf := &bytes.Buffer{}
+	txn := app.StartTransaction("test txn")
+	md := txn.GetLinkingMetadata()
+	logger := newTestLogger(buf, app.Application, zap.InfoLevel).
+		With(Context(newrelic.NewContext(context.Background(), txn)))
+	logger.Warn("hello world")
+
+	txn.ExpectLogEvents(t, []internal.WantLog{
+		{
+			Severity:  zap.WarnLevel.String(),
+			Message:   "hello world",
+			Timestamp: internal.MatchAnyUnixMilli,
+			SpanID:    md.SpanID,
+			TraceID:   md.TraceID,
+		},
+	})
+	txn.End()
+}
+
+func TestCoreAttributes(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn,
+		newrelic.ConfigAppLogForwardingEnabled(true),
+		newrelic.ConfigAppLogForwardingAttributeAllowList("order_id", "user.*"),
+	)
+	buf := &bytes.Buffer{}
+	txn := app.StartTransaction("test txn")
+	logger := newTestLogger(buf, app.Application, zap.InfoLevel).
+		With(zap.String("user.email", "me@example.com"))
+	logger.Info("order placed", Transaction(txn), zap.Int("order_id", 1234), zap.String("zip", "zap"))
+
+	txn.ExpectLogEvents(t, []internal.WantLog{
+		{
+			Severity:  zap.InfoLevel.String(),
+			Message:   "order placed",
+			Timestamp: internal.MatchAnyUnixMilli,
+			SpanID:    internal.MatchAnyString,
+			TraceID:   internal.MatchAnyString,
+			Attributes: map[string]interface{}{
+				"order_id":   1234,
+				"user.email": "me@example.com",
+			},
+		},
+	})
+	txn.End()
+}
+
+func TestCoreWithoutTransaction(t *testing.T) {
+	core := NewCore(nil, zap.InfoLevel)
+	if c := core.With(nil); c != core {
+		t.Error("core copied without fields")
+	}
+	if txn := transactionFromFields([]zapcore.Field{Context(context.Background())}); txn != nil {
+		t.Error(txn)
+	}
+}
@@ -4,9 +4,14 @@
 package nrzap_test
 
 import (
-	"github.com/newrelic/go-agent/v3/integrations/nrzap"
-	"github.com/newrelic/go-agent/v3/newrelic"
+	"context"
+	"os"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/integrations/nrzap"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func Example() {
@@ -20,3 +25,43 @@ func Example() {
 		nrzap.ConfigLogger(z.Named("newrelic")),
 	)
 }
+
+func ExampleWrapCore() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+
+	// Write debug logs to the console and forward warnings and errors to
+	// New Relic:
+	z, _ := zap.NewDevelopment(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return nrzap.WrapCore(core, app, zap.WarnLevel)
+	}))
+
+	// Link logs to the trace of a transaction:
+	txn := app.StartTransaction("job")
+	defer txn.End()
+	z.Warn("job is slow", nrzap.Transaction(txn))
+}
+
+func ExampleNewCore() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+	console := zapcore.NewCore(
+		zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()),
+		zapcore.Lock(os.Stdout),
+		zap.DebugLevel,
+	)
+	// Forward at most 100 logs each second, and then every 10th, of info
+	// level and above, while every log is written to the console:
+	forwarded := zapcore.NewSampler(nrzap.NewCore(app, zap.InfoLevel), time.Second, 100, 10)
+	z := zap.New(zapcore.NewTee(console, forwarded))
+
+	// Loggers created with a transaction link all of their logs to it:
+	ctx := newrelic.NewContext(context.Background(), app.StartTransaction("job"))
+	z.With(nrzap.Context(ctx)).Info("job started")
+}
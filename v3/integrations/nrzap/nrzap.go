@@ -4,11 +4,15 @@
 // Package nrzap supports https://github.com/uber-go/zap
 //
 // Wrap your zap Logger using nrzap.Transform to send agent log messages to zap.
+//
+// Use nrzap.WrapCore or nrzap.NewCore to forward your application's logs to
+// New Relic through the agent, linked to the trace of the transaction given by
+// the nrzap.Transaction field.
 package nrzap
 
 import (
-	"github.com/newrelic/go-agent/v3/internal"
-	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
 	"go.uber.org/zap"
 )
 
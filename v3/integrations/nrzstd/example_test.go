@@ -0,0 +1,18 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrzstd_test
+
+import (
+	"github.com/rainforestpay/go-agent/v3/integrations/nrzstd"
+	"github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+func Example() {
+	newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+		// Use nrzstd to compress payloads using zstd when supported:
+		nrzstd.ConfigCompression(),
+	)
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrzstd supports compressing harvest payloads using
+// https://github.com/klauspost/compress/tree/master/zstd
+//
+// Use nrzstd.ConfigCompression to compress payloads using zstd instead of gzip
+// whenever New Relic advertises support for it.  zstd payloads are typically
+// smaller than gzip payloads, which reduces egress for applications recording
+// many events.
+package nrzstd
+
+import (
+	"github.com/klauspost/compress/zstd"
+	"github.com/rainforestpay/go-agent/v3/internal"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+func init() { internal.TrackUsage("integration", "compression", "zstd") }
+
+type encoder struct{ enc *zstd.Encoder }
+
+func (e encoder) ContentEncoding() string { return "zstd" }
+
+func (e encoder) Encode(payload []byte) ([]byte, error) {
+	return e.enc.EncodeAll(payload, nil), nil
+}
+
+// NewEncoder returns a newrelic.PayloadEncoder which compresses payloads using
+// zstd with the options provided.
+func NewEncoder(opts ...zstd.EOption) (newrelic.PayloadEncoder, error) {
+	// A nil writer is allowed since only EncodeAll is used.
+	enc, err := zstd.NewWriter(nil, opts...)
+	if nil != err {
+		return nil, err
+	}
+	return encoder{enc: enc}, nil
+}
+
+// ConfigCompression configures the newrelic.Application to compress payloads
+// using zstd at the default compression level.
+func ConfigCompression() newrelic.ConfigOption {
+	enc, err := NewEncoder()
+	return func(cfg *newrelic.Config) {
+		if nil != err {
+			cfg.Error = err
+			return
+		}
+		cfg.Compression.Encoder = enc
+	}
+}
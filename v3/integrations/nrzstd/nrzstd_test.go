@@ -0,0 +1,49 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrzstd
+
+import (
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+func TestEncoderRoundTrip(t *testing.T) {
+	enc, err := NewEncoder()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if e := enc.ContentEncoding(); e != "zstd" {
+		t.Error(e)
+	}
+	payload := []byte(`[{"zip":"zap"},{"zip":"zap"},{"zip":"zap"}]`)
+	encoded, err := enc.Encode(payload)
+	if nil != err {
+		t.Fatal(err)
+	}
+	dec, err := zstd.NewReader(nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer dec.Close()
+	decoded, err := dec.DecodeAll(encoded, nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if string(decoded) != string(payload) {
+		t.Error(string(decoded))
+	}
+}
+
+func TestConfigCompression(t *testing.T) {
+	var cfg newrelic.Config
+	ConfigCompression()(&cfg)
+	if nil != cfg.Error {
+		t.Error(cfg.Error)
+	}
+	if nil == cfg.Compression.Encoder {
+		t.Error("encoder not set")
+	}
+}
@@ -8,8 +8,12 @@ package awssupport
 
 import (
 	"context"
+	"encoding/base32"
+	"fmt"
 	"net/http"
+	"net/url"
 	"reflect"
+	"strings"
 
 	"github.com/rainforestpay/go-agent/v3/internal/integrationsupport"
 	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
@@ -21,25 +25,137 @@ var segmentContextKey = contextKeyType(struct{}{})
 
 type endable interface{ End() }
 
-func getTableName(params interface{}) string {
-	var tableName string
+func getStringField(params interface{}, field string) string {
+	var val string
 
 	v := reflect.ValueOf(params)
 	if v.IsValid() && v.Kind() == reflect.Ptr {
 		e := v.Elem()
 		if e.Kind() == reflect.Struct {
-			n := e.FieldByName("TableName")
+			n := e.FieldByName(field)
 			if n.IsValid() {
-				if name, ok := n.Interface().(*string); ok {
-					if nil != name {
-						tableName = *name
+				if str, ok := n.Interface().(*string); ok {
+					if nil != str {
+						val = *str
 					}
 				}
 			}
 		}
 	}
 
-	return tableName
+	return val
+}
+
+func getTableName(params interface{}) string {
+	return getStringField(params, "TableName")
+}
+
+const arnPrefix = "arn:"
+
+// GetARN returns the Amazon Resource Name of the resource targeted by the
+// request, or the empty string if it cannot be determined from the request
+// parameters.  SNS topics, SQS queues, and Lambda functions are supported.
+// The region and accountID are used to complete the ARN when the parameters
+// only contain a partial identifier.
+func GetARN(serviceName, region, accountID string, params interface{}) string {
+	switch strings.ToLower(serviceName) {
+	case "sns":
+		if arn := getStringField(params, "TopicArn"); arn != "" {
+			return arn
+		}
+		return getStringField(params, "TargetArn")
+	case "sqs":
+		return sqsQueueARN(getStringField(params, "QueueUrl"), region)
+	case "lambda":
+		return lambdaFunctionARN(getStringField(params, "FunctionName"), region, accountID)
+	}
+	return ""
+}
+
+// sqsQueueARN converts a queue URL of the form
+// https://sqs.us-east-2.amazonaws.com/123456789012/MyQueue into its ARN.
+func sqsQueueARN(queueURL, region string) string {
+	if queueURL == "" {
+		return ""
+	}
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	if region == "" {
+		// Host names look like sqs.us-east-2.amazonaws.com.
+		hostParts := strings.Split(u.Hostname(), ".")
+		if len(hostParts) > 2 && hostParts[0] == "sqs" {
+			region = hostParts[1]
+		}
+	}
+	if region == "" {
+		return ""
+	}
+	return arnPrefix + "aws:sqs:" + region + ":" + parts[0] + ":" + parts[1]
+}
+
+// lambdaFunctionARN accepts any of the function name formats allowed by the
+// Lambda API: a full ARN, a partial ARN (123456789012:function:my-function),
+// or a bare function name optionally followed by a version or alias.
+func lambdaFunctionARN(functionName, region, accountID string) string {
+	if functionName == "" {
+		return ""
+	}
+	if strings.HasPrefix(functionName, arnPrefix) {
+		return functionName
+	}
+	if region == "" {
+		return ""
+	}
+	parts := strings.Split(functionName, ":")
+	if len(parts) >= 3 && parts[1] == "function" {
+		return arnPrefix + "aws:lambda:" + region + ":" + functionName
+	}
+	if accountID == "" {
+		return ""
+	}
+	return arnPrefix + "aws:lambda:" + region + ":" + accountID + ":function:" + functionName
+}
+
+// GetARNAccountID returns the account ID portion of the given ARN, or the
+// empty string if the ARN is malformed.
+func GetARNAccountID(arn string) string {
+	if !strings.HasPrefix(arn, arnPrefix) {
+		return ""
+	}
+	// arn:partition:service:region:account-id:resource
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return ""
+	}
+	return parts[4]
+}
+
+// GetAccessKeyAccountID returns the account ID encoded in the given AWS
+// access key ID, or the empty string if the key is not a long term (AKIA) or
+// temporary (ASIA) access key.
+func GetAccessKeyAccountID(accessKeyID string) string {
+	if len(accessKeyID) != 20 {
+		return ""
+	}
+	if prefix := accessKeyID[:4]; prefix != "AKIA" && prefix != "ASIA" {
+		return ""
+	}
+	decoded, err := base32.StdEncoding.DecodeString(accessKeyID[4:])
+	if err != nil {
+		return ""
+	}
+	// The account ID is stored in bits 1 through 40 of the first six bytes.
+	var n uint64
+	for _, b := range decoded[:6] {
+		n = n<<8 | uint64(b)
+	}
+	return fmt.Sprintf("%012d", (n&0x7fffffffff80)>>7)
 }
 
 // GetRequestID looks for the AWS request ID header.
@@ -58,7 +174,11 @@ type StartSegmentInputs struct {
 	ServiceName string
 	Operation   string
 	Region      string
-	Params      interface{}
+	// AccountID is the AWS account ID of the caller.  It is optional and
+	// only used to complete the ARN of resources which are referenced by
+	// name, such as Lambda functions.
+	AccountID string
+	Params    interface{}
 }
 
 // StartSegment starts a segment of either type DatastoreSegment or
@@ -95,6 +215,17 @@ func StartSegment(input StartSegmentInputs) *http.Request {
 	integrationsupport.AddAgentSpanAttribute(txn, newrelic.SpanAttributeAWSOperation, input.Operation)
 	integrationsupport.AddAgentSpanAttribute(txn, newrelic.SpanAttributeAWSRegion, input.Region)
 
+	// These attributes allow the backend to create relationships between
+	// this entity and the cloud resources it calls.
+	arn := GetARN(input.ServiceName, input.Region, input.AccountID, input.Params)
+	accountID := input.AccountID
+	if accountID == "" {
+		accountID = GetARNAccountID(arn)
+	}
+	integrationsupport.AddAgentSpanAttribute(txn, newrelic.SpanAttributeCloudRegion, input.Region)
+	integrationsupport.AddAgentSpanAttribute(txn, newrelic.SpanAttributeCloudAccountID, accountID)
+	integrationsupport.AddAgentSpanAttribute(txn, newrelic.SpanAttributeAWSARN, arn)
+
 	ctx := context.WithValue(httpCtx, segmentContextKey, segment)
 	return input.HTTPRequest.WithContext(ctx)
 }
@@ -36,6 +36,9 @@ type ConnectReply struct {
 	RequestHeadersMap     map[string]string `json:"request_headers_map"`
 	MaxPayloadSizeInBytes int               `json:"max_payload_size_in_bytes"`
 	EntityGUID            string            `json:"entity_guid"`
+	// ContentEncodings lists the payload compression algorithms supported
+	// by the collector in addition to gzip.
+	ContentEncodings []string `json:"content_encodings"`
 
 	// Transaction Name Modifiers
 	SegmentTerms segmentRules `json:"transaction_segment_terms"`
@@ -92,22 +95,36 @@ type ConnectReply struct {
 	SamplingTarget                uint64 `json:"sampling_target"`
 	SamplingTargetPeriodInSeconds int    `json:"sampling_target_period_in_seconds"`
 
-	ServerSideConfig struct {
-		TransactionTracerEnabled *bool `json:"transaction_tracer.enabled"`
-		// TransactionTracerThreshold should contain either a number or
-		// "apdex_f" if it is non-nil.
-		TransactionTracerThreshold           interface{} `json:"transaction_tracer.transaction_threshold"`
-		TransactionTracerStackTraceThreshold *float64    `json:"transaction_tracer.stack_trace_threshold"`
-		ErrorCollectorEnabled                *bool       `json:"error_collector.enabled"`
-		ErrorCollectorIgnoreStatusCodes      []int       `json:"error_collector.ignore_status_codes"`
-		CrossApplicationTracerEnabled        *bool       `json:"cross_application_tracer.enabled"`
-	} `json:"agent_config"`
+	ServerSideConfig ServerSideConfig `json:"agent_config"`
 
 	// Faster Event Harvest
 	EventData              EventHarvestConfig `json:"event_harvest_config"`
 	SpanEventHarvestConfig `json:"span_event_harvest_config"`
 }
 
+// ServerSideConfig contains the settings configured in the New Relic UI
+// which override those of the agent.  They are also the settings which may be
+// changed while the application is running, see Config.RemoteConfig.
+type ServerSideConfig struct {
+	TransactionTracerEnabled *bool `json:"transaction_tracer.enabled"`
+	// TransactionTracerThreshold should contain either a number or
+	// "apdex_f" if it is non-nil.
+	TransactionTracerThreshold           interface{}         `json:"transaction_tracer.transaction_threshold"`
+	TransactionTracerStackTraceThreshold *float64            `json:"transaction_tracer.stack_trace_threshold"`
+	TransactionTracerExplainThreshold    *float64            `json:"transaction_tracer.explain_threshold"`
+	TransactionEventsEnabled             *bool               `json:"transaction_events.enabled"`
+	SlowSQLEnabled                       *bool               `json:"slow_sql.enabled"`
+	SpanEventsEnabled                    *bool               `json:"span_events.enabled"`
+	ErrorCollectorEnabled                *bool               `json:"error_collector.enabled"`
+	ErrorCollectorCaptureEvents          *bool               `json:"error_collector.capture_events"`
+	ErrorCollectorIgnoreStatusCodes      []int               `json:"error_collector.ignore_status_codes"`
+	ErrorCollectorIgnoreClasses          []string            `json:"error_collector.ignore_classes"`
+	ErrorCollectorIgnoreMessages         map[string][]string `json:"error_collector.ignore_messages"`
+	CrossApplicationTracerEnabled        *bool               `json:"cross_application_tracer.enabled"`
+	AttributeValueLengthLimit            *int                `json:"attributes.value_length_limit"`
+	SpanEventsMaxAttributeValues         *int                `json:"span_events.max_attribute_values"`
+}
+
 // EventHarvestConfig contains fields relating to faster event harvest.
 // This structure is used in the connect request (to send up defaults)
 // and in the connect response (to get the server values).
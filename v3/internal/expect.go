@@ -33,6 +33,8 @@ type WantLog struct {
 	SpanID    string
 	TraceID   string
 	Timestamp int64
+	// Attributes are checked if non-nil.
+	Attributes map[string]interface{}
 }
 
 func uniquePointer() *struct{} {
@@ -0,0 +1,161 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package integrationsupport
+
+import (
+	"context"
+
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+// GraphQLAnonymousOperation is the name used for unnamed GraphQL operations.
+const GraphQLAnonymousOperation = "<anonymous>"
+
+// GraphQLOperation describes the GraphQL operation handled by a transaction.
+type GraphQLOperation struct {
+	// Type is "query", "mutation", or "subscription".
+	Type string
+	// Name is the operation name, which may be empty.
+	Name string
+	// PersistedQueryHash is the hash of the persisted query, if any.
+	PersistedQueryHash string
+}
+
+// NameGraphQLTransaction names the transaction after the GraphQL operation,
+// eg. "query/GetUser", and adds the operation's agent attributes.  Naming the
+// transaction after the operation rather than the request body keeps the
+// number of transaction names bounded.
+func NameGraphQLTransaction(txn *newrelic.Transaction, op GraphQLOperation) {
+	if nil == txn {
+		return
+	}
+	if "" == op.Type {
+		op.Type = "query"
+	}
+	if "" == op.Name {
+		op.Name = GraphQLAnonymousOperation
+	}
+	txn.SetName(op.Type + "/" + op.Name)
+	AddAgentAttribute(txn, newrelic.AttributeGraphQLOperationType, op.Type, nil)
+	AddAgentAttribute(txn, newrelic.AttributeGraphQLOperationName, op.Name, nil)
+	if "" != op.PersistedQueryHash {
+		AddAgentAttribute(txn, newrelic.AttributeGraphQLPersistedQueryHash, op.PersistedQueryHash, nil)
+	}
+}
+
+type persistedQueryHashKey struct{}
+
+// WithGraphQLPersistedQueryHash returns a copy of the context containing the
+// hash of the persisted query being executed.
+func WithGraphQLPersistedQueryHash(ctx context.Context, hash string) context.Context {
+	return context.WithValue(ctx, persistedQueryHashKey{}, hash)
+}
+
+// GraphQLPersistedQueryHash returns the hash added to the context using
+// WithGraphQLPersistedQueryHash.
+func GraphQLPersistedQueryHash(ctx context.Context) string {
+	if nil == ctx {
+		return ""
+	}
+	hash, _ := ctx.Value(persistedQueryHashKey{}).(string)
+	return hash
+}
+
+// GraphQLOperationType returns the type of the operation in the GraphQL
+// document with the name provided, or of the first operation if the name is
+// empty.  An empty string is returned if no such operation is found.  The
+// document is scanned rather than parsed, so that integrations can determine
+// the type before their library has parsed the document.
+func GraphQLOperationType(document, operationName string) string {
+	var words []string
+	depth := 0
+	for i := 0; i < len(document); {
+		c := document[i]
+		switch {
+		case '#' == c:
+			for i < len(document) && '\n' != document[i] {
+				i++
+			}
+		case '"' == c:
+			i = skipGraphQLString(document, i)
+		case '{' == c:
+			if 0 == depth {
+				if typ := graphQLDefinitionType(words, operationName); "" != typ {
+					return typ
+				}
+				words = nil
+			}
+			depth++
+			i++
+		case '}' == c:
+			depth--
+			i++
+		case '(' == c && 0 == depth:
+			// Skip variable definitions.
+			for i < len(document) && ')' != document[i] {
+				i++
+			}
+		case 0 == depth && isGraphQLNameStart(c):
+			start := i
+			for i < len(document) && (isGraphQLNameStart(document[i]) || (document[i] >= '0' && document[i] <= '9')) {
+				i++
+			}
+			words = append(words, document[start:i])
+		default:
+			i++
+		}
+	}
+	return ""
+}
+
+// graphQLDefinitionType returns the operation type of the definition
+// beginning with the words provided if its name matches.
+func graphQLDefinitionType(words []string, operationName string) string {
+	if 0 == len(words) {
+		// Query shorthand: an anonymous query.
+		if "" == operationName {
+			return "query"
+		}
+		return ""
+	}
+	switch words[0] {
+	case "query", "mutation", "subscription":
+		var name string
+		if len(words) > 1 {
+			name = words[1]
+		}
+		if "" == operationName || name == operationName {
+			return words[0]
+		}
+	}
+	return ""
+}
+
+// skipGraphQLString returns the index following the string or block string
+// beginning at i.
+func skipGraphQLString(document string, i int) int {
+	if len(document) >= i+3 && `"""` == document[i:i+3] {
+		for i += 3; i < len(document); i++ {
+			if '\\' == document[i] {
+				i++
+			} else if len(document) >= i+3 && `"""` == document[i:i+3] {
+				return i + 3
+			}
+		}
+		return i
+	}
+	for i++; i < len(document); i++ {
+		switch document[i] {
+		case '\\':
+			i++
+		case '"', '\n':
+			return i + 1
+		}
+	}
+	return i
+}
+
+func isGraphQLNameStart(c byte) bool {
+	return '_' == c || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,93 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package integrationsupport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+func TestGraphQLOperationType(t *testing.T) {
+	testcases := []struct {
+		document string
+		name     string
+		expect   string
+	}{
+		{document: `{ hello }`, expect: "query"},
+		{document: `{ hello }`, name: "Hello", expect: ""},
+		{document: `query { hello }`, expect: "query"},
+		{document: `mutation AddUser($name: String = "a{") { add(name: $name) { id } }`, expect: "mutation"},
+		{document: `subscription OnEvent { event }`, name: "OnEvent", expect: "subscription"},
+		{document: `# mutation Commented
+			query GetUser { user { ...F } }
+			fragment F on User { id }
+			mutation SetUser @live { set }`, name: "SetUser", expect: "mutation"},
+		{document: `query A { a(s: """ mutation B { b } """) } mutation B { b }`, name: "B", expect: "mutation"},
+		{document: `query GetUser { user }`, name: "Missing", expect: ""},
+		{document: ``, expect: ""},
+	}
+	for _, tc := range testcases {
+		if typ := GraphQLOperationType(tc.document, tc.name); typ != tc.expect {
+			t.Errorf("document=%q name=%q: expected %q, got %q", tc.document, tc.name, tc.expect, typ)
+		}
+	}
+}
+
+func TestGraphQLPersistedQueryHash(t *testing.T) {
+	if hash := GraphQLPersistedQueryHash(context.Background()); "" != hash {
+		t.Error(hash)
+	}
+	ctx := WithGraphQLPersistedQueryHash(context.Background(), "abc123")
+	if hash := GraphQLPersistedQueryHash(ctx); "abc123" != hash {
+		t.Error(hash)
+	}
+}
+
+func TestNameGraphQLTransaction(t *testing.T) {
+	app := testApp(t)
+	txn := app.StartTransaction("hello")
+	NameGraphQLTransaction(txn, GraphQLOperation{
+		Type:               "mutation",
+		Name:               "AddUser",
+		PersistedQueryHash: "abc123",
+	})
+	txn.End()
+
+	txn = app.StartTransaction("hello")
+	NameGraphQLTransaction(txn, GraphQLOperation{})
+	txn.End()
+
+	app.Private.(internal.Expect).ExpectTxnEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "OtherTransaction/Go/mutation/AddUser",
+				"guid":     internal.MatchAnything,
+				"traceId":  internal.MatchAnything,
+				"priority": internal.MatchAnything,
+				"sampled":  internal.MatchAnything,
+			},
+			AgentAttributes: map[string]interface{}{
+				newrelic.AttributeGraphQLOperationType:      "mutation",
+				newrelic.AttributeGraphQLOperationName:      "AddUser",
+				newrelic.AttributeGraphQLPersistedQueryHash: "abc123",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "OtherTransaction/Go/query/<anonymous>",
+				"guid":     internal.MatchAnything,
+				"traceId":  internal.MatchAnything,
+				"priority": internal.MatchAnything,
+				"sampled":  internal.MatchAnything,
+			},
+			AgentAttributes: map[string]interface{}{
+				newrelic.AttributeGraphQLOperationType: "query",
+				newrelic.AttributeGraphQLOperationName: "<anonymous>",
+			},
+		},
+	})
+}
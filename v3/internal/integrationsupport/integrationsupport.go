@@ -108,3 +108,98 @@ var SampleEverythingReplyFn = func(reply *internal.ConnectReply) {
 	reply.EntityGUID = TestEntityGUID
 	reply.EventData = internal.DefaultEventHarvestConfig(internal.MaxTxnEvents, internal.MaxLogEvents, internal.MaxCustomEvents)
 }
+
+// ReplyFns combines ConnectReply functions into a single function that
+// applies each of them in order.  This allows the limit functions below to be
+// used together with SampleEverythingReplyFn.
+func ReplyFns(fns ...func(*internal.ConnectReply)) func(*internal.ConnectReply) {
+	return func(reply *internal.ConnectReply) {
+		for _, fn := range fns {
+			if nil != fn {
+				fn(reply)
+			}
+		}
+	}
+}
+
+func uintPtr(x uint) *uint { return &x }
+
+// TxnEventLimitReplyFn returns a ConnectReply function that simulates the
+// collector limiting the number of transaction events stored per harvest.
+func TxnEventLimitReplyFn(limit uint) func(*internal.ConnectReply) {
+	return func(reply *internal.ConnectReply) {
+		reply.EventData.Limits.TxnEvents = uintPtr(limit)
+	}
+}
+
+// CustomEventLimitReplyFn returns a ConnectReply function that simulates the
+// collector limiting the number of custom events stored per harvest.
+func CustomEventLimitReplyFn(limit uint) func(*internal.ConnectReply) {
+	return func(reply *internal.ConnectReply) {
+		reply.EventData.Limits.CustomEvents = uintPtr(limit)
+	}
+}
+
+// LogEventLimitReplyFn returns a ConnectReply function that simulates the
+// collector limiting the number of log events stored per harvest.
+func LogEventLimitReplyFn(limit uint) func(*internal.ConnectReply) {
+	return func(reply *internal.ConnectReply) {
+		reply.EventData.Limits.LogEvents = uintPtr(limit)
+	}
+}
+
+// ErrorEventLimitReplyFn returns a ConnectReply function that simulates the
+// collector limiting the number of error events stored per harvest.
+func ErrorEventLimitReplyFn(limit uint) func(*internal.ConnectReply) {
+	return func(reply *internal.ConnectReply) {
+		reply.EventData.Limits.ErrorEvents = uintPtr(limit)
+	}
+}
+
+// SpanEventLimitReplyFn returns a ConnectReply function that simulates the
+// collector limiting the number of span events stored per harvest.  The
+// limit only applies when distributed tracing is enabled.
+func SpanEventLimitReplyFn(limit uint) func(*internal.ConnectReply) {
+	return func(reply *internal.ConnectReply) {
+		reply.SpanEventHarvestConfig.HarvestLimit = uintPtr(limit)
+	}
+}
+
+// MaxPayloadSizeReplyFn returns a ConnectReply function that simulates the
+// collector limiting the size of compressed harvest payloads.
+func MaxPayloadSizeReplyFn(bytes int) func(*internal.ConnectReply) {
+	return func(reply *internal.ConnectReply) {
+		reply.MaxPayloadSizeInBytes = bytes
+	}
+}
+
+func intPtr(x int) *int { return &x }
+
+// AttributeValueLengthLimitReplyFn returns a ConnectReply function that
+// simulates the collector truncating string attribute values to the given
+// number of bytes.  It overrides Config.AttributeLimits.ValueLength.
+func AttributeValueLengthLimitReplyFn(limit int) func(*internal.ConnectReply) {
+	return func(reply *internal.ConnectReply) {
+		reply.ServerSideConfig.AttributeValueLengthLimit = intPtr(limit)
+	}
+}
+
+// SpanAttributeLimitReplyFn returns a ConnectReply function that simulates
+// the collector limiting the number of distinct values of each custom span
+// attribute per harvest.  It overrides Config.SpanEvents.MaxAttributeValues.
+func SpanAttributeLimitReplyFn(limit int) func(*internal.ConnectReply) {
+	return func(reply *internal.ConnectReply) {
+		reply.ServerSideConfig.SpanEventsMaxAttributeValues = intPtr(limit)
+	}
+}
+
+// CollectNothingReplyFn is a ConnectReply function that simulates the
+// collector disabling collection of all events, traces, and errors.
+var CollectNothingReplyFn = func(reply *internal.ConnectReply) {
+	reply.CollectAnalyticsEvents = false
+	reply.CollectCustomEvents = false
+	reply.CollectTraces = false
+	reply.CollectErrors = false
+	reply.CollectErrorEvents = false
+	reply.CollectSpanEvents = false
+}
@@ -100,3 +100,107 @@ func TestConcurrentCalls(t *testing.T) {
 	go addAttr()
 	wg.Wait()
 }
+
+func TestReplyFnsEventLimits(t *testing.T) {
+	app := NewTestApp(ReplyFns(
+		SampleEverythingReplyFn,
+		CustomEventLimitReplyFn(1),
+		TxnEventLimitReplyFn(2),
+		nil,
+	), BasicConfigFn)
+
+	txnIntrinsics := map[string]interface{}{
+		"name":     "OtherTransaction/Go/hello",
+		"guid":     internal.MatchAnything,
+		"traceId":  internal.MatchAnything,
+		"priority": internal.MatchAnything,
+		"sampled":  internal.MatchAnything,
+	}
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": 1})
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": 2})
+	for i := 0; i < 3; i++ {
+		app.StartTransaction("hello").End()
+	}
+
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "myEvent",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"zip": internal.MatchAnything,
+		},
+	}})
+	app.ExpectTxnEvents(t, []internal.WantEvent{
+		{Intrinsics: txnIntrinsics},
+		{Intrinsics: txnIntrinsics},
+	})
+}
+
+func TestSpanEventLimitReplyFn(t *testing.T) {
+	app := NewTestApp(ReplyFns(SampleEverythingReplyFn, SpanEventLimitReplyFn(0)), DTEnabledCfgFn)
+	txn := app.StartTransaction("hello")
+	txn.StartSegment("mySegment").End()
+	txn.End()
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{})
+}
+
+func TestAttributeValueLengthLimitReplyFn(t *testing.T) {
+	app := NewTestApp(ReplyFns(SampleEverythingReplyFn, AttributeValueLengthLimitReplyFn(3)), BasicConfigFn)
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": "abcdef"})
+
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "myEvent",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"zip": "abc",
+		},
+	}})
+}
+
+func TestSpanAttributeLimitReplyFn(t *testing.T) {
+	app := NewTestApp(ReplyFns(SampleEverythingReplyFn, SpanAttributeLimitReplyFn(1)), DTEnabledCfgFn)
+	txn := app.StartTransaction("hello")
+	for _, id := range []string{"first", "second"} {
+		seg := txn.StartSegment("mySegment")
+		seg.AddAttribute("id", id)
+		seg.End()
+	}
+	txn.End()
+
+	spanIntrinsics := map[string]interface{}{
+		"name":     "Custom/mySegment",
+		"parentId": internal.MatchAnything,
+		"category": "generic",
+	}
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics:     spanIntrinsics,
+			UserAttributes: map[string]interface{}{"id": "first"},
+		},
+		{
+			Intrinsics:     spanIntrinsics,
+			UserAttributes: map[string]interface{}{"id": "[OVERFLOW]"},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+		},
+	})
+}
+
+func TestCollectNothingReplyFn(t *testing.T) {
+	app := NewTestApp(CollectNothingReplyFn, BasicConfigFn)
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": 1})
+	app.StartTransaction("hello").End()
+
+	app.ExpectCustomEvents(t, []internal.WantEvent{})
+	app.ExpectTxnEvents(t, []internal.WantEvent{})
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package integrationsupport
+
+import (
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+// AddRPCAttributes adds the agent attributes describing a remote procedure
+// call to the transaction.
+func AddRPCAttributes(txn *newrelic.Transaction, system, service, method string) {
+	AddAgentAttribute(txn, newrelic.AttributeRPCSystem, system, nil)
+	AddAgentAttribute(txn, newrelic.AttributeRPCService, service, nil)
+	AddAgentAttribute(txn, newrelic.AttributeRPCMethod, method, nil)
+}
+
+// RPCErrorExpected returns true if a remote procedure call which failed with
+// the error code provided, in the form used by Twirp and Connect, eg.
+// "not_found", should be noticed as an expected error.  Codes caused by the
+// caller are expected, while codes indicating a problem with the server are
+// not.
+func RPCErrorExpected(code string) bool {
+	switch code {
+	case "unknown", "unimplemented", "internal", "unavailable", "dataloss", "data_loss":
+		return false
+	}
+	return true
+}
+
+// NoticeRPCError notices the failure of a remote procedure call with the
+// error code provided as either an expected or an unexpected error, and adds
+// the code as an agent attribute.
+func NoticeRPCError(txn *newrelic.Transaction, class, code, message string) {
+	if nil == txn {
+		return
+	}
+	AddAgentAttribute(txn, newrelic.AttributeRPCErrorCode, code, nil)
+	err := &newrelic.Error{
+		Message: message,
+		Class:   class,
+	}
+	if RPCErrorExpected(code) {
+		txn.NoticeExpectedError(err)
+	} else {
+		txn.NoticeError(err)
+	}
+}
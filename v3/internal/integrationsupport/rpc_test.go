@@ -0,0 +1,48 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package integrationsupport
+
+import (
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestRPCErrorExpected(t *testing.T) {
+	for code, expect := range map[string]bool{
+		"not_found":         true,
+		"invalid_argument":  true,
+		"deadline_exceeded": true,
+		"unknown":           false,
+		"internal":          false,
+		"unavailable":       false,
+		"dataloss":          false,
+		"data_loss":         false,
+	} {
+		if expected := RPCErrorExpected(code); expected != expect {
+			t.Errorf("code=%q: expected %t, got %t", code, expect, expected)
+		}
+	}
+}
+
+func TestNoticeRPCError(t *testing.T) {
+	NoticeRPCError(nil, "class", "internal", "msg")
+
+	app := NewTestApp(SampleEverythingReplyFn, BasicConfigFn)
+	txn := app.StartTransaction("hello")
+	NoticeRPCError(txn, "RPC Error: not_found", "not_found", "missing")
+	txn.End()
+	txn = app.StartTransaction("hello")
+	NoticeRPCError(txn, "RPC Error: internal", "internal", "oops")
+	txn.End()
+
+	app.ExpectErrors(t, []internal.WantError{
+		{TxnName: "OtherTransaction/Go/hello", Msg: "missing", Klass: "RPC Error: not_found"},
+		{TxnName: "OtherTransaction/Go/hello", Msg: "oops", Klass: "RPC Error: internal"},
+	})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "ErrorsExpected/all"},
+		{Name: "Errors/all"},
+	})
+}
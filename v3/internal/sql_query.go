@@ -0,0 +1,67 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import (
+	"regexp"
+	"strings"
+)
+
+func extractTable(s string) string {
+	s = extractTableRegex.ReplaceAllString(s, "")
+	if idx := strings.Index(s, "."); idx > 0 {
+		s = s[idx+1:]
+	}
+	return s
+}
+
+var (
+	basicTable        = `[^)(\]\[\}\{\s,;]+`
+	enclosedTable     = `[\[\(\{]` + `\s*` + basicTable + `\s*` + `[\]\)\}]`
+	tablePattern      = `(` + `\s+` + basicTable + `|` + `\s*` + enclosedTable + `)`
+	extractTableRegex = regexp.MustCompile(`[\s` + "`" + `"'\(\)\{\}\[\]]*`)
+	updateRegex       = regexp.MustCompile(`(?is)^update(?:\s+(?:low_priority|ignore|or|rollback|abort|replace|fail|only))*` + tablePattern)
+	sqlOperations     = map[string]*regexp.Regexp{
+		"select":   regexp.MustCompile(`(?is)^.*\sfrom` + tablePattern),
+		"delete":   regexp.MustCompile(`(?is)^.*\sfrom` + tablePattern),
+		"insert":   regexp.MustCompile(`(?is)^.*\sinto?` + tablePattern),
+		"update":   updateRegex,
+		"copy":     regexp.MustCompile(`(?is)^copy` + tablePattern),
+		"call":     nil,
+		"create":   nil,
+		"drop":     nil,
+		"show":     nil,
+		"set":      nil,
+		"exec":     nil,
+		"execute":  nil,
+		"alter":    nil,
+		"commit":   nil,
+		"rollback": nil,
+	}
+	firstWordRegex   = regexp.MustCompile(`^\w+`)
+	cCommentRegex    = regexp.MustCompile(`(?is)/\*.*?\*/`)
+	lineCommentRegex = regexp.MustCompile(`(?im)(?:--|#).*?$`)
+	sqlPrefixRegex   = regexp.MustCompile(`^[\s;]*`)
+)
+
+// ParseSQL returns the lowercase operation, eg. "select", and the first table
+// of a SQL query.  Both are empty if the operation is not recognized.
+// matched reports whether the query's table clause was found, in which case
+// the table may still be empty if no name could be extracted from it.
+func ParseSQL(query string) (operation, table string, matched bool) {
+	s := cCommentRegex.ReplaceAllString(query, "")
+	s = lineCommentRegex.ReplaceAllString(s, "")
+	s = sqlPrefixRegex.ReplaceAllString(s, "")
+	op := strings.ToLower(firstWordRegex.FindString(s))
+	rg, ok := sqlOperations[op]
+	if !ok {
+		return "", "", false
+	}
+	if nil != rg {
+		if m := rg.FindStringSubmatch(s); len(m) > 1 {
+			return op, extractTable(m[1]), true
+		}
+	}
+	return op, "", false
+}
@@ -0,0 +1,46 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package internal
+
+import "testing"
+
+func TestExtractTable(t *testing.T) {
+	for idx, tc := range []string{
+		"table",
+		"`table`",
+		`"table"`,
+		"`database.table`",
+		"`database`.table",
+		"database.`table`",
+		"`database`.`table`",
+		"  { table }",
+		"\n[table]",
+		"\t    ( 'database'.`table`  ) ",
+	} {
+		table := extractTable(tc)
+		if table != "table" {
+			t.Error(idx, table)
+		}
+	}
+}
+
+func TestParseSQL(t *testing.T) {
+	for _, tc := range []struct {
+		query, operation, table string
+	}{
+		{query: "SELECT * FROM users WHERE id = ?", operation: "select", table: "users"},
+		{query: "/* comment */ insert into `db`.`orders` (id) values (?)", operation: "insert", table: "orders"},
+		{query: "UPDATE accounts SET x = ?", operation: "update", table: "accounts"},
+		{query: "create table things (id int)", operation: "create", table: ""},
+		{query: "COPY public.events (id, name) FROM STDIN", operation: "copy", table: "events"},
+		{query: "COPY (SELECT * FROM events) TO STDOUT", operation: "copy", table: ""},
+		{query: "VACUUM", operation: "", table: ""},
+		{query: "", operation: "", table: ""},
+	} {
+		op, table, _ := ParseSQL(tc.query)
+		if op != tc.operation || table != tc.table {
+			t.Errorf("query=%q got operation=%q table=%q", tc.query, op, table)
+		}
+	}
+}
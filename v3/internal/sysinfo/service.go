@@ -0,0 +1,10 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sysinfo
+
+// ServiceName returns the name of the Windows service running the process, or
+// the empty string if the process is not run as a service.
+func ServiceName() (string, error) {
+	return getServiceName()
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sysinfo
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                  = syscall.NewLazyDLL("advapi32.dll")
+	procOpenSCManagerW        = advapi32.NewProc("OpenSCManagerW")
+	procEnumServicesStatusExW = advapi32.NewProc("EnumServicesStatusExW")
+	procCloseServiceHandle    = advapi32.NewProc("CloseServiceHandle")
+)
+
+const (
+	scManagerEnumerateService = 0x0004
+	scEnumProcessInfo         = 0
+	serviceWin32              = 0x00000030
+	serviceActive             = 0x00000001
+	errorMoreData             = syscall.Errno(234)
+)
+
+// enumServiceStatusProcess is ENUM_SERVICE_STATUS_PROCESSW.
+type enumServiceStatusProcess struct {
+	serviceName             *uint16
+	displayName             *uint16
+	serviceType             uint32
+	currentState            uint32
+	controlsAccepted        uint32
+	win32ExitCode           uint32
+	serviceSpecificExitCode uint32
+	checkPoint              uint32
+	waitHint                uint32
+	processID               uint32
+	serviceFlags            uint32
+}
+
+func getServiceName() (string, error) {
+	scm, _, err := procOpenSCManagerW.Call(0, 0, scManagerEnumerateService)
+	if 0 == scm {
+		return "", err
+	}
+	defer procCloseServiceHandle.Call(scm)
+
+	// The first call returns the size of the buffer needed.  The services
+	// may change between calls, so retry while the buffer is too small.
+	var needed, count, resume uint32
+	buf := make([]byte, 1)
+	for {
+		ret, _, err := procEnumServicesStatusExW.Call(
+			scm,
+			scEnumProcessInfo,
+			serviceWin32,
+			serviceActive,
+			uintptr(unsafe.Pointer(&buf[0])),
+			uintptr(len(buf)),
+			uintptr(unsafe.Pointer(&needed)),
+			uintptr(unsafe.Pointer(&count)),
+			uintptr(unsafe.Pointer(&resume)),
+			0,
+		)
+		if 0 != ret {
+			break
+		}
+		if err != errorMoreData {
+			return "", err
+		}
+		buf = make([]byte, needed)
+		resume = 0
+	}
+	if 0 == count {
+		return "", nil
+	}
+
+	pid := uint32(os.Getpid())
+	services := (*[1 << 20]enumServiceStatusProcess)(unsafe.Pointer(&buf[0]))[:count:count]
+	for _, s := range services {
+		if s.processID == pid {
+			return utf16PtrToString(s.serviceName), nil
+		}
+	}
+	return "", nil
+}
+
+func utf16PtrToString(p *uint16) string {
+	if nil == p {
+		return ""
+	}
+	var s []uint16
+	for ptr := unsafe.Pointer(p); ; ptr = unsafe.Pointer(uintptr(ptr) + 2) {
+		c := *(*uint16)(ptr)
+		if 0 == c {
+			break
+		}
+		s = append(s, c)
+	}
+	return syscall.UTF16ToString(s)
+}
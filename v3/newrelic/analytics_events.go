@@ -12,22 +12,44 @@ import (
 
 type analyticsEvent struct {
 	priority priority
+	// rank is compared before priority: events of a lower rank are
+	// discarded first.  It is the error severity for error events and zero
+	// for all other events.
+	rank int
 	jsonWriter
 }
 
+func (e analyticsEvent) isLowerPriority(other analyticsEvent) bool {
+	if e.rank != other.rank {
+		return e.rank < other.rank
+	}
+	return e.priority.isLowerPriority(other.priority)
+}
+
 type analyticsEventHeap []analyticsEvent
 
 type analyticsEvents struct {
 	numSeen        int
 	failedHarvests int
 	events         analyticsEventHeap
+	// keepDropped is set for custom events by Config.OnDataDropped: the
+	// events sampled out are then kept in dropped until the harvest, up
+	// to droppedDataLimit.
+	keepDropped bool
+	dropped     []analyticsEvent
+	// memoryLimitDropped holds the events dropped by Config.MemoryLimit
+	// when keepDropped is set, up to droppedDataLimit.
+	memoryLimitDropped []analyticsEvent
+	// droppedDiscarded counts the events sampled out or dropped by
+	// Config.MemoryLimit which were not kept because of droppedDataLimit.
+	droppedDiscarded int
 }
 
 func (events *analyticsEvents) NumSeen() float64  { return float64(events.numSeen) }
 func (events *analyticsEvents) NumSaved() float64 { return float64(len(events.events)) }
 
 func (h analyticsEventHeap) Len() int           { return len(h) }
-func (h analyticsEventHeap) Less(i, j int) bool { return h[i].priority.isLowerPriority(h[j].priority) }
+func (h analyticsEventHeap) Less(i, j int) bool { return h[i].isLowerPriority(h[j]) }
 func (h analyticsEventHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
 
 // Push and Pop are unused: only heap.Init and heap.Fix are used.
@@ -51,6 +73,7 @@ func (events *analyticsEvents) addEvent(e analyticsEvent) {
 
 	if events.capacity() == 0 {
 		// Configurable event harvest limits may be zero.
+		events.drop(e)
 		return
 	}
 
@@ -65,10 +88,12 @@ func (events *analyticsEvents) addEvent(e analyticsEvent) {
 		return
 	}
 
-	if e.priority.isLowerPriority((events.events)[0].priority) {
+	if e.isLowerPriority((events.events)[0]) {
+		events.drop(e)
 		return
 	}
 
+	events.drop(events.events[0])
 	events.events[0] = e
 	heap.Fix(events.events, 0)
 }
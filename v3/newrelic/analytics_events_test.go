@@ -24,8 +24,8 @@ func (x priorityWriter) WriteJSON(buf *bytes.Buffer) {
 
 func sampleAnalyticsEvent(priority priority) analyticsEvent {
 	return analyticsEvent{
-		priority,
-		priorityWriter(priority),
+		priority:   priority,
+		jsonWriter: priorityWriter(priority),
 	}
 }
 
@@ -212,7 +212,7 @@ func TestAnalyticsEventMergeFailedLimitReached(t *testing.T) {
 
 func analyticsEventBenchmarkHelper(b *testing.B, w jsonWriter) {
 	events := newAnalyticsEvents(internal.MaxTxnEvents)
-	event := analyticsEvent{0, w}
+	event := analyticsEvent{priority: 0, jsonWriter: w}
 	for n := 0; n < internal.MaxTxnEvents; n++ {
 		events.addEvent(event)
 	}
@@ -246,7 +246,7 @@ func BenchmarkCustomEventsCollectorJSON(b *testing.B) {
 		"string": "myString",
 		"bool":   true,
 		"int64":  int64(123),
-	}, now)
+	}, now, defaultAttributeLimits)
 	if nil != err {
 		b.Fatal(err)
 	}
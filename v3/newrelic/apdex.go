@@ -37,6 +37,32 @@ func calculateApdexZone(threshold, duration time.Duration) apdexZone {
 	return apdexFailing
 }
 
+// ApdexZone is an Apdex classification, see Config.Apdex.ResponseCodeZones.
+type ApdexZone string
+
+const (
+	// ApdexSatisfying classifies a transaction as satisfying.
+	ApdexSatisfying ApdexZone = "S"
+	// ApdexTolerating classifies a transaction as tolerating.
+	ApdexTolerating ApdexZone = "T"
+	// ApdexFrustrating classifies a transaction as frustrating.
+	ApdexFrustrating ApdexZone = "F"
+)
+
+// zone returns apdexNone if the ApdexZone is invalid.
+func (z ApdexZone) zone() apdexZone {
+	switch z {
+	case ApdexSatisfying:
+		return apdexSatisfying
+	case ApdexTolerating:
+		return apdexTolerating
+	case ApdexFrustrating:
+		return apdexFailing
+	default:
+		return apdexNone
+	}
+}
+
 func (zone apdexZone) label() string {
 	switch zone {
 	case apdexSatisfying:
@@ -5,6 +5,7 @@ package newrelic
 
 import (
 	"encoding/json"
+	"math"
 	"strings"
 	"time"
 
@@ -35,6 +36,10 @@ type appRun struct {
 	// flexible harvest periods.  This field is created once at appRun
 	// creation.
 	harvestConfig harvestConfig
+
+	// payloadEncoder is Config.Compression.Encoder if New Relic supports
+	// its content encoding, and nil otherwise.
+	payloadEncoder PayloadEncoder
 }
 
 const (
@@ -43,43 +48,22 @@ const (
 
 func newAppRun(config config, reply *internal.ConnectReply) *appRun {
 	run := &appRun{
-		Reply:           reply,
-		AttributeConfig: createAttributeConfig(config, reply.SecurityPolicies.AttributesInclude.Enabled()),
-		Config:          config,
-		rulesCache:      newRulesCache(txnNameCacheLimit),
+		Reply:      reply,
+		Config:     config,
+		rulesCache: newRulesCache(txnNameCacheLimit),
 	}
 
 	// Overwrite local settings with any server-side-config settings
 	// present. NOTE!  This requires that the Config provided to this
 	// function is a value and not a pointer: We do not want to change the
 	// input Config with values particular to this connection.
-
-	if v := run.Reply.ServerSideConfig.TransactionTracerEnabled; nil != v {
-		run.Config.TransactionTracer.Enabled = *v
-	}
-	if v := run.Reply.ServerSideConfig.ErrorCollectorEnabled; nil != v {
-		run.Config.ErrorCollector.Enabled = *v
-	}
-	if v := run.Reply.ServerSideConfig.CrossApplicationTracerEnabled; nil != v {
-		run.Config.CrossApplicationTracer.Enabled = *v
-	}
-	if v := run.Reply.ServerSideConfig.TransactionTracerThreshold; nil != v {
-		switch val := v.(type) {
-		case float64:
-			run.Config.TransactionTracer.Threshold.IsApdexFailing = false
-			run.Config.TransactionTracer.Threshold.Duration = internal.FloatSecondsToDuration(val)
-		case string:
-			if val == "apdex_f" {
-				run.Config.TransactionTracer.Threshold.IsApdexFailing = true
-			}
-		}
-	}
-	if v := run.Reply.ServerSideConfig.TransactionTracerStackTraceThreshold; nil != v {
-		run.Config.TransactionTracer.Segments.StackTraceThreshold = internal.FloatSecondsToDuration(*v)
-	}
-	if v := run.Reply.ServerSideConfig.ErrorCollectorIgnoreStatusCodes; nil != v {
-		run.Config.ErrorCollector.IgnoreStatusCodes = v
+	run.applyServerSideConfig(&run.Reply.ServerSideConfig, "server-side")
+	if nil != config.remoteConfig {
+		run.applyServerSideConfig(config.remoteConfig, "remote")
 	}
+	// The attribute config is created after the server-side-config
+	// settings are applied since they may change the attribute limits.
+	run.AttributeConfig = createAttributeConfig(run.Config, reply.SecurityPolicies.AttributesInclude.Enabled())
 
 	if !run.Reply.CollectErrorEvents {
 		run.Config.ErrorCollector.CaptureEvents = false
@@ -97,7 +81,9 @@ func newAppRun(config config, reply *internal.ConnectReply) *appRun {
 
 	// Distributed tracing takes priority over cross-app-tracing per:
 	// https://source.datanerd.us/agents/agent-specs/blob/master/Distributed-Tracing.md#distributed-trace-payload
-	if run.Config.DistributedTracer.Enabled {
+	// Read-only cross-app-tracing never creates headers, and so may be used
+	// alongside distributed tracing.
+	if run.Config.DistributedTracer.Enabled && !run.Config.CrossApplicationTracer.ReadOnly {
 		run.Config.CrossApplicationTracer.Enabled = false
 	}
 
@@ -107,7 +93,7 @@ func newAppRun(config config, reply *internal.ConnectReply) *appRun {
 	run.adaptiveSampler = newAdaptiveSampler(
 		time.Duration(reply.SamplingTargetPeriodInSeconds)*time.Second,
 		reply.SamplingTarget,
-		time.Now())
+		run.Config.Clock.Now())
 
 	if run.Reply.RunID != "" {
 		js, _ := json.Marshal(settings(run.Config.Config))
@@ -123,11 +109,101 @@ func newAppRun(config config, reply *internal.ConnectReply) *appRun {
 		MaxErrorEvents:  run.MaxErrorEvents(),
 		MaxSpanEvents:   run.MaxSpanEvents(),
 		LoggingConfig:   run.LoggingConfig(),
+
+		MaxErrorEventsPerClass: run.MaxErrorEventsPerClass(),
+		SlowQueriesByInstance:  run.Config.DatastoreTracer.SlowQuery.AggregateByInstance,
+		MaxSpanAttributeValues: run.Config.SpanEvents.MaxAttributeValues,
+		FailedMetrics: failedMetricLimits{
+			maxMetrics: run.Config.Harvest.FailedMetrics.MaxMetrics,
+			maxAge:     run.Config.Harvest.FailedMetrics.MaxAge,
+		},
+		MemoryLimit: run.Config.MemoryLimit,
+
+		KeepDroppedCustomEvents: nil != run.Config.OnDataDropped,
 	}
+	run.payloadEncoder = negotiatePayloadEncoder(run.Config.Compression.Encoder, run.Reply.ContentEncodings)
 
 	return run
 }
 
+// applyServerSideConfig overwrites the settings of the run's Config with those
+// present in the settings from New Relic or from Config.RemoteConfig,
+// identified by source.
+func (run *appRun) applyServerSideConfig(ssc *internal.ServerSideConfig, source string) {
+	if v := ssc.TransactionTracerEnabled; nil != v {
+		run.Config.TransactionTracer.Enabled = *v
+	}
+	if v := ssc.ErrorCollectorEnabled; nil != v {
+		run.Config.ErrorCollector.Enabled = *v
+	}
+	if v := ssc.CrossApplicationTracerEnabled; nil != v {
+		run.Config.CrossApplicationTracer.Enabled = *v
+	}
+	if v := ssc.TransactionTracerThreshold; nil != v {
+		switch val := v.(type) {
+		case float64:
+			run.Config.TransactionTracer.Threshold.IsApdexFailing = false
+			run.Config.TransactionTracer.Threshold.Duration = internal.FloatSecondsToDuration(val)
+		case string:
+			if val == "apdex_f" {
+				run.Config.TransactionTracer.Threshold.IsApdexFailing = true
+			}
+		}
+	}
+	if v := ssc.TransactionTracerStackTraceThreshold; nil != v {
+		run.Config.TransactionTracer.Segments.StackTraceThreshold = internal.FloatSecondsToDuration(*v)
+	}
+	if v := ssc.ErrorCollectorIgnoreStatusCodes; nil != v {
+		run.Config.ErrorCollector.IgnoreStatusCodes = v
+	}
+	if v := ssc.ErrorCollectorCaptureEvents; nil != v {
+		run.Config.ErrorCollector.CaptureEvents = *v
+	}
+	if v := ssc.TransactionTracerExplainThreshold; nil != v {
+		run.Config.DatastoreTracer.SlowQuery.Threshold = internal.FloatSecondsToDuration(*v)
+	}
+	if v := ssc.SlowSQLEnabled; nil != v {
+		run.Config.DatastoreTracer.SlowQuery.Enabled = *v
+	}
+	if v := ssc.TransactionEventsEnabled; nil != v {
+		run.Config.TransactionEvents.Enabled = *v
+	}
+	if v := ssc.SpanEventsEnabled; nil != v {
+		run.Config.SpanEvents.Enabled = *v
+	}
+	if v := ssc.AttributeValueLengthLimit; nil != v {
+		if *v <= 0 || *v > maxAttributeValueLengthLimit {
+			run.Config.Logger.Warn("ignoring "+source+" attributes.value_length_limit", map[string]interface{}{
+				"value": *v,
+			})
+		} else {
+			run.Config.AttributeLimits.ValueLength = *v
+		}
+	}
+	if v := ssc.SpanEventsMaxAttributeValues; nil != v {
+		run.Config.SpanEvents.MaxAttributeValues = *v
+	}
+	ignoreClasses := ssc.ErrorCollectorIgnoreClasses
+	ignoreMessages := ssc.ErrorCollectorIgnoreMessages
+	if nil != ignoreMessages {
+		if err := validateIgnoreMessages(ignoreMessages); nil != err {
+			run.Config.Logger.Warn("ignoring "+source+" error_collector.ignore_messages", map[string]interface{}{
+				"reason": err.Error(),
+			})
+			ignoreMessages = nil
+		}
+	}
+	if nil != ignoreClasses || nil != ignoreMessages {
+		if nil != ignoreClasses {
+			run.Config.ErrorCollector.IgnoreClasses = ignoreClasses
+		}
+		if nil != ignoreMessages {
+			run.Config.ErrorCollector.IgnoreMessages = ignoreMessages
+		}
+		run.Config.errorIgnorer = newErrorIgnorer(run.Config.ErrorCollector.IgnoreClasses, run.Config.ErrorCollector.IgnoreMessages)
+	}
+}
+
 func newPlaceholderAppRun(config config) *appRun {
 	reply := internal.ConnectReplyDefaults()
 	// Do no sampling if the app isn't connected:
@@ -203,6 +279,26 @@ func (run *appRun) MaxErrorEvents() int {
 	return run.limit(internal.MaxErrorEvents, run.ptrErrorEvents)
 }
 
+// MaxErrorEventsPerClass converts Config.ErrorCollector.MaxEventsPerClass,
+// which is a per minute limit, into a limit for each error event harvest.
+func (run *appRun) MaxErrorEventsPerClass() int {
+	perMinute := run.Config.ErrorCollector.MaxEventsPerClass
+	if perMinute <= 0 {
+		return 0
+	}
+	period := fixedHarvestPeriod
+	for tp, p := range run.ReportPeriods() {
+		if tp&harvestErrorEvents != 0 {
+			period = p
+		}
+	}
+	limit := int(math.Ceil(float64(perMinute) * period.Seconds() / time.Minute.Seconds()))
+	if limit < 1 {
+		limit = 1
+	}
+	return limit
+}
+
 func (run *appRun) LoggingConfig() (config loggingConfig) {
 	logging := run.Config.ApplicationLogging
 
@@ -211,6 +307,7 @@ func (run *appRun) LoggingConfig() (config loggingConfig) {
 	config.maxLogEvents = run.MaxLogEvents()
 	config.collectMetrics = logging.Enabled && logging.Metrics.Enabled
 	config.localEnrichment = logging.Enabled && logging.LocalDecorating.Enabled
+	config.keepDropped = nil != run.Config.OnDataDropped
 
 	return config
 }
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/logger"
 )
 
 func TestResponseCodeIsError(t *testing.T) {
@@ -123,6 +124,70 @@ func TestTxnTraceThreshold(t *testing.T) {
 	}
 }
 
+func TestServerSideConfig(t *testing.T) {
+	cfg := config{Config: defaultConfig()}
+	cfg.ErrorCollector.IgnoreClasses = []string{"local"}
+	reply := internal.ConnectReplyDefaults()
+	err := json.Unmarshal([]byte(`{"agent_config":{
+		"transaction_tracer.explain_threshold":0.25,
+		"transaction_events.enabled":false,
+		"slow_sql.enabled":false,
+		"span_events.enabled":false,
+		"error_collector.capture_events":false,
+		"error_collector.ignore_classes":["remote"],
+		"error_collector.ignore_messages":{"*errors.errorString":["^context canceled$"]}
+	}}`), &reply)
+	if nil != err {
+		t.Fatal(err)
+	}
+	run := newAppRun(cfg, reply)
+	if run.Config.DatastoreTracer.SlowQuery.Threshold != 250*time.Millisecond {
+		t.Error(run.Config.DatastoreTracer.SlowQuery.Threshold)
+	}
+	if run.Config.TransactionEvents.Enabled {
+		t.Error("transaction events should be disabled")
+	}
+	if run.Config.DatastoreTracer.SlowQuery.Enabled {
+		t.Error("slow queries should be disabled")
+	}
+	if run.Config.SpanEvents.Enabled {
+		t.Error("span events should be disabled")
+	}
+	if run.Config.ErrorCollector.CaptureEvents {
+		t.Error("error events should be disabled")
+	}
+	if run.Config.errorIgnorer.ignored("local", "") {
+		t.Error("local ignore classes should be replaced")
+	}
+	if !run.Config.errorIgnorer.ignored("remote", "") {
+		t.Error("remote class should be ignored")
+	}
+	if !run.Config.errorIgnorer.ignored("*errors.errorString", "context canceled") {
+		t.Error("remote message should be ignored")
+	}
+	// The input config must not be changed.
+	if !cfg.TransactionEvents.Enabled || !reflect.DeepEqual(cfg.ErrorCollector.IgnoreClasses, []string{"local"}) {
+		t.Error(cfg.TransactionEvents.Enabled, cfg.ErrorCollector.IgnoreClasses)
+	}
+}
+
+func TestServerSideConfigInvalidIgnoreMessages(t *testing.T) {
+	cfg := config{Config: defaultConfig()}
+	cfg.Logger = logger.ShimLogger{}
+	cfg.ErrorCollector.IgnoreMessages = map[string][]string{"local": {"^zap$"}}
+	reply := internal.ConnectReplyDefaults()
+	err := json.Unmarshal([]byte(`{"agent_config":{
+		"error_collector.ignore_messages":{"remote":["("]}
+	}}`), &reply)
+	if nil != err {
+		t.Fatal(err)
+	}
+	run := newAppRun(cfg, reply)
+	if !reflect.DeepEqual(run.Config.ErrorCollector.IgnoreMessages, cfg.ErrorCollector.IgnoreMessages) {
+		t.Error(run.Config.ErrorCollector.IgnoreMessages)
+	}
+}
+
 func TestEmptyReplyEventHarvestDefaults(t *testing.T) {
 	run := newAppRun(config{Config: defaultConfig()}, &internal.ConnectReply{})
 	assertHarvestConfig(t, run.harvestConfig, expectHarvestConfig{
@@ -465,3 +530,36 @@ func TestCreateTransactionName(t *testing.T) {
 		t.Error("wanted:", want, "got:", out)
 	}
 }
+
+func TestMaxErrorEventsPerClass(t *testing.T) {
+	cfg := defaultConfig()
+	run := newAppRun(config{Config: cfg}, internal.ConnectReplyDefaults())
+	if n := run.harvestConfig.MaxErrorEventsPerClass; n != 0 {
+		t.Error(n)
+	}
+
+	cfg.ErrorCollector.MaxEventsPerClass = 30
+	run = newAppRun(config{Config: cfg}, internal.ConnectReplyDefaults())
+	if n := run.harvestConfig.MaxErrorEventsPerClass; n != 30 {
+		t.Error(n)
+	}
+
+	// The per minute limit is scaled to the error event harvest period.
+	reply, err := internal.UnmarshalConnectReply([]byte(`{"return_value":{
+			"event_harvest_config": {
+				"report_period_ms": 5000,
+				"harvest_limits": { "error_event_data": 3 }
+			}}}`), internal.PreconnectReply{})
+	if nil != err {
+		t.Fatal(err)
+	}
+	run = newAppRun(config{Config: cfg}, reply)
+	if n := run.harvestConfig.MaxErrorEventsPerClass; n != 3 {
+		t.Error(n)
+	}
+	cfg.ErrorCollector.MaxEventsPerClass = 1
+	run = newAppRun(config{Config: cfg}, reply)
+	if n := run.harvestConfig.MaxErrorEventsPerClass; n != 1 {
+		t.Error(n)
+	}
+}
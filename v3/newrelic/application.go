@@ -4,6 +4,7 @@
 package newrelic
 
 import (
+	"context"
 	"os"
 	"time"
 )
@@ -28,7 +29,8 @@ func (app *Application) StartTransaction(name string, opts ...TraceOption) *Tran
 // eventType must consist of alphanumeric characters, underscores, and
 // colons, and must contain fewer than 255 bytes.
 //
-// Each value in the params map must be a number, string, or boolean.
+// Each value in the params map must be a number, string, or boolean, or a
+// slice or map as described in Transaction.AddAttribute.
 // Keys must be less than 255 bytes.  The params map may not contain
 // more than 64 attributes.  For more information, and a set of
 // restricted keywords, see:
@@ -51,6 +53,66 @@ func (app *Application) RecordCustomEvent(eventType string, params map[string]in
 	}
 }
 
+// RecordCustomEvents adds a batch of custom events.  It is more efficient
+// than calling RecordCustomEvent once per event and reports how many of the
+// events were accepted and how many were dropped.
+//
+// Events are dropped when they are invalid (see RecordCustomEvent for the
+// rules that apply to each event), when custom events are disabled, or when
+// the custom event reservoir for the current harvest cycle is already full.
+// A non-zero dropped count caused by a full reservoir is a signal that the
+// caller is producing events faster than they can be reported and should
+// consider submitting fewer events or retrying them after the next harvest.
+//
+// An error is logged if any of the events are invalid.
+func (app *Application) RecordCustomEvents(events []CustomEventData) (accepted int, dropped int) {
+	if nil == app {
+		return 0, len(events)
+	}
+	if nil == app.app {
+		return 0, len(events)
+	}
+	accepted, dropped, err := app.app.RecordCustomEvents(events)
+	if err != nil {
+		app.app.Error("unable to record custom events", map[string]interface{}{
+			"dropped": dropped,
+			"reason":  err.Error(),
+		})
+	}
+	return accepted, dropped
+}
+
+// NoticeError records an error which occurred outside of any transaction,
+// such as during startup or in a background loop.  The error is recorded as an
+// error event with the application's host context but without transaction
+// details.  Use Transaction.NoticeError for errors that occur within a
+// transaction.
+//
+// The error's message, class, and attributes are determined as they are by
+// Transaction.NoticeError, so newrelic.Error and errors implementing the
+// ErrorClass and ErrorAttributes methods may be used.  Errors matching
+// Config.ErrorCollector.IgnoreClasses or IgnoreMessages are not recorded, and
+// WithErrorExpected records the error as expected.
+//
+// An error is logged if the error could not be recorded.
+func (app *Application) NoticeError(err error, options ...NoticeErrorOption) {
+	if nil == app {
+		return
+	}
+	if nil == app.app {
+		return
+	}
+	var opts noticeErrorOptions
+	for _, o := range options {
+		o(&opts)
+	}
+	if e := app.app.NoticeError(err, opts); nil != e {
+		app.app.Error("unable to notice error", map[string]interface{}{
+			"reason": e.Error(),
+		})
+	}
+}
+
 // RecordCustomMetric records a custom metric.  The metric name you
 // provide will be prefixed by "Custom/".  Custom metrics are not
 // currently supported in serverless mode.
@@ -97,6 +159,37 @@ func (app *Application) RecordLog(logEvent LogData) {
 	}
 }
 
+// ConnectInfo returns the identifiers of the application's connection to New
+// Relic, such as its entity GUID and run ID, and the server-side
+// configuration it received.  ConnectInfo.Connected is false if the
+// application is not connected.
+func (app *Application) ConnectInfo() ConnectInfo {
+	if nil == app {
+		return ConnectInfo{}
+	}
+	if nil == app.app {
+		return ConnectInfo{}
+	}
+	return app.app.ConnectInfo()
+}
+
+// RecordDeployment records a deployment marker for the application using
+// New Relic's change tracking API, so that deploy tooling does not need its
+// own HTTP client or entity lookup.  The revision is required; the changelog
+// and user are optional.  The application must be connected, see
+// WaitForConnection.  Config.Deployments.APIKey must be set to a User API
+// key, unless Config.Deployments.UseLicense is set to authenticate with the
+// license key.  RecordDeployment blocks until the request completes.
+func (app *Application) RecordDeployment(revision, changelog, user string) error {
+	if nil == app {
+		return nil
+	}
+	if nil == app.app {
+		return nil
+	}
+	return app.app.RecordDeployment(revision, changelog, user)
+}
+
 // WaitForConnection blocks until the application is connected, is
 // incapable of being connected, or the timeout has been reached.  This
 // method is useful for short-lived processes since the application will
@@ -122,6 +215,25 @@ func (app *Application) WaitForConnection(timeout time.Duration) error {
 	return app.app.WaitForConnection(timeout)
 }
 
+// WaitForConnectionCtx is like WaitForConnection, but waits until ctx is done
+// rather than for a timeout.  If ctx is done before the application connects,
+// the returned error wraps ctx.Err() and describes the most recent failed
+// connect attempt, such as a DNS failure or an invalid license key.  Use
+// WithConnectAttemptCallback to be told about each attempt as it is made:
+//
+//	err := app.WaitForConnectionCtx(ctx, newrelic.WithConnectAttemptCallback(
+//		func(a newrelic.ConnectAttempt) {
+//			if nil != a.Err {
+//				log.Printf("New Relic connect attempt %d failed (%s): %v", a.Attempt, a.Reason, a.Err)
+//			}
+//		}))
+func (app *Application) WaitForConnectionCtx(ctx context.Context, options ...WaitForConnectionOption) error {
+	if nil == app {
+		return nil
+	}
+	return app.app.WaitForConnectionCtx(ctx, options...)
+}
+
 // Shutdown flushes data to New Relic's servers and stops all
 // agent-related goroutines managing this application.  After Shutdown
 // is called, the Application is disabled and will never collect data
@@ -0,0 +1,170 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/rainforestpay/go-agent/v3/internal/jsonx"
+)
+
+const (
+	// attributeMapDepthLimit is the deepest nesting of maps accepted as an
+	// attribute value.
+	attributeMapDepthLimit = 4
+	// attributeMapEntryLimit is the maximum number of flattened entries in a
+	// map attribute value.
+	attributeMapEntryLimit = attributeUserLimit
+)
+
+// attributeMap is a validated map attribute value.  Event and span formats
+// only allow scalar attribute values, so nested maps are flattened: each entry
+// is sent as its own attribute whose key is the attribute key and the entry's
+// path joined with dots.  For example, the attribute "user" with value
+// map[string]interface{}{"id": 1, "plan": map[string]interface{}{"tier": "pro"}}
+// is sent as "user.id" and "user.plan.tier".
+type attributeMap []attributeMapEntry
+
+type attributeMapEntry struct {
+	// key is the entry's path within the map, excluding the attribute key.
+	key   string
+	value interface{}
+}
+
+type attributeMapDepthErr struct{ key string }
+
+func (e attributeMapDepthErr) Error() string {
+	return fmt.Sprintf("attribute '%s' map value exceeds nesting limit %d",
+		e.key, attributeMapDepthLimit)
+}
+
+type attributeMapEntriesErr struct{ key string }
+
+func (e attributeMapEntriesErr) Error() string {
+	return fmt.Sprintf("attribute '%s' map value exceeds entry limit %d",
+		e.key, attributeMapEntryLimit)
+}
+
+// attributeCount returns the number of attributes a validated value is sent
+// as: one, or the number of flattened entries of a map.
+func attributeCount(val interface{}) int {
+	if m, ok := val.(attributeMap); ok {
+		return len(m)
+	}
+	return 1
+}
+
+// validateAttributeMap validates and flattens a map attribute value.  The
+// flattened keys are checked against the key length limit.
+func (l attributeLimits) validateAttributeMap(key string, m map[string]interface{}) (attributeMap, error) {
+	var out attributeMap
+	if err := l.flattenAttributeMap(key, "", m, 1, &out); nil != err {
+		return nil, err
+	}
+	limit := l.keyLength()
+	for _, e := range out {
+		if len(key)+1+len(e.key) > limit {
+			return nil, invalidAttributeKeyErr{key: key + "." + e.key, limit: limit}
+		}
+	}
+	return out, nil
+}
+
+func (l attributeLimits) flattenAttributeMap(key, prefix string, m map[string]interface{}, depth int, out *attributeMap) error {
+	if depth > attributeMapDepthLimit {
+		return attributeMapDepthErr{key: key}
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// Sort the keys so that the attributes are written in a consistent
+	// order.
+	sort.Strings(keys)
+	for _, k := range keys {
+		path := prefix + k
+		if nested, ok := m[k].(map[string]interface{}); ok {
+			if err := l.flattenAttributeMap(key, path+".", nested, depth+1, out); nil != err {
+				return err
+			}
+			continue
+		}
+		val, err := l.validateAttributeValue(key+"."+path, m[k])
+		if nil != err {
+			return err
+		}
+		if len(*out) >= attributeMapEntryLimit {
+			return attributeMapEntriesErr{key: key}
+		}
+		*out = append(*out, attributeMapEntry{key: path, value: val})
+	}
+	return nil
+}
+
+// sliceAttributeValue encodes a slice attribute value as a JSON array string.
+// Elements are dropped from the end of the array until it fits within the
+// attribute value length limit, so that the value remains valid JSON.
+func (l attributeLimits) sliceAttributeValue(key string, val interface{}) (string, error) {
+	var n int
+	var appendElem func(buf *bytes.Buffer, i int) error
+	switch v := val.(type) {
+	case []string:
+		n = len(v)
+		appendElem = func(buf *bytes.Buffer, i int) error {
+			jsonx.AppendString(buf, v[i])
+			return nil
+		}
+	case []int:
+		n = len(v)
+		appendElem = func(buf *bytes.Buffer, i int) error {
+			jsonx.AppendInt(buf, int64(v[i]))
+			return nil
+		}
+	case []int64:
+		n = len(v)
+		appendElem = func(buf *bytes.Buffer, i int) error {
+			jsonx.AppendInt(buf, v[i])
+			return nil
+		}
+	case []float64:
+		n = len(v)
+		appendElem = func(buf *bytes.Buffer, i int) error {
+			if err := validateFloat(v[i], key); nil != err {
+				return err
+			}
+			return jsonx.AppendFloat(buf, v[i])
+		}
+	case []bool:
+		n = len(v)
+		appendElem = func(buf *bytes.Buffer, i int) error {
+			buf.WriteString(strconv.FormatBool(v[i]))
+			return nil
+		}
+	default:
+		return "", errInvalidAttributeType{key: key, val: val}
+	}
+
+	limit := l.valueLength()
+	buf := &bytes.Buffer{}
+	buf.WriteByte('[')
+	for i := 0; i < n; i++ {
+		mark := buf.Len()
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if err := appendElem(buf, i); nil != err {
+			return "", err
+		}
+		// Leave room for the closing bracket.
+		if buf.Len()+1 > limit {
+			buf.Truncate(mark)
+			break
+		}
+	}
+	buf.WriteByte(']')
+	return buf.String(), nil
+}
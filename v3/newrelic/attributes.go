@@ -52,6 +52,13 @@ const (
 	AttributeCodeFilepath = "code.filepath"
 	// AttributeCodeLineno contains the Code Level Metrics source file line number name.
 	AttributeCodeLineno = "code.lineno"
+	// AttributeRequestCanceled is true if the web request's context was
+	// canceled by the client before the transaction ended.
+	AttributeRequestCanceled = "request.canceled"
+	// AttributeBrowserCorrelationToken is the token created by
+	// Transaction.BrowserCorrelationToken, which is shared with the
+	// Browser agent to tie the transaction to frontend events.
+	AttributeBrowserCorrelationToken = "browser.correlationToken"
 )
 
 // Attributes destined for Errors and Transaction Traces:
@@ -125,6 +132,35 @@ const (
 	AttributeMessageCorrelationID = "message.correlationId"
 )
 
+// Attributes for GraphQL transactions:
+//
+// Transactions handled by the nrgraphgophers and nrgraphqlgo integrations are
+// named after the GraphQL operation, eg. "query/GetUser", and have these
+// attributes added automatically.
+const (
+	// The type of the operation: "query", "mutation", or "subscription".
+	AttributeGraphQLOperationType = "graphql.operation.type"
+	// The name of the operation, or "<anonymous>" if it is unnamed.
+	AttributeGraphQLOperationName = "graphql.operation.name"
+	// The hash identifying the persisted query used, if any.
+	AttributeGraphQLPersistedQueryHash = "graphql.persistedQuery.hash"
+)
+
+// Attributes for remote procedure call transactions:
+//
+// Transactions handled by the nrtwirp and nrconnect integrations have these
+// attributes added automatically.
+const (
+	// The remote procedure call system, eg. "twirp" or "connect_rpc".
+	AttributeRPCSystem = "rpc.system"
+	// The full name of the service called, eg. "pkg.Service".
+	AttributeRPCService = "rpc.service"
+	// The name of the method called.
+	AttributeRPCMethod = "rpc.method"
+	// The error code returned by the call, eg. "not_found", if it failed.
+	AttributeRPCErrorCode = "rpc.errorCode"
+)
+
 // Attributes destined for Span Events. These attributes appear only on Span
 // Events and are not available to transaction events, error events, or traced
 // errors.
@@ -138,6 +174,7 @@ const (
 	SpanAttributeDBStatement             = "db.statement"
 	SpanAttributeDBInstance              = "db.instance"
 	SpanAttributeDBCollection            = "db.collection"
+	SpanAttributeDBCommandCount          = "db.commandCount"
 	SpanAttributePeerAddress             = "peer.address"
 	SpanAttributePeerHostname            = "peer.hostname"
 	SpanAttributeHTTPURL                 = "http.url"
@@ -151,6 +188,23 @@ const (
 	SpanAttributeParentAccount           = "parent.account"
 	SpanAttributeParentTransportDuration = "parent.transportDuration"
 	SpanAttributeParentTransportType     = "parent.transportType"
+	SpanAttributeCloudAccountID          = "cloud.account.id"
+	SpanAttributeCloudRegion             = "cloud.region"
+	SpanAttributeAWSARN                  = "aws.arn"
+	// Added to external segments from ExternalSegment.Outcome.
+	SpanAttributeExternalRetryCount    = "external.retryCount"
+	SpanAttributeExternalRedirectCount = "external.redirectCount"
+	SpanAttributeExternalCircuitState  = "external.circuitState"
+	SpanAttributeExternalFailure       = "external.failure"
+	SpanAttributeExternalHedgeID       = "external.hedge.id"
+	SpanAttributeExternalHedgeWinner   = "external.hedge.winner"
+	// Added to the segments of an ExternalCall, see StartExternalCall.
+	SpanAttributeExternalAttempts = "external.attempts"
+	// Added to segments still open when their transaction ended, see
+	// Config.SegmentGuards.EndUnfinished.
+	SpanAttributeUnfinished = "nr.unfinished"
+	// Added to segments longer than Config.SegmentGuards.MaxDuration.
+	SpanAttributeDurationCapped = "nr.durationCapped"
 
 	// Deprecated: This attribute is a duplicate of AttributeResponseCode and
 	// will be removed in a later release.
@@ -6,6 +6,7 @@ package newrelic
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"net/http"
 	"net/url"
@@ -52,15 +53,27 @@ var (
 		AttributeMessageExchangeType:        destNone,
 		AttributeMessageReplyTo:             destNone,
 		AttributeMessageCorrelationID:       destNone,
+		AttributeGraphQLOperationType:       usualDests,
+		AttributeGraphQLOperationName:       usualDests,
+		AttributeGraphQLPersistedQueryHash:  usualDests,
+		AttributeRPCSystem:                  usualDests,
+		AttributeRPCService:                 usualDests,
+		AttributeRPCMethod:                  usualDests,
+		AttributeRPCErrorCode:               usualDests,
 		AttributeCodeFunction:               usualDests,
 		AttributeCodeNamespace:              usualDests,
 		AttributeCodeFilepath:               usualDests,
 		AttributeCodeLineno:                 usualDests,
+		AttributeRequestCanceled:            usualDests,
+		AttributeBrowserCorrelationToken:    usualDests,
 
 		// Span specific attributes
 		SpanAttributeDBStatement:             usualDests,
 		SpanAttributeDBInstance:              usualDests,
 		SpanAttributeDBCollection:            usualDests,
+		SpanAttributeDBCommandCount:          usualDests,
+		SpanAttributeUnfinished:              usualDests,
+		SpanAttributeDurationCapped:          usualDests,
 		SpanAttributePeerAddress:             usualDests,
 		SpanAttributePeerHostname:            usualDests,
 		SpanAttributeHTTPURL:                 usualDests,
@@ -75,6 +88,16 @@ var (
 		SpanAttributeParentAccount:           usualDests,
 		SpanAttributeParentTransportDuration: usualDests,
 		SpanAttributeParentTransportType:     usualDests,
+		SpanAttributeCloudAccountID:          usualDests,
+		SpanAttributeCloudRegion:             usualDests,
+		SpanAttributeAWSARN:                  usualDests,
+		SpanAttributeExternalRetryCount:      usualDests,
+		SpanAttributeExternalRedirectCount:   usualDests,
+		SpanAttributeExternalAttempts:        usualDests,
+		SpanAttributeExternalCircuitState:    usualDests,
+		SpanAttributeExternalFailure:         usualDests,
+		SpanAttributeExternalHedgeID:         usualDests,
+		SpanAttributeExternalHedgeWinner:     usualDests,
 	}
 )
 
@@ -121,6 +144,8 @@ type attributeConfig struct {
 	// over modifiers appearing earlier.
 	wildcardModifiers []*attributeModifier
 	agentDests        map[string]destinationSet
+	// limits are the length limits of custom attributes.
+	limits attributeLimits
 }
 
 type includeExclude struct {
@@ -209,6 +234,7 @@ func createAttributeConfig(input config, includeEnabled bool) *attributeConfig {
 	c := &attributeConfig{
 		exactMatchModifiers: make(map[string]*attributeModifier),
 		wildcardModifiers:   make([]*attributeModifier, 0, 64),
+		limits:              input.attributeLimits(),
 	}
 
 	processDest(c, includeEnabled, &input.Attributes, destAll)
@@ -225,6 +251,18 @@ func createAttributeConfig(input config, includeEnabled bool) *attributeConfig {
 	for name, dest := range agentAttributeDefaultDests {
 		c.agentDests[name] = applyAttributeConfig(c, name, dest)
 	}
+	if !input.HighSecurity {
+		for _, name := range requestCaptureAttributeNames(input.Config) {
+			if _, ok := c.agentDests[name]; !ok {
+				c.agentDests[name] = applyAttributeConfig(c, name, usualDests)
+			}
+		}
+	}
+	for _, name := range deploymentMetadataAttributeNames(input.Config) {
+		if _, ok := c.agentDests[name]; !ok {
+			c.agentDests[name] = applyAttributeConfig(c, name, usualDests)
+		}
+	}
 
 	return c
 }
@@ -287,7 +325,10 @@ func (attr agentAttributes) Remove(id string) {
 type attributes struct {
 	config *attributeConfig
 	user   map[string]userAttribute
-	Agent  agentAttributes
+	// userCount is the number of user attributes, where map values count
+	// as each of their flattened entries.
+	userCount int
+	Agent     agentAttributes
 }
 
 // newAttributes creates a new Attributes.
@@ -308,11 +349,14 @@ func (e errInvalidAttributeType) Error() string {
 	return fmt.Sprintf("attribute '%s' value of type %T is invalid", e.key, e.val)
 }
 
-type invalidAttributeKeyErr struct{ key string }
+type invalidAttributeKeyErr struct {
+	key   string
+	limit int
+}
 
 func (e invalidAttributeKeyErr) Error() string {
 	return fmt.Sprintf("attribute key '%.32s...' exceeds length limit %d",
-		e.key, attributeKeyLengthLimit)
+		e.key, e.limit)
 }
 
 type userAttributeLimitErr struct{ key string }
@@ -332,6 +376,53 @@ func (e invalidFloatAttrValue) Error() string {
 
 }
 
+// attributeLimits are the length limits of custom attributes, see
+// Config.AttributeLimits.  Zero limits are replaced by the defaults.
+type attributeLimits struct {
+	keyLengthLimit   int
+	valueLengthLimit int
+	hashTruncated    bool
+}
+
+var defaultAttributeLimits = attributeLimits{
+	keyLengthLimit:   attributeKeyLengthLimit,
+	valueLengthLimit: attributeValueLengthLimit,
+}
+
+// truncatedHashLength is the length of the hash suffix, eg. "#1a2b3c4d",
+// added to values truncated when attributeLimits.hashTruncated is set.
+const truncatedHashLength = 9
+
+func (l attributeLimits) keyLength() int {
+	if l.keyLengthLimit > 0 {
+		return l.keyLengthLimit
+	}
+	return attributeKeyLengthLimit
+}
+
+func (l attributeLimits) valueLength() int {
+	if l.valueLengthLimit > 0 {
+		return l.valueLengthLimit
+	}
+	return attributeValueLengthLimit
+}
+
+// truncateValue truncates string values longer than the value length limit.
+// If hashTruncated is set, truncated values end with a hash of the complete
+// value so that long values sharing a prefix remain distinguishable.
+func (l attributeLimits) truncateValue(val string) string {
+	limit := l.valueLength()
+	if len(val) <= limit {
+		return val
+	}
+	if l.hashTruncated && limit > truncatedHashLength {
+		h := fnv.New32a()
+		h.Write([]byte(val))
+		return stringLengthByteLimit(val, limit-truncatedHashLength) + fmt.Sprintf("#%08x", h.Sum32())
+	}
+	return stringLengthByteLimit(val, limit)
+}
+
 func truncateStringValueIfLong(val string) string {
 	if len(val) > attributeValueLengthLimit {
 		return stringLengthByteLimit(val, attributeValueLengthLimit)
@@ -339,10 +430,38 @@ func truncateStringValueIfLong(val string) string {
 	return val
 }
 
-// validateUserAttribute validates a user attribute.
+// validateUserAttribute validates a user attribute using the default
+// attribute limits.
 func validateUserAttribute(key string, val interface{}) (interface{}, error) {
+	return defaultAttributeLimits.validateUserAttribute(key, val)
+}
+
+// validateUserAttribute validates a user attribute.  Slice values are
+// converted to JSON array strings and map values to attributeMaps.
+func (l attributeLimits) validateUserAttribute(key string, val interface{}) (interface{}, error) {
+	var err error
+	if m, ok := val.(map[string]interface{}); ok {
+		val, err = l.validateAttributeMap(key, m)
+	} else {
+		val, err = l.validateAttributeValue(key, val)
+	}
+	if nil != err {
+		return nil, err
+	}
+
+	// Attributes whose keys are excessively long are dropped rather than
+	// truncated to avoid worrying about the application of configuration to
+	// truncated values or performing the truncation after configuration.
+	if limit := l.keyLength(); len(key) > limit {
+		return nil, invalidAttributeKeyErr{key: key, limit: limit}
+	}
+	return val, nil
+}
+
+// validateAttributeValue validates a scalar or slice attribute value.
+func (l attributeLimits) validateAttributeValue(key string, val interface{}) (interface{}, error) {
 	if str, ok := val.(string); ok {
-		val = interface{}(truncateStringValueIfLong(str))
+		val = interface{}(l.truncateValue(str))
 	}
 
 	switch v := val.(type) {
@@ -357,19 +476,14 @@ func validateUserAttribute(key string, val interface{}) (interface{}, error) {
 		if err := validateFloat(v, key); err != nil {
 			return nil, err
 		}
+	case []string, []int, []int64, []float64, []bool:
+		return l.sliceAttributeValue(key, v)
 	default:
 		return nil, errInvalidAttributeType{
 			key: key,
 			val: val,
 		}
 	}
-
-	// Attributes whose keys are excessively long are dropped rather than
-	// truncated to avoid worrying about the application of configuration to
-	// truncated values or performing the truncation after configuration.
-	if len(key) > attributeKeyLengthLimit {
-		return nil, invalidAttributeKeyErr{key: key}
-	}
 	return val, nil
 }
 
@@ -385,7 +499,7 @@ func validateFloat(v float64, key string) error {
 
 // addUserAttribute adds a user attribute.
 func addUserAttribute(a *attributes, key string, val interface{}, d destinationSet) error {
-	val, err := validateUserAttribute(key, val)
+	val, err := a.config.limits.validateUserAttribute(key, val)
 	if nil != err {
 		return err
 	}
@@ -397,7 +511,11 @@ func addUserAttribute(a *attributes, key string, val interface{}, d destinationS
 		a.user = make(map[string]userAttribute)
 	}
 
-	if _, exists := a.user[key]; !exists && len(a.user) >= attributeUserLimit {
+	count := a.userCount + attributeCount(val)
+	if old, ok := a.user[key]; ok {
+		count -= attributeCount(old.value)
+	}
+	if count > attributeUserLimit {
 		return userAttributeLimitErr{key}
 	}
 
@@ -406,9 +524,103 @@ func addUserAttribute(a *attributes, key string, val interface{}, d destinationS
 		value: val,
 		dests: dests,
 	}
+	a.userCount = count
 	return nil
 }
 
+// redactable returns the agent and user attributes as plain values for
+// Config.RedactAttributes.  User attributes whose values are maps are not
+// included.
+func (a *attributes) redactable() (agent map[string]interface{}, user map[string]interface{}) {
+	agent = make(map[string]interface{}, len(a.Agent))
+	for key, val := range a.Agent {
+		if nil != val.otherVal {
+			agent[key] = val.otherVal
+		} else {
+			agent[key] = val.stringVal
+		}
+	}
+	user = make(map[string]interface{}, len(a.user))
+	for key, ua := range a.user {
+		if _, ok := ua.value.(attributeMap); !ok {
+			user[key] = ua.value
+		}
+	}
+	return agent, user
+}
+
+// redacted replaces the attributes with the values returned by redactable
+// after they were modified by Config.RedactAttributes.  User attributes keep
+// their destinations, and invalid user attribute values are dropped.
+func (a *attributes) redacted(agent map[string]interface{}, user map[string]interface{}) {
+	for key := range a.Agent {
+		delete(a.Agent, key)
+	}
+	for key, val := range agent {
+		if str, ok := val.(string); ok {
+			a.Agent.Add(key, str, nil)
+		} else {
+			a.Agent.Add(key, "", val)
+		}
+	}
+	for key, ua := range a.user {
+		if _, ok := ua.value.(attributeMap); ok {
+			continue
+		}
+		a.userCount -= attributeCount(ua.value)
+		val, ok := user[key]
+		if !ok {
+			delete(a.user, key)
+			continue
+		}
+		if v, err := a.config.limits.validateUserAttribute(key, val); nil == err {
+			a.user[key] = userAttribute{value: v, dests: ua.dests}
+			a.userCount += attributeCount(v)
+		} else {
+			delete(a.user, key)
+		}
+	}
+	for key, val := range user {
+		if _, ok := a.user[key]; !ok {
+			addUserAttribute(a, key, val, destAll)
+		}
+	}
+}
+
+// redactableErrorAttributes returns the attributes of an error for
+// Config.RedactAttributes.  Attributes whose values are maps are not
+// included.
+func redactableErrorAttributes(attrs map[string]interface{}) map[string]interface{} {
+	vals := make(map[string]interface{}, len(attrs))
+	for key, val := range attrs {
+		if _, ok := val.(attributeMap); !ok {
+			vals[key] = val
+		}
+	}
+	return vals
+}
+
+// redactedErrorAttributes returns the attributes of an error after the values
+// returned by redactableErrorAttributes were modified by
+// Config.RedactAttributes.  Invalid values are dropped.
+func (l attributeLimits) redactedErrorAttributes(attrs map[string]interface{}, vals map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(vals))
+	for key, val := range attrs {
+		if _, ok := val.(attributeMap); ok {
+			out[key] = val
+		}
+	}
+	for key, val := range vals {
+		if v, err := l.validateUserAttribute(key, val); nil == err {
+			out[key] = v
+		}
+	}
+	if 0 == len(out) {
+		return nil
+	}
+	return out
+}
+
 func writeAttributeValueJSON(w *jsonFieldsWriter, key string, val interface{}) {
 	switch v := val.(type) {
 	case string:
@@ -445,6 +657,10 @@ func writeAttributeValueJSON(w *jsonFieldsWriter, key string, val interface{}) {
 		w.floatField(key, float64(v))
 	case float64:
 		w.floatField(key, v)
+	case attributeMap:
+		for _, e := range v {
+			writeAttributeValueJSON(w, key+"."+e.key, e.value)
+		}
 	default:
 		w.stringField(key, fmt.Sprintf("%T", v))
 	}
@@ -523,6 +739,98 @@ func requestAgentAttributes(a *attributes, method string, hdrs http.Header, u *u
 	}
 }
 
+const (
+	requestParametersAttributePrefix  = "request.parameters."
+	requestHeadersAttributePrefix     = "request.headers."
+	deploymentMetadataAttributePrefix = "deployment."
+	redactedAttributeValue            = "[REDACTED]"
+)
+
+// secretKeyFragments identify query parameters and headers whose values are
+// redacted even when their capture is configured.
+var secretKeyFragments = []string{
+	"password", "passwd", "secret", "token", "auth", "apikey", "api_key",
+	"api-key", "session", "cookie", "credential", "signature", "private",
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, fragment := range secretKeyFragments {
+		if strings.Contains(key, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestCaptureAttributeNames returns the names of the agent attributes
+// configured by Config.RequestCapture.
+func requestCaptureAttributeNames(c Config) []string {
+	var names []string
+	for _, name := range c.RequestCapture.QueryParameters {
+		names = append(names, requestParametersAttributePrefix+name)
+	}
+	for _, key := range c.RequestCapture.Metadata {
+		names = append(names, requestHeadersAttributePrefix+strings.ToLower(key))
+	}
+	return names
+}
+
+func addCapturedAttribute(a *attributes, id, key string, values []string) {
+	if 0 == len(values) {
+		return
+	}
+	if _, ok := agentAttributeDefaultDests[id]; ok {
+		return
+	}
+	val := strings.Join(values, ",")
+	if isSecretKey(key) {
+		val = redactedAttributeValue
+	}
+	a.Agent.Add(id, val, nil)
+}
+
+// requestCaptureAttributes gathers the agent attributes configured by
+// Config.RequestCapture from the request's query parameters and headers.
+func requestCaptureAttributes(a *attributes, c config, hdrs http.Header, u *url.URL) {
+	if c.HighSecurity {
+		return
+	}
+	if nil != u && len(c.RequestCapture.QueryParameters) > 0 {
+		query := u.Query()
+		for _, name := range c.RequestCapture.QueryParameters {
+			addCapturedAttribute(a, requestParametersAttributePrefix+name, name, query[name])
+		}
+	}
+	if nil != hdrs {
+		for _, key := range c.RequestCapture.Metadata {
+			addCapturedAttribute(a, requestHeadersAttributePrefix+strings.ToLower(key), key, hdrs.Values(key))
+		}
+	}
+}
+
+// deploymentMetadataAttributeNames returns the names of the agent attributes
+// configured by Config.DeploymentMetadata.
+func deploymentMetadataAttributeNames(c Config) []string {
+	var names []string
+	for key := range c.DeploymentMetadata {
+		names = append(names, deploymentMetadataAttributePrefix+key)
+	}
+	return names
+}
+
+// deploymentMetadataAttributes adds the agent attributes configured by
+// Config.DeploymentMetadata.
+func deploymentMetadataAttributes(a *attributes, c Config) {
+	for key, val := range c.DeploymentMetadata {
+		id := deploymentMetadataAttributePrefix + key
+		if _, ok := agentAttributeDefaultDests[id]; ok {
+			continue
+		}
+		a.Agent.Add(id, val, nil)
+	}
+}
+
 // responseHeaderAttributes gather agent attributes from the response headers.
 func responseHeaderAttributes(a *attributes, h http.Header) {
 	if nil == h {
@@ -6,7 +6,9 @@ package newrelic
 import (
 	"bytes"
 	"encoding/json"
+	"math"
 	"net/http"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -170,6 +172,7 @@ func TestWriteAttributeValueJSON(t *testing.T) {
 	writeAttributeValueJSON(&w, "a", int(-5))
 	writeAttributeValueJSON(&w, "a", float32(1.5))
 	writeAttributeValueJSON(&w, "a", float64(4.56))
+	writeAttributeValueJSON(&w, "a", attributeMap{{key: "b", value: 1}, {key: "c.d", value: "e"}})
 	buf.WriteByte('}')
 
 	expect := compactJSONString(`{
@@ -188,7 +191,9 @@ func TestWriteAttributeValueJSON(t *testing.T) {
 		"a":-4,
 		"a":-5,
 		"a":1.5,
-		"a":4.56
+		"a":4.56,
+		"a.b":1,
+		"a.c.d":"e"
 		}`)
 	js := buf.String()
 	if js != expect {
@@ -217,10 +222,19 @@ func TestValidAttributeTypes(t *testing.T) {
 		{Input: uint(0), Valid: true},
 		{Input: int(0), Valid: true},
 		{Input: uintptr(0), Valid: true},
+		{Input: []string{"a"}, Valid: true},
+		{Input: []int{1}, Valid: true},
+		{Input: []int64{1}, Valid: true},
+		{Input: []float64{1.5}, Valid: true},
+		{Input: []bool{true}, Valid: true},
+		{Input: map[string]interface{}{"a": 1}, Valid: true},
 		// Invalid attribute types.
 		{Input: nil, Valid: false},
 		{Input: struct{}{}, Valid: false},
 		{Input: &struct{}{}, Valid: false},
+		{Input: []interface{}{"a"}, Valid: false},
+		{Input: map[string]string{"a": "b"}, Valid: false},
+		{Input: map[string]interface{}{"a": struct{}{}}, Valid: false},
 	}
 
 	for _, tc := range testcases {
@@ -232,6 +246,197 @@ func TestValidAttributeTypes(t *testing.T) {
 	}
 }
 
+func TestSliceAttributeValues(t *testing.T) {
+	testcases := []struct {
+		Input  interface{}
+		Expect string
+	}{
+		{Input: []string{}, Expect: `[]`},
+		{Input: []string{"a", `b"c`}, Expect: `["a","b\"c"]`},
+		{Input: []int{1, -2}, Expect: `[1,-2]`},
+		{Input: []int64{3}, Expect: `[3]`},
+		{Input: []float64{1.5, 2}, Expect: `[1.5,2]`},
+		{Input: []bool{true, false}, Expect: `[true,false]`},
+	}
+	for _, tc := range testcases {
+		val, err := validateUserAttribute("key", tc.Input)
+		if nil != err || val != tc.Expect {
+			t.Error(tc.Input, val, err)
+		}
+	}
+}
+
+func TestSliceAttributeValueTruncated(t *testing.T) {
+	elem := strings.Repeat("a", 100)
+	val, err := validateUserAttribute("key", []string{elem, elem, elem})
+	if nil != err {
+		t.Fatal(err)
+	}
+	js := val.(string)
+	if len(js) > attributeValueLengthLimit {
+		t.Error(len(js))
+	}
+	var decoded []string
+	if err := json.Unmarshal([]byte(js), &decoded); nil != err {
+		t.Fatal(js, err)
+	}
+	if len(decoded) != 2 || decoded[0] != elem || decoded[1] != elem {
+		t.Error(decoded)
+	}
+}
+
+func TestAttributeLimits(t *testing.T) {
+	limits := attributeLimits{keyLengthLimit: 10, valueLengthLimit: 1000}
+	long := strings.Repeat("a", 2000)
+	val, err := limits.validateUserAttribute("key", long)
+	if nil != err || val != long[:1000] {
+		t.Error(val, err)
+	}
+	if _, err := limits.validateUserAttribute("a-long-key", 1); nil != err {
+		t.Error(err)
+	}
+	_, err = limits.validateUserAttribute("a-longer-key", 1)
+	if e, ok := err.(invalidAttributeKeyErr); !ok || e.limit != 10 {
+		t.Error(err)
+	}
+	// Zero limits are replaced by the defaults.
+	val, err = attributeLimits{}.validateUserAttribute("key", long)
+	if nil != err || val != long[:attributeValueLengthLimit] {
+		t.Error(val, err)
+	}
+}
+
+func TestAttributeLimitsHashTruncated(t *testing.T) {
+	limits := attributeLimits{hashTruncated: true}
+	prefix := strings.Repeat("a", 300)
+	one, _ := limits.validateUserAttribute("key", prefix+"one")
+	two, _ := limits.validateUserAttribute("key", prefix+"two")
+	if len(one.(string)) != attributeValueLengthLimit || len(two.(string)) != attributeValueLengthLimit {
+		t.Error(one, two)
+	}
+	if one == two {
+		t.Error("truncated values are not distinguishable", one)
+	}
+	if !strings.HasPrefix(one.(string), prefix[:attributeValueLengthLimit-truncatedHashLength]+"#") {
+		t.Error(one)
+	}
+	// Values within the limit are not changed.
+	if val, _ := limits.validateUserAttribute("key", "short"); val != "short" {
+		t.Error(val)
+	}
+}
+
+func TestSliceAttributeValueInvalidFloat(t *testing.T) {
+	_, err := validateUserAttribute("key", []float64{1, math.NaN()})
+	if _, ok := err.(invalidFloatAttrValue); !ok {
+		t.Error(err)
+	}
+}
+
+func TestMapAttributeValueFlattened(t *testing.T) {
+	val, err := validateUserAttribute("user", map[string]interface{}{
+		"plan": map[string]interface{}{"tier": "pro"},
+		"id":   1,
+		"tags": []string{"a"},
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+	expect := attributeMap{
+		{key: "id", value: 1},
+		{key: "plan.tier", value: "pro"},
+		{key: "tags", value: `["a"]`},
+	}
+	if !reflect.DeepEqual(val, expect) {
+		t.Error(val)
+	}
+}
+
+func TestMapAttributeValueTooDeep(t *testing.T) {
+	m := map[string]interface{}{"a": 1}
+	for i := 0; i < attributeMapDepthLimit; i++ {
+		m = map[string]interface{}{"n": m}
+	}
+	_, err := validateUserAttribute("key", m)
+	if _, ok := err.(attributeMapDepthErr); !ok {
+		t.Error(err)
+	}
+}
+
+func TestMapAttributeValueTooManyEntries(t *testing.T) {
+	m := make(map[string]interface{})
+	for i := 0; i <= attributeMapEntryLimit; i++ {
+		m[strconv.Itoa(i)] = i
+	}
+	_, err := validateUserAttribute("key", m)
+	if _, ok := err.(attributeMapEntriesErr); !ok {
+		t.Error(err)
+	}
+}
+
+func TestUserAttributeLimitCountsMapEntries(t *testing.T) {
+	cfg := createAttributeConfig(config{Config: defaultConfig()}, true)
+	attrs := newAttributes(cfg)
+
+	large := make(map[string]interface{})
+	for i := 0; i < 100; i++ {
+		large[strconv.Itoa(i)] = i
+	}
+	if err := addUserAttribute(attrs, "large", large, destAll); nil == err {
+		t.Error("100 entry map accepted")
+	}
+
+	half := make(map[string]interface{})
+	for i := 0; i < attributeUserLimit/2; i++ {
+		half[strconv.Itoa(i)] = i
+	}
+	for _, key := range []string{"a", "b"} {
+		if err := addUserAttribute(attrs, key, half, destAll); nil != err {
+			t.Fatal(key, err)
+		}
+	}
+	if err := addUserAttribute(attrs, "c", 1, destAll); nil == err {
+		t.Error("attribute accepted over the limit")
+	}
+	// Replacing an attribute only counts the new value.
+	if err := addUserAttribute(attrs, "b", 1, destAll); nil != err {
+		t.Error(err)
+	}
+	if err := addUserAttribute(attrs, "c", 1, destAll); nil != err {
+		t.Error(err)
+	}
+}
+
+func TestRedactedUserAttributeCount(t *testing.T) {
+	cfg := createAttributeConfig(config{Config: defaultConfig()}, true)
+	attrs := newAttributes(cfg)
+
+	for i := 0; i < attributeUserLimit; i++ {
+		s := strconv.Itoa(i)
+		if err := addUserAttribute(attrs, s, s, destAll); nil != err {
+			t.Fatal(err)
+		}
+	}
+	// Attributes removed by redaction no longer count towards the limit.
+	_, user := attrs.redactable()
+	delete(user, "0")
+	attrs.redacted(nil, user)
+	if err := addUserAttribute(attrs, "new", 1, destAll); nil != err {
+		t.Error(err)
+	}
+	if err := addUserAttribute(attrs, "another", 1, destAll); nil == err {
+		t.Error("attribute accepted over the limit")
+	}
+}
+
+func TestMapAttributeValueKeyLength(t *testing.T) {
+	lengthyKey := strings.Repeat("a", attributeKeyLengthLimit)
+	_, err := validateUserAttribute("key", map[string]interface{}{lengthyKey: 1})
+	if _, ok := err.(invalidAttributeKeyErr); !ok {
+		t.Error(err)
+	}
+}
+
 func TestUserAttributeValLength(t *testing.T) {
 	cfg := createAttributeConfig(config{Config: defaultConfig()}, true)
 	attrs := newAttributes(cfg)
@@ -399,6 +604,40 @@ func TestRequestAgentAttributesPresent(t *testing.T) {
 	})
 }
 
+func TestRequestCaptureAttributes(t *testing.T) {
+	req, err := http.NewRequest("GET", "http://www.newrelic.com?page=2&page=3&api_key=123&other=me&sessionToken=abc", nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Tenant-ID", "tenant")
+	req.Header.Set("X-Auth-Token", "token")
+	req.Header.Set("X-Other", "other")
+	req.Header.Set("Referer", "http://www.example.com")
+
+	c := config{Config: defaultConfig()}
+	c.RequestCapture.QueryParameters = []string{"page", "api_key", "sessionToken", "missing"}
+	c.RequestCapture.Metadata = []string{"x-tenant-id", "X-Auth-Token", "Referer"}
+	cfg := createAttributeConfig(c, true)
+
+	attrs := newAttributes(cfg)
+	requestCaptureAttributes(attrs, c, req.Header, req.URL)
+	got := agentAttributesMap(attrs, destAll)
+	expectAttributes(t, got, map[string]interface{}{
+		"request.parameters.page":         "2,3",
+		"request.parameters.api_key":      "[REDACTED]",
+		"request.parameters.sessionToken": "[REDACTED]",
+		"request.headers.x-tenant-id":     "tenant",
+		"request.headers.x-auth-token":    "[REDACTED]",
+	})
+
+	c.HighSecurity = true
+	cfg = createAttributeConfig(c, true)
+	attrs = newAttributes(cfg)
+	requestCaptureAttributes(attrs, c, req.Header, req.URL)
+	got = agentAttributesMap(attrs, destAll)
+	expectAttributes(t, got, map[string]interface{}{})
+}
+
 func BenchmarkAgentAttributes(b *testing.B) {
 	cfg := createAttributeConfig(config{Config: defaultConfig()}, true)
 
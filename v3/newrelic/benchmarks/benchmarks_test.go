@@ -0,0 +1,157 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package benchmarks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+	"github.com/rainforestpay/go-agent/v3/newrelic/newrelictest"
+)
+
+// configuration is one combination of the features benchmarked.
+type configuration struct {
+	dt    bool
+	clm   bool
+	spans bool
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func (c configuration) String() string {
+	return fmt.Sprintf("dt=%s,clm=%s,spans=%s", onOff(c.dt), onOff(c.clm), onOff(c.spans))
+}
+
+func (c configuration) newApp() *newrelictest.App {
+	return newrelictest.NewApp(func(cfg *newrelic.Config) {
+		cfg.DistributedTracer.Enabled = c.dt
+		cfg.CodeLevelMetrics.Enabled = c.clm
+		cfg.SpanEvents.Enabled = c.spans
+	})
+}
+
+// configurations returns every combination of the benchmarked features.  Span
+// events are only recorded when distributed tracing is enabled, so the
+// combinations with distributed tracing disabled and span events enabled are
+// omitted.
+func configurations() []configuration {
+	var out []configuration
+	for _, dt := range []bool{false, true} {
+		for _, clm := range []bool{false, true} {
+			for _, spans := range []bool{false, true} {
+				if spans && !dt {
+					continue
+				}
+				out = append(out, configuration{dt: dt, clm: clm, spans: spans})
+			}
+		}
+	}
+	return out
+}
+
+// runConfigurations runs fn as a sub-benchmark of b for each configuration.
+func runConfigurations(b *testing.B, fn func(b *testing.B, app *newrelictest.App)) {
+	for _, c := range configurations() {
+		app := c.newApp()
+		b.Run(c.String(), func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			fn(b, app)
+		})
+		app.Shutdown(0)
+	}
+}
+
+func recordTransaction(app *newrelictest.App) {
+	app.StartTransaction("txn").End()
+}
+
+func recordSegment(txn *newrelic.Transaction) {
+	txn.StartSegment("segment").End()
+}
+
+var (
+	benchRequest = httptest.NewRequest("GET", "/hello", nil)
+	benchHandler = func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello world"))
+	}
+)
+
+// BenchmarkTransaction measures starting and ending a background
+// transaction.
+func BenchmarkTransaction(b *testing.B) {
+	runConfigurations(b, func(b *testing.B, app *newrelictest.App) {
+		for i := 0; i < b.N; i++ {
+			recordTransaction(app)
+		}
+	})
+}
+
+// BenchmarkWebTransaction measures a request served by a handler
+// instrumented with WrapHandleFunc.
+func BenchmarkWebTransaction(b *testing.B) {
+	runConfigurations(b, func(b *testing.B, app *newrelictest.App) {
+		_, handler := newrelic.WrapHandleFunc(app.Application, "/hello", benchHandler)
+		w := httptest.NewRecorder()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			handler(w, benchRequest)
+		}
+	})
+}
+
+// BenchmarkSegment measures starting and ending a segment in a transaction.
+// Segments are the most common instrumentation and must have minimal cost.
+func BenchmarkSegment(b *testing.B) {
+	runConfigurations(b, func(b *testing.B, app *newrelictest.App) {
+		txn := app.StartTransaction("txn")
+		defer txn.End()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			recordSegment(txn)
+		}
+	})
+}
+
+// BenchmarkDatastoreSegment measures a datastore segment with a
+// parameterized query.
+func BenchmarkDatastoreSegment(b *testing.B) {
+	runConfigurations(b, func(b *testing.B, app *newrelictest.App) {
+		txn := app.StartTransaction("txn")
+		defer txn.End()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			s := newrelic.DatastoreSegment{
+				StartTime:          txn.StartSegmentNow(),
+				Product:            newrelic.DatastorePostgres,
+				Collection:         "users",
+				Operation:          "SELECT",
+				ParameterizedQuery: "SELECT name FROM users WHERE id = $1",
+			}
+			s.End()
+		}
+	})
+}
+
+// BenchmarkExternalSegment measures an external segment created from an
+// outbound request.
+func BenchmarkExternalSegment(b *testing.B) {
+	runConfigurations(b, func(b *testing.B, app *newrelictest.App) {
+		txn := app.StartTransaction("txn")
+		defer txn.End()
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			newrelic.StartExternalSegment(txn, req).End()
+		}
+	})
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package benchmarks
+
+import (
+	"testing"
+)
+
+// allocationBudget is the maximum number of allocations per operation.  The
+// budgets leave some headroom above the measured counts; raise one only when
+// the extra cost of a change has been justified.
+type allocationBudget struct {
+	transaction float64
+	segment     float64
+}
+
+// budgetFor returns the budget for a configuration.  Each enabled feature adds
+// to the budget of the base configuration.
+func budgetFor(c configuration) allocationBudget {
+	b := allocationBudget{transaction: 10, segment: 1}
+	if c.dt {
+		b.transaction += 10
+	}
+	if c.clm {
+		b.transaction += 5
+	}
+	if c.spans {
+		b.transaction += 4
+		b.segment += 4
+	}
+	return b
+}
+
+func TestAllocationBudgets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping allocation budgets in short mode")
+	}
+	for _, c := range configurations() {
+		c := c
+		t.Run(c.String(), func(t *testing.T) {
+			app := c.newApp()
+			defer app.Shutdown(0)
+			budget := budgetFor(c)
+
+			allocs := testing.AllocsPerRun(100, func() { recordTransaction(app) })
+			t.Logf("transaction allocs/op: %v", allocs)
+			if allocs > budget.transaction {
+				t.Errorf("transaction allocs/op %v exceeds budget %v", allocs, budget.transaction)
+			}
+
+			txn := app.StartTransaction("txn")
+			defer txn.End()
+			allocs = testing.AllocsPerRun(100, func() { recordSegment(txn) })
+			t.Logf("segment allocs/op: %v", allocs)
+			if allocs > budget.segment {
+				t.Errorf("segment allocs/op %v exceeds budget %v", allocs, budget.segment)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package benchmarks measures the overhead the agent adds to each transaction
+// and segment.  Every benchmark is run against each of the configurations in
+// this package, which toggle distributed tracing, code level metrics, and span
+// events, so that the sub-benchmark names identify the features responsible
+// for a change:
+//
+//	BenchmarkTransaction/dt=on,clm=off,spans=on
+//
+// The applications used never connect to New Relic: data is merged directly
+// into an in-memory harvest, so the numbers are approximate.
+//
+// Run the benchmarks and compare them to a baseline from the v3 directory:
+//
+//	make bench-baseline   # record bench-baseline.txt on the merge-base with main
+//	make bench-compare    # record bench-new.txt and compare with benchstat
+//
+// TestAllocationBudgets fails when the allocations made by a transaction or
+// segment exceed the budgets in budget_test.go.  Allocation counts, unlike
+// timings, are stable enough to check on every test run.
+package benchmarks
@@ -12,6 +12,10 @@ var (
 	browserStartTag   = []byte(`<script type="text/javascript">`)
 	browserEndTag     = []byte(`</script>`)
 	browserInfoPrefix = []byte(`window.NREUM||(NREUM={});NREUM.info=`)
+	// browserCorrelationPrefix sets the correlation token as a custom
+	// attribute once the Browser agent API is available.
+	browserCorrelationPrefix = []byte(`;window.newrelic&&newrelic.setCustomAttribute("` + AttributeBrowserCorrelationToken + `",`)
+	browserCorrelationSuffix = []byte(`)`)
 )
 
 // browserInfo contains the fields that are marshalled into the Browser agent's
@@ -35,6 +39,8 @@ type browserInfo struct {
 type BrowserTimingHeader struct {
 	agentLoader string
 	info        browserInfo
+	// correlationToken is created by Transaction.BrowserCorrelationToken.
+	correlationToken string
 }
 
 func appendSlices(slices ...[]byte) []byte {
@@ -80,7 +86,26 @@ func (h *BrowserTimingHeader) WithoutTags() []byte {
 		return nil
 	}
 
-	return appendSlices([]byte(h.agentLoader), browserInfoPrefix, info)
+	if "" == h.correlationToken {
+		return appendSlices([]byte(h.agentLoader), browserInfoPrefix, info)
+	}
+	token, err := json.Marshal(h.correlationToken)
+	if err != nil {
+		return nil
+	}
+	return appendSlices([]byte(h.agentLoader), browserInfoPrefix, info,
+		browserCorrelationPrefix, token, browserCorrelationSuffix)
+}
+
+// CorrelationToken returns the token created by
+// Transaction.BrowserCorrelationToken before the header was created, or the
+// empty string if there is none.  This method returns the empty string if the
+// receiver is nil.
+func (h *BrowserTimingHeader) CorrelationToken() string {
+	if nil == h {
+		return ""
+	}
+	return h.correlationToken
 }
 
 // browserAttributes returns a string with the attributes that are attached to
@@ -22,6 +22,10 @@ func TestNilBrowserTimingHeader(t *testing.T) {
 	if out := h.WithoutTags(); out != nil {
 		t.Errorf("unexpected WithoutTags output for a disabled header: expected a blank string; got %s", out)
 	}
+
+	if out := h.CorrelationToken(); out != "" {
+		t.Errorf("unexpected CorrelationToken output for a disabled header: expected a blank string; got %s", out)
+	}
 }
 
 func TestEnabled(t *testing.T) {
@@ -67,6 +71,21 @@ func TestEnabled(t *testing.T) {
 	}
 }
 
+func TestCorrelationToken(t *testing.T) {
+	h := &BrowserTimingHeader{
+		agentLoader:      "loader();",
+		correlationToken: "abc",
+	}
+	expected := `loader();window.NREUM||(NREUM={});NREUM.info={"beacon":"","licenseKey":"","applicationID":"","transactionName":"","queueTime":0,"applicationTime":0,"atts":"","errorBeacon":"","agent":""}` +
+		`;window.newrelic&&newrelic.setCustomAttribute("browser.correlationToken","abc")`
+	if actual := h.WithoutTags(); string(actual) != expected {
+		t.Errorf("header did not match: expected %s; got %s", expected, string(actual))
+	}
+	if token := h.CorrelationToken(); token != "abc" {
+		t.Error(token)
+	}
+}
+
 func TestBrowserAttributesNil(t *testing.T) {
 	expected := `{"u":{},"a":{}}`
 	actual := string(browserAttributes(nil))
@@ -88,6 +88,8 @@ type traceOptSet struct {
 	LocationOverride *CodeLocation
 	SuppressCLM      bool
 	DemandCLM        bool
+	ForceTrace       bool
+	NoSpanEvents     bool
 	IgnoredPrefixes  []string
 	PathPrefixes     []string
 }
@@ -516,6 +518,20 @@ func (c *CachedCodeLocation) ThisCodeLocation(skiplevels ...int) *CodeLocation {
 	return c.location
 }
 
+//
+// codeLevelMetricsIgnoredPrefixes returns the configured prefixes of functions
+// to skip when looking for application code on the call stack, or nil if none
+// are configured.
+//
+func (c config) codeLevelMetricsIgnoredPrefixes() []string {
+	prefixes := c.CodeLevelMetrics.IgnoredPrefixes
+	// for backward compatibility, add the singleton IgnoredPrefix if there is one
+	if c.CodeLevelMetrics.IgnoredPrefix != "" {
+		prefixes = append(prefixes, c.CodeLevelMetrics.IgnoredPrefix)
+	}
+	return prefixes
+}
+
 func removeCodeLevelMetrics(remAttr func(string)) {
 	remAttr(AttributeCodeLineno)
 	remAttr(AttributeCodeNamespace)
@@ -554,11 +570,7 @@ func reportCodeLevelMetrics(tOpts traceOptSet, run *appRun, setAttr func(string,
 			var frame runtime.Frame
 
 			if tOpts.IgnoredPrefixes == nil {
-				tOpts.IgnoredPrefixes = run.Config.CodeLevelMetrics.IgnoredPrefixes
-				// for backward compatibility, add the singleton IgnoredPrefix if there is one
-				if run.Config.CodeLevelMetrics.IgnoredPrefix != "" {
-					tOpts.IgnoredPrefixes = append(tOpts.IgnoredPrefixes, run.Config.CodeLevelMetrics.IgnoredPrefix)
-				}
+				tOpts.IgnoredPrefixes = run.Config.codeLevelMetricsIgnoredPrefixes()
 				if tOpts.IgnoredPrefixes == nil {
 					tOpts.IgnoredPrefixes = append(tOpts.IgnoredPrefixes, defaultAgentProjectRoot)
 				}
@@ -48,6 +48,8 @@ type rpmCmd struct {
 	Data              []byte
 	RequestHeadersMap map[string]string
 	MaxPayloadSize    int
+	// Encoder compresses the data.  Gzip is used if it is nil.
+	Encoder PayloadEncoder
 }
 
 // rpmControls contains fields which will be the same for all calls made
@@ -157,7 +159,7 @@ func compress(b []byte, gzipWriterPool *sync.Pool) (*bytes.Buffer, error) {
 }
 
 func collectorRequestInternal(url string, cmd rpmCmd, cs rpmControls) rpmResponse {
-	compressed, err := compress(cmd.Data, cs.GzipWriterPool)
+	compressed, encoding, err := encodePayload(cmd, cs.GzipWriterPool)
 	if nil != err {
 		return rpmResponse{Err: err}
 	}
@@ -174,7 +176,7 @@ func collectorRequestInternal(url string, cmd rpmCmd, cs rpmControls) rpmRespons
 	req.Header.Add("Accept-Encoding", "identity, deflate")
 	req.Header.Add("Content-Type", "application/octet-stream")
 	req.Header.Add("User-Agent", userAgentPrefix+Version)
-	req.Header.Add("Content-Encoding", "gzip")
+	req.Header.Add("Content-Encoding", encoding)
 	for k, v := range cmd.RequestHeadersMap {
 		req.Header.Add(k, v)
 	}
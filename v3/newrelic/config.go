@@ -4,12 +4,18 @@
 package newrelic
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -37,6 +43,22 @@ type Config struct {
 	// for more examples and logging integrations.
 	Logger Logger
 
+	// ErrorLogLimit limits how often the Logger is given the same error,
+	// eg. "unable to end datastore segment" repeated for every request of
+	// a misinstrumented handler.  Errors with the same message and reason
+	// are logged at most MaxPerInterval times in each Interval.  Further
+	// errors are counted and their number is logged in a warning when the
+	// interval ends.  At most 100 distinct errors are tracked at a time:
+	// other errors are limited together as "other errors".
+	ErrorLogLimit struct {
+		// MaxPerInterval is the number of times the same error is logged
+		// in each interval.  Zero disables the limit.  The default is 10.
+		MaxPerInterval int
+		// Interval is the duration of each interval.  The default is one
+		// minute.
+		Interval time.Duration
+	}
+
 	// Enabled controls whether the agent will communicate with the New Relic
 	// servers and spawn goroutines.  Setting this to be false is useful in
 	// testing and staging situations.
@@ -48,6 +70,15 @@ type Config struct {
 	// https://docs.newrelic.com/docs/using-new-relic/user-interface-functions/organize-your-data/labels-categories-organize-apps-monitors
 	Labels map[string]string
 
+	// DeploymentMetadata are key value pairs describing the running build,
+	// such as a commit SHA or build ID.  Each pair is recorded as an agent
+	// attribute named "deployment.<key>" on transaction events, error
+	// events, transaction traces, traced errors, and span events so that
+	// data can be filtered by release.  Like other agent attributes, these
+	// may be excluded using Config.Attributes.Exclude.  Custom events and log
+	// events do not carry agent attributes and so are not decorated.
+	DeploymentMetadata map[string]string
+
 	// HighSecurity guarantees that certain agent settings can not be made
 	// more permissive.  This setting must match the corresponding account
 	// setting in the New Relic UI.
@@ -73,6 +104,51 @@ type Config struct {
 		Enabled bool
 		// MaxSamplesStored sets the desired maximum custom event samples stored
 		MaxSamplesStored int
+		// EventAPIFallback controls a degraded mode in which custom events
+		// that repeatedly fail to be sent to the collector are sent
+		// directly to the Event API instead.  This keeps business events
+		// from being lost during collector incidents.  Custom events are
+		// also sent to the Event API when the application is
+		// disconnected, and custom events recorded while the application
+		// is connecting are sent when it fails to connect.  Requests to
+		// the Event API are made in the background, and Shutdown waits
+		// for them to complete.
+		//
+		// https://docs.newrelic.com/docs/data-apis/ingest-apis/event-api/introduction-event-api/
+		EventAPIFallback struct {
+			// Enabled controls whether the fallback is used.  InsertKey
+			// and AccountID are required when Enabled is true.
+			Enabled bool
+			// InsertKey is the insert key used to authenticate with the
+			// Event API.  It is not included in the settings reported
+			// to New Relic.
+			InsertKey string
+			// AccountID is the New Relic account the events are
+			// recorded to.
+			AccountID string
+			// FailedHarvests is the number of consecutive failed
+			// collector harvests of a batch of custom events after which
+			// the batch is sent to the Event API.  Batches are dropped
+			// after 10 failed harvests, so values of 10 or more disable
+			// the fallback.  It is also the number of consecutive failed
+			// connect attempts after which the custom events recorded
+			// while connecting are sent to the Event API.
+			FailedHarvests int
+			// Host overrides the Event API host.  By default the host is
+			// chosen using the region of the license key.
+			Host string
+		}
+	}
+
+	// CustomMetrics controls the behavior of
+	// Application.RecordCustomMetric.  Custom metric names starting with a
+	// namespace used by the agent's own metrics, eg. "Datastore/" or
+	// "WebTransaction", are always rejected.
+	CustomMetrics struct {
+		// RequiredPrefix, if not empty, is the prefix which every custom
+		// metric name must start with, eg. "Billing/".  Custom metrics
+		// without this prefix are rejected.
+		RequiredPrefix string
 	}
 
 	// TransactionEvents controls the behavior of transaction analytics
@@ -88,6 +164,75 @@ type Config struct {
 		MaxSamplesStored int
 	}
 
+	// Transaction controls the behavior of transactions.
+	Transaction struct {
+		// IgnoreSynthetics excludes transactions started by New Relic
+		// Synthetics monitors from Apdex and from the error metrics used
+		// to calculate error rates, so that synthetic probes do not skew
+		// them.  Their traces, events, and errors are still reported.
+		// The default is false.
+		IgnoreSynthetics bool
+	}
+
+	// Apdex controls the Apdex classification of transactions.
+	Apdex struct {
+		// BackgroundTransactions includes background transactions, such
+		// as those of gRPC services and queue consumers, in Apdex using
+		// the same threshold as web transactions.  Their scores are
+		// recorded as the "ApdexOther" and
+		// "ApdexOther/Transaction/{name}" metrics so that they do not
+		// change the Apdex of web transactions.  The default is false.
+		BackgroundTransactions bool
+		// ResponseCodeZones maps response codes, such as gRPC status
+		// codes, to the Apdex zone of the transactions which set them,
+		// in place of the zone calculated from the duration and errors
+		// of the transaction, eg.
+		//
+		//	cfg.Apdex.ResponseCodeZones = map[int]newrelic.ApdexZone{
+		//		4: newrelic.ApdexFrustrating, // DEADLINE_EXCEEDED
+		//		8: newrelic.ApdexTolerating,  // RESOURCE_EXHAUSTED
+		//	}
+		//
+		// Background transactions set their response code using
+		// Transaction.SetWebResponse(nil).WriteHeader(code).
+		ResponseCodeZones map[int]ApdexZone
+	}
+
+	// TransactionDurationHistogram controls the recording of transaction
+	// duration histograms.  When enabled, each transaction is counted in
+	// a metric named after the transaction and the smallest bucket
+	// containing its duration, eg.
+	// "TransactionHistogram/WebTransaction/Go/users/bucket/0.25", or
+	// "TransactionHistogram/WebTransaction/Go/users/bucket/+Inf" if it is
+	// longer than every bucket.  The buckets are not cumulative: a
+	// transaction is only counted in one bucket.  Unlike transaction
+	// events, these metrics are not sampled, and so may be used to compute
+	// percentiles.
+	TransactionDurationHistogram struct {
+		// Enabled controls whether duration histograms are recorded.  The
+		// default is false.
+		Enabled bool
+		// Buckets are the upper bounds of the histogram buckets, which
+		// must be positive and increasing, and must not be empty when
+		// Enabled is true.  The default is 50ms, 100ms, 250ms, 500ms, 1s,
+		// 2.5s, 5s and 10s.
+		Buckets []time.Duration
+	}
+
+	// TransactionExemplars controls the recording of an exemplar for each
+	// transaction name: the slowest sampled transaction of that name during
+	// each metrics harvest period.  Exemplars are recorded as custom events
+	// of type "TransactionExemplar" with the attributes "transactionName",
+	// "traceId", "guid", and "duration" in seconds, so that dashboards can
+	// link from a latency spike in a transaction's metrics to a
+	// representative distributed trace.  Exemplars require that
+	// DistributedTracer and CustomInsightsEvents are enabled.
+	TransactionExemplars struct {
+		// Enabled controls whether exemplars are recorded.  The default is
+		// false.
+		Enabled bool
+	}
+
 	// ErrorCollector controls the capture of errors.
 	ErrorCollector struct {
 		// Enabled controls whether errors are captured.  This setting
@@ -108,6 +253,33 @@ type Config struct {
 		// as errors, and then re-panic them.  By default, this is
 		// set to false.
 		RecordPanics bool
+		// StackTraceDepth is the maximum number of frames in the stack
+		// trace of a traced error.  Leading frames belonging to the agent
+		// or matching CodeLevelMetrics.IgnoredPrefixes are removed first
+		// so that the trace begins in application code.  Values less than
+		// 1 or greater than 100 are treated as 100, the default.
+		StackTraceDepth int
+		// MaxEventsPerClass limits the number of error events recorded
+		// each minute for any single error class, so that a flood of
+		// one error does not evict all other errors from the event
+		// reservoir.  Errors over the limit are counted but not sent.
+		// The default of zero means no limit.
+		MaxEventsPerClass int
+		// IgnoreClasses lists the classes of errors which are not
+		// recorded, eg. "*errors.errorString" or "panic".  Ignored errors
+		// are not reported and do not count against the error rate.
+		IgnoreClasses []string
+		// IgnoreMessages maps error classes to regular expressions
+		// matching the messages of errors of that class which are not
+		// recorded, eg.
+		//
+		//	cfg.ErrorCollector.IgnoreMessages = map[string][]string{
+		//		"*errors.errorString": {"^context canceled$"},
+		//	}
+		//
+		// This applies to errors noticed using NoticeError, panics
+		// recorded by Transaction.End, and response code errors.
+		IgnoreMessages map[string][]string
 	}
 
 	// TransactionTracer controls the capture of transaction traces.
@@ -133,8 +305,10 @@ type Config struct {
 		Segments struct {
 			// StackTraceThreshold is the threshold at which
 			// segments will be given a stack trace in the
-			// transaction trace.  Lowering this setting will
-			// increase overhead.
+			// transaction trace.  The stack trace is recorded in
+			// the "backtrace" parameter of segments whose exclusive
+			// duration meets the threshold.  Lowering this setting
+			// will increase overhead.
 			StackTraceThreshold time.Duration
 			// Threshold is the threshold at which segments will be
 			// added to the trace.  Lowering this setting may
@@ -145,6 +319,18 @@ type Config struct {
 			// trace segment.
 			Attributes AttributeDestinationConfig
 		}
+		// MaxTraceSize is the maximum size in bytes of each serialized
+		// transaction trace, before compression.  Traces which exceed
+		// it, eg. because their segments have long SQL statements and
+		// stack traces, are summarized rather than exceeding the
+		// collector's payload limit: the segments of the deepest
+		// subtrees are removed, starting with the shortest, while the
+		// slowest path through the trace is kept.  The number and total
+		// duration of the segments removed beneath each remaining
+		// segment are recorded in its "summarized_segments" and
+		// "summarized_duration_millis" parameters.  Zero means no size
+		// limit.  The default is 256KiB.
+		MaxTraceSize int
 	}
 
 	// BrowserMonitoring contains settings which control the behavior of
@@ -169,6 +355,234 @@ type Config struct {
 	// be used to configure a proxy.
 	Transport http.RoundTripper
 
+	// CollectorTransport tunes the HTTP transport used to communicate with
+	// New Relic.  It is ignored if Transport is set.  Up to
+	// Harvest.MaxConcurrentRequests payloads are sent at once, so keeping at
+	// least that many idle connections per host avoids a new TLS handshake
+	// for most requests.
+	CollectorTransport struct {
+		// MaxIdleConns is the maximum number of idle connections kept
+		// across all New Relic hosts.  Zero means no limit.  The default
+		// is 100.
+		MaxIdleConns int
+		// MaxIdleConnsPerHost is the maximum number of idle connections
+		// kept for each New Relic host.  The default is 100.
+		MaxIdleConnsPerHost int
+		// IdleConnTimeout is how long an idle connection is kept before
+		// it is closed.  Zero means no limit.  The default is 90 seconds.
+		IdleConnTimeout time.Duration
+		// TLSSessionCacheSize is the number of TLS sessions cached so
+		// that new connections can resume a session rather than perform
+		// a full handshake.  Zero disables the cache.  The default is 64.
+		TLSSessionCacheSize int
+		// DisableHTTP2 prevents the use of HTTP/2, which otherwise allows
+		// the payloads sent at once to share a single connection.
+		DisableHTTP2 bool
+	}
+
+	// Compression controls how harvest payloads sent to New Relic are
+	// compressed.  Payloads are compressed using gzip unless Encoder is set
+	// and New Relic advertises support for its content encoding when the
+	// application connects.
+	Compression struct {
+		// Encoder compresses payloads using an alternative algorithm.
+		// The nrzstd integration provides a zstd Encoder.
+		Encoder PayloadEncoder
+	}
+
+	// Connect controls how the application retries connecting to New Relic
+	// after a failed connect attempt.
+	Connect struct {
+		// Backoff sets the delay between connect attempts.
+		Backoff struct {
+			// Schedule is the delay after each consecutive failed
+			// attempt.  The last delay is repeated for all further
+			// attempts.  Delays must not be negative.  The default is
+			// 15s, 15s, 30s, 1m, 2m, 5m.
+			Schedule []time.Duration
+			// Max caps each delay before jitter is applied, so that
+			// delays at the cap remain spread out.  Zero means no cap.
+			// The default is 5 minutes.
+			Max time.Duration
+			// Jitter randomizes each delay by up to this fraction of the
+			// delay in either direction, so that many processes started
+			// at the same time do not retry in lockstep.  It must be
+			// between 0 and 1.  The default is 0.2.
+			Jitter float64
+		}
+	}
+
+	// Clock is the source of time for transactions, segments, and harvest
+	// scheduling.  It defaults to the system clock and should only be
+	// replaced in tests.  It is not included in the settings reported to
+	// New Relic.
+	Clock Clock
+
+	// DebugCapture writes a copy of every harvest payload as indented JSON
+	// for local debugging.  Payloads are still sent to New Relic.  Captured
+	// payloads may contain sensitive data and this should not be enabled in
+	// production.
+	DebugCapture struct {
+		// Writer receives each payload as a JSON object containing the
+		// endpoint method, timestamp, and payload.  Writes are
+		// serialized.
+		Writer io.Writer
+		// Directory receives each payload as a separate file, named
+		// using the harvest time, a sequence number, and the endpoint
+		// method.  The directory must already exist.
+		Directory string
+	}
+
+	// Deployments controls how Application.RecordDeployment records
+	// deployment markers using the change tracking API.
+	//
+	// https://docs.newrelic.com/docs/change-tracking/change-tracking-introduction/
+	Deployments struct {
+		// APIKey is the User API key used to authenticate with the change
+		// tracking API.  It is not included in the settings reported to
+		// New Relic.
+		APIKey string
+		// UseLicense authenticates with the License when APIKey is
+		// empty.  Without it, RecordDeployment fails if APIKey is not
+		// set.
+		UseLicense bool
+		// Host overrides the NerdGraph host.  By default the host is
+		// chosen using the region of the license key.
+		Host string
+	}
+
+	// HarvestTelemetry controls supportability metrics describing the
+	// agent's own harvest cycles: the duration of each harvest, and the
+	// duration, uncompressed payload size, and response code of each
+	// collector request.  The metrics of a harvest are reported with the
+	// following harvest.  This is useful when diagnosing gaps in data.
+	HarvestTelemetry struct {
+		Enabled bool
+	}
+
+	// LifecycleEvents controls the recording of custom events describing
+	// the state of the agent, so that the health of a fleet of agents can
+	// be queried.  The event types are "AgentConnect", "AgentReconnect",
+	// "AgentShutdown", and "HarvestFailure", each with a "reason"
+	// attribute.  HarvestFailure events also have the attributes
+	// "endpoint", "statusCode", and "retainData", and are reported with the
+	// following harvest.  No event is recorded when New Relic disconnects
+	// the application since no further data is sent.  Lifecycle events
+	// require that CustomInsightsEvents are enabled.
+	LifecycleEvents struct {
+		// Enabled controls whether lifecycle events are recorded.  The
+		// default is false.
+		Enabled bool
+	}
+
+	// StatsD controls a listener which accepts metrics in the StatsD and
+	// DogStatsD line formats, eg. "checkout.latency:320|ms", and records
+	// them as custom metrics, see Application.RecordCustomMetric.  This
+	// allows services without a StatsD sidecar to send all of their metrics
+	// through the agent.  Counters ("c") are scaled by their sample rate,
+	// timers ("ms") are converted to seconds, and gauges ("g"), histograms
+	// ("h"), and distributions ("d") are recorded as given.  Sets ("s"),
+	// DogStatsD tags, events, and service checks are ignored.
+	StatsD struct {
+		Enabled bool
+		// Address is the local socket to listen on, either
+		// "udp://host:port" or "unixgram:///path/to/socket".  The
+		// default is "udp://127.0.0.1:8125".  A socket left at the
+		// unixgram path by a previous process is removed before
+		// listening, and the socket is removed when the application
+		// shuts down.
+		Address string
+	}
+
+	// RemoteConfig controls reading settings which may change while the
+	// application is running from a local source, such as the endpoint
+	// of New Relic Agent Control or a file mounted from a Kubernetes
+	// ConfigMap managed by the New Relic Kubernetes operator.  The source
+	// is read every PollPeriod, and when its contents change its settings
+	// are applied to the transactions started afterwards.  It contains a
+	// JSON object of the settings which may also be configured in the New
+	// Relic UI, and which it overrides:
+	//
+	//	{
+	//		"transaction_tracer.enabled": true,
+	//		"transaction_tracer.transaction_threshold": 0.5,
+	//		"transaction_tracer.stack_trace_threshold": 0.5,
+	//		"transaction_tracer.explain_threshold": 0.5,
+	//		"transaction_events.enabled": true,
+	//		"slow_sql.enabled": true,
+	//		"span_events.enabled": true,
+	//		"error_collector.enabled": true,
+	//		"error_collector.capture_events": true,
+	//		"error_collector.ignore_status_codes": [404],
+	//		"error_collector.ignore_classes": ["*errors.errorString"],
+	//		"error_collector.ignore_messages": {"*errors.errorString": ["canceled"]},
+	//		"cross_application_tracer.enabled": false
+	//	}
+	//
+	// Other settings are ignored.  A missing file or a 404 response
+	// reverts to the application's settings.  Invalid contents are logged
+	// and the settings last read are kept.
+	RemoteConfig struct {
+		Enabled bool
+		// File is the path of the file containing the settings.
+		File string
+		// URL is the HTTP endpoint returning the settings, eg.
+		// "http://localhost:51200/config".  It is used if File is
+		// empty.  Responses with an ETag header are re-read using
+		// If-None-Match.
+		URL string
+		// PollPeriod is how often the source is read.  The default is
+		// 30 seconds.
+		PollPeriod time.Duration
+	}
+
+	// Harvest controls access to the data of each harvest.
+	Harvest struct {
+		// Observer, if set, is given the metrics of each harvest
+		// after the metric rules from New Relic have been applied and
+		// before they are sent, see HarvestObserver.  It is not
+		// included in the settings reported to New Relic.
+		Observer HarvestObserver `json:"-"`
+		// MaxConcurrentRequests is the maximum number of payloads of a
+		// harvest which are sent to New Relic at the same time.  The
+		// first payload is always sent alone, so that a response which
+		// disconnects or restarts the application stops the harvest
+		// before the other payloads are sent.  Values less than 1 send
+		// the payloads one at a time.  The default is 4.
+		MaxConcurrentRequests int
+		// FailedMetrics limits the metrics of failed harvests which are
+		// retained to be sent with the next harvest, so that a long
+		// collector outage does not grow memory use.  The metrics
+		// dropped are counted by the supportability metrics
+		// "Supportability/Go/FailedHarvest/Metrics/Dropped/Cap", ".../Age",
+		// and ".../Attempts", the last counting metrics which failed to
+		// be sent in 5 consecutive harvests.
+		FailedMetrics struct {
+			// MaxMetrics is the maximum number of metrics retained
+			// from failed harvests, in addition to those recorded
+			// since.  Values of metrics already recorded are always
+			// merged.  Zero means no limit.  The default is 2000.
+			MaxMetrics int
+			// MaxAge is the maximum age of the metrics retained from
+			// failed harvests, measured from the start of their
+			// harvest period.  Zero means no limit.  The default is
+			// 10 minutes.
+			MaxAge time.Duration
+		}
+	}
+
+	// MemoryLimit is the approximate maximum number of bytes of data the
+	// Application buffers between harvests.  When the estimated size of the
+	// buffered data exceeds the limit, the lowest priority data is dropped
+	// until it fits: span events first, then custom events, log events,
+	// transaction events, and error events.  If dropping every event is not
+	// enough, the metrics of failed harvests are no longer retained.  The
+	// data dropped is counted by the supportability metrics
+	// "Supportability/Go/MemoryLimit/Dropped/<type>", and the custom events
+	// and log events dropped are passed to OnDataDropped.  Zero, the
+	// default, means no limit.
+	MemoryLimit int
+
 	// Utilization controls the detection and gathering of system
 	// information.
 	Utilization struct {
@@ -212,6 +626,42 @@ type Config struct {
 		DynoNamePrefixesToShorten []string
 	}
 
+	// ProcessMetadata controls the details of the process reported in the
+	// environment when the application connects.  These help to tell
+	// apart the processes of hosts running many applications, such as
+	// Windows services and IIS application pools.
+	ProcessMetadata struct {
+		// Enabled controls whether the process owner, the name of the
+		// Windows service running the process, and the IIS application
+		// pool from the APP_POOL_ID environment variable are reported.
+		// Default is true.
+		Enabled bool
+		// CommandLine controls whether the command line is also
+		// reported.  The values of arguments whose names look like
+		// secrets, eg. "--password=x", "-token x" or "/apikey:x", are
+		// replaced by "[REDACTED]" and the Scrubbing rules are applied.
+		// The command line is never reported when HighSecurity is
+		// enabled.  Default is true.
+		CommandLine bool
+	}
+
+	// Environment controls reporting environment variables in the
+	// environment when the application connects, eg. build and deployment
+	// metadata standardized in environment variables.
+	Environment struct {
+		// IncludeVars lists the names of the environment variables
+		// reported, eg. "BUILD_NUMBER".  A name ending in "*" matches
+		// every variable with that prefix.  The default is empty, so no
+		// variables are reported.  The values of variables whose names
+		// look like secrets, eg. "DEPLOY_TOKEN", are always redacted.
+		// No variables are reported when HighSecurity is enabled.
+		IncludeVars []string
+		// RedactPatterns are regular expressions.  The portions of the
+		// values of included variables matching any of them are replaced
+		// by "[REDACTED]".
+		RedactPatterns []string
+	}
+
 	// CrossApplicationTracer controls behavior relating to cross application
 	// tracing (CAT).  In the case where CrossApplicationTracer and
 	// DistributedTracer are both enabled, DistributedTracer takes precedence.
@@ -219,6 +669,15 @@ type Config struct {
 	// https://docs.newrelic.com/docs/apm/transactions/cross-application-traces/introduction-cross-application-traces
 	CrossApplicationTracer struct {
 		Enabled bool
+		// ReadOnly, when true along with Enabled, accepts the CAT headers
+		// of inbound requests but never adds CAT headers to outbound
+		// requests or responses.  Unlike full CAT, read-only CAT is not
+		// overridden by DistributedTracer, which makes it possible to
+		// link transactions with callers which still send CAT headers
+		// while migrating to distributed tracing.  The
+		// Supportability/CrossApplicationTracing/Inbound/Received metric
+		// counts transactions whose inbound request had CAT headers.
+		ReadOnly bool
 	}
 
 	// DistributedTracer controls behavior relating to Distributed Tracing.  In
@@ -240,6 +699,82 @@ type Config struct {
 		// ReservoirLimit sets the desired maximum span event reservoir limit
 		// for collecting span event data. The collector MAY override this value.
 		ReservoirLimit int
+		// RelationshipMetrics controls the recording of metrics describing
+		// the services which call this application, as identified by the
+		// distributed tracing headers they send.  When enabled, each
+		// transaction which accepts a New Relic payload records the metric
+		// "Relationship/Inbound/{type}/{account}/{app}/{transport}" scoped
+		// to the transaction name, eg. scoped to
+		// "OtherTransaction/Message/Kafka/Topic/Named/orders".  This allows
+		// service maps to show relationships over transports other than
+		// HTTP, such as Kafka topics and gRPC services.  The default is
+		// false.
+		RelationshipMetrics bool
+		// MaxTraceStateSize is the maximum length in bytes of the outbound
+		// W3C tracestate header.  Some proxies reject requests with large
+		// headers, and without a limit the tracestate header grows with
+		// each vendor a trace passes through.  Entries of other vendors
+		// are pruned following the W3C rules: entries longer than 128
+		// bytes are removed first, followed by the oldest entries, which
+		// are those at the end of the header.  The New Relic entry is
+		// never removed.  At most 32 entries are propagated regardless of
+		// this setting.  Zero means no size limit.  The default is 512.
+		MaxTraceStateSize int
+		// HeaderValidation controls the reporting of malformed inbound
+		// traceparent, tracestate, and newrelic headers, which are
+		// otherwise ignored, so that the upstream service corrupting them
+		// can be found.
+		HeaderValidation struct {
+			// Mode is HeaderValidationLenient or HeaderValidationStrict.
+			// The default of "" is HeaderValidationLenient.
+			Mode HeaderValidationMode
+			// LogMalformedHeaders controls whether, in strict mode, each
+			// malformed header is logged as an error along with the name
+			// of the transaction and a sample of the header's value.  The
+			// sample is truncated to 256 bytes and scrubbed using
+			// Config.Scrubbing.  The logs are limited by
+			// Config.ErrorLogLimit.  The default is false.
+			LogMalformedHeaders bool
+		}
+		// SamplingRules override the adaptive sampler for transactions
+		// whose names match, eg. to sample every checkout transaction and
+		// few health checks:
+		//
+		//	cfg.DistributedTracer.SamplingRules = []newrelic.SamplingRule{
+		//		{Pattern: `^POST /checkout$`, SampleRate: 1},
+		//		{Pattern: `/healthz`, SampleRate: 0.01},
+		//	}
+		//
+		// The rules are evaluated in order when the transaction's
+		// sampling decision is made, and the first matching rule
+		// decides.  Transactions matching no rule are sampled by the
+		// adaptive sampler, and transactions matching a rule do not
+		// count towards its target.  The decision of an inbound
+		// distributed trace payload takes precedence over the rules.
+		SamplingRules []SamplingRule
+		// DebugHeader allows a request to be traced in full on demand,
+		// eg. by a support engineer reproducing a problem in
+		// production.  A request containing a valid debug header is
+		// sampled regardless of the adaptive sampler, SamplingRules, and
+		// any inbound sampling decision, receives a transaction trace,
+		// and is collected at DetailLevelVerbose.  The header is
+		// propagated to outbound requests so that every service along
+		// the trace sharing the Secret does the same.  The header's
+		// value is created using DebugTraceHeaderValue.
+		DebugHeader struct {
+			// Name is the name of the header, eg. "X-Debug-Trace".
+			// The default of "" disables the debug header.
+			Name string
+			// Secret is the key used to sign the header's value.  It
+			// is required if Name is set, and is not included in the
+			// settings reported to New Relic.
+			Secret string
+			// MaxTTL is the longest time before its expiry that a
+			// header's value is accepted, so that a leaked value
+			// cannot be used indefinitely.  It must be positive if
+			// Name is set.  The default is one hour.
+			MaxTTL time.Duration
+		}
 	}
 
 	// SpanEvents controls behavior relating to Span Events.  Span Events
@@ -248,6 +783,47 @@ type Config struct {
 		Enabled bool
 		// Attributes controls the attributes included on Spans.
 		Attributes AttributeDestinationConfig
+		// Compression controls the merging of consecutive identical spans,
+		// such as those created by N+1 query patterns, which would
+		// otherwise use up the span events of the transaction.  When
+		// enabled, a span is merged into the previous span of the
+		// transaction if both have the same parent, name, and attributes,
+		// are no longer than MaxDuration, and have not been referenced as
+		// a parent.  The merged span covers the time from the start of
+		// the first span to the end of the last, and its
+		// nr.compressed.count and nr.compressed.duration attributes
+		// record the number of spans merged and their total duration.
+		Compression struct {
+			Enabled bool
+			// MaxDuration is the maximum duration of the spans which may
+			// be merged.  The default is 10ms.
+			MaxDuration time.Duration
+		}
+		// MaxAttributeValues, if non-zero, limits the number of distinct
+		// values of each custom span attribute in each harvest, protecting
+		// against instrumentation which adds unbounded values such as IDs
+		// or timestamps.  Further values are replaced with "[OVERFLOW]"
+		// and counted by the
+		// "Supportability/SpanEvent/UserAttributes/Overflow" metric.
+		// The limit does not apply to span events sent to Infinite
+		// Tracing.  The default is zero.
+		MaxAttributeValues int
+	}
+
+	// SegmentGuards protects against segments which are not ended properly,
+	// eg. because of a forgotten defer, and which would otherwise be missing
+	// or have misleading durations.
+	SegmentGuards struct {
+		// EndUnfinished ends the segments which are still open when their
+		// transaction ends.  Each is recorded as a custom segment ending
+		// with the transaction, named after the name given to
+		// Transaction.StartSegment, or "Unfinished" if none was given,
+		// with the nr.unfinished attribute.  The default is false.
+		EndUnfinished bool
+		// MaxDuration, if non-zero, is the maximum duration recorded for a
+		// segment.  Longer segments are recorded with this duration and
+		// the nr.durationCapped attribute.  The default is zero.
+		MaxDuration time.Duration
 	}
 
 	// InfiniteTracing controls behavior related to Infinite Tracing tail based
@@ -266,6 +842,27 @@ type Config struct {
 			// Port is the Trace Observer port to connect to. The default is
 			// 443.
 			Port int
+			// Hosts are additional Trace Observer hosts, each formatted as
+			// "host" or "host:port".  Port is used for hosts without a
+			// port.  When set, span events are load balanced across Host
+			// and Hosts using round robin, and hosts which cannot be
+			// reached are skipped until they recover.  Setting Hosts
+			// without Host also enables Infinite Tracing support.
+			Hosts []string
+			// TLS configures the TLS connection to the Trace Observer.  By
+			// default, the Trace Observer is verified using the system's
+			// root certificates and no client certificate is presented.
+			TLS struct {
+				// CertFile and KeyFile are the paths to the PEM encoded
+				// client certificate and key presented to the Trace
+				// Observer for mutual TLS.  Both must be set together.
+				CertFile string
+				KeyFile  string
+				// CAFile is the path to the PEM encoded certificates used
+				// instead of the system's root certificates to verify the
+				// Trace Observer.
+				CAFile string
+			}
 		}
 		// SpanEvents controls the behavior of the span events sent to the
 		// Trace Observer.
@@ -285,6 +882,14 @@ type Config struct {
 		InstanceReporting struct {
 			Enabled bool
 		}
+		// InstanceHostnameAliases rewrites the host of datastore
+		// segments, keyed by the host as given to the segment, eg.
+		// "pgbouncer.internal", to a logical instance name, eg.
+		// "orders-db".  Use this setting when datastore traffic flows
+		// through a proxy so that instance metrics and span attributes
+		// identify the database rather than the proxy.  Aliases apply
+		// only when InstanceReporting is enabled.
+		InstanceHostnameAliases map[string]string
 		// DatabaseNameReporting controls whether the database name is
 		// collected for datastore segments.
 		DatabaseNameReporting struct {
@@ -299,9 +904,48 @@ type Config struct {
 		SlowQuery struct {
 			Enabled   bool
 			Threshold time.Duration
+			// AggregateByInstance aggregates slow queries across the
+			// transactions of each harvest by both query and datastore
+			// instance, rather than by query alone, and records the
+			// names and GUIDs of up to five of the transactions which
+			// made the query.  This lets a query which is slow on one
+			// instance, or slow in many endpoints at once, be reported
+			// with its true count and example transactions.  The
+			// default is false.
+			AggregateByInstance bool
+		}
+		// SQLCommenter controls whether queries made using
+		// InstrumentSQLDriver and InstrumentSQLConnector, and so the
+		// nrmysql, nrpq, and nrsqlite3 integrations, have a
+		// sqlcommenter comment appended, eg.
+		//
+		//	SELECT * FROM users /*application='my%20app',traceparent='00-...-01'*/
+		//
+		// so that the queries in the database's slow query logs can be
+		// joined with their traces.  Comments are only added to queries
+		// made with a transaction-containing context which are not
+		// prepared statements, and not to queries which already contain
+		// a comment.  Comments make otherwise identical queries distinct,
+		// which may defeat query caches.  The default is false.
+		SQLCommenter struct {
+			Enabled bool
 		}
 	}
 
+	// BreakdownHostMetrics controls the recording of metrics giving the
+	// time each transaction spends in each external host and datastore
+	// instance.  When enabled, each transaction records the unscoped
+	// metrics "Breakdown/{txnName}/External/{host}/all" and
+	// "Breakdown/{txnName}/Datastore/instance/{product}/{host}/{port}",
+	// eg. "Breakdown/WebTransaction/Go/checkout/External/api.stripe.com/all",
+	// so that the time spent in a dependency by a given endpoint can be
+	// graphed directly.  Datastore instance metrics require
+	// DatastoreTracer.InstanceReporting.  These metrics have a high
+	// cardinality, so the default is false.
+	BreakdownHostMetrics struct {
+		Enabled bool
+	}
+
 	// Config Settings for Logs in Context features
 	ApplicationLogging ApplicationLogging
 
@@ -311,11 +955,138 @@ type Config struct {
 	// Events, and Browser timing header.
 	Attributes AttributeDestinationConfig
 
+	// OnAttributeDropped, if set, is called with each attribute or
+	// datastore query parameter dropped because its key or value is
+	// invalid, eg. a key longer than 255 bytes, or because the attribute
+	// limit has been reached.  This allows instrumentation bugs to be
+	// found and fixed.  It is called synchronously while the transaction
+	// is locked, and so must not use the transaction.  It is not included
+	// in the settings reported to New Relic.
+	OnAttributeDropped func(DroppedAttribute) `json:"-"`
+
+	// OnDataDropped, if set, is called with batches of the custom events and
+	// log events dropped by the agent, so that records which must not be
+	// lost can be written to a fallback sink.  Events are dropped when their
+	// reservoir is full, see CustomInsightsEvents.MaxSamplesStored and
+	// ApplicationLogging.Forwarding.MaxSamplesStored, when they cannot be
+	// sent to New Relic, and to stay within MemoryLimit.  Up to 1000 events
+	// of each type dropped because their reservoir is full or because of the
+	// MemoryLimit are kept until the next harvest, which increases memory
+	// use; further events are counted by the supportability metric
+	// "Supportability/Go/OnDataDropped/Discarded/<type>".  It is called from
+	// the goroutine sending each harvest.  Harvests may overlap, so it may
+	// be called concurrently and must be safe for concurrent use.  It should
+	// not block.  It is not included in the settings reported to New Relic.
+	OnDataDropped func(DroppedData) `json:"-"`
+
+	// SecurityAgent, if set, is notified as transactions start and end,
+	// as their external and datastore segments end, and as their request
+	// and outbound headers are accessed, so that a security module can
+	// be attached to the application, see SecurityAgent.  It is not
+	// included in the settings reported to New Relic.
+	SecurityAgent SecurityAgent `json:"-"`
+
+	// RedactAttributes, if set, is called when each transaction ends with
+	// the transaction's attributes, each of its errors, and every span
+	// event and transaction trace segment it recorded, before they are
+	// harvested.  The transaction's attributes are those of its
+	// transaction event, transaction trace, error events, and error
+	// traces.  The attributes of the RedactableData may be modified or
+	// deleted, eg. to mask account numbers which have leaked into URLs.
+	// Custom events and log events are not passed to RedactAttributes.
+	// This is intended as a defense-in-depth layer: sensitive data should
+	// not be recorded in the first place.  It is not included in the
+	// settings reported to New Relic.
+	RedactAttributes func(*RedactableData) `json:"-"`
+
+	// Scrubbing controls the masking of sensitive data, eg. card or account
+	// numbers, which may have leaked into error messages, span and segment
+	// attributes, request URIs, and log messages.  Each rule is applied in
+	// order before the data is harvested, and the number of values it
+	// modified is reported using a supportability metric.
+	Scrubbing struct {
+		Rules []ScrubbingRule
+	}
+
+	// AttributeLimits controls the length limits of custom attributes:
+	// those added using Transaction.AddAttribute and Segment.AddAttribute,
+	// the attributes of custom events, and the attributes of errors
+	// implementing ErrorAttributer.
+	AttributeLimits struct {
+		// KeyLength is the maximum length in bytes of attribute keys.
+		// Attributes with longer keys are dropped.  It must be at most
+		// 255, and zero is replaced by 255.  The default is 255.
+		KeyLength int
+		// ValueLength is the maximum length in bytes of string attribute
+		// values.  Longer values are truncated.  It must be at most 4095,
+		// and zero is replaced by 255.  The default is 255.
+		ValueLength int
+		// HashTruncated controls whether truncated values end with a
+		// hash of the complete value, eg. "#1a2b3c4d", so that long
+		// values sharing a prefix remain distinguishable.  The default
+		// is false.
+		HashTruncated bool
+	}
+
+	// RequestCapture controls the capture of request values which are not
+	// recorded by default.  The values of keys which look like they hold
+	// secrets, eg. "password" or "api_key", are redacted.  Nothing is
+	// captured when HighSecurity is enabled.
+	RequestCapture struct {
+		// QueryParameters is the list of HTTP query parameters added to
+		// transactions as "request.parameters.<name>" attributes.
+		QueryParameters []string
+		// Metadata is the list of request headers, or gRPC metadata keys,
+		// added to transactions as "request.headers.<key>" attributes.
+		// Keys are case insensitive and recorded in lower case.
+		Metadata []string
+	}
+
 	// RuntimeSampler controls the collection of runtime statistics like
 	// CPU/Memory usage, goroutine count, and GC pauses.
 	RuntimeSampler struct {
 		// Enabled controls whether runtime statistics are captured.
 		Enabled bool
+		// Diagnostics controls the collection of scheduler latency and
+		// goroutine growth, which often explain latency that the other
+		// runtime statistics do not.  The 50th and 99th percentile
+		// scheduler latencies are recorded as the
+		// "Go/Runtime/Scheduler/Latency/..." metrics and the goroutine
+		// growth per minute as the "Go/Runtime/Goroutines/Growth Rate"
+		// metric.  A "GoRuntimeDiagnostic" custom event is recorded
+		// each time a threshold is exceeded.  Diagnostics are only
+		// collected when RuntimeSampler.Enabled is also true.
+		Diagnostics struct {
+			// Enabled controls whether diagnostics are captured.
+			Enabled bool
+			// SchedulerLatencyThreshold is the 99th percentile
+			// scheduler latency above which an event is recorded.
+			// Zero disables the event.
+			SchedulerLatencyThreshold time.Duration
+			// GoroutineGrowthThreshold is the growth in the number of
+			// goroutines per minute above which an event is
+			// recorded.  Zero disables the event.
+			GoroutineGrowthThreshold float64
+		}
+	}
+
+	// Scraper reads the given expvar variables and runtime/metrics
+	// samples at each harvest and records them as custom metrics, so that
+	// services do not each need their own polling code.  Expvar variables
+	// are recorded as "{Prefix}Expvar/{name}" and runtime metrics as
+	// "{Prefix}Runtime{name}", eg. "Custom/Runtime/sched/goroutines:goroutines".
+	Scraper struct {
+		// Expvars are the names of the expvar variables to record.  The
+		// variables must have numeric values.  A key of an expvar.Map
+		// is named "{map}.{key}", eg. "cache.hits".
+		Expvars []string
+		// RuntimeMetrics are the names of the runtime/metrics samples
+		// to record, eg. "/gc/heap/objects:objects".  Histogram
+		// metrics are not supported.
+		RuntimeMetrics []string
+		// Prefix is the prefix of the metric names.  The default is
+		// "Custom/".
+		Prefix string
 	}
 
 	// ServerlessMode contains fields which control behavior when running in
@@ -418,6 +1189,8 @@ type Config struct {
 		RedactIgnoredPrefixes bool
 		// IgnoredPrefixes is a list of module path prefixes. Any module whose import pathname
 		// begins with one of these prefixes is excluded from the dependency reporting.
+		// A module swapped by a replace directive is matched using the path of its
+		// replacement, which is the path reported.
 		// This list of ignored prefixes itself is not reported outside the agent.
 		IgnoredPrefixes []string
 	}
@@ -530,8 +1303,27 @@ type ApplicationLogging struct {
 		Enabled bool
 		// Number of log records to send per minute to New Relic.
 		// Controls the overall memory consumption when using log forwarding.
-		// SHOULD be sent as part of the harvest_limits on Connect.
+		// SHOULD be sent as part of the harvest_limits on Connect.  Values
+		// greater than 10000, or negative, use the default of 10000.  Logs
+		// which do not fit are sampled out and counted by the
+		// Logging/Forwarding/Dropped metric.
 		MaxSamplesStored int
+		// AttributeAllowList lists the keys of the LogData.Attributes
+		// recorded as attributes of forwarded log events, eg. "order_id".
+		// A key ending in "*" matches every key with that prefix.  Other
+		// attributes are dropped, as are values which would be invalid as
+		// custom attributes.  The default is empty, so no attributes are
+		// recorded.
+		AttributeAllowList []string
+		// CaptureErrors controls whether logs of severity ERROR, FATAL,
+		// CRITICAL, or PANIC recorded using Transaction.RecordLog are also
+		// recorded as errors of the transaction, as if by
+		// Transaction.NoticeError, so that errors need not be both logged
+		// and noticed.  Logs whose message equals or contains that of an
+		// error already recorded by the transaction are not recorded
+		// again.  The errors have the class "LogError" and the log's
+		// attributes.  Requires Enabled.  The default is false.
+		CaptureErrors bool
 	}
 	Metrics struct {
 		// Toggles whether the agent gathers the the user facing Logging/lines and Logging/lines/{SEVERITY}
@@ -544,6 +1336,86 @@ type ApplicationLogging struct {
 	}
 }
 
+// DroppedAttribute describes an attribute dropped by the agent, see
+// Config.OnAttributeDropped.
+type DroppedAttribute struct {
+	// Key is the key of the attribute.
+	Key string
+	// Kind is the kind of attribute dropped: "transaction" for
+	// attributes added using Transaction.AddAttribute, "span" for
+	// attributes added to segments, or "query parameter" for datastore
+	// query parameters.
+	Kind string
+	// Reason is the error describing why the attribute was dropped.
+	Reason error
+}
+
+// The reasons of DroppedData.
+const (
+	// DroppedReasonReservoirFull is the reason of events sampled out
+	// because their reservoir was full.
+	DroppedReasonReservoirFull = "reservoir full"
+	// DroppedReasonHarvestFailure is the reason of events discarded
+	// because they could not be sent to New Relic.
+	DroppedReasonHarvestFailure = "harvest failure"
+	// DroppedReasonMemoryLimit is the reason of events dropped to stay
+	// within Config.MemoryLimit.
+	DroppedReasonMemoryLimit = "memory limit"
+)
+
+// DroppedData is a batch of events dropped by the agent, see
+// Config.OnDataDropped.
+type DroppedData struct {
+	// Type is "CustomEvent" for custom events and "LogEvent" for log
+	// events.
+	Type string
+	// Reason is DroppedReasonReservoirFull,
+	// DroppedReasonHarvestFailure, or DroppedReasonMemoryLimit.
+	Reason string
+	// Events holds the JSON of each event.  Custom events are objects
+	// with "eventType", "timestamp", and their attributes, as accepted by
+	// the Event API.  Log events are objects with "level", "message",
+	// "timestamp", and the linking and custom attributes of the log.
+	Events []json.RawMessage
+}
+
+// attributeLimits returns the custom attribute length limits.
+func (c Config) attributeLimits() attributeLimits {
+	return attributeLimits{
+		keyLengthLimit:   c.AttributeLimits.KeyLength,
+		valueLengthLimit: c.AttributeLimits.ValueLength,
+		hashTruncated:    c.AttributeLimits.HashTruncated,
+	}
+}
+
+// RedactableData is a transaction, error, span event, or transaction trace
+// segment passed to Config.RedactAttributes.
+type RedactableData struct {
+	// Kind is "transaction" for the attributes of a transaction, "error"
+	// for the attributes of one of its errors, "span" for span events,
+	// and "segment" for transaction trace segments.
+	Kind string
+	// Name is the name of the transaction, the class of the error, or the
+	// name of the span or segment, eg. "External/example.com/http/GET".
+	Name string
+	// AgentAttributes and UserAttributes hold the attributes.  Values of
+	// spans and segments are strings, ints, float64s, or bools; values
+	// set to other types are dropped.  Map valued user attributes and
+	// datastore query parameters are not included and are left
+	// unchanged.  Segments do not distinguish user attributes, so all of
+	// their attributes are in AgentAttributes, and the attributes of an
+	// error are all in UserAttributes.
+	AgentAttributes map[string]interface{}
+	UserAttributes  map[string]interface{}
+}
+
+// attributeDropped calls OnAttributeDropped, if set.
+func (c Config) attributeDropped(key string, kind string, reason error) {
+	if nil != c.OnAttributeDropped {
+		c.OnAttributeDropped(DroppedAttribute{Key: key, Kind: kind, Reason: reason})
+	}
+}
+
 // AttributeDestinationConfig controls the attributes sent to each destination.
 // For more information, see:
 // https://docs.newrelic.com/docs/agents/manage-apm-agents/agent-data/agent-attributes
@@ -568,19 +1440,82 @@ type AttributeDestinationConfig struct {
 	//	cfg.Attributes.Exclude = append(cfg.Attributes.Exclude, "request.*")
 	//
 	Exclude []string
+	// RequestURIPolicy controls how the "request.uri" attribute is
+	// recorded, for endpoints whose paths contain identifiers which must
+	// not be sent.  It may only be set in Config.Attributes, and applies to
+	// every destination: setting it for another destination is an error.
+	// The default of "" is RequestURIFull.
+	RequestURIPolicy RequestURIPolicy
 }
 
+// RequestURIPolicy controls how the "request.uri" attribute is recorded, see
+// AttributeDestinationConfig.RequestURIPolicy.
+type RequestURIPolicy string
+
+const (
+	// RequestURIFull records the request's URL without query parameters.
+	RequestURIFull RequestURIPolicy = "full"
+	// RequestURITemplate records the name given to the transaction, eg.
+	// the pattern "GET /users/{id}" used by WrapHandle, in place of the
+	// URL.  The URL is never recorded, so this policy is only useful
+	// when transactions are named after their routes.
+	RequestURITemplate RequestURIPolicy = "template"
+	// RequestURIHash records the hex encoded SHA-256 hash of the URL, so
+	// that requests to the same URL may be grouped without the URL being
+	// sent.
+	RequestURIHash RequestURIPolicy = "hash"
+)
+
+// HeaderValidationMode controls the reporting of malformed distributed
+// tracing headers, see Config.DistributedTracer.HeaderValidation.
+type HeaderValidationMode string
+
+const (
+	// HeaderValidationLenient ignores malformed headers.  Only the
+	// general distributed tracing supportability metrics are recorded.
+	HeaderValidationLenient HeaderValidationMode = "lenient"
+	// HeaderValidationStrict additionally records the supportability
+	// metric "Supportability/DistributedTrace/MalformedHeader/{header}"
+	// for each malformed header, and logs them if
+	// HeaderValidation.LogMalformedHeaders is set.
+	HeaderValidationStrict HeaderValidationMode = "strict"
+)
+
 // defaultConfig creates a Config populated with default settings.
 func defaultConfig() Config {
 	c := Config{}
 
 	c.Enabled = true
 	c.Labels = make(map[string]string)
+	c.Clock = systemClock{}
+	c.AttributeLimits.KeyLength = attributeKeyLengthLimit
+	c.AttributeLimits.ValueLength = attributeValueLengthLimit
+	c.ErrorLogLimit.MaxPerInterval = 10
+	c.ErrorLogLimit.Interval = time.Minute
+	c.CollectorTransport.MaxIdleConns = 100
+	c.CollectorTransport.MaxIdleConnsPerHost = 100
+	c.CollectorTransport.IdleConnTimeout = 90 * time.Second
+	c.CollectorTransport.TLSSessionCacheSize = 64
+	c.Connect.Backoff.Schedule = append([]time.Duration(nil), connectBackoffSchedule...)
+	c.Connect.Backoff.Max = connectBackoffMax
+	c.Connect.Backoff.Jitter = connectBackoffJitter
 	c.CustomInsightsEvents.Enabled = true
 	c.CustomInsightsEvents.MaxSamplesStored = internal.MaxCustomEvents
+	c.CustomInsightsEvents.EventAPIFallback.FailedHarvests = 3
 	c.TransactionEvents.Enabled = true
 	c.TransactionEvents.Attributes.Enabled = true
 	c.TransactionEvents.MaxSamplesStored = internal.MaxTxnEvents
+	c.TransactionDurationHistogram.Enabled = false
+	c.TransactionDurationHistogram.Buckets = []time.Duration{
+		50 * time.Millisecond,
+		100 * time.Millisecond,
+		250 * time.Millisecond,
+		500 * time.Millisecond,
+		1 * time.Second,
+		2500 * time.Millisecond,
+		5 * time.Second,
+		10 * time.Second,
+	}
 	c.HighSecurity = false
 	c.ErrorCollector.Enabled = true
 	c.ErrorCollector.CaptureEvents = true
@@ -591,6 +1526,7 @@ func defaultConfig() Config {
 		http.StatusNotFound, // 404
 	}
 	c.ErrorCollector.Attributes.Enabled = true
+	c.ErrorCollector.StackTraceDepth = maxStackTraceFrames
 	c.Utilization.DetectAWS = true
 	c.Utilization.DetectAzure = true
 	c.Utilization.DetectPCF = true
@@ -599,12 +1535,18 @@ func defaultConfig() Config {
 	c.Utilization.DetectKubernetes = true
 	c.Attributes.Enabled = true
 	c.RuntimeSampler.Enabled = true
+	c.RuntimeSampler.Diagnostics.SchedulerLatencyThreshold = 10 * time.Millisecond
+	c.RuntimeSampler.Diagnostics.GoroutineGrowthThreshold = 1000
 
 	c.TransactionTracer.Enabled = true
 	c.TransactionTracer.Threshold.IsApdexFailing = true
 	c.TransactionTracer.Threshold.Duration = 500 * time.Millisecond
 	c.TransactionTracer.Segments.Threshold = 2 * time.Millisecond
 	c.TransactionTracer.Segments.StackTraceThreshold = 500 * time.Millisecond
+	c.TransactionTracer.MaxTraceSize = defaultMaxTxnTraceSize
+	c.Harvest.MaxConcurrentRequests = 4
+	c.Harvest.FailedMetrics.MaxMetrics = maxMetrics
+	c.Harvest.FailedMetrics.MaxAge = 10 * time.Minute
 	c.TransactionTracer.Attributes.Enabled = true
 	c.TransactionTracer.Segments.Attributes.Enabled = true
 
@@ -622,8 +1564,12 @@ func defaultConfig() Config {
 	c.CrossApplicationTracer.Enabled = false
 	c.DistributedTracer.Enabled = true
 	c.DistributedTracer.ReservoirLimit = defaultMaxSpanEvents
+	c.DistributedTracer.MaxTraceStateSize = defaultMaxTraceStateSize
+	c.DistributedTracer.DebugHeader.MaxTTL = defaultDebugTraceMaxTTL
 	c.SpanEvents.Enabled = true
 	c.SpanEvents.Attributes.Enabled = true
+	c.SpanEvents.Compression.Enabled = false
+	c.SpanEvents.Compression.MaxDuration = 10 * time.Millisecond
 
 	c.DatastoreTracer.InstanceReporting.Enabled = true
 	c.DatastoreTracer.DatabaseNameReporting.Enabled = true
@@ -631,11 +1577,17 @@ func defaultConfig() Config {
 	c.DatastoreTracer.SlowQuery.Enabled = true
 	c.DatastoreTracer.SlowQuery.Threshold = 10 * time.Millisecond
 
+	c.StatsD.Address = "udp://127.0.0.1:8125"
+	c.RemoteConfig.PollPeriod = 30 * time.Second
+	c.Scraper.Prefix = "Custom/"
+
 	c.ServerlessMode.ApdexThreshold = 500 * time.Millisecond
 	c.ServerlessMode.Enabled = false
 
 	c.Heroku.UseDynoNames = true
 	c.Heroku.DynoNamePrefixesToShorten = []string{"scheduler", "run"}
+	c.ProcessMetadata.Enabled = true
+	c.ProcessMetadata.CommandLine = true
 
 	c.InfiniteTracing.TraceObserver.Port = 443
 	c.InfiniteTracing.SpanEvents.QueueSize = 10000
@@ -664,6 +1616,28 @@ var (
 	errAppNameLimit                     = fmt.Errorf("max of %d rollup application names", appNameLimit)
 	errHighSecurityWithSecurityPolicies = errors.New("SecurityPoliciesToken and HighSecurity are incompatible; please ensure HighSecurity is set to false if SecurityPoliciesToken is a non-empty string and a security policy has been set for your account")
 	errInfTracingServerless             = errors.New("ServerlessMode cannot be used with Infinite Tracing")
+	errEventAPIFallbackMissingKey       = errors.New("CustomInsightsEvents.EventAPIFallback requires InsertKey and AccountID")
+	errConnectBackoffJitter             = errors.New("Connect.Backoff.Jitter must be between 0 and 1")
+	errConnectBackoffSchedule           = errors.New("Connect.Backoff.Schedule delays must not be negative")
+	errTraceObserverClientCert          = errors.New("InfiniteTracing.TraceObserver.TLS requires both CertFile and KeyFile")
+	errTraceObserverCAFile              = errors.New("InfiniteTracing.TraceObserver.TLS.CAFile contains no certificates")
+	errHistogramBuckets                 = errors.New("TransactionDurationHistogram.Buckets must be positive and increasing")
+	errHistogramNoBuckets               = errors.New("TransactionDurationHistogram.Buckets must not be empty when enabled")
+	errAttributeLimits                  = fmt.Errorf("AttributeLimits.KeyLength must be at most %d and AttributeLimits.ValueLength at most %d",
+		attributeKeyLengthLimit, maxAttributeValueLengthLimit)
+	errScrubbingRuleName = errors.New("Scrubbing.Rules must each have a Name")
+	errStatsDAddress     = errors.New(`StatsD.Address must have the form "udp://host:port" or "unixgram:///path"`)
+	errApdexZone         = errors.New(`Apdex.ResponseCodeZones values must be "S", "T", or "F"`)
+	errRequestURIPolicy  = errors.New(`Attributes.RequestURIPolicy must be "", "full", "template", or "hash"`)
+	errRequestURIDest    = errors.New("RequestURIPolicy may only be set in Attributes")
+	errHeaderValidation  = errors.New(`DistributedTracer.HeaderValidation.Mode must be "", "lenient", or "strict"`)
+	errSamplingRuleRate  = errors.New("DistributedTracer.SamplingRules SampleRate must be between 0 and 1")
+	errDebugHeaderSecret = errors.New("DistributedTracer.DebugHeader requires a Secret")
+	errDebugHeaderMaxTTL = errors.New("DistributedTracer.DebugHeader.MaxTTL must be positive")
+	errMemoryLimit       = errors.New("MemoryLimit must not be negative")
+
+	errRemoteConfigSource = errors.New("RemoteConfig requires File or URL")
+	errRemoteConfigPeriod = errors.New("RemoteConfig.PollPeriod must be positive")
 )
 
 // validate checks the config for improper fields.  If the config is invalid,
@@ -688,16 +1662,108 @@ func (c Config) validate() error {
 	if strings.Count(c.AppName, ";") >= appNameLimit {
 		return errAppNameLimit
 	}
-	if "" != c.InfiniteTracing.TraceObserver.Host && c.ServerlessMode.Enabled {
+	if ("" != c.InfiniteTracing.TraceObserver.Host || 0 != len(c.InfiniteTracing.TraceObserver.Hosts)) && c.ServerlessMode.Enabled {
 		return errInfTracingServerless
 	}
+	if fb := c.CustomInsightsEvents.EventAPIFallback; fb.Enabled && ("" == fb.InsertKey || "" == fb.AccountID) {
+		return errEventAPIFallbackMissingKey
+	}
+	if j := c.Connect.Backoff.Jitter; j < 0 || j > 1 {
+		return errConnectBackoffJitter
+	}
+	for _, d := range c.Connect.Backoff.Schedule {
+		if d < 0 {
+			return errConnectBackoffSchedule
+		}
+	}
+	if l := c.AttributeLimits; l.KeyLength < 0 || l.KeyLength > attributeKeyLengthLimit ||
+		l.ValueLength < 0 || l.ValueLength > maxAttributeValueLengthLimit {
+		return errAttributeLimits
+	}
+	if c.TransactionDurationHistogram.Enabled && 0 == len(c.TransactionDurationHistogram.Buckets) {
+		return errHistogramNoBuckets
+	}
+	for i, b := range c.TransactionDurationHistogram.Buckets {
+		if b <= 0 || (i > 0 && b <= c.TransactionDurationHistogram.Buckets[i-1]) {
+			return errHistogramBuckets
+		}
+	}
+	if err := validateScrubbingRules(c.Scrubbing.Rules); nil != err {
+		return err
+	}
+	for _, zone := range c.Apdex.ResponseCodeZones {
+		if apdexNone == zone.zone() {
+			return errApdexZone
+		}
+	}
+	switch c.Attributes.RequestURIPolicy {
+	case "", RequestURIFull, RequestURITemplate, RequestURIHash:
+	default:
+		return errRequestURIPolicy
+	}
+	for _, dest := range []AttributeDestinationConfig{
+		c.TransactionEvents.Attributes,
+		c.ErrorCollector.Attributes,
+		c.TransactionTracer.Attributes,
+		c.TransactionTracer.Segments.Attributes,
+		c.BrowserMonitoring.Attributes,
+		c.SpanEvents.Attributes,
+	} {
+		if "" != dest.RequestURIPolicy {
+			return errRequestURIDest
+		}
+	}
+	switch c.DistributedTracer.HeaderValidation.Mode {
+	case "", HeaderValidationLenient, HeaderValidationStrict:
+	default:
+		return errHeaderValidation
+	}
+	if err := validateSamplingRules(c.DistributedTracer.SamplingRules); nil != err {
+		return err
+	}
+	if dh := c.DistributedTracer.DebugHeader; "" != dh.Name {
+		if "" == dh.Secret {
+			return errDebugHeaderSecret
+		}
+		if dh.MaxTTL <= 0 {
+			return errDebugHeaderMaxTTL
+		}
+	}
+	if c.MemoryLimit < 0 {
+		return errMemoryLimit
+	}
+	if err := validateIgnoreMessages(c.ErrorCollector.IgnoreMessages); nil != err {
+		return err
+	}
+	if err := validateRuntimeMetrics(c.Scraper.RuntimeMetrics); nil != err {
+		return err
+	}
+	if c.StatsD.Enabled {
+		if _, _, err := statsDNetworkAddress(c.StatsD.Address); nil != err {
+			return err
+		}
+	}
+	for _, pattern := range c.Environment.RedactPatterns {
+		if _, err := regexp.Compile(pattern); nil != err {
+			return fmt.Errorf("invalid Environment.RedactPatterns %q: %v", pattern, err)
+		}
+	}
+	if c.RemoteConfig.Enabled {
+		if "" == c.RemoteConfig.File && "" == c.RemoteConfig.URL {
+			return errRemoteConfigSource
+		}
+		if c.RemoteConfig.PollPeriod <= 0 {
+			return errRemoteConfigPeriod
+		}
+	}
 
 	return nil
 }
 
 func (c Config) validateTraceObserverConfig() (*observerURL, error) {
 	configHost := c.InfiniteTracing.TraceObserver.Host
-	if "" == configHost {
+	hosts := c.InfiniteTracing.TraceObserver.Hosts
+	if "" == configHost && 0 == len(hosts) {
 		// This is the only instance from which we can return nil, nil.
 		// If the user requests use of a trace observer, we must either provide
 		// them with a valid observerURL _or_ alert them to the failure to do so.
@@ -709,10 +1775,66 @@ func (c Config) validateTraceObserverConfig() (*observerURL, error) {
 	if !c.DistributedTracer.Enabled || !c.SpanEvents.Enabled {
 		return nil, errSpanOrDTDisabled
 	}
-	return &observerURL{
-		host:   fmt.Sprintf("%s:%d", configHost, c.InfiniteTracing.TraceObserver.Port),
-		secure: configHost != localTestingHost,
-	}, nil
+	port := strconv.Itoa(c.InfiniteTracing.TraceObserver.Port)
+	var all []string
+	if "" != configHost {
+		all = append(all, net.JoinHostPort(configHost, port))
+	}
+	for _, h := range hosts {
+		if _, _, err := net.SplitHostPort(h); nil != err {
+			h = net.JoinHostPort(h, port)
+		}
+		all = append(all, h)
+	}
+	primary, _, _ := net.SplitHostPort(all[0])
+	url := &observerURL{
+		host:   all[0],
+		secure: primary != localTestingHost,
+	}
+	if len(all) > 1 {
+		url.hosts = all
+	}
+	tlsConfig, err := c.traceObserverTLSConfig()
+	if nil != err {
+		return nil, err
+	}
+	if nil != tlsConfig {
+		url.secure = true
+		url.tlsConfig = tlsConfig
+	}
+	return url, nil
+}
+
+// traceObserverTLSConfig returns the TLS configuration used to connect to the
+// trace observer, or nil if the default configuration should be used.
+func (c Config) traceObserverTLSConfig() (*tls.Config, error) {
+	cfg := c.InfiniteTracing.TraceObserver.TLS
+	if "" == cfg.CertFile && "" == cfg.KeyFile && "" == cfg.CAFile {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{}
+	if "" != cfg.CertFile || "" != cfg.KeyFile {
+		if "" == cfg.CertFile || "" == cfg.KeyFile {
+			return nil, errTraceObserverClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if nil != err {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if "" != cfg.CAFile {
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if nil != err {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errTraceObserverCAFile
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
 }
 
 // maxTxnEvents returns the configured maximum number of Transaction Events if it has been configured
@@ -766,11 +1888,92 @@ func copyConfigReferenceFields(cfg Config) Config {
 			cp.Labels[key] = val
 		}
 	}
+	if nil != cfg.Apdex.ResponseCodeZones {
+		cp.Apdex.ResponseCodeZones = make(map[int]ApdexZone, len(cfg.Apdex.ResponseCodeZones))
+		for code, zone := range cfg.Apdex.ResponseCodeZones {
+			cp.Apdex.ResponseCodeZones[code] = zone
+		}
+	}
+	if nil != cfg.DeploymentMetadata {
+		cp.DeploymentMetadata = make(map[string]string, len(cfg.DeploymentMetadata))
+		for key, val := range cfg.DeploymentMetadata {
+			cp.DeploymentMetadata[key] = val
+		}
+	}
+	if nil != cfg.Connect.Backoff.Schedule {
+		schedule := make([]time.Duration, len(cfg.Connect.Backoff.Schedule))
+		copy(schedule, cfg.Connect.Backoff.Schedule)
+		cp.Connect.Backoff.Schedule = schedule
+	}
 	if nil != cfg.ErrorCollector.IgnoreStatusCodes {
 		ignored := make([]int, len(cfg.ErrorCollector.IgnoreStatusCodes))
 		copy(ignored, cfg.ErrorCollector.IgnoreStatusCodes)
 		cp.ErrorCollector.IgnoreStatusCodes = ignored
 	}
+	if nil != cfg.RequestCapture.QueryParameters {
+		params := make([]string, len(cfg.RequestCapture.QueryParameters))
+		copy(params, cfg.RequestCapture.QueryParameters)
+		cp.RequestCapture.QueryParameters = params
+	}
+	if nil != cfg.InfiniteTracing.TraceObserver.Hosts {
+		hosts := make([]string, len(cfg.InfiniteTracing.TraceObserver.Hosts))
+		copy(hosts, cfg.InfiniteTracing.TraceObserver.Hosts)
+		cp.InfiniteTracing.TraceObserver.Hosts = hosts
+	}
+	if nil != cfg.ErrorCollector.IgnoreClasses {
+		classes := make([]string, len(cfg.ErrorCollector.IgnoreClasses))
+		copy(classes, cfg.ErrorCollector.IgnoreClasses)
+		cp.ErrorCollector.IgnoreClasses = classes
+	}
+	if nil != cfg.ErrorCollector.IgnoreMessages {
+		messages := make(map[string][]string, len(cfg.ErrorCollector.IgnoreMessages))
+		for class, patterns := range cfg.ErrorCollector.IgnoreMessages {
+			messages[class] = append([]string(nil), patterns...)
+		}
+		cp.ErrorCollector.IgnoreMessages = messages
+	}
+	if nil != cfg.Scrubbing.Rules {
+		rules := make([]ScrubbingRule, len(cfg.Scrubbing.Rules))
+		copy(rules, cfg.Scrubbing.Rules)
+		cp.Scrubbing.Rules = rules
+	}
+	if nil != cfg.DistributedTracer.SamplingRules {
+		rules := make([]SamplingRule, len(cfg.DistributedTracer.SamplingRules))
+		copy(rules, cfg.DistributedTracer.SamplingRules)
+		cp.DistributedTracer.SamplingRules = rules
+	}
+	if nil != cfg.TransactionDurationHistogram.Buckets {
+		buckets := make([]time.Duration, len(cfg.TransactionDurationHistogram.Buckets))
+		copy(buckets, cfg.TransactionDurationHistogram.Buckets)
+		cp.TransactionDurationHistogram.Buckets = buckets
+	}
+	if nil != cfg.DatastoreTracer.InstanceHostnameAliases {
+		aliases := make(map[string]string, len(cfg.DatastoreTracer.InstanceHostnameAliases))
+		for host, alias := range cfg.DatastoreTracer.InstanceHostnameAliases {
+			aliases[host] = alias
+		}
+		cp.DatastoreTracer.InstanceHostnameAliases = aliases
+	}
+	if nil != cfg.Scraper.Expvars {
+		cp.Scraper.Expvars = append([]string(nil), cfg.Scraper.Expvars...)
+	}
+	if nil != cfg.Scraper.RuntimeMetrics {
+		cp.Scraper.RuntimeMetrics = append([]string(nil), cfg.Scraper.RuntimeMetrics...)
+	}
+	if nil != cfg.Environment.IncludeVars {
+		cp.Environment.IncludeVars = append([]string(nil), cfg.Environment.IncludeVars...)
+	}
+	if nil != cfg.Environment.RedactPatterns {
+		cp.Environment.RedactPatterns = append([]string(nil), cfg.Environment.RedactPatterns...)
+	}
+	if nil != cfg.ApplicationLogging.Forwarding.AttributeAllowList {
+		cp.ApplicationLogging.Forwarding.AttributeAllowList = append([]string(nil), cfg.ApplicationLogging.Forwarding.AttributeAllowList...)
+	}
+	if nil != cfg.RequestCapture.Metadata {
+		metadata := make([]string, len(cfg.RequestCapture.Metadata))
+		copy(metadata, cfg.RequestCapture.Metadata)
+		cp.RequestCapture.Metadata = metadata
+	}
 
 	cp.Attributes = copyDestConfig(cfg.Attributes)
 	cp.ErrorCollector.Attributes = copyDestConfig(cfg.ErrorCollector.Attributes)
@@ -790,7 +1993,19 @@ func transportSetting(t http.RoundTripper) interface{} {
 	return fmt.Sprintf("%T", t)
 }
 
+func writerSetting(w io.Writer) interface{} {
+	if nil == w {
+		return nil
+	}
+	return fmt.Sprintf("%T", w)
+}
+
 func loggerSetting(lg Logger) interface{} {
+	// The limiter added for Config.ErrorLogLimit is not reported in place
+	// of the Logger it wraps.
+	if lim, ok := lg.(*errorLogLimiter); ok {
+		lg = lim.Logger
+	}
 	if nil == lg {
 		return nil
 	}
@@ -813,6 +2028,11 @@ func (s settings) MarshalJSON() ([]byte, error) {
 	c.Transport = nil
 	l := c.Logger
 	c.Logger = nil
+	c.Clock = nil
+	captureWriter := c.DebugCapture.Writer
+	c.DebugCapture.Writer = nil
+	encoder := c.Compression.Encoder
+	c.Compression.Encoder = nil
 
 	js, err := json.Marshal(c)
 	if err != nil {
@@ -826,8 +2046,28 @@ func (s settings) MarshalJSON() ([]byte, error) {
 	// The License field is not simply ignored by adding the `json:"-"` tag
 	// to it since we want to allow consumers to populate Config from JSON.
 	delete(fields, `License`)
+	delete(fields, `Clock`)
+	if customEvents, ok := fields["CustomInsightsEvents"].(map[string]interface{}); ok {
+		if fallback, ok := customEvents["EventAPIFallback"].(map[string]interface{}); ok {
+			delete(fallback, "InsertKey")
+		}
+	}
+	if deployments, ok := fields["Deployments"].(map[string]interface{}); ok {
+		delete(deployments, "APIKey")
+	}
+	if dt, ok := fields["DistributedTracer"].(map[string]interface{}); ok {
+		if debugHeader, ok := dt["DebugHeader"].(map[string]interface{}); ok {
+			delete(debugHeader, "Secret")
+		}
+	}
 	fields[`Transport`] = transportSetting(transport)
 	fields[`Logger`] = loggerSetting(l)
+	if capture, ok := fields["DebugCapture"].(map[string]interface{}); ok {
+		capture["Writer"] = writerSetting(captureWriter)
+	}
+	if compression, ok := fields["Compression"].(map[string]interface{}); ok {
+		compression["Encoder"] = payloadEncoderSetting(encoder)
+	}
 
 	// Browser monitoring support.
 	if c.BrowserMonitoring.Enabled {
@@ -959,6 +2199,23 @@ type config struct {
 	metadata         map[string]string
 	hostname         string
 	traceObserverURL *observerURL
+	// scrubber applies the Config.Scrubbing rules.  It is shared by every
+	// appRun so that its counts are reported once.
+	scrubber *scrubber
+	// errorIgnorer applies Config.ErrorCollector.IgnoreClasses and
+	// IgnoreMessages.
+	errorIgnorer *errorIgnorer
+	// samplingRules are the compiled
+	// Config.DistributedTracer.SamplingRules.
+	samplingRules []samplingRule
+	// remoteConfig holds the settings last read from the
+	// Config.RemoteConfig source, or nil.
+	remoteConfig *internal.ServerSideConfig
+	// process holds the Config.ProcessMetadata details.
+	process processMetadata
+	// environmentVars are the variables included by
+	// Config.Environment.IncludeVars.
+	environmentVars []environmentVar
 }
 
 func (c Config) computeDynoHostname(getenv func(string) string) string {
@@ -996,6 +2253,12 @@ func newInternalConfig(cfg Config, getenv func(string) string, environ []string)
 	if nil == cfg.Logger {
 		cfg.Logger = logger.ShimLogger{}
 	}
+	if nil == cfg.Clock {
+		cfg.Clock = systemClock{}
+	}
+	if lim := cfg.ErrorLogLimit; lim.MaxPerInterval > 0 && lim.Interval > 0 {
+		cfg.Logger = newErrorLogLimiter(cfg.Logger, cfg.Clock, lim.MaxPerInterval, lim.Interval)
+	}
 	var hostname string
 	if host := cfg.computeDynoHostname(getenv); host != "" {
 		hostname = host
@@ -1004,11 +2267,17 @@ func newInternalConfig(cfg Config, getenv func(string) string, environ []string)
 	} else {
 		hostname = "unknown"
 	}
+	scrubber := newScrubber(cfg.Scrubbing.Rules)
 	return config{
 		Config:           cfg,
 		metadata:         gatherMetadata(environ),
 		hostname:         hostname,
 		traceObserverURL: obsURL,
+		scrubber:         scrubber,
+		errorIgnorer:     newErrorIgnorer(cfg.ErrorCollector.IgnoreClasses, cfg.ErrorCollector.IgnoreMessages),
+		samplingRules:    newSamplingRules(cfg.DistributedTracer.SamplingRules),
+		process:          gatherProcessMetadata(cfg, scrubber, getenv, os.Args),
+		environmentVars:  gatherEnvironmentVars(cfg, environ),
 	}, nil
 }
 
@@ -1044,3 +2313,61 @@ func (c config) preconnectHost() string {
 	}
 	return preconnectHostDefault
 }
+
+// errorStackTraceDepth returns the maximum number of frames in traced error
+// stack traces.
+func (c config) errorStackTraceDepth() int {
+	if d := c.ErrorCollector.StackTraceDepth; d > 0 && d < maxStackTraceFrames {
+		return d
+	}
+	return maxStackTraceFrames
+}
+
+// connectBackoff returns the delay before the next connect attempt after the
+// given number of consecutive failed attempts, counting from zero.
+func (c config) connectBackoff(attempt int) time.Duration {
+	b := c.Connect.Backoff
+	schedule := b.Schedule
+	if 0 == len(schedule) {
+		schedule = connectBackoffSchedule
+	}
+	d := getConnectBackoffTime(schedule, attempt)
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	if b.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + b.Jitter*(2*float64(randFloat32())-1)))
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+var deploymentHostDefault = "api.newrelic.com"
+
+// deploymentHost returns the NerdGraph host used by RecordDeployment.
+func (c config) deploymentHost() string {
+	if h := c.Deployments.Host; "" != h {
+		return h
+	}
+	m := preconnectRegionLicenseRegex.FindStringSubmatch(c.License)
+	if len(m) > 1 && strings.HasPrefix(m[1], "eu") {
+		return "api.eu.newrelic.com"
+	}
+	return deploymentHostDefault
+}
+
+var eventAPIHostDefault = "insights-collector.newrelic.com"
+
+// eventAPIHost returns the Event API host used by the custom event fallback.
+func (c config) eventAPIHost() string {
+	if h := c.CustomInsightsEvents.EventAPIFallback.Host; "" != h {
+		return h
+	}
+	m := preconnectRegionLicenseRegex.FindStringSubmatch(c.License)
+	if len(m) > 1 {
+		return "insights-collector." + m[1] + ".nr-data.net"
+	}
+	return eventAPIHostDefault
+}
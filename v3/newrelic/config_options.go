@@ -262,6 +262,25 @@ func ConfigAppLogForwardingMaxSamplesStored(maxSamplesStored int) ConfigOption {
 	}
 }
 
+// ConfigAppLogForwardingCaptureErrors enables or disables recording logs of
+// severity ERROR, FATAL, CRITICAL, or PANIC recorded by transactions as
+// transaction errors.
+// Defaults: enabled=false
+func ConfigAppLogForwardingCaptureErrors(enabled bool) ConfigOption {
+	return func(cfg *Config) {
+		cfg.ApplicationLogging.Forwarding.CaptureErrors = enabled
+	}
+}
+
+// ConfigAppLogForwardingAttributeAllowList sets the structured fields of logs
+// which are recorded as log event attributes.  A key ending in "*" matches
+// every field beginning with the rest of the key.
+func ConfigAppLogForwardingAttributeAllowList(keys ...string) ConfigOption {
+	return func(cfg *Config) {
+		cfg.ApplicationLogging.Forwarding.AttributeAllowList = keys
+	}
+}
+
 // ConfigLogger populates the Config's Logger.
 func ConfigLogger(l Logger) ConfigOption {
 	return func(cfg *Config) { cfg.Logger = l }
@@ -304,6 +323,32 @@ func ConfigModuleDependencyMetricsRedactIgnoredPrefixes(enabled bool) ConfigOpti
 	}
 }
 
+// ConfigProcessMetadataEnabled controls whether the process owner, Windows
+// service name, IIS application pool, and redacted command line are reported
+// when the application connects.
+func ConfigProcessMetadataEnabled(enabled bool) ConfigOption {
+	return func(cfg *Config) {
+		cfg.ProcessMetadata.Enabled = enabled
+	}
+}
+
+// ConfigProcessMetadataCommandLine controls whether the redacted command line
+// is reported along with the other process metadata.
+func ConfigProcessMetadataCommandLine(enabled bool) ConfigOption {
+	return func(cfg *Config) {
+		cfg.ProcessMetadata.CommandLine = enabled
+	}
+}
+
+// ConfigEnvironmentIncludeVars sets the names of the environment variables
+// reported when the application connects.  A name ending in "*" matches every
+// variable with that prefix.
+func ConfigEnvironmentIncludeVars(names ...string) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Environment.IncludeVars = names
+	}
+}
+
 // ConfigDebugLogger populates the config with a Logger at debug level.
 func ConfigDebugLogger(w io.Writer) ConfigOption {
 	return ConfigLogger(NewDebugLogger(w))
@@ -323,6 +368,7 @@ func ConfigDebugLogger(w io.Writer) ConfigOption {
 //		NEW_RELIC_CODE_LEVEL_METRICS_REDACT_PATH_PREFIXES    		sets CodeLevelMetrics.RedactPathPrefixes to a boolean value
 //	 	NEW_RELIC_CODE_LEVEL_METRICS_REDACT_IGNORED_PREFIXES 		sets CodeLevelMetrics.RedactIgnoredPrefixes to a boolean value
 //		NEW_RELIC_CODE_LEVEL_METRICS_IGNORED_PREFIX       			sets CodeLevelMetrics.IgnoredPrefixes using a comma-separated list
+//		NEW_RELIC_DEPLOYMENT_METADATA                     			sets DeploymentMetadata using a semi-colon delimited string of colon-separated pairs, eg. "commit:3f2a9c1;build:1024"
 //		NEW_RELIC_DISTRIBUTED_TRACING_ENABLED             			sets DistributedTracer.Enabled using strconv.ParseBool
 //		NEW_RELIC_ENABLED                                 			sets Enabled using strconv.ParseBool
 //		NEW_RELIC_HIGH_SECURITY                           			sets HighSecurity using strconv.ParseBool
@@ -344,6 +390,7 @@ func ConfigDebugLogger(w io.Writer) ConfigOption {
 //	 	NEW_RELIC_APPLICATION_LOGGING_METRICS_ENABLED		  		sets ApplicationLogging.Metrics.Enabled. Set to false to disable the collection of application log metrics.
 //	 	NEW_RELIC_APPLICATION_LOGGING_LOCAL_DECORATING_ENABLED      sets ApplicationLogging.LocalDecoration.Enabled. Set to true to enable local log decoration.
 //		NEW_RELIC_APPLICATION_LOGGING_FORWARDING_MAX_SAMPLES_STORED	sets ApplicationLogging.LogForwarding.Limit. Set to 0 to prevent captured logs from being forwarded.
+//		NEW_RELIC_APPLICATION_LOGGING_FORWARDING_ATTRIBUTE_ALLOW_LIST	sets ApplicationLogging.Forwarding.AttributeAllowList using a comma-separated list.
 //
 // This function is strict and will assign Config.Error if any of the
 // environment variables cannot be parsed.
@@ -416,12 +463,23 @@ func configFromEnvironment(getenv func(string) string) ConfigOption {
 			}
 		}
 
+		if env := getenv("NEW_RELIC_DEPLOYMENT_METADATA"); env != "" {
+			if metadata := getLabels(env); len(metadata) > 0 {
+				cfg.DeploymentMetadata = metadata
+			} else {
+				cfg.Error = fmt.Errorf("invalid NEW_RELIC_DEPLOYMENT_METADATA value: %s", env)
+			}
+		}
+
 		if env := getenv("NEW_RELIC_ATTRIBUTES_INCLUDE"); env != "" {
 			cfg.Attributes.Include = strings.Split(env, ",")
 		}
 		if env := getenv("NEW_RELIC_ATTRIBUTES_EXCLUDE"); env != "" {
 			cfg.Attributes.Exclude = strings.Split(env, ",")
 		}
+		if env := getenv("NEW_RELIC_APPLICATION_LOGGING_FORWARDING_ATTRIBUTE_ALLOW_LIST"); env != "" {
+			cfg.ApplicationLogging.Forwarding.AttributeAllowList = strings.Split(env, ",")
+		}
 
 		if env := getenv("NEW_RELIC_CODE_LEVEL_METRICS_SCOPE"); env != "" {
 			var ok bool
@@ -252,3 +252,38 @@ func TestConfigFromEnvironmentLabelsSuccess(t *testing.T) {
 		t.Error(cfg.Labels)
 	}
 }
+
+func TestConfigFromEnvironmentDeploymentMetadata(t *testing.T) {
+	cfgOpt := configFromEnvironment(func(s string) string {
+		switch s {
+		case "NEW_RELIC_DEPLOYMENT_METADATA":
+			return "commit:3f2a9c1;build:1024"
+		default:
+			return ""
+		}
+	})
+	cfg := defaultConfig()
+	cfgOpt(&cfg)
+	if nil != cfg.Error {
+		t.Fatal(cfg.Error)
+	}
+	if !reflect.DeepEqual(cfg.DeploymentMetadata, map[string]string{"commit": "3f2a9c1", "build": "1024"}) {
+		t.Error(cfg.DeploymentMetadata)
+	}
+}
+
+func TestConfigFromEnvironmentInvalidDeploymentMetadata(t *testing.T) {
+	cfgOpt := configFromEnvironment(func(s string) string {
+		switch s {
+		case "NEW_RELIC_DEPLOYMENT_METADATA":
+			return ";;;"
+		default:
+			return ""
+		}
+	})
+	cfg := defaultConfig()
+	cfgOpt(&cfg)
+	if cfg.Error == nil {
+		t.Error("error expected")
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rainforestpay/go-agent/v3/internal"
 	"github.com/rainforestpay/go-agent/v3/internal/crossagent"
@@ -85,6 +86,7 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 	cfg.AppName = "my appname"
 	cfg.License = "0123456789012345678901234567890123456789"
 	cfg.Labels["zip"] = "zap"
+	cfg.CustomInsightsEvents.EventAPIFallback.InsertKey = "my insert key"
 	cfg.ErrorCollector.IgnoreStatusCodes = append(cfg.ErrorCollector.IgnoreStatusCodes, 405)
 	cfg.Attributes.Include = append(cfg.Attributes.Include, "1")
 	cfg.Attributes.Exclude = append(cfg.Attributes.Exclude, "2")
@@ -101,12 +103,19 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 	cfg.TransactionTracer.Segments.Attributes.Include = append(cfg.TransactionTracer.Segments.Attributes.Include, "13")
 	cfg.TransactionTracer.Segments.Attributes.Exclude = append(cfg.TransactionTracer.Segments.Attributes.Exclude, "14")
 	cfg.Transport = &http.Transport{}
+	cfg.DebugCapture.Writer = &strings.Builder{}
 	cfg.Logger = NewLogger(os.Stdout)
+	cfg.RequestCapture.QueryParameters = []string{"page"}
+	cfg.RequestCapture.Metadata = []string{"X-Tenant-ID"}
+	cfg.ErrorCollector.IgnoreClasses = []string{"panic"}
+	cfg.ErrorCollector.IgnoreMessages = map[string][]string{"*errors.errorString": {"canceled"}}
 
 	cp := copyConfigReferenceFields(cfg)
 
 	cfg.Labels["zop"] = "zup"
 	cfg.ErrorCollector.IgnoreStatusCodes[0] = 201
+	cfg.ErrorCollector.IgnoreClasses[0] = "zap"
+	cfg.ErrorCollector.IgnoreMessages["*errors.errorString"][0] = "zap"
 	cfg.Attributes.Include[0] = "zap"
 	cfg.Attributes.Exclude[0] = "zap"
 	cfg.TransactionEvents.Attributes.Include[0] = "zap"
@@ -121,6 +130,8 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 	cfg.SpanEvents.Attributes.Exclude[0] = "zap"
 	cfg.TransactionTracer.Segments.Attributes.Include[0] = "zap"
 	cfg.TransactionTracer.Segments.Attributes.Exclude[0] = "zap"
+	cfg.RequestCapture.QueryParameters[0] = "zap"
+	cfg.RequestCapture.Metadata[0] = "zap"
 
 	expect := internal.CompactJSONString(fmt.Sprintf(`[
 	{
@@ -129,10 +140,13 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 		"agent_version":"0.2.2",
 		"host":"my-hostname",
 		"settings":{
+			"Apdex":{"BackgroundTransactions":false,"ResponseCodeZones":null},
 			"AppName":"my appname",
 			"ApplicationLogging": {
 				"Enabled": true,
 				"Forwarding": {
+					"AttributeAllowList": null,
+					"CaptureErrors": false,
 					"Enabled": true,
 					"MaxSamplesStored": %d
 				},
@@ -143,36 +157,57 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 					"Enabled": true
 				}
 			},
-			"Attributes":{"Enabled":true,"Exclude":["2"],"Include":["1"]},
+			"AttributeLimits":{"HashTruncated":false,"KeyLength":255,"ValueLength":255},
+			"Attributes":{"Enabled":true,"Exclude":["2"],"Include":["1"],"RequestURIPolicy":""},
+			"BreakdownHostMetrics":{"Enabled":false},
 			"BrowserMonitoring":{
-				"Attributes":{"Enabled":false,"Exclude":["10"],"Include":["9"]},
+				"Attributes":{"Enabled":false,"Exclude":["10"],"Include":["9"],"RequestURIPolicy":""},
 				"Enabled":true
 			},
 			"CodeLevelMetrics":{"Enabled":false,"IgnoredPrefix":"","IgnoredPrefixes":null,"PathPrefix":"","PathPrefixes":null,"RedactIgnoredPrefixes":true,"RedactPathPrefixes":true,"Scope":"all"},
-			"CrossApplicationTracer":{"Enabled":false},
+			"CollectorTransport":{"DisableHTTP2":false,"IdleConnTimeout":90000000000,"MaxIdleConns":100,"MaxIdleConnsPerHost":100,"TLSSessionCacheSize":64},
+			"Compression":{"Encoder":null},
+			"Connect":{"Backoff":{"Jitter":0.2,"Max":300000000000,"Schedule":[15000000000,15000000000,30000000000,60000000000,120000000000,300000000000]}},
+			"CrossApplicationTracer":{"Enabled":false,"ReadOnly":false},
 			"CustomInsightsEvents":{
 				"Enabled":true,
+				"EventAPIFallback":{"AccountID":"","Enabled":false,"FailedHarvests":3,"Host":""},
 				"MaxSamplesStored":%d
 			},
+			"CustomMetrics":{"RequiredPrefix":""},
 			"DatastoreTracer":{
 				"DatabaseNameReporting":{"Enabled":true},
+				"InstanceHostnameAliases":null,
 				"InstanceReporting":{"Enabled":true},
 				"QueryParameters":{"Enabled":true},
+				"SQLCommenter":{"Enabled":false},
 				"SlowQuery":{
+					"AggregateByInstance":false,
 					"Enabled":true,
 					"Threshold":10000000
 				}
 			},
-			"DistributedTracer":{"Enabled":true,"ExcludeNewRelicHeader":false,"ReservoirLimit":2000},
+			"DebugCapture":{"Directory":"","Writer":"*strings.Builder"},
+			"DeploymentMetadata":null,
+			"Deployments":{"Host":"","UseLicense":false},
+			"DistributedTracer":{"DebugHeader":{"MaxTTL":3600000000000,"Name":""},"Enabled":true,"ExcludeNewRelicHeader":false,"HeaderValidation":{"LogMalformedHeaders":false,"Mode":""},"MaxTraceStateSize":512,"RelationshipMetrics":false,"ReservoirLimit":2000,"SamplingRules":null},
 			"Enabled":true,
+			"Environment":{"IncludeVars":null,"RedactPatterns":null},
 			"Error":null,
 			"ErrorCollector":{
-				"Attributes":{"Enabled":true,"Exclude":["6"],"Include":["5"]},
+				"Attributes":{"Enabled":true,"Exclude":["6"],"Include":["5"],"RequestURIPolicy":""},
 				"CaptureEvents":true,
 				"Enabled":true,
+				"IgnoreClasses":["panic"],
+				"IgnoreMessages":{"*errors.errorString":["canceled"]},
 				"IgnoreStatusCodes":[0,5,404,405],
-				"RecordPanics":false
+				"MaxEventsPerClass":0,
+				"RecordPanics":false,
+				"StackTraceDepth":100
 			},
+			"ErrorLogLimit":{"Interval":60000000000,"MaxPerInterval":10},
+			"Harvest":{"FailedMetrics":{"MaxAge":600000000000,"MaxMetrics":2000},"MaxConcurrentRequests":4},
+			"HarvestTelemetry":{"Enabled":false},
 			"Heroku":{
 				"DynoNamePrefixesToShorten":["scheduler","run"],
 				"UseDynoNames":true
@@ -184,14 +219,24 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 				"SpanEvents": {"QueueSize":10000},
 				"TraceObserver": {
 					"Host": "",
-					"Port": 443
+					"Hosts": null,
+					"Port": 443,
+					"TLS": {"CAFile":"","CertFile":"","KeyFile":""}
                 }
 			},
 			"Labels":{"zip":"zap"},
+			"LifecycleEvents":{"Enabled":false},
 			"Logger":"*logger.logFile",
+			"MemoryLimit":0,
 			"ModuleDependencyMetrics":{"Enabled":true,"IgnoredPrefixes":null,"RedactIgnoredPrefixes":true},
-			"RuntimeSampler":{"Enabled":true},
+			"ProcessMetadata":{"CommandLine":true,"Enabled":true},
+			"RemoteConfig":{"Enabled":false,"File":"","PollPeriod":30000000000,"URL":""},
+			"RequestCapture":{"Metadata":["X-Tenant-ID"],"QueryParameters":["page"]},
+			"RuntimeSampler":{"Diagnostics":{"Enabled":false,"GoroutineGrowthThreshold":1000,"SchedulerLatencyThreshold":10000000},"Enabled":true},
+			"Scraper":{"Expvars":null,"Prefix":"Custom/","RuntimeMetrics":null},
+			"Scrubbing":{"Rules":null},
 			"SecurityPoliciesToken":"",
+			"SegmentGuards":{"EndUnfinished":false,"MaxDuration":0},
 			"ServerlessMode":{
 				"AccountID":"",
 				"ApdexThreshold":500000000,
@@ -201,20 +246,30 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 			},
 			"SpanEvents":{
 				"Attributes":{
-					"Enabled":true,"Exclude":["12"],"Include":["11"]
+					"Enabled":true,"Exclude":["12"],"Include":["11"],"RequestURIPolicy":""
 				},
-				"Enabled":true
+				"Compression":{"Enabled":false,"MaxDuration":10000000},
+				"Enabled":true,
+				"MaxAttributeValues":0
+			},
+			"StatsD":{"Address":"udp://127.0.0.1:8125","Enabled":false},
+			"Transaction":{"IgnoreSynthetics":false},
+			"TransactionDurationHistogram":{
+				"Buckets":[50000000,100000000,250000000,500000000,1000000000,2500000000,5000000000,10000000000],
+				"Enabled":false
 			},
 			"TransactionEvents":{
-				"Attributes":{"Enabled":true,"Exclude":["4"],"Include":["3"]},
+				"Attributes":{"Enabled":true,"Exclude":["4"],"Include":["3"],"RequestURIPolicy":""},
 				"Enabled":true,
 				"MaxSamplesStored": %d
 			},
+			"TransactionExemplars":{"Enabled":false},
 			"TransactionTracer":{
-				"Attributes":{"Enabled":true,"Exclude":["8"],"Include":["7"]},
+				"Attributes":{"Enabled":true,"Exclude":["8"],"Include":["7"],"RequestURIPolicy":""},
 				"Enabled":true,
+				"MaxTraceSize":262144,
 				"Segments":{
-					"Attributes":{"Enabled":true,"Exclude":["14"],"Include":["13"]},
+					"Attributes":{"Enabled":true,"Exclude":["14"],"Include":["13"],"RequestURIPolicy":""},
 					"StackTraceThreshold":500000000,
 					"Threshold":2000000
 				},
@@ -323,10 +378,13 @@ func TestCopyConfigReferenceFieldsAbsent(t *testing.T) {
 		"agent_version":"0.2.2",
 		"host":"my-hostname",
 		"settings":{
+			"Apdex":{"BackgroundTransactions":false,"ResponseCodeZones":null},
 			"AppName":"my appname",
 			"ApplicationLogging": {
 				"Enabled": true,
 				"Forwarding": {
+					"AttributeAllowList": null,
+					"CaptureErrors": false,
 					"Enabled": true,
 					"MaxSamplesStored": %d
 				},
@@ -337,40 +395,62 @@ func TestCopyConfigReferenceFieldsAbsent(t *testing.T) {
 					"Enabled": true
 				}
 			},
-			"Attributes":{"Enabled":true,"Exclude":null,"Include":null},
+			"AttributeLimits":{"HashTruncated":false,"KeyLength":255,"ValueLength":255},
+			"Attributes":{"Enabled":true,"Exclude":null,"Include":null,"RequestURIPolicy":""},
+			"BreakdownHostMetrics":{"Enabled":false},
 			"BrowserMonitoring":{
 				"Attributes":{
 					"Enabled":false,
 					"Exclude":null,
-					"Include":null
+					"Include":null,
+					"RequestURIPolicy":""
 				},
 				"Enabled":true
 			},
 			"CodeLevelMetrics":{"Enabled":false,"IgnoredPrefix":"","IgnoredPrefixes":null,"PathPrefix":"","PathPrefixes":null,"RedactIgnoredPrefixes":true,"RedactPathPrefixes":true,"Scope":"all"},
-			"CrossApplicationTracer":{"Enabled":false},
+			"CollectorTransport":{"DisableHTTP2":false,"IdleConnTimeout":90000000000,"MaxIdleConns":100,"MaxIdleConnsPerHost":100,"TLSSessionCacheSize":64},
+			"Compression":{"Encoder":null},
+			"Connect":{"Backoff":{"Jitter":0.2,"Max":300000000000,"Schedule":[15000000000,15000000000,30000000000,60000000000,120000000000,300000000000]}},
+			"CrossApplicationTracer":{"Enabled":false,"ReadOnly":false},
 			"CustomInsightsEvents":{
 				"Enabled":true,
+				"EventAPIFallback":{"AccountID":"","Enabled":false,"FailedHarvests":3,"Host":""},
 				"MaxSamplesStored":%d
 			},
+			"CustomMetrics":{"RequiredPrefix":""},
 			"DatastoreTracer":{
 				"DatabaseNameReporting":{"Enabled":true},
+				"InstanceHostnameAliases":null,
 				"InstanceReporting":{"Enabled":true},
 				"QueryParameters":{"Enabled":true},
+				"SQLCommenter":{"Enabled":false},
 				"SlowQuery":{
+					"AggregateByInstance":false,
 					"Enabled":true,
 					"Threshold":10000000
 				}
 			},
-			"DistributedTracer":{"Enabled":true,"ExcludeNewRelicHeader":false,"ReservoirLimit":2000},
+			"DebugCapture":{"Directory":"","Writer":null},
+			"DeploymentMetadata":null,
+			"Deployments":{"Host":"","UseLicense":false},
+			"DistributedTracer":{"DebugHeader":{"MaxTTL":3600000000000,"Name":""},"Enabled":true,"ExcludeNewRelicHeader":false,"HeaderValidation":{"LogMalformedHeaders":false,"Mode":""},"MaxTraceStateSize":512,"RelationshipMetrics":false,"ReservoirLimit":2000,"SamplingRules":null},
 			"Enabled":true,
+			"Environment":{"IncludeVars":null,"RedactPatterns":null},
 			"Error":null,
 			"ErrorCollector":{
-				"Attributes":{"Enabled":true,"Exclude":null,"Include":null},
+				"Attributes":{"Enabled":true,"Exclude":null,"Include":null,"RequestURIPolicy":""},
 				"CaptureEvents":true,
 				"Enabled":true,
+				"IgnoreClasses":null,
+				"IgnoreMessages":null,
 				"IgnoreStatusCodes":null,
-				"RecordPanics":false
+				"MaxEventsPerClass":0,
+				"RecordPanics":false,
+				"StackTraceDepth":100
 			},
+			"ErrorLogLimit":{"Interval":60000000000,"MaxPerInterval":10},
+			"Harvest":{"FailedMetrics":{"MaxAge":600000000000,"MaxMetrics":2000},"MaxConcurrentRequests":4},
+			"HarvestTelemetry":{"Enabled":false},
 			"Heroku":{
 				"DynoNamePrefixesToShorten":["scheduler","run"],
 				"UseDynoNames":true
@@ -382,14 +462,24 @@ func TestCopyConfigReferenceFieldsAbsent(t *testing.T) {
 				"SpanEvents": {"QueueSize":10000},
 				"TraceObserver": {
 					"Host": "",
-					"Port": 443
+					"Hosts": null,
+					"Port": 443,
+					"TLS": {"CAFile":"","CertFile":"","KeyFile":""}
                 }
 			},
 			"Labels":null,
+			"LifecycleEvents":{"Enabled":false},
 			"Logger":null,
+			"MemoryLimit":0,
 			"ModuleDependencyMetrics":{"Enabled":true,"IgnoredPrefixes":null,"RedactIgnoredPrefixes":true},
-			"RuntimeSampler":{"Enabled":true},
+			"ProcessMetadata":{"CommandLine":true,"Enabled":true},
+			"RemoteConfig":{"Enabled":false,"File":"","PollPeriod":30000000000,"URL":""},
+			"RequestCapture":{"Metadata":null,"QueryParameters":null},
+			"RuntimeSampler":{"Diagnostics":{"Enabled":false,"GoroutineGrowthThreshold":1000,"SchedulerLatencyThreshold":10000000},"Enabled":true},
+			"Scraper":{"Expvars":null,"Prefix":"Custom/","RuntimeMetrics":null},
+			"Scrubbing":{"Rules":null},
 			"SecurityPoliciesToken":"",
+			"SegmentGuards":{"EndUnfinished":false,"MaxDuration":0},
 			"ServerlessMode":{
 				"AccountID":"",
 				"ApdexThreshold":500000000,
@@ -398,19 +488,29 @@ func TestCopyConfigReferenceFieldsAbsent(t *testing.T) {
 				"TrustedAccountKey":""
 			},
 			"SpanEvents":{
-				"Attributes":{"Enabled":true,"Exclude":null,"Include":null},
-				"Enabled":true
+				"Attributes":{"Enabled":true,"Exclude":null,"Include":null,"RequestURIPolicy":""},
+				"Compression":{"Enabled":false,"MaxDuration":10000000},
+				"Enabled":true,
+				"MaxAttributeValues":0
+			},
+			"StatsD":{"Address":"udp://127.0.0.1:8125","Enabled":false},
+			"Transaction":{"IgnoreSynthetics":false},
+			"TransactionDurationHistogram":{
+				"Buckets":[50000000,100000000,250000000,500000000,1000000000,2500000000,5000000000,10000000000],
+				"Enabled":false
 			},
 			"TransactionEvents":{
-				"Attributes":{"Enabled":true,"Exclude":null,"Include":null},
+				"Attributes":{"Enabled":true,"Exclude":null,"Include":null,"RequestURIPolicy":""},
 				"Enabled":true,
 				"MaxSamplesStored": %d
 			},
+			"TransactionExemplars":{"Enabled":false},
 			"TransactionTracer":{
-				"Attributes":{"Enabled":true,"Exclude":null,"Include":null},
+				"Attributes":{"Enabled":true,"Exclude":null,"Include":null,"RequestURIPolicy":""},
 				"Enabled":true,
+				"MaxTraceSize":262144,
 				"Segments":{
-					"Attributes":{"Enabled":true,"Exclude":null,"Include":null},
+					"Attributes":{"Enabled":true,"Exclude":null,"Include":null,"RequestURIPolicy":""},
 					"StackTraceThreshold":500000000,
 					"Threshold":2000000
 				},
@@ -666,6 +766,206 @@ func TestPreconnectHost(t *testing.T) {
 	}
 }
 
+func TestEventAPIHost(t *testing.T) {
+	testcases := []struct {
+		license  string
+		override string
+		expect   string
+	}{
+		{
+			license: "0123456789012345678901234567890123456789",
+			expect:  eventAPIHostDefault,
+		},
+		{
+			license:  "eu01xx6789012345678901234567890123456789",
+			override: "other-insights.example.com",
+			expect:   "other-insights.example.com",
+		},
+		{
+			license: "eu01xx6789012345678901234567890123456789",
+			expect:  "insights-collector.eu01.nr-data.net",
+		},
+	}
+	for idx, tc := range testcases {
+		cfg := config{Config: defaultConfig()}
+		cfg.License = tc.license
+		cfg.CustomInsightsEvents.EventAPIFallback.Host = tc.override
+		if got := cfg.eventAPIHost(); got != tc.expect {
+			t.Error("testcase", idx, got, tc.expect)
+		}
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	testcases := []struct {
+		name   string
+		cfgFn  func(cfg *Config)
+		expect error
+	}{
+		{
+			name:   "defaults",
+			cfgFn:  func(cfg *Config) {},
+			expect: nil,
+		},
+		{
+			name: "event api fallback without key",
+			cfgFn: func(cfg *Config) {
+				cfg.CustomInsightsEvents.EventAPIFallback.Enabled = true
+			},
+			expect: errEventAPIFallbackMissingKey,
+		},
+		{
+			name: "event api fallback with key",
+			cfgFn: func(cfg *Config) {
+				cfg.CustomInsightsEvents.EventAPIFallback.Enabled = true
+				cfg.CustomInsightsEvents.EventAPIFallback.InsertKey = "insert-key"
+				cfg.CustomInsightsEvents.EventAPIFallback.AccountID = "123"
+			},
+			expect: nil,
+		},
+		{
+			name:   "negative backoff jitter",
+			cfgFn:  func(cfg *Config) { cfg.Connect.Backoff.Jitter = -0.1 },
+			expect: errConnectBackoffJitter,
+		},
+		{
+			name:   "backoff jitter above one",
+			cfgFn:  func(cfg *Config) { cfg.Connect.Backoff.Jitter = 1.5 },
+			expect: errConnectBackoffJitter,
+		},
+		{
+			name:   "backoff jitter of one",
+			cfgFn:  func(cfg *Config) { cfg.Connect.Backoff.Jitter = 1 },
+			expect: nil,
+		},
+		{
+			name:   "negative backoff delay",
+			cfgFn:  func(cfg *Config) { cfg.Connect.Backoff.Schedule = []time.Duration{time.Second, -time.Second} },
+			expect: errConnectBackoffSchedule,
+		},
+		{
+			name:   "negative attribute key length",
+			cfgFn:  func(cfg *Config) { cfg.AttributeLimits.KeyLength = -1 },
+			expect: errAttributeLimits,
+		},
+		{
+			name:   "attribute key length above limit",
+			cfgFn:  func(cfg *Config) { cfg.AttributeLimits.KeyLength = 256 },
+			expect: errAttributeLimits,
+		},
+		{
+			name:   "negative attribute value length",
+			cfgFn:  func(cfg *Config) { cfg.AttributeLimits.ValueLength = -1 },
+			expect: errAttributeLimits,
+		},
+		{
+			name:   "attribute value length above limit",
+			cfgFn:  func(cfg *Config) { cfg.AttributeLimits.ValueLength = 4096 },
+			expect: errAttributeLimits,
+		},
+		{
+			name: "attribute limits",
+			cfgFn: func(cfg *Config) {
+				cfg.AttributeLimits.KeyLength = 100
+				cfg.AttributeLimits.ValueLength = 4095
+			},
+			expect: nil,
+		},
+		{
+			name: "zero histogram bucket",
+			cfgFn: func(cfg *Config) {
+				cfg.TransactionDurationHistogram.Buckets = []time.Duration{0}
+			},
+			expect: errHistogramBuckets,
+		},
+		{
+			name: "repeated histogram bucket",
+			cfgFn: func(cfg *Config) {
+				cfg.TransactionDurationHistogram.Buckets = []time.Duration{time.Second, time.Second}
+			},
+			expect: errHistogramBuckets,
+		},
+		{
+			name: "decreasing histogram buckets",
+			cfgFn: func(cfg *Config) {
+				cfg.TransactionDurationHistogram.Buckets = []time.Duration{time.Second, time.Millisecond}
+			},
+			expect: errHistogramBuckets,
+		},
+		{
+			name: "histogram enabled without buckets",
+			cfgFn: func(cfg *Config) {
+				cfg.TransactionDurationHistogram.Enabled = true
+				cfg.TransactionDurationHistogram.Buckets = nil
+			},
+			expect: errHistogramNoBuckets,
+		},
+		{
+			name: "apdex response code zones",
+			cfgFn: func(cfg *Config) {
+				cfg.Apdex.ResponseCodeZones = map[int]ApdexZone{4: ApdexFrustrating, 8: ApdexTolerating, 0: ApdexSatisfying}
+			},
+			expect: nil,
+		},
+		{
+			name: "invalid apdex response code zone",
+			cfgFn: func(cfg *Config) {
+				cfg.Apdex.ResponseCodeZones = map[int]ApdexZone{4: ApdexFrustrating, 14: "frustrating"}
+			},
+			expect: errApdexZone,
+		},
+		{
+			name:   "full request uri policy",
+			cfgFn:  func(cfg *Config) { cfg.Attributes.RequestURIPolicy = RequestURIFull },
+			expect: nil,
+		},
+		{
+			name:   "template request uri policy",
+			cfgFn:  func(cfg *Config) { cfg.Attributes.RequestURIPolicy = RequestURITemplate },
+			expect: nil,
+		},
+		{
+			name:   "hash request uri policy",
+			cfgFn:  func(cfg *Config) { cfg.Attributes.RequestURIPolicy = RequestURIHash },
+			expect: nil,
+		},
+		{
+			name:   "invalid request uri policy",
+			cfgFn:  func(cfg *Config) { cfg.Attributes.RequestURIPolicy = "path" },
+			expect: errRequestURIPolicy,
+		},
+		{
+			name:   "request uri policy for one destination",
+			cfgFn:  func(cfg *Config) { cfg.SpanEvents.Attributes.RequestURIPolicy = RequestURIHash },
+			expect: errRequestURIDest,
+		},
+		{
+			name:   "lenient header validation",
+			cfgFn:  func(cfg *Config) { cfg.DistributedTracer.HeaderValidation.Mode = HeaderValidationLenient },
+			expect: nil,
+		},
+		{
+			name:   "strict header validation",
+			cfgFn:  func(cfg *Config) { cfg.DistributedTracer.HeaderValidation.Mode = HeaderValidationStrict },
+			expect: nil,
+		},
+		{
+			name:   "invalid header validation",
+			cfgFn:  func(cfg *Config) { cfg.DistributedTracer.HeaderValidation.Mode = "loose" },
+			expect: errHeaderValidation,
+		},
+	}
+	for _, tc := range testcases {
+		c := defaultConfig()
+		c.License = "0123456789012345678901234567890123456789"
+		c.AppName = "my app"
+		tc.cfgFn(&c)
+		if err := c.validate(); err != tc.expect {
+			t.Error(tc.name, err)
+		}
+	}
+}
+
 func TestPreconnectHostCrossAgent(t *testing.T) {
 	var testcases []struct {
 		Name               string `json:"name"`
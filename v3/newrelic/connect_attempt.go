@@ -0,0 +1,221 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// ConnectFailureReason categorizes why an attempt to connect the application
+// to New Relic failed.
+type ConnectFailureReason string
+
+// Connect failure reasons.
+const (
+	// ConnectFailureDNS means the collector host name could not be
+	// resolved.
+	ConnectFailureDNS ConnectFailureReason = "dns"
+	// ConnectFailureProxy means the proxy configured for the application's
+	// Transport could not be reached.
+	ConnectFailureProxy ConnectFailureReason = "proxy"
+	// ConnectFailureNetwork means the collector could not be reached.
+	ConnectFailureNetwork ConnectFailureReason = "network"
+	// ConnectFailureLicense means the collector rejected the license key.
+	ConnectFailureLicense ConnectFailureReason = "license"
+	// ConnectFailureDisconnect means the collector instructed the
+	// application to disconnect.  No further attempts are made.
+	ConnectFailureDisconnect ConnectFailureReason = "disconnect"
+	// ConnectFailureCollector means the collector responded with an
+	// unexpected status code or reply.
+	ConnectFailureCollector ConnectFailureReason = "collector"
+)
+
+// ConnectAttempt describes an attempt to connect the application to New
+// Relic.  It is passed to the callback given to WithConnectAttemptCallback.
+type ConnectAttempt struct {
+	// Attempt is the number of the attempt, starting at 1.  It is reset
+	// when the application reconnects after the collector restarts it.
+	Attempt int
+	// Err is nil if the attempt succeeded.
+	Err error
+	// Reason categorizes Err.  It is empty if the attempt succeeded.
+	Reason ConnectFailureReason
+	// StatusCode is the status code of the collector response to a failed
+	// attempt, or zero if no response was received.
+	StatusCode int
+	// RetryIn is the time until the next attempt.  It is zero if the
+	// attempt succeeded or no further attempts will be made.
+	RetryIn time.Duration
+}
+
+func connectFailureReason(resp rpmResponse) ConnectFailureReason {
+	if resp.IsDisconnect() {
+		return ConnectFailureDisconnect
+	}
+	switch resp.statusCode {
+	case 0:
+	case 401, 403:
+		return ConnectFailureLicense
+	default:
+		return ConnectFailureCollector
+	}
+	var dnsErr *net.DNSError
+	if errors.As(resp.Err, &dnsErr) {
+		return ConnectFailureDNS
+	}
+	var opErr *net.OpError
+	if errors.As(resp.Err, &opErr) && "proxyconnect" == opErr.Op {
+		return ConnectFailureProxy
+	}
+	var netErr net.Error
+	if errors.As(resp.Err, &netErr) {
+		return ConnectFailureNetwork
+	}
+	return ConnectFailureCollector
+}
+
+// connectAttempts records the outcome of the most recent connect attempt so
+// that it can be reported by WaitForConnectionCtx, and passes each attempt to
+// the callbacks of the waiters.
+type connectAttempts struct {
+	sync.Mutex
+	// seq counts attempts over the lifetime of the application.  Unlike
+	// ConnectAttempt.Attempt, it is never reset.
+	seq  int
+	last ConnectAttempt
+	// callbacks are the WithConnectAttemptCallback functions of the
+	// waiters, keyed by the id returned by watch.
+	callbacks map[int]func(ConnectAttempt)
+	nextID    int
+}
+
+// record is called by the connect loop after each attempt.  The callbacks
+// are called while holding the lock so that each sees every attempt exactly
+// once and in order.
+func (ca *connectAttempts) record(attempt ConnectAttempt) {
+	ca.Lock()
+	defer ca.Unlock()
+	ca.seq++
+	ca.last = attempt
+	for _, fn := range ca.callbacks {
+		fn(attempt)
+	}
+}
+
+func (ca *connectAttempts) get() (int, ConnectAttempt) {
+	ca.Lock()
+	defer ca.Unlock()
+	return ca.seq, ca.last
+}
+
+// watch calls fn with the most recent attempt, if there has been one, and
+// then with each later attempt until unwatch is called with the returned id.
+func (ca *connectAttempts) watch(fn func(ConnectAttempt)) int {
+	ca.Lock()
+	defer ca.Unlock()
+	if ca.seq > 0 {
+		fn(ca.last)
+	}
+	if nil == ca.callbacks {
+		ca.callbacks = make(map[int]func(ConnectAttempt))
+	}
+	ca.nextID++
+	ca.callbacks[ca.nextID] = fn
+	return ca.nextID
+}
+
+func (ca *connectAttempts) unwatch(id int) {
+	ca.Lock()
+	defer ca.Unlock()
+	delete(ca.callbacks, id)
+}
+
+// WaitForConnectionOption configures Application.WaitForConnectionCtx.
+type WaitForConnectionOption func(*waitForConnectionOptions)
+
+type waitForConnectionOptions struct {
+	onAttempt func(ConnectAttempt)
+}
+
+// WithConnectAttemptCallback sets a function called with the outcome of each
+// connect attempt made while Application.WaitForConnectionCtx is waiting.  The
+// function is called by the goroutine connecting the application as soon as
+// each attempt completes, and so it should return quickly and must not call
+// WaitForConnectionCtx.  If the most recent attempt was made before
+// WaitForConnectionCtx was called, the function is first called with that
+// attempt.
+func WithConnectAttemptCallback(fn func(ConnectAttempt)) WaitForConnectionOption {
+	return func(o *waitForConnectionOptions) {
+		o.onAttempt = fn
+	}
+}
+
+// connectWaitError is returned by WaitForConnectionCtx when the context is
+// done before the application connects.
+type connectWaitError struct {
+	err  error
+	last ConnectAttempt
+}
+
+func (e connectWaitError) Error() string {
+	if nil == e.last.Err {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("%s: connect attempt %d failed (%s): %v",
+		e.err, e.last.Attempt, e.last.Reason, e.last.Err)
+}
+
+func (e connectWaitError) Unwrap() error { return e.err }
+
+func (app *app) WaitForConnectionCtx(ctx context.Context, options ...WaitForConnectionOption) error {
+	if nil == app {
+		return nil
+	}
+	if !app.config.Enabled {
+		return nil
+	}
+	if app.config.ServerlessMode.Enabled {
+		return nil
+	}
+	var opts waitForConnectionOptions
+	for _, o := range options {
+		if nil != o {
+			o(&opts)
+		}
+	}
+	if nil != opts.onAttempt {
+		id := app.connectAttempts.watch(opts.onAttempt)
+		defer app.connectAttempts.unwatch(id)
+	}
+	pollPeriod := 50 * time.Millisecond
+	ticker := time.NewTicker(pollPeriod)
+	defer ticker.Stop()
+
+	for {
+		run, err := app.getState()
+		if nil != err {
+			return err
+		}
+		if run.Reply.RunID != "" {
+			if shouldUseTraceObserver(run.Config) {
+				if obs := app.getObserver(); obs != nil && obs.initialConnCompleted() {
+					return nil
+				}
+			} else {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			_, last := app.connectAttempts.get()
+			return connectWaitError{err: ctx.Err(), last: last}
+		case <-ticker.C:
+		}
+	}
+}
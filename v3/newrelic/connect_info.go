@@ -0,0 +1,72 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "encoding/json"
+
+// ConnectInfo describes the application's connection to New Relic.  It is
+// returned by Application.ConnectInfo so that applications can log or export
+// the identifiers linking them to their New Relic entity.
+type ConnectInfo struct {
+	// Connected is true if the application is connected.  The other fields
+	// are empty until it is.
+	Connected bool
+	// RunID identifies the current connection.  It changes each time the
+	// application reconnects.
+	RunID string
+	// EntityGUID identifies the application's New Relic entity.
+	EntityGUID string
+	// AccountID is the New Relic account of the application.
+	AccountID string
+	// TrustedAccountKey identifies the accounts trusted by distributed
+	// tracing.
+	TrustedAccountKey string
+	// PrimaryAppID is the ID of the application's primary application
+	// name.
+	PrimaryAppID string
+	// ServerSideConfig holds the settings configured in the New Relic UI
+	// which override the local Config, keyed by setting name, eg.
+	// "transaction_tracer.enabled".  It is a copy: changing it has no
+	// effect.
+	ServerSideConfig map[string]interface{}
+}
+
+// serverSideConfigSnapshot returns the server-side configuration settings
+// which have been set.
+func serverSideConfigSnapshot(run *appRun) map[string]interface{} {
+	js, err := json.Marshal(run.Reply.ServerSideConfig)
+	if nil != err {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(js, &fields); nil != err {
+		return nil
+	}
+	for key, val := range fields {
+		if nil == val {
+			delete(fields, key)
+		}
+	}
+	if 0 == len(fields) {
+		return nil
+	}
+	return fields
+}
+
+// ConnectInfo implements newrelic.Application's ConnectInfo.
+func (app *app) ConnectInfo() ConnectInfo {
+	run, _ := app.getState()
+	if "" == run.Reply.RunID {
+		return ConnectInfo{}
+	}
+	return ConnectInfo{
+		Connected:         true,
+		RunID:             run.Reply.RunID.String(),
+		EntityGUID:        run.Reply.EntityGUID,
+		AccountID:         run.Reply.AccountID,
+		TrustedAccountKey: run.Reply.TrustedAccountKey,
+		PrimaryAppID:      run.Reply.PrimaryAppID,
+		ServerSideConfig:  serverSideConfigSnapshot(run),
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestConnectInfo(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.RunID = "my-run-id"
+		reply.EntityGUID = "my-entity-guid"
+		enabled := false
+		reply.ServerSideConfig.TransactionTracerEnabled = &enabled
+		reply.ServerSideConfig.ErrorCollectorIgnoreStatusCodes = []int{404}
+	}
+	app := testApp(replyfn, nil, t)
+	info := app.Application.ConnectInfo()
+	if !info.Connected || info.RunID != "my-run-id" || info.EntityGUID != "my-entity-guid" ||
+		info.AccountID != "123" || info.TrustedAccountKey != "123" || info.PrimaryAppID != "456" {
+		t.Errorf("%+v", info)
+	}
+	ssc := info.ServerSideConfig
+	if len(ssc) != 2 || ssc["transaction_tracer.enabled"] != false {
+		t.Error(ssc)
+	}
+	if codes, ok := ssc["error_collector.ignore_status_codes"].([]interface{}); !ok || len(codes) != 1 || codes[0] != float64(404) {
+		t.Error(ssc)
+	}
+
+	// The snapshot is a copy.
+	ssc["transaction_tracer.enabled"] = true
+	if app.Application.ConnectInfo().ServerSideConfig["transaction_tracer.enabled"] != false {
+		t.Error("server side config modified")
+	}
+}
+
+func TestConnectInfoNotConnected(t *testing.T) {
+	app := testApp(nil, nil, t)
+	if info := app.Application.ConnectInfo(); info.Connected || "" != info.EntityGUID || nil != info.ServerSideConfig {
+		t.Errorf("%+v", info)
+	}
+	var nilApp *Application
+	if info := nilApp.ConnectInfo(); info.Connected {
+		t.Errorf("%+v", info)
+	}
+}
@@ -38,6 +38,43 @@ func FromContext(ctx context.Context) *Transaction {
 	return h
 }
 
+// LinkingMetadataFromContext returns the linking metadata of the Transaction
+// in the context: the entity GUID, entity name, hostname, and the trace and
+// span IDs of the transaction's currently active segment.  Use it to decorate
+// log lines or exported data when only the context is available.  The zero
+// LinkingMetadata is returned if the context has no Transaction, and the trace
+// and span IDs are empty once the Transaction has ended.
+func LinkingMetadataFromContext(ctx context.Context) LinkingMetadata {
+	return FromContext(ctx).GetLinkingMetadata()
+}
+
+// StartTransactionCtx starts a Transaction using the Application, and returns
+// a context carrying it along with a function which ends it.  Together with
+// SegmentFromContext, it allows libraries to be instrumented using only the
+// context.Context:
+//
+//	ctx, end := newrelic.StartTransactionCtx(ctx, app, "processBatch")
+//	defer end()
+//
+// The context is returned unchanged if the Application is nil.
+func StartTransactionCtx(ctx context.Context, app *Application, name string, opts ...TraceOption) (context.Context, func()) {
+	txn := app.StartTransaction(name, opts...)
+	if nil == txn {
+		return ctx, func() {}
+	}
+	return NewContext(ctx, txn), txn.End
+}
+
+// SegmentFromContext starts a Segment of the Transaction in the context, and
+// returns a function which ends it:
+//
+//	defer newrelic.SegmentFromContext(ctx, "parseRecords")()
+//
+// The function has no effect if the context has no Transaction.
+func SegmentFromContext(ctx context.Context, name string) func() {
+	return FromContext(ctx).StartSegment(name).End
+}
+
 // RequestWithTransactionContext adds the Transaction to the request's context.
 func RequestWithTransactionContext(req *http.Request, txn *Transaction) *http.Request {
 	ctx := req.Context()
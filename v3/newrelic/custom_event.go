@@ -25,6 +25,19 @@ var (
 		customEventAttributeLimit)
 )
 
+// CustomEventData contains the fields of a single custom event submitted
+// using Application.RecordCustomEvents.  The same restrictions apply as for
+// Application.RecordCustomEvent.
+type CustomEventData struct {
+	// EventType must consist of alphanumeric characters, underscores, and
+	// colons, and must contain fewer than 255 bytes.
+	EventType string
+	// Params holds the event's attributes.  Each value must be a number,
+	// string, or boolean, or a slice or map as described in
+	// Transaction.AddAttribute.
+	Params map[string]interface{}
+}
+
 // customEvent is a custom event.
 type customEvent struct {
 	eventType       string
@@ -75,7 +88,7 @@ func eventTypeValidate(eventType string) error {
 }
 
 // CreateCustomEvent creates a custom event.
-func createCustomEvent(eventType string, params map[string]interface{}, now time.Time) (*customEvent, error) {
+func createCustomEvent(eventType string, params map[string]interface{}, now time.Time, limits attributeLimits) (*customEvent, error) {
 	if err := eventTypeValidate(eventType); nil != err {
 		return nil, err
 	}
@@ -85,11 +98,16 @@ func createCustomEvent(eventType string, params map[string]interface{}, now time
 	}
 
 	truncatedParams := make(map[string]interface{})
+	count := 0
 	for key, val := range params {
-		val, err := validateUserAttribute(key, val)
+		val, err := limits.validateUserAttribute(key, val)
 		if nil != err {
 			return nil, err
 		}
+		// Map values count as each of their flattened entries.
+		if count += attributeCount(val); count > customEventAttributeLimit {
+			return nil, errNumAttributes
+		}
 		truncatedParams[key] = val
 	}
 
@@ -104,3 +122,14 @@ func createCustomEvent(eventType string, params map[string]interface{}, now time
 func (e *customEvent) MergeIntoHarvest(h *harvest) {
 	h.CustomEvents.Add(e)
 }
+
+// customEventBatch allows many custom events to be sent to the harvest
+// in a single message.
+type customEventBatch []*customEvent
+
+// MergeIntoHarvest implements Harvestable.
+func (batch customEventBatch) MergeIntoHarvest(h *harvest) {
+	for _, e := range batch {
+		h.CustomEvents.Add(e)
+	}
+}
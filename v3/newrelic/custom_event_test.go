@@ -30,7 +30,7 @@ var (
 // ordering.
 
 func TestCreateCustomEventSuccess(t *testing.T) {
-	event, err := createCustomEvent("myEvent", map[string]interface{}{"alpha": 1}, now)
+	event, err := createCustomEvent("myEvent", map[string]interface{}{"alpha": 1}, now, defaultAttributeLimits)
 	if nil != err {
 		t.Fatal(err)
 	}
@@ -44,7 +44,7 @@ func TestCreateCustomEventSuccess(t *testing.T) {
 }
 
 func TestInvalidEventTypeCharacter(t *testing.T) {
-	event, err := createCustomEvent("myEvent!", map[string]interface{}{"alpha": 1}, now)
+	event, err := createCustomEvent("myEvent!", map[string]interface{}{"alpha": 1}, now, defaultAttributeLimits)
 	if err != errEventTypeRegex {
 		t.Fatal(err)
 	}
@@ -54,7 +54,7 @@ func TestInvalidEventTypeCharacter(t *testing.T) {
 }
 
 func TestLongEventType(t *testing.T) {
-	event, err := createCustomEvent(strLen512, map[string]interface{}{"alpha": 1}, now)
+	event, err := createCustomEvent(strLen512, map[string]interface{}{"alpha": 1}, now, defaultAttributeLimits)
 	if err != errEventTypeLength {
 		t.Fatal(err)
 	}
@@ -64,7 +64,7 @@ func TestLongEventType(t *testing.T) {
 }
 
 func TestNilParams(t *testing.T) {
-	event, err := createCustomEvent("myEvent", nil, now)
+	event, err := createCustomEvent("myEvent", nil, now, defaultAttributeLimits)
 	if nil != err {
 		t.Fatal(err)
 	}
@@ -78,7 +78,7 @@ func TestNilParams(t *testing.T) {
 }
 
 func TestMissingEventType(t *testing.T) {
-	event, err := createCustomEvent("", map[string]interface{}{"alpha": 1}, now)
+	event, err := createCustomEvent("", map[string]interface{}{"alpha": 1}, now, defaultAttributeLimits)
 	if err != errEventTypeRegex {
 		t.Fatal(err)
 	}
@@ -88,7 +88,7 @@ func TestMissingEventType(t *testing.T) {
 }
 
 func TestEmptyParams(t *testing.T) {
-	event, err := createCustomEvent("myEvent", map[string]interface{}{}, now)
+	event, err := createCustomEvent("myEvent", map[string]interface{}{}, now, defaultAttributeLimits)
 	if nil != err {
 		t.Fatal(err)
 	}
@@ -102,7 +102,7 @@ func TestEmptyParams(t *testing.T) {
 }
 
 func TestTruncatedStringValue(t *testing.T) {
-	event, err := createCustomEvent("myEvent", map[string]interface{}{"alpha": strLen512}, now)
+	event, err := createCustomEvent("myEvent", map[string]interface{}{"alpha": strLen512}, now, defaultAttributeLimits)
 	if nil != err {
 		t.Fatal(err)
 	}
@@ -116,7 +116,7 @@ func TestTruncatedStringValue(t *testing.T) {
 }
 
 func TestInvalidValueType(t *testing.T) {
-	event, err := createCustomEvent("myEvent", map[string]interface{}{"alpha": []string{}}, now)
+	event, err := createCustomEvent("myEvent", map[string]interface{}{"alpha": []interface{}{}}, now, defaultAttributeLimits)
 	if _, ok := err.(errInvalidAttributeType); !ok {
 		t.Fatal(err)
 	}
@@ -125,8 +125,22 @@ func TestInvalidValueType(t *testing.T) {
 	}
 }
 
+func TestSliceValue(t *testing.T) {
+	event, err := createCustomEvent("myEvent", map[string]interface{}{"alpha": []string{"a", "b"}}, now, defaultAttributeLimits)
+	if nil != err {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(event)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if string(js) != `[{"type":"myEvent","timestamp":1417136460000},{"alpha":"[\"a\",\"b\"]"},{}]` {
+		t.Fatal(string(js))
+	}
+}
+
 func TestInvalidCustomAttributeKey(t *testing.T) {
-	event, err := createCustomEvent("myEvent", map[string]interface{}{strLen512: 1}, now)
+	event, err := createCustomEvent("myEvent", map[string]interface{}{strLen512: 1}, now, defaultAttributeLimits)
 	if nil == err {
 		t.Fatal(err)
 	}
@@ -143,7 +157,7 @@ func TestTooManyAttributes(t *testing.T) {
 	for i := 0; i < customEventAttributeLimit+1; i++ {
 		params[strconv.Itoa(i)] = i
 	}
-	event, err := createCustomEvent("myEvent", params, now)
+	event, err := createCustomEvent("myEvent", params, now, defaultAttributeLimits)
 	if errNumAttributes != err {
 		t.Fatal(err)
 	}
@@ -152,6 +166,30 @@ func TestTooManyAttributes(t *testing.T) {
 	}
 }
 
+func TestTooManyMapAttributes(t *testing.T) {
+	large := make(map[string]interface{})
+	for i := 0; i < 100; i++ {
+		large[strconv.Itoa(i)] = i
+	}
+	if _, err := createCustomEvent("myEvent", map[string]interface{}{"m": large}, now, defaultAttributeLimits); nil == err {
+		t.Error("100 entry map accepted")
+	}
+
+	half := make(map[string]interface{})
+	for i := 0; i < customEventAttributeLimit/2; i++ {
+		half[strconv.Itoa(i)] = i
+	}
+	params := map[string]interface{}{"a": half, "b": half}
+	if _, err := createCustomEvent("myEvent", params, now, defaultAttributeLimits); nil != err {
+		t.Error(err)
+	}
+	params["c"] = 1
+	event, err := createCustomEvent("myEvent", params, now, defaultAttributeLimits)
+	if errNumAttributes != err || nil != event {
+		t.Error(event, err)
+	}
+}
+
 func TestCustomEventAttributeTypes(t *testing.T) {
 	testcases := []struct {
 		val interface{}
@@ -176,7 +214,7 @@ func TestCustomEventAttributeTypes(t *testing.T) {
 	}
 
 	for _, tc := range testcases {
-		event, err := createCustomEvent("myEvent", map[string]interface{}{"key": tc.val}, now)
+		event, err := createCustomEvent("myEvent", map[string]interface{}{"key": tc.val}, now, defaultAttributeLimits)
 		if nil != err {
 			t.Fatal(err)
 		}
@@ -192,7 +230,7 @@ func TestCustomEventAttributeTypes(t *testing.T) {
 
 func TestCustomParamsCopied(t *testing.T) {
 	params := map[string]interface{}{"alpha": 1}
-	event, err := createCustomEvent("myEvent", params, now)
+	event, err := createCustomEvent("myEvent", params, now, defaultAttributeLimits)
 	if nil != err {
 		t.Fatal(err)
 	}
@@ -209,7 +247,7 @@ func TestCustomParamsCopied(t *testing.T) {
 
 func TestMultipleAttributeJSON(t *testing.T) {
 	params := map[string]interface{}{"alpha": 1, "beta": 2}
-	event, err := createCustomEvent("myEvent", params, now)
+	event, err := createCustomEvent("myEvent", params, now, defaultAttributeLimits)
 	if nil != err {
 		t.Fatal(err)
 	}
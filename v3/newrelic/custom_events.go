@@ -20,7 +20,7 @@ func (cs *customEvents) Add(e *customEvent) {
 	// As a result, customEvents do not inherit their priority from the transaction, though
 	// they are still sampled according to priority sampling.
 	priority := newPriority()
-	cs.addEvent(analyticsEvent{priority, e})
+	cs.addEvent(analyticsEvent{priority: priority, jsonWriter: e})
 }
 
 func (cs *customEvents) MergeIntoHarvest(h *harvest) {
@@ -3,6 +3,43 @@
 
 package newrelic
 
+import "strings"
+
+// reservedMetricNamespaces are the prefixes of the metrics recorded by the
+// agent.  Custom metric names starting with these prefixes are rejected so
+// that they cannot be confused with, or corrupt, the agent's rollups.
+var reservedMetricNamespaces = []string{
+	"Apdex",
+	"CPU/",
+	"Datastore/",
+	"Errors/",
+	"External/",
+	"GC/",
+	"Go/",
+	"HttpDispatcher",
+	"Instance/",
+	"Memory/",
+	"MessageBroker/",
+	"OtherTransaction",
+	"Supportability/",
+	"WebFrontend/",
+	"WebTransaction",
+}
+
+// validateCustomMetricName returns an error if the custom metric name is in a
+// reserved namespace or does not have the required prefix.
+func validateCustomMetricName(name string, requiredPrefix string) error {
+	for _, ns := range reservedMetricNamespaces {
+		if strings.HasPrefix(name, ns) {
+			return errMetricNameReserved
+		}
+	}
+	if !strings.HasPrefix(name, requiredPrefix) {
+		return errMetricNamePrefix
+	}
+	return nil
+}
+
 // customMetric is a custom metric.
 type customMetric struct {
 	RawInputName string
@@ -13,3 +50,11 @@ type customMetric struct {
 func (m customMetric) MergeIntoHarvest(h *harvest) {
 	h.Metrics.addValue(customMetricName(m.RawInputName), "", m.Value, unforced)
 }
+
+// rejectedCustomMetric records a custom metric rejected because of its name.
+type rejectedCustomMetric struct{}
+
+// MergeIntoHarvest implements Harvestable.
+func (rejectedCustomMetric) MergeIntoHarvest(h *harvest) {
+	h.Metrics.addSingleCount(supportCustomMetricRejected, forced)
+}
@@ -0,0 +1,40 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+// dataUsage records the bytes exchanged with the collector by the requests of
+// one harvest.  It is merged into the following harvest as supportability
+// metrics.
+type dataUsage struct {
+	requests []dataUsageRequest
+}
+
+type dataUsageRequest struct {
+	method   string
+	sent     int
+	received int
+}
+
+// add records a request.  Requests which failed without a response, for
+// example because of a network error, are not recorded since their payload
+// may not have been sent.
+func (u *dataUsage) add(method string, sent int, resp rpmResponse) {
+	if 0 == resp.statusCode {
+		return
+	}
+	u.requests = append(u.requests, dataUsageRequest{
+		method:   method,
+		sent:     sent,
+		received: len(resp.body),
+	})
+}
+
+// MergeIntoHarvest implements harvestable.
+func (u *dataUsage) MergeIntoHarvest(h *harvest) {
+	for _, r := range u.requests {
+		sent, received := float64(r.sent), float64(r.received)
+		h.Metrics.addValueExclusive(supportCollectorOutputBytes, "", sent, received, forced)
+		h.Metrics.addValueExclusive(supportCollectorPrefix+r.method+"/Output/Bytes", "", sent, received, forced)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestDataUsageMergeIntoHarvest(t *testing.T) {
+	h := newHarvest(time.Now(), testHarvestCfgr)
+	usage := &dataUsage{}
+	usage.add(cmdMetrics, 100, rpmResponse{statusCode: 202, body: []byte("{}")})
+	usage.add(cmdCustomEvents, 50, rpmResponse{statusCode: 500})
+	usage.add(cmdCustomEvents, 70, rpmResponse{Err: errors.New("network failure")})
+	usage.MergeIntoHarvest(h)
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: "Supportability/Go/Collector/Output/Bytes", Scope: "", Forced: true, Data: []float64{2, 150, 2, 50, 100, 12500}},
+		{Name: "Supportability/Go/Collector/metric_data/Output/Bytes", Scope: "", Forced: true, Data: []float64{1, 100, 2, 100, 100, 10000}},
+		{Name: "Supportability/Go/Collector/custom_event_data/Output/Bytes", Scope: "", Forced: true, Data: []float64{1, 50, 0, 50, 50, 2500}},
+	})
+}
+
+type dataUsageTransport struct{}
+
+func (dataUsageTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return makeResponse(202, `{"return_value":{}}`), nil
+}
+
+func TestDataUsageHarvest(t *testing.T) {
+	app := testApp(nil, nil, t)
+	run, _ := app.app.getState()
+	app.app.rpmControls.Client = &http.Client{Transport: dataUsageTransport{}}
+	now := app.app.config.Clock.Now()
+	h := newHarvest(now, run.harvestConfig)
+	h.Metrics.addSingleCount("myMetric", forced)
+	app.app.doHarvest(h, now, run)
+
+	// The usage of the harvest is recorded in the following harvest.
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Supportability/Go/Collector/Output/Bytes", Scope: "", Forced: true, Data: nil},
+		{Name: "Supportability/Go/Collector/metric_data/Output/Bytes", Scope: "", Forced: true, Data: nil},
+	})
+	m := app.app.testHarvest.Metrics.metrics[metricID{Name: "Supportability/Go/Collector/metric_data/Output/Bytes"}]
+	if nil == m || m.data.countSatisfied != 1 || m.data.totalTolerated == 0 || m.data.exclusiveFailed != float64(len(`{"return_value":{}}`)) {
+		t.Error(m)
+	}
+}
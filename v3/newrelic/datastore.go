@@ -3,8 +3,20 @@
 
 package newrelic
 
+import (
+	"net"
+	"strings"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
 // DatastoreProduct is used to identify your datastore type in New Relic.  It
-// is used in the DatastoreSegment Product field.
+// is used in the DatastoreSegment Product field.  Use the constants below for
+// the products they cover.  Products matching a constant or a common alias
+// regardless of case, eg. "postgresql" or "SQLITE3", are recorded using the
+// constant, so that their metrics are not split.  Other products are
+// recorded as given, without surrounding whitespace and with any "/", which
+// separates the parts of metric names, replaced by "_".
 type DatastoreProduct string
 
 // Datastore names used across New Relic agents:
@@ -32,4 +44,113 @@ const (
 	DatastoreTarantool     DatastoreProduct = "Tarantool"
 	DatastoreVoltDB        DatastoreProduct = "VoltDB"
 	DatastoreAerospike     DatastoreProduct = "Aerospike"
+	DatastoreClickHouse    DatastoreProduct = "ClickHouse"
+	DatastoreCockroachDB   DatastoreProduct = "CockroachDB"
+	DatastoreScylla        DatastoreProduct = "Scylla"
+	DatastoreTiDB          DatastoreProduct = "TiDB"
+	DatastoreDuckDB        DatastoreProduct = "DuckDB"
+	DatastoreLibSQL        DatastoreProduct = "LibSQL"
+	DatastoreSQLCipher     DatastoreProduct = "SQLCipher"
 )
+
+// datastoreProducts maps the lower case names of the product constants, and of
+// their common aliases, to the constants.
+var datastoreProducts = func() map[string]DatastoreProduct {
+	m := map[string]DatastoreProduct{
+		"crdb":       DatastoreCockroachDB,
+		"cockroach":  DatastoreCockroachDB,
+		"db2":        DatastoreIBMDB2,
+		"dynamo":     DatastoreDynamoDB,
+		"elastic":    DatastoreElasticsearch,
+		"mongo":      DatastoreMongoDB,
+		"pg":         DatastorePostgres,
+		"postgresql": DatastorePostgres,
+		"scylladb":   DatastoreScylla,
+		"sqlite3":    DatastoreSQLite,
+		"sqlserver":  DatastoreMSSQL,
+	}
+	for _, p := range []DatastoreProduct{
+		DatastoreCassandra, DatastoreCouchDB, DatastoreDerby,
+		DatastoreDynamoDB, DatastoreElasticsearch, DatastoreFirebird,
+		DatastoreIBMDB2, DatastoreInformix, DatastoreMemcached,
+		DatastoreMongoDB, DatastoreMSSQL, DatastoreMySQL, DatastoreNeptune,
+		DatastoreOracle, DatastorePostgres, DatastoreRedis, DatastoreRiak,
+		DatastoreSnowflake, DatastoreSolr, DatastoreSQLite,
+		DatastoreTarantool, DatastoreVoltDB, DatastoreAerospike,
+		DatastoreClickHouse, DatastoreCockroachDB, DatastoreScylla,
+		DatastoreTiDB, DatastoreDuckDB, DatastoreLibSQL, DatastoreSQLCipher,
+	} {
+		m[strings.ToLower(string(p))] = p
+	}
+	return m
+}()
+
+// normalizeDatastoreProduct returns the product recorded for the
+// DatastoreSegment Product field, see DatastoreProduct.
+func normalizeDatastoreProduct(p DatastoreProduct) DatastoreProduct {
+	name := strings.TrimSpace(string(p))
+	if known, ok := datastoreProducts[strings.ToLower(name)]; ok {
+		return known
+	}
+	return DatastoreProduct(strings.ReplaceAll(name, "/", "_"))
+}
+
+// nonSQLDatastoreProducts are the products whose queries are never parsed as
+// SQL, since their commands may look like SQL statements, eg. the Redis "SET".
+var nonSQLDatastoreProducts = map[DatastoreProduct]bool{
+	DatastoreAerospike:     true,
+	DatastoreCouchDB:       true,
+	DatastoreElasticsearch: true,
+	DatastoreMemcached:     true,
+	DatastoreMongoDB:       true,
+	DatastoreNeptune:       true,
+	DatastoreRedis:         true,
+	DatastoreRiak:          true,
+	DatastoreSolr:          true,
+	DatastoreTarantool:     true,
+}
+
+// deriveDatastoreOperation sets the empty Operation and Collection fields of
+// the segment using its ParameterizedQuery, if it is a SQL statement.
+func deriveDatastoreOperation(s *DatastoreSegment) {
+	if "" == s.ParameterizedQuery || ("" != s.Operation && "" != s.Collection) {
+		return
+	}
+	if nonSQLDatastoreProducts[s.Product] {
+		return
+	}
+	op, table, _ := internal.ParseSQL(s.ParameterizedQuery)
+	if "" == op {
+		return
+	}
+	if "" == s.Operation {
+		s.Operation = op
+	}
+	// The table is only used if it belongs to the same operation.
+	if "" == s.Collection && strings.EqualFold(s.Operation, op) {
+		s.Collection = table
+	}
+}
+
+// datastoreInstance returns the host and port, path, or ID of the datastore
+// at the address, see DatastoreSegment.SetInstance.
+func datastoreInstance(network, address string) (host, portPathOrID string) {
+	if "" == address {
+		return "", ""
+	}
+	if strings.HasPrefix(network, "unix") {
+		return "localhost", address
+	}
+	host, port, err := net.SplitHostPort(address)
+	if nil != err {
+		// A path given without the "unix" network is still a socket.
+		if strings.HasPrefix(address, "/") {
+			return "localhost", address
+		}
+		return address, ""
+	}
+	if "" == host {
+		host = "localhost"
+	}
+	return host, port
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// debugCapture writes harvest payloads to the destinations configured in
+// Config.DebugCapture.
+type debugCapture struct {
+	// This mutex serializes writes since harvests may run concurrently.
+	sync.Mutex
+	writer    io.Writer
+	directory string
+	// seq numbers the files written to the directory, so that payloads
+	// for the same method in one harvest do not overwrite each other.
+	seq uint64
+}
+
+// newDebugCapture returns nil if debug capture is not configured.
+func newDebugCapture(c config) *debugCapture {
+	if nil == c.DebugCapture.Writer && "" == c.DebugCapture.Directory {
+		return nil
+	}
+	return &debugCapture{
+		writer:    c.DebugCapture.Writer,
+		directory: c.DebugCapture.Directory,
+	}
+}
+
+// capturedPayload is the format of each harvest payload written.
+type capturedPayload struct {
+	Method    string          `json:"method"`
+	Timestamp int64           `json:"timestamp"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+func formatCapturedPayload(cmd string, harvestStart time.Time, data []byte) ([]byte, error) {
+	payload := data
+	if !json.Valid(data) {
+		// Payloads are expected to be JSON, but any other data is
+		// still captured as a string.
+		var err error
+		if payload, err = json.Marshal(string(data)); nil != err {
+			return nil, err
+		}
+	}
+	js, err := json.Marshal(capturedPayload{
+		Method:    cmd,
+		Timestamp: timeToIntMillis(harvestStart),
+		Payload:   payload,
+	})
+	if nil != err {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, js, "", "  "); nil != err {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// capture writes the payload to the configured Writer and Directory.  It is
+// safe to call on a nil debugCapture.
+func (dc *debugCapture) capture(cmd string, harvestStart time.Time, data []byte) error {
+	if nil == dc {
+		return nil
+	}
+	out, err := formatCapturedPayload(cmd, harvestStart, data)
+	if nil != err {
+		return err
+	}
+
+	dc.Lock()
+	defer dc.Unlock()
+
+	if nil != dc.writer {
+		if _, err := dc.writer.Write(out); nil != err {
+			return err
+		}
+	}
+	if "" != dc.directory {
+		dc.seq++
+		name := fmt.Sprintf("%d-%d-%s.json", harvestStart.UnixNano(), dc.seq, cmd)
+		if err := os.WriteFile(filepath.Join(dc.directory, name), out, 0600); nil != err {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDebugCaptureDisabled(t *testing.T) {
+	dc := newDebugCapture(config{Config: defaultConfig()})
+	if nil != dc {
+		t.Fatal(dc)
+	}
+	if err := dc.capture(cmdMetrics, time.Now(), []byte(`[]`)); nil != err {
+		t.Error(err)
+	}
+}
+
+func TestDebugCaptureWriter(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := config{Config: defaultConfig()}
+	cfg.DebugCapture.Writer = &buf
+	dc := newDebugCapture(cfg)
+
+	start := time.Unix(1417136460, 0)
+	if err := dc.capture(cmdCustomEvents, start, []byte(`["run-id",{"reservoir_size":10},[]]`)); nil != err {
+		t.Fatal(err)
+	}
+	expect := `{
+  "method": "custom_event_data",
+  "timestamp": 1417136460000,
+  "payload": [
+    "run-id",
+    {
+      "reservoir_size": 10
+    },
+    []
+  ]
+}
+`
+	if out := buf.String(); out != expect {
+		t.Error(out)
+	}
+}
+
+func TestDebugCaptureInvalidJSON(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := config{Config: defaultConfig()}
+	cfg.DebugCapture.Writer = &buf
+	dc := newDebugCapture(cfg)
+
+	if err := dc.capture(cmdMetrics, time.Now(), []byte(`not json`)); nil != err {
+		t.Fatal(err)
+	}
+	var captured capturedPayload
+	if err := json.Unmarshal(buf.Bytes(), &captured); nil != err {
+		t.Fatal(err)
+	}
+	if string(captured.Payload) != `"not json"` {
+		t.Error(string(captured.Payload))
+	}
+}
+
+func TestDebugCaptureDirectory(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config{Config: defaultConfig()}
+	cfg.DebugCapture.Directory = dir
+	dc := newDebugCapture(cfg)
+
+	start := time.Unix(1417136460, 0)
+	if err := dc.capture(cmdErrorData, start, []byte(`[]`)); nil != err {
+		t.Fatal(err)
+	}
+	// A second payload for the same method in the same harvest is
+	// written to a separate file.
+	if err := dc.capture(cmdErrorData, start, []byte(`{}`)); nil != err {
+		t.Fatal(err)
+	}
+	for name, payload := range map[string]string{
+		"1417136460000000000-1-error_data.json": `[]`,
+		"1417136460000000000-2-error_data.json": `{}`,
+	} {
+		js, err := os.ReadFile(filepath.Join(dir, name))
+		if nil != err {
+			t.Fatal(err)
+		}
+		var captured capturedPayload
+		if err := json.Unmarshal(js, &captured); nil != err {
+			t.Fatal(err)
+		}
+		if captured.Method != cmdErrorData || string(captured.Payload) != payload {
+			t.Error(string(js))
+		}
+	}
+}
+
+func TestDebugCaptureDirectoryMissing(t *testing.T) {
+	cfg := config{Config: defaultConfig()}
+	cfg.DebugCapture.Directory = filepath.Join(t.TempDir(), "missing")
+	dc := newDebugCapture(cfg)
+
+	if err := dc.capture(cmdErrorData, time.Now(), []byte(`[]`)); nil == err {
+		t.Error("expected error writing to missing directory")
+	}
+}
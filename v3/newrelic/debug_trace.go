@@ -0,0 +1,85 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultDebugTraceMaxTTL is the default DebugHeader.MaxTTL.
+const defaultDebugTraceMaxTTL = time.Hour
+
+// DebugTraceHeaderValue returns the value of the
+// Config.DistributedTracer.DebugHeader which forces the sampling and verbose
+// collection of a request until the expiry time.  The secret must match the
+// DebugHeader.Secret of the applications receiving the request, and the
+// expiry must be no further in the future than their DebugHeader.MaxTTL.  The
+// value has the form "<expiry>.<signature>", where expiry is a Unix timestamp
+// in seconds and signature is the hex encoded HMAC-SHA256 of the expiry.
+func DebugTraceHeaderValue(secret string, expires time.Time) string {
+	expiry := strconv.FormatInt(expires.Unix(), 10)
+	return expiry + "." + debugTraceSignature(secret, expiry)
+}
+
+func debugTraceSignature(secret, expiry string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(expiry))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// validDebugTraceToken returns whether the token was created by
+// DebugTraceHeaderValue with the secret, has not expired, and does not expire
+// more than maxTTL from now.
+func validDebugTraceToken(token, secret string, maxTTL time.Duration, now time.Time) bool {
+	idx := strings.IndexByte(token, '.')
+	if idx < 0 {
+		return false
+	}
+	expiry, signature := token[:idx], token[idx+1:]
+	secs, err := strconv.ParseInt(expiry, 10, 64)
+	if nil != err || now.Unix() > secs || secs-now.Unix() > int64(maxTTL/time.Second) {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(debugTraceSignature(secret, expiry)))
+}
+
+// acceptDebugTraceHeader returns whether the headers contain a valid
+// DistributedTracer.DebugHeader.  The token is recorded so that it can be
+// propagated to outbound requests.
+func (txn *txn) acceptDebugTraceHeader(hdrs http.Header) bool {
+	cfg := txn.Config.DistributedTracer.DebugHeader
+	if "" == cfg.Name {
+		return false
+	}
+	token := hdrs.Get(cfg.Name)
+	if "" == token {
+		return false
+	}
+	support := &txn.DistributedTracingSupport
+	if !validDebugTraceToken(token, cfg.Secret, cfg.MaxTTL, txn.Config.Clock.Now()) {
+		support.DebugHeaderRejected = true
+		return false
+	}
+	support.DebugHeaderAccepted = true
+	txn.debugTraceToken = token
+	return true
+}
+
+// forceDebugTrace samples the transaction, overriding any inbound sampling
+// decision, and collects it at DetailLevelVerbose with a transaction trace.
+func (txn *txn) forceDebugTrace() {
+	txn.BetterCAT.Sampled = true
+	if txn.BetterCAT.Priority < 1 {
+		txn.BetterCAT.Priority += 1.0
+	}
+	txn.sampledCalculated = true
+	txn.TxnTrace.forced = true
+	txn.setDetailLevelLocked(DetailLevelVerbose)
+}
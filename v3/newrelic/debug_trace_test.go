@@ -0,0 +1,184 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+const (
+	testDebugHeader = "X-Debug-Trace"
+	testDebugSecret = "my debug secret"
+)
+
+func TestValidDebugTraceToken(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	token := DebugTraceHeaderValue(testDebugSecret, now.Add(time.Hour))
+	if !strings.HasPrefix(token, "1700003600.") {
+		t.Error(token)
+	}
+	maxTTL := defaultDebugTraceMaxTTL
+	if !validDebugTraceToken(token, testDebugSecret, maxTTL, now) {
+		t.Error("valid token rejected", token)
+	}
+	if validDebugTraceToken(token, testDebugSecret, maxTTL, now.Add(2*time.Hour)) {
+		t.Error("expired token accepted", token)
+	}
+	if validDebugTraceToken(token, testDebugSecret, 30*time.Minute, now) {
+		t.Error("token expiring after the max ttl accepted", token)
+	}
+	if validDebugTraceToken(token, "another secret", maxTTL, now) {
+		t.Error("token with wrong secret accepted", token)
+	}
+	forged := "1800000000" + token[strings.IndexByte(token, '.'):]
+	if validDebugTraceToken(forged, testDebugSecret, maxTTL, now) {
+		t.Error("forged token accepted", forged)
+	}
+	longLived := DebugTraceHeaderValue(testDebugSecret, now.Add(365*24*time.Hour))
+	if validDebugTraceToken(longLived, testDebugSecret, maxTTL, now) {
+		t.Error("long lived token accepted", longLived)
+	}
+	for _, token := range []string{"", "1", "abc.def", "1800000000."} {
+		if validDebugTraceToken(token, testDebugSecret, maxTTL, now) {
+			t.Error("malformed token accepted", token)
+		}
+	}
+}
+
+func TestValidateDebugHeader(t *testing.T) {
+	c := defaultConfig()
+	c.License = "0123456789012345678901234567890123456789"
+	c.AppName = "my app"
+	c.DistributedTracer.DebugHeader.Name = testDebugHeader
+	if err := c.validate(); err != errDebugHeaderSecret {
+		t.Error(err)
+	}
+	c.DistributedTracer.DebugHeader.Secret = testDebugSecret
+	if err := c.validate(); nil != err {
+		t.Error(err)
+	}
+	c.DistributedTracer.DebugHeader.MaxTTL = 0
+	if err := c.validate(); err != errDebugHeaderMaxTTL {
+		t.Error(err)
+	}
+	c.DistributedTracer.DebugHeader.MaxTTL = defaultDebugTraceMaxTTL
+	js, err := json.Marshal(settings(c))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(js), testDebugSecret) {
+		t.Error("debug header secret reported", string(js))
+	}
+}
+
+func debugHeaderConfig(cfg *Config) {
+	enableBetterCAT(cfg)
+	cfg.DistributedTracer.DebugHeader.Name = testDebugHeader
+	cfg.DistributedTracer.DebugHeader.Secret = testDebugSecret
+}
+
+func TestDebugHeaderForcesSampling(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleNothing()
+	}
+	app := testApp(replyfn, debugHeaderConfig, t)
+	txn := app.StartTransaction("hello")
+
+	token := DebugTraceHeaderValue(testDebugSecret, time.Now().Add(time.Hour))
+	hdrs := http.Header{}
+	hdrs.Set(DistributedTraceW3CTraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	hdrs.Set(DistributedTraceW3CTraceStateHeader, "123@nr=0-0-123-456-00f067aa0ba902b7-52fdfc072182654f-0-0.437714-1577830891900")
+	hdrs.Set(testDebugHeader, token)
+	txn.AcceptDistributedTraceHeaders(TransportHTTP, hdrs)
+	app.expectNoLoggedErrors(t)
+
+	if !txn.IsSampled() {
+		t.Error("debug trace not sampled")
+	}
+	if internalTxn := txn.thread.txn; !internalTxn.TxnTrace.forced || internalTxn.detailLevel != DetailLevelVerbose {
+		t.Error(internalTxn.TxnTrace.forced, internalTxn.detailLevel)
+	}
+	if p := txn.thread.txn.BetterCAT.Priority; p < 1 {
+		t.Error(p)
+	}
+
+	outbound := http.Header{}
+	txn.InsertDistributedTraceHeaders(outbound)
+	if v := outbound.Get(testDebugHeader); v != token {
+		t.Error(v)
+	}
+	if tp := outbound.Get(DistributedTraceW3CTraceParentHeader); !strings.HasSuffix(tp, "-01") {
+		t.Error(tp)
+	}
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Supportability/DistributedTrace/DebugHeader/Accepted", Scope: "", Forced: true, Data: nil},
+	})
+}
+
+func TestDebugHeaderWithoutTraceContext(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleNothing()
+	}
+	app := testApp(replyfn, debugHeaderConfig, t)
+	txn := app.StartTransaction("hello")
+	hdrs := http.Header{}
+	hdrs.Set(testDebugHeader, DebugTraceHeaderValue(testDebugSecret, time.Now().Add(time.Hour)))
+	txn.AcceptDistributedTraceHeaders(TransportHTTP, hdrs)
+	if !txn.IsSampled() {
+		t.Error("debug trace not sampled")
+	}
+	txn.End()
+}
+
+func TestDebugHeaderRejected(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleNothing()
+	}
+	app := testApp(replyfn, debugHeaderConfig, t)
+	txn := app.StartTransaction("hello")
+
+	hdrs := http.Header{}
+	hdrs.Set(testDebugHeader, DebugTraceHeaderValue("wrong secret", time.Now().Add(time.Hour)))
+	txn.AcceptDistributedTraceHeaders(TransportHTTP, hdrs)
+
+	if txn.IsSampled() {
+		t.Error("invalid debug trace sampled")
+	}
+	if internalTxn := txn.thread.txn; internalTxn.TxnTrace.forced || internalTxn.detailLevel != DetailLevelStandard {
+		t.Error(internalTxn.TxnTrace.forced, internalTxn.detailLevel)
+	}
+	outbound := http.Header{}
+	txn.InsertDistributedTraceHeaders(outbound)
+	if v := outbound.Get(testDebugHeader); "" != v {
+		t.Error(v)
+	}
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Supportability/DistributedTrace/DebugHeader/Rejected", Scope: "", Forced: true, Data: nil},
+	})
+}
+
+func TestDebugHeaderDisabled(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	hdrs := http.Header{}
+	hdrs.Set(testDebugHeader, DebugTraceHeaderValue(testDebugSecret, time.Now().Add(time.Hour)))
+	txn.AcceptDistributedTraceHeaders(TransportHTTP, hdrs)
+	if "" != txn.thread.txn.debugTraceToken {
+		t.Error("debug header accepted when disabled")
+	}
+	txn.End()
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	errDeploymentRevision     = errors.New("deployment revision required")
+	errDeploymentNotConnected = errors.New("application not connected: the entity of the deployment is unknown")
+	errDeploymentAPIKey       = errors.New("deployment api key required: set Deployments.APIKey or Deployments.UseLicense")
+)
+
+// deploymentMutation creates a deployment marker using NerdGraph's change
+// tracking API.
+const deploymentMutation = `mutation($deployment: ChangeTrackingDeploymentInput!) {
+  changeTrackingCreateDeployment(deployment: $deployment) { deploymentId }
+}`
+
+// deploymentRequest is a request to record a deployment marker for an
+// entity.
+type deploymentRequest struct {
+	Host       string
+	APIKey     string
+	EntityGUID string
+	Revision   string
+	Changelog  string
+	User       string
+	Timestamp  time.Time
+}
+
+func (r deploymentRequest) url() string {
+	return "https://" + r.Host + "/graphql"
+}
+
+func (r deploymentRequest) body() ([]byte, error) {
+	deployment := map[string]interface{}{
+		"entityGuid": r.EntityGUID,
+		"version":    r.Revision,
+		"timestamp":  timeToIntMillis(r.Timestamp),
+	}
+	if "" != r.Changelog {
+		deployment["changelog"] = r.Changelog
+	}
+	if "" != r.User {
+		deployment["user"] = r.User
+	}
+	return json.Marshal(map[string]interface{}{
+		"query":     deploymentMutation,
+		"variables": map[string]interface{}{"deployment": deployment},
+	})
+}
+
+// deploymentResponse is the part of the NerdGraph response used to detect
+// errors, which are reported with a 200 status code.
+type deploymentResponse struct {
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// sendDeployment records the deployment marker.
+func sendDeployment(r deploymentRequest, cs rpmControls) error {
+	data, err := r.body()
+	if nil != err {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", r.url(), bytes.NewReader(data))
+	if nil != err {
+		return err
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("User-Agent", userAgentPrefix+Version)
+	req.Header.Add("API-Key", r.APIKey)
+
+	resp, err := cs.Client.Do(req)
+	if nil != err {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("deployment api response code: %d", resp.StatusCode)
+	}
+	var dr deploymentResponse
+	if err := json.Unmarshal(body, &dr); nil != err {
+		return fmt.Errorf("unable to parse deployment api response: %v", err)
+	}
+	if len(dr.Errors) > 0 {
+		msgs := make([]string, len(dr.Errors))
+		for i, e := range dr.Errors {
+			msgs[i] = e.Message
+		}
+		return fmt.Errorf("deployment api errors: %s", strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// RecordDeployment implements newrelic.Application's RecordDeployment.
+func (app *app) RecordDeployment(revision, changelog, user string) error {
+	if "" == revision {
+		return errDeploymentRevision
+	}
+	run, _ := app.getState()
+	if "" == run.Reply.EntityGUID {
+		return errDeploymentNotConnected
+	}
+	apiKey, keySource := app.config.Deployments.APIKey, "Deployments.APIKey"
+	if "" == apiKey {
+		if !app.config.Deployments.UseLicense {
+			return errDeploymentAPIKey
+		}
+		apiKey, keySource = app.config.License, "License"
+	}
+	app.Info("recording deployment", map[string]interface{}{
+		"revision":   revision,
+		"key-source": keySource,
+	})
+	return sendDeployment(deploymentRequest{
+		Host:       app.config.deploymentHost(),
+		APIKey:     apiKey,
+		EntityGUID: run.Reply.EntityGUID,
+		Revision:   revision,
+		Changelog:  changelog,
+		User:       user,
+		Timestamp:  app.config.Clock.Now(),
+	}, app.rpmControls)
+}
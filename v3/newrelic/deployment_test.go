@@ -0,0 +1,163 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/logger"
+)
+
+type deploymentMock struct {
+	response *http.Response
+	err      error
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (m *deploymentMock) RoundTrip(r *http.Request) (*http.Response, error) {
+	m.requests = append(m.requests, r)
+	body, err := ioutil.ReadAll(r.Body)
+	if nil != err {
+		return nil, err
+	}
+	m.bodies = append(m.bodies, body)
+	return m.response, m.err
+}
+
+func deploymentTestApp(m *deploymentMock, entityGUID string, cfgFn func(*Config)) *app {
+	cfg := defaultConfig()
+	cfg.License = "eu01xx6789012345678901234567890123456789"
+	cfg.Clock = &limiterTestClock{now: time.Unix(1417136460, 0)}
+	if nil != cfgFn {
+		cfgFn(&cfg)
+	}
+	reply := internal.ConnectReplyDefaults()
+	reply.EntityGUID = entityGUID
+	c := config{Config: cfg}
+	return &app{
+		Logger:         logger.ShimLogger{},
+		config:         c,
+		placeholderRun: newAppRun(c, reply),
+		rpmControls: rpmControls{
+			Client: &http.Client{Transport: m},
+			Logger: logger.ShimLogger{},
+		},
+	}
+}
+
+func TestRecordDeployment(t *testing.T) {
+	m := &deploymentMock{response: makeResponse(200, `{"data":{"changeTrackingCreateDeployment":{"deploymentId":"abc"}}}`)}
+	app := deploymentTestApp(m, "my-entity-guid", func(cfg *Config) {
+		cfg.Deployments.APIKey = "my-api-key"
+	})
+	if err := app.RecordDeployment("v1.2.3", "fixed bugs", "deployer"); nil != err {
+		t.Fatal(err)
+	}
+	if len(m.requests) != 1 {
+		t.Fatal(len(m.requests))
+	}
+	req := m.requests[0]
+	if u := req.URL.String(); u != "https://api.eu.newrelic.com/graphql" {
+		t.Error(u)
+	}
+	if h := req.Header.Get("API-Key"); h != "my-api-key" {
+		t.Error(h)
+	}
+	var body struct {
+		Query     string `json:"query"`
+		Variables struct {
+			Deployment map[string]interface{} `json:"deployment"`
+		} `json:"variables"`
+	}
+	if err := json.Unmarshal(m.bodies[0], &body); nil != err {
+		t.Fatal(err)
+	}
+	if body.Query != deploymentMutation {
+		t.Error(body.Query)
+	}
+	d := body.Variables.Deployment
+	if d["entityGuid"] != "my-entity-guid" || d["version"] != "v1.2.3" ||
+		d["changelog"] != "fixed bugs" || d["user"] != "deployer" ||
+		d["timestamp"] != float64(1417136460000) {
+		t.Error(string(m.bodies[0]))
+	}
+}
+
+func TestRecordDeploymentLicenseKey(t *testing.T) {
+	m := &deploymentMock{response: makeResponse(200, `{"data":{}}`)}
+	app := deploymentTestApp(m, "my-entity-guid", func(cfg *Config) {
+		cfg.License = "0123456789012345678901234567890123456789"
+		cfg.Deployments.UseLicense = true
+	})
+	if err := app.RecordDeployment("v1", "", ""); nil != err {
+		t.Fatal(err)
+	}
+	req := m.requests[0]
+	if u := req.URL.String(); u != "https://api.newrelic.com/graphql" {
+		t.Error(u)
+	}
+	if h := req.Header.Get("API-Key"); h != "0123456789012345678901234567890123456789" {
+		t.Error(h)
+	}
+	var body struct {
+		Variables struct {
+			Deployment map[string]interface{} `json:"deployment"`
+		} `json:"variables"`
+	}
+	if err := json.Unmarshal(m.bodies[0], &body); nil != err {
+		t.Fatal(err)
+	}
+	d := body.Variables.Deployment
+	if _, ok := d["changelog"]; ok {
+		t.Error(d)
+	}
+	if _, ok := d["user"]; ok {
+		t.Error(d)
+	}
+}
+
+func TestRecordDeploymentErrors(t *testing.T) {
+	m := &deploymentMock{response: makeResponse(200, `{"data":{}}`)}
+	if err := deploymentTestApp(m, "my-entity-guid", nil).RecordDeployment("", "", ""); err != errDeploymentRevision {
+		t.Error(err)
+	}
+	if err := deploymentTestApp(m, "", nil).RecordDeployment("v1", "", ""); err != errDeploymentNotConnected {
+		t.Error(err)
+	}
+	if err := deploymentTestApp(m, "my-entity-guid", nil).RecordDeployment("v1", "", ""); err != errDeploymentAPIKey {
+		t.Error(err)
+	}
+	if len(m.requests) != 0 {
+		t.Error(len(m.requests))
+	}
+
+	withKey := func(cfg *Config) { cfg.Deployments.APIKey = "my-api-key" }
+	m = &deploymentMock{response: makeResponse(200, `{"errors":[{"message":"Invalid API key"}]}`)}
+	err := deploymentTestApp(m, "my-entity-guid", withKey).RecordDeployment("v1", "", "")
+	if nil == err || err.Error() != "deployment api errors: Invalid API key" {
+		t.Error(err)
+	}
+	m = &deploymentMock{response: makeResponse(403, ``)}
+	if err := deploymentTestApp(m, "my-entity-guid", withKey).RecordDeployment("v1", "", ""); nil == err {
+		t.Error("error response accepted")
+	}
+	m = &deploymentMock{err: errors.New("client error")}
+	if err := deploymentTestApp(m, "my-entity-guid", withKey).RecordDeployment("v1", "", ""); nil == err {
+		t.Error("client error not returned")
+	}
+}
+
+func TestRecordDeploymentNilApplication(t *testing.T) {
+	var app *Application
+	if err := app.RecordDeployment("v1", "", ""); nil != err {
+		t.Error(err)
+	}
+}
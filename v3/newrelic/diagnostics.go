@@ -0,0 +1,164 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"math"
+	"runtime"
+	runtimemetrics "runtime/metrics"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+const (
+	schedLatenciesRuntimeMetric = "/sched/latencies:seconds"
+	runtimeDiagnosticEventType  = "GoRuntimeDiagnostic"
+)
+
+// diagnosticsSample is a snapshot of the scheduler latency histogram and
+// goroutine count.
+type diagnosticsSample struct {
+	when           time.Time
+	numGoroutine   int
+	schedLatencies *runtimemetrics.Float64Histogram
+}
+
+// getDiagnosticsSample gathers a new diagnosticsSample.
+func getDiagnosticsSample(now time.Time) *diagnosticsSample {
+	s := &diagnosticsSample{
+		when:         now,
+		numGoroutine: runtime.NumGoroutine(),
+	}
+	samples := []runtimemetrics.Sample{{Name: schedLatenciesRuntimeMetric}}
+	runtimemetrics.Read(samples)
+	if samples[0].Value.Kind() == runtimemetrics.KindFloat64Histogram {
+		s.schedLatencies = samples[0].Value.Float64Histogram()
+	}
+	return s
+}
+
+// diagnosticsSamples is used as the parameter to getDiagnosticsStats to avoid
+// mixing up the previous and current sample.
+type diagnosticsSamples struct {
+	Previous *diagnosticsSample
+	Current  *diagnosticsSample
+}
+
+// diagnosticsStats contains the diagnostics for a period of time.
+type diagnosticsStats struct {
+	numGoroutine int
+	// goroutineGrowth is the change in the number of goroutines per
+	// minute.
+	goroutineGrowth float64
+	// hasSchedLatency is false if no goroutines were scheduled during
+	// the period.
+	hasSchedLatency    bool
+	schedLatencyMedian time.Duration
+	schedLatencyP99    time.Duration
+}
+
+// histogramPercentile returns the upper bound of the bucket containing the
+// given percentile, or the lower bound if that bucket is unbounded.
+func histogramPercentile(counts []uint64, buckets []float64, percentile float64) float64 {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	if 0 == total {
+		return 0
+	}
+	target := uint64(math.Ceil(percentile * float64(total)))
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen >= target {
+			if math.IsInf(buckets[i+1], 1) {
+				return buckets[i]
+			}
+			return buckets[i+1]
+		}
+	}
+	return buckets[len(buckets)-1]
+}
+
+// getDiagnosticsStats combines two diagnosticsSamples into a
+// diagnosticsStats.
+func getDiagnosticsStats(ss diagnosticsSamples) diagnosticsStats {
+	cur := ss.Current
+	prev := ss.Previous
+
+	s := diagnosticsStats{
+		numGoroutine: cur.numGoroutine,
+	}
+	if elapsed := cur.when.Sub(prev.when); elapsed > 0 {
+		s.goroutineGrowth = float64(cur.numGoroutine-prev.numGoroutine) / elapsed.Minutes()
+	}
+
+	if nil == cur.schedLatencies {
+		return s
+	}
+	// The histogram is cumulative, so the previous counts are subtracted
+	// to find the latencies of the period.
+	counts := make([]uint64, len(cur.schedLatencies.Counts))
+	copy(counts, cur.schedLatencies.Counts)
+	if nil != prev.schedLatencies && len(prev.schedLatencies.Counts) == len(counts) {
+		for i, c := range prev.schedLatencies.Counts {
+			counts[i] -= c
+		}
+	}
+	buckets := cur.schedLatencies.Buckets
+	if median := histogramPercentile(counts, buckets, 0.5); median > 0 {
+		s.hasSchedLatency = true
+		s.schedLatencyMedian = time.Duration(median * float64(time.Second))
+		s.schedLatencyP99 = time.Duration(histogramPercentile(counts, buckets, 0.99) * float64(time.Second))
+	}
+	return s
+}
+
+// MergeIntoHarvest implements Harvestable.
+func (s diagnosticsStats) MergeIntoHarvest(h *harvest) {
+	h.Metrics.addValue(goroutineGrowthRate, "", s.goroutineGrowth, forced)
+	if s.hasSchedLatency {
+		h.Metrics.addValue(schedLatencyMedian, "", s.schedLatencyMedian.Seconds(), forced)
+		h.Metrics.addValue(schedLatencyP99, "", s.schedLatencyP99.Seconds(), forced)
+	}
+}
+
+// events returns the parameters of the GoRuntimeDiagnostic custom events for
+// the thresholds which have been exceeded.
+func (s diagnosticsStats) events(c Config) []map[string]interface{} {
+	var events []map[string]interface{}
+	threshold := c.RuntimeSampler.Diagnostics.SchedulerLatencyThreshold
+	if threshold > 0 && s.schedLatencyP99 > threshold {
+		events = append(events, map[string]interface{}{
+			"diagnostic": "schedulerLatency",
+			"value":      s.schedLatencyP99.Seconds(),
+			"threshold":  threshold.Seconds(),
+			"goroutines": s.numGoroutine,
+		})
+	}
+	growth := c.RuntimeSampler.Diagnostics.GoroutineGrowthThreshold
+	if growth > 0 && s.goroutineGrowth > growth {
+		events = append(events, map[string]interface{}{
+			"diagnostic": "goroutineGrowth",
+			"value":      s.goroutineGrowth,
+			"threshold":  growth,
+			"goroutines": s.numGoroutine,
+		})
+	}
+	return events
+}
+
+// recordDiagnostics records the diagnostics metrics and threshold events.
+func (app *app) recordDiagnostics(runID internal.AgentRunID, s diagnosticsStats) {
+	app.Consume(runID, s)
+	for _, params := range s.events(app.config.Config) {
+		if err := app.RecordCustomEvent(runtimeDiagnosticEventType, params); nil != err {
+			app.Debug("unable to record runtime diagnostic event", map[string]interface{}{
+				"reason": err.Error(),
+			})
+		}
+	}
+}
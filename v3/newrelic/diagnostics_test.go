@@ -0,0 +1,172 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"math"
+	runtimemetrics "runtime/metrics"
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestGetDiagnosticsSample(t *testing.T) {
+	now := time.Now()
+	sample := getDiagnosticsSample(now)
+	if now != sample.when {
+		t.Error(now, sample.when)
+	}
+	if sample.numGoroutine <= 0 {
+		t.Error(sample.numGoroutine)
+	}
+	if nil == sample.schedLatencies {
+		t.Error("missing scheduler latencies")
+	}
+}
+
+func TestHistogramPercentile(t *testing.T) {
+	buckets := []float64{0, 0.001, 0.01, 0.1, math.Inf(1)}
+	testcases := []struct {
+		counts     []uint64
+		percentile float64
+		expect     float64
+	}{
+		{counts: []uint64{0, 0, 0, 0}, percentile: 0.5, expect: 0},
+		{counts: []uint64{50, 49, 1, 0}, percentile: 0.5, expect: 0.001},
+		{counts: []uint64{50, 49, 1, 0}, percentile: 0.99, expect: 0.01},
+		{counts: []uint64{50, 49, 1, 0}, percentile: 1, expect: 0.1},
+		{counts: []uint64{0, 0, 0, 3}, percentile: 0.99, expect: 0.1},
+	}
+	for _, tc := range testcases {
+		if p := histogramPercentile(tc.counts, buckets, tc.percentile); p != tc.expect {
+			t.Error(tc.counts, tc.percentile, p, tc.expect)
+		}
+	}
+}
+
+func TestGetDiagnosticsStats(t *testing.T) {
+	now := time.Now()
+	buckets := []float64{0, 0.001, 0.01, 0.1, math.Inf(1)}
+	stats := getDiagnosticsStats(diagnosticsSamples{
+		Previous: &diagnosticsSample{
+			when:         now,
+			numGoroutine: 10,
+			schedLatencies: &runtimemetrics.Float64Histogram{
+				Counts:  []uint64{100, 0, 0, 5},
+				Buckets: buckets,
+			},
+		},
+		Current: &diagnosticsSample{
+			when:         now.Add(30 * time.Second),
+			numGoroutine: 70,
+			schedLatencies: &runtimemetrics.Float64Histogram{
+				Counts:  []uint64{150, 49, 1, 5},
+				Buckets: buckets,
+			},
+		},
+	})
+	expect := diagnosticsStats{
+		numGoroutine:       70,
+		goroutineGrowth:    120,
+		hasSchedLatency:    true,
+		schedLatencyMedian: time.Millisecond,
+		schedLatencyP99:    10 * time.Millisecond,
+	}
+	if stats != expect {
+		t.Errorf("%+v", stats)
+	}
+}
+
+func TestGetDiagnosticsStatsNoLatencies(t *testing.T) {
+	now := time.Now()
+	hist := &runtimemetrics.Float64Histogram{
+		Counts:  []uint64{100, 0, 0, 5},
+		Buckets: []float64{0, 0.001, 0.01, 0.1, math.Inf(1)},
+	}
+	stats := getDiagnosticsStats(diagnosticsSamples{
+		Previous: &diagnosticsSample{when: now, numGoroutine: 10, schedLatencies: hist},
+		Current:  &diagnosticsSample{when: now.Add(time.Minute), numGoroutine: 5, schedLatencies: hist},
+	})
+	expect := diagnosticsStats{
+		numGoroutine:    5,
+		goroutineGrowth: -5,
+	}
+	if stats != expect {
+		t.Errorf("%+v", stats)
+	}
+}
+
+func TestDiagnosticsMetricsCreated(t *testing.T) {
+	h := newHarvest(time.Now(), testHarvestCfgr)
+	stats := diagnosticsStats{
+		numGoroutine:       70,
+		goroutineGrowth:    120,
+		hasSchedLatency:    true,
+		schedLatencyMedian: time.Millisecond,
+		schedLatencyP99:    10 * time.Millisecond,
+	}
+	stats.MergeIntoHarvest(h)
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: "Go/Runtime/Goroutines/Growth Rate", Scope: "", Forced: true, Data: []float64{1, 120, 120, 120, 120, 14400}},
+		{Name: "Go/Runtime/Scheduler/Latency/50th Percentile", Scope: "", Forced: true, Data: []float64{1, 0.001, 0.001, 0.001, 0.001, 0.000001}},
+		{Name: "Go/Runtime/Scheduler/Latency/99th Percentile", Scope: "", Forced: true, Data: []float64{1, 0.01, 0.01, 0.01, 0.01, 0.0001}},
+	})
+}
+
+func TestRecordDiagnosticsEvents(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.RuntimeSampler.Diagnostics.Enabled = true
+		cfg.RuntimeSampler.Diagnostics.SchedulerLatencyThreshold = 5 * time.Millisecond
+		cfg.RuntimeSampler.Diagnostics.GoroutineGrowthThreshold = 100
+	}, t)
+	a := app.Application.app
+	run, _ := a.getState()
+	a.recordDiagnostics(run.Reply.RunID, diagnosticsStats{
+		numGoroutine:       70,
+		goroutineGrowth:    120,
+		hasSchedLatency:    true,
+		schedLatencyMedian: time.Millisecond,
+		schedLatencyP99:    10 * time.Millisecond,
+	})
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "GoRuntimeDiagnostic",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"diagnostic": "schedulerLatency",
+			"value":      0.01,
+			"threshold":  0.005,
+			"goroutines": 70,
+		},
+	}, {
+		Intrinsics: map[string]interface{}{
+			"type":      "GoRuntimeDiagnostic",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"diagnostic": "goroutineGrowth",
+			"value":      120,
+			"threshold":  100,
+			"goroutines": 70,
+		},
+	}})
+}
+
+func TestRecordDiagnosticsBelowThresholds(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.RuntimeSampler.Diagnostics.Enabled = true
+	}, t)
+	a := app.Application.app
+	run, _ := a.getState()
+	a.recordDiagnostics(run.Reply.RunID, diagnosticsStats{
+		numGoroutine:       70,
+		goroutineGrowth:    120,
+		hasSchedLatency:    true,
+		schedLatencyMedian: time.Millisecond,
+		schedLatencyP99:    2 * time.Millisecond,
+	})
+	app.ExpectCustomEvents(t, []internal.WantEvent{})
+}
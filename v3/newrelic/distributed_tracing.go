@@ -278,6 +278,49 @@ func (p payload) W3CTraceState() string {
 	return state
 }
 
+const (
+	// maxTraceStateEntries is the maximum number of tracestate entries
+	// allowed by the W3C specification.
+	maxTraceStateEntries = 32
+	// maxTraceStateEntrySize is the length above which tracestate entries
+	// are the first to be removed when the header is too large.
+	maxTraceStateEntrySize = 128
+	// defaultMaxTraceStateSize is the length of the tracestate header which
+	// the W3C specification recommends vendors propagate.
+	defaultMaxTraceStateSize = 512
+)
+
+// pruneTraceState removes entries from the tracestate until it contains at
+// most maxTraceStateEntries entries and, if maxSize is positive, until it is
+// no longer than maxSize bytes.  Following the W3C specification, entries
+// longer than maxTraceStateEntrySize are removed first, then the entries at
+// the end of the header, which are the oldest.  The first entry, which is
+// the entry of this agent, is always kept.
+func pruneTraceState(state string, maxSize int) string {
+	entries := strings.Split(state, ",")
+	size := len(state)
+	tooLarge := func() bool {
+		return len(entries) > maxTraceStateEntries || (maxSize > 0 && size > maxSize)
+	}
+	if !tooLarge() {
+		return state
+	}
+	remove := func(i int) {
+		// Each entry after the first is preceded by a comma.
+		size -= len(entries[i]) + 1
+		entries = append(entries[:i], entries[i+1:]...)
+	}
+	for i := len(entries) - 1; i > 0 && tooLarge(); i-- {
+		if len(entries[i]) > maxTraceStateEntrySize {
+			remove(i)
+		}
+	}
+	for len(entries) > 1 && tooLarge() {
+		remove(len(entries) - 1)
+	}
+	return strings.Join(entries, ",")
+}
+
 var (
 	trueVal  = true
 	falseVal = false
@@ -7,6 +7,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -699,3 +701,37 @@ func Test_processTraceState_invalidEntry(t *testing.T) {
 		t.Errorf("expected invalidNRTraceState error but got %v", err)
 	}
 }
+
+func TestPruneTraceState(t *testing.T) {
+	long := "long=" + strings.Repeat("x", 130)
+	testcases := []struct {
+		state   string
+		maxSize int
+		expect  string
+	}{
+		{state: "nr=1,a=1,b=2", maxSize: 0, expect: "nr=1,a=1,b=2"},
+		{state: "nr=1,a=1,b=2", maxSize: 12, expect: "nr=1,a=1,b=2"},
+		{state: "nr=1,a=1,b=2", maxSize: 11, expect: "nr=1,a=1"},
+		{state: "nr=1,a=1,b=2", maxSize: 8, expect: "nr=1,a=1"},
+		{state: "nr=1,a=1,b=2", maxSize: 7, expect: "nr=1"},
+		{state: "nr=1,a=1,b=2", maxSize: 1, expect: "nr=1"},
+		{state: "nr=1," + long + ",a=1,b=2", maxSize: 20, expect: "nr=1,a=1,b=2"},
+		{state: "nr=1," + long + ",a=1,b=2", maxSize: 0, expect: "nr=1," + long + ",a=1,b=2"},
+	}
+	for _, tc := range testcases {
+		if s := pruneTraceState(tc.state, tc.maxSize); s != tc.expect {
+			t.Errorf("state=%s maxSize=%d got=%s expect=%s", tc.state, tc.maxSize, s, tc.expect)
+		}
+	}
+}
+
+func TestPruneTraceStateMaxEntries(t *testing.T) {
+	entries := []string{"nr=1"}
+	for i := 0; i < 40; i++ {
+		entries = append(entries, "v"+strconv.Itoa(i)+"=1")
+	}
+	pruned := strings.Split(pruneTraceState(strings.Join(entries, ","), 0), ",")
+	if !reflect.DeepEqual(pruned, entries[:maxTraceStateEntries]) {
+		t.Error(pruned)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// The types of DroppedData.
+const (
+	droppedTypeCustomEvent = "CustomEvent"
+	droppedTypeLogEvent    = "LogEvent"
+)
+
+// droppedDataLimit is the maximum number of events of each type sampled out
+// of a reservoir which are kept until the harvest for Config.OnDataDropped, so
+// that memory use stays bounded.  Further events are only counted.
+const droppedDataLimit = 1000
+
+// drop keeps an event sampled out of the reservoir for Config.OnDataDropped.
+func (events *analyticsEvents) drop(e analyticsEvent) {
+	if !events.keepDropped {
+		return
+	}
+	if len(events.dropped) < droppedDataLimit {
+		events.dropped = append(events.dropped, e)
+	} else {
+		events.droppedDiscarded++
+	}
+}
+
+// drop keeps a log event sampled out of the reservoir for
+// Config.OnDataDropped.
+func (events *logEvents) drop(e logEvent) {
+	if !events.config.keepDropped {
+		return
+	}
+	if len(events.dropped) < droppedDataLimit {
+		events.dropped = append(events.dropped, e)
+	} else {
+		events.droppedDiscarded++
+	}
+}
+
+// dropForMemoryLimit keeps an event dropped by Config.MemoryLimit for
+// Config.OnDataDropped.
+func (events *analyticsEvents) dropForMemoryLimit(e analyticsEvent) {
+	if !events.keepDropped {
+		return
+	}
+	if len(events.memoryLimitDropped) < droppedDataLimit {
+		events.memoryLimitDropped = append(events.memoryLimitDropped, e)
+	} else {
+		events.droppedDiscarded++
+	}
+}
+
+// dropForMemoryLimit keeps a log event dropped by Config.MemoryLimit for
+// Config.OnDataDropped.
+func (events *logEvents) dropForMemoryLimit(e logEvent) {
+	if !events.config.keepDropped {
+		return
+	}
+	if len(events.memoryLimitDropped) < droppedDataLimit {
+		events.memoryLimitDropped = append(events.memoryLimitDropped, e)
+	} else {
+		events.droppedDiscarded++
+	}
+}
+
+func customEventsJSON(events []analyticsEvent) []json.RawMessage {
+	js := make([]json.RawMessage, 0, len(events))
+	for _, e := range events {
+		buf := &bytes.Buffer{}
+		if ce, ok := e.jsonWriter.(*customEvent); ok {
+			ce.WriteEventAPIJSON(buf)
+		} else {
+			e.WriteJSON(buf)
+		}
+		js = append(js, buf.Bytes())
+	}
+	return js
+}
+
+func logEventsJSON(events []logEvent) []json.RawMessage {
+	js := make([]json.RawMessage, 0, len(events))
+	for _, e := range events {
+		buf := &bytes.Buffer{}
+		e.WriteJSON(buf)
+		js = append(js, buf.Bytes())
+	}
+	return js
+}
+
+// dataDropped calls Config.OnDataDropped, if set, with the batch of events.
+func (app *app) dataDropped(tp string, reason string, events []json.RawMessage) {
+	if nil == app.config.OnDataDropped || 0 == len(events) {
+		return
+	}
+	app.config.OnDataDropped(DroppedData{
+		Type:   tp,
+		Reason: reason,
+		Events: events,
+	})
+}
+
+// reservoirDataDropped reports the events sampled out of the reservoirs of
+// the harvest, and those dropped by Config.MemoryLimit.
+func (app *app) reservoirDataDropped(h *harvest) {
+	if nil != h.CustomEvents {
+		app.dataDropped(droppedTypeCustomEvent, DroppedReasonReservoirFull, customEventsJSON(h.CustomEvents.dropped))
+		app.dataDropped(droppedTypeCustomEvent, DroppedReasonMemoryLimit, customEventsJSON(h.CustomEvents.memoryLimitDropped))
+		h.CustomEvents.dropped = nil
+		h.CustomEvents.memoryLimitDropped = nil
+	}
+	if nil != h.LogEvents {
+		app.dataDropped(droppedTypeLogEvent, DroppedReasonReservoirFull, logEventsJSON(h.LogEvents.dropped))
+		app.dataDropped(droppedTypeLogEvent, DroppedReasonMemoryLimit, logEventsJSON(h.LogEvents.memoryLimitDropped))
+		h.LogEvents.dropped = nil
+		h.LogEvents.memoryLimitDropped = nil
+	}
+}
+
+// harvestDataDropped reports the events of a payload which could not be sent.
+func (app *app) harvestDataDropped(p payloadCreator) {
+	switch events := p.(type) {
+	case *customEvents:
+		app.dataDropped(droppedTypeCustomEvent, DroppedReasonHarvestFailure, customEventsJSON(events.events))
+	case *logEvents:
+		app.dataDropped(droppedTypeLogEvent, DroppedReasonHarvestFailure, logEventsJSON(events.logs))
+	}
+}
+
+// retriesExhausted returns whether the events of a payload would be
+// discarded rather than merged into the next harvest, see
+// failedEventsAttemptsLimit.
+func retriesExhausted(p payloadCreator) bool {
+	switch events := p.(type) {
+	case *customEvents:
+		return events.failedHarvests+1 >= failedEventsAttemptsLimit
+	case *logEvents:
+		return events.failedHarvests+1 >= failedEventsAttemptsLimit
+	}
+	return false
+}
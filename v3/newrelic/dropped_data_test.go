@@ -0,0 +1,180 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+type droppedDataRecorder struct {
+	batches []DroppedData
+}
+
+func (r *droppedDataRecorder) config(cfg *Config) {
+	cfg.OnDataDropped = func(d DroppedData) {
+		r.batches = append(r.batches, d)
+	}
+}
+
+// reservoirsOfOne limits the custom event and log event reservoirs to a
+// single event.
+func reservoirsOfOne(reply *internal.ConnectReply) {
+	reply.EventData = internal.DefaultEventHarvestConfig(internal.MaxTxnEvents, 1, 1)
+}
+
+func TestOnDataDroppedCustomEventReservoirFull(t *testing.T) {
+	r := &droppedDataRecorder{}
+	app := testApp(reservoirsOfOne, r.config, t)
+	app.RecordCustomEvent("myType", map[string]interface{}{"zip": 1})
+	app.RecordCustomEvent("myType", map[string]interface{}{"zip": 2})
+	app.app.reservoirDataDropped(app.app.testHarvest)
+
+	if len(r.batches) != 1 {
+		t.Fatal(r.batches)
+	}
+	d := r.batches[0]
+	if d.Type != "CustomEvent" || d.Reason != DroppedReasonReservoirFull || len(d.Events) != 1 {
+		t.Fatal(d)
+	}
+	var event map[string]interface{}
+	if err := json.Unmarshal(d.Events[0], &event); nil != err {
+		t.Fatal(err, string(d.Events[0]))
+	}
+	if event["eventType"] != "myType" || event["zip"] == nil {
+		t.Error(event)
+	}
+	// The dropped events are only reported once.
+	app.app.reservoirDataDropped(app.app.testHarvest)
+	if len(r.batches) != 1 {
+		t.Error(r.batches)
+	}
+}
+
+func TestOnDataDroppedMemoryLimit(t *testing.T) {
+	r := &droppedDataRecorder{}
+	app := testApp(nil, func(cfg *Config) {
+		r.config(cfg)
+		cfg.MemoryLimit = 1
+	}, t)
+	app.RecordCustomEvent("myType", map[string]interface{}{"zip": 1})
+	app.app.reservoirDataDropped(app.app.testHarvest)
+
+	if len(r.batches) != 1 {
+		t.Fatal(r.batches)
+	}
+	d := r.batches[0]
+	if d.Type != "CustomEvent" || d.Reason != DroppedReasonMemoryLimit || len(d.Events) != 1 {
+		t.Fatal(d)
+	}
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: supportMemoryLimitCustomEvents, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestOnDataDroppedLogReservoirFull(t *testing.T) {
+	r := &droppedDataRecorder{}
+	app := testApp(reservoirsOfOne, func(cfg *Config) {
+		r.config(cfg)
+		cfg.ApplicationLogging.Enabled = true
+		cfg.ApplicationLogging.Forwarding.Enabled = true
+		cfg.ApplicationLogging.Forwarding.MaxSamplesStored = 1
+	}, t)
+	txn := app.StartTransaction("hello")
+	txn.RecordLog(LogData{Severity: "INFO", Message: "first"})
+	txn.RecordLog(LogData{Severity: "INFO", Message: "second"})
+	txn.End()
+	app.RecordLog(LogData{Severity: "INFO", Message: "third"})
+	app.app.reservoirDataDropped(app.app.testHarvest)
+
+	if len(r.batches) != 1 {
+		t.Fatal(r.batches)
+	}
+	d := r.batches[0]
+	if d.Type != "LogEvent" || d.Reason != DroppedReasonReservoirFull || len(d.Events) != 2 {
+		t.Fatal(d)
+	}
+	for _, js := range d.Events {
+		var event map[string]interface{}
+		if err := json.Unmarshal(js, &event); nil != err {
+			t.Fatal(err, string(js))
+		}
+		if event["level"] != "INFO" || event["message"] == nil {
+			t.Error(event)
+		}
+	}
+}
+
+func TestOnDataDroppedLimit(t *testing.T) {
+	r := &droppedDataRecorder{}
+	app := testApp(reservoirsOfOne, r.config, t)
+	for i := 0; i < droppedDataLimit+6; i++ {
+		app.RecordCustomEvent("myType", map[string]interface{}{"zip": i})
+	}
+	h := app.app.testHarvest
+	if n := len(h.CustomEvents.dropped); n != droppedDataLimit {
+		t.Error(n)
+	}
+	if n := h.CustomEvents.droppedDiscarded; n != 5 {
+		t.Error(n)
+	}
+
+	ready := h.Ready(app.app.config.Clock.Now().Add(time.Hour))
+	app.app.reservoirDataDropped(ready)
+	if len(r.batches) != 1 || len(r.batches[0].Events) != droppedDataLimit {
+		t.Error(len(r.batches))
+	}
+	expectMetricsPresent(t, ready.Metrics, []internal.WantMetric{
+		{Name: "Supportability/Go/OnDataDropped/Discarded/CustomEvent", Scope: "", Forced: true, Data: []float64{5, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestOnDataDroppedUnset(t *testing.T) {
+	app := testApp(reservoirsOfOne, nil, t)
+	app.RecordCustomEvent("myType", map[string]interface{}{"zip": 1})
+	app.RecordCustomEvent("myType", map[string]interface{}{"zip": 2})
+	if dropped := app.app.testHarvest.CustomEvents.dropped; nil != dropped {
+		t.Error(dropped)
+	}
+}
+
+func TestOnDataDroppedHarvestFailure(t *testing.T) {
+	r := &droppedDataRecorder{}
+	app := testApp(nil, r.config, t)
+	run, _ := app.app.getState()
+	app.app.rpmControls.Client = &http.Client{Transport: harvestFailureTransport{}}
+	now := app.app.config.Clock.Now()
+	h := newHarvest(now, run.harvestConfig)
+	event, err := createCustomEvent("myType", map[string]interface{}{"zip": 1}, now, defaultAttributeLimits)
+	if nil != err {
+		t.Fatal(err)
+	}
+	h.CustomEvents.Add(event)
+	h.CustomEvents.failedHarvests = failedEventsAttemptsLimit - 2
+	h.Metrics = nil
+
+	// The events are retained until the attempts limit is reached.
+	app.app.doHarvest(h, now, run)
+	if len(r.batches) != 0 {
+		t.Fatal(r.batches)
+	}
+	h.CustomEvents = app.app.testHarvest.CustomEvents
+	app.app.testHarvest.CustomEvents = newCustomEvents(h.CustomEvents.capacity())
+	app.app.doHarvest(h, now, run)
+
+	if len(r.batches) != 1 {
+		t.Fatal(r.batches)
+	}
+	d := r.batches[0]
+	if d.Type != "CustomEvent" || d.Reason != DroppedReasonHarvestFailure || len(d.Events) != 1 {
+		t.Error(d)
+	}
+	if n := len(app.app.testHarvest.CustomEvents.events); n != 0 {
+		t.Error("dropped events were retained", n)
+	}
+}
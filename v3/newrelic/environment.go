@@ -6,20 +6,49 @@ package newrelic
 import (
 	"encoding/json"
 	"fmt"
+	"os/user"
 	"reflect"
+	"regexp"
 	"runtime"
 	"runtime/debug"
+	"sort"
 	"strings"
+
+	"github.com/rainforestpay/go-agent/v3/internal/sysinfo"
 )
 
-// environment describes the application's environment.
+// environment describes the application's environment.  Fields tagged
+// omitempty are not reported when empty.
 type environment struct {
-	NumCPU   int      `env:"runtime.NumCPU"`
-	Compiler string   `env:"runtime.Compiler"`
-	GOARCH   string   `env:"runtime.GOARCH"`
-	GOOS     string   `env:"runtime.GOOS"`
-	Version  string   `env:"runtime.Version"`
-	Modules  []string `env:"Modules"`
+	NumCPU         int      `env:"runtime.NumCPU"`
+	Compiler       string   `env:"runtime.Compiler"`
+	GOARCH         string   `env:"runtime.GOARCH"`
+	GOOS           string   `env:"runtime.GOOS"`
+	Version        string   `env:"runtime.Version"`
+	Modules        []string `env:"Modules"`
+	ProcessOwner   string   `env:"Process.Owner,omitempty"`
+	CommandLine    string   `env:"Process.CommandLine,omitempty"`
+	WindowsService string   `env:"Windows.ServiceName,omitempty"`
+	IISAppPool     string   `env:"IIS.AppPoolID,omitempty"`
+	// Variables are reported using their names, after the other fields.
+	Variables []environmentVar `env:"-"`
+}
+
+// environmentVar is an environment variable included by
+// Config.Environment.IncludeVars.
+type environmentVar struct {
+	name  string
+	value string
+}
+
+// processMetadata holds the Config.ProcessMetadata details.  It is gathered
+// in NewApplication, since the environment variables and command line may
+// change after startup.
+type processMetadata struct {
+	owner          string
+	commandLine    string
+	windowsService string
+	iisAppPool     string
 }
 
 var (
@@ -35,7 +64,7 @@ var (
 
 // newEnvironment returns a new Environment.
 func newEnvironment(c *config) environment {
-	return environment{
+	env := environment{
 		Compiler: runtime.Compiler,
 		GOARCH:   runtime.GOARCH,
 		GOOS:     runtime.GOOS,
@@ -43,6 +72,111 @@ func newEnvironment(c *config) environment {
 		NumCPU:   runtime.NumCPU(),
 		Modules:  getDependencyModuleList(c),
 	}
+	if nil != c {
+		env.ProcessOwner = c.process.owner
+		env.CommandLine = c.process.commandLine
+		env.WindowsService = c.process.windowsService
+		env.IISAppPool = c.process.iisAppPool
+		env.Variables = c.environmentVars
+	}
+	return env
+}
+
+func gatherProcessMetadata(c Config, s *scrubber, getenv func(string) string, args []string) processMetadata {
+	var p processMetadata
+	if !c.ProcessMetadata.Enabled {
+		return p
+	}
+	if u, err := user.Current(); nil == err {
+		p.owner = u.Username
+	}
+	if c.ProcessMetadata.CommandLine && !c.HighSecurity {
+		p.commandLine = s.scrub(redactCommandLine(args))
+	}
+	p.windowsService, _ = sysinfo.ServiceName()
+	p.iisAppPool = getenv("APP_POOL_ID")
+	return p
+}
+
+// gatherEnvironmentVars returns the variables of the environ included by
+// Environment.IncludeVars, sorted by name.  The values of variables whose
+// names look like secrets are redacted, as are the portions of values matching
+// Environment.RedactPatterns, which must have been validated.  No variables
+// are returned when HighSecurity is enabled.
+func gatherEnvironmentVars(c Config, environ []string) []environmentVar {
+	include := c.Environment.IncludeVars
+	if 0 == len(include) || c.HighSecurity {
+		return nil
+	}
+	var patterns []*regexp.Regexp
+	for _, pattern := range c.Environment.RedactPatterns {
+		patterns = append(patterns, regexp.MustCompile(pattern))
+	}
+	var vars []environmentVar
+	for _, pair := range environ {
+		idx := strings.Index(pair, "=")
+		if idx <= 0 || !allowListed(include, pair[0:idx]) {
+			continue
+		}
+		v := environmentVar{name: pair[0:idx], value: pair[idx+1:]}
+		if isSecretKey(v.name) {
+			v.value = redactedAttributeValue
+		}
+		for _, re := range patterns {
+			v.value = re.ReplaceAllString(v.value, redactedAttributeValue)
+		}
+		vars = append(vars, v)
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].name < vars[j].name })
+	return vars
+}
+
+// redactCommandLine joins the arguments, redacting the values of those whose
+// names look like secrets: "--password=x", "-token x" and "/apikey:x".
+func redactCommandLine(args []string) string {
+	redacted := make([]string, len(args))
+	secretValue := false
+	for i, arg := range args {
+		if secretValue && !strings.HasPrefix(arg, "-") {
+			redacted[i] = redactedAttributeValue
+			secretValue = false
+			continue
+		}
+		secretValue = false
+		name, hasValue := commandLineFlag(arg)
+		if "" != name && isSecretKey(name) {
+			if hasValue {
+				arg = arg[:strings.IndexAny(arg, "=:")+1] + redactedAttributeValue
+			} else {
+				secretValue = true
+			}
+		}
+		redacted[i] = arg
+	}
+	return strings.Join(redacted, " ")
+}
+
+// commandLineFlag returns the name of the flag given by the argument, if any,
+// and whether the argument includes the flag's value.
+func commandLineFlag(arg string) (string, bool) {
+	var name string
+	switch {
+	case strings.HasPrefix(arg, "-"):
+		name = strings.TrimLeft(arg, "-")
+	case strings.HasPrefix(arg, "/"):
+		name = arg[1:]
+	default:
+		return "", false
+	}
+	idx := strings.IndexAny(name, "=:")
+	if idx >= 0 {
+		name = name[:idx]
+	}
+	// Paths are not flags.
+	if strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+	return name, idx >= 0
 }
 
 // indended for testing purposes. This just returns the formatted
@@ -52,8 +186,14 @@ func injectDependencyModuleList(c *config, modules []*debug.Module) []string {
 
 	if c != nil && c.ModuleDependencyMetrics.Enabled {
 		for _, module := range modules {
-			if module != nil && includeModule(module.Path, c.ModuleDependencyMetrics.IgnoredPrefixes) {
-				modList = append(modList, fmt.Sprintf("%s(%s)", module.Path, module.Version))
+			if module == nil {
+				continue
+			}
+			// The prefixes are matched against the path that is
+			// reported, which is the replacement's path for a
+			// replaced module.
+			if path, _ := reportedModule(module); includeModule(path, c.ModuleDependencyMetrics.IgnoredPrefixes) {
+				modList = append(modList, formatModule(module))
 			}
 		}
 	}
@@ -61,19 +201,33 @@ func injectDependencyModuleList(c *config, modules []*debug.Module) []string {
 }
 
 func getDependencyModuleList(c *config) []string {
-	var modList []string
-
 	if c != nil && c.ModuleDependencyMetrics.Enabled {
 		info, ok := debug.ReadBuildInfo()
 		if info != nil && ok {
-			for _, module := range info.Deps {
-				if module != nil && includeModule(module.Path, c.ModuleDependencyMetrics.IgnoredPrefixes) {
-					modList = append(modList, fmt.Sprintf("%s(%s)", module.Path, module.Version))
-				}
-			}
+			return injectDependencyModuleList(c, info.Deps)
 		}
 	}
-	return modList
+	return nil
+}
+
+// reportedModule returns the path and version of the module's code compiled
+// into the application.  A module swapped by a replace directive reports its
+// replacement, or "devel" when it is replaced by a local directory.
+func reportedModule(module *debug.Module) (path string, version string) {
+	if r := module.Replace; r != nil {
+		if r.Version == "" {
+			return module.Path, "devel"
+		}
+		return r.Path, r.Version
+	}
+	return module.Path, module.Version
+}
+
+// formatModule returns the reported path and version of the module, eg.
+// "example.com/mod(v1.2.3)".
+func formatModule(module *debug.Module) string {
+	path, version := reportedModule(module)
+	return fmt.Sprintf("%s(%s)", path, version)
 }
 
 func includeModule(name string, ignoredModulePrefixes []string) bool {
@@ -94,16 +248,28 @@ func (e environment) MarshalJSON() ([]byte, error) {
 	val := reflect.ValueOf(e)
 	numFields := val.NumField()
 
-	arr = make([][]interface{}, numFields)
+	arr = make([][]interface{}, 0, numFields)
 
 	for i := 0; i < numFields; i++ {
 		v := val.Field(i)
 		t := val.Type().Field(i).Tag.Get("env")
 
-		arr[i] = []interface{}{
+		if t == "-" {
+			continue
+		}
+		if name := strings.TrimSuffix(t, ",omitempty"); name != t {
+			if v.IsZero() {
+				continue
+			}
+			t = name
+		}
+		arr = append(arr, []interface{}{
 			t,
 			v.Interface(),
-		}
+		})
+	}
+	for _, v := range e.Variables {
+		arr = append(arr, []interface{}{v.name, v.value})
 	}
 
 	return json.Marshal(arr)
@@ -27,13 +27,21 @@ func (e *errorEvent) WriteJSON(buf *bytes.Buffer) {
 	w.stringField("error.class", e.Klass)
 	w.stringField("error.message", e.Msg)
 	w.intField("timestamp", timeToIntMillis(e.When))
-	w.stringField("transactionName", e.FinalName)
-	if e.SpanID != "" {
-		w.stringField("spanId", e.SpanID)
+	addOptionalStringField(&w, errorSeverityAttr, string(e.Severity))
+	if e.Expect {
+		w.stringField(expectErrorAttr, "true")
 	}
+	// Errors recorded outside of a transaction have no transaction name and
+	// no transaction intrinsics.
+	if "" != e.FinalName {
+		w.stringField("transactionName", e.FinalName)
+		if e.SpanID != "" {
+			w.stringField("spanId", e.SpanID)
+		}
 
-	sharedTransactionIntrinsics(&e.txnEvent, &w)
-	sharedBetterCATIntrinsics(&e.txnEvent, &w)
+		sharedTransactionIntrinsics(&e.txnEvent, &w)
+		sharedBetterCATIntrinsics(&e.txnEvent, &w)
+	}
 
 	buf.WriteByte('}')
 	buf.WriteByte(',')
@@ -43,18 +51,47 @@ func (e *errorEvent) WriteJSON(buf *bytes.Buffer) {
 	buf.WriteByte(']')
 }
 
+// appErrorEvent is an error event recorded using Application.NoticeError.
+type appErrorEvent struct {
+	errorEvent
+	priority priority
+}
+
+// MergeIntoHarvest implements Harvestable.
+func (e *appErrorEvent) MergeIntoHarvest(h *harvest) {
+	h.ErrorEvents.Add(&e.errorEvent, e.priority)
+}
+
 type errorEvents struct {
 	*analyticsEvents
+	// perClassLimit is the maximum number of events of each error class
+	// added, or zero for no limit.  It keeps errors of a single class from
+	// evicting all others from the reservoir.
+	perClassLimit int
+	classCounts   map[string]int
+	classLimited  int
 }
 
-func newErrorEvents(max int) *errorEvents {
+func newErrorEvents(max int, perClassLimit int) *errorEvents {
 	return &errorEvents{
 		analyticsEvents: newAnalyticsEvents(max),
+		perClassLimit:   perClassLimit,
 	}
 }
 
 func (events *errorEvents) Add(e *errorEvent, p priority) {
-	events.addEvent(analyticsEvent{p, e})
+	if events.perClassLimit > 0 {
+		if nil == events.classCounts {
+			events.classCounts = make(map[string]int)
+		}
+		if events.classCounts[e.Klass] >= events.perClassLimit {
+			events.numSeen++
+			events.classLimited++
+			return
+		}
+		events.classCounts[e.Klass]++
+	}
+	events.addEvent(analyticsEvent{priority: p, rank: e.Severity.rank(), jsonWriter: e})
 }
 
 func (events *errorEvents) MergeIntoHarvest(h *harvest) {
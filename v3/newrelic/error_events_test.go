@@ -230,3 +230,65 @@ func TestErrorEventMarshalWithInboundCaller(t *testing.T) {
 		{}
 	]`)
 }
+
+func TestErrorEventMarshalWithoutTransaction(t *testing.T) {
+	testErrorEventJSON(t, &errorEvent{
+		errorData: sampleErrorData,
+	}, `[
+		{
+			"type":"TransactionError",
+			"error.class":"*errors.errorString",
+			"error.message":"hello",
+			"timestamp":1417136460000
+		},
+		{},
+		{}
+	]`)
+}
+
+func TestErrorEventsPerClassLimit(t *testing.T) {
+	events := newErrorEvents(10, 2)
+	for _, class := range []string{"A", "A", "B", "A", "A", "C"} {
+		events.Add(&errorEvent{errorData: errorData{Klass: class}}, 0)
+	}
+	if n := events.NumSaved(); n != 4 {
+		t.Error(n)
+	}
+	if n := events.NumSeen(); n != 6 {
+		t.Error(n)
+	}
+	if events.classLimited != 2 {
+		t.Error(events.classLimited)
+	}
+}
+
+func TestErrorEventsNoPerClassLimit(t *testing.T) {
+	events := newErrorEvents(10, 0)
+	for i := 0; i < 5; i++ {
+		events.Add(&errorEvent{errorData: errorData{Klass: "A"}}, 0)
+	}
+	if n := events.NumSaved(); n != 5 {
+		t.Error(n)
+	}
+	if events.classLimited != 0 {
+		t.Error(events.classLimited)
+	}
+}
+
+func TestErrorEventsSeverityEviction(t *testing.T) {
+	events := newErrorEvents(2, 0)
+	events.Add(&errorEvent{errorData: errorData{Klass: "critical", Severity: ErrorSeverityCritical}}, 0.1)
+	events.Add(&errorEvent{errorData: errorData{Klass: "warning", Severity: ErrorSeverityWarning}}, 0.9)
+	events.Add(&errorEvent{errorData: errorData{Klass: "error"}}, 0.2)
+	events.Add(&errorEvent{errorData: errorData{Klass: "warning", Severity: ErrorSeverityWarning}}, 0.99)
+	if n := events.NumSaved(); n != 2 {
+		t.Fatal(n)
+	}
+	classes := map[string]bool{}
+	for _, e := range events.events {
+		classes[e.jsonWriter.(*errorEvent).Klass] = true
+	}
+	if !classes["critical"] || !classes["error"] {
+		t.Error(classes)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// errorIgnorer decides whether noticed errors are ignored, see
+// Config.ErrorCollector.IgnoreClasses and IgnoreMessages.  A nil errorIgnorer
+// ignores no errors.
+type errorIgnorer struct {
+	classes  map[string]struct{}
+	messages map[string][]*regexp.Regexp
+}
+
+func validateIgnoreMessages(messages map[string][]string) error {
+	for class, patterns := range messages {
+		for _, pattern := range patterns {
+			if _, err := regexp.Compile(pattern); nil != err {
+				return fmt.Errorf("invalid ErrorCollector.IgnoreMessages pattern for class %q: %v", class, err)
+			}
+		}
+	}
+	return nil
+}
+
+// newErrorIgnorer compiles the ignore settings, whose patterns must have been
+// validated using validateIgnoreMessages.  It returns nil if no errors are
+// ignored.
+func newErrorIgnorer(classes []string, messages map[string][]string) *errorIgnorer {
+	if 0 == len(classes) && 0 == len(messages) {
+		return nil
+	}
+	ig := &errorIgnorer{
+		classes:  make(map[string]struct{}, len(classes)),
+		messages: make(map[string][]*regexp.Regexp, len(messages)),
+	}
+	for _, class := range classes {
+		ig.classes[class] = struct{}{}
+	}
+	for class, patterns := range messages {
+		for _, pattern := range patterns {
+			ig.messages[class] = append(ig.messages[class], regexp.MustCompile(pattern))
+		}
+	}
+	return ig
+}
+
+// ignored returns true if errors of the class with the message are ignored.
+func (ig *errorIgnorer) ignored(class, msg string) bool {
+	if nil == ig {
+		return false
+	}
+	if _, ok := ig.classes[class]; ok {
+		return true
+	}
+	for _, re := range ig.messages[class] {
+		if re.MatchString(msg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestErrorIgnorer(t *testing.T) {
+	var nilIgnorer *errorIgnorer
+	if nilIgnorer.ignored("panic", "oops") {
+		t.Error("nil ignorer ignored error")
+	}
+	if ig := newErrorIgnorer(nil, nil); nil != ig {
+		t.Error(ig)
+	}
+
+	ig := newErrorIgnorer([]string{"*net.OpError"}, map[string][]string{
+		"*errors.errorString": {"^context canceled$", "shutting down"},
+	})
+	testcases := []struct {
+		class, msg string
+		ignored    bool
+	}{
+		{class: "*net.OpError", msg: "dial tcp: timeout", ignored: true},
+		{class: "*errors.errorString", msg: "context canceled", ignored: true},
+		{class: "*errors.errorString", msg: "server shutting down now", ignored: true},
+		{class: "*errors.errorString", msg: "context canceled by user", ignored: false},
+		{class: "*errors.errorString", msg: "oops", ignored: false},
+		{class: "panic", msg: "context canceled", ignored: false},
+	}
+	for _, tc := range testcases {
+		if ig.ignored(tc.class, tc.msg) != tc.ignored {
+			t.Errorf("class=%q msg=%q ignored=%t", tc.class, tc.msg, !tc.ignored)
+		}
+	}
+}
+
+func TestValidateIgnoreMessages(t *testing.T) {
+	if err := validateIgnoreMessages(map[string][]string{"a": {"^ok$"}}); nil != err {
+		t.Error(err)
+	}
+	if err := validateIgnoreMessages(map[string][]string{"a": {"("}}); nil == err {
+		t.Error("invalid pattern accepted")
+	}
+}
+
+func ignoreErrorsCfgFn(cfg *Config) {
+	enableRecordPanics(cfg)
+	cfg.DistributedTracer.Enabled = false
+	cfg.ErrorCollector.IgnoreClasses = []string{panicErrorKlass}
+	cfg.ErrorCollector.IgnoreMessages = map[string][]string{
+		"*errors.errorString": {"^context canceled$"},
+	}
+}
+
+func TestNoticeErrorIgnored(t *testing.T) {
+	app := testApp(nil, ignoreErrorsCfgFn, t)
+	txn := app.StartTransaction("hello")
+	txn.NoticeError(context.Canceled)
+	txn.End()
+
+	app.expectNoLoggedErrors(t)
+	app.ExpectErrors(t, []internal.WantError{})
+	app.ExpectErrorEvents(t, []internal.WantEvent{})
+	app.ExpectMetrics(t, backgroundMetrics)
+}
+
+func TestNoticeErrorNotIgnored(t *testing.T) {
+	app := testApp(nil, ignoreErrorsCfgFn, t)
+	txn := app.StartTransaction("hello")
+	txn.NoticeError(errors.New("context canceled early"))
+	txn.End()
+
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName: "OtherTransaction/Go/hello",
+		Msg:     "context canceled early",
+		Klass:   "*errors.errorString",
+	}})
+	app.ExpectMetrics(t, backgroundErrorMetrics)
+}
+
+func TestPanicIgnored(t *testing.T) {
+	app := testApp(nil, ignoreErrorsCfgFn, t)
+	txn := app.StartTransaction("hello")
+	deferEndPanic(txn, myError{})
+
+	app.ExpectErrors(t, []internal.WantError{})
+	app.ExpectMetrics(t, backgroundMetrics)
+}
+
+func TestApplicationNoticeErrorIgnored(t *testing.T) {
+	app := testApp(nil, ignoreErrorsCfgFn, t)
+	app.Application.NoticeError(context.Canceled)
+
+	app.expectNoLoggedErrors(t)
+	app.ExpectErrorEvents(t, []internal.WantEvent{})
+}
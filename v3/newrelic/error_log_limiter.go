@@ -0,0 +1,114 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// errorLogLimiterMaxKeys limits the number of distinct errors tracked by an
+// errorLogLimiter.  Further errors are counted together under
+// errorLogOverflowKey until the tracked errors are flushed.
+const errorLogLimiterMaxKeys = 100
+
+// errorLogOverflowKey identifies the errors beyond errorLogLimiterMaxKeys.
+var errorLogOverflowKey = errorLogKey{msg: "other errors"}
+
+// errorLogLimiter is a Logger which limits how often the same error is logged,
+// see Config.ErrorLogLimit.  Errors are identified by their message and
+// reason.  Messages logged at other levels are not limited.
+type errorLogLimiter struct {
+	Logger
+	clock          Clock
+	maxPerInterval int
+	interval       time.Duration
+
+	sync.Mutex
+	errors map[errorLogKey]*errorLogCount
+}
+
+type errorLogKey struct {
+	msg    string
+	reason string
+}
+
+type errorLogCount struct {
+	start      time.Time
+	logged     int
+	suppressed int
+}
+
+func newErrorLogLimiter(lg Logger, clock Clock, maxPerInterval int, interval time.Duration) *errorLogLimiter {
+	return &errorLogLimiter{
+		Logger:         lg,
+		clock:          clock,
+		maxPerInterval: maxPerInterval,
+		interval:       interval,
+		errors:         make(map[errorLogKey]*errorLogCount),
+	}
+}
+
+// Error implements Logger.
+func (l *errorLogLimiter) Error(msg string, context map[string]interface{}) {
+	key := errorLogKey{msg: msg}
+	if reason, ok := context["reason"]; ok {
+		key.reason = fmt.Sprint(reason)
+	}
+	now := l.clock.Now()
+
+	l.Lock()
+	count, ok := l.errors[key]
+	if !ok && len(l.errors) >= errorLogLimiterMaxKeys {
+		key = errorLogOverflowKey
+		count, ok = l.errors[key]
+	}
+	if ok && now.Sub(count.start) >= l.interval {
+		l.summarize(key, count)
+		ok = false
+	}
+	if !ok {
+		count = &errorLogCount{start: now}
+		l.errors[key] = count
+	}
+	log := count.logged < l.maxPerInterval
+	if log {
+		count.logged++
+	} else {
+		count.suppressed++
+	}
+	l.Unlock()
+
+	if log {
+		l.Logger.Error(msg, context)
+	}
+}
+
+// summarize logs the number of errors suppressed during the interval, if any.
+// It must be called with the lock held.
+func (l *errorLogLimiter) summarize(key errorLogKey, count *errorLogCount) {
+	if 0 == count.suppressed {
+		return
+	}
+	l.Logger.Warn("repeated errors suppressed", map[string]interface{}{
+		"message":    key.msg,
+		"reason":     key.reason,
+		"suppressed": count.suppressed,
+		"interval":   l.interval.String(),
+	})
+}
+
+// flush summarizes and forgets the errors whose interval has ended.
+func (l *errorLogLimiter) flush(now time.Time) {
+	l.Lock()
+	defer l.Unlock()
+
+	for key, count := range l.errors {
+		if now.Sub(count.start) >= l.interval {
+			l.summarize(key, count)
+			delete(l.errors, key)
+		}
+	}
+}
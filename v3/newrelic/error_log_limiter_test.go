@@ -0,0 +1,120 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+)
+
+type limiterTestClock struct {
+	systemClock
+	now time.Time
+}
+
+func (c *limiterTestClock) Now() time.Time { return c.now }
+
+type limiterTestLogger struct {
+	errorSaverLogger
+	warnings []recordedLogMessage
+}
+
+func (lg *limiterTestLogger) Warn(msg string, context map[string]interface{}) {
+	lg.warnings = append(lg.warnings, recordedLogMessage{msg: msg, context: context})
+}
+
+func TestErrorLogLimiter(t *testing.T) {
+	clock := &limiterTestClock{now: time.Now()}
+	lg := &limiterTestLogger{}
+	lim := newErrorLogLimiter(lg, clock, 2, time.Minute)
+	reason := map[string]interface{}{"reason": "already ended"}
+
+	for i := 0; i < 5; i++ {
+		lim.Error("unable to end segment", reason)
+	}
+	lim.Error("unable to end segment", map[string]interface{}{"reason": "other"})
+	lim.Error("unable to add attribute", reason)
+	if len(lg.errors) != 4 {
+		t.Fatal(len(lg.errors))
+	}
+	lim.flush(clock.now.Add(time.Second))
+	if len(lg.warnings) != 0 {
+		t.Fatal(lg.warnings)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	lim.Error("unable to end segment", reason)
+	if len(lg.errors) != 5 {
+		t.Fatal(len(lg.errors))
+	}
+	if len(lg.warnings) != 1 {
+		t.Fatal(lg.warnings)
+	}
+	if w := lg.warnings[0]; w.msg != "repeated errors suppressed" ||
+		w.context["message"] != "unable to end segment" ||
+		w.context["reason"] != "already ended" ||
+		w.context["suppressed"] != 3 {
+		t.Error(w)
+	}
+}
+
+func TestErrorLogLimiterFlush(t *testing.T) {
+	clock := &limiterTestClock{now: time.Now()}
+	lg := &limiterTestLogger{}
+	lim := newErrorLogLimiter(lg, clock, 1, time.Minute)
+
+	lim.Error("unable to end segment", nil)
+	lim.Error("unable to end segment", nil)
+	lim.flush(clock.now.Add(time.Minute))
+	if len(lg.warnings) != 1 || lg.warnings[0].context["suppressed"] != 1 {
+		t.Fatal(lg.warnings)
+	}
+	lim.flush(clock.now.Add(2 * time.Minute))
+	if len(lg.warnings) != 1 {
+		t.Error(lg.warnings)
+	}
+	lim.Error("unable to end segment", nil)
+	if len(lg.errors) != 2 {
+		t.Error(len(lg.errors))
+	}
+}
+
+func TestErrorLogLimiterMaxKeys(t *testing.T) {
+	clock := &limiterTestClock{now: time.Now()}
+	lg := &limiterTestLogger{}
+	lim := newErrorLogLimiter(lg, clock, 1, time.Minute)
+
+	for i := 0; i < errorLogLimiterMaxKeys+5; i++ {
+		lim.Error("unable to end segment", map[string]interface{}{"reason": i})
+	}
+	if len(lim.errors) != errorLogLimiterMaxKeys+1 {
+		t.Fatal(len(lim.errors))
+	}
+	// The first error beyond the limit is logged, and the rest are
+	// suppressed.
+	if len(lg.errors) != errorLogLimiterMaxKeys+1 {
+		t.Fatal(len(lg.errors))
+	}
+	lim.flush(clock.now.Add(time.Minute))
+	if len(lg.warnings) != 1 {
+		t.Fatal(lg.warnings)
+	}
+	if w := lg.warnings[0]; w.context["message"] != errorLogOverflowKey.msg ||
+		w.context["suppressed"] != 4 {
+		t.Error(w)
+	}
+	if len(lim.errors) != 0 {
+		t.Error(len(lim.errors))
+	}
+}
+
+func TestErrorLogLimiterSetting(t *testing.T) {
+	lim := newErrorLogLimiter(&limiterTestLogger{}, systemClock{}, 2, time.Minute)
+	if s := loggerSetting(lim); s != "*newrelic.limiterTestLogger" {
+		t.Error(s)
+	}
+	if s := loggerSetting(newErrorLogLimiter(nil, systemClock{}, 2, time.Minute)); s != nil {
+		t.Error(s)
+	}
+}
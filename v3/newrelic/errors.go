@@ -3,6 +3,8 @@
 
 package newrelic
 
+import "time"
+
 // stackTracer can be implemented by errors to provide a stack trace when using
 // Transaction.NoticeError.
 type stackTracer interface {
@@ -57,3 +59,61 @@ func (e Error) ErrorAttributes() map[string]interface{} { return e.Attributes }
 
 // StackTrace returns the error's stack.
 func (e Error) StackTrace() []uintptr { return e.Stack }
+
+// NoticeErrorOption configures an error recorded using
+// Application.NoticeError.
+type NoticeErrorOption func(*noticeErrorOptions)
+
+type noticeErrorOptions struct {
+	timestamp time.Time
+	expect    bool
+}
+
+// WithErrorTimestamp sets the time at which the error occurred.  Use it when
+// the error is reported some time after it happened, such as when draining a
+// queue of failures.  By default the error is given the time NoticeError is
+// called.
+func WithErrorTimestamp(t time.Time) NoticeErrorOption {
+	return func(o *noticeErrorOptions) {
+		o.timestamp = t
+	}
+}
+
+// WithErrorExpected marks the error as expected, as does
+// Transaction.NoticeExpectedError.  The error event is recorded with the
+// error.expected attribute.
+func WithErrorExpected() NoticeErrorOption {
+	return func(o *noticeErrorOptions) {
+		o.expect = true
+	}
+}
+
+// ErrorSeverity is the severity of an error recorded using
+// Transaction.RecordException.  It is recorded as the error.severity
+// attribute of traced errors and error events.  When the number of errors
+// exceeds the limits of a transaction or harvest cycle, errors of a lower
+// severity are discarded first so that critical errors are never displaced by
+// warnings.
+type ErrorSeverity string
+
+// These are the valid error severities.  Errors recorded using
+// Transaction.NoticeError are considered to be of ErrorSeverityError.
+const (
+	ErrorSeverityWarning  ErrorSeverity = "warning"
+	ErrorSeverityError    ErrorSeverity = "error"
+	ErrorSeverityCritical ErrorSeverity = "critical"
+)
+
+// rank orders the severities from least to most severe.  Errors without a
+// severity rank as ErrorSeverityError.  Invalid severities rank zero.
+func (s ErrorSeverity) rank() int {
+	switch s {
+	case ErrorSeverityWarning:
+		return 1
+	case "", ErrorSeverityError:
+		return 2
+	case ErrorSeverityCritical:
+		return 3
+	}
+	return 0
+}
@@ -62,6 +62,7 @@ type errorData struct {
 	Klass           string
 	SpanID          string
 	Expect          bool
+	Severity        ErrorSeverity
 }
 
 // txnError combines error data with information about a transaction.  txnError is used for
@@ -85,13 +86,44 @@ func newTxnErrors(max int) txnErrors {
 	return make([]*errorData, 0, max)
 }
 
-// Add adds a TxnError.
+// slot returns the index at which an error of the given severity would be
+// added: len(errors) if there is room, the index of the first error of the
+// lowest severity if that severity is lower, or -1 if the error would be
+// discarded.
+func (errors txnErrors) slot(severity ErrorSeverity) int {
+	if len(errors) < cap(errors) {
+		return len(errors)
+	}
+	return lowestSeverity(len(errors), severity, func(i int) ErrorSeverity {
+		return errors[i].Severity
+	})
+}
+
+// Add adds a TxnError.  Once the set is full, an error replaces the first
+// error of a lower severity.
 func (errors *txnErrors) Add(e errorData) {
-	if len(*errors) < cap(*errors) {
+	switch i := errors.slot(e.Severity); {
+	case i == len(*errors):
 		*errors = append(*errors, &e)
+	case i >= 0:
+		(*errors)[i] = &e
 	}
 }
 
+// lowestSeverity returns the index of the first of the n errors with the
+// lowest severity, or -1 if none has a severity lower than severity.
+func lowestSeverity(n int, severity ErrorSeverity, severityAt func(i int) ErrorSeverity) int {
+	idx := -1
+	rank := severity.rank()
+	for i := 0; i < n; i++ {
+		if r := severityAt(i).rank(); r < rank {
+			idx = i
+			rank = r
+		}
+	}
+	return idx
+}
+
 func (h *tracedError) WriteJSON(buf *bytes.Buffer) {
 	buf.WriteByte('[')
 	jsonx.AppendFloat(buf, timeToFloatMilliseconds(h.When))
@@ -114,7 +146,7 @@ func (h *tracedError) WriteJSON(buf *bytes.Buffer) {
 	buf.WriteByte(',')
 	buf.WriteString(`"intrinsics"`)
 	buf.WriteByte(':')
-	intrinsicsJSON(&h.txnEvent, buf, h.errorData.Expect)
+	intrinsicsJSON(&h.txnEvent, buf, h.errorData.Expect, h.errorData.Severity, nil)
 	if nil != h.Stack {
 		buf.WriteByte(',')
 		buf.WriteString(`"stack_trace"`)
@@ -140,15 +172,24 @@ func newHarvestErrors(max int) harvestErrors {
 }
 
 // mergeTxnErrors merges a transaction's errors into the harvest's errors.
+// Once the harvest's errors are full, an error replaces the first error of a
+// lower severity.
 func mergeTxnErrors(errors *harvestErrors, errs txnErrors, txnEvent txnEvent) {
 	for _, e := range errs {
-		if len(*errors) == cap(*errors) {
-			return
-		}
-		*errors = append(*errors, &tracedError{
+		traced := &tracedError{
 			txnEvent:  txnEvent,
 			errorData: *e,
+		}
+		if len(*errors) < cap(*errors) {
+			*errors = append(*errors, traced)
+			continue
+		}
+		i := lowestSeverity(len(*errors), e.Severity, func(i int) ErrorSeverity {
+			return (*errors)[i].Severity
 		})
+		if i >= 0 {
+			(*errors)[i] = traced
+		}
 	}
 }
 
@@ -6,6 +6,7 @@ package newrelic
 import (
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 )
@@ -322,6 +323,84 @@ func TestErrorsLifecycle(t *testing.T) {
 	}
 }
 
+func TestErrorsSeverityEviction(t *testing.T) {
+	ers := newTxnErrors(3)
+	ers.Add(errorData{Klass: "warning1", Severity: ErrorSeverityWarning})
+	ers.Add(errorData{Klass: "error1"})
+	ers.Add(errorData{Klass: "warning2", Severity: ErrorSeverityWarning})
+	ers.Add(errorData{Klass: "critical1", Severity: ErrorSeverityCritical})
+	ers.Add(errorData{Klass: "warning3", Severity: ErrorSeverityWarning})
+	ers.Add(errorData{Klass: "critical2", Severity: ErrorSeverityCritical})
+	ers.Add(errorData{Klass: "critical3", Severity: ErrorSeverityCritical})
+	ers.Add(errorData{Klass: "critical4", Severity: ErrorSeverityCritical})
+
+	var classes []string
+	for _, e := range ers {
+		classes = append(classes, e.Klass)
+	}
+	if s := strings.Join(classes, ","); s != "critical1,critical3,critical2" {
+		t.Error(s)
+	}
+}
+
+func TestMergeTxnErrorsSeverityEviction(t *testing.T) {
+	he := newHarvestErrors(2)
+	warnings := newTxnErrors(2)
+	warnings.Add(errorData{Klass: "warning1", Severity: ErrorSeverityWarning})
+	warnings.Add(errorData{Klass: "warning2", Severity: ErrorSeverityWarning})
+	mergeTxnErrors(&he, warnings, txnEvent{FinalName: "txn1"})
+
+	others := newTxnErrors(2)
+	others.Add(errorData{Klass: "critical1", Severity: ErrorSeverityCritical})
+	others.Add(errorData{Klass: "warning3", Severity: ErrorSeverityWarning})
+	mergeTxnErrors(&he, others, txnEvent{FinalName: "txn2"})
+
+	if len(he) != 2 {
+		t.Fatal(len(he))
+	}
+	if he[0].Klass != "critical1" || he[0].FinalName != "txn2" {
+		t.Error(he[0].Klass, he[0].FinalName)
+	}
+	if he[1].Klass != "warning2" || he[1].FinalName != "txn1" {
+		t.Error(he[1].Klass, he[1].FinalName)
+	}
+}
+
+func TestErrorTraceMarshalSeverity(t *testing.T) {
+	he := &tracedError{
+		errorData: errorData{
+			When:     time.Date(2014, time.November, 28, 1, 1, 0, 0, time.UTC),
+			Msg:      "my_msg",
+			Klass:    "my_class",
+			Severity: ErrorSeverityCritical,
+		},
+		txnEvent: txnEvent{
+			FinalName: "my_txn_name",
+			TotalTime: 2 * time.Second,
+		},
+	}
+	js, err := json.Marshal(he)
+	if nil != err {
+		t.Error(err)
+	}
+	expect := `
+	[
+		1.41713646e+12,
+		"my_txn_name",
+		"my_msg",
+		"my_class",
+		{
+			"agentAttributes":{},
+			"userAttributes":{},
+			"intrinsics":{
+				"totalTime":2,
+				"error.severity":"critical"
+			}
+		}
+	]`
+	testExpectedJSON(t, expect, string(js))
+}
+
 func BenchmarkErrorsJSON(b *testing.B) {
 	when := time.Date(2014, time.November, 28, 1, 1, 0, 0, time.UTC)
 	max := 20
@@ -0,0 +1,238 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// eventAPITimeout limits each request to the Event API.  It is a variable
+// so that it can be changed in tests.
+var eventAPITimeout = 10 * time.Second
+
+// eventAPIRequest is a request to send custom events directly to the
+// Event API.  It is used as a fallback when the collector cannot accept
+// custom events.
+type eventAPIRequest struct {
+	Host      string
+	AccountID string
+	InsertKey string
+	Data      []byte
+}
+
+func (r eventAPIRequest) url() string {
+	return "https://" + r.Host + "/v1/accounts/" + r.AccountID + "/events"
+}
+
+// WriteEventAPIJSON writes the event as a single flat object in the format
+// expected by the Event API.
+func (e *customEvent) WriteEventAPIJSON(buf *bytes.Buffer) {
+	w := jsonFieldsWriter{buf: buf}
+	buf.WriteByte('{')
+	w.stringField("eventType", e.eventType)
+	w.intField("timestamp", timeToIntMillis(e.timestamp))
+	for key, val := range e.truncatedParams {
+		writeAttributeValueJSON(&w, key, val)
+	}
+	buf.WriteByte('}')
+}
+
+// EventAPIJSON returns the events as a JSON array for the Event API, or nil
+// if there are no events.
+func (cs *customEvents) EventAPIJSON() []byte {
+	if 0 == len(cs.events) {
+		return nil
+	}
+	estimate := 256 * len(cs.events)
+	buf := bytes.NewBuffer(make([]byte, 0, estimate))
+	buf.WriteByte('[')
+	for i, e := range cs.events {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		if ce, ok := e.jsonWriter.(*customEvent); ok {
+			ce.WriteEventAPIJSON(buf)
+		} else {
+			buf.WriteString("{}")
+		}
+	}
+	buf.WriteByte(']')
+	return buf.Bytes()
+}
+
+// sendEventAPI sends custom events to the Event API.
+func sendEventAPI(r eventAPIRequest, cs rpmControls) error {
+	compressed, err := compress(r.Data, cs.GzipWriterPool)
+	if nil != err {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", r.url(), compressed)
+	if nil != err {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), eventAPITimeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Content-Encoding", "gzip")
+	req.Header.Add("User-Agent", userAgentPrefix+Version)
+	req.Header.Add("X-Insert-Key", r.InsertKey)
+
+	resp, err := cs.Client.Do(req)
+	if nil != err {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("event api response code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// eventAPIFallbackEnabled returns whether custom events may be sent to the
+// Event API.
+func (app *app) eventAPIFallbackEnabled() bool {
+	fb := app.config.CustomInsightsEvents.EventAPIFallback
+	return fb.Enabled && "" != fb.InsertKey && "" != fb.AccountID
+}
+
+// eventAPIFallback sends custom events to the Event API if the fallback is
+// enabled and the events have failed to be harvested enough times.  It
+// returns true if the events are being sent and should not be retained, see
+// sendEventAPIFallback.
+func (app *app) eventAPIFallback(cs *customEvents, onFailure func()) bool {
+	if cs.failedHarvests+1 < app.config.CustomInsightsEvents.EventAPIFallback.FailedHarvests {
+		return false
+	}
+	return app.sendEventAPIFallback(cs, onFailure)
+}
+
+// sendEventAPIFallback sends custom events to the Event API in the
+// background if the fallback is enabled, regardless of how many times the
+// events have failed to be harvested.  It returns false if the events are
+// not sent.  Otherwise onFailure, if not nil, is called from the background
+// goroutine if the events could not be sent.
+func (app *app) sendEventAPIFallback(cs *customEvents, onFailure func()) bool {
+	if nil == cs || !app.eventAPIFallbackEnabled() {
+		return false
+	}
+	data := cs.EventAPIJSON()
+	if nil == data {
+		return false
+	}
+	fb := app.config.CustomInsightsEvents.EventAPIFallback
+	r := eventAPIRequest{
+		Host:      app.config.eventAPIHost(),
+		AccountID: fb.AccountID,
+		InsertKey: fb.InsertKey,
+		Data:      data,
+	}
+	if !app.startEventAPISend() {
+		return false
+	}
+	go func() {
+		defer app.eventAPISends.Done()
+		if err := sendEventAPI(r, app.rpmControls); nil != err {
+			app.Warn("unable to send custom events to the event api", map[string]interface{}{
+				"error":           err.Error(),
+				"failed_harvests": cs.failedHarvests + 1,
+			})
+			if nil != onFailure {
+				onFailure()
+			}
+			return
+		}
+		app.Info("sent custom events to the event api", map[string]interface{}{
+			"events":          len(cs.events),
+			"failed_harvests": cs.failedHarvests + 1,
+		})
+	}()
+	return true
+}
+
+// startEventAPISend adds an Event API request to eventAPISends.  It returns
+// false if shutdown is already waiting for the requests, since harvests
+// running in other goroutines may still end after that.
+func (app *app) startEventAPISend() bool {
+	app.eventAPISendsLock.Lock()
+	defer app.eventAPISendsLock.Unlock()
+	if app.eventAPISendsClosed {
+		return false
+	}
+	app.eventAPISends.Add(1)
+	return true
+}
+
+// waitEventAPISends prevents further Event API requests and waits for those
+// in progress to complete.
+func (app *app) waitEventAPISends() {
+	app.eventAPISendsLock.Lock()
+	app.eventAPISendsClosed = true
+	app.eventAPISendsLock.Unlock()
+	app.eventAPISends.Wait()
+}
+
+// eventAPIConnectFallback sends the custom events recorded while the
+// application is not connected to the Event API once enough consecutive
+// connect attempts have failed.
+func (app *app) eventAPIConnectFallback(failedAttempts int) {
+	if failedAttempts < app.config.CustomInsightsEvents.EventAPIFallback.FailedHarvests {
+		return
+	}
+	app.sendEventAPIFallback(app.pendingCustomEvents.take(), nil)
+}
+
+// pendingCustomEvents holds the custom events recorded while the application
+// is not connected, when the Event API fallback is enabled.  They are merged
+// into the harvest once the application connects, or sent to the Event API
+// if it fails to connect or is disconnected.
+type pendingCustomEvents struct {
+	sync.Mutex
+	events *customEvents
+}
+
+// add adds the custom events of data, returning false if data does not hold
+// custom events.
+func (p *pendingCustomEvents) add(data harvestable, max int) bool {
+	var events []*customEvent
+	switch d := data.(type) {
+	case *customEvent:
+		events = []*customEvent{d}
+	case customEventBatch:
+		events = d
+	default:
+		return false
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	if nil == p.events {
+		p.events = newCustomEvents(max)
+	}
+	for _, e := range events {
+		p.events.Add(e)
+	}
+	return true
+}
+
+// take removes and returns the pending custom events, or nil if there are
+// none.
+func (p *pendingCustomEvents) take() *customEvents {
+	p.Lock()
+	defer p.Unlock()
+	cs := p.events
+	p.events = nil
+	return cs
+}
@@ -0,0 +1,243 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal/logger"
+)
+
+type eventAPIMock struct {
+	sync.Mutex
+	response *http.Response
+	err      error
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (m *eventAPIMock) RoundTrip(r *http.Request) (*http.Response, error) {
+	gz, err := gzip.NewReader(r.Body)
+	if nil != err {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(gz)
+	if nil != err {
+		return nil, err
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.requests = append(m.requests, r)
+	m.bodies = append(m.bodies, body)
+	return m.response, m.err
+}
+
+func eventAPITestApp(m *eventAPIMock, cfgFn func(*Config)) *app {
+	cfg := defaultConfig()
+	cfg.License = "eu01xx6789012345678901234567890123456789"
+	cfg.CustomInsightsEvents.EventAPIFallback.Enabled = true
+	cfg.CustomInsightsEvents.EventAPIFallback.InsertKey = "my-insert-key"
+	cfg.CustomInsightsEvents.EventAPIFallback.AccountID = "123"
+	if nil != cfgFn {
+		cfgFn(&cfg)
+	}
+	return &app{
+		Logger: logger.ShimLogger{},
+		config: config{Config: cfg},
+		rpmControls: rpmControls{
+			Client: &http.Client{Transport: m},
+			Logger: logger.ShimLogger{},
+			GzipWriterPool: &sync.Pool{
+				New: func() interface{} {
+					return gzip.NewWriter(io.Discard)
+				},
+			},
+		},
+	}
+}
+
+func eventAPITestEvents(t *testing.T, failedHarvests int) *customEvents {
+	e, err := createCustomEvent("myEvent", map[string]interface{}{"zip": "zap"}, time.Unix(1417136460, 0), defaultAttributeLimits)
+	if nil != err {
+		t.Fatal(err)
+	}
+	cs := newCustomEvents(10)
+	cs.Add(e)
+	cs.failedHarvests = failedHarvests
+	return cs
+}
+
+func TestCustomEventsEventAPIJSON(t *testing.T) {
+	cs := newCustomEvents(10)
+	if js := cs.EventAPIJSON(); nil != js {
+		t.Error(string(js))
+	}
+	cs = eventAPITestEvents(t, 0)
+	js := cs.EventAPIJSON()
+	expect := `[{"eventType":"myEvent","timestamp":1417136460000,"zip":"zap"}]`
+	if string(js) != expect {
+		t.Error(string(js))
+	}
+}
+
+func TestEventAPIFallbackSuccess(t *testing.T) {
+	m := &eventAPIMock{response: makeResponse(202, `{"success":true}`)}
+	app := eventAPITestApp(m, nil)
+	if !app.eventAPIFallback(eventAPITestEvents(t, 2), func() { t.Error("send failed") }) {
+		t.Fatal("events not sent")
+	}
+	app.eventAPISends.Wait()
+	if len(m.requests) != 1 {
+		t.Fatal(len(m.requests))
+	}
+	req := m.requests[0]
+	if u := req.URL.String(); u != "https://insights-collector.eu01.nr-data.net/v1/accounts/123/events" {
+		t.Error(u)
+	}
+	if h := req.Header.Get("X-Insert-Key"); h != "my-insert-key" {
+		t.Error(h)
+	}
+	if h := req.Header.Get("Content-Encoding"); h != "gzip" {
+		t.Error(h)
+	}
+	var events []map[string]interface{}
+	if err := json.Unmarshal(m.bodies[0], &events); nil != err {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0]["eventType"] != "myEvent" || events[0]["zip"] != "zap" {
+		t.Error(string(m.bodies[0]))
+	}
+}
+
+func TestEventAPIFallbackAfterShutdown(t *testing.T) {
+	m := &eventAPIMock{response: makeResponse(202, `{"success":true}`)}
+	app := eventAPITestApp(m, nil)
+	app.waitEventAPISends()
+	// Harvests still running once shutdown waits for the requests do
+	// not start new ones.
+	if app.eventAPIFallback(eventAPITestEvents(t, 2), nil) {
+		t.Error("events sent after shutdown")
+	}
+	app.eventAPISends.Wait()
+	if len(m.requests) != 0 {
+		t.Error(len(m.requests))
+	}
+}
+
+func TestEventAPIFallbackBelowThreshold(t *testing.T) {
+	m := &eventAPIMock{response: makeResponse(202, `{"success":true}`)}
+	app := eventAPITestApp(m, nil)
+	if app.eventAPIFallback(eventAPITestEvents(t, 1), nil) {
+		t.Error("events sent before threshold")
+	}
+	if len(m.requests) != 0 {
+		t.Error(len(m.requests))
+	}
+}
+
+func TestEventAPIFallbackDisabled(t *testing.T) {
+	m := &eventAPIMock{response: makeResponse(202, `{"success":true}`)}
+	app := eventAPITestApp(m, func(cfg *Config) {
+		cfg.CustomInsightsEvents.EventAPIFallback.Enabled = false
+	})
+	if app.eventAPIFallback(eventAPITestEvents(t, 5), nil) {
+		t.Error("events sent when fallback disabled")
+	}
+	if len(m.requests) != 0 {
+		t.Error(len(m.requests))
+	}
+}
+
+func TestEventAPIFallbackFailure(t *testing.T) {
+	for _, m := range []*eventAPIMock{
+		{response: makeResponse(403, ``)},
+		{err: errors.New("client error")},
+	} {
+		app := eventAPITestApp(m, nil)
+		failed := false
+		if !app.eventAPIFallback(eventAPITestEvents(t, 5), func() { failed = true }) {
+			t.Error("events not sent")
+		}
+		app.eventAPISends.Wait()
+		if !failed {
+			t.Error("failure not reported", m.response, m.err)
+		}
+	}
+}
+
+// eventAPIBlockingTransport blocks each request until it is canceled.
+type eventAPIBlockingTransport struct{}
+
+func (eventAPIBlockingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	<-r.Context().Done()
+	return nil, r.Context().Err()
+}
+
+func TestEventAPIFallbackTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { eventAPITimeout = timeout }(eventAPITimeout)
+	eventAPITimeout = 10 * time.Millisecond
+
+	app := eventAPITestApp(nil, nil)
+	app.rpmControls.Client = &http.Client{Transport: eventAPIBlockingTransport{}}
+	failed := false
+	if !app.eventAPIFallback(eventAPITestEvents(t, 5), func() { failed = true }) {
+		t.Error("events not sent")
+	}
+	app.eventAPISends.Wait()
+	if !failed {
+		t.Error("timeout not reported")
+	}
+}
+
+func TestEventAPIConnectFallback(t *testing.T) {
+	m := &eventAPIMock{response: makeResponse(202, `{"success":true}`)}
+	app := eventAPITestApp(m, nil)
+	app.placeholderRun = newPlaceholderAppRun(app.config)
+	if err := app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": "zap"}); nil != err {
+		t.Fatal(err)
+	}
+
+	// The events are kept until enough connect attempts have failed.
+	app.eventAPIConnectFallback(2)
+	app.eventAPISends.Wait()
+	if len(m.requests) != 0 {
+		t.Fatal(len(m.requests))
+	}
+	app.eventAPIConnectFallback(3)
+	app.eventAPISends.Wait()
+	if len(m.requests) != 1 {
+		t.Fatal(len(m.requests))
+	}
+	var events []map[string]interface{}
+	if err := json.Unmarshal(m.bodies[0], &events); nil != err {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0]["eventType"] != "myEvent" {
+		t.Error(string(m.bodies[0]))
+	}
+	if cs := app.pendingCustomEvents.take(); nil != cs {
+		t.Error("events not removed", len(cs.events))
+	}
+}
+
+func TestEventAPIPendingEventsRequireFallback(t *testing.T) {
+	app := eventAPITestApp(&eventAPIMock{}, func(cfg *Config) {
+		cfg.CustomInsightsEvents.EventAPIFallback.Enabled = false
+	})
+	app.placeholderRun = newPlaceholderAppRun(app.config)
+	if err := app.RecordCustomEvent("myEvent", nil); nil != err {
+		t.Fatal(err)
+	}
+	if cs := app.pendingCustomEvents.take(); nil != cs {
+		t.Error("events kept when fallback disabled", len(cs.events))
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "time"
+
+// exemplarEventType is the type of the custom events recorded for
+// Config.TransactionExemplars.
+const exemplarEventType = "TransactionExemplar"
+
+// txnExemplar identifies the trace of a sampled transaction.
+type txnExemplar struct {
+	traceID   string
+	guid      string
+	duration  time.Duration
+	timestamp time.Time
+}
+
+// txnExemplars holds the slowest sampled transaction of each name during a
+// metrics harvest period, see Config.TransactionExemplars.
+type txnExemplars map[string]txnExemplar
+
+// witness keeps the exemplar if it is the slowest of its transaction name.
+func (es txnExemplars) witness(name string, e txnExemplar) {
+	if old, ok := es[name]; !ok || e.duration > old.duration {
+		es[name] = e
+	}
+}
+
+// addEvents records a custom event for each exemplar.
+func (es txnExemplars) addEvents(cs *customEvents) {
+	for name, e := range es {
+		event, err := createCustomEvent(exemplarEventType, map[string]interface{}{
+			"transactionName": name,
+			"traceId":         e.traceID,
+			"guid":            e.guid,
+			"duration":        e.duration.Seconds(),
+		}, e.timestamp, defaultAttributeLimits)
+		if nil != err {
+			continue
+		}
+		cs.Add(event)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestTxnExemplarsWitness(t *testing.T) {
+	es := make(txnExemplars)
+	es.witness("WebTransaction/Go/hello", txnExemplar{traceID: "a", duration: time.Second})
+	es.witness("WebTransaction/Go/hello", txnExemplar{traceID: "b", duration: 3 * time.Second})
+	es.witness("WebTransaction/Go/hello", txnExemplar{traceID: "c", duration: 2 * time.Second})
+	es.witness("WebTransaction/Go/other", txnExemplar{traceID: "d", duration: time.Millisecond})
+	if len(es) != 2 || es["WebTransaction/Go/hello"].traceID != "b" || es["WebTransaction/Go/other"].traceID != "d" {
+		t.Error(es)
+	}
+}
+
+func TestTxnExemplarsHarvest(t *testing.T) {
+	now := time.Now()
+	h := newHarvest(now, testHarvestCfgr)
+	h.Exemplars.witness("WebTransaction/Go/hello", txnExemplar{
+		traceID:   "trace-id",
+		guid:      "txn-id",
+		duration:  1500 * time.Millisecond,
+		timestamp: now,
+	})
+	ready := h.Ready(now.Add(fixedHarvestPeriod + time.Second))
+	if len(h.Exemplars) != 0 {
+		t.Error(h.Exemplars)
+	}
+	expectCustomEvents(t, ready.CustomEvents, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      exemplarEventType,
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"transactionName": "WebTransaction/Go/hello",
+			"traceId":         "trace-id",
+			"guid":            "txn-id",
+			"duration":        1.5,
+		},
+	}})
+}
+
+func TestTransactionExemplars(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.TransactionExemplars.Enabled = true
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	traceID := txn.GetTraceMetadata().TraceID
+	txn.End()
+
+	e, ok := app.app.testHarvest.Exemplars["OtherTransaction/Go/hello"]
+	if !ok || e.traceID != traceID || "" == e.guid {
+		t.Error(app.app.testHarvest.Exemplars)
+	}
+}
+
+func TestTransactionExemplarsDisabled(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	txn.End()
+
+	if len(app.app.testHarvest.Exemplars) != 0 {
+		t.Error(app.app.testHarvest.Exemplars)
+	}
+}
@@ -249,6 +249,9 @@ func expectLogEvent(v internal.Validator, actual logEvent, want internal.WantLog
 		v.Error(fmt.Sprintf("unexpected log timestamp: got %d, want %d", actual.timestamp, want.Timestamp))
 		return
 	}
+	if nil != want.Attributes {
+		expectAttributes(v, actual.attributes, want.Attributes)
+	}
 }
 
 func expectEvent(v internal.Validator, e json.Marshaler, expect internal.WantEvent) {
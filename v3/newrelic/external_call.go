@@ -0,0 +1,205 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+)
+
+type externalCallContextKeyType struct{}
+
+var externalCallContextKey = externalCallContextKeyType(struct{}{})
+
+// ExternalCall groups the requests made for one logical external call, such
+// as retries made by a retry library to a different URL, under a single
+// segment.  NewRoundTripper groups redirects and retries to the same URL
+// without an ExternalCall.  Create an ExternalCall using
+// StartExternalCall.  All methods on ExternalCall are nil safe.
+type ExternalCall struct {
+	segment *Segment
+
+	sync.Mutex
+	attempts        int
+	redirects       int
+	attemptRedirect int
+}
+
+// StartExternalCall starts a segment for a logical external call made
+// using the Transaction in the context, and returns a context carrying the
+// ExternalCall.  Requests made with the returned context using an
+// http.RoundTripper created by NewRoundTripper are recorded as child external
+// segments of the call, each with its own ExternalOutcome: the number of
+// attempts made before it, the number of redirects followed within its
+// attempt, and whether it failed with a timeout, a connection error, or a
+// 4xx or 5xx response.  When the call ends its segment is given the
+// "external.attempts" and "external.redirectCount" attributes.
+//
+//	ctx, call := newrelic.StartExternalCall(ctx, "payments/charge")
+//	defer call.End()
+//	for i := 0; i < 3; i++ {
+//		req, _ := http.NewRequestWithContext(ctx, "POST", url, body)
+//		if resp, err := client.Do(req); nil == err && resp.StatusCode < 500 {
+//			break
+//		}
+//	}
+//
+// The name is used as the segment name.  If the context has no Transaction,
+// the context is returned unchanged along with a nil ExternalCall.
+func StartExternalCall(ctx context.Context, name string) (context.Context, *ExternalCall) {
+	txn := FromContext(ctx)
+	if nil == txn {
+		return ctx, nil
+	}
+	call := &ExternalCall{segment: txn.StartSegment(name)}
+	return context.WithValue(ctx, externalCallContextKey, call), call
+}
+
+// End finishes the external call.
+func (c *ExternalCall) End() {
+	if nil == c {
+		return
+	}
+	c.Lock()
+	attempts, redirects := c.attempts, c.redirects
+	c.Unlock()
+	if thd := c.segment.StartTime.thread; nil != thd {
+		thd.addExternalCallAttributes(c.segment.StartTime.start, attempts, redirects)
+	}
+	c.segment.End()
+}
+
+// externalCallFromContext returns the ExternalCall in the context if present,
+// and nil otherwise.
+func externalCallFromContext(ctx context.Context) *ExternalCall {
+	if nil == ctx {
+		return nil
+	}
+	c, _ := ctx.Value(externalCallContextKey).(*ExternalCall)
+	return c
+}
+
+// request records a request made for the call and returns the retry and
+// redirect counts of its segment.  The http.Client populates the Response
+// field of requests made to follow a redirect.
+func (c *ExternalCall) request(r *http.Request) ExternalOutcome {
+	c.Lock()
+	defer c.Unlock()
+	if nil != r.Response && c.attempts > 0 {
+		c.redirects++
+		c.attemptRedirect++
+	} else {
+		c.attempts++
+		c.attemptRedirect = 0
+	}
+	return ExternalOutcome{
+		RetryCount:    c.attempts - 1,
+		RedirectCount: c.attemptRedirect,
+	}
+}
+
+// roundTripGroup is a logical external call recognized by NewRoundTripper for
+// requests made without an ExternalCall: a request, the redirects followed for
+// it by an http.Client, and the retries made for it on the same goroutine with
+// the same method and URL after it failed.  When the transaction ends, a span
+// is added for each group of more than one request and the spans of the
+// requests are made its children.
+type roundTripGroup struct {
+	method string
+	url    string
+	// last is the response to the group's latest request, which an
+	// http.Client sets as the Response of the request following a
+	// redirect.
+	last *http.Response
+	// failed is true if the group's latest request failed.
+	failed          bool
+	attempts        int
+	redirects       int
+	attemptRedirect int
+	spans           []*spanEvent
+}
+
+// startRoundTrip finds or creates the group of a request made using
+// NewRoundTripper and returns the retry and redirect counts of its segment.
+func (t *txnData) startRoundTrip(thread *tracingThread, r *http.Request) (*roundTripGroup, ExternalOutcome) {
+	g := thread.roundTrip
+	switch {
+	case nil != g && nil != r.Response && r.Response == g.last:
+		g.redirects++
+		g.attemptRedirect++
+	case nil != g && nil == r.Response && g.failed && r.Method == g.method && r.URL.String() == g.url:
+		g.attempts++
+		g.attemptRedirect = 0
+	default:
+		g = &roundTripGroup{method: r.Method, url: r.URL.String(), attempts: 1}
+		thread.roundTrip = g
+	}
+	if 2 == g.attempts+g.redirects {
+		t.roundTripGroups = append(t.roundTripGroups, g)
+	}
+	g.last = nil
+	g.failed = false
+	return g, ExternalOutcome{
+		RetryCount:    g.attempts - 1,
+		RedirectCount: g.attemptRedirect,
+	}
+}
+
+// addRoundTripGroupSpans adds a span for each group of requests made using
+// NewRoundTripper, named after the group's first request and given the
+// "external.attempts" and "external.redirectCount" attributes, and makes it
+// the parent of the spans of the group's requests.
+func (txn *txn) addRoundTripGroupSpans() {
+	for _, g := range txn.roundTripGroups {
+		if len(g.spans) < 2 || len(txn.SpanEvents) >= defaultMaxSpanEvents {
+			continue
+		}
+		first := g.spans[0]
+		stop := first.Timestamp.Add(first.Duration)
+		for _, child := range g.spans[1:] {
+			if end := child.Timestamp.Add(child.Duration); end.After(stop) {
+				stop = end
+			}
+		}
+		evt := &spanEvent{
+			GUID:      txn.TraceIDGenerator.GenerateSpanID(),
+			ParentID:  first.ParentID,
+			Timestamp: first.Timestamp,
+			Duration:  stop.Sub(first.Timestamp),
+			Name:      first.Name,
+			Category:  spanCategoryGeneric,
+		}
+		evt.AgentAttributes.addInt(SpanAttributeExternalAttempts, g.attempts)
+		evt.AgentAttributes.addInt(SpanAttributeExternalRedirectCount, g.redirects)
+		evt.AgentAttributes = txn.Attrs.filterSpanAttributes(evt.AgentAttributes, destSpan)
+		for _, child := range g.spans {
+			child.ParentID = evt.GUID
+		}
+		txn.SpanEvents = append(txn.SpanEvents, evt)
+	}
+}
+
+// externalFailure classifies the result of a round trip.
+func externalFailure(resp *http.Response, err error) ExternalFailure {
+	if nil != err {
+		var netErr net.Error
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return ExternalFailureTimeout
+		}
+		return ExternalFailureConnection
+	}
+	if nil == resp {
+		return ""
+	}
+	switch {
+	case resp.StatusCode >= 500:
+		return ExternalFailureServerError
+	case resp.StatusCode >= 400:
+		return ExternalFailureClientError
+	}
+	return ""
+}
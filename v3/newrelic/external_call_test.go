@@ -0,0 +1,323 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestExternalCallRetriesAndRedirects(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	requests := 0
+	client := &http.Client{Transport: NewRoundTripper(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		resp := &http.Response{
+			Header:  http.Header{},
+			Body:    ioutil.NopCloser(strings.NewReader("")),
+			Request: r,
+		}
+		switch requests {
+		case 1:
+			resp.StatusCode = 503
+		case 2:
+			resp.StatusCode = 302
+			resp.Header.Set("Location", "http://example.com/next")
+		default:
+			resp.StatusCode = 200
+		}
+		return resp, nil
+	}))}
+
+	ctx, call := StartExternalCall(NewContext(context.Background(), txn), "payments/charge")
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/start", nil)
+		resp, err := client.Do(req)
+		if nil != err {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if 200 == resp.StatusCode {
+			break
+		}
+	}
+	call.End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"name":      "External/example.com/http/GET",
+				"category":  "http",
+				"component": "http",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"http.url":         "http://example.com/start",
+				"http.method":      "GET",
+				"http.statusCode":  503,
+				"external.failure": "server_error",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"name":      "External/example.com/http/GET",
+				"category":  "http",
+				"component": "http",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"http.url":            "http://example.com/start",
+				"http.method":         "GET",
+				"http.statusCode":     302,
+				"external.retryCount": 1,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"name":      "External/example.com/http/GET",
+				"category":  "http",
+				"component": "http",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"http.url":               "http://example.com/next",
+				"http.method":            "GET",
+				"http.statusCode":        200,
+				"external.retryCount":    1,
+				"external.redirectCount": 1,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId": internal.MatchAnything,
+				"name":     "Custom/payments/charge",
+				"category": "generic",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"external.attempts":      2,
+				"external.redirectCount": 1,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+
+	// Each request must be a child of the call's segment.
+	var callID string
+	parents := map[string]int{}
+	for _, e := range app.app.testHarvest.SpanEvents.events {
+		evt := e.jsonWriter.(*spanEvent)
+		if "Custom/payments/charge" == evt.Name {
+			callID = evt.GUID
+		} else {
+			parents[evt.ParentID]++
+		}
+	}
+	if "" == callID || 3 != parents[callID] {
+		t.Error(callID, parents)
+	}
+}
+
+func TestRoundTripperGroupsRetriesAndRedirects(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	requests := 0
+	client := &http.Client{Transport: NewRoundTripper(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		resp := &http.Response{
+			Header:  http.Header{},
+			Body:    ioutil.NopCloser(strings.NewReader("")),
+			Request: r,
+		}
+		switch requests {
+		case 1:
+			resp.StatusCode = 503
+		case 2:
+			resp.StatusCode = 302
+			resp.Header.Set("Location", "http://example.com/next")
+		default:
+			resp.StatusCode = 200
+		}
+		return resp, nil
+	}))}
+
+	ctx := NewContext(context.Background(), txn)
+	for i := 0; i < 3; i++ {
+		// The third request is not a retry since the second succeeded.
+		req, _ := http.NewRequestWithContext(ctx, "GET", "http://example.com/start", nil)
+		resp, err := client.Do(req)
+		if nil != err {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	external := func(url string, status int, attrs map[string]interface{}) internal.WantEvent {
+		agentAttrs := map[string]interface{}{
+			"http.url":        url,
+			"http.method":     "GET",
+			"http.statusCode": status,
+		}
+		for k, v := range attrs {
+			agentAttrs[k] = v
+		}
+		return internal.WantEvent{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"name":      "External/example.com/http/GET",
+				"category":  "http",
+				"component": "http",
+				"span.kind": "client",
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: agentAttrs,
+		}
+	}
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		external("http://example.com/start", 503, nil),
+		external("http://example.com/start", 302, map[string]interface{}{
+			"external.retryCount": 1,
+		}),
+		external("http://example.com/next", 200, map[string]interface{}{
+			"external.retryCount":    1,
+			"external.redirectCount": 1,
+		}),
+		external("http://example.com/start", 200, nil),
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId": internal.MatchAnything,
+				"name":     "External/example.com/http/GET",
+				"category": "generic",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"external.attempts":      2,
+				"external.redirectCount": 1,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+
+	// The first three requests must be children of the group's span, which
+	// is a child of the root span along with the fourth request.
+	var rootID, groupID, groupParentID string
+	parents := map[string]int{}
+	for _, e := range app.app.testHarvest.SpanEvents.events {
+		evt := e.jsonWriter.(*spanEvent)
+		switch {
+		case evt.IsEntrypoint:
+			rootID = evt.GUID
+		case spanCategoryGeneric == evt.Category:
+			groupID = evt.GUID
+			groupParentID = evt.ParentID
+		}
+		parents[evt.ParentID]++
+	}
+	if "" == groupID || groupParentID != rootID || 3 != parents[groupID] || 2 != parents[rootID] {
+		t.Error(rootID, groupID, groupParentID, parents)
+	}
+}
+
+func TestExternalCallEndAddsAttributesToCallSegment(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	_, call := StartExternalCall(NewContext(context.Background(), txn), "payments/charge")
+	// The call's segment is ended while a segment started after it is
+	// still open.
+	txn.StartSegment("inner")
+	call.End()
+	txn.End()
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId": internal.MatchAnything,
+				"name":     "Custom/payments/charge",
+				"category": "generic",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"external.attempts":      0,
+				"external.redirectCount": 0,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestExternalCallNoTransaction(t *testing.T) {
+	ctx := context.Background()
+	gotCtx, call := StartExternalCall(ctx, "payments/charge")
+	if gotCtx != ctx || nil != call {
+		t.Error(gotCtx, call)
+	}
+	// End is nil safe.
+	call.End()
+}
+
+func TestExternalFailure(t *testing.T) {
+	testcases := []struct {
+		resp *http.Response
+		err  error
+		want ExternalFailure
+	}{
+		{resp: &http.Response{StatusCode: 200}, want: ""},
+		{resp: &http.Response{StatusCode: 404}, want: ExternalFailureClientError},
+		{resp: &http.Response{StatusCode: 502}, want: ExternalFailureServerError},
+		{err: context.DeadlineExceeded, want: ExternalFailureTimeout},
+		{err: errors.New("connection refused"), want: ExternalFailureConnection},
+	}
+	for _, tc := range testcases {
+		if got := externalFailure(tc.resp, tc.err); got != tc.want {
+			t.Error(tc.resp, tc.err, got, tc.want)
+		}
+	}
+}
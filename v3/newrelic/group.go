@@ -0,0 +1,139 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs tasks in goroutines on behalf of a Transaction.  It is modeled
+// on golang.org/x/sync/errgroup.Group: Wait returns the first error returned
+// by a task, and the Group's context is canceled when a task fails.  In
+// addition, each task runs with its own Transaction reference created using
+// NewGoroutine, is timed by a segment, and has its errors and panics recorded
+// on the Transaction using NoticeError.  A panic in a task is recovered and
+// returned from Wait as a newrelic.Error with the class "panic".
+//
+//	g, ctx := newrelic.NewGroup(ctx)
+//	g.SetLimit(4)
+//	for _, id := range ids {
+//		id := id
+//		g.Go("fetchUser", func(ctx context.Context) error {
+//			return fetchUser(ctx, id)
+//		})
+//	}
+//	err := g.Wait()
+//
+// Tasks are given a context carrying their Transaction reference, see
+// FromContext.  A Group must not be reused after Wait returns.
+type Group struct {
+	txn    *Transaction
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	sem    chan struct{}
+
+	errOnce sync.Once
+	err     error
+}
+
+// NewGroup creates a Group for the Transaction in the context, see
+// FromContext, and a derived context which is canceled the first time a
+// task returns an error or Wait returns.  If the context has no
+// Transaction, the Group runs its tasks without instrumentation.
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{
+		txn:    FromContext(ctx),
+		ctx:    ctx,
+		cancel: cancel,
+	}, ctx
+}
+
+// SetLimit limits the number of tasks running at once to n, turning the
+// Group into a worker pool: Go blocks until a task slot is free.  A
+// negative n removes the limit.  SetLimit must not be called while tasks
+// are running.
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// Go runs the task in a new goroutine within a segment of the given name.
+// If the Group has a limit, Go blocks until the task can be started.
+func (g *Group) Go(name string, task func(ctx context.Context) error) {
+	if nil != g.sem {
+		g.sem <- struct{}{}
+	}
+	g.start(name, task)
+}
+
+// TryGo runs the task in a new goroutine only if the Group's limit allows
+// it, and reports whether the task was started.
+func (g *Group) TryGo(name string, task func(ctx context.Context) error) bool {
+	if nil != g.sem {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.start(name, task)
+	return true
+}
+
+func (g *Group) start(name string, task func(ctx context.Context) error) {
+	// NewGoroutine must be called before the goroutine starts, since the
+	// calling goroutine may end the Transaction reference it holds.
+	txn := g.txn.NewGoroutine()
+	g.wg.Add(1)
+	go func() {
+		defer g.done()
+		if err := g.run(txn, name, task); nil != err {
+			txn.NoticeError(err)
+			g.errOnce.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+func (g *Group) run(txn *Transaction, name string, task func(ctx context.Context) error) (err error) {
+	ctx := g.ctx
+	if nil != txn {
+		ctx = NewContext(ctx, txn)
+	}
+	seg := txn.StartSegment(name)
+	defer func() {
+		if r := recover(); nil != r {
+			err = Error{
+				Message: panicValueMsg(r),
+				Class:   panicErrorKlass,
+				Stack:   NewStackTrace(),
+			}
+		}
+		seg.End()
+	}()
+	return task(ctx)
+}
+
+func (g *Group) done() {
+	if nil != g.sem {
+		<-g.sem
+	}
+	g.wg.Done()
+}
+
+// Wait blocks until all tasks have returned, then returns the first error
+// returned by a task, if any.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
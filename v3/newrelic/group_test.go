@@ -0,0 +1,91 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestGroup(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("hello")
+	g, ctx := NewGroup(NewContext(context.Background(), txn))
+	// A limit of one runs the tasks in order.
+	g.SetLimit(1)
+	g.Go("ok", func(ctx context.Context) error {
+		if nil == FromContext(ctx) || txn == FromContext(ctx) {
+			t.Error("task not given its own transaction reference")
+		}
+		return nil
+	})
+	g.Go("fail", func(ctx context.Context) error {
+		return errors.New("task failed")
+	})
+	g.Go("panic", func(ctx context.Context) error {
+		panic("task panicked")
+	})
+	err := g.Wait()
+	txn.End()
+	if nil == err || "task failed" != err.Error() {
+		t.Error(err)
+	}
+	if nil == ctx.Err() {
+		t.Error("context not canceled")
+	}
+	app.expectNoLoggedErrors(t)
+	app.ExpectErrors(t, []internal.WantError{
+		{TxnName: "OtherTransaction/Go/hello", Msg: "task failed", Klass: "*errors.errorString"},
+		{TxnName: "OtherTransaction/Go/hello", Msg: "task panicked", Klass: panicErrorKlass},
+	})
+	scope := "OtherTransaction/Go/hello"
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Custom/ok", Scope: scope, Forced: false, Data: nil},
+		{Name: "Custom/fail", Scope: scope, Forced: false, Data: nil},
+		{Name: "Custom/panic", Scope: scope, Forced: false, Data: nil},
+	})
+}
+
+func TestGroupTryGo(t *testing.T) {
+	g, _ := NewGroup(context.Background())
+	g.SetLimit(1)
+	release := make(chan struct{})
+	if !g.TryGo("block", func(ctx context.Context) error {
+		<-release
+		return nil
+	}) {
+		t.Error("first task not started")
+	}
+	if g.TryGo("rejected", func(ctx context.Context) error { return nil }) {
+		t.Error("task started beyond limit")
+	}
+	close(release)
+	if err := g.Wait(); nil != err {
+		t.Error(err)
+	}
+}
+
+func TestGroupNoTransaction(t *testing.T) {
+	g, ctx := NewGroup(context.Background())
+	var count int32
+	for i := 0; i < 10; i++ {
+		g.Go("task", func(ctx context.Context) error {
+			atomic.AddInt32(&count, 1)
+			return nil
+		})
+	}
+	if err := g.Wait(); nil != err {
+		t.Error(err)
+	}
+	if 10 != atomic.LoadInt32(&count) {
+		t.Error(count)
+	}
+	if nil == ctx.Err() {
+		t.Error("context not canceled after Wait")
+	}
+}
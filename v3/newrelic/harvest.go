@@ -70,6 +70,14 @@ type harvest struct {
 	LogEvents    *logEvents
 	TxnEvents    *txnEvents
 	ErrorEvents  *errorEvents
+	// Exemplars are added to CustomEvents at the end of each metrics
+	// harvest period.
+	Exemplars txnExemplars
+	// SpanAttributeGuard is reset with each span event harvest.  It is
+	// nil unless Config.SpanEvents.MaxAttributeValues is set.
+	SpanAttributeGuard *spanAttributeGuard
+	// memoryLimit is set by Config.MemoryLimit, see fitMemoryLimit.
+	memoryLimit int
 }
 
 const (
@@ -88,14 +96,26 @@ func (h *harvest) Ready(now time.Time) *harvest {
 		return nil
 	}
 
+	if 0 != types&harvestMetricsTraces && len(h.Exemplars) > 0 {
+		h.Exemplars.addEvents(h.CustomEvents)
+		h.Exemplars = make(txnExemplars)
+	}
+
 	if 0 != types&harvestCustomEvents {
 		h.Metrics.addCount(customEventsSeen, h.CustomEvents.NumSeen(), forced)
 		h.Metrics.addCount(customEventsSent, h.CustomEvents.NumSaved(), forced)
+		if n := h.CustomEvents.droppedDiscarded; n > 0 {
+			h.Metrics.addCount(supportDroppedDataDiscarded+droppedTypeCustomEvent, float64(n), forced)
+		}
 		ready.CustomEvents = h.CustomEvents
 		h.CustomEvents = newCustomEvents(h.CustomEvents.capacity())
+		h.CustomEvents.keepDropped = ready.CustomEvents.keepDropped
 	}
 	if 0 != types&harvestLogEvents {
 		h.LogEvents.RecordLoggingMetrics(h.Metrics)
+		if n := h.LogEvents.droppedDiscarded; n > 0 {
+			h.Metrics.addCount(supportDroppedDataDiscarded+droppedTypeLogEvent, float64(n), forced)
+		}
 		ready.LogEvents = h.LogEvents
 		h.LogEvents = newLogEvents(h.LogEvents.commonAttributes, h.LogEvents.config)
 	}
@@ -108,14 +128,18 @@ func (h *harvest) Ready(now time.Time) *harvest {
 	if 0 != types&harvestErrorEvents {
 		h.Metrics.addCount(errorEventsSeen, h.ErrorEvents.NumSeen(), forced)
 		h.Metrics.addCount(errorEventsSent, h.ErrorEvents.NumSaved(), forced)
+		if n := h.ErrorEvents.classLimited; n > 0 {
+			h.Metrics.addCount(errorEventsClassLimited, float64(n), forced)
+		}
 		ready.ErrorEvents = h.ErrorEvents
-		h.ErrorEvents = newErrorEvents(h.ErrorEvents.capacity())
+		h.ErrorEvents = newErrorEvents(h.ErrorEvents.capacity(), h.ErrorEvents.perClassLimit)
 	}
 	if 0 != types&harvestSpanEvents {
 		h.Metrics.addCount(spanEventsSeen, h.SpanEvents.NumSeen(), forced)
 		h.Metrics.addCount(spanEventsSent, h.SpanEvents.NumSaved(), forced)
 		ready.SpanEvents = h.SpanEvents
 		h.SpanEvents = newSpanEvents(h.SpanEvents.capacity())
+		h.SpanAttributeGuard.reset()
 	}
 	// NOTE! Metrics must happen after the event harvest conditionals to
 	// ensure that the metrics contain the event supportability metrics.
@@ -124,9 +148,11 @@ func (h *harvest) Ready(now time.Time) *harvest {
 		ready.ErrorTraces = h.ErrorTraces
 		ready.SlowSQLs = h.SlowSQLs
 		ready.TxnTraces = h.TxnTraces
+		limits := h.Metrics.failedLimits
 		h.Metrics = newMetricTable(maxMetrics, now)
+		h.Metrics.failedLimits = limits
 		h.ErrorTraces = newHarvestErrors(maxHarvestErrors)
-		h.SlowSQLs = newSlowQueries(maxHarvestSlowSQLs)
+		h.SlowSQLs = newHarvestSlowQueries(h.SlowSQLs.byInstance)
 		h.TxnTraces = newHarvestTraces()
 	}
 	return ready
@@ -179,22 +205,44 @@ type harvestConfig struct {
 	MaxCustomEvents  int
 	MaxErrorEvents   int
 	MaxTxnEvents     int
+	// MaxErrorEventsPerClass is the maximum number of error events of a
+	// single class in each error event harvest, or zero for no limit.
+	MaxErrorEventsPerClass int
+	// SlowQueriesByInstance is set by
+	// Config.DatastoreTracer.SlowQuery.AggregateByInstance.
+	SlowQueriesByInstance bool
+	// MaxSpanAttributeValues is set by
+	// Config.SpanEvents.MaxAttributeValues.
+	MaxSpanAttributeValues int
+	// FailedMetrics is set by Config.Harvest.FailedMetrics.
+	FailedMetrics failedMetricLimits
+	// MemoryLimit is set by Config.MemoryLimit.
+	MemoryLimit int
+	// KeepDroppedCustomEvents is set if Config.OnDataDropped is set.
+	KeepDroppedCustomEvents bool
 }
 
 // newHarvest returns a new Harvest.
 func newHarvest(now time.Time, configurer harvestConfig) *harvest {
-	return &harvest{
+	h := &harvest{
 		timer:        newHarvestTimer(now, configurer.ReportPeriods),
 		Metrics:      newMetricTable(maxMetrics, now),
 		ErrorTraces:  newHarvestErrors(maxHarvestErrors),
 		TxnTraces:    newHarvestTraces(),
-		SlowSQLs:     newSlowQueries(maxHarvestSlowSQLs),
+		SlowSQLs:     newHarvestSlowQueries(configurer.SlowQueriesByInstance),
 		SpanEvents:   newSpanEvents(configurer.MaxSpanEvents),
 		CustomEvents: newCustomEvents(configurer.MaxCustomEvents),
 		LogEvents:    newLogEvents(configurer.CommonAttributes, configurer.LoggingConfig),
 		TxnEvents:    newTxnEvents(configurer.MaxTxnEvents),
-		ErrorEvents:  newErrorEvents(configurer.MaxErrorEvents),
+		ErrorEvents:  newErrorEvents(configurer.MaxErrorEvents, configurer.MaxErrorEventsPerClass),
+		Exemplars:    make(txnExemplars),
+
+		SpanAttributeGuard: newSpanAttributeGuard(configurer.MaxSpanAttributeValues),
+		memoryLimit:        configurer.MemoryLimit,
 	}
+	h.Metrics.failedLimits = configurer.FailedMetrics
+	h.CustomEvents.keepDropped = configurer.KeepDroppedCustomEvents
+	return h
 }
 
 func createTrackUsageMetrics(metrics *metricTable) {
@@ -212,6 +260,12 @@ func createTraceObserverMetrics(to traceObserver, metrics *metricTable) {
 	}
 }
 
+func createScrubbingMetrics(s *scrubber, metrics *metricTable) {
+	for name, val := range s.dumpSupportabilityMetrics() {
+		metrics.addCount(name, val, forced)
+	}
+}
+
 func createAppLoggingSupportabilityMetrics(lc *loggingConfig, metrics *metricTable) {
 	lc.connectMetrics(metrics)
 }
@@ -242,6 +296,8 @@ func (h *harvest) CreateFinalMetrics(run *appRun, to traceObserver) {
 	h.Metrics.addValue(supportLogEventLimit, "", float64(hc.LoggingConfig.maxLogEvents), forced)
 
 	createTraceObserverMetrics(to, h.Metrics)
+	createScrubbingMetrics(run.Config.scrubber, h.Metrics)
+	createScrapedMetrics(run.Config.Config, h.Metrics)
 	createTrackUsageMetrics(h.Metrics)
 	createAppLoggingSupportabilityMetrics(&hc.LoggingConfig, h.Metrics)
 
@@ -274,6 +330,12 @@ func createTxnMetrics(args *txnData, metrics *metricTable) {
 		durationRollup = webRollup
 		totalTimeRollup = totalTimeWeb
 		metrics.addDuration(dispatcherMetric, "", args.Duration, 0, forced)
+		if m := statusClassMetric(args.responseCode); "" != m {
+			metrics.addSingleCount(m, forced)
+		}
+		if args.clientCanceled {
+			metrics.addSingleCount(dispatcherClientCanceled, forced)
+		}
 	} else {
 		durationRollup = backgroundRollup
 		totalTimeRollup = totalTimeBackground
@@ -281,6 +343,9 @@ func createTxnMetrics(args *txnData, metrics *metricTable) {
 
 	metrics.addDuration(args.FinalName, "", args.Duration, 0, forced)
 	metrics.addDuration(durationRollup, "", args.Duration, 0, forced)
+	if nil != args.histogramBuckets {
+		metrics.addSingleCount(histogramMetric(args.FinalName, args.histogramBuckets, args.Duration), unforced)
+	}
 
 	metrics.addDuration(totalTimeRollup, "", args.TotalTime, args.TotalTime, forced)
 	metrics.addDuration(totalTimeRollup+"/"+withoutFirstSegment, "", args.TotalTime, args.TotalTime, unforced)
@@ -306,6 +371,10 @@ func createTxnMetrics(args *txnData, metrics *metricTable) {
 			m = transportDurationMetric(caller)
 			metrics.addDuration(m.all, "", d, d, unforced)
 			metrics.addDuration(m.webOrOther(args.IsWeb), "", d, d, unforced)
+
+			if args.relationshipMetrics {
+				metrics.addDuration(relationshipMetric(caller), args.FinalName, args.Duration, args.Duration, unforced)
+			}
 		}
 
 		// CAT Error Metrics
@@ -317,17 +386,21 @@ func createTxnMetrics(args *txnData, metrics *metricTable) {
 
 		args.DistributedTracingSupport.createMetrics(metrics)
 	}
+	args.CrossAppTracingSupport.createMetrics(metrics)
 
 	// Apdex Metrics
-	if args.Zone != apdexNone {
+	if args.Zone != apdexNone && args.IsWeb {
 		metrics.addApdex(apdexRollup, "", args.ApdexThreshold, args.Zone, forced)
 
 		mname := apdexPrefix + withoutFirstSegment
 		metrics.addApdex(mname, "", args.ApdexThreshold, args.Zone, unforced)
+	} else if args.Zone != apdexNone {
+		metrics.addApdex(apdexOtherRollup, "", args.ApdexThreshold, args.Zone, forced)
+		metrics.addApdex(apdexOtherPrefix+withoutFirstSegment, "", args.ApdexThreshold, args.Zone, unforced)
 	}
 
 	// Error Metrics
-	if args.NoticeErrors() {
+	if args.NoticeErrors() && !args.noErrorMetrics {
 		metrics.addSingleCount(errorsRollupMetric.all, forced)
 		metrics.addSingleCount(errorsRollupMetric.webOrOther(args.IsWeb), forced)
 		metrics.addSingleCount(errorsPrefix+args.FinalName, forced)
@@ -358,6 +431,7 @@ var (
 			true,
 			false,
 			internal.MaxLogEvents,
+			false,
 		},
 	}
 )
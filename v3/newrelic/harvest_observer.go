@@ -0,0 +1,63 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"sort"
+	"time"
+)
+
+// HarvestObserver is given a read-only copy of the data in each harvest
+// before it is sent to New Relic, eg. to mirror the agent's metrics into
+// another monitoring system.  Assign it to Config.Harvest.Observer.
+type HarvestObserver interface {
+	// ObserveMetrics is called with the metrics of each metric harvest,
+	// covering the period from start to end.  Metrics which could not be
+	// sent are retried with the following harvest, so after a failed
+	// harvest the metrics of both periods are observed together.
+	// ObserveMetrics is called synchronously in the harvest goroutine and
+	// must not block.
+	ObserveMetrics(start, end time.Time, metrics []HarvestMetric)
+}
+
+// HarvestMetric is a metric aggregated over a harvest period.  Durations
+// are in seconds.  Apdex metrics, whose names begin with "Apdex", instead
+// use Count, Total, and Exclusive for the number of satisfied, tolerated,
+// and frustrated transactions.
+type HarvestMetric struct {
+	Name string
+	// Scope is the name of the transaction for scoped metrics, and empty
+	// for unscoped metrics.
+	Scope      string
+	Count      float64
+	Total      float64
+	Exclusive  float64
+	Min        float64
+	Max        float64
+	SumSquares float64
+}
+
+// observe gives the metrics to the observer, sorted by name and scope.
+func (mt *metricTable) observe(o HarvestObserver, end time.Time) {
+	metrics := make([]HarvestMetric, 0, len(mt.metrics))
+	for id, m := range mt.metrics {
+		metrics = append(metrics, HarvestMetric{
+			Name:       id.Name,
+			Scope:      id.Scope,
+			Count:      m.data.countSatisfied,
+			Total:      m.data.totalTolerated,
+			Exclusive:  m.data.exclusiveFailed,
+			Min:        m.data.min,
+			Max:        m.data.max,
+			SumSquares: m.data.sumSquares,
+		})
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Name != metrics[j].Name {
+			return metrics[i].Name < metrics[j].Name
+		}
+		return metrics[i].Scope < metrics[j].Scope
+	})
+	o.ObserveMetrics(mt.metricPeriodStart, end, metrics)
+}
@@ -0,0 +1,46 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type harvestObserverRecorder struct {
+	start, end time.Time
+	metrics    []HarvestMetric
+}
+
+func (r *harvestObserverRecorder) ObserveMetrics(start, end time.Time, metrics []HarvestMetric) {
+	r.start = start
+	r.end = end
+	r.metrics = metrics
+}
+
+func TestMetricTableObserve(t *testing.T) {
+	start := time.Unix(1417136460, 0)
+	end := start.Add(time.Minute)
+	mt := newMetricTable(20, start)
+	mt.addDuration("WebTransaction/Go/hello", "", 2*time.Second, time.Second, forced)
+	mt.addDuration("Custom/segment", "WebTransaction/Go/hello", time.Second, time.Second, unforced)
+	mt.addDuration("Custom/segment", "", time.Second, time.Second, unforced)
+	mt.addApdex("Apdex", "", time.Second, apdexTolerating, forced)
+
+	r := &harvestObserverRecorder{}
+	mt.observe(r, end)
+	if !r.start.Equal(start) || !r.end.Equal(end) {
+		t.Error(r.start, r.end)
+	}
+	expect := []HarvestMetric{
+		{Name: "Apdex", Count: 0, Total: 1, Exclusive: 0, Min: 1, Max: 1},
+		{Name: "Custom/segment", Count: 1, Total: 1, Exclusive: 1, Min: 1, Max: 1, SumSquares: 1},
+		{Name: "Custom/segment", Scope: "WebTransaction/Go/hello", Count: 1, Total: 1, Exclusive: 1, Min: 1, Max: 1, SumSquares: 1},
+		{Name: "WebTransaction/Go/hello", Count: 1, Total: 2, Exclusive: 1, Min: 2, Max: 2, SumSquares: 4},
+	}
+	if !reflect.DeepEqual(r.metrics, expect) {
+		t.Errorf("%+v", r.metrics)
+	}
+}
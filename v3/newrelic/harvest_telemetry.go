@@ -0,0 +1,46 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"strconv"
+	"time"
+)
+
+// harvestTelemetry describes one harvest cycle when Config.HarvestTelemetry
+// is enabled.  It is merged into the following harvest as supportability
+// metrics.
+type harvestTelemetry struct {
+	duration time.Duration
+	requests []harvestRequestTelemetry
+}
+
+// harvestRequestTelemetry describes a single collector request of a harvest.
+type harvestRequestTelemetry struct {
+	method     string
+	duration   time.Duration
+	bytes      int
+	statusCode int
+}
+
+// harvestResponseName returns the suffix used for the response metric of a
+// request.  Requests which failed without a response, for example because of
+// a network error, are recorded as "Error".
+func harvestResponseName(statusCode int) string {
+	if 0 == statusCode {
+		return "Error"
+	}
+	return strconv.Itoa(statusCode)
+}
+
+// MergeIntoHarvest implements harvestable.
+func (t *harvestTelemetry) MergeIntoHarvest(h *harvest) {
+	h.Metrics.addDuration(supportHarvestDuration, "", t.duration, t.duration, forced)
+	for _, r := range t.requests {
+		prefix := supportHarvestPrefix + r.method
+		h.Metrics.addDuration(prefix+"/Duration", "", r.duration, r.duration, forced)
+		h.Metrics.addValue(prefix+"/Bytes", "", float64(r.bytes), forced)
+		h.Metrics.addSingleCount(prefix+"/Response/"+harvestResponseName(r.statusCode), forced)
+	}
+}
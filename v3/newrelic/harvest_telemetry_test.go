@@ -0,0 +1,34 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestHarvestTelemetryMergeIntoHarvest(t *testing.T) {
+	h := newHarvest(time.Now(), testHarvestCfgr)
+	telemetry := &harvestTelemetry{
+		duration: 3 * time.Second,
+		requests: []harvestRequestTelemetry{
+			{method: cmdMetrics, duration: time.Second, bytes: 100, statusCode: 202},
+			{method: cmdCustomEvents, duration: 2 * time.Second, bytes: 50, statusCode: 500},
+			{method: cmdCustomEvents, duration: time.Second, bytes: 50, statusCode: 0},
+		},
+	}
+	telemetry.MergeIntoHarvest(h)
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: "Supportability/Go/Harvest/Duration", Scope: "", Forced: true, Data: []float64{1, 3, 3, 3, 3, 9}},
+		{Name: "Supportability/Go/Harvest/metric_data/Duration", Scope: "", Forced: true, Data: []float64{1, 1, 1, 1, 1, 1}},
+		{Name: "Supportability/Go/Harvest/metric_data/Bytes", Scope: "", Forced: true, Data: []float64{1, 100, 100, 100, 100, 10000}},
+		{Name: "Supportability/Go/Harvest/metric_data/Response/202", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: "Supportability/Go/Harvest/custom_event_data/Duration", Scope: "", Forced: true, Data: []float64{2, 3, 3, 1, 2, 5}},
+		{Name: "Supportability/Go/Harvest/custom_event_data/Bytes", Scope: "", Forced: true, Data: []float64{2, 100, 100, 50, 50, 5000}},
+		{Name: "Supportability/Go/Harvest/custom_event_data/Response/500", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: "Supportability/Go/Harvest/custom_event_data/Response/Error", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
@@ -302,7 +302,7 @@ func TestHarvestCustomEventsReady(t *testing.T) {
 		MaxCustomEvents: 3,
 	})
 	params := map[string]interface{}{"zip": 1}
-	ce, _ := createCustomEvent("myEvent", params, time.Now())
+	ce, _ := createCustomEvent("myEvent", params, time.Now(), defaultAttributeLimits)
 	h.CustomEvents.Add(ce)
 	ready := h.Ready(now.Add(10 * time.Second))
 	payloads := ready.Payloads(true)
@@ -350,6 +350,7 @@ func TestHarvestLogEventsReady(t *testing.T) {
 		"User 'xyz' logged in",
 		"123456789ADF",
 		"ADF09876565",
+		nil,
 	}
 
 	h.LogEvents.Add(&logEvent)
@@ -385,6 +386,8 @@ func TestHarvestLogEventsReady(t *testing.T) {
 		{Name: logsSeen, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
 		{Name: logsSeen + "/" + logEvent.severity, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
 		{Name: logsDropped, Scope: "", Forced: true, Data: []float64{0, 0, 0, 0, 0, 0}},
+		{Name: logEventsSeen, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: logEventsSent, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
 	})
 }
 
@@ -479,6 +482,32 @@ func TestHarvestErrorEventsReady(t *testing.T) {
 	})
 }
 
+func TestHarvestErrorEventsClassLimited(t *testing.T) {
+	now := time.Now()
+	h := newHarvest(now, harvestConfig{
+		ReportPeriods:          map[harvestTypes]time.Duration{harvestTypesAll: fixedHarvestPeriod},
+		MaxErrorEvents:         10,
+		MaxErrorEventsPerClass: 1,
+	})
+	for i := 0; i < 3; i++ {
+		h.ErrorEvents.Add(&errorEvent{
+			errorData: errorData{Klass: "klass", Msg: "msg", When: now},
+		}, 0)
+	}
+	ready := h.Ready(now.Add(61 * time.Second))
+	if n := ready.ErrorEvents.NumSaved(); n != 1 {
+		t.Error(n)
+	}
+	if h.ErrorEvents.perClassLimit != 1 || h.ErrorEvents.classLimited != 0 {
+		t.Error("error events not correctly reset")
+	}
+	expectMetricsPresent(t, ready.Metrics, []internal.WantMetric{
+		{Name: errorEventsSeen, Scope: "", Forced: true, Data: []float64{3, 0, 0, 0, 0, 0}},
+		{Name: errorEventsSent, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: errorEventsClassLimited, Scope: "", Forced: true, Data: []float64{2, 0, 0, 0, 0, 0}},
+	})
+}
+
 func TestHarvestSpanEventsReady(t *testing.T) {
 	now := time.Now()
 	fixedHarvestTypes := harvestMetricsTraces & harvestCustomEvents & harvestTxnEvents & harvestErrorEvents
@@ -622,11 +651,12 @@ func TestMergeFailedHarvest(t *testing.T) {
 		"User 'xyz' logged in",
 		"123456789ADF",
 		"ADF09876565",
+		nil,
 	}
 
 	h.LogEvents.Add(&logEvent)
 	customEventParams := map[string]interface{}{"zip": 1}
-	ce, err := createCustomEvent("myEvent", customEventParams, time.Now())
+	ce, err := createCustomEvent("myEvent", customEventParams, time.Now(), defaultAttributeLimits)
 	if nil != err {
 		t.Fatal(err)
 	}
@@ -1171,3 +1201,17 @@ func TestConfigurableHarvestZeroHarvestLimits(t *testing.T) {
 		}
 	}
 }
+
+func TestHarvestFailedMetricLimits(t *testing.T) {
+	now := time.Now()
+	cfg := testHarvestCfgr
+	cfg.FailedMetrics = failedMetricLimits{maxMetrics: 3, maxAge: time.Minute}
+	h := newHarvest(now, cfg)
+	if h.Metrics.failedLimits != cfg.FailedMetrics {
+		t.Error(h.Metrics.failedLimits)
+	}
+	h.Ready(now.Add(61 * time.Second))
+	if h.Metrics.failedLimits != cfg.FailedMetrics {
+		t.Error(h.Metrics.failedLimits)
+	}
+}
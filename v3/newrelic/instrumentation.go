@@ -5,6 +5,7 @@ package newrelic
 
 import (
 	"net/http"
+	"strings"
 )
 
 // instrumentation.go contains helpers built on the lower level api.
@@ -111,28 +112,131 @@ func WrapHandleFunc(app *Application, pattern string, handler func(http.Response
 	return p, func(w http.ResponseWriter, r *http.Request) { h.ServeHTTP(w, r) }
 }
 
+// WrapServeMux instruments every handler of an http.ServeMux with
+// Transactions.  Each Transaction is named using the request method and the
+// pattern of the handler matching the request, which is the pattern Go 1.23
+// sets as http.Request.Pattern.  The method of Go 1.22 method patterns is
+// removed, so the handler registered as "GET /orders/{id}" names transactions
+// "GET /orders/{id}" rather than "GET GET /orders/{id}".  Requests matching no
+// handler are named "NotFound".  To instrument this code:
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("GET /orders/{id}", orderHandler)
+//	http.ListenAndServe(":8000", mux)
+//
+// Perform this replacement:
+//
+//	http.ListenAndServe(":8000", newrelic.WrapServeMux(app, mux))
+//
+// WrapServeMux adds the Transaction to the request's context.  Access it using
+// FromContext.  The WrapServeMux function is safe to call if app is nil.
+func WrapServeMux(app *Application, mux *http.ServeMux, options ...TraceOption) http.Handler {
+	if app == nil {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		txn := app.StartTransaction(serveMuxTransactionName(r.Method, pattern), options...)
+		defer txn.End()
+
+		w = txn.SetWebResponse(w)
+		txn.SetWebRequestHTTP(r)
+
+		r = RequestWithTransactionContext(r, txn)
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// serveMuxTransactionName returns the name of a transaction whose request
+// matched the http.ServeMux pattern.
+func serveMuxTransactionName(method, pattern string) string {
+	if pattern == "" {
+		return "NotFound"
+	}
+	// Go 1.22 patterns have the form "[METHOD ][HOST]/[PATH]".
+	if idx := strings.IndexAny(pattern, " \t"); idx >= 0 {
+		pattern = strings.TrimLeft(pattern[idx:], " \t")
+	}
+	return method + " " + pattern
+}
+
 // NewRoundTripper creates an http.RoundTripper to instrument external requests
 // and add distributed tracing headers.  The http.RoundTripper returned creates
 // an external segment before delegating to the original http.RoundTripper
 // provided (or http.DefaultTransport if none is provided).  The
 // http.RoundTripper will look for a Transaction in the request's context
 // (using FromContext).
+//
+// The redirects followed by an http.Client, and the retries of a failed
+// request made on the same goroutine with the same method and URL, are
+// grouped into a single logical call: each external segment is given its
+// retry and redirect counts, and when the transaction ends a span named after
+// the first request is added as the parent of the requests' spans, with the
+// total number of attempts and redirects.  To group requests explicitly, eg.
+// retries to different URLs, use StartExternalCall: the external segments of
+// requests made with its context are recorded as children of its segment.
 func NewRoundTripper(original http.RoundTripper) http.RoundTripper {
 	if nil == original {
 		original = http.DefaultTransport
 	}
-	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
-		// The specification of http.RoundTripper requires that the request is never modified.
-		request = cloneRequest(request)
-		segment := StartExternalSegment(nil, request)
+	return &roundTripper{original: original}
+}
 
-		response, err := original.RoundTrip(request)
+// roundTripper is the http.RoundTripper returned by NewRoundTripper.
+type roundTripper struct {
+	original http.RoundTripper
+}
+
+func (rt *roundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	// The specification of http.RoundTripper requires that the request is never modified.
+	request = cloneRequest(request)
+	segment := StartExternalSegmentFromContext(request.Context(), request)
+	call := externalCallFromContext(request.Context())
+	thd := segment.StartTime.thread
+	if nil != call {
+		segment.Outcome = call.request(request)
+	} else if nil != thd {
+		segment.group, segment.Outcome = thd.startRoundTrip(request)
+	}
 
-		segment.Response = response
-		segment.End()
+	response, err := rt.original.RoundTrip(request)
 
-		return response, err
-	})
+	failure := externalFailure(response, err)
+	if nil != call {
+		segment.Outcome.Failure = failure
+	}
+	segment.Response = response
+	segment.End()
+	if nil != segment.group {
+		thd.endRoundTrip(segment.group, response, "" != failure)
+	}
+
+	return response, err
+}
+
+// WrapClient returns a copy of the http.Client whose Transport is wrapped
+// using NewRoundTripper, so that every request made with a context carrying
+// a Transaction is recorded as an external segment:
+//
+//	client := newrelic.WrapClient(http.DefaultClient)
+//	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+//	resp, err := client.Do(req)
+//
+// Library code that is only given a context can use the returned client
+// without access to the Transaction.  The client is returned unchanged if
+// its Transport is already instrumented.  A nil client is treated as
+// http.DefaultClient.
+func WrapClient(client *http.Client) *http.Client {
+	if nil == client {
+		client = http.DefaultClient
+	}
+	if _, ok := client.Transport.(*roundTripper); ok {
+		return client
+	}
+	wrapped := *client
+	wrapped.Transport = NewRoundTripper(client.Transport)
+	return &wrapped
 }
 
 // cloneRequest mimics implementation of
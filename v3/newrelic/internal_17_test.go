@@ -56,6 +56,7 @@ func TestWrapHandleFunc(t *testing.T) {
 		{Name: "WebTransactionTotalTime/Go/GET /hello", Scope: "", Forced: false, Data: nil},
 		{Name: "WebTransactionTotalTime", Scope: "", Forced: true, Data: nil},
 		{Name: "HttpDispatcher", Scope: "", Forced: true, Data: nil},
+		{Name: "HttpDispatcher/StatusClass/2xx", Scope: "", Forced: true, Data: singleCount},
 		{Name: "Apdex", Scope: "", Forced: true, Data: nil},
 		{Name: "Apdex/Go/GET /hello", Scope: "", Forced: false, Data: nil},
 		{Name: "Errors/all", Scope: "", Forced: true, Data: singleCount},
@@ -102,6 +103,7 @@ func TestWrapHandle(t *testing.T) {
 		{Name: "WebTransactionTotalTime/Go/GET /hello", Scope: "", Forced: false, Data: nil},
 		{Name: "WebTransactionTotalTime", Scope: "", Forced: true, Data: nil},
 		{Name: "HttpDispatcher", Scope: "", Forced: true, Data: nil},
+		{Name: "HttpDispatcher/StatusClass/2xx", Scope: "", Forced: true, Data: singleCount},
 		{Name: "Apdex", Scope: "", Forced: true, Data: nil},
 		{Name: "Apdex/Go/GET /hello", Scope: "", Forced: false, Data: nil},
 		{Name: "Errors/all", Scope: "", Forced: true, Data: singleCount},
@@ -123,6 +125,49 @@ func TestWrapHandleNilApp(t *testing.T) {
 	}
 }
 
+func TestWrapServeMux(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	mux := http.NewServeMux()
+	mux.HandleFunc(helloPath, myErrorHandler)
+	w := newCompatibleResponseRecorder()
+	WrapServeMux(app.Application, mux).ServeHTTP(w, helloRequest)
+
+	if out := w.Body.String(); "my response" != out {
+		t.Error(out)
+	}
+	app.ExpectErrors(t, []internal.WantError{
+		{
+			TxnName: "WebTransaction/Go/GET /hello",
+			Msg:     "my msg",
+			Klass:   "newrelic.myError",
+		},
+	})
+}
+
+func TestWrapServeMuxNilApp(t *testing.T) {
+	var app *Application
+	mux := http.NewServeMux()
+	if h := WrapServeMux(app, mux); h != mux {
+		t.Error(h)
+	}
+}
+
+func TestServeMuxTransactionName(t *testing.T) {
+	testcases := []struct {
+		method, pattern, expect string
+	}{
+		{method: "GET", pattern: "", expect: "NotFound"},
+		{method: "GET", pattern: "/hello", expect: "GET /hello"},
+		{method: "GET", pattern: "GET /orders/{id}", expect: "GET /orders/{id}"},
+		{method: "HEAD", pattern: "GET  example.com/orders/", expect: "HEAD example.com/orders/"},
+	}
+	for _, tc := range testcases {
+		if name := serveMuxTransactionName(tc.method, tc.pattern); name != tc.expect {
+			t.Errorf("%s %q: got %q, expected %q", tc.method, tc.pattern, name, tc.expect)
+		}
+	}
+}
+
 func TestRoundTripper(t *testing.T) {
 	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
 	txn := app.StartTransaction("hello")
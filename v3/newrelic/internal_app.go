@@ -5,6 +5,7 @@ package newrelic
 
 import (
 	"compress/gzip"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rainforestpay/go-agent/v3/internal"
@@ -23,6 +25,13 @@ type appData struct {
 	data harvestable
 }
 
+// collectorError is a collector response which ends the run it was received
+// for.  The id is empty for responses to connect attempts.
+type collectorError struct {
+	id   internal.AgentRunID
+	resp rpmResponse
+}
+
 type app struct {
 	Logger
 	config      config
@@ -50,11 +59,13 @@ type app struct {
 	// Sends to these channels should not occur without a <-shutdownStarted
 	// select option to prevent deadlock.
 	dataChan           chan appData
-	collectorErrorChan chan rpmResponse
+	collectorErrorChan chan collectorError
 	connectChan        chan *appRun
+	remoteConfigChan   chan *internal.ServerSideConfig
 
 	// This mutex protects both `run` and `err`, both of which should only
-	// be accessed using getState and setState.
+	// be accessed using getState and setState.  It also protects
+	// remoteConfig.
 	sync.RWMutex
 	// run is non-nil when the app is successfully connected.  It is
 	// immutable.
@@ -62,60 +73,110 @@ type app struct {
 	// err is non-nil if the application will never be connected again
 	// (disconnect, license exception, shutdown).
 	err error
+	// remoteConfig holds the settings last read from the
+	// Config.RemoteConfig source, see runConfig.
+	remoteConfig *internal.ServerSideConfig
 
 	serverless *serverlessHarvest
+
+	// debugCapture is nil unless Config.DebugCapture is configured.
+	debugCapture *debugCapture
+
+	// customEventsStored approximates the number of custom events added to
+	// the current harvest's reservoir.  It is reset when custom events are
+	// harvested and must be accessed atomically.
+	customEventsStored int64
+
+	connectAttempts connectAttempts
+
+	// pendingCustomEvents and eventAPISends are used by the custom event
+	// Event API fallback.  eventAPISends tracks the requests in progress
+	// so that they can complete before shutdown.  eventAPISendsLock guards
+	// eventAPISendsClosed, which is set once shutdown waits for the
+	// requests, after which no request is started.
+	pendingCustomEvents pendingCustomEvents
+	eventAPISends       sync.WaitGroup
+	eventAPISendsLock   sync.Mutex
+	eventAPISendsClosed bool
 }
 
 func (app *app) doHarvest(h *harvest, harvestStart time.Time, run *appRun) {
+	app.reservoirDataDropped(h)
 	h.CreateFinalMetrics(run, app.getObserver())
+	if o := app.config.Harvest.Observer; nil != o && nil != h.Metrics {
+		h.Metrics.observe(o, harvestStart)
+	}
 
-	payloads := h.Payloads(app.config.DistributedTracer.Enabled)
-	for _, p := range payloads {
-		cmd := p.EndpointMethod()
-		var data []byte
-
+	var telemetry *harvestTelemetry
+	if app.config.HarvestTelemetry.Enabled {
+		telemetry = &harvestTelemetry{}
 		defer func() {
-			if r := recover(); r != nil {
-				app.Warn("panic occured when creating harvest data", map[string]interface{}{
-					"cmd":   cmd,
-					"panic": r,
-				})
-
-				// make sure the loop continues
-				data = nil
-			}
+			telemetry.duration = app.config.Clock.Now().Sub(harvestStart)
+			app.Consume(run.Reply.RunID, telemetry)
 		}()
+	}
 
-		data, err := p.Data(run.Reply.RunID.String(), harvestStart)
+	usage := &dataUsage{}
+	defer func() {
+		if len(usage.requests) > 0 {
+			app.Consume(run.Reply.RunID, usage)
+		}
+	}()
 
-		if err != nil {
-			app.Warn("unable to create harvest data", map[string]interface{}{
-				"cmd":   cmd,
-				"error": err.Error(),
-			})
+	payloads := h.Payloads(app.config.DistributedTracer.Enabled)
+	stop := make(chan struct{})
+	inFlight := make(chan struct{}, maxHarvestRequests(app.config))
+	requests := app.sendHarvestPayloads(payloads, harvestStart, run, inFlight, stop)
+
+	// The responses are handled in the order of the payloads, so that the
+	// data retained is merged into the harvest by this goroutine only.
+	var ended *rpmResponse
+	for _, req := range requests {
+		<-req.done
+		if !req.started {
+			// The request was not started because an earlier response
+			// ended the harvest.  After a restart exception the
+			// payload is sent once the application has reconnected.
+			if nil != ended && ended.IsRestartException() {
+				app.Consume(run.Reply.RunID, req.payload)
+			} else if cs, ok := req.payload.(*customEvents); ok {
+				// The application is disconnected.
+				app.sendEventAPIFallback(cs, nil)
+			}
 			continue
 		}
-		if data == nil {
+		if !req.sent {
 			continue
 		}
 
-		call := rpmCmd{
-			Collector:         run.Reply.Collector,
-			RunID:             run.Reply.RunID.String(),
-			Name:              cmd,
-			Data:              data,
-			RequestHeadersMap: run.Reply.RequestHeadersMap,
-			MaxPayloadSize:    run.Reply.MaxPayloadSizeInBytes,
+		cmd := req.payload.EndpointMethod()
+		resp := req.resp
+		usage.add(cmd, req.bytes, resp)
+		if nil != telemetry {
+			telemetry.requests = append(telemetry.requests, harvestRequestTelemetry{
+				method:     cmd,
+				duration:   req.duration,
+				bytes:      req.bytes,
+				statusCode: resp.statusCode,
+			})
 		}
 
-		resp := collectorRequest(call, app.rpmControls)
-
-		if resp.IsDisconnect() || resp.IsRestartException() {
-			select {
-			case app.collectorErrorChan <- resp:
-			case <-app.shutdownStarted:
+		endsHarvest := resp.IsDisconnect() || resp.IsRestartException()
+		if endsHarvest && nil == ended {
+			ended = &resp
+			close(stop)
+		}
+		// The request's slot is released once its response has been
+		// checked, so that a limit of one request sends the payloads
+		// serially and no request is started after the harvest ended.
+		<-inFlight
+		if endsHarvest {
+			if resp.IsRestartException() {
+				app.Consume(run.Reply.RunID, req.payload)
+			} else if cs, ok := req.payload.(*customEvents); ok {
+				app.sendEventAPIFallback(cs, nil)
 			}
-			return
+			continue
 		}
 
 		if resp.Err != nil {
@@ -124,30 +185,194 @@ func (app *app) doHarvest(h *harvest, harvestStart time.Time, run *appRun) {
 				"error":       resp.Err.Error(),
 				"retain_data": resp.ShouldSaveHarvestData(),
 			})
+			if event := app.lifecycleEvent(run, lifecycleHarvestFailure, resp.Err.Error(), map[string]interface{}{
+				"endpoint":   cmd,
+				"statusCode": resp.statusCode,
+				"retainData": resp.ShouldSaveHarvestData(),
+			}); nil != event {
+				app.Consume(run.Reply.RunID, event)
+			}
 		}
 
-		if resp.ShouldSaveHarvestData() {
-			app.Consume(run.Reply.RunID, p)
+		retain := resp.ShouldSaveHarvestData() && !retriesExhausted(req.payload)
+		if cs, ok := req.payload.(*customEvents); ok && nil != resp.Err && app.eventAPIFallback(cs, func() {
+			if retain {
+				app.Consume(run.Reply.RunID, cs)
+			} else {
+				app.harvestDataDropped(cs)
+			}
+		}) {
+			continue
+		}
+
+		if retain {
+			app.Consume(run.Reply.RunID, req.payload)
+		} else if nil != resp.Err {
+			app.harvestDataDropped(req.payload)
+		}
+	}
+
+	if nil != ended {
+		select {
+		case app.collectorErrorChan <- collectorError{id: run.Reply.RunID, resp: *ended}:
+		case <-app.shutdownStarted:
 		}
 	}
 }
 
+// harvestRequest is the request sending one payload of a harvest.
+type harvestRequest struct {
+	payload payloadCreator
+	// done is closed once the request has completed or will not be
+	// started.
+	done chan struct{}
+	// started is false if the request was not started because the
+	// harvest ended, and sent is false if the payload had no data.
+	started  bool
+	sent     bool
+	bytes    int
+	duration time.Duration
+	resp     rpmResponse
+}
+
+func maxHarvestRequests(c config) int {
+	if c.Harvest.MaxConcurrentRequests < 1 {
+		return 1
+	}
+	return c.Harvest.MaxConcurrentRequests
+}
+
+// sendHarvestPayloads sends the payloads in order, each request taking a slot
+// of inFlight which the caller releases.  The data of each payload is created
+// before a slot is taken, so that payloads without data never take one.  The
+// first payload with data is sent alone, and the others are only sent once
+// its response has neither disconnected nor restarted the application.
+// Requests not yet started once stop is closed are not started.
+func (app *app) sendHarvestPayloads(payloads []payloadCreator, harvestStart time.Time, run *appRun, inFlight chan struct{}, stop <-chan struct{}) []*harvestRequest {
+	requests := make([]*harvestRequest, len(payloads))
+	for i, p := range payloads {
+		requests[i] = &harvestRequest{payload: p, done: make(chan struct{})}
+	}
+
+	go func() {
+		var first *harvestRequest
+		for _, req := range requests {
+			if nil != first {
+				<-first.done
+				if first.resp.IsDisconnect() || first.resp.IsRestartException() {
+					close(req.done)
+					continue
+				}
+			}
+			select {
+			case <-stop:
+				close(req.done)
+				continue
+			default:
+			}
+			req.started = true
+			data := app.harvestPayloadData(req.payload, harvestStart, run)
+			if nil == data {
+				close(req.done)
+				continue
+			}
+			inFlight <- struct{}{}
+			select {
+			case <-stop:
+				<-inFlight
+				req.started = false
+				close(req.done)
+				continue
+			default:
+			}
+			if nil == first {
+				first = req
+			}
+			go func(req *harvestRequest) {
+				defer close(req.done)
+				app.sendHarvestPayload(req, data, harvestStart, run)
+			}(req)
+		}
+	}()
+	return requests
+}
+
+// harvestPayloadData returns the data of the payload, or nil if it has no
+// data or its data could not be created.
+func (app *app) harvestPayloadData(p payloadCreator, harvestStart time.Time, run *appRun) (data []byte) {
+	cmd := p.EndpointMethod()
+	defer func() {
+		if r := recover(); r != nil {
+			app.Warn("panic occured when creating harvest data", map[string]interface{}{
+				"cmd":   cmd,
+				"panic": r,
+			})
+			data = nil
+		}
+	}()
+
+	data, err := p.Data(run.Reply.RunID.String(), harvestStart)
+	if err != nil {
+		app.Warn("unable to create harvest data", map[string]interface{}{
+			"cmd":   cmd,
+			"error": err.Error(),
+		})
+		return nil
+	}
+	return data
+}
+
+func (app *app) sendHarvestPayload(req *harvestRequest, data []byte, harvestStart time.Time, run *appRun) {
+	cmd := req.payload.EndpointMethod()
+	if err := app.debugCapture.capture(cmd, harvestStart, data); nil != err {
+		app.Warn("unable to capture harvest data", map[string]interface{}{
+			"cmd":   cmd,
+			"error": err.Error(),
+		})
+	}
+
+	call := rpmCmd{
+		Collector:         run.Reply.Collector,
+		RunID:             run.Reply.RunID.String(),
+		Name:              cmd,
+		Data:              data,
+		RequestHeadersMap: run.Reply.RequestHeadersMap,
+		MaxPayloadSize:    run.Reply.MaxPayloadSizeInBytes,
+		Encoder:           run.payloadEncoder,
+	}
+
+	requestStart := app.config.Clock.Now()
+	req.resp = collectorRequest(call, app.rpmControls)
+	req.duration = app.config.Clock.Now().Sub(requestStart)
+	req.bytes = len(data)
+	req.sent = true
+}
+
 func (app *app) connectRoutine() {
 	attempts := 0
 	for {
 		reply, resp := connectAttempt(app.config, app.rpmControls)
+		attempts++
 
 		if reply != nil {
+			app.connectAttempts.record(ConnectAttempt{Attempt: attempts})
 			select {
-			case app.connectChan <- newAppRun(app.config, reply):
+			case app.connectChan <- newAppRun(app.runConfig(), reply):
 			case <-app.shutdownStarted:
 			}
 			return
 		}
 
+		attempt := ConnectAttempt{
+			Attempt:    attempts,
+			Err:        resp.Err,
+			Reason:     connectFailureReason(resp),
+			StatusCode: resp.statusCode,
+		}
 		if resp.IsDisconnect() {
+			app.connectAttempts.record(attempt)
 			select {
-			case app.collectorErrorChan <- resp:
+			case app.collectorErrorChan <- collectorError{resp: resp}:
 			case <-app.shutdownStarted:
 			}
 			return
@@ -155,13 +380,17 @@ func (app *app) connectRoutine() {
 
 		if nil != resp.Err {
 			app.Warn("application connect failure", map[string]interface{}{
-				"error": resp.Err.Error(),
+				"error":  resp.Err.Error(),
+				"reason": string(attempt.Reason),
 			})
 		}
 
-		backoff := getConnectBackoffTime(attempts)
-		time.Sleep(time.Duration(backoff) * time.Second)
-		attempts++
+		app.eventAPIConnectFallback(attempts)
+
+		backoff := app.config.connectBackoff(attempts - 1)
+		attempt.RetryIn = backoff
+		app.connectAttempts.record(attempt)
+		time.Sleep(backoff)
 	}
 }
 
@@ -196,15 +425,28 @@ func (app *app) connectTraceObserver(reply *internal.ConnectReply) {
 	app.setObserver(observer)
 }
 
-// Connect backoff time follows the sequence defined at
+// The default connect backoff follows the sequence defined at
 // https://source.datanerd.us/agents/agent-specs/blob/master/Collector-Response-Handling.md#retries-and-backoffs
-func getConnectBackoffTime(attempt int) int {
-	connectBackoffTimes := [...]int{15, 15, 30, 60, 120, 300}
-	l := len(connectBackoffTimes)
+var connectBackoffSchedule = []time.Duration{
+	15 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+	60 * time.Second,
+	120 * time.Second,
+	300 * time.Second,
+}
+
+const (
+	connectBackoffMax    = 5 * time.Minute
+	connectBackoffJitter = 0.2
+)
+
+func getConnectBackoffTime(schedule []time.Duration, attempt int) time.Duration {
+	l := len(schedule)
 	if (attempt < 0) || (attempt >= l) {
-		return connectBackoffTimes[l-1]
+		return schedule[l-1]
 	}
-	return connectBackoffTimes[attempt]
+	return schedule[attempt]
 }
 
 func processConnectMessages(run *appRun, lg Logger) {
@@ -226,26 +468,40 @@ func processConnectMessages(run *appRun, lg Logger) {
 }
 
 func (app *app) process() {
-	// Both the harvest and the run are non-nil when the app is connected,
-	// and nil otherwise.
+	// Both the harvest and the run are non-nil when the app is connected.
+	// While the app reconnects after a restart exception the harvest of the
+	// previous run is kept, and data for that run is still merged into it,
+	// so that it can be sent once the app has reconnected.
 	var h *harvest
 	var run *appRun
+	// restartedRunID and restartStatus identify the run ended by a restart
+	// exception while the app reconnects.
+	var restartedRunID internal.AgentRunID
+	var restartStatus int
 
-	harvestTicker := time.NewTicker(time.Second)
+	harvestTicker := app.config.Clock.NewTicker(time.Second)
 	defer harvestTicker.Stop()
 
 	for {
 		select {
-		case <-harvestTicker.C:
+		case <-harvestTicker.C():
+			if lim, ok := app.Logger.(*errorLogLimiter); ok {
+				lim.flush(app.config.Clock.Now())
+			}
 			if nil != run {
-				now := time.Now()
+				now := app.config.Clock.Now()
 				if ready := h.Ready(now); nil != ready {
+					if nil != ready.CustomEvents {
+						atomic.StoreInt64(&app.customEventsStored, 0)
+					}
 					go app.doHarvest(ready, now, run)
 				}
 			}
 		case d := <-app.dataChan:
 			if nil != run && run.Reply.RunID == d.id {
-				d.data.MergeIntoHarvest(h)
+				h.merge(d.data)
+			} else if nil == run && nil != h && "" != d.id && restartedRunID == d.id {
+				h.merge(d.data)
 			}
 		case timeout := <-app.initiateShutdown:
 			close(app.shutdownStarted)
@@ -267,35 +523,60 @@ func (app *app) process() {
 					select {
 					case d := <-app.dataChan:
 						if run.Reply.RunID == d.id {
-							d.data.MergeIntoHarvest(h)
+							h.merge(d.data)
 						}
 					default:
 						done = true
 					}
 				}
-				app.doHarvest(h, time.Now(), run)
+				app.recordLifecycleEvent(h, run, lifecycleShutdown, lifecycleReasonShutdown, nil)
+				app.doHarvest(h, app.config.Clock.Now(), run)
 			}
+			app.waitEventAPISends()
 
 			close(app.shutdownComplete)
 			app.setObserver(nil)
 			return
-		case resp := <-app.collectorErrorChan:
-			run = nil
-			h = nil
-			app.setState(nil, nil)
-
+		case ce := <-app.collectorErrorChan:
+			resp := ce.resp
 			if resp.IsDisconnect() {
+				// The data of the harvest is discarded, apart from
+				// the custom events which may be sent to the Event
+				// API.
+				if nil != h {
+					app.sendEventAPIFallback(h.CustomEvents, nil)
+				}
+				app.sendEventAPIFallback(app.pendingCustomEvents.take(), nil)
+				run = nil
+				h = nil
+				restartedRunID = ""
 				app.setState(nil, resp.Err)
 				app.Error("application disconnected", map[string]interface{}{
 					"app": app.config.AppName,
 				})
 			} else if resp.IsRestartException() {
+				if nil == run || run.Reply.RunID != ce.id {
+					// Another harvest of the same run has
+					// already caused the restart.
+					break
+				}
+				restartedRunID = run.Reply.RunID
+				restartStatus = resp.statusCode
+				run = nil
+				app.setState(nil, nil)
 				app.Info("application restarted", map[string]interface{}{
-					"app": app.config.AppName,
+					"app":         app.config.AppName,
+					"status_code": resp.statusCode,
 				})
 				go app.connectRoutine()
 			}
+		case ssc := <-app.remoteConfigChan:
+			run = app.applyRemoteConfig(run, ssc)
 		case run = <-app.connectChan:
+			if c := app.runConfig(); c.remoteConfig != run.Config.remoteConfig {
+				// The remote config changed while connecting.
+				run = newAppRun(c, run.Reply)
+			}
 			if shouldUseTraceObserver(run.Config) {
 				app.connectTraceObserver(run.Reply)
 			} else if shouldUseTraceObserver(app.config) {
@@ -313,8 +594,26 @@ func (app *app) process() {
 				entityGUID: run.Reply.EntityGUID,
 			}
 
-			h = newHarvest(time.Now(), run.harvestConfig)
+			previous := h
+			h = newHarvest(app.config.Clock.Now(), run.harvestConfig)
+			atomic.StoreInt64(&app.customEventsStored, 0)
+			if nil != previous {
+				for _, p := range previous.Payloads(false) {
+					h.merge(p)
+				}
+				h.Metrics.addSingleCount(supportCollectorRestart, forced)
+				h.Metrics.addSingleCount(fmt.Sprintf("%s/%d", supportCollectorHTTPError, restartStatus), forced)
+				app.recordLifecycleEvent(h, run, lifecycleReconnect, lifecycleReasonRestart, map[string]interface{}{
+					"statusCode": restartStatus,
+				})
+			} else {
+				app.recordLifecycleEvent(h, run, lifecycleConnect, lifecycleReasonStartup, nil)
+			}
+			restartedRunID = ""
 			app.setState(run, nil)
+			if pending := app.pendingCustomEvents.take(); nil != pending {
+				h.CustomEvents.Merge(pending.analyticsEvents)
+			}
 
 			app.Info("application connected", map[string]interface{}{
 				"app": app.config.AppName,
@@ -356,6 +655,10 @@ func (app *app) Shutdown(timeout time.Duration) {
 
 func runSampler(app *app, period time.Duration) {
 	previous := getSystemSample(time.Now(), app)
+	var previousDiagnostics *diagnosticsSample
+	if app.config.RuntimeSampler.Diagnostics.Enabled {
+		previousDiagnostics = getDiagnosticsSample(previous.when)
+	}
 	t := time.NewTicker(period)
 	for {
 		select {
@@ -367,6 +670,14 @@ func runSampler(app *app, period time.Duration) {
 				Current:  current,
 			}))
 			previous = current
+			if nil != previousDiagnostics {
+				currentDiagnostics := getDiagnosticsSample(now)
+				app.recordDiagnostics(run.Reply.RunID, getDiagnosticsStats(diagnosticsSamples{
+					Previous: previousDiagnostics,
+					Current:  currentDiagnostics,
+				}))
+				previousDiagnostics = currentDiagnostics
+			}
 		case <-app.shutdownStarted:
 			t.Stop()
 			return
@@ -375,43 +686,20 @@ func runSampler(app *app, period time.Duration) {
 }
 
 func (app *app) WaitForConnection(timeout time.Duration) error {
-	if nil == app {
-		return nil
-	}
-	if !app.config.Enabled {
-		return nil
-	}
-	if app.config.ServerlessMode.Enabled {
-		return nil
-	}
-	deadline := time.Now().Add(timeout)
-	pollPeriod := 50 * time.Millisecond
-
-	for {
-		run, err := app.getState()
-		if nil != err {
-			return err
-		}
-		if run.Reply.RunID != "" {
-			if shouldUseTraceObserver(run.Config) {
-				if obs := app.getObserver(); obs != nil && obs.initialConnCompleted() {
-					return nil
-				}
-			} else {
-				return nil
-			}
-		}
-		if time.Now().After(deadline) {
-			return fmt.Errorf("timeout out after %s", timeout.String())
-		}
-		time.Sleep(pollPeriod)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := app.WaitForConnectionCtx(ctx)
+	if e, ok := err.(connectWaitError); ok {
+		e.err = fmt.Errorf("timeout out after %s", timeout.String())
+		return e
 	}
+	return err
 }
 
 func newApp(c config) *app {
 	transport := c.Transport
 	if nil == transport {
-		transport = collectorDefaultTransport
+		transport = newCollectorTransport(c)
 	}
 	app := &app{
 		Logger:         c.Logger,
@@ -425,8 +713,10 @@ func newApp(c config) *app {
 		shutdownStarted:    make(chan struct{}),
 		shutdownComplete:   make(chan struct{}),
 		connectChan:        make(chan *appRun, 1),
-		collectorErrorChan: make(chan rpmResponse, 1),
+		remoteConfigChan:   make(chan *internal.ServerSideConfig, 1),
+		collectorErrorChan: make(chan collectorError, 1),
 		dataChan:           make(chan appData, appDataChanSize),
+		debugCapture:       newDebugCapture(c),
 		rpmControls: rpmControls{
 			License: c.License,
 			Client: &http.Client{
@@ -460,6 +750,12 @@ func newApp(c config) *app {
 			if app.config.RuntimeSampler.Enabled {
 				go runSampler(app, runtimeSamplerPeriod)
 			}
+			if app.config.StatsD.Enabled {
+				app.startStatsD()
+			}
+			if app.config.RemoteConfig.Enabled {
+				go app.remoteConfigRoutine()
+			}
 		}
 	}
 
@@ -481,7 +777,8 @@ func (app *app) HarvestTesting(replyfn func(*internal.ConnectReply)) {
 		replyfn(reply)
 		app.placeholderRun = newAppRun(app.config, reply)
 	}
-	app.testHarvest = newHarvest(time.Now(), app.placeholderRun.harvestConfig)
+	app.testHarvest = newHarvest(app.config.Clock.Now(), app.placeholderRun.harvestConfig)
+	atomic.StoreInt64(&app.customEventsStored, 0)
 }
 
 func (app *app) getState() (*appRun, error) {
@@ -528,7 +825,11 @@ func (app *app) StartTransaction(name string, opts ...TraceOption) *Transaction
 		return nil
 	}
 	run, _ := app.getState()
-	return newTransaction(newTxn(app, run, name, opts...))
+	txn := newTransaction(newTxn(app, run, name, opts...))
+	if sa := txn.thread.securityAgent(); nil != sa {
+		sa.TransactionStarted(txn)
+	}
+	return txn
 }
 
 var (
@@ -537,6 +838,39 @@ var (
 	errCustomEventsRemoteDisabled = errors.New("custom events disabled by server")
 )
 
+// customEventsAllowed returns an error if custom events may not currently be
+// recorded.
+func (app *app) customEventsAllowed(run *appRun) error {
+	if app.config.Config.HighSecurity {
+		return errHighSecurityEnabled
+	}
+	if !app.config.CustomInsightsEvents.Enabled {
+		return errCustomEventsDisabled
+	}
+	if !run.Reply.CollectCustomEvents {
+		return errCustomEventsRemoteDisabled
+	}
+	if !run.Reply.SecurityPolicies.CustomEvents.Enabled() {
+		return errSecurityPolicy
+	}
+	return nil
+}
+
+// reserveCustomEvent claims a place in the custom event reservoir, returning
+// false if the reservoir is full.  Serverless mode harvests after each
+// invocation so it is never considered full.
+func (app *app) reserveCustomEvent(run *appRun) bool {
+	stored := atomic.AddInt64(&app.customEventsStored, 1)
+	if app.config.ServerlessMode.Enabled {
+		return true
+	}
+	if stored > int64(run.harvestConfig.MaxCustomEvents) {
+		atomic.AddInt64(&app.customEventsStored, -1)
+		return false
+	}
+	return true
+}
+
 // RecordCustomEvent implements newrelic.Application's RecordCustomEvent.
 func (app *app) RecordCustomEvent(eventType string, params map[string]interface{}) error {
 	if nil == app {
@@ -550,30 +884,131 @@ func (app *app) RecordCustomEvent(eventType string, params map[string]interface{
 		return errCustomEventsDisabled
 	}
 
-	event, e := createCustomEvent(eventType, params, time.Now())
+	run, _ := app.getState()
+	event, e := createCustomEvent(eventType, params, app.config.Clock.Now(), run.Config.attributeLimits())
 	if nil != e {
 		return e
 	}
 
+	if err := app.customEventsAllowed(run); nil != err {
+		return err
+	}
+
+	// Single events are always sent to the reservoir, which samples them
+	// once it is full.  They are still counted so that batches submitted
+	// with RecordCustomEvents observe the reservoir filling up.
+	atomic.AddInt64(&app.customEventsStored, 1)
+	app.Consume(run.Reply.RunID, event)
+
+	return nil
+}
+
+// RecordCustomEvents implements newrelic.Application's RecordCustomEvents.
+// The error returned describes the first invalid event, or the reason that
+// no events could be recorded.
+func (app *app) RecordCustomEvents(events []CustomEventData) (accepted int, dropped int, err error) {
+	if nil == app {
+		return 0, 0, nil
+	}
+	if len(events) == 0 {
+		return 0, 0, nil
+	}
+
 	run, _ := app.getState()
-	if !run.Reply.CollectCustomEvents {
-		return errCustomEventsRemoteDisabled
+	if err := app.customEventsAllowed(run); nil != err {
+		return 0, len(events), err
 	}
 
-	if !run.Reply.SecurityPolicies.CustomEvents.Enabled() {
-		return errSecurityPolicy
+	now := app.config.Clock.Now()
+	limits := run.Config.attributeLimits()
+	batch := make(customEventBatch, 0, len(events))
+	for _, data := range events {
+		event, e := createCustomEvent(data.EventType, data.Params, now, limits)
+		if nil != e {
+			if nil == err {
+				err = e
+			}
+			dropped++
+			continue
+		}
+		if !app.reserveCustomEvent(run) {
+			dropped++
+			continue
+		}
+		batch = append(batch, event)
 	}
 
-	app.Consume(run.Reply.RunID, event)
+	if len(batch) > 0 {
+		app.Consume(run.Reply.RunID, batch)
+	}
+
+	return len(batch), dropped, err
+}
+
+var (
+	errErrorEventsDisabled = errors.New("error events disabled")
+)
 
+// NoticeError implements newrelic.Application's NoticeError.
+func (app *app) NoticeError(input error, opts noticeErrorOptions) error {
+	if nil == input {
+		return errNilError
+	}
+
+	run, _ := app.getState()
+	if !run.Config.ErrorCollector.Enabled {
+		return errorsDisabled
+	}
+	if !run.Config.ErrorCollector.CaptureEvents {
+		return errErrorEventsDisabled
+	}
+
+	data, err := errDataFromError(input, opts.expect, run.Config.attributeLimits())
+	if nil != err {
+		return err
+	}
+	if run.Config.errorIgnorer.ignored(data.Klass, data.Msg) {
+		return nil
+	}
+	// Error events do not include a stack trace.
+	data.Stack = nil
+	if opts.timestamp.IsZero() {
+		data.When = app.config.Clock.Now()
+	} else {
+		data.When = opts.timestamp
+	}
+	if run.Config.HighSecurity {
+		data.Msg = highSecurityErrorMsg
+	}
+	if !run.Reply.SecurityPolicies.AllowRawExceptionMessages.Enabled() {
+		data.Msg = securityPolicyErrorMsg
+	}
+	data.Msg = run.Config.scrubber.scrub(data.Msg)
+	if run.Config.HighSecurity || !run.Reply.SecurityPolicies.CustomParameters.Enabled() {
+		data.ExtraAttributes = nil
+	}
+
+	attrs := newAttributes(run.AttributeConfig)
+	attrs.Agent.Add(AttributeHostDisplayName, run.Config.HostDisplayName, nil)
+	deploymentMetadataAttributes(attrs, run.Config.Config)
+
+	app.Consume(run.Reply.RunID, &appErrorEvent{
+		errorEvent: errorEvent{
+			errorData: data,
+			txnEvent:  txnEvent{Attrs: attrs},
+		},
+		priority: newPriority(),
+	})
 	return nil
 }
 
 var (
-	errMetricInf        = errors.New("invalid metric value: inf")
-	errMetricNaN        = errors.New("invalid metric value: NaN")
-	errMetricNameEmpty  = errors.New("missing metric name")
-	errMetricServerless = errors.New("custom metrics are not currently supported in serverless mode")
+	errMetricInf          = errors.New("invalid metric value: inf")
+	errMetricNaN          = errors.New("invalid metric value: NaN")
+	errMetricNameEmpty    = errors.New("missing metric name")
+	errMetricServerless   = errors.New("custom metrics are not currently supported in serverless mode")
+	errMetricNameReserved = errors.New("metric name is in a namespace reserved by the agent")
+	errMetricNamePrefix   = errors.New("metric name does not have the prefix required by Config.CustomMetrics.RequiredPrefix")
 )
 
 // RecordCustomMetric implements newrelic.Application's RecordCustomMetric.
@@ -594,6 +1029,10 @@ func (app *app) RecordCustomMetric(name string, value float64) error {
 		return errMetricNameEmpty
 	}
 	run, _ := app.getState()
+	if err := validateCustomMetricName(name, app.config.CustomMetrics.RequiredPrefix); nil != err {
+		app.Consume(run.Reply.RunID, rejectedCustomMetric{})
+		return err
+	}
 	app.Consume(run.Reply.RunID, customMetric{
 		RawInputName: name,
 		Value:        value,
@@ -617,6 +1056,8 @@ func (app *app) RecordLog(log *LogData) error {
 	}
 
 	run, _ := app.getState()
+	event.message = app.config.scrubber.scrub(event.message)
+	event.attributes = run.Config.logAttributes(run.Reply, event.attributes)
 	app.Consume(run.Reply.RunID, &event)
 	return nil
 }
@@ -634,11 +1075,18 @@ func (app *app) Consume(id internal.AgentRunID, data harvestable) {
 	app.serverless.Consume(data)
 
 	if nil != app.testHarvest {
-		data.MergeIntoHarvest(app.testHarvest)
+		app.testHarvest.merge(data)
 		return
 	}
 
 	if "" == id {
+		// Custom events recorded while the application is connecting
+		// are kept for the Event API fallback.
+		if app.eventAPIFallbackEnabled() {
+			if _, err := app.getState(); nil == err {
+				app.pendingCustomEvents.add(data, app.config.maxCustomEvents())
+			}
+		}
 		return
 	}
 
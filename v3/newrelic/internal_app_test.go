@@ -4,8 +4,14 @@
 package newrelic
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -23,12 +29,175 @@ func TestConnectBackoff(t *testing.T) {
 	}
 
 	for k, v := range attempts {
-		if b := getConnectBackoffTime(k); b != v {
+		if b := getConnectBackoffTime(connectBackoffSchedule, k); b != time.Duration(v)*time.Second {
 			t.Error(fmt.Sprintf("Invalid connect backoff for attempt #%d:", k), v)
 		}
 	}
 }
 
+func TestConfigConnectBackoff(t *testing.T) {
+	cfg := config{Config: defaultConfig()}
+	cfg.Connect.Backoff.Jitter = 0
+	if b := cfg.connectBackoff(2); b != 30*time.Second {
+		t.Error(b)
+	}
+
+	cfg.Connect.Backoff.Schedule = []time.Duration{time.Second, 10 * time.Second}
+	cfg.Connect.Backoff.Max = 5 * time.Second
+	if b := cfg.connectBackoff(0); b != time.Second {
+		t.Error(b)
+	}
+	if b := cfg.connectBackoff(1); b != 5*time.Second {
+		t.Error(b)
+	}
+
+	cfg.Connect.Backoff.Schedule = nil
+	cfg.Connect.Backoff.Max = 0
+	if b := cfg.connectBackoff(10); b != 300*time.Second {
+		t.Error(b)
+	}
+}
+
+func TestConfigConnectBackoffJitter(t *testing.T) {
+	cfg := config{Config: defaultConfig()}
+	cfg.Connect.Backoff.Schedule = []time.Duration{100 * time.Second}
+	cfg.Connect.Backoff.Max = 0
+	cfg.Connect.Backoff.Jitter = 0.5
+	distinct := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		b := cfg.connectBackoff(0)
+		if b < 50*time.Second || b > 150*time.Second {
+			t.Fatal(b)
+		}
+		distinct[b] = true
+	}
+	if len(distinct) < 2 {
+		t.Error("backoff not jittered", distinct)
+	}
+
+	// The cap is applied before jitter, so that delays at the cap are
+	// still spread out.
+	cfg.Connect.Backoff.Schedule = []time.Duration{300 * time.Second}
+	cfg.Connect.Backoff.Max = 100 * time.Second
+	distinct = make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		b := cfg.connectBackoff(0)
+		if b < 50*time.Second || b > 150*time.Second {
+			t.Fatal(b)
+		}
+		distinct[b] = true
+	}
+	if len(distinct) < 2 {
+		t.Error("capped backoff not jittered", distinct)
+	}
+}
+
+func TestConnectFailureReason(t *testing.T) {
+	testcases := []struct {
+		resp   rpmResponse
+		expect ConnectFailureReason
+	}{
+		{resp: rpmResponse{Err: &net.DNSError{Err: "no such host", Name: "collector.newrelic.com"}}, expect: ConnectFailureDNS},
+		{resp: rpmResponse{Err: fmt.Errorf("wrapped: %w", &net.OpError{Op: "proxyconnect", Err: errors.New("refused")})}, expect: ConnectFailureProxy},
+		{resp: rpmResponse{Err: &net.OpError{Op: "dial", Err: errors.New("refused")}}, expect: ConnectFailureNetwork},
+		{resp: newRPMResponse(401), expect: ConnectFailureLicense},
+		{resp: newRPMResponse(410), expect: ConnectFailureDisconnect},
+		{resp: rpmResponse{Err: errors.New("bad reply"), disconnectSecurityPolicy: true}, expect: ConnectFailureDisconnect},
+		{resp: newRPMResponse(500), expect: ConnectFailureCollector},
+		{resp: rpmResponse{Err: errMissingAgentRunID}, expect: ConnectFailureCollector},
+	}
+	for _, tc := range testcases {
+		if reason := connectFailureReason(tc.resp); reason != tc.expect {
+			t.Error(tc.resp.Err, tc.resp.statusCode, reason, tc.expect)
+		}
+	}
+}
+
+func newFailingConnectApp(t *testing.T, rt http.RoundTripper) *Application {
+	app, err := NewApplication(
+		ConfigAppName(sampleAppName),
+		ConfigLicense(testLicenseKey),
+		func(cfg *Config) {
+			cfg.Transport = rt
+			cfg.Utilization.DetectAWS = false
+			cfg.Utilization.DetectAzure = false
+			cfg.Utilization.DetectGCP = false
+			cfg.Utilization.DetectPCF = false
+			cfg.Utilization.DetectDocker = false
+			cfg.Utilization.DetectKubernetes = false
+		},
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestWaitForConnectionCtxReportsFailure(t *testing.T) {
+	app := newFailingConnectApp(t, roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, &net.DNSError{Err: "no such host", Name: r.URL.Host}
+	}))
+	defer app.Shutdown(time.Second)
+
+	attempts := make(chan ConnectAttempt, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	err := app.WaitForConnectionCtx(ctx, WithConnectAttemptCallback(func(a ConnectAttempt) {
+		attempts <- a
+	}))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	if !strings.Contains(err.Error(), "connect attempt 1 failed (dns)") {
+		t.Error(err)
+	}
+	select {
+	case a := <-attempts:
+		if a.Attempt != 1 || a.Reason != ConnectFailureDNS || nil == a.Err || a.RetryIn < 12*time.Second || a.RetryIn > 18*time.Second {
+			t.Error(a)
+		}
+	default:
+		t.Error("callback not called")
+	}
+}
+
+func TestConnectAttemptsWatch(t *testing.T) {
+	var ca connectAttempts
+	ca.record(ConnectAttempt{Attempt: 1})
+	var seen []int
+	id := ca.watch(func(a ConnectAttempt) {
+		seen = append(seen, a.Attempt)
+	})
+	// Attempts recorded in quick succession are each passed to the
+	// callback.
+	ca.record(ConnectAttempt{Attempt: 2})
+	ca.record(ConnectAttempt{Attempt: 3})
+	ca.unwatch(id)
+	ca.record(ConnectAttempt{Attempt: 4})
+	if len(seen) != 3 || seen[0] != 1 || seen[1] != 2 || seen[2] != 3 {
+		t.Error(seen)
+	}
+}
+
+func TestWaitForConnectionTimeoutIncludesReason(t *testing.T) {
+	app := newFailingConnectApp(t, roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 401,
+			Body:       ioutil.NopCloser(strings.NewReader("")),
+		}, nil
+	}))
+	defer app.Shutdown(time.Second)
+
+	err := app.WaitForConnection(500 * time.Millisecond)
+	if nil == err {
+		t.Fatal("expected timeout error")
+	}
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "timeout out after 500ms") || !strings.Contains(msg, "(license)") {
+		t.Error(msg)
+	}
+}
+
 func TestNilApplication(t *testing.T) {
 	var app *Application
 	if txn := app.StartTransaction("name"); txn != nil {
@@ -39,6 +208,9 @@ func TestNilApplication(t *testing.T) {
 	if err := app.WaitForConnection(2 * time.Second); nil != err {
 		t.Error(err)
 	}
+	if err := app.WaitForConnectionCtx(context.Background()); nil != err {
+		t.Error(err)
+	}
 	app.Shutdown(2 * time.Second)
 }
 
@@ -52,6 +224,9 @@ func TestEmptyApplication(t *testing.T) {
 	if err := app.WaitForConnection(2 * time.Second); nil != err {
 		t.Error(err)
 	}
+	if err := app.WaitForConnectionCtx(context.Background()); nil != err {
+		t.Error(err)
+	}
 	app.Shutdown(2 * time.Second)
 }
 
@@ -148,3 +323,235 @@ func TestRecordLog(t *testing.T) {
 		},
 	})
 }
+
+func TestTransactionLogsDropped(t *testing.T) {
+	testApp := newTestApp(
+		sampleEverythingReplyFn,
+		configTestAppLogFn,
+		ConfigAppLogForwardingMaxSamplesStored(2),
+	)
+
+	txn := testApp.StartTransaction("hello")
+	for i := 0; i < 3; i++ {
+		txn.RecordLog(LogData{
+			Severity: "Info",
+			Message:  "Test Message",
+		})
+	}
+	txn.End()
+
+	h := testApp.Private.(*app).testHarvest
+	if seen, saved := h.LogEvents.NumSeen(), h.LogEvents.NumSaved(); seen != 3 || saved != 2 {
+		t.Errorf("expected 3 logs seen and 2 saved, got %v seen and %v saved", seen, saved)
+	}
+}
+
+func TestRecordLogAttributes(t *testing.T) {
+	testApp := newTestApp(
+		sampleEverythingReplyFn,
+		configTestAppLogFn,
+		ConfigAppLogForwardingAttributeAllowList("order_id", "user.*"),
+	)
+
+	testApp.Application.RecordLog(LogData{
+		Severity: "Info",
+		Message:  "Order placed",
+		Attributes: map[string]interface{}{
+			"order_id":   1234,
+			"user.email": "me@example.com",
+			"user.admin": false,
+			"username":   "me",
+			"user.bad":   struct{}{},
+		},
+	})
+	testApp.Application.RecordLog(LogData{
+		Severity: "Info",
+		Message:  "No attributes",
+		Attributes: map[string]interface{}{
+			"username": "me",
+		},
+	})
+
+	testApp.ExpectLogEvents(t, []internal.WantLog{
+		{
+			Severity:  "Info",
+			Message:   "Order placed",
+			Timestamp: internal.MatchAnyUnixMilli,
+			Attributes: map[string]interface{}{
+				"order_id":   1234,
+				"user.email": "me@example.com",
+				"user.admin": false,
+			},
+		},
+		{
+			Severity:   "Info",
+			Message:    "No attributes",
+			Timestamp:  internal.MatchAnyUnixMilli,
+			Attributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestRecordLogAttributesCustomParametersDisabled(t *testing.T) {
+	testApp := newTestApp(
+		func(reply *internal.ConnectReply) {
+			reply.SetSampleEverything()
+			reply.SecurityPolicies.CustomParameters.SetEnabled(false)
+		},
+		configTestAppLogFn,
+		ConfigAppLogForwardingAttributeAllowList("order_id"),
+	)
+
+	testApp.Application.RecordLog(LogData{
+		Severity:   "Info",
+		Message:    "Order placed",
+		Attributes: map[string]interface{}{"order_id": 1234},
+	})
+	txn := testApp.StartTransaction("hello")
+	txn.RecordLog(LogData{
+		Severity:   "Info",
+		Message:    "Order placed in txn",
+		Attributes: map[string]interface{}{"order_id": 1234},
+	})
+	md := txn.GetTraceMetadata()
+	txn.End()
+
+	testApp.ExpectLogEvents(t, []internal.WantLog{
+		{
+			Severity:   "Info",
+			Message:    "Order placed",
+			Timestamp:  internal.MatchAnyUnixMilli,
+			Attributes: map[string]interface{}{},
+		},
+		{
+			Severity:   "Info",
+			Message:    "Order placed in txn",
+			Timestamp:  internal.MatchAnyUnixMilli,
+			TraceID:    md.TraceID,
+			SpanID:     md.SpanID,
+			Attributes: map[string]interface{}{},
+		},
+	})
+}
+
+// harvestRequestTransport records the largest number of concurrent harvest
+// requests.
+type harvestRequestTransport struct {
+	sync.Mutex
+	status      int
+	requests    int
+	inFlight    int
+	maxInFlight int
+}
+
+func (rt *harvestRequestTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rt.Lock()
+	rt.requests++
+	rt.inFlight++
+	if rt.inFlight > rt.maxInFlight {
+		rt.maxInFlight = rt.inFlight
+	}
+	rt.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	rt.Lock()
+	rt.inFlight--
+	rt.Unlock()
+	return makeResponse(rt.status, `{"return_value":{}}`), nil
+}
+
+func harvestWithPayloads(app expectApp, rt *harvestRequestTransport) {
+	run, _ := app.app.getState()
+	app.app.rpmControls.Client = &http.Client{Transport: rt}
+	now := app.app.config.Clock.Now()
+	h := newHarvest(now, run.harvestConfig)
+	h.Metrics.addSingleCount("myMetric", forced)
+	event, _ := createCustomEvent("myType", map[string]interface{}{"zip": 1}, now, defaultAttributeLimits)
+	h.CustomEvents.Add(event)
+	h.ErrorEvents.Add(&errorEvent{errorData: errorData{Klass: "myClass", Msg: "my msg"}}, priority(0.5))
+	app.app.doHarvest(h, now, run)
+}
+
+func TestHarvestConcurrentRequests(t *testing.T) {
+	for _, limit := range []int{1, 2} {
+		app := testApp(nil, func(cfg *Config) {
+			cfg.Harvest.MaxConcurrentRequests = limit
+		}, t)
+		rt := &harvestRequestTransport{status: 202}
+		harvestWithPayloads(app, rt)
+		if rt.requests != 3 || rt.maxInFlight != limit {
+			t.Error(limit, rt.requests, rt.maxInFlight)
+		}
+	}
+}
+
+func TestHarvestRestartStopsRequests(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.Harvest.MaxConcurrentRequests = 1
+	}, t)
+	rt := &harvestRequestTransport{status: 409}
+	harvestWithPayloads(app, rt)
+	if rt.requests != 1 {
+		t.Error(rt.requests)
+	}
+	select {
+	case ce := <-app.app.collectorErrorChan:
+		if !ce.resp.IsRestartException() {
+			t.Error(ce.resp)
+		}
+	default:
+		t.Error("restart exception not reported")
+	}
+	// The payloads are returned to the harvest, whether or not their
+	// request was started.
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "myMetric", Scope: "", Forced: true, Data: nil},
+	})
+	if n := len(app.app.testHarvest.CustomEvents.events); n != 1 {
+		t.Error("custom events", n)
+	}
+	if n := len(app.app.testHarvest.ErrorEvents.events); n != 1 {
+		t.Error("error events", n)
+	}
+}
+
+func TestHarvestFirstRequestSentAlone(t *testing.T) {
+	for _, status := range []int{409, 410} {
+		app := testApp(nil, func(cfg *Config) {
+			cfg.Harvest.MaxConcurrentRequests = 3
+		}, t)
+		rt := &harvestRequestTransport{status: status}
+		harvestWithPayloads(app, rt)
+		// No other request is started once the first response has
+		// restarted or disconnected the application.
+		if rt.requests != 1 || rt.maxInFlight != 1 {
+			t.Error(status, rt.requests, rt.maxInFlight)
+		}
+	}
+}
+
+func TestHarvestDisconnectSendsCustomEventsToEventAPI(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.Harvest.MaxConcurrentRequests = 1
+		cfg.CustomInsightsEvents.EventAPIFallback.Enabled = true
+		cfg.CustomInsightsEvents.EventAPIFallback.InsertKey = "my-insert-key"
+		cfg.CustomInsightsEvents.EventAPIFallback.AccountID = "123"
+	}, t)
+	rt := &harvestRequestTransport{status: 410}
+	harvestWithPayloads(app, rt)
+	app.app.eventAPISends.Wait()
+	// The first request disconnects the application, and the custom
+	// events which were not sent are sent to the Event API.
+	if rt.requests != 2 {
+		t.Error(rt.requests)
+	}
+	select {
+	case ce := <-app.app.collectorErrorChan:
+		if !ce.resp.IsDisconnect() {
+			t.Error(ce.resp)
+		}
+	default:
+		t.Error("disconnect not reported")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"math"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/rainforestpay/go-agent/v3/internal"
@@ -174,6 +175,39 @@ func TestUserAttributeBasics(t *testing.T) {
 	})
 }
 
+func TestUserAttributeSliceAndMapValues(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = false
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.AddAttribute("user", map[string]interface{}{
+		"id":   1,
+		"plan": map[string]interface{}{"tier": "pro"},
+	})
+	txn.AddAttribute("tags", []string{"a", "b"})
+	app.expectNoLoggedErrors(t)
+	txn.AddAttribute("invalid_map", map[string]interface{}{"zip": struct{}{}})
+	app.expectSingleLoggedError(t, "unable to add attribute", map[string]interface{}{
+		"reason": `attribute 'invalid_map.zip' value of type struct {} is invalid`,
+	})
+	txn.End()
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name": "OtherTransaction/Go/hello",
+			},
+			AgentAttributes: map[string]interface{}{},
+			UserAttributes: map[string]interface{}{
+				"user.id":        1,
+				"user.plan.tier": "pro",
+				"tags":           `["a","b"]`,
+			},
+		},
+	})
+}
+
 func TestUserAttributeConfiguration(t *testing.T) {
 	cfgfn := func(cfg *Config) {
 		cfg.DistributedTracer.Enabled = false
@@ -577,6 +611,60 @@ func TestAgentAttributesExcludedFromTxnTraces(t *testing.T) {
 	})
 }
 
+func TestDeploymentMetadata(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.DeploymentMetadata = map[string]string{
+			"commit": "3f2a9c1",
+			"build":  "1024",
+		}
+		cfg.TransactionEvents.Attributes.Exclude = []string{"deployment.build"}
+	}, t)
+	txn := app.StartTransaction("hello")
+	txn.NoticeError(errors.New("zap"))
+	txn.End()
+	app.Application.NoticeError(errors.New("zip"))
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		AgentAttributes: map[string]interface{}{
+			"deployment.commit": "3f2a9c1",
+		},
+	}})
+	app.ExpectErrorEvents(t, []internal.WantEvent{{
+		AgentAttributes: map[string]interface{}{
+			"deployment.commit": "3f2a9c1",
+			"deployment.build":  "1024",
+		},
+	}, {
+		AgentAttributes: map[string]interface{}{
+			"deployment.commit": "3f2a9c1",
+			"deployment.build":  "1024",
+		},
+	}})
+	app.ExpectSpanEvents(t, []internal.WantEvent{{
+		AgentAttributes: map[string]interface{}{
+			"deployment.commit": "3f2a9c1",
+			"deployment.build":  "1024",
+			"error.class":       "*errors.errorString",
+			"error.message":     "zap",
+		},
+	}})
+}
+
+func TestDeploymentMetadataHighSecurity(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.HighSecurity = true
+		cfg.DeploymentMetadata = map[string]string{"commit": "3f2a9c1"}
+	}, t)
+	txn := app.StartTransaction("hello")
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		AgentAttributes: map[string]interface{}{
+			"deployment.commit": "3f2a9c1",
+		},
+	}})
+}
+
 func TestRequestURIPresent(t *testing.T) {
 	cfgfn := func(cfg *Config) {
 		cfg.TransactionTracer.Threshold.IsApdexFailing = false
@@ -636,6 +724,67 @@ func TestRequestURIPresent(t *testing.T) {
 	})
 }
 
+func TestRequestURITemplate(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.Attributes.RequestURIPolicy = RequestURITemplate
+		cfg.TransactionTracer.Threshold.IsApdexFailing = false
+		cfg.TransactionTracer.Threshold.Duration = 0
+		cfg.DistributedTracer.Enabled = false
+	}, t)
+	txn := app.StartTransaction("hello")
+	u, _ := url.Parse("/users/123?remove=me")
+	txn.SetWebRequest(WebRequest{URL: u})
+	txn.SetName("GET /users/{id}")
+	txn.NoticeError(errors.New("zap"))
+	txn.End()
+
+	agentAttributes := map[string]interface{}{"request.uri": "GET /users/{id}"}
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		AgentAttributes: agentAttributes,
+	}})
+	app.ExpectErrorEvents(t, []internal.WantEvent{{
+		AgentAttributes: agentAttributes,
+	}})
+	app.ExpectTxnTraces(t, []internal.WantTxnTrace{{
+		MetricName:      "WebTransaction/Go/GET /users/{id}",
+		NumSegments:     0,
+		AgentAttributes: agentAttributes,
+		UserAttributes:  map[string]interface{}{},
+	}})
+}
+
+func TestRequestURITemplateNameFrozen(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.Attributes.RequestURIPolicy = RequestURITemplate
+	}, t)
+	txn := app.StartTransaction("GET /users/{id}")
+	// Creating outbound headers freezes the transaction name.
+	txn.InsertDistributedTraceHeaders(http.Header{})
+	u, _ := url.Parse("/users/123")
+	txn.SetWebRequest(WebRequest{URL: u})
+	txn.End()
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		AgentAttributes: map[string]interface{}{"request.uri": "GET /users/{id}"},
+	}})
+}
+
+func TestRequestURIHash(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.Attributes.RequestURIPolicy = RequestURIHash
+	}, t)
+	txn := app.StartTransaction("hello")
+	u, _ := url.Parse("/users/123?remove=me")
+	txn.SetWebRequest(WebRequest{URL: u})
+	txn.End()
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		// sha256 of "/users/123"
+		AgentAttributes: map[string]interface{}{"request.uri": "adda36c31b3663ccf5ded6002ab6519226ddb6492f29c9ae82c2b9ab0cea073c"},
+	}})
+}
+
 func TestRequestURIExcluded(t *testing.T) {
 	cfgfn := func(cfg *Config) {
 		cfg.TransactionTracer.Threshold.IsApdexFailing = false
@@ -1129,3 +1278,137 @@ func TestAddSpanAttr_ValidatedValues(t *testing.T) {
 		},
 	})
 }
+
+func TestAddTraceIntrinsic(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.TransactionTracer.Threshold.IsApdexFailing = false
+		cfg.TransactionTracer.Threshold.Duration = 0
+		cfg.DistributedTracer.Enabled = false
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.AddTraceIntrinsic("tenant", "acme")
+	txn.AddTraceIntrinsic("shard", 3)
+	txn.End()
+
+	app.ExpectTxnTraces(t, []internal.WantTxnTrace{{
+		MetricName:     "OtherTransaction/Go/hello",
+		UserAttributes: map[string]interface{}{},
+		Intrinsics: map[string]interface{}{
+			"totalTime": internal.MatchAnything,
+			"tenant":    "acme",
+			"shard":     3,
+		},
+	}})
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		UserAttributes: map[string]interface{}{},
+	}})
+}
+
+func TestAddTraceIntrinsicAttributeLimits(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.TransactionTracer.Threshold.IsApdexFailing = false
+		cfg.TransactionTracer.Threshold.Duration = 0
+		cfg.DistributedTracer.Enabled = false
+		cfg.AttributeLimits.KeyLength = 6
+		cfg.AttributeLimits.ValueLength = 3
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.AddTraceIntrinsic("tenant", "acme")
+	txn.AddTraceIntrinsic("tenants", "acme")
+	app.expectSingleLoggedError(t, "unable to add trace intrinsic", map[string]interface{}{
+		"reason": invalidAttributeKeyErr{key: "tenants", limit: 6}.Error(),
+	})
+	txn.End()
+
+	app.ExpectTxnTraces(t, []internal.WantTxnTrace{{
+		MetricName:     "OtherTransaction/Go/hello",
+		UserAttributes: map[string]interface{}{},
+		Intrinsics: map[string]interface{}{
+			"totalTime": internal.MatchAnything,
+			"tenant":    "acm",
+		},
+	}})
+}
+
+func TestAddTraceIntrinsicReserved(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("hello")
+	txn.AddTraceIntrinsic("guid", "abc")
+	app.expectSingleLoggedError(t, "unable to add trace intrinsic", map[string]interface{}{
+		"reason": reservedIntrinsicErr{key: "guid"}.Error(),
+	})
+	txn.End()
+}
+
+func TestAddTraceIntrinsicHighSecurity(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.HighSecurity = true
+		cfg.DistributedTracer.Enabled = false
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.AddTraceIntrinsic("tenant", "acme")
+	app.expectSingleLoggedError(t, "unable to add trace intrinsic", map[string]interface{}{
+		"reason": errHighSecurityEnabled.Error(),
+	})
+	txn.End()
+}
+
+func TestOnAttributeDropped(t *testing.T) {
+	var dropped []DroppedAttribute
+	app := testApp(nil, func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = false
+		cfg.OnAttributeDropped = func(d DroppedAttribute) {
+			dropped = append(dropped, d)
+		}
+	}, t)
+	txn := app.StartTransaction("hello")
+	txn.AddAttribute("valid", 1)
+	txn.AddAttribute("invalid", struct{}{})
+	seg := txn.StartSegment("segment")
+	seg.AddAttribute("invalid", struct{}{})
+	seg.End()
+	(&DatastoreSegment{
+		StartTime: txn.StartSegmentNow(),
+		Product:   DatastorePostgres,
+		QueryParameters: map[string]interface{}{
+			"valid":   1,
+			"invalid": struct{}{},
+		},
+	}).End()
+	txn.End()
+
+	if len(dropped) != 3 {
+		t.Fatal(dropped)
+	}
+	for i, kind := range []string{"transaction", "span", "query parameter"} {
+		if d := dropped[i]; d.Key != "invalid" || d.Kind != kind || nil == d.Reason {
+			t.Error(i, d)
+		}
+	}
+}
+
+func TestAttributeLimitsConfig(t *testing.T) {
+	long := strings.Repeat("a", 1000)
+	app := testApp(nil, func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = false
+		cfg.AttributeLimits.ValueLength = 500
+	}, t)
+	txn := app.StartTransaction("hello")
+	txn.AddAttribute("long", long)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		UserAttributes: map[string]interface{}{
+			"long": long[:500],
+		},
+	}})
+}
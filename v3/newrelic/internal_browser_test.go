@@ -5,6 +5,7 @@ package newrelic
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/rainforestpay/go-agent/v3/internal"
@@ -158,6 +159,40 @@ func TestBrowserTimingHeaderTxnIgnored(t *testing.T) {
 	}
 }
 
+func TestBrowserCorrelationToken(t *testing.T) {
+	app := testApp(browserReplyFields, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("hello")
+	token := txn.BrowserCorrelationToken()
+	if "" == token || token != txn.BrowserCorrelationToken() {
+		t.Fatal(token)
+	}
+	hdr := txn.BrowserTimingHeader()
+	app.expectNoLoggedErrors(t)
+	if hdr.CorrelationToken() != token {
+		t.Error(hdr.CorrelationToken(), token)
+	}
+	js := `newrelic.setCustomAttribute("browser.correlationToken","` + token + `")`
+	if out := string(hdr.WithoutTags()); !strings.HasSuffix(out, js) {
+		t.Error(out)
+	}
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		AgentAttributes: map[string]interface{}{
+			AttributeBrowserCorrelationToken: token,
+		},
+	}})
+
+	if token := txn.BrowserCorrelationToken(); "" != token {
+		t.Error(token)
+	}
+	app.expectSingleLoggedError(t, "unable to create browser correlation token", map[string]interface{}{
+		"reason": errAlreadyEnded.Error(),
+	})
+}
+
 func BenchmarkBrowserTimingHeaderSuccess(b *testing.B) {
 	app := testApp(browserReplyFields, nil, b)
 	txn := app.StartTransaction("hello")
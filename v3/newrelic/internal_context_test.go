@@ -4,7 +4,9 @@
 package newrelic
 
 import (
+	"context"
 	"net/http"
+	"reflect"
 	"testing"
 
 	"github.com/rainforestpay/go-agent/v3/internal"
@@ -63,6 +65,60 @@ func TestStartExternalSegmentNilTransaction(t *testing.T) {
 	})
 }
 
+func TestStartExternalSegmentFromContext(t *testing.T) {
+	// Test that StartExternalSegmentFromContext uses the transaction in
+	// the context when the request does not carry one.
+
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("myTxn")
+	ctx := NewContext(context.Background(), txn)
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	segment := StartExternalSegmentFromContext(ctx, req)
+	segment.End()
+
+	// Without a transaction in ctx the request's context is used.
+	req = RequestWithTransactionContext(req, txn)
+	segment = StartExternalSegmentFromContext(context.Background(), req)
+	segment.End()
+	txn.End()
+
+	scope := "OtherTransaction/Go/myTxn"
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "OtherTransaction/Go/myTxn", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransaction/all", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransactionTotalTime/Go/myTxn", Scope: "", Forced: false, Data: nil},
+		{Name: "OtherTransactionTotalTime", Scope: "", Forced: true, Data: nil},
+		{Name: "External/all", Scope: "", Forced: true, Data: nil},
+		{Name: "External/allOther", Scope: "", Forced: true, Data: nil},
+		{Name: "External/example.com/all", Scope: "", Forced: false, Data: nil},
+		{Name: "External/example.com/http/GET", Scope: scope, Forced: false, Data: nil},
+	})
+}
+
+func TestWrapClient(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("myTxn")
+
+	original := &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200}, nil
+	})}
+	client := WrapClient(original)
+	if client == original || original.Transport == client.Transport {
+		t.Error("original client modified")
+	}
+	if WrapClient(client) != client {
+		t.Error("instrumented client wrapped twice")
+	}
+	req, _ := http.NewRequestWithContext(NewContext(context.Background(), txn), "GET", "http://example.com", nil)
+	client.Do(req)
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "External/example.com/http/GET", Scope: "OtherTransaction/Go/myTxn", Forced: false, Data: nil},
+	})
+}
+
 func TestNewRoundTripperNilTransaction(t *testing.T) {
 	// Test that NewRoundTripper pulls the transaction from the
 	// request's context if it is not explicitly provided.
@@ -126,3 +182,71 @@ func TestNewRoundTripperNilTransaction(t *testing.T) {
 		},
 	})
 }
+
+func TestLinkingMetadataFromContext(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+		reply.EntityGUID = "entities-are-guid"
+		reply.TraceIDGenerator = internal.NewTraceIDGenerator(12345)
+	}
+	app := testApp(replyfn, ConfigDistributedTracerEnabled(true), t)
+	txn := app.StartTransaction("hello")
+	ctx := NewContext(context.Background(), txn)
+
+	seg := txn.StartSegment("mySegment")
+	metadata := LinkingMetadataFromContext(ctx)
+	if metadata != txn.GetLinkingMetadata() {
+		t.Error(metadata, txn.GetLinkingMetadata())
+	}
+	if metadata.TraceID == "" || metadata.SpanID == "" || metadata.EntityGUID == "" || metadata.Hostname == "" {
+		t.Error("missing linking metadata:", metadata)
+	}
+	seg.End()
+	if after := LinkingMetadataFromContext(ctx); after.SpanID == metadata.SpanID {
+		t.Error("span ID did not follow the active segment:", after.SpanID)
+	}
+	txn.End()
+
+	if m := LinkingMetadataFromContext(ctx); m.TraceID != "" || m.SpanID != "" {
+		t.Error("expected no trace after transaction ended:", m)
+	}
+}
+
+func TestLinkingMetadataFromContextMissingTransaction(t *testing.T) {
+	if m := LinkingMetadataFromContext(context.Background()); !reflect.DeepEqual(m, LinkingMetadata{}) {
+		t.Error(m)
+	}
+	if m := LinkingMetadataFromContext(nil); !reflect.DeepEqual(m, LinkingMetadata{}) {
+		t.Error(m)
+	}
+}
+
+func TestStartTransactionCtx(t *testing.T) {
+	app := testApp(nil, nil, t)
+	ctx, end := StartTransactionCtx(context.Background(), app.Application, "hello")
+	if txn := FromContext(ctx); nil == txn {
+		t.Fatal("transaction not added to context")
+	}
+	endSegment := SegmentFromContext(ctx, "mySegment")
+	endSegment()
+	end()
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "OtherTransaction/Go/hello", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransaction/all", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransactionTotalTime/Go/hello", Scope: "", Forced: false, Data: nil},
+		{Name: "OtherTransactionTotalTime", Scope: "", Forced: true, Data: nil},
+		{Name: "Custom/mySegment", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/mySegment", Scope: "OtherTransaction/Go/hello", Forced: false, Data: nil},
+		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Scope: "", Forced: false, Data: nil},
+		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allOther", Scope: "", Forced: false, Data: nil},
+	})
+}
+
+func TestContextHelpersWithoutTransaction(t *testing.T) {
+	ctx, end := StartTransactionCtx(context.Background(), nil, "hello")
+	if txn := FromContext(ctx); nil != txn {
+		t.Error(txn)
+	}
+	end()
+	SegmentFromContext(ctx, "mySegment")()
+}
@@ -31,6 +31,14 @@ var (
 		"client_cross_process_id":     "12345#67890",
 		"nr.tripId":                   internal.MatchAnything,
 	}
+	catReceivedMetric = internal.WantMetric{
+		Name: "Supportability/CrossApplicationTracing/Inbound/Received", Scope: "", Forced: true, Data: singleCount,
+	}
+	catIgnoredMetric = internal.WantMetric{
+		Name: "Supportability/CrossApplicationTracing/Inbound/Ignored", Scope: "", Forced: true, Data: singleCount,
+	}
+	catAcceptedMetrics = append([]internal.WantMetric{catReceivedMetric}, webMetrics2xx...)
+	catIgnoredMetrics  = append([]internal.WantMetric{catReceivedMetric, catIgnoredMetric}, webMetrics2xx...)
 )
 
 func inboundCrossProcessRequestFactory() *http.Request {
@@ -87,7 +95,7 @@ func TestCrossProcessWriteHeaderSuccess(t *testing.T) {
 		t.Error(w.Header().Get(cat.NewRelicAppDataName))
 	}
 
-	app.ExpectMetrics(t, webMetrics)
+	app.ExpectMetrics(t, catAcceptedMetrics)
 	app.ExpectTxnEvents(t, []internal.WantEvent{
 		{
 			Intrinsics: catIntrinsics,
@@ -122,7 +130,7 @@ func TestCrossProcessWriteSuccess(t *testing.T) {
 		t.Error(w.Header().Get(cat.NewRelicAppDataName))
 	}
 
-	app.ExpectMetrics(t, webMetrics)
+	app.ExpectMetrics(t, catAcceptedMetrics)
 	app.ExpectTxnEvents(t, []internal.WantEvent{
 		{
 			Intrinsics: catIntrinsics,
@@ -152,7 +160,7 @@ func TestCrossProcessLocallyDisabled(t *testing.T) {
 		t.Error(w.Header().Get(cat.NewRelicAppDataName))
 	}
 
-	app.ExpectMetrics(t, webMetrics)
+	app.ExpectMetrics(t, catIgnoredMetrics)
 	app.ExpectTxnEvents(t, []internal.WantEvent{
 		{
 			Intrinsics: map[string]interface{}{
@@ -188,7 +196,7 @@ func TestCrossProcessDisabledByServerSideConfig(t *testing.T) {
 		t.Error(w.Header().Get(cat.NewRelicAppDataName))
 	}
 
-	app.ExpectMetrics(t, webMetrics)
+	app.ExpectMetrics(t, catIgnoredMetrics)
 	app.ExpectTxnEvents(t, []internal.WantEvent{
 		{
 			Intrinsics: map[string]interface{}{
@@ -225,7 +233,7 @@ func TestCrossProcessEnabledByServerSideConfig(t *testing.T) {
 		t.Error(w.Header().Get(cat.NewRelicAppDataName))
 	}
 
-	app.ExpectMetrics(t, webMetrics)
+	app.ExpectMetrics(t, catAcceptedMetrics)
 	app.ExpectTxnEvents(t, []internal.WantEvent{
 		{
 			Intrinsics: catIntrinsics,
@@ -236,3 +244,69 @@ func TestCrossProcessEnabledByServerSideConfig(t *testing.T) {
 		},
 	})
 }
+
+func TestCrossProcessReadOnly(t *testing.T) {
+	// Test that read-only CAT accepts inbound CAT headers alongside
+	// distributed tracing, but never creates CAT headers.
+	cfgFn := func(cfg *Config) {
+		cfg.CrossApplicationTracer.Enabled = true
+		cfg.CrossApplicationTracer.ReadOnly = true
+		cfg.DistributedTracer.Enabled = true
+	}
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		crossProcessReplyFn(reply)
+	}
+	app := testApp(replyfn, cfgFn, t)
+	w := httptest.NewRecorder()
+	txn := app.StartTransaction("hello")
+	rw := txn.SetWebResponse(w)
+	txn.SetWebRequestHTTP(inboundCrossProcessRequestFactory())
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	StartExternalSegment(txn, req).End()
+	rw.WriteHeader(200)
+	txn.End()
+
+	if "" != w.Header().Get(cat.NewRelicAppDataName) {
+		t.Error(w.Header().Get(cat.NewRelicAppDataName))
+	}
+	if "" != req.Header.Get(cat.NewRelicIDName) || "" != req.Header.Get(cat.NewRelicTxnName) {
+		t.Error(req.Header)
+	}
+	if "" == req.Header.Get(DistributedTraceW3CTraceParentHeader) {
+		t.Error("distributed tracing headers not found", req.Header)
+	}
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{catReceivedMetric})
+	app.ExpectTxnEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":                        "WebTransaction/Go/hello",
+				"nr.apdexPerfZone":            "S",
+				"nr.referringTransactionGuid": internal.MatchAnything,
+				"nr.referringPathHash":        "41c04f7d",
+				"nr.pathHash":                 internal.MatchAnything,
+				"client_cross_process_id":     "12345#67890",
+				"nr.tripId":                   internal.MatchAnything,
+				"guid":                        internal.MatchAnything,
+				"traceId":                     internal.MatchAnything,
+				"priority":                    internal.MatchAnything,
+				"sampled":                     internal.MatchAnything,
+				"externalCallCount":           1,
+				"externalDuration":            internal.MatchAnything,
+			},
+			AgentAttributes: nil,
+			UserAttributes:  map[string]interface{}{},
+		},
+	})
+}
+
+func TestCrossProcessIgnoredMetric(t *testing.T) {
+	// Test that inbound CAT headers are counted when CAT is disabled.
+	app := testApp(crossProcessReplyFn, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequestHTTP(inboundCrossProcessRequestFactory())
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{catReceivedMetric, catIgnoredMetric})
+}
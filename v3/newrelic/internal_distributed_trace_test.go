@@ -1696,6 +1696,123 @@ func TestW3CTraceHeadersSpansDisabledWithTraceState(t *testing.T) {
 	}, backgroundUnknownCallerWithTransport...))
 }
 
+func TestW3CTraceStatePruned(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		enableW3COnly(cfg)
+		cfg.DistributedTracer.MaxTraceStateSize = 100
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	txn := app.StartTransaction("hello")
+
+	incomingHdrs := http.Header{}
+	incomingHdrs.Set(DistributedTraceW3CTraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	incomingHdrs.Set(DistributedTraceW3CTraceStateHeader, "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE,blanco=00f067aa0ba902b7")
+	txn.AcceptDistributedTraceHeaders(TransportHTTP, incomingHdrs)
+
+	hdrs := http.Header{}
+	txn.InsertDistributedTraceHeaders(hdrs)
+
+	expected := http.Header{
+		DistributedTraceW3CTraceParentHeader: []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-9566c74d10d1e2c6-01"},
+		DistributedTraceW3CTraceStateHeader:  []string{"123@nr=0-0-123-456-9566c74d10d1e2c6-52fdfc072182654f-1-1.437714-1577830891900,rojo=00f067aa0ba902b7"},
+	}
+	verifyHeaders(t, hdrs, expected)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+}
+
+func TestHeaderValidationStrictMalformedTraceParent(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		enableW3COnly(cfg)
+		cfg.DistributedTracer.HeaderValidation.Mode = HeaderValidationStrict
+		cfg.DistributedTracer.HeaderValidation.LogMalformedHeaders = true
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	txn := app.StartTransaction("hello")
+
+	hdrs := http.Header{}
+	hdrs.Set(DistributedTraceW3CTraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7")
+	if err := txn.thread.AcceptDistributedTraceHeaders(TransportHTTP, hdrs); err != errNumEntries {
+		t.Error(err)
+	}
+	app.expectSingleLoggedError(t, "malformed distributed trace header", map[string]interface{}{
+		"header":      DistributedTraceW3CTraceParentHeader,
+		"value":       "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"reason":      errNumEntries.Error(),
+		"transaction": "hello",
+	})
+	txn.End()
+
+	app.ExpectMetrics(t, append([]internal.WantMetric{
+		{Name: "Supportability/TraceContext/TraceParent/Parse/Exception", Scope: "", Forced: true, Data: nil},
+		{Name: "Supportability/DistributedTrace/MalformedHeader/TraceParent", Scope: "", Forced: true, Data: nil},
+	}, backgroundUnknownCallerWithTransport...))
+}
+
+func TestHeaderValidationStrictInvalidTraceState(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		enableW3COnly(cfg)
+		cfg.DistributedTracer.HeaderValidation.Mode = HeaderValidationStrict
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	txn := app.StartTransaction("hello")
+
+	hdrs := http.Header{}
+	hdrs.Set(DistributedTraceW3CTraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	hdrs.Set(DistributedTraceW3CTraceStateHeader, "123@nr=0-0-123")
+	txn.AcceptDistributedTraceHeaders(TransportHTTP, hdrs)
+	app.expectNoLoggedErrors(t)
+	txn.End()
+
+	app.ExpectMetrics(t, append([]internal.WantMetric{
+		{Name: "Supportability/TraceContext/Accept/Success", Scope: "", Forced: true, Data: nil},
+		{Name: "Supportability/TraceContext/TraceState/InvalidNrEntry", Scope: "", Forced: true, Data: nil},
+		{Name: "Supportability/DistributedTrace/MalformedHeader/TraceState", Scope: "", Forced: true, Data: nil},
+	}, backgroundUnknownCallerWithTransport...))
+}
+
+func TestHeaderValidationStrictMalformedNewRelic(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.DistributedTracer.HeaderValidation.Mode = HeaderValidationStrict
+		cfg.DistributedTracer.HeaderValidation.LogMalformedHeaders = true
+		cfg.Scrubbing.Rules = []ScrubbingRule{{Name: "secret", Pattern: "secret", Replacement: "***"}}
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	txn := app.StartTransaction("hello")
+
+	hdrs := http.Header{}
+	hdrs.Set(DistributedTraceNewRelicHeader, "{secret")
+	if err := txn.thread.AcceptDistributedTraceHeaders(TransportHTTP, hdrs); nil == err {
+		t.Error("expected error")
+	}
+	app.expectSingleLoggedError(t, "malformed distributed trace header", map[string]interface{}{
+		"header": DistributedTraceNewRelicHeader,
+		"value":  "{***",
+	})
+	txn.End()
+
+	app.ExpectMetrics(t, append([]internal.WantMetric{
+		{Name: "Supportability/DistributedTrace/AcceptPayload/ParseException", Scope: "", Forced: true, Data: nil},
+		{Name: "Supportability/DistributedTrace/MalformedHeader/NewRelic", Scope: "", Forced: true, Data: nil},
+	}, backgroundUnknownCallerWithTransport...))
+}
+
+func TestHeaderValidationLenientMalformedTraceParent(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableW3COnly, t)
+	txn := app.StartTransaction("hello")
+
+	hdrs := http.Header{}
+	hdrs.Set(DistributedTraceW3CTraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7")
+	txn.thread.AcceptDistributedTraceHeaders(TransportHTTP, hdrs)
+	app.expectNoLoggedErrors(t)
+	txn.End()
+
+	app.ExpectMetrics(t, append([]internal.WantMetric{
+		{Name: "Supportability/TraceContext/TraceParent/Parse/Exception", Scope: "", Forced: true, Data: nil},
+	}, backgroundUnknownCallerWithTransport...))
+}
+
 func TestW3CTraceHeadersTxnEventsDisabled(t *testing.T) {
 	cfgfn := func(cfg *Config) {
 		enableW3COnly(cfg)
@@ -2339,3 +2456,123 @@ func TestW3CHeaderCases(t *testing.T) {
 		})
 	}
 }
+
+func TestRelationshipMetrics(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.DistributedTracer.RelationshipMetrics = true
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	hdrs := getDTHeaders(app.Application)
+	txn := app.StartTransaction("Message/Kafka/Topic/Named/orders")
+	txn.AcceptDistributedTraceHeaders(TransportKafka, hdrs)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Relationship/Inbound/App/123/456/Kafka", Scope: "OtherTransaction/Go/Message/Kafka/Topic/Named/orders", Forced: false, Data: nil},
+	})
+}
+
+func TestRelationshipMetricsDisabled(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	hdrs := getDTHeaders(app.Application)
+	txn := app.StartTransaction("hello")
+	txn.AcceptDistributedTraceHeaders(TransportHTTP, hdrs)
+	txn.End()
+	app.ExpectMetrics(t, distributedTracingSuccessMetrics)
+}
+
+func TestSetSampled(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleNothing()
+	}
+	app := testApp(replyfn, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	txn.SetSampled(true)
+	app.expectNoLoggedErrors(t)
+	if !txn.IsSampled() {
+		t.Error("transaction not sampled")
+	}
+	if p := txn.thread.txn.BetterCAT.Priority; p < 1 || p >= 2 {
+		t.Error(p)
+	}
+	txn.SetSampled(false)
+	if txn.IsSampled() {
+		t.Error("transaction sampled")
+	}
+	if p := txn.thread.txn.BetterCAT.Priority; p >= 1 {
+		t.Error(p)
+	}
+	txn.End()
+}
+
+func TestSetSampledOverridesInboundPayload(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	hdrs := getDTHeaders(app.Application)
+	txn := app.StartTransaction("hello")
+	txn.AcceptDistributedTraceHeaders(TransportHTTP, hdrs)
+	if !txn.IsSampled() {
+		t.Error("inbound sampling decision not used")
+	}
+	txn.SetSampled(false)
+	outbound := http.Header{}
+	txn.InsertDistributedTraceHeaders(outbound)
+	if tp := outbound.Get(DistributedTraceW3CTraceParentHeader); !strings.HasSuffix(tp, "-00") {
+		t.Error(tp)
+	}
+	txn.End()
+	app.expectNoLoggedErrors(t)
+}
+
+func TestSetPriority(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	txn.SetPriority(0.25)
+	app.expectNoLoggedErrors(t)
+	if p := txn.thread.txn.BetterCAT.Priority; p != 0.25 {
+		t.Error(p)
+	}
+	txn.SetSampled(true)
+	txn.SetPriority(0.5)
+	if p := txn.thread.txn.BetterCAT.Priority; p != 1.5 {
+		t.Error(p)
+	}
+	txn.SetPriority(1.5)
+	app.expectSingleLoggedError(t, "unable to set priority", map[string]interface{}{
+		"reason": errPriorityRange.Error(),
+	})
+	txn.End()
+}
+
+func TestSetSamplingAfterOutboundPayload(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	txn.InsertDistributedTraceHeaders(http.Header{})
+	txn.SetSampled(false)
+	app.expectSingleLoggedError(t, "unable to set sampled", map[string]interface{}{
+		"reason": errOutboundPayloadCreated.Error(),
+	})
+	txn.SetPriority(0.5)
+	app.expectSingleLoggedError(t, "unable to set priority", map[string]interface{}{
+		"reason": errOutboundPayloadCreated.Error(),
+	})
+	if !txn.IsSampled() {
+		t.Error("propagated sampling decision changed")
+	}
+	txn.End()
+}
+
+func TestSetSampledDTDisabled(t *testing.T) {
+	app := testApp(nil, disableCAT, t)
+	txn := app.StartTransaction("hello")
+	txn.SetSampled(true)
+	app.expectSingleLoggedError(t, "unable to set sampled", map[string]interface{}{
+		"reason": errSamplingDTDisabled.Error(),
+	})
+	txn.End()
+	txn.SetPriority(0.5)
+	app.expectSingleLoggedError(t, "unable to set priority", map[string]interface{}{
+		"reason": errSamplingDTDisabled.Error(),
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/rainforestpay/go-agent/v3/internal"
 )
@@ -233,6 +234,71 @@ func TestNoticeErrorTracedErrorsRemotelyDisabled(t *testing.T) {
 	app.ExpectMetrics(t, backgroundErrorMetrics)
 }
 
+func TestRecordException(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("hello")
+	txn.RecordException(myError{}, ErrorSeverityCritical)
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	app.ExpectErrors(t, []internal.WantError{
+		{
+			TxnName: "OtherTransaction/Go/hello",
+			Msg:     "my msg",
+			Klass:   "newrelic.myError",
+		},
+	})
+	app.ExpectErrorEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"error.class":     "newrelic.myError",
+				"error.message":   "my msg",
+				"error.severity":  "critical",
+				"transactionName": "OtherTransaction/Go/hello",
+			},
+		},
+	})
+	app.ExpectMetrics(t, backgroundErrorMetrics)
+}
+
+func TestRecordExceptionInvalidSeverity(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("hello")
+	txn.RecordException(myError{}, ErrorSeverity("fatal"))
+	app.expectSingleLoggedError(t, "unable to record exception", map[string]interface{}{
+		"reason": errErrorSeverity.Error(),
+	})
+	txn.End()
+	app.ExpectErrors(t, []internal.WantError{})
+	app.ExpectErrorEvents(t, []internal.WantEvent{})
+	app.ExpectMetrics(t, backgroundMetrics)
+}
+
+func TestRecordExceptionCriticalNotDisplaced(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("hello")
+	for i := 0; i < maxTxnErrors; i++ {
+		txn.RecordException(Error{Message: "warning", Class: "warning"}, ErrorSeverityWarning)
+	}
+	txn.RecordException(Error{Message: "critical", Class: "critical"}, ErrorSeverityCritical)
+	txn.RecordException(Error{Message: "warning", Class: "warning"}, ErrorSeverityWarning)
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	want := internal.WantError{
+		TxnName: "OtherTransaction/Go/hello",
+		Msg:     "warning",
+		Klass:   "warning",
+	}
+	wantErrors := []internal.WantError{{
+		TxnName: "OtherTransaction/Go/hello",
+		Msg:     "critical",
+		Klass:   "critical",
+	}}
+	for i := 1; i < maxTxnErrors; i++ {
+		wantErrors = append(wantErrors, want)
+	}
+	app.ExpectErrors(t, wantErrors)
+}
+
 func TestNoticeErrorNil(t *testing.T) {
 	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
 	txn := app.StartTransaction("hello")
@@ -702,7 +768,7 @@ func TestErrorClass(t *testing.T) {
 	}
 
 	for idx, tc := range testcases {
-		data, err := errDataFromError(tc.Error, false)
+		data, err := errDataFromError(tc.Error, false, defaultAttributeLimits)
 		if err != nil {
 			t.Errorf("testcase %d: got error: %v", idx, err)
 			continue
@@ -813,3 +879,188 @@ func TestNoticeErrorPanicRecoverySpanID(t *testing.T) {
 	})
 	app.ExpectMetrics(t, backgroundErrorMetricsUnknownCaller)
 }
+
+func testHarvestErrorEvents(ea expectApp) []analyticsEvent {
+	return ea.Private.(*app).testHarvest.ErrorEvents.events
+}
+
+func TestApplicationNoticeError(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.HostDisplayName = "my host"
+	}, t)
+	when := time.Date(2014, time.November, 28, 1, 1, 0, 0, time.UTC)
+	app.Application.NoticeError(Error{
+		Message:    "my msg",
+		Class:      "my class",
+		Attributes: map[string]interface{}{"zip": "zap"},
+	}, WithErrorTimestamp(when))
+	app.expectNoLoggedErrors(t)
+	app.ExpectErrorEvents(t, []internal.WantEvent{{
+		UserAttributes: map[string]interface{}{
+			"zip": "zap",
+		},
+		AgentAttributes: map[string]interface{}{
+			"host.displayName": "my host",
+		},
+	}})
+	events := testHarvestErrorEvents(app)
+	if len(events) != 1 {
+		t.Fatal(len(events))
+	}
+	testErrorEventJSON(t, events[0].jsonWriter.(*errorEvent), `[
+		{
+			"type":"TransactionError",
+			"error.class":"my class",
+			"error.message":"my msg",
+			"timestamp":1417136460000
+		},
+		{"zip":"zap"},
+		{"host.displayName":"my host"}
+	]`)
+	app.ExpectErrors(t, []internal.WantError{})
+}
+
+func TestApplicationNoticeErrorDefaultTimestamp(t *testing.T) {
+	app := testApp(nil, nil, t)
+	before := time.Now()
+	app.Application.NoticeError(myError{})
+	after := time.Now()
+	app.expectNoLoggedErrors(t)
+	events := testHarvestErrorEvents(app)
+	if len(events) != 1 {
+		t.Fatal(len(events))
+	}
+	when := events[0].jsonWriter.(*errorEvent).When
+	if when.Before(before) || when.After(after) {
+		t.Error(when, before, after)
+	}
+}
+
+func TestApplicationNoticeErrorHighSecurity(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.HighSecurity = true
+	}, t)
+	app.Application.NoticeError(Error{
+		Message:    "my msg",
+		Class:      "my class",
+		Attributes: map[string]interface{}{"zip": "zap"},
+	})
+	app.expectNoLoggedErrors(t)
+	events := testHarvestErrorEvents(app)
+	if len(events) != 1 {
+		t.Fatal(len(events))
+	}
+	e := events[0].jsonWriter.(*errorEvent)
+	if e.Msg != highSecurityErrorMsg {
+		t.Error(e.Msg)
+	}
+	if nil != e.ExtraAttributes {
+		t.Error(e.ExtraAttributes)
+	}
+}
+
+func TestApplicationNoticeErrorEventsDisabled(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.ErrorCollector.CaptureEvents = false
+	}, t)
+	app.Application.NoticeError(myError{})
+	app.expectSingleLoggedError(t, "unable to notice error", map[string]interface{}{
+		"reason": errErrorEventsDisabled.Error(),
+	})
+	app.ExpectErrorEvents(t, []internal.WantEvent{})
+}
+
+func TestApplicationNoticeErrorExpected(t *testing.T) {
+	app := testApp(nil, nil, t)
+	when := time.Date(2014, time.November, 28, 1, 1, 0, 0, time.UTC)
+	app.Application.NoticeError(Error{Message: "my msg", Class: "my class"},
+		WithErrorExpected(), WithErrorTimestamp(when))
+	app.expectNoLoggedErrors(t)
+	events := testHarvestErrorEvents(app)
+	if len(events) != 1 {
+		t.Fatal(len(events))
+	}
+	testErrorEventJSON(t, events[0].jsonWriter.(*errorEvent), `[
+		{
+			"type":"TransactionError",
+			"error.class":"my class",
+			"error.message":"my msg",
+			"timestamp":1417136460000,
+			"error.expected":"true"
+		},
+		{},
+		{}
+	]`)
+}
+
+func TestApplicationNoticeErrorNil(t *testing.T) {
+	app := testApp(nil, nil, t)
+	app.Application.NoticeError(nil)
+	app.expectSingleLoggedError(t, "unable to notice error", map[string]interface{}{
+		"reason": errNilError.Error(),
+	})
+	app.ExpectErrorEvents(t, []internal.WantEvent{})
+
+	var nilApp *Application
+	nilApp.NoticeError(myError{})
+}
+
+func captureLogErrorsCfg(cfg *Config) {
+	cfg.DistributedTracer.Enabled = false
+	cfg.ApplicationLogging.Enabled = true
+	cfg.ApplicationLogging.Forwarding.Enabled = true
+	cfg.ApplicationLogging.Forwarding.CaptureErrors = true
+}
+
+func TestCaptureLogErrors(t *testing.T) {
+	app := testApp(nil, captureLogErrorsCfg, t)
+	txn := app.StartTransaction("hello")
+	txn.RecordLog(LogData{Severity: "info", Message: "charging card"})
+	txn.RecordLog(LogData{Severity: "error", Message: "payment failed"})
+	txn.RecordLog(LogData{Severity: "ERROR", Message: "payment failed"})
+	txn.NoticeError(myError{})
+	txn.RecordLog(LogData{Severity: "fatal", Message: "giving up: my msg"})
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	app.ExpectErrors(t, []internal.WantError{
+		{
+			TxnName: "OtherTransaction/Go/hello",
+			Msg:     "payment failed",
+			Klass:   "LogError",
+		},
+		{
+			TxnName: "OtherTransaction/Go/hello",
+			Msg:     "my msg",
+			Klass:   "newrelic.myError",
+		},
+	})
+	app.ExpectErrorEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"error.class":     "LogError",
+				"error.message":   "payment failed",
+				"error.severity":  "error",
+				"transactionName": "OtherTransaction/Go/hello",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"error.class":     "newrelic.myError",
+				"error.message":   "my msg",
+				"transactionName": "OtherTransaction/Go/hello",
+			},
+		},
+	})
+}
+
+func TestCaptureLogErrorsDisabled(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		captureLogErrorsCfg(cfg)
+		cfg.ApplicationLogging.Forwarding.CaptureErrors = false
+	}, t)
+	txn := app.StartTransaction("hello")
+	txn.RecordLog(LogData{Severity: "error", Message: "payment failed"})
+	txn.End()
+	app.ExpectErrors(t, []internal.WantError{})
+	app.ExpectErrorEvents(t, []internal.WantEvent{})
+}
@@ -6,6 +6,7 @@ package newrelic
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -541,3 +542,152 @@ func TestTraceSegmentAttributesSpecificallyDisabled(t *testing.T) {
 		},
 	})
 }
+
+func TestRedactAttributes(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	var kinds []string
+	cfgfn := func(cfg *Config) {
+		cfg.TransactionTracer.Segments.Threshold = 0
+		cfg.TransactionTracer.Segments.StackTraceThreshold = 1 * time.Hour
+		cfg.TransactionTracer.Threshold.IsApdexFailing = false
+		cfg.TransactionTracer.Threshold.Duration = 0
+		cfg.DistributedTracer.Enabled = true
+		cfg.RedactAttributes = func(data *RedactableData) {
+			kinds = append(kinds, data.Kind+" "+data.Name)
+			if url, ok := data.AgentAttributes["http.url"].(string); ok {
+				data.AgentAttributes["http.url"] = strings.Replace(url, "4111111111111111", "****", 1)
+			}
+			delete(data.UserAttributes, "secret")
+		}
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	req, _ := http.NewRequest("GET", "http://example.com/accounts/4111111111111111", nil)
+	seg := StartExternalSegment(txn, req)
+	seg.AddAttribute("secret", "shh")
+	seg.AddAttribute("visible", 1)
+	seg.End()
+	txn.End()
+
+	if len(kinds) != 4 {
+		t.Error(kinds)
+	}
+	app.ExpectSpanEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":          "External/example.com/http/GET",
+			"category":      "http",
+			"parentId":      internal.MatchAnything,
+			"component":     "http",
+			"span.kind":     "client",
+			"sampled":       true,
+			"guid":          internal.MatchAnything,
+			"transactionId": internal.MatchAnything,
+			"priority":      internal.MatchAnything,
+			"traceId":       internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"visible": 1,
+		},
+		AgentAttributes: map[string]interface{}{
+			"http.url":    "http://example.com/accounts/****",
+			"http.method": "GET",
+		},
+	}, {
+		Intrinsics: map[string]interface{}{
+			"name":             "OtherTransaction/Go/hello",
+			"transaction.name": "OtherTransaction/Go/hello",
+			"sampled":          true,
+			"category":         "generic",
+			"nr.entryPoint":    true,
+		},
+		UserAttributes:  map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{},
+	}})
+	app.ExpectTxnTraces(t, []internal.WantTxnTrace{{
+		MetricName: "OtherTransaction/Go/hello",
+		Root: internal.WantTraceSegment{
+			SegmentName: "ROOT",
+			Attributes:  map[string]interface{}{},
+			Children: []internal.WantTraceSegment{{
+				SegmentName: "OtherTransaction/Go/hello",
+				Attributes:  map[string]interface{}{"exclusive_duration_millis": internal.MatchAnything},
+				Children: []internal.WantTraceSegment{{
+					SegmentName: "External/example.com/http/GET",
+					Attributes: map[string]interface{}{
+						"http.url": "http://example.com/accounts/****",
+					},
+				}},
+			}},
+		},
+	}})
+}
+
+func TestRedactTransactionAndErrorAttributes(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.RedactAttributes = func(data *RedactableData) {
+			switch data.Kind {
+			case "transaction":
+				if uri, ok := data.AgentAttributes["request.uri"].(string); ok {
+					data.AgentAttributes["request.uri"] = strings.Replace(uri, "4111111111111111", "****", 1)
+				}
+				delete(data.UserAttributes, "secret")
+				data.UserAttributes["added"] = true
+			case "error":
+				data.UserAttributes["account"] = "****"
+			}
+		}
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	req, _ := http.NewRequest("GET", "http://example.com/accounts/4111111111111111", nil)
+	txn.SetWebRequestHTTP(req)
+	txn.AddAttribute("secret", "shh")
+	txn.AddAttribute("visible", 1)
+	txn.NoticeError(Error{
+		Message:    "my msg",
+		Class:      "my class",
+		Attributes: map[string]interface{}{"account": "4111111111111111"},
+	})
+	txn.End()
+
+	agentAttrs := map[string]interface{}{
+		"request.uri":          "http://example.com/accounts/****",
+		"request.method":       "GET",
+		"request.headers.host": "example.com",
+	}
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/hello",
+			"error":            true,
+			"nr.apdexPerfZone": internal.MatchAnything,
+			"guid":             internal.MatchAnything,
+			"traceId":          internal.MatchAnything,
+			"priority":         internal.MatchAnything,
+			"sampled":          internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"visible": 1,
+			"added":   true,
+		},
+		AgentAttributes: agentAttrs,
+	}})
+	app.ExpectErrorEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"error.class":     "my class",
+			"error.message":   "my msg",
+			"transactionName": "WebTransaction/Go/hello",
+			"guid":            internal.MatchAnything,
+			"traceId":         internal.MatchAnything,
+			"priority":        internal.MatchAnything,
+			"sampled":         internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"visible": 1,
+			"added":   true,
+			"account": "****",
+		},
+		AgentAttributes: agentAttrs,
+	}})
+}
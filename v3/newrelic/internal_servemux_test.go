@@ -0,0 +1,44 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build go1.22
+// +build go1.22
+
+//go:debug httpmuxgo121=0
+
+package newrelic
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestWrapServeMuxMethodPattern(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("order " + r.PathValue("id")))
+	})
+	req, _ := http.NewRequest("GET", "http://example.com/orders/123", nil)
+	w := newCompatibleResponseRecorder()
+	WrapServeMux(app.Application, mux).ServeHTTP(w, req)
+
+	if out := w.Body.String(); "order 123" != out {
+		t.Error(out)
+	}
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/GET /orders/{id}",
+			"nr.apdexPerfZone": internal.MatchAnything,
+		},
+		AgentAttributes: map[string]interface{}{
+			"httpResponseCode":     "200",
+			"http.statusCode":      "200",
+			"request.method":       "GET",
+			"request.uri":          "http://example.com/orders/123",
+			"request.headers.host": "example.com",
+		},
+	}})
+}
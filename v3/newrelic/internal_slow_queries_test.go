@@ -693,6 +693,66 @@ func TestSlowQueryInstanceDisabled(t *testing.T) {
 	}, webMetrics...))
 }
 
+func TestSlowQueryInstanceHostnameAliases(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.DatastoreTracer.SlowQuery.Threshold = 0
+		cfg.DatastoreTracer.InstanceHostnameAliases = map[string]string{
+			"pgbouncer.internal": "orders-db",
+		}
+		cfg.DistributedTracer.Enabled = false
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequestHTTP(helloRequest)
+	s1 := DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            DatastorePostgres,
+		Collection:         "users",
+		Operation:          "INSERT",
+		ParameterizedQuery: "INSERT INTO users (name, age) VALUES ($1, $2)",
+		Host:               "pgbouncer.internal",
+		PortPathOrID:       "6432",
+	}
+	s1.End()
+	s2 := DatastoreSegment{
+		StartTime:    txn.StartSegmentNow(),
+		Product:      DatastorePostgres,
+		Collection:   "users",
+		Operation:    "INSERT",
+		Host:         "db-server-1",
+		PortPathOrID: "5432",
+	}
+	s2.End()
+	txn.End()
+
+	app.ExpectSlowQueries(t, []internal.WantSlowQuery{
+		{
+			Count:        1,
+			MetricName:   "Datastore/statement/Postgres/users/INSERT",
+			Query:        "INSERT INTO users (name, age) VALUES ($1, $2)",
+			TxnName:      "WebTransaction/Go/hello",
+			TxnURL:       "/hello",
+			DatabaseName: "",
+			Host:         "orders-db",
+			PortPathOrID: "6432",
+		},
+		{
+			Count:        1,
+			MetricName:   "Datastore/statement/Postgres/users/INSERT",
+			Query:        "'INSERT' on 'users' using 'Postgres'",
+			TxnName:      "WebTransaction/Go/hello",
+			TxnURL:       "/hello",
+			DatabaseName: "",
+			Host:         "db-server-1",
+			PortPathOrID: "5432",
+		},
+	})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/instance/Postgres/orders-db/6432", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/instance/Postgres/db-server-1/5432", Scope: "", Forced: false, Data: nil},
+	})
+}
+
 func TestSlowQueryInstanceDisabledLocalhost(t *testing.T) {
 	cfgfn := func(cfg *Config) {
 		cfg.DatastoreTracer.SlowQuery.Threshold = 0
@@ -902,13 +962,13 @@ func TestSlowQueryParamsInvalid(t *testing.T) {
 		Operation:          "INSERT",
 		ParameterizedQuery: "INSERT INTO users (name, age) VALUES ($1, $2)",
 		QueryParameters: map[string]interface{}{
-			"cookies": []string{"chocolate", "sugar", "oatmeal"},
+			"cookies": []interface{}{"chocolate", "sugar", "oatmeal"},
 			"number":  5,
 		},
 	}
 	s1.End()
 	app.expectSingleLoggedError(t, "unable to end datastore segment", map[string]interface{}{
-		"reason": "attribute 'cookies' value of type []string is invalid",
+		"reason": "attribute 'cookies' value of type []interface {} is invalid",
 	})
 	txn.End()
 
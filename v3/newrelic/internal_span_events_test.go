@@ -4,8 +4,10 @@
 package newrelic
 
 import (
+	"errors"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/rainforestpay/go-agent/v3/internal"
 )
@@ -184,6 +186,63 @@ func TestSpanEventDatastoreExternal(t *testing.T) {
 	})
 }
 
+func TestSpanEventDatastorePipeline(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	segment := DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            DatastoreRedis,
+		PipelineOperations: []string{"get", "set", "get"},
+	}
+	segment.End()
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"sampled":   true,
+				"name":      "Datastore/operation/Redis/pipeline",
+				"category":  "datastore",
+				"component": "Redis",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"db.statement":    "'pipeline' on 'unknown' using 'Redis'",
+				"db.commandCount": 3,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+	scope := "OtherTransaction/Go/hello"
+	app.ExpectMetrics(t, append([]internal.WantMetric{
+		{Name: "Datastore/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/allOther", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/Redis/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/Redis/allOther", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/operation/Redis/pipeline", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/operation/Redis/pipeline", Scope: scope, Forced: false, Data: nil},
+		{Name: "Datastore/pipeline/Redis/get", Scope: "", Forced: false, Data: []float64{2, 0, 0, 0, 0, 0}},
+		{Name: "Datastore/pipeline/Redis/set", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+	}, backgroundMetricsUnknownCaller...))
+}
+
 func TestSpanEventAttributesDisabled(t *testing.T) {
 	// Test that SpanEvents.Attributes.Enabled correctly disables span
 	// attributes.
@@ -893,6 +952,150 @@ func TestSpanEventHTTPStatusCode(t *testing.T) {
 	})
 }
 
+func TestSpanEventExternalOutcome(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	s := ExternalSegment{
+		StartTime: txn.StartSegmentNow(),
+		Host:      "payments",
+		Library:   "grpc",
+		Procedure: "Charge",
+		Outcome: ExternalOutcome{
+			RetryCount:   2,
+			CircuitState: ExternalCircuitHalfOpen,
+			Failure:      ExternalFailureTimeout,
+		},
+	}
+	s.End()
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"name":      "External/payments/grpc/Charge",
+				"category":  "http",
+				"component": "grpc",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"external.retryCount":   2,
+				"external.circuitState": "half_open",
+				"external.failure":      "timeout",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestSpanEventExternalHedge(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	for _, winner := range []bool{false, true} {
+		s := ExternalSegment{
+			StartTime: txn.StartSegmentNow(),
+			Host:      "payments",
+			Library:   "grpc",
+			Procedure: "Charge",
+		}
+		s.SetHedge("charge-1234", winner)
+		s.End()
+	}
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	var nilSegment *ExternalSegment
+	nilSegment.SetHedge("charge-1234", true)
+
+	external := func(winner bool) internal.WantEvent {
+		return internal.WantEvent{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"name":      "External/payments/grpc/Charge",
+				"category":  "http",
+				"component": "grpc",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"external.hedge.id":     "charge-1234",
+				"external.hedge.winner": winner,
+			},
+		}
+	}
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		external(false),
+		external(true),
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestSpanEventExternalOutcomeExcluded(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.SpanEvents.Attributes.Exclude = []string{SpanAttributeExternalCircuitState}
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	s := ExternalSegment{
+		StartTime: txn.StartSegmentNow(),
+		Host:      "payments",
+		Outcome: ExternalOutcome{
+			CircuitState: ExternalCircuitOpen,
+			Failure:      ExternalFailureCircuitOpen,
+		},
+	}
+	s.End()
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"name":      "External/payments/http",
+				"category":  "http",
+				"component": "http",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"external.failure": "circuit_open",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
 func TestSpanEvent_TxnCustomAttrsAreCopied(t *testing.T) {
 	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
 	txn := app.StartTransaction("hello")
@@ -1082,6 +1285,48 @@ func TestSpanEventExcludeCustomAttrs(t *testing.T) {
 	})
 }
 
+func TestAddSpanAttributeMapValue(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	seg := txn.StartSegment("segment")
+	seg.AddAttribute("order", map[string]interface{}{
+		"id":    "abc",
+		"items": []int{1, 2},
+	})
+	app.expectNoLoggedErrors(t)
+	seg.End()
+	txn.End()
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId": internal.MatchAnything,
+				"name":     "Custom/segment",
+				"category": "generic",
+			},
+			UserAttributes: map[string]interface{}{
+				"order.id":    "abc",
+				"order.items": "[1,2]",
+			},
+			AgentAttributes: map[string]interface{}{},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
 func TestAddSpanAttributeHighSecurity(t *testing.T) {
 	cfgfn := func(cfg *Config) {
 		cfg.DistributedTracer.Enabled = true
@@ -1160,3 +1405,284 @@ func TestAddSpanAttributeSecurityPolicyDisablesParameters(t *testing.T) {
 		},
 	})
 }
+
+func TestSpanEventCompression(t *testing.T) {
+	// Test that consecutive identical short spans are merged when span
+	// compression is enabled.
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.SpanEvents.Compression.Enabled = true
+		cfg.SpanEvents.Compression.MaxDuration = time.Hour
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	for _, collection := range []string{"users", "users", "users", "orders"} {
+		segment := DatastoreSegment{
+			StartTime:  txn.StartSegmentNow(),
+			Product:    DatastoreMySQL,
+			Collection: collection,
+			Operation:  "select",
+		}
+		segment.End()
+	}
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"name":      "Datastore/statement/MySQL/users/select",
+				"category":  "datastore",
+				"component": "MySQL",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"db.collection":          "users",
+				"db.statement":           "'select' on 'users' using 'MySQL'",
+				"nr.compressed.count":    3,
+				"nr.compressed.duration": internal.MatchAnything,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"name":      "Datastore/statement/MySQL/orders/select",
+				"category":  "datastore",
+				"component": "MySQL",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"db.collection": "orders",
+				"db.statement":  "'select' on 'orders' using 'MySQL'",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestSpanEventCompressionReferenced(t *testing.T) {
+	// Test that spans whose identifier was used, eg. in outbound
+	// distributed tracing headers, and spans longer than the maximum
+	// duration are not merged.
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.SpanEvents.Compression.Enabled = true
+		cfg.SpanEvents.Compression.MaxDuration = time.Hour
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.StartSegment("work").End()
+	seg := txn.StartSegment("work")
+	txn.InsertDistributedTraceHeaders(http.Header{})
+	seg.End()
+	txn.StartSegment("work").End()
+	txn.End()
+
+	work := internal.WantEvent{
+		Intrinsics: map[string]interface{}{
+			"parentId": internal.MatchAnything,
+			"name":     "Custom/work",
+			"category": "generic",
+		},
+		UserAttributes:  map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{},
+	}
+	app.ExpectSpanEvents(t, []internal.WantEvent{work, work, work, {
+		Intrinsics: map[string]interface{}{
+			"name":             "OtherTransaction/Go/hello",
+			"transaction.name": "OtherTransaction/Go/hello",
+			"sampled":          true,
+			"category":         "generic",
+			"nr.entryPoint":    true,
+		},
+		UserAttributes:  map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{},
+	}})
+
+	app = testApp(replyfn, func(cfg *Config) {
+		cfgfn(cfg)
+		cfg.SpanEvents.Compression.MaxDuration = time.Millisecond
+	}, t)
+	txn = app.StartTransaction("hello")
+	seg = txn.StartSegment("work")
+	time.Sleep(2 * time.Millisecond)
+	seg.End()
+	txn.StartSegment("work").End()
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{work, work, {
+		Intrinsics: map[string]interface{}{
+			"name":             "OtherTransaction/Go/hello",
+			"transaction.name": "OtherTransaction/Go/hello",
+			"sampled":          true,
+			"category":         "generic",
+			"nr.entryPoint":    true,
+		},
+		UserAttributes:  map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{},
+	}})
+}
+
+func TestSegmentGuardsEndUnfinished(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.SegmentGuards.EndUnfinished = true
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.StartSegment("forgotten")
+	txn.StartSegmentNow()
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Custom/forgotten", Scope: "OtherTransaction/Go/hello"},
+		{Name: "Custom/Unfinished", Scope: "OtherTransaction/Go/hello"},
+	})
+	app.ExpectSpanEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"parentId": internal.MatchAnything,
+			"name":     "Custom/Unfinished",
+			"category": "generic",
+		},
+		UserAttributes: map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{
+			SpanAttributeUnfinished: true,
+		},
+	}, {
+		Intrinsics: map[string]interface{}{
+			"parentId": internal.MatchAnything,
+			"name":     "Custom/forgotten",
+			"category": "generic",
+		},
+		UserAttributes: map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{
+			SpanAttributeUnfinished: true,
+		},
+	}, {
+		Intrinsics: map[string]interface{}{
+			"name":             "OtherTransaction/Go/hello",
+			"transaction.name": "OtherTransaction/Go/hello",
+			"sampled":          true,
+			"category":         "generic",
+			"nr.entryPoint":    true,
+		},
+		UserAttributes:  map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{},
+	}})
+}
+
+func TestSegmentGuardsMaxDuration(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.SegmentGuards.MaxDuration = time.Millisecond
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	seg := txn.StartSegment("slow")
+	time.Sleep(2 * time.Millisecond)
+	seg.End()
+	txn.StartSegment("fast").End()
+	txn.End()
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"parentId": internal.MatchAnything,
+			"name":     "Custom/slow",
+			"category": "generic",
+			"duration": 0.001,
+		},
+		UserAttributes: map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{
+			SpanAttributeDurationCapped: true,
+		},
+	}, {
+		Intrinsics: map[string]interface{}{
+			"parentId": internal.MatchAnything,
+			"name":     "Custom/fast",
+			"category": "generic",
+		},
+		UserAttributes:  map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{},
+	}, {
+		Intrinsics: map[string]interface{}{
+			"name":             "OtherTransaction/Go/hello",
+			"transaction.name": "OtherTransaction/Go/hello",
+			"sampled":          true,
+			"category":         "generic",
+			"nr.entryPoint":    true,
+		},
+		UserAttributes:  map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{},
+	}})
+}
+
+func TestDisableSpanEvents(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.StartSegment("before").End()
+	txn.DisableSpanEvents()
+	txn.StartSegment("after").End()
+	txn.NoticeError(errors.New("oops"))
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{})
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName: "OtherTransaction/Go/hello",
+		Msg:     "oops",
+		Klass:   "*errors.errorString",
+	}})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "OtherTransaction/Go/hello", Scope: "", Forced: true, Data: nil},
+		{Name: "Custom/after", Scope: "OtherTransaction/Go/hello", Forced: false, Data: nil},
+	})
+}
+
+func TestWithoutSpanEvents(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello", WithoutSpanEvents())
+	txn.StartSegment("segment").End()
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{})
+
+	txn = app.StartTransaction("hello")
+	txn.End()
+	txn.DisableSpanEvents()
+	app.expectSingleLoggedError(t, "unable to disable span events", map[string]interface{}{
+		"reason": errAlreadyEnded.Error(),
+	})
+}
@@ -197,3 +197,39 @@ func TestSyntheticsStandalone(t *testing.T) {
 		},
 	})
 }
+
+func TestSyntheticsIgnored(t *testing.T) {
+	cfgFn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = false
+		cfg.CrossApplicationTracer.Enabled = false
+		cfg.Transaction.IgnoreSynthetics = true
+	}
+	app := testApp(syntheticsConnectReplyFn, cfgFn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequestHTTP(inboundSyntheticsRequestBuilder(false, false))
+	txn.NoticeError(myError{})
+	txn.End()
+
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "WebTransaction/Go/hello", Scope: "", Forced: true, Data: nil},
+		{Name: "WebTransaction", Scope: "", Forced: true, Data: nil},
+		{Name: "WebTransactionTotalTime/Go/hello", Scope: "", Forced: false, Data: nil},
+		{Name: "WebTransactionTotalTime", Scope: "", Forced: true, Data: nil},
+		{Name: "HttpDispatcher", Scope: "", Forced: true, Data: nil},
+	})
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":                    "WebTransaction/Go/hello",
+			"nr.syntheticsResourceId": "rrrrrrr-rrrr-1234-rrrr-rrrrrrrrrrrr",
+			"nr.syntheticsJobId":      "jjjjjjj-jjjj-1234-jjjj-jjjjjjjjjjjj",
+			"nr.syntheticsMonitorId":  "mmmmmmm-mmmm-1234-mmmm-mmmmmmmmmmmm",
+			"nr.guid":                 internal.MatchAnything,
+			"error":                   true,
+		},
+	}})
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName: "WebTransaction/Go/hello",
+		Msg:     "my msg",
+		Klass:   "newrelic.myError",
+	}})
+}
@@ -4,8 +4,11 @@
 package newrelic
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"math"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -32,6 +35,15 @@ var (
 		{Name: "Errors/allWeb", Scope: "", Forced: true, Data: singleCount},
 		{Name: "Errors/WebTransaction/Go/hello", Scope: "", Forced: true, Data: singleCount},
 	}, webMetrics...)
+	webMetrics2xx = append([]internal.WantMetric{
+		{Name: "HttpDispatcher/StatusClass/2xx", Scope: "", Forced: true, Data: singleCount},
+	}, webMetrics...)
+	webMetrics4xx = append([]internal.WantMetric{
+		{Name: "HttpDispatcher/StatusClass/4xx", Scope: "", Forced: true, Data: singleCount},
+	}, webMetrics...)
+	webErrorMetrics4xx = append([]internal.WantMetric{
+		{Name: "HttpDispatcher/StatusClass/4xx", Scope: "", Forced: true, Data: singleCount},
+	}, webErrorMetrics...)
 	backgroundMetrics = []internal.WantMetric{
 		{Name: "OtherTransaction/Go/hello", Scope: "", Forced: true, Data: nil},
 		{Name: "OtherTransaction/all", Scope: "", Forced: true, Data: nil},
@@ -352,6 +364,101 @@ func TestRecordCustomEventRemoteDisable(t *testing.T) {
 	app.ExpectCustomEvents(t, []internal.WantEvent{})
 }
 
+func TestRecordCustomEventsSuccess(t *testing.T) {
+	app := testApp(nil, nil, t)
+	accepted, dropped := app.RecordCustomEvents([]CustomEventData{
+		{EventType: "myType", Params: validParams},
+		{EventType: "myType", Params: validParams},
+	})
+	if accepted != 2 || dropped != 0 {
+		t.Error(accepted, dropped)
+	}
+	app.expectNoLoggedErrors(t)
+	app.ExpectCustomEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"type":      "myType",
+				"timestamp": internal.MatchAnything,
+			},
+			UserAttributes: validParams,
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"type":      "myType",
+				"timestamp": internal.MatchAnything,
+			},
+			UserAttributes: validParams,
+		},
+	})
+}
+
+func TestRecordCustomEventsBadInput(t *testing.T) {
+	app := testApp(nil, nil, t)
+	accepted, dropped := app.RecordCustomEvents([]CustomEventData{
+		{EventType: "????", Params: validParams},
+		{EventType: "myType", Params: validParams},
+	})
+	if accepted != 1 || dropped != 1 {
+		t.Error(accepted, dropped)
+	}
+	app.expectSingleLoggedError(t, "unable to record custom events", map[string]interface{}{
+		"dropped": 1,
+		"reason":  errEventTypeRegex.Error(),
+	})
+	app.ExpectCustomEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"type":      "myType",
+				"timestamp": internal.MatchAnything,
+			},
+			UserAttributes: validParams,
+		},
+	})
+}
+
+func TestRecordCustomEventsReservoirFull(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		// Two custom events per harvest cycle.
+		reply.MockConnectReplyEventLimits(&internal.RequestEventLimits{CustomEvents: 24})
+	}
+	app := testApp(replyfn, nil, t)
+	app.RecordCustomEvent("myType", validParams)
+	accepted, dropped := app.RecordCustomEvents([]CustomEventData{
+		{EventType: "myType", Params: validParams},
+		{EventType: "myType", Params: validParams},
+		{EventType: "myType", Params: validParams},
+	})
+	if accepted != 1 || dropped != 2 {
+		t.Error(accepted, dropped)
+	}
+	app.expectNoLoggedErrors(t)
+}
+
+func TestRecordCustomEventsEventsDisabled(t *testing.T) {
+	cfgfn := func(cfg *Config) { cfg.CustomInsightsEvents.Enabled = false }
+	app := testApp(nil, cfgfn, t)
+	accepted, dropped := app.RecordCustomEvents([]CustomEventData{
+		{EventType: "myType", Params: validParams},
+	})
+	if accepted != 0 || dropped != 1 {
+		t.Error(accepted, dropped)
+	}
+	app.expectSingleLoggedError(t, "unable to record custom events", map[string]interface{}{
+		"reason": errCustomEventsDisabled.Error(),
+	})
+	app.ExpectCustomEvents(t, []internal.WantEvent{})
+}
+
+func TestRecordCustomEventsNilApplication(t *testing.T) {
+	var app *Application
+	accepted, dropped := app.RecordCustomEvents([]CustomEventData{
+		{EventType: "myType", Params: validParams},
+	})
+	if accepted != 0 || dropped != 1 {
+		t.Error(accepted, dropped)
+	}
+}
+
 func TestRecordCustomMetricSuccess(t *testing.T) {
 	app := testApp(nil, nil, t)
 	app.RecordCustomMetric("myMetric", 123.0)
@@ -398,6 +505,35 @@ func TestRecordCustomMetricNegativeInf(t *testing.T) {
 	})
 }
 
+func TestRecordCustomMetricReservedName(t *testing.T) {
+	app := testApp(nil, nil, t)
+	app.RecordCustomMetric("Datastore/all", 123.0)
+	app.expectSingleLoggedError(t, "unable to record custom metric", map[string]interface{}{
+		"metric-name": "Datastore/all",
+		"reason":      errMetricNameReserved.Error(),
+	})
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "Supportability/Go/CustomMetric/Rejected", Scope: "", Forced: true, Data: singleCount},
+	})
+}
+
+func TestRecordCustomMetricRequiredPrefix(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.CustomMetrics.RequiredPrefix = "Billing/"
+	}, t)
+	app.RecordCustomMetric("myMetric", 123.0)
+	app.expectSingleLoggedError(t, "unable to record custom metric", map[string]interface{}{
+		"metric-name": "myMetric",
+		"reason":      errMetricNamePrefix.Error(),
+	})
+	app.RecordCustomMetric("Billing/myMetric", 123.0)
+	expectData := []float64{1, 123.0, 123.0, 123.0, 123.0, 123.0 * 123.0}
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "Supportability/Go/CustomMetric/Rejected", Scope: "", Forced: true, Data: singleCount},
+		{Name: "Custom/Billing/myMetric", Scope: "", Forced: false, Data: expectData},
+	})
+}
+
 type sampleResponseWriter struct {
 	code    int
 	written int
@@ -497,6 +633,84 @@ func TestSetName(t *testing.T) {
 	app.ExpectMetrics(t, backgroundMetrics)
 }
 
+func TestSetNameFunc(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequestHTTP(helloRequest)
+	txn.SetNameFunc(func(info TransactionNameInfo) string {
+		if info.Name != "hello" || !info.IsWeb || info.StatusCode != 404 {
+			t.Error(info)
+		}
+		if hit, _ := info.Attributes["cache.hit"].(bool); hit {
+			return info.Name + "/cached"
+		}
+		return info.Name + "/uncached"
+	})
+	txn.AddAttribute("cache.hit", true)
+	txn.SetWebResponse(nil).WriteHeader(404)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "WebTransaction/Go/hello/cached"},
+	})
+	txn.SetNameFunc(nil)
+	app.expectSingleLoggedError(t, "unable to set transaction name function", map[string]interface{}{
+		"reason": errAlreadyEnded.Error(),
+	})
+}
+
+func TestTransactionDurationHistogram(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = false
+		cfg.TransactionDurationHistogram.Enabled = true
+		cfg.TransactionDurationHistogram.Buckets = []time.Duration{
+			time.Hour,
+			2 * time.Hour,
+		}
+	}, t)
+	app.StartTransaction("hello").End()
+	app.StartTransaction("hello").End()
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "TransactionHistogram/OtherTransaction/Go/hello/bucket/3600", Scope: "", Forced: false, Data: nil},
+	})
+
+	app = testApp(nil, func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = false
+		cfg.TransactionDurationHistogram.Enabled = true
+		cfg.TransactionDurationHistogram.Buckets = []time.Duration{time.Nanosecond}
+	}, t)
+	txn := app.StartTransaction("hello")
+	time.Sleep(time.Millisecond)
+	txn.End()
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "TransactionHistogram/OtherTransaction/Go/hello/bucket/+Inf", Scope: "", Forced: false, Data: singleCount},
+	})
+}
+
+func TestHistogramMetric(t *testing.T) {
+	buckets := []time.Duration{50 * time.Millisecond, time.Second}
+	for d, expect := range map[time.Duration]string{
+		0:                      "TransactionHistogram/WebTransaction/Go/hello/bucket/0.05",
+		50 * time.Millisecond:  "TransactionHistogram/WebTransaction/Go/hello/bucket/0.05",
+		100 * time.Millisecond: "TransactionHistogram/WebTransaction/Go/hello/bucket/1",
+		2 * time.Second:        "TransactionHistogram/WebTransaction/Go/hello/bucket/+Inf",
+	} {
+		if m := histogramMetric("WebTransaction/Go/hello", buckets, d); m != expect {
+			t.Errorf("duration=%s: expected %q, got %q", d, expect, m)
+		}
+	}
+}
+
+func TestSetNameFuncKeepsName(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("one")
+	txn.SetNameFunc(func(TransactionNameInfo) string { return "" })
+	txn.SetName("hello")
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetrics(t, backgroundMetrics)
+}
+
 func deferEndPanic(txn *Transaction, panicMe interface{}) (r interface{}) {
 	defer func() {
 		r = recover()
@@ -675,7 +889,7 @@ func TestResponseCodeError(t *testing.T) {
 			}),
 		},
 	})
-	app.ExpectMetrics(t, webErrorMetrics)
+	app.ExpectMetrics(t, webErrorMetrics4xx)
 }
 
 func TestResponseCode404Filtered(t *testing.T) {
@@ -695,7 +909,7 @@ func TestResponseCode404Filtered(t *testing.T) {
 
 	app.ExpectErrors(t, []internal.WantError{})
 	app.ExpectErrorEvents(t, []internal.WantEvent{})
-	app.ExpectMetrics(t, webMetrics)
+	app.ExpectMetrics(t, webMetrics4xx)
 }
 
 func TestResponseCodeCustomFilter(t *testing.T) {
@@ -716,7 +930,7 @@ func TestResponseCodeCustomFilter(t *testing.T) {
 
 	app.ExpectErrors(t, []internal.WantError{})
 	app.ExpectErrorEvents(t, []internal.WantEvent{})
-	app.ExpectMetrics(t, webMetrics)
+	app.ExpectMetrics(t, webMetrics4xx)
 }
 
 func TestResponseCodeServerSideFilterObserved(t *testing.T) {
@@ -740,7 +954,7 @@ func TestResponseCodeServerSideFilterObserved(t *testing.T) {
 
 	app.ExpectErrors(t, []internal.WantError{})
 	app.ExpectErrorEvents(t, []internal.WantEvent{})
-	app.ExpectMetrics(t, webMetrics)
+	app.ExpectMetrics(t, webMetrics4xx)
 }
 
 func TestResponseCodeServerSideOverwriteLocal(t *testing.T) {
@@ -782,7 +996,72 @@ func TestResponseCodeServerSideOverwriteLocal(t *testing.T) {
 			}),
 		},
 	})
-	app.ExpectMetrics(t, webErrorMetrics)
+	app.ExpectMetrics(t, webErrorMetrics4xx)
+}
+
+func TestResponseCodeStatusClassMetric(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequestHTTP(helloRequest)
+	txn.SetWebResponse(nil).WriteHeader(http.StatusServiceUnavailable)
+	txn.End()
+
+	app.ExpectMetrics(t, append([]internal.WantMetric{
+		{Name: "HttpDispatcher/StatusClass/5xx", Scope: "", Forced: true, Data: singleCount},
+	}, webErrorMetrics...))
+}
+
+func TestClientCanceledRequest(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	ctx, cancel := context.WithCancel(context.Background())
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequestHTTP(helloRequest.WithContext(ctx))
+	cancel()
+	txn.End()
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/hello",
+			"nr.apdexPerfZone": "S",
+		},
+		AgentAttributes: map[string]interface{}{
+			"request.uri":                   "/hello",
+			"request.headers.host":          "my_domain.com",
+			"request.headers.contentLength": 753,
+			"request.method":                "GET",
+			"request.headers.accept":        "text/plain",
+			"request.headers.contentType":   "text/html; charset=utf-8",
+			AttributeRequestCanceled:        true,
+		},
+	}})
+	app.ExpectMetrics(t, append([]internal.WantMetric{
+		{Name: "HttpDispatcher/ClientCanceled", Scope: "", Forced: true, Data: singleCount},
+	}, webMetrics...))
+}
+
+func TestClientRequestNotCanceled(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequestHTTP(helloRequest.WithContext(ctx))
+	txn.End()
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/hello",
+			"nr.apdexPerfZone": "S",
+		},
+		AgentAttributes: map[string]interface{}{
+			"request.uri":                   "/hello",
+			"request.headers.host":          "my_domain.com",
+			"request.headers.contentLength": 753,
+			"request.method":                "GET",
+			"request.headers.accept":        "text/plain",
+			"request.headers.contentType":   "text/html; charset=utf-8",
+		},
+	}})
+	app.ExpectMetrics(t, webMetrics)
 }
 
 func TestResponseCodeAfterEnd(t *testing.T) {
@@ -826,7 +1105,7 @@ func TestResponseCodeAfterWrite(t *testing.T) {
 
 	app.ExpectErrors(t, []internal.WantError{})
 	app.ExpectErrorEvents(t, []internal.WantEvent{})
-	app.ExpectMetrics(t, webMetrics)
+	app.ExpectMetrics(t, webMetrics2xx)
 }
 
 func TestQueueTime(t *testing.T) {
@@ -1324,6 +1603,123 @@ func TestTraceDatastoreMissingProductOperationCollection(t *testing.T) {
 	})
 }
 
+func TestTraceDatastoreDerivedFromQuery(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("hello")
+	for _, s := range []DatastoreSegment{
+		{Product: DatastorePostgres, ParameterizedQuery: "SELECT * FROM users WHERE id = $1"},
+		{Product: DatastorePostgres, Operation: "delete", ParameterizedQuery: "INSERT INTO orders (id) VALUES ($1)"},
+		{Product: DatastoreRedis, ParameterizedQuery: "SET mykey myvalue"},
+	} {
+		s.StartTime = txn.StartSegmentNow()
+		s.End()
+	}
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	scope := "OtherTransaction/Go/hello"
+	app.ExpectMetrics(t, append([]internal.WantMetric{
+		{Name: "Datastore/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/allOther", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/Postgres/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/Postgres/allOther", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/Redis/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/Redis/allOther", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/operation/Postgres/select", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/statement/Postgres/users/select", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/statement/Postgres/users/select", Scope: scope, Forced: false, Data: nil},
+		{Name: "Datastore/operation/Postgres/delete", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/operation/Postgres/delete", Scope: scope, Forced: false, Data: nil},
+		{Name: "Datastore/operation/Redis/other", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/operation/Redis/other", Scope: scope, Forced: false, Data: nil},
+	}, backgroundMetrics...))
+}
+
+func TestTraceDatastoreProductNormalized(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("hello")
+	for _, product := range []DatastoreProduct{"postgresql", " POSTGRES ", "My/Store"} {
+		s := DatastoreSegment{StartTime: txn.StartSegmentNow(), Product: product, Operation: "get"}
+		s.End()
+	}
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	scope := "OtherTransaction/Go/hello"
+	app.ExpectMetrics(t, append([]internal.WantMetric{
+		{Name: "Datastore/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/allOther", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/Postgres/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/Postgres/allOther", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/operation/Postgres/get", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/operation/Postgres/get", Scope: scope, Forced: false, Data: nil},
+		{Name: "Datastore/My_Store/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/My_Store/allOther", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/operation/My_Store/get", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/operation/My_Store/get", Scope: scope, Forced: false, Data: nil},
+	}, backgroundMetrics...))
+}
+
+func TestNormalizeDatastoreProduct(t *testing.T) {
+	testcases := map[DatastoreProduct]DatastoreProduct{
+		"":             "",
+		"MySQL":        DatastoreMySQL,
+		"sqlite3":      DatastoreSQLite,
+		"CRDB":         DatastoreCockroachDB,
+		"scyllaDB":     DatastoreScylla,
+		"clickhouse":   DatastoreClickHouse,
+		"tidb":         DatastoreTiDB,
+		"duckdb":       DatastoreDuckDB,
+		"libsql":       DatastoreLibSQL,
+		"sqlcipher":    DatastoreSQLCipher,
+		" SqlServer\t": DatastoreMSSQL,
+		"MyStore":      "MyStore",
+		"a/b":          "a_b",
+	}
+	for product, expect := range testcases {
+		if p := normalizeDatastoreProduct(product); p != expect {
+			t.Errorf("%q: got %q, expected %q", product, p, expect)
+		}
+	}
+}
+
+func TestDatastoreSegmentSetInstance(t *testing.T) {
+	testcases := []struct {
+		network, address string
+		host, ppoid      string
+	}{
+		{network: "tcp", address: "db.example.com:5432", host: "db.example.com", ppoid: "5432"},
+		{network: "", address: "[::1]:6379", host: "::1", ppoid: "6379"},
+		{network: "tcp", address: ":3306", host: "localhost", ppoid: "3306"},
+		{network: "unix", address: "/var/run/redis.sock", host: "localhost", ppoid: "/var/run/redis.sock"},
+		{network: "unixpacket", address: "relative.sock", host: "localhost", ppoid: "relative.sock"},
+		{network: "", address: "/tmp/mysql.sock", host: "localhost", ppoid: "/tmp/mysql.sock"},
+		{network: "ip4:1", address: "192.0.2.1", host: "192.0.2.1", ppoid: ""},
+		{network: "tcp", address: "", host: "", ppoid: ""},
+	}
+	for _, tc := range testcases {
+		s := &DatastoreSegment{}
+		s.SetInstance(tc.network, tc.address)
+		if s.Host != tc.host || s.PortPathOrID != tc.ppoid {
+			t.Errorf("%s %q: got %q %q", tc.network, tc.address, s.Host, s.PortPathOrID)
+		}
+	}
+
+	s := &DatastoreSegment{}
+	s.SetInstanceFromAddr(&net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5432})
+	if s.Host != "10.0.0.1" || s.PortPathOrID != "5432" {
+		t.Error(s.Host, s.PortPathOrID)
+	}
+	s.SetInstanceFromAddr(&net.UnixAddr{Name: "/tmp/.s.PGSQL.5432", Net: "unix"})
+	if s.Host != "localhost" || s.PortPathOrID != "/tmp/.s.PGSQL.5432" {
+		t.Error(s.Host, s.PortPathOrID)
+	}
+	s.SetInstanceFromAddr(nil)
+	if s.Host != "localhost" {
+		t.Error(s.Host)
+	}
+	var nilSegment *DatastoreSegment
+	nilSegment.SetInstance("tcp", "db:1")
+}
+
 func TestTraceDatastoreNilTxn(t *testing.T) {
 	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
 	txn := app.StartTransaction("hello")
@@ -1434,6 +1830,49 @@ func TestTraceExternal(t *testing.T) {
 	})
 }
 
+func TestBreakdownHostMetrics(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = false
+		cfg.BreakdownHostMetrics.Enabled = true
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequestHTTP(helloRequest)
+	s := ExternalSegment{
+		StartTime: txn.StartSegmentNow(),
+		URL:       "http://example.com/",
+	}
+	s.End()
+	ds := DatastoreSegment{
+		StartTime:    txn.StartSegmentNow(),
+		Product:      DatastoreMySQL,
+		Collection:   "users",
+		Operation:    "INSERT",
+		Host:         "db-server-1",
+		PortPathOrID: "3306",
+	}
+	ds.End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	scope := "WebTransaction/Go/hello"
+	app.ExpectMetrics(t, append([]internal.WantMetric{
+		{Name: "External/all", Scope: "", Forced: true, Data: nil},
+		{Name: "External/allWeb", Scope: "", Forced: true, Data: nil},
+		{Name: "External/example.com/all", Scope: "", Forced: false, Data: nil},
+		{Name: "External/example.com/http", Scope: scope, Forced: false, Data: nil},
+		{Name: "Breakdown/WebTransaction/Go/hello/External/example.com/all", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/allWeb", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/MySQL/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/MySQL/allWeb", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/operation/MySQL/INSERT", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/statement/MySQL/users/INSERT", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/statement/MySQL/users/INSERT", Scope: scope, Forced: false, Data: nil},
+		{Name: "Datastore/instance/MySQL/db-server-1/3306", Scope: "", Forced: false, Data: nil},
+		{Name: "Breakdown/WebTransaction/Go/hello/Datastore/instance/MySQL/db-server-1/3306", Scope: "", Forced: false, Data: nil},
+	}, webMetrics...))
+}
+
 func TestExternalSegmentCustomFieldsWithURL(t *testing.T) {
 	replyfn := func(reply *internal.ConnectReply) {
 		reply.SetSampleEverything()
@@ -1848,6 +2287,43 @@ func TestTraceNoSegments(t *testing.T) {
 	})
 }
 
+func TestTraceForced(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("fast")
+	txn.End()
+	txn = app.StartTransaction("hello")
+	txn.ForceTrace()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnTraces(t, []internal.WantTxnTrace{
+		{MetricName: "OtherTransaction/Go/hello"},
+	})
+	txn.ForceTrace()
+	app.expectSingleLoggedError(t, "unable to force transaction trace", map[string]interface{}{
+		"reason": errAlreadyEnded.Error(),
+	})
+}
+
+func TestTraceForcedOption(t *testing.T) {
+	app := testApp(nil, ConfigDistributedTracerEnabled(false), t)
+	txn := app.StartTransaction("hello", WithForcedTrace())
+	txn.End()
+	app.ExpectTxnTraces(t, []internal.WantTxnTrace{
+		{MetricName: "OtherTransaction/Go/hello"},
+	})
+}
+
+func TestTraceForcedDisabledLocally(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.TransactionTracer.Enabled = false
+		cfg.DistributedTracer.Enabled = false
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello", WithForcedTrace())
+	txn.End()
+	app.ExpectTxnTraces(t, []internal.WantTxnTrace{})
+}
+
 func TestTraceDisabledLocally(t *testing.T) {
 	cfgfn := func(cfg *Config) {
 		cfg.TransactionTracer.Threshold.IsApdexFailing = false
@@ -2254,3 +2730,198 @@ func TestMessageProducerSegmentNilSegment(t *testing.T) {
 	var s *MessageProducerSegment
 	s.End()
 }
+
+func TestApdexBackgroundTransactions(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.Apdex.BackgroundTransactions = true
+	}, t)
+	txn := app.StartTransaction("hello")
+	txn.End()
+	app.ExpectMetrics(t, append([]internal.WantMetric{
+		{Name: "ApdexOther", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0.5, 0.5, 0}},
+		{Name: "ApdexOther/Transaction/Go/hello", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0.5, 0.5, 0}},
+	}, backgroundMetricsUnknownCaller...))
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "OtherTransaction/Go/hello",
+			"nr.apdexPerfZone": "S",
+			"guid":             internal.MatchAnything,
+			"traceId":          internal.MatchAnything,
+			"priority":         internal.MatchAnything,
+			"sampled":          internal.MatchAnything,
+		},
+	}})
+}
+
+func TestApdexResponseCodeZones(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.Apdex.BackgroundTransactions = true
+		cfg.Apdex.ResponseCodeZones = map[int]ApdexZone{
+			4: ApdexFrustrating,
+			8: ApdexTolerating,
+		}
+	}, t)
+	for _, code := range []int{4, 8, 0} {
+		txn := app.StartTransaction("hello")
+		txn.SetWebResponse(nil).WriteHeader(code)
+		txn.End()
+	}
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "ApdexOther", Scope: "", Forced: true, Data: []float64{1, 1, 1, 0.5, 0.5, 0}},
+	})
+}
+
+func TestApdexResponseCodeZonesWeb(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.Apdex.ResponseCodeZones = map[int]ApdexZone{
+			503: ApdexTolerating,
+		}
+	}, t)
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequestHTTP(helloRequest)
+	txn.SetWebResponse(nil).WriteHeader(503)
+	txn.End()
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Apdex", Scope: "", Forced: true, Data: []float64{0, 1, 0, 0.5, 0.5, 0}},
+		{Name: "Apdex/Go/hello", Scope: "", Forced: false, Data: []float64{0, 1, 0, 0.5, 0.5, 0}},
+	})
+}
+
+func detailLevelTestSegments(txn *Transaction) {
+	s := DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            DatastoreMySQL,
+		Collection:         "users",
+		Operation:          "INSERT",
+		ParameterizedQuery: "INSERT INTO users (name) VALUES ($1)",
+		QueryParameters:    map[string]interface{}{"name": "zap"},
+	}
+	s.End()
+	txn.NoticeError(errors.New("oops"))
+}
+
+func TestSetDetailLevelBasic(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.DatastoreTracer.SlowQuery.Threshold = 0
+		cfg.TransactionTracer.Segments.Threshold = 0
+		cfg.TransactionTracer.Segments.StackTraceThreshold = 0
+		cfg.DistributedTracer.Enabled = false
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetDetailLevel(DetailLevelBasic)
+	detailLevelTestSegments(txn)
+	app.expectNoLoggedErrors(t)
+
+	internalTxn := txn.thread.txn
+	if stack := internalTxn.Errors[0].Stack; nil != stack {
+		t.Error("unexpected error stack trace", stack)
+	}
+	if stack := internalTxn.SlowQueries.priorityQueue[0].StackTrace; nil != stack {
+		t.Error("unexpected slow query stack trace", stack)
+	}
+	if 0 == len(internalTxn.TxnTrace.nodes) {
+		t.Fatal("missing segments")
+	}
+	for _, node := range internalTxn.TxnTrace.nodes {
+		if nil != node.StackTrace {
+			t.Error("unexpected segment stack trace", node.name)
+		}
+	}
+	txn.End()
+
+	app.ExpectSlowQueries(t, []internal.WantSlowQuery{{
+		Count:      1,
+		MetricName: "Datastore/statement/MySQL/users/INSERT",
+		Query:      "INSERT INTO users (name) VALUES ($1)",
+		TxnName:    "OtherTransaction/Go/hello",
+		Params:     map[string]interface{}{},
+	}})
+}
+
+func TestSetDetailLevelVerbose(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.DatastoreTracer.SlowQuery.Threshold = 0
+		cfg.DatastoreTracer.QueryParameters.Enabled = false
+		cfg.TransactionTracer.Segments.Threshold = 0
+		cfg.TransactionTracer.Segments.StackTraceThreshold = time.Hour
+		cfg.DistributedTracer.Enabled = false
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetDetailLevel(DetailLevelVerbose)
+	detailLevelTestSegments(txn)
+	app.expectNoLoggedErrors(t)
+
+	internalTxn := txn.thread.txn
+	if nil == internalTxn.Errors[0].Stack {
+		t.Error("missing error stack trace")
+	}
+	if 0 == len(internalTxn.TxnTrace.nodes) {
+		t.Fatal("missing segments")
+	}
+	for _, node := range internalTxn.TxnTrace.nodes {
+		if nil == node.StackTrace {
+			t.Error("missing segment stack trace", node.name)
+		}
+	}
+	txn.End()
+
+	app.ExpectSlowQueries(t, []internal.WantSlowQuery{{
+		Count:      1,
+		MetricName: "Datastore/statement/MySQL/users/INSERT",
+		Query:      "INSERT INTO users (name) VALUES ($1)",
+		TxnName:    "OtherTransaction/Go/hello",
+		Params:     map[string]interface{}{"name": "zap"},
+	}})
+}
+
+func TestSetDetailLevelVerboseHighSecurity(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.DatastoreTracer.SlowQuery.Threshold = 0
+		cfg.HighSecurity = true
+		cfg.DistributedTracer.Enabled = false
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetDetailLevel(DetailLevelVerbose)
+	detailLevelTestSegments(txn)
+	txn.End()
+
+	app.ExpectSlowQueries(t, []internal.WantSlowQuery{{
+		Count:      1,
+		MetricName: "Datastore/statement/MySQL/users/INSERT",
+		Query:      "INSERT INTO users (name) VALUES ($1)",
+		TxnName:    "OtherTransaction/Go/hello",
+		Params:     map[string]interface{}{},
+	}})
+}
+
+func TestSetDetailLevelInvalid(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.SetDetailLevel(DetailLevel(7))
+	app.expectSingleLoggedError(t, "unable to set detail level", map[string]interface{}{
+		"reason": errDetailLevel.Error(),
+	})
+	txn.End()
+	txn.SetDetailLevel(DetailLevelBasic)
+	app.expectSingleLoggedError(t, "unable to set detail level", map[string]interface{}{
+		"reason": errAlreadyEnded.Error(),
+	})
+}
+
+func TestSetDetailLevelBasicRemovesCodeLevelMetrics(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.CodeLevelMetrics.Enabled = true
+	}, t)
+	txn := app.StartTransaction("hello")
+	txn.SetDetailLevel(DetailLevelBasic)
+	txn.SetOption()
+	txn.End()
+	for _, attr := range []string{AttributeCodeFunction, AttributeCodeNamespace, AttributeCodeFilepath, AttributeCodeLineno} {
+		if _, ok := txn.thread.txn.Attrs.Agent[attr]; ok {
+			t.Error("unexpected attribute", attr)
+		}
+	}
+}
@@ -4,6 +4,9 @@
 package newrelic
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
@@ -11,6 +14,7 @@ import (
 	"net/url"
 	"reflect"
 	"runtime/debug"
+	"strings"
 	"sync"
 	"time"
 
@@ -32,10 +36,38 @@ type txn struct {
 
 	ignore bool
 
+	// nameFunc is registered using SetNameFunc and names the transaction
+	// when it ends.
+	nameFunc func(TransactionNameInfo) string
+
 	// wroteHeader prevents capturing multiple response code errors if the
 	// user erroneously calls WriteHeader multiple times.
 	wroteHeader bool
 
+	// spanEventsDisabled is set using Transaction.DisableSpanEvents or
+	// WithoutSpanEvents.
+	spanEventsDisabled bool
+
+	// requestURITemplate is set when the request.uri attribute is
+	// recorded once the transaction's name is frozen, see
+	// RequestURITemplate.
+	requestURITemplate bool
+
+	// detailLevel is set using Transaction.SetDetailLevel.
+	detailLevel DetailLevel
+
+	// debugTraceToken is the value of the accepted
+	// DistributedTracer.DebugHeader, which is propagated to outbound
+	// requests.
+	debugTraceToken string
+
+	// browserCorrelationToken is created by BrowserCorrelationToken.
+	browserCorrelationToken string
+
+	// requestContext is the context of the web request, used to detect
+	// requests canceled by the client.
+	requestContext context.Context
+
 	txnData
 
 	mainThread   tracingThread
@@ -88,13 +120,19 @@ func (txn *txn) setOption(opts ...TraceOption) {
 	// remove those attributes now entirely. We've already spent the time to collect
 	// the data, but that's water under the bridge at this point and the user is saying
 	// explicitly they don't want them.
-	if txnOpts.SuppressCLM {
+	if txnOpts.SuppressCLM || txn.detailLevel == DetailLevelBasic {
 		removeCodeLevelMetrics(txn.Attrs.Agent.Remove)
 	} else if txn.appRun != nil && txn.appRun.Config.CodeLevelMetrics.Enabled && (txn.appRun.Config.CodeLevelMetrics.Scope == 0 || (txn.appRun.Config.CodeLevelMetrics.Scope&TransactionCLM) != 0) {
 		// If we're given an explicit code location to report, do that now. This will override
 		// any previous code-level metrics information in the transaction.
 		reportCodeLevelMetrics(txnOpts, txn.appRun, txn.Attrs.Agent.Add)
 	}
+	if txnOpts.ForceTrace {
+		txn.TxnTrace.forced = true
+	}
+	if txnOpts.NoSpanEvents {
+		txn.spanEventsDisabled = true
+	}
 }
 
 func newTxn(app *app, run *appRun, name string, opts ...TraceOption) *thread {
@@ -106,7 +144,7 @@ func newTxn(app *app, run *appRun, name string, opts ...TraceOption) *thread {
 	for _, o := range opts {
 		o(&txnOpts)
 	}
-	txn.markStart(time.Now())
+	txn.markStart(txn.Config.Clock.Now())
 
 	txn.Name = name
 	txn.Attrs = newAttributes(run.AttributeConfig)
@@ -125,11 +163,25 @@ func newTxn(app *app, run *appRun, name string, opts ...TraceOption) *thread {
 	}
 
 	txn.Attrs.Agent.Add(AttributeHostDisplayName, txn.Config.HostDisplayName, nil)
+	deploymentMetadataAttributes(txn.Attrs, txn.Config.Config)
 	txn.TxnTrace.Enabled = txn.Config.TransactionTracer.Enabled
 	txn.TxnTrace.SegmentThreshold = txn.Config.TransactionTracer.Segments.Threshold
 	txn.TxnTrace.StackTraceThreshold = txn.Config.TransactionTracer.Segments.StackTraceThreshold
+	txn.TxnTrace.maxSize = txn.Config.TransactionTracer.MaxTraceSize
+	txn.TxnTrace.forced = txnOpts.ForceTrace
+	txn.spanEventsDisabled = txnOpts.NoSpanEvents
 	txn.SlowQueriesEnabled = txn.Config.DatastoreTracer.SlowQuery.Enabled
 	txn.SlowQueryThreshold = txn.Config.DatastoreTracer.SlowQuery.Threshold
+	txn.slowQueriesByInstance = txn.Config.DatastoreTracer.SlowQuery.AggregateByInstance
+	txn.maxSegmentDuration = txn.Config.SegmentGuards.MaxDuration
+	txn.relationshipMetrics = txn.Config.DistributedTracer.RelationshipMetrics
+	txn.breakdownHostMetrics = txn.Config.BreakdownHostMetrics.Enabled
+	if txn.Config.TransactionDurationHistogram.Enabled {
+		txn.histogramBuckets = txn.Config.TransactionDurationHistogram.Buckets
+	}
+	if txn.Config.SpanEvents.Compression.Enabled {
+		txn.spanCompressionMaxDuration = txn.Config.SpanEvents.Compression.MaxDuration
+	}
 
 	// Synthetics support is tied up with a transaction's Old CAT field,
 	// CrossProcess. To support Synthetics with either BetterCAT or Old CAT,
@@ -138,6 +190,7 @@ func newTxn(app *app, run *appRun, name string, opts ...TraceOption) *thread {
 	doOldCAT := txn.Config.CrossApplicationTracer.Enabled
 	noGUID := txn.Config.DistributedTracer.Enabled
 	txn.CrossProcess.Init(doOldCAT, noGUID, run.Reply)
+	txn.CrossProcess.ReadOnly = txn.Config.CrossApplicationTracer.ReadOnly
 
 	return &thread{
 		txn:    txn,
@@ -166,12 +219,72 @@ func (txn *txn) shouldCollectSpanEvents() bool {
 	if !txn.Config.SpanEvents.Enabled {
 		return false
 	}
+	if txn.spanEventsDisabled {
+		return false
+	}
 	if shouldUseTraceObserver(txn.Config) {
 		return true
 	}
 	return txn.lazilyCalculateSampled()
 }
 
+var (
+	errSamplingDTDisabled = errors.New("DistributedTracer must be enabled to set the sampling decision")
+	errPriorityRange      = errors.New("priority must be between 0 and 1")
+)
+
+// checkSamplingOverride returns an error if the sampling decision can no
+// longer be changed, either because the transaction has ended or because
+// the decision has already been propagated to another service.
+func (txn *txn) checkSamplingOverride() error {
+	if !txn.BetterCAT.Enabled {
+		return errSamplingDTDisabled
+	}
+	if txn.finished {
+		return errAlreadyEnded
+	}
+	if txn.numPayloadsCreated > 0 {
+		return errOutboundPayloadCreated
+	}
+	return nil
+}
+
+func (txn *txn) SetSampled(sampled bool) error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if err := txn.checkSamplingOverride(); nil != err {
+		return err
+	}
+	// Sampled transactions have a priority between 1 and 2 so that they
+	// are retained in preference to those which are not sampled.
+	if sampled && txn.BetterCAT.Priority < 1 {
+		txn.BetterCAT.Priority += 1.0
+	} else if !sampled && txn.BetterCAT.Priority >= 1 {
+		txn.BetterCAT.Priority -= 1.0
+	}
+	txn.BetterCAT.Sampled = sampled
+	txn.sampledCalculated = true
+	return nil
+}
+
+func (txn *txn) SetPriority(p float32) error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if err := txn.checkSamplingOverride(); nil != err {
+		return err
+	}
+	if p < 0 || p > 1 {
+		return errPriorityRange
+	}
+	txn.BetterCAT.Priority = priority(p)
+	if txn.sampledCalculated && txn.BetterCAT.Sampled {
+		txn.BetterCAT.Priority += 1.0
+	}
+	return nil
+}
+
 func (txn *txn) shouldCreateSpanGUID() bool {
 	if !txn.Config.DistributedTracer.Enabled {
 		return false
@@ -193,7 +306,11 @@ func (txn *txn) lazilyCalculateSampled() bool {
 	if txn.sampledCalculated {
 		return txn.BetterCAT.Sampled
 	}
-	txn.BetterCAT.Sampled = txn.appRun.adaptiveSampler.computeSampled(txn.BetterCAT.Priority.Float32(), time.Now())
+	if sampled, ok := computeSampledByRule(txn.Config.samplingRules, txn.Name, txn.BetterCAT.Priority); ok {
+		txn.BetterCAT.Sampled = sampled
+	} else {
+		txn.BetterCAT.Sampled = txn.appRun.adaptiveSampler.computeSampled(txn.BetterCAT.Priority.Float32(), txn.Config.Clock.Now())
+	}
 	if txn.BetterCAT.Sampled {
 		txn.BetterCAT.Priority += 1.0
 	}
@@ -217,9 +334,23 @@ func (txn *txn) SetWebRequest(r WebRequest) error {
 		txn.Queuing = queueDuration(h, txn.Start)
 		txn.acceptDistributedTraceHeadersLocked(r.Transport, h)
 		txn.CrossProcess.InboundHTTPRequest(h)
+		if md := httpHeaderToMetadata(h); "" != md.ID || "" != md.TxnData {
+			txn.CrossAppTracingSupport.InboundReceived = true
+			txn.CrossAppTracingSupport.InboundIgnored = !txn.CrossProcess.Enabled
+		}
 	}
 
 	requestAgentAttributes(txn.Attrs, r.Method, h, r.URL, r.Host)
+	if nil != txn.Config.scrubber && nil != r.URL {
+		txn.Attrs.Agent.Add(AttributeRequestURI, txn.Config.scrubber.scrub(safeURL(r.URL)), nil)
+	}
+	if nil != r.URL {
+		txn.applyRequestURIPolicy()
+	}
+	requestCaptureAttributes(txn.Attrs, txn.Config, h, r.URL)
+	if nil != r.Context {
+		txn.requestContext = r.Context
+	}
 
 	return nil
 }
@@ -259,9 +390,136 @@ func (thd *thread) StoreLog(log *logEvent) {
 	defer txn.Unlock()
 
 	if txn.logs == nil {
-		txn.logs = make(logEventHeap, 0, internal.MaxLogEvents)
+		txn.logs = make(logEventHeap, 0, txn.Config.maxLogEvents())
+	}
+	txn.logsSeen++
+	log.message = txn.Config.scrubber.scrub(log.message)
+	log.attributes = txn.Config.logAttributes(txn.Reply, log.attributes)
+	if dropped := txn.logs.Add(log); nil != dropped && nil != txn.Config.OnDataDropped && len(txn.droppedLogs) < droppedDataLimit {
+		txn.droppedLogs = append(txn.droppedLogs, *dropped)
+	}
+	thd.captureLogError(log)
+}
+
+// captureLogError records a log of an error severity as an error of the
+// transaction, see Config.ApplicationLogging.Forwarding.CaptureErrors.
+func (thd *thread) captureLogError(log *logEvent) {
+	txn := thd.txn
+	logging := txn.Config.ApplicationLogging
+	if !logging.Enabled || !logging.Forwarding.Enabled || !logging.Forwarding.CaptureErrors {
+		return
+	}
+	severity, ok := logErrorSeverity(log.severity)
+	if !ok || txn.finished {
+		return
+	}
+	for _, e := range txn.Errors {
+		if "" != e.Msg && strings.Contains(log.message, e.Msg) {
+			return
+		}
+	}
+	data := errorData{
+		When:     time.Now(),
+		Stack:    getStackTrace(),
+		Msg:      log.message,
+		Klass:    logErrorClass,
+		Severity: severity,
+	}
+	if !txn.Config.HighSecurity && txn.Reply.SecurityPolicies.CustomParameters.Enabled() {
+		data.ExtraAttributes = log.attributes
+	}
+	thd.noticeErrorInternal(data, false)
+}
+
+// unfinishedSegmentName names the unfinished segments ended by
+// endUnfinishedSegments which were not named when started.
+const unfinishedSegmentName = "Unfinished"
+
+// endUnfinishedSegments ends the segments still open when the transaction
+// ends so that they are recorded.
+func (txn *txn) endUnfinishedSegments(now time.Time) {
+	threads := append([]*tracingThread{&txn.mainThread}, txn.asyncThreads...)
+	count := 0
+	for _, thread := range threads {
+		for depth := len(thread.stack) - 1; depth >= 0; depth-- {
+			frame := &thread.stack[depth]
+			name := frame.name
+			if "" == name {
+				name = unfinishedSegmentName
+			}
+			frame.agentAttributes.addBool(SpanAttributeUnfinished, true)
+			start := segmentStartTime{Stamp: frame.Stamp, Depth: depth}
+			if nil == endBasicSegment(&txn.txnData, thread, start, now, name) {
+				count++
+			}
+		}
+	}
+	if count > 0 {
+		txn.Config.Logger.Warn("transaction ended with unfinished segments", map[string]interface{}{
+			"name":     txn.Name,
+			"segments": count,
+		})
+	}
+}
+
+// scrubAttributes applies the Config.Scrubbing rules to the attributes of
+// each span event and transaction trace segment.
+func (txn *txn) scrubAttributes() {
+	for _, evt := range txn.SpanEvents {
+		txn.Config.scrubber.scrubAttributes(evt.AgentAttributes)
+		txn.Config.scrubber.scrubAttributes(evt.UserAttributes)
+	}
+	for i := range txn.TxnTrace.nodes {
+		txn.Config.scrubber.scrubAttributes(txn.TxnTrace.nodes[i].attributes)
+	}
+}
+
+// redactAttributes calls Config.RedactAttributes with the transaction, each
+// of its errors, and each span event and transaction trace segment, and
+// replaces their attributes with the result.
+func (txn *txn) redactAttributes() {
+	if nil != txn.Attrs {
+		agent, user := txn.Attrs.redactable()
+		data := &RedactableData{
+			Kind:            "transaction",
+			Name:            txn.FinalName,
+			AgentAttributes: agent,
+			UserAttributes:  user,
+		}
+		txn.Config.RedactAttributes(data)
+		txn.Attrs.redacted(data.AgentAttributes, data.UserAttributes)
+	}
+	for _, e := range txn.Errors {
+		data := &RedactableData{
+			Kind:            "error",
+			Name:            e.Klass,
+			AgentAttributes: map[string]interface{}{},
+			UserAttributes:  redactableErrorAttributes(e.ExtraAttributes),
+		}
+		txn.Config.RedactAttributes(data)
+		e.ExtraAttributes = txn.Config.attributeLimits().redactedErrorAttributes(e.ExtraAttributes, data.UserAttributes)
+	}
+	for _, evt := range txn.SpanEvents {
+		data := &RedactableData{
+			Kind:            "span",
+			Name:            evt.Name,
+			AgentAttributes: evt.AgentAttributes.redactable(),
+			UserAttributes:  evt.UserAttributes.redactable(),
+		}
+		txn.Config.RedactAttributes(data)
+		evt.AgentAttributes = evt.AgentAttributes.redacted(data.AgentAttributes)
+		evt.UserAttributes = evt.UserAttributes.redacted(data.UserAttributes)
+	}
+	for i := range txn.TxnTrace.nodes {
+		node := &txn.TxnTrace.nodes[i]
+		data := &RedactableData{
+			Kind:            "segment",
+			Name:            node.name,
+			AgentAttributes: spanAttributeMap(node.attributes).redactable(),
+		}
+		txn.Config.RedactAttributes(data)
+		node.attributes = spanAttributeMap(node.attributes).redacted(data.AgentAttributes)
 	}
-	txn.logs.Add(log)
 }
 
 func (txn *txn) freezeName() {
@@ -272,17 +530,55 @@ func (txn *txn) freezeName() {
 	if "" == txn.FinalName {
 		txn.ignore = true
 	}
+	if txn.requestURITemplate {
+		txn.Attrs.Agent.Add(AttributeRequestURI, txn.Name, nil)
+	}
+}
+
+// applyRequestURIPolicy replaces the request.uri attribute according to
+// Config.Attributes.RequestURIPolicy.
+func (txn *txn) applyRequestURIPolicy() {
+	switch txn.Config.Attributes.RequestURIPolicy {
+	case RequestURITemplate:
+		txn.Attrs.Agent.Remove(AttributeRequestURI)
+		if "" != txn.FinalName {
+			txn.Attrs.Agent.Add(AttributeRequestURI, txn.Name, nil)
+		} else {
+			txn.requestURITemplate = true
+		}
+	case RequestURIHash:
+		if uri, ok := txn.Attrs.Agent[AttributeRequestURI]; ok {
+			sum := sha256.Sum256([]byte(uri.stringVal))
+			txn.Attrs.Agent.Add(AttributeRequestURI, hex.EncodeToString(sum[:]), nil)
+		}
+	}
 }
 
 func (txn *txn) getsApdex() bool {
-	return txn.IsWeb
+	return (txn.IsWeb || txn.Config.Apdex.BackgroundTransactions) && !txn.ignoredSynthetics()
+}
+
+// responseCodeApdexZone returns the zone mapped to the transaction's response
+// code by Config.Apdex.ResponseCodeZones, or apdexNone.
+func (txn *txn) responseCodeApdexZone() apdexZone {
+	if !txn.wroteHeader {
+		return apdexNone
+	}
+	return txn.Config.Apdex.ResponseCodeZones[txn.responseCode].zone()
+}
+
+// ignoredSynthetics returns true if the transaction was started by a
+// Synthetics monitor and is excluded from Apdex and error rates by
+// Config.Transaction.IgnoreSynthetics.
+func (txn *txn) ignoredSynthetics() bool {
+	return txn.Config.Transaction.IgnoreSynthetics && txn.CrossProcess.IsSynthetics()
 }
 
 func (txn *txn) shouldSaveTrace() bool {
 	if !txn.Config.TransactionTracer.Enabled {
 		return false
 	}
-	if txn.CrossProcess.IsSynthetics() {
+	if txn.CrossProcess.IsSynthetics() || txn.TxnTrace.forced {
 		return true
 	}
 	return txn.Duration >= txn.txnTraceThreshold(txn.ApdexThreshold)
@@ -300,12 +596,27 @@ func (txn *txn) MergeIntoHarvest(h *harvest) {
 	createTxnMetrics(&txn.txnData, h.Metrics)
 	mergeBreakdownMetrics(&txn.txnData, h.Metrics)
 
+	if txn.Config.TransactionExemplars.Enabled && txn.BetterCAT.Enabled && txn.BetterCAT.Sampled && nil != h.Exemplars {
+		h.Exemplars.witness(txn.FinalName, txnExemplar{
+			traceID:   txn.BetterCAT.TraceID,
+			guid:      txn.BetterCAT.TxnID,
+			duration:  txn.Duration,
+			timestamp: txn.Start,
+		})
+	}
+
 	// Dump log events into harvest
 	// Note: this will create a surge of log events that could affect sampling.
 	for _, logEvent := range txn.logs {
 		logEvent.priority = priority
 		h.LogEvents.Add(&logEvent)
 	}
+	// Logs dropped by the transaction are counted as seen so that they are
+	// reported by the Logging/Forwarding/Dropped metric.
+	h.LogEvents.numSeen += txn.logsSeen - len(txn.logs)
+	for _, logEvent := range txn.droppedLogs {
+		h.LogEvents.drop(logEvent)
+	}
 
 	if txn.Config.TransactionEvents.Enabled {
 		// Allocate a new TxnEvent to prevent a reference to the large transaction.
@@ -343,6 +654,7 @@ func (txn *txn) MergeIntoHarvest(h *harvest) {
 	}
 
 	if txn.shouldCollectSpanEvents() && !shouldUseTraceObserver(txn.Config) {
+		h.SpanAttributeGuard.apply(txn.txnData.SpanEvents, h.Metrics)
 		h.SpanEvents.MergeSpanEvents(txn.txnData.SpanEvents)
 	}
 }
@@ -362,9 +674,10 @@ func headersJustWritten(thd *thread, code int, hdr http.Header) {
 
 	responseHeaderAttributes(txn.Attrs, hdr)
 	responseCodeAttribute(txn.Attrs, code)
+	txn.responseCode = code
 
 	if txn.appRun.responseCodeIsError(code) {
-		e := txnErrorFromResponseCode(time.Now(), code)
+		e := txnErrorFromResponseCode(txn.Config.Clock.Now(), code)
 		e.Stack = getStackTrace()
 		thd.noticeErrorInternal(e, false)
 	}
@@ -422,15 +735,24 @@ func (thd *thread) End(recovered interface{}) error {
 
 	txn.finished = true
 
+	if txn.Config.SegmentGuards.EndUnfinished {
+		txn.endUnfinishedSegments(txn.Config.Clock.Now())
+	}
+
 	if nil != recovered {
-		e := txnErrorFromPanic(time.Now(), recovered)
+		e := txnErrorFromPanic(txn.Config.Clock.Now(), recovered)
 		e.Stack = getStackTrace()
 		thd.noticeErrorInternal(e, false)
 		log.Println(string(debug.Stack()))
 	}
 
-	txn.markEnd(time.Now(), thd.thread)
+	txn.markEnd(txn.Config.Clock.Now(), thd.thread)
+	txn.applyNameFunc()
 	txn.freezeName()
+	if nil != txn.requestContext && context.Canceled == txn.requestContext.Err() {
+		txn.clientCanceled = true
+		txn.Attrs.Agent.Add(AttributeRequestCanceled, "", true)
+	}
 	// Make a sampling decision if there have been no segments or outbound
 	// payloads.
 	txn.lazilyCalculateSampled()
@@ -445,9 +767,12 @@ func (thd *thread) End(recovered interface{}) error {
 	// Assign apdexThreshold regardless of whether or not the transaction
 	// gets apdex since it may be used to calculate the trace threshold.
 	txn.ApdexThreshold = internal.CalculateApdexThreshold(txn.Reply, txn.FinalName)
+	txn.noErrorMetrics = txn.ignoredSynthetics()
 
 	if txn.getsApdex() {
-		if txn.HasErrors() && txn.NoticeErrors() {
+		if zone := txn.responseCodeApdexZone(); apdexNone != zone {
+			txn.Zone = zone
+		} else if txn.HasErrors() && txn.NoticeErrors() {
 			txn.Zone = apdexFailing
 		} else {
 			txn.Zone = calculateApdexZone(txn.ApdexThreshold, txn.Duration)
@@ -466,6 +791,12 @@ func (thd *thread) End(recovered interface{}) error {
 	}
 
 	if txn.shouldCollectSpanEvents() {
+		if txn.spanCompressionMaxDuration > 0 {
+			for _, evt := range txn.SpanEvents {
+				evt.addCompressionAttributes()
+			}
+		}
+		txn.addRoundTripGroupSpans()
 		root := &spanEvent{
 			GUID:         txn.GetRootSpanID(),
 			Timestamp:    txn.Start,
@@ -508,6 +839,13 @@ func (thd *thread) End(recovered interface{}) error {
 		}
 	}
 
+	if nil != txn.Config.scrubber {
+		txn.scrubAttributes()
+	}
+	if nil != txn.Config.RedactAttributes {
+		txn.redactAttributes()
+	}
+
 	if !txn.ignore {
 		txn.app.Consume(txn.Reply.RunID, txn)
 		if observer := txn.app.getObserver(); nil != observer {
@@ -542,12 +880,37 @@ func (txn *txn) AddAttribute(name string, value interface{}) error {
 		return errAlreadyEnded
 	}
 
-	return addUserAttribute(txn.Attrs, name, value, destAll)
+	err := addUserAttribute(txn.Attrs, name, value, destAll)
+	if nil != err {
+		txn.Config.attributeDropped(name, "transaction", err)
+	}
+	return err
+}
+
+func (txn *txn) AddTraceIntrinsic(key string, value interface{}) error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.Config.HighSecurity {
+		return errHighSecurityEnabled
+	}
+
+	if !txn.Reply.SecurityPolicies.CustomParameters.Enabled() {
+		return errSecurityPolicy
+	}
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+
+	return txn.TxnTrace.addIntrinsic(key, value, txn.Config.attributeLimits())
 }
 
 var (
 	errorsDisabled        = errors.New("errors disabled")
 	errNilError           = errors.New("nil error")
+	errErrorSeverity      = errors.New("invalid error severity")
+	errDetailLevel        = errors.New("invalid detail level")
 	errAlreadyEnded       = errors.New("transaction has already ended")
 	errSecurityPolicy     = errors.New("disabled by security policy")
 	errTransactionIgnored = errors.New("transaction has been ignored")
@@ -564,6 +927,9 @@ func (thd *thread) noticeErrorInternal(err errorData, expect bool) error {
 	if !txn.Config.ErrorCollector.Enabled {
 		return errorsDisabled
 	}
+	if txn.Config.errorIgnorer.ignored(err.Klass, err.Msg) {
+		return nil
+	}
 
 	if !expect {
 		thd.noticeErrors = true
@@ -582,11 +948,18 @@ func (thd *thread) noticeErrorInternal(err errorData, expect bool) error {
 	if !txn.Reply.SecurityPolicies.AllowRawExceptionMessages.Enabled() {
 		err.Msg = securityPolicyErrorMsg
 	}
+	err.Msg = txn.Config.scrubber.scrub(err.Msg)
 
 	if txn.shouldCollectSpanEvents() {
 		err.SpanID = txn.CurrentSpanIdentifier(thd.thread)
 		addErrorAttrs(thd, err)
 	}
+	if txn.detailLevel == DetailLevelBasic {
+		err.Stack = nil
+	} else if txn.Errors.slot(err.Severity) >= 0 {
+		err.Stack = err.Stack.trim(txn.Config.codeLevelMetricsIgnoredPrefixes(),
+			txn.Config.errorStackTraceDepth())
+	}
 	txn.Errors.Add(err)
 	txn.txnData.txnEvent.HasError = true //mark transaction as having an error
 	return nil
@@ -649,7 +1022,7 @@ func errorAttributesMethod(err error) map[string]interface{} {
 	return nil
 }
 
-func errDataFromError(input error, expect bool) (data errorData, err error) {
+func errDataFromError(input error, expect bool, limits attributeLimits) (data errorData, err error) {
 	cause := errorCause(input)
 
 	data = errorData{
@@ -696,7 +1069,7 @@ func errDataFromError(input error, expect bool) (data errorData, err error) {
 
 		data.ExtraAttributes = make(map[string]interface{})
 		for key, val := range unvetted {
-			val, err = validateUserAttribute(key, val)
+			val, err = limits.validateUserAttribute(key, val)
 			if nil != err {
 				return
 			}
@@ -708,6 +1081,18 @@ func errDataFromError(input error, expect bool) (data errorData, err error) {
 }
 
 func (thd *thread) NoticeError(input error, expect bool) error {
+	return thd.noticeError(input, expect, "")
+}
+
+// RecordException records an error of the given severity.
+func (thd *thread) RecordException(input error, severity ErrorSeverity) error {
+	if 0 == severity.rank() {
+		return errErrorSeverity
+	}
+	return thd.noticeError(input, false, severity)
+}
+
+func (thd *thread) noticeError(input error, expect bool, severity ErrorSeverity) error {
 	txn := thd.txn
 	txn.Lock()
 	defer txn.Unlock()
@@ -720,7 +1105,7 @@ func (thd *thread) NoticeError(input error, expect bool) error {
 		return errNilError
 	}
 
-	data, err := errDataFromError(input, expect)
+	data, err := errDataFromError(input, expect, txn.Config.attributeLimits())
 	if nil != err {
 		return err
 	}
@@ -728,6 +1113,7 @@ func (thd *thread) NoticeError(input error, expect bool) error {
 	if txn.Config.HighSecurity || !txn.Reply.SecurityPolicies.CustomParameters.Enabled() {
 		data.ExtraAttributes = nil
 	}
+	data.Severity = severity
 
 	return thd.noticeErrorInternal(data, expect)
 }
@@ -744,6 +1130,91 @@ func (txn *txn) SetName(name string) error {
 	return nil
 }
 
+func (txn *txn) SetNameFunc(fn func(TransactionNameInfo) string) error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+
+	txn.nameFunc = fn
+	return nil
+}
+
+// applyNameFunc renames the transaction using the function registered with
+// SetNameFunc.  It must be called before the name is frozen.
+func (txn *txn) applyNameFunc() {
+	if nil == txn.nameFunc || "" != txn.FinalName {
+		return
+	}
+	attrs := make(map[string]interface{}, len(txn.Attrs.user))
+	for key, attr := range txn.Attrs.user {
+		attrs[key] = attr.value
+	}
+	name := txn.nameFunc(TransactionNameInfo{
+		Name:       txn.Name,
+		IsWeb:      txn.IsWeb,
+		StatusCode: txn.responseCode,
+		Attributes: attrs,
+	})
+	if "" != name {
+		txn.Name = name
+	}
+}
+
+func (txn *txn) SetDetailLevel(level DetailLevel) error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+	return txn.setDetailLevelLocked(level)
+}
+
+func (txn *txn) setDetailLevelLocked(level DetailLevel) error {
+	switch level {
+	case DetailLevelBasic:
+		removeCodeLevelMetrics(txn.Attrs.Agent.Remove)
+		txn.TxnTrace.StackTraceThreshold = txn.Config.TransactionTracer.Segments.StackTraceThreshold
+		txn.TxnTrace.stackTracesDisabled = true
+	case DetailLevelStandard:
+		txn.TxnTrace.StackTraceThreshold = txn.Config.TransactionTracer.Segments.StackTraceThreshold
+		txn.TxnTrace.stackTracesDisabled = false
+	case DetailLevelVerbose:
+		txn.TxnTrace.StackTraceThreshold = 0
+		txn.TxnTrace.stackTracesDisabled = false
+	default:
+		return errDetailLevel
+	}
+	txn.detailLevel = level
+	return nil
+}
+
+func (txn *txn) ForceTrace() error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+	txn.TxnTrace.forced = true
+	return nil
+}
+
+func (txn *txn) DisableSpanEvents() error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+	txn.spanEventsDisabled = true
+	txn.SpanEvents = nil
+	return nil
+}
+
 func (txn *txn) Ignore() error {
 	txn.Lock()
 	defer txn.Unlock()
@@ -755,12 +1226,13 @@ func (txn *txn) Ignore() error {
 	return nil
 }
 
-func (thd *thread) startSegmentAt(at time.Time) SegmentStartTime {
+func (thd *thread) startSegmentAt(at time.Time, name string) SegmentStartTime {
 	var s segmentStartTime
 	txn := thd.txn
 	txn.Lock()
 	if !txn.finished {
 		s = startSegment(&txn.txnData, thd.thread, at)
+		thd.thread.stack[s.Depth].name = name
 	}
 	txn.Unlock()
 	return SegmentStartTime{
@@ -822,14 +1294,15 @@ func (txn *txn) BrowserTimingHeader() (*BrowserTimingHeader, error) {
 	}
 
 	return &BrowserTimingHeader{
-		agentLoader: txn.Reply.AgentLoader,
+		agentLoader:      txn.Reply.AgentLoader,
+		correlationToken: txn.browserCorrelationToken,
 		info: browserInfo{
 			Beacon:                txn.Reply.Beacon,
 			LicenseKey:            txn.Reply.BrowserKey,
 			ApplicationID:         txn.Reply.AppID,
 			TransactionName:       name,
 			QueueTimeMillis:       txn.Queuing.Nanoseconds() / (1000 * 1000),
-			ApplicationTimeMillis: time.Now().Sub(txn.Start).Nanoseconds() / (1000 * 1000),
+			ApplicationTimeMillis: txn.Config.Clock.Now().Sub(txn.Start).Nanoseconds() / (1000 * 1000),
 			ObfuscatedAttributes:  attrs,
 			ErrorBeacon:           txn.Reply.ErrorBeacon,
 			Agent:                 txn.Reply.JSAgentFile,
@@ -837,6 +1310,20 @@ func (txn *txn) BrowserTimingHeader() (*BrowserTimingHeader, error) {
 	}, nil
 }
 
+func (txn *txn) BrowserCorrelationToken() (string, error) {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return "", errAlreadyEnded
+	}
+	if "" == txn.browserCorrelationToken {
+		txn.browserCorrelationToken = txn.Reply.TraceIDGenerator.GenerateSpanID()
+		txn.Attrs.Agent.Add(AttributeBrowserCorrelationToken, txn.browserCorrelationToken, nil)
+	}
+	return txn.browserCorrelationToken, nil
+}
+
 func createThread(txn *txn) *tracingThread {
 	newThread := newTracingThread(&txn.txnData)
 	txn.asyncThreads = append(txn.asyncThreads, newThread)
@@ -869,7 +1356,7 @@ func endBasic(s *Segment) error {
 	if txn.finished {
 		err = errAlreadyEnded
 	} else {
-		err = endBasicSegment(&txn.txnData, thd.thread, s.StartTime.start, time.Now(), s.Name)
+		err = endBasicSegment(&txn.txnData, thd.thread, s.StartTime.start, txn.Config.Clock.Now(), s.Name)
 	}
 	txn.Unlock()
 	return err
@@ -887,11 +1374,20 @@ func endDatastore(s *DatastoreSegment) error {
 	if txn.finished {
 		return errAlreadyEnded
 	}
+	s.Product = normalizeDatastoreProduct(s.Product)
+	// The query is parsed before it may be removed by the security
+	// policies below.
+	deriveDatastoreOperation(s)
 	if txn.Config.HighSecurity {
 		s.QueryParameters = nil
 	}
-	if !txn.Config.DatastoreTracer.QueryParameters.Enabled {
+	switch txn.detailLevel {
+	case DetailLevelBasic:
 		s.QueryParameters = nil
+	case DetailLevelStandard:
+		if !txn.Config.DatastoreTracer.QueryParameters.Enabled {
+			s.QueryParameters = nil
+		}
 	}
 	if txn.Reply.SecurityPolicies.RecordSQL.IsSet() {
 		s.QueryParameters = nil
@@ -905,21 +1401,27 @@ func endDatastore(s *DatastoreSegment) error {
 	if !txn.Config.DatastoreTracer.InstanceReporting.Enabled {
 		s.Host = ""
 		s.PortPathOrID = ""
+	} else if alias, ok := txn.Config.DatastoreTracer.InstanceHostnameAliases[s.Host]; ok {
+		s.Host = alias
 	}
 	return endDatastoreSegment(endDatastoreParams{
 		TxnData:            &txn.txnData,
 		Thread:             thd.thread,
 		Start:              s.StartTime.start,
-		Now:                time.Now(),
+		Now:                txn.Config.Clock.Now(),
 		Product:            string(s.Product),
 		Collection:         s.Collection,
 		Operation:          s.Operation,
 		ParameterizedQuery: s.ParameterizedQuery,
+		PipelineOperations: s.PipelineOperations,
 		QueryParameters:    s.QueryParameters,
-		Host:               s.Host,
-		PortPathOrID:       s.PortPathOrID,
-		Database:           s.DatabaseName,
-		ThisHost:           txn.appRun.Config.hostname,
+		QueryParameterDropped: func(key string, err error) {
+			txn.Config.attributeDropped(key, "query parameter", err)
+		},
+		Host:         s.Host,
+		PortPathOrID: s.PortPathOrID,
+		Database:     s.DatabaseName,
+		ThisHost:     txn.appRun.Config.hostname,
 	})
 }
 
@@ -978,7 +1480,7 @@ func endExternal(s *ExternalSegment) error {
 		TxnData:    &txn.txnData,
 		Thread:     thd.thread,
 		Start:      s.StartTime.start,
-		Now:        time.Now(),
+		Now:        txn.Config.Clock.Now(),
 		Logger:     txn.Config.Logger,
 		Response:   s.Response,
 		URL:        u,
@@ -986,6 +1488,8 @@ func endExternal(s *ExternalSegment) error {
 		Library:    s.Library,
 		Method:     externalSegmentMethod(s),
 		StatusCode: s.statusCode,
+		Outcome:    s.Outcome,
+		Group:      s.group,
 	})
 }
 
@@ -1010,7 +1514,7 @@ func endMessage(s *MessageProducerSegment) error {
 		TxnData:         &txn.txnData,
 		Thread:          thd.thread,
 		Start:           s.StartTime.start,
-		Now:             time.Now(),
+		Now:             txn.Config.Clock.Now(),
 		Library:         s.Library,
 		Logger:          txn.Config.Logger,
 		DestinationName: s.DestinationName,
@@ -1056,6 +1560,9 @@ func outboundHeaders(s *ExternalSegment) http.Header {
 	// hdr may be empty, or it may contain headers.  If DistributedTracer
 	// is enabled, add more to the existing hdr
 	thd.CreateDistributedTracePayload(hdr)
+	if sa := thd.securityAgent(); nil != sa {
+		sa.OutboundHeaders(newTransaction(thd), hdr)
+	}
 
 	return hdr
 }
@@ -1064,6 +1571,23 @@ const (
 	maxSampledDistributedPayloads = 35
 )
 
+// traceParent returns the W3C traceparent of the current span, or "" if
+// distributed tracing is disabled.  Unlike CreateDistributedTracePayload, it
+// does not count as an outbound payload.
+func (thd *thread) traceParent() string {
+	txn := thd.txn
+	txn.Lock()
+	defer txn.Unlock()
+
+	if !txn.BetterCAT.Enabled || txn.finished {
+		return ""
+	}
+	p := payload{TracedID: txn.BetterCAT.TraceID}
+	p.SetSampled(txn.lazilyCalculateSampled())
+	p.ID = txn.CurrentSpanIdentifier(thd.thread)
+	return p.W3CTraceParent()
+}
+
 func (thd *thread) CreateDistributedTracePayload(hdrs http.Header) {
 	txn := thd.txn
 	txn.Lock()
@@ -1138,6 +1662,9 @@ func (thd *thread) CreateDistributedTracePayload(hdrs http.Header) {
 		p.ID = txn.CurrentSpanIdentifier(thd.thread)
 	}
 	hdrs.Set(DistributedTraceW3CTraceParentHeader, p.W3CTraceParent())
+	if "" != txn.debugTraceToken {
+		hdrs.Set(txn.Config.DistributedTracer.DebugHeader.Name, txn.debugTraceToken)
+	}
 
 	if !txn.Config.SpanEvents.Enabled {
 		p.ID = ""
@@ -1145,7 +1672,7 @@ func (thd *thread) CreateDistributedTracePayload(hdrs http.Header) {
 	if !txn.Config.TransactionEvents.Enabled {
 		p.TransactionID = ""
 	}
-	hdrs.Set(DistributedTraceW3CTraceStateHeader, p.W3CTraceState())
+	hdrs.Set(DistributedTraceW3CTraceStateHeader, pruneTraceState(p.W3CTraceState(), txn.Config.DistributedTracer.MaxTraceStateSize))
 }
 
 var (
@@ -1189,6 +1716,12 @@ func (txn *txn) acceptDistributedTraceHeadersLocked(t TransportType, hdrs http.H
 		return nil
 	}
 
+	if txn.acceptDebugTraceHeader(hdrs) {
+		// The debug trace is forced once the inbound payload, which
+		// may contain a sampling decision, has been accepted.
+		defer txn.forceDebugTrace()
+	}
+
 	if "" == txn.Reply.AccountID || "" == txn.Reply.TrustedAccountKey {
 		// We can't accept a payload:  The application is not yet
 		// connected or serverless distributed tracing configuration was
@@ -1199,6 +1732,9 @@ func (txn *txn) acceptDistributedTraceHeadersLocked(t TransportType, hdrs http.H
 	txn.BetterCAT.TransportType = t.toString()
 
 	payload, err := acceptPayload(hdrs, txn.Reply.TrustedAccountKey, support)
+	if txn.Config.DistributedTracer.HeaderValidation.Mode == HeaderValidationStrict {
+		txn.reportMalformedHeaders(hdrs, err)
+	}
 	if nil != err {
 		return err
 	}
@@ -1241,6 +1777,44 @@ func (txn *txn) acceptDistributedTraceHeadersLocked(t TransportType, hdrs http.H
 	return nil
 }
 
+// malformedHeaderSampleLimit is the number of bytes of a malformed header
+// which are logged.
+const malformedHeaderSampleLimit = 256
+
+// reportMalformedHeaders records the supportability metrics and logs of the
+// malformed headers found by acceptPayload, which returned err.
+func (txn *txn) reportMalformedHeaders(hdrs http.Header, err error) {
+	support := &txn.DistributedTracingSupport
+	if support.TraceContextParentParseException {
+		support.MalformedTraceParent = true
+		txn.logMalformedHeader(DistributedTraceW3CTraceParentHeader, hdrs.Get(DistributedTraceW3CTraceParentHeader), err)
+	}
+	if support.TraceContextStateInvalidNrEntry {
+		support.MalformedTraceState = true
+		txn.logMalformedHeader(DistributedTraceW3CTraceStateHeader,
+			strings.Join(hdrs[DistributedTraceW3CTraceStateHeader], ","), errInvalidNRTraceState)
+	}
+	if support.AcceptPayloadParseException {
+		support.MalformedNewRelic = true
+		txn.logMalformedHeader(DistributedTraceNewRelicHeader, hdrs.Get(DistributedTraceNewRelicHeader), err)
+	}
+}
+
+func (txn *txn) logMalformedHeader(name, value string, reason error) {
+	if !txn.Config.DistributedTracer.HeaderValidation.LogMalformedHeaders {
+		return
+	}
+	if len(value) > malformedHeaderSampleLimit {
+		value = value[:malformedHeaderSampleLimit]
+	}
+	txn.Config.Logger.Error("malformed distributed trace header", map[string]interface{}{
+		"header":      name,
+		"value":       txn.Config.scrubber.scrub(value),
+		"reason":      reason.Error(),
+		"transaction": txn.Name,
+	})
+}
+
 func (txn *txn) Application() *Application {
 	return newApplication(txn.app)
 }
@@ -1254,6 +1828,40 @@ func (thd *thread) AddAgentSpanAttribute(key string, val string) {
 	thd.thread.AddAgentSpanAttribute(key, val)
 }
 
+// addExternalCallAttributes adds the attempt and redirect counts of an
+// ExternalCall to the segment started at start.
+func (thd *thread) addExternalCallAttributes(start segmentStartTime, attempts, redirects int) {
+	txn := thd.txn
+	txn.Lock()
+	defer txn.Unlock()
+	if txn.finished {
+		return
+	}
+	thd.thread.addSegmentAgentAttributeInt(start, SpanAttributeExternalAttempts, attempts)
+	thd.thread.addSegmentAgentAttributeInt(start, SpanAttributeExternalRedirectCount, redirects)
+}
+
+// startRoundTrip records a request made using NewRoundTripper without an
+// ExternalCall, see txnData.startRoundTrip.
+func (thd *thread) startRoundTrip(r *http.Request) (*roundTripGroup, ExternalOutcome) {
+	txn := thd.txn
+	txn.Lock()
+	defer txn.Unlock()
+	if txn.finished {
+		return nil, ExternalOutcome{}
+	}
+	return txn.startRoundTrip(thd.thread, r)
+}
+
+// endRoundTrip records the result of a request started with startRoundTrip.
+func (thd *thread) endRoundTrip(g *roundTripGroup, resp *http.Response, failed bool) {
+	txn := thd.txn
+	txn.Lock()
+	defer txn.Unlock()
+	g.last = resp
+	g.failed = failed
+}
+
 func (thd *thread) AddUserSpanAttribute(key string, val interface{}) error {
 	txn := thd.txn
 	txn.Lock()
@@ -5,19 +5,46 @@ package newrelic
 
 import (
 	"bytes"
+	"fmt"
 )
 
 const (
-	expectErrorAttr = "error.expected"
+	expectErrorAttr   = "error.expected"
+	errorSeverityAttr = "error.severity"
 )
 
+// reservedIntrinsics are the intrinsics written by the agent, which may not
+// be replaced using Transaction.AddTraceIntrinsic.
+var reservedIntrinsics = map[string]struct{}{
+	"totalTime":                  {},
+	"guid":                       {},
+	"traceId":                    {},
+	"priority":                   {},
+	"sampled":                    {},
+	expectErrorAttr:              {},
+	errorSeverityAttr:            {},
+	"client_cross_process_id":    {},
+	"trip_id":                    {},
+	"path_hash":                  {},
+	"referring_transaction_guid": {},
+	"synthetics_resource_id":     {},
+	"synthetics_job_id":          {},
+	"synthetics_monitor_id":      {},
+}
+
+type reservedIntrinsicErr struct{ key string }
+
+func (e reservedIntrinsicErr) Error() string {
+	return fmt.Sprintf("intrinsic '%s' is reserved", e.key)
+}
+
 func addOptionalStringField(w *jsonFieldsWriter, key, value string) {
 	if value != "" {
 		w.stringField(key, value)
 	}
 }
 
-func intrinsicsJSON(e *txnEvent, buf *bytes.Buffer, expect bool) {
+func intrinsicsJSON(e *txnEvent, buf *bytes.Buffer, expect bool, severity ErrorSeverity, custom map[string]interface{}) {
 	w := jsonFieldsWriter{buf: buf}
 
 	buf.WriteByte('{')
@@ -34,6 +61,7 @@ func intrinsicsJSON(e *txnEvent, buf *bytes.Buffer, expect bool) {
 	if expect {
 		w.stringField(expectErrorAttr, "true")
 	}
+	addOptionalStringField(&w, errorSeverityAttr, string(severity))
 
 	if e.CrossProcess.Used() {
 		addOptionalStringField(&w, "client_cross_process_id", e.CrossProcess.ClientID)
@@ -48,5 +76,9 @@ func intrinsicsJSON(e *txnEvent, buf *bytes.Buffer, expect bool) {
 		addOptionalStringField(&w, "synthetics_monitor_id", e.CrossProcess.Synthetics.MonitorID)
 	}
 
+	for key, val := range custom {
+		writeAttributeValueJSON(&w, key, val)
+	}
+
 	buf.WriteByte('}')
 }
@@ -0,0 +1,55 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "sync/atomic"
+
+// The types of the custom events recorded for Config.LifecycleEvents.
+const (
+	lifecycleConnect        = "AgentConnect"
+	lifecycleReconnect      = "AgentReconnect"
+	lifecycleShutdown       = "AgentShutdown"
+	lifecycleHarvestFailure = "HarvestFailure"
+)
+
+// The reasons of the lifecycle events other than HarvestFailure, whose
+// reason is the error of the failed request.
+const (
+	lifecycleReasonStartup  = "startup"
+	lifecycleReasonRestart  = "collector restart"
+	lifecycleReasonShutdown = "Application.Shutdown"
+)
+
+// lifecycleEvent returns a custom event of Config.LifecycleEvents, or nil if
+// lifecycle events are disabled or custom events are not allowed for the run.
+func (app *app) lifecycleEvent(run *appRun, eventType, reason string, params map[string]interface{}) *customEvent {
+	if !app.config.LifecycleEvents.Enabled || nil == run {
+		return nil
+	}
+	if nil != app.customEventsAllowed(run) {
+		return nil
+	}
+	if nil == params {
+		params = make(map[string]interface{}, 1)
+	}
+	params["reason"] = reason
+	event, err := createCustomEvent(eventType, params, app.config.Clock.Now(), run.Config.attributeLimits())
+	if nil != err {
+		app.Debug("unable to create lifecycle event", map[string]interface{}{
+			"type":  eventType,
+			"error": err.Error(),
+		})
+		return nil
+	}
+	atomic.AddInt64(&app.customEventsStored, 1)
+	return event
+}
+
+// recordLifecycleEvent merges a lifecycle event into the harvest.  It must
+// only be called by the goroutine which owns the harvest.
+func (app *app) recordLifecycleEvent(h *harvest, run *appRun, eventType, reason string, params map[string]interface{}) {
+	if event := app.lifecycleEvent(run, eventType, reason, params); nil != event {
+		h.merge(event)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func enableLifecycleEvents(cfg *Config) {
+	cfg.LifecycleEvents.Enabled = true
+}
+
+func TestRecordLifecycleEvent(t *testing.T) {
+	app := testApp(nil, enableLifecycleEvents, t)
+	run, _ := app.app.getState()
+	app.app.recordLifecycleEvent(app.app.testHarvest, run, lifecycleConnect, lifecycleReasonStartup, nil)
+	app.app.recordLifecycleEvent(app.app.testHarvest, run, lifecycleReconnect, lifecycleReasonRestart, map[string]interface{}{
+		"statusCode": 409,
+	})
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "AgentConnect",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"reason": "startup",
+		},
+	}, {
+		Intrinsics: map[string]interface{}{
+			"type":      "AgentReconnect",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"reason":     "collector restart",
+			"statusCode": 409,
+		},
+	}})
+}
+
+func TestLifecycleEventsDisabled(t *testing.T) {
+	app := testApp(nil, nil, t)
+	run, _ := app.app.getState()
+	app.app.recordLifecycleEvent(app.app.testHarvest, run, lifecycleShutdown, lifecycleReasonShutdown, nil)
+	app.ExpectCustomEvents(t, []internal.WantEvent{})
+}
+
+func TestLifecycleEventsCustomEventsDisabled(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.CollectCustomEvents = false
+	}
+	app := testApp(replyfn, enableLifecycleEvents, t)
+	run, _ := app.app.getState()
+	app.app.recordLifecycleEvent(app.app.testHarvest, run, lifecycleShutdown, lifecycleReasonShutdown, nil)
+	app.ExpectCustomEvents(t, []internal.WantEvent{})
+}
+
+type harvestFailureTransport struct{}
+
+func (harvestFailureTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return makeResponse(500, ""), nil
+}
+
+func TestHarvestFailureLifecycleEvent(t *testing.T) {
+	app := testApp(nil, enableLifecycleEvents, t)
+	run, _ := app.app.getState()
+	app.app.rpmControls.Client = &http.Client{Transport: harvestFailureTransport{}}
+	now := app.app.config.Clock.Now()
+	h := newHarvest(now, run.harvestConfig)
+	h.Metrics.addSingleCount("myMetric", forced)
+	app.app.doHarvest(h, now, run)
+
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "HarvestFailure",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"reason":     internal.MatchAnything,
+			"endpoint":   cmdMetrics,
+			"statusCode": 500,
+			"retainData": true,
+		},
+	}})
+	// The metrics of the failed harvest are retained.
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "myMetric", Scope: "", Forced: true, Data: nil},
+	})
+}
@@ -34,8 +34,13 @@ const (
 	maxMetrics          = 2 * 1000
 	maxRegularTraces    = 1
 	maxSyntheticsTraces = 20
+	maxForcedTraces     = 20
 	maxHarvestErrors    = 20
 	maxHarvestSlowSQLs  = 10
+	// maxSlowQueryExamples is the maximum number of example transactions
+	// recorded for each slow query, see
+	// Config.DatastoreTracer.SlowQuery.AggregateByInstance.
+	maxSlowQueryExamples = 5
 	// maxSpanEvents is the maximum number of Span Events that can be captured
 	// per 60-second harvest cycle
 	// DEPRECATED: replaced with DistributedTracer.ReservoirLimit configuration value
@@ -46,7 +51,11 @@ const (
 	// attributes
 	attributeKeyLengthLimit   = 255
 	attributeValueLengthLimit = 255
-	attributeUserLimit        = 64
+	// maxAttributeValueLengthLimit is the collector's limit, up to which
+	// the attribute value length limit may be raised using
+	// Config.AttributeLimits.
+	maxAttributeValueLengthLimit = 4095
+	attributeUserLimit           = 64
 	// attributeErrorLimit limits the number of extra attributes that can be
 	// provided when noticing an error.
 	attributeErrorLimit       = 32
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/rainforestpay/go-agent/v3/internal"
 	"github.com/rainforestpay/go-agent/v3/internal/logcontext"
 )
 
@@ -25,6 +26,25 @@ type logEvent struct {
 	message   string
 	spanID    string
 	traceID   string
+	// attributes are the LogData.Attributes, which are filtered by
+	// config.logAttributes when the event is recorded.
+	attributes map[string]interface{}
+}
+
+// logErrorClass is the class of the errors recorded for logs, see
+// Config.ApplicationLogging.Forwarding.CaptureErrors.
+const logErrorClass = "LogError"
+
+// logErrorSeverity returns the severity of the error recorded for a log of
+// the given severity, and false if the log is not an error.
+func logErrorSeverity(severity string) (ErrorSeverity, bool) {
+	switch strings.ToUpper(severity) {
+	case "ERROR":
+		return ErrorSeverityError, true
+	case "FATAL", "CRITICAL", "PANIC":
+		return ErrorSeverityCritical, true
+	}
+	return "", false
 }
 
 // LogData contains data fields that are needed to generate log events.
@@ -32,6 +52,9 @@ type LogData struct {
 	Timestamp int64  // Optional: Unix Millisecond Timestamp; A timestamp will be generated if unset
 	Severity  string // Optional: Severity of log being consumed
 	Message   string // Optional: Message of log being consumed; Maximum size: 32768 Bytes.
+	// Optional: Structured fields of the log.  Only those listed in
+	// Config.ApplicationLogging.Forwarding.AttributeAllowList are recorded.
+	Attributes map[string]interface{}
 }
 
 // writeJSON prepares JSON in the format expected by the collector.
@@ -47,6 +70,15 @@ func (e *logEvent) WriteJSON(buf *bytes.Buffer) {
 	if len(e.traceID) > 0 {
 		w.stringField(logcontext.LogTraceIDFieldName, e.traceID)
 	}
+	if len(e.attributes) > 0 {
+		buf.WriteByte(',')
+		buf.WriteString(`"attributes":{`)
+		aw := jsonFieldsWriter{buf: buf}
+		for key, val := range e.attributes {
+			writeAttributeValueJSON(&aw, key, val)
+		}
+		buf.WriteByte('}')
+	}
 
 	w.needsComma = false
 	buf.WriteByte(',')
@@ -88,11 +120,64 @@ func (data *LogData) toLogEvent() (logEvent, error) {
 		message:   data.Message,
 		severity:  data.Severity,
 		timestamp: data.Timestamp,
+
+		attributes: data.Attributes,
 	}
 
 	return event, nil
 }
 
+// allowListed returns whether the key matches an entry of the allow list, eg.
+// Config.ApplicationLogging.Forwarding.AttributeAllowList.  An entry ending in
+// "*" matches every key with that prefix.
+func allowListed(allowList []string, key string) bool {
+	for _, allowed := range allowList {
+		if strings.HasSuffix(allowed, "*") {
+			if strings.HasPrefix(key, allowed[:len(allowed)-1]) {
+				return true
+			}
+		} else if key == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// logAttributes returns the attributes of a log which are allowed by the
+// Config.ApplicationLogging.Forwarding.AttributeAllowList.  They are validated
+// and truncated like custom attributes, and string values are scrubbed like
+// the log message.  Invalid attributes are dropped.  No attributes are
+// forwarded if custom attributes are forbidden by HighSecurity or by the
+// custom parameters security policy.
+func (c config) logAttributes(reply *internal.ConnectReply, attrs map[string]interface{}) map[string]interface{} {
+	allowList := c.ApplicationLogging.Forwarding.AttributeAllowList
+	if len(attrs) == 0 || len(allowList) == 0 {
+		return nil
+	}
+	if c.HighSecurity || !reply.SecurityPolicies.CustomParameters.Enabled() {
+		return nil
+	}
+	limits := c.attributeLimits()
+	var allowed map[string]interface{}
+	for key, val := range attrs {
+		if !allowListed(allowList, key) {
+			continue
+		}
+		val, err := limits.validateUserAttribute(key, val)
+		if nil != err {
+			continue
+		}
+		if str, ok := val.(string); ok {
+			val = c.scrubber.scrub(str)
+		}
+		if nil == allowed {
+			allowed = make(map[string]interface{})
+		}
+		allowed[key] = val
+	}
+	return allowed
+}
+
 func (e *logEvent) MergeIntoHarvest(h *harvest) {
 	h.LogEvents.Add(e)
 }
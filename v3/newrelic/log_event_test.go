@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/rainforestpay/go-agent/v3/internal"
 	"github.com/rainforestpay/go-agent/v3/internal/logcontext"
 	"github.com/rainforestpay/go-agent/v3/internal/sysinfo"
 )
@@ -29,6 +30,25 @@ func TestWriteJSON(t *testing.T) {
 	}
 }
 
+func TestWriteJSONWithAttributes(t *testing.T) {
+	event := logEvent{
+		severity:   "INFO",
+		message:    "test message",
+		timestamp:  123456,
+		attributes: map[string]interface{}{"order_id": 1234},
+	}
+	actual, err := event.MarshalJSON()
+	if err != nil {
+		t.Error(err)
+	}
+
+	expect := `{"level":"INFO","message":"test message","attributes":{"order_id":1234},"timestamp":123456}`
+	actualString := string(actual)
+	if expect != actualString {
+		t.Errorf("Log json did not build correctly: expecting %s, got %s", expect, actualString)
+	}
+}
+
 func TestToLogEvent(t *testing.T) {
 	type testcase struct {
 		name          string
@@ -313,3 +333,29 @@ func BenchmarkAppendLinkingMetadata(b *testing.B) {
 		md.appendLinkingMetadata(buf)
 	}
 }
+
+func TestLogAttributesHighSecurity(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.License = testLicenseKey
+	cfg.AppName = sampleAppName
+	cfg.ApplicationLogging.Forwarding.AttributeAllowList = []string{"order_id"}
+	reply := internal.ConnectReplyDefaults()
+	attrs := map[string]interface{}{"order_id": 1234}
+
+	c, err := newInternalConfig(cfg, func(string) string { return "" }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.logAttributes(reply, attrs); len(got) != 1 {
+		t.Errorf("expected allowed attribute to be kept, got %v", got)
+	}
+
+	cfg.HighSecurity = true
+	c, err = newInternalConfig(cfg, func(string) string { return "" }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.logAttributes(reply, attrs); got != nil {
+		t.Errorf("expected no attributes with high security, got %v", got)
+	}
+}
@@ -25,6 +25,15 @@ type logEvents struct {
 	commonAttributes
 	config loggingConfig
 	logs   logEventHeap
+	// dropped holds the log events sampled out if config.keepDropped is
+	// set by Config.OnDataDropped.
+	dropped []logEvent
+	// memoryLimitDropped holds the log events dropped by
+	// Config.MemoryLimit if config.keepDropped is set.
+	memoryLimitDropped []logEvent
+	// droppedDiscarded counts the log events sampled out or dropped by
+	// Config.MemoryLimit which were not kept because of droppedDataLimit.
+	droppedDiscarded int
 }
 
 // NumSeen returns the number of events seen
@@ -53,6 +62,8 @@ func (events *logEvents) RecordLoggingMetrics(metrics *metricTable) {
 
 	if events.config.collectEvents {
 		metrics.addCount(logsDropped, seen-saved, forced)
+		metrics.addCount(logEventsSeen, seen, forced)
+		metrics.addCount(logEventsSent, saved, forced)
 	}
 }
 
@@ -67,7 +78,10 @@ func (h logEventHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
 
 // To avoid using interface reflection, this function is used in place of Push() to add log events to the heap
 // Please replace all of this when the minimum supported version of go is 1.18 so that we can use generics
-func (h *logEventHeap) Add(event *logEvent) {
+//
+// It returns the event sampled out to make room for the event, or the event
+// itself if it is sampled out, or nil if the heap was not full.
+func (h *logEventHeap) Add(event *logEvent) *logEvent {
 	// when fewer events are in the heap than the capacity, do not heap sort
 	if len(*h) < cap(*h) {
 		// copy log event onto event heap
@@ -78,15 +92,17 @@ func (h *logEventHeap) Add(event *logEvent) {
 			// is not being reached).
 			heap.Init(*h)
 		}
-		return
+		return nil
 	}
 
 	if event.priority.isLowerPriority((*h)[0].priority) {
-		return
+		return event
 	}
 
+	dropped := (*h)[0]
 	(*h)[0] = *event
 	heap.Fix(h, 0)
+	return &dropped
 }
 
 // Push and Pop are unused: only heap.Init and heap.Fix are used.
@@ -119,7 +135,9 @@ func (events *logEvents) Add(e *logEvent) {
 	}
 
 	// Add logs to event heap
-	events.logs.Add(e)
+	if dropped := events.logs.Add(e); nil != dropped {
+		events.drop(*dropped)
+	}
 }
 
 func (events *logEvents) mergeFailed(other *logEvents) {
@@ -473,6 +473,7 @@ func BenchmarkRecordLoggingMetrics(b *testing.B) {
 			fmt.Sprintf("User 'xyz' logged in %d", i),
 			"123456789ADF",
 			"ADF09876565",
+			nil,
 		}
 
 		h.LogEvents.Add(&logEvent)
@@ -0,0 +1,109 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "sort"
+
+// The estimated sizes in bytes of the data buffered in a harvest, including
+// the attributes of each event.  They are used to enforce Config.MemoryLimit
+// without serializing the data.
+const (
+	estimatedSpanEventSize = 1024
+	estimatedEventSize     = 512
+	estimatedLogEventSize  = 512
+	estimatedMetricSize    = 128
+)
+
+// droppableEvents is an event pool whose lowest priority events can be
+// dropped to stay within Config.MemoryLimit.
+type droppableEvents interface {
+	dropLowest(n int) int
+}
+
+// dropLowest removes up to n of the lowest priority events, returning the
+// number removed.  The capacity of the reservoir is unchanged.  The events
+// removed are kept for Config.OnDataDropped.
+func (events *analyticsEvents) dropLowest(n int) int {
+	if n > len(events.events) {
+		n = len(events.events)
+	}
+	if n <= 0 {
+		return 0
+	}
+	// A sorted slice is a valid heap, as is what remains of it.
+	sort.Sort(events.events)
+	for _, e := range events.events[:n] {
+		events.dropForMemoryLimit(e)
+	}
+	kept := copy(events.events, events.events[n:])
+	events.events = events.events[:kept]
+	return n
+}
+
+// dropLowest removes up to n of the lowest priority log events, returning the
+// number removed.  The capacity of the reservoir is unchanged.  The events
+// removed are kept for Config.OnDataDropped.
+func (events *logEvents) dropLowest(n int) int {
+	if n > len(events.logs) {
+		n = len(events.logs)
+	}
+	if n <= 0 {
+		return 0
+	}
+	sort.Sort(events.logs)
+	for _, e := range events.logs[:n] {
+		events.dropForMemoryLimit(e)
+	}
+	kept := copy(events.logs, events.logs[n:])
+	events.logs = events.logs[:kept]
+	return n
+}
+
+// estimatedSize returns the approximate number of bytes of the data buffered
+// in the harvest.
+func (h *harvest) estimatedSize() int {
+	return estimatedMetricSize*len(h.Metrics.metrics) +
+		estimatedSpanEventSize*len(h.SpanEvents.events) +
+		estimatedEventSize*(len(h.CustomEvents.events)+len(h.TxnEvents.events)+len(h.ErrorEvents.events)) +
+		estimatedLogEventSize*len(h.LogEvents.logs)
+}
+
+// fitMemoryLimit drops the lowest priority events until the harvest, grown by
+// extra bytes, is within Config.MemoryLimit.  Span events are dropped first,
+// then custom, log, transaction, and error events.  It returns false if
+// dropping every event is not enough.
+func (h *harvest) fitMemoryLimit(extra int) bool {
+	if h.memoryLimit <= 0 {
+		return true
+	}
+	over := h.estimatedSize() + extra - h.memoryLimit
+	for _, pool := range []struct {
+		events droppableEvents
+		size   int
+		metric string
+	}{
+		{events: h.SpanEvents, size: estimatedSpanEventSize, metric: supportMemoryLimitSpanEvents},
+		{events: h.CustomEvents, size: estimatedEventSize, metric: supportMemoryLimitCustomEvents},
+		{events: h.LogEvents, size: estimatedLogEventSize, metric: supportMemoryLimitLogEvents},
+		{events: h.TxnEvents, size: estimatedEventSize, metric: supportMemoryLimitTxnEvents},
+		{events: h.ErrorEvents, size: estimatedEventSize, metric: supportMemoryLimitErrorEvents},
+	} {
+		if over <= 0 {
+			return true
+		}
+		dropped := pool.events.dropLowest((over + pool.size - 1) / pool.size)
+		if dropped > 0 {
+			h.Metrics.addCount(pool.metric, float64(dropped), forced)
+			over -= dropped * pool.size
+		}
+	}
+	return over <= 0
+}
+
+// merge merges the data into the harvest, dropping data as necessary to stay
+// within Config.MemoryLimit.
+func (h *harvest) merge(data harvestable) {
+	data.MergeIntoHarvest(h)
+	h.fitMemoryLimit(0)
+}
@@ -0,0 +1,188 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func memoryLimitTestHarvest(limit int) *harvest {
+	cfg := testHarvestCfgr
+	cfg.MemoryLimit = limit
+	return newHarvest(time.Now(), cfg)
+}
+
+func eventPriorities(events *analyticsEvents) map[priority]bool {
+	priorities := make(map[priority]bool)
+	for _, e := range events.events {
+		priorities[e.priority] = true
+	}
+	return priorities
+}
+
+func TestAnalyticsEventsDropLowest(t *testing.T) {
+	events := newAnalyticsEvents(5)
+	for _, p := range []priority{0.4, 0.1, 0.5, 0.3, 0.2} {
+		events.addEvent(analyticsEvent{priority: p})
+	}
+	if n := events.dropLowest(2); n != 2 {
+		t.Error(n)
+	}
+	if p := eventPriorities(events); len(p) != 3 || !p[0.3] || !p[0.4] || !p[0.5] {
+		t.Error(p)
+	}
+	if events.capacity() != 5 || events.numSeen != 5 {
+		t.Error(events.capacity(), events.numSeen)
+	}
+	// The reservoir remains usable once dropped from.
+	events.addEvent(analyticsEvent{priority: 0.6})
+	events.addEvent(analyticsEvent{priority: 0.7})
+	events.addEvent(analyticsEvent{priority: 0.8})
+	if p := eventPriorities(events); len(p) != 5 || p[0.3] || !p[0.8] {
+		t.Error(p)
+	}
+	if n := events.dropLowest(10); n != 5 || len(events.events) != 0 {
+		t.Error(n, len(events.events))
+	}
+}
+
+func TestLogEventsDropLowest(t *testing.T) {
+	events := newLogEvents(testCommonAttributes, loggingConfigEnabled(3))
+	events.Add(sampleLogEvent(0.9, infoLevel, "high"))
+	events.Add(sampleLogEvent(0.1, infoLevel, "low"))
+	events.Add(sampleLogEvent(0.5, infoLevel, "medium"))
+	if n := events.dropLowest(1); n != 1 {
+		t.Error(n)
+	}
+	if len(events.logs) != 2 {
+		t.Fatal(len(events.logs))
+	}
+	for _, e := range events.logs {
+		if e.message == "low" {
+			t.Error("lowest priority log event kept")
+		}
+	}
+	if nil != events.memoryLimitDropped {
+		t.Error(events.memoryLimitDropped)
+	}
+	// The dropped events are kept for Config.OnDataDropped.
+	events.config.keepDropped = true
+	events.dropLowest(1)
+	if len(events.memoryLimitDropped) != 1 || events.memoryLimitDropped[0].message != "medium" {
+		t.Error(events.memoryLimitDropped)
+	}
+}
+
+func TestFitMemoryLimitDropsSpansFirst(t *testing.T) {
+	h := memoryLimitTestHarvest(2*estimatedSpanEventSize + 2*estimatedEventSize)
+	for _, p := range []priority{0.1, 0.2, 0.3, 0.4} {
+		h.SpanEvents.addEvent(analyticsEvent{priority: p})
+	}
+	h.TxnEvents.addEvent(analyticsEvent{priority: 0.1})
+	h.TxnEvents.addEvent(analyticsEvent{priority: 0.2})
+
+	if !h.fitMemoryLimit(0) {
+		t.Error("limit not met")
+	}
+	if p := eventPriorities(h.SpanEvents.analyticsEvents); len(p) != 2 || !p[0.3] || !p[0.4] {
+		t.Error(p)
+	}
+	if n := len(h.TxnEvents.events); n != 2 {
+		t.Error(n)
+	}
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: supportMemoryLimitSpanEvents, Scope: "", Forced: true, Data: []float64{2, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestFitMemoryLimitDropsEventsInOrder(t *testing.T) {
+	h := memoryLimitTestHarvest(estimatedEventSize)
+	h.SpanEvents.addEvent(analyticsEvent{priority: 0.5})
+	h.CustomEvents.addEvent(analyticsEvent{priority: 0.5})
+	h.LogEvents.Add(sampleLogEvent(0.5, infoLevel, "message"))
+	h.TxnEvents.addEvent(analyticsEvent{priority: 0.1})
+	h.ErrorEvents.addEvent(analyticsEvent{priority: 0.1})
+
+	if !h.fitMemoryLimit(0) {
+		t.Error("limit not met")
+	}
+	if len(h.SpanEvents.events) != 0 || len(h.CustomEvents.events) != 0 ||
+		len(h.LogEvents.logs) != 0 || len(h.TxnEvents.events) != 0 {
+		t.Error(len(h.SpanEvents.events), len(h.CustomEvents.events),
+			len(h.LogEvents.logs), len(h.TxnEvents.events))
+	}
+	if n := len(h.ErrorEvents.events); n != 1 {
+		t.Error(n)
+	}
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: supportMemoryLimitSpanEvents, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: supportMemoryLimitCustomEvents, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: supportMemoryLimitLogEvents, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: supportMemoryLimitTxnEvents, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestFitMemoryLimitDisabled(t *testing.T) {
+	h := memoryLimitTestHarvest(0)
+	for i := 0; i < 10; i++ {
+		h.SpanEvents.addEvent(analyticsEvent{priority: 0.5})
+	}
+	if !h.fitMemoryLimit(1 << 30) {
+		t.Error("limit applied when disabled")
+	}
+	if n := len(h.SpanEvents.events); n != 10 {
+		t.Error(n)
+	}
+}
+
+func TestMemoryLimitFailedMetricsDropped(t *testing.T) {
+	now := time.Now()
+	h := memoryLimitTestHarvest(300)
+	h.SpanEvents.addEvent(analyticsEvent{priority: 0.5})
+
+	// The span event is dropped to make room for the failed metrics.
+	failed := newMetricTable(100, now)
+	failed.addSingleCount("one", unforced)
+	failed.MergeIntoHarvest(h)
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: "one", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: supportMemoryLimitSpanEvents, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+
+	// There is no room left for more failed metrics.
+	failed = newMetricTable(100, now)
+	failed.addSingleCount("two", unforced)
+	failed.addSingleCount("three", unforced)
+	failed.MergeIntoHarvest(h)
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: "one", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: supportMemoryLimitSpanEvents, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: supportMemoryLimitFailedMetrics, Scope: "", Forced: true, Data: []float64{2, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestMemoryLimitApplied(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.MemoryLimit = 1
+	}
+	app := testApp(nil, cfgfn, t)
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": 1})
+	app.ExpectCustomEvents(t, []internal.WantEvent{})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: supportMemoryLimitCustomEvents, Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestValidateMemoryLimit(t *testing.T) {
+	c := defaultConfig()
+	c.License = "0123456789012345678901234567890123456789"
+	c.AppName = "my app"
+	c.MemoryLimit = -1
+	if err := c.validate(); err != errMemoryLimit {
+		t.Error(err)
+	}
+}
@@ -3,12 +3,19 @@
 
 package newrelic
 
-import "fmt"
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
 
 const (
 	apdexRollup = "Apdex"
 	apdexPrefix = "Apdex/"
 
+	apdexOtherRollup = "ApdexOther"
+	apdexOtherPrefix = "ApdexOther/Transaction/"
+
 	webRollup        = "WebTransaction"
 	backgroundRollup = "OtherTransaction/all"
 
@@ -22,8 +29,19 @@ const (
 	// therefore should only be made for web transactions.
 	dispatcherMetric = "HttpDispatcher"
 
+	// Web transaction rollups by response status class, eg.
+	// "HttpDispatcher/StatusClass/2xx", and for requests canceled by the
+	// client before the transaction ended.
+	dispatcherStatusClassPrefix = "HttpDispatcher/StatusClass/"
+	dispatcherClientCanceled    = "HttpDispatcher/ClientCanceled"
+
 	queueMetric = "WebFrontend/QueueTime"
 
+	// Transaction duration histogram metrics, see
+	// Config.TransactionDurationHistogram.
+	histogramPrefix   = "TransactionHistogram/"
+	histogramOverflow = "+Inf"
+
 	// Transaction name prefixes are located in connect_reply.go.
 
 	instanceReporting = "Instance/Reporting"
@@ -39,13 +57,64 @@ const (
 	// https://source.datanerd.us/agents/agent-specs/blob/master/Error-Events.md
 	errorEventsSeen = "Supportability/Events/TransactionError/Seen"
 	errorEventsSent = "Supportability/Events/TransactionError/Sent"
+	// errorEventsClassLimited counts the error events dropped by
+	// Config.ErrorCollector.MaxEventsPerClass.
+	errorEventsClassLimited = "Supportability/Events/TransactionError/ClassLimited"
 
 	// https://source.datanerd.us/agents/agent-specs/blob/master/Span-Events.md
 	spanEventsSeen = "Supportability/SpanEvent/TotalEventsSeen"
 	spanEventsSent = "Supportability/SpanEvent/TotalEventsSent"
+	// spanAttributeOverflow counts the span attribute values replaced
+	// because of Config.SpanEvents.MaxAttributeValues.  The key is not
+	// part of the name, since the keys are chosen by the application.
+	spanAttributeOverflow = "Supportability/SpanEvent/UserAttributes/Overflow"
 
 	supportabilityDropped = "Supportability/MetricsDropped"
 
+	// supportDroppedDataDiscarded counts the events sampled out of a
+	// reservoir which were not given to Config.OnDataDropped because of
+	// droppedDataLimit.  It is suffixed with the DroppedData type.
+	supportDroppedDataDiscarded = "Supportability/Go/OnDataDropped/Discarded/"
+
+	// supportCustomMetricRejected counts the custom metrics rejected
+	// because of their name, see Config.CustomMetrics.
+	supportCustomMetricRejected = "Supportability/Go/CustomMetric/Rejected"
+
+	// Collector restart exceptions, recorded after the application has
+	// reconnected.  The HTTP error metric is suffixed with the status code.
+	supportCollectorRestart   = "Supportability/Go/Collector/Restart"
+	supportCollectorHTTPError = "Supportability/Agent/Collector/HTTPError"
+
+	// Data usage metrics describe the harvest requests: the count is the
+	// number of requests, the total is the number of uncompressed bytes
+	// sent, and the exclusive value is the number of bytes received.  The
+	// metric of each endpoint method is eg.
+	// "Supportability/Go/Collector/metric_data/Output/Bytes".
+	supportCollectorOutputBytes = "Supportability/Go/Collector/Output/Bytes"
+	supportCollectorPrefix      = "Supportability/Go/Collector/"
+
+	// Harvest cycle metrics recorded when Config.HarvestTelemetry is
+	// enabled.  Per request metrics are prefixed with the endpoint method,
+	// eg. "Supportability/Go/Harvest/metric_data/Duration".
+	supportHarvestDuration = "Supportability/Go/Harvest/Duration"
+	supportHarvestPrefix   = "Supportability/Go/Harvest/"
+
+	// Failed harvest metrics count the metrics of failed harvests which
+	// were dropped rather than merged into the next harvest, see
+	// Config.Harvest.FailedMetrics.
+	supportFailedMetricsCap      = "Supportability/Go/FailedHarvest/Metrics/Dropped/Cap"
+	supportFailedMetricsAge      = "Supportability/Go/FailedHarvest/Metrics/Dropped/Age"
+	supportFailedMetricsAttempts = "Supportability/Go/FailedHarvest/Metrics/Dropped/Attempts"
+
+	// Memory limit metrics count the data dropped to keep the buffered data
+	// within Config.MemoryLimit.
+	supportMemoryLimitSpanEvents    = "Supportability/Go/MemoryLimit/Dropped/SpanEvents"
+	supportMemoryLimitCustomEvents  = "Supportability/Go/MemoryLimit/Dropped/CustomEvents"
+	supportMemoryLimitLogEvents     = "Supportability/Go/MemoryLimit/Dropped/LogEvents"
+	supportMemoryLimitTxnEvents     = "Supportability/Go/MemoryLimit/Dropped/TransactionEvents"
+	supportMemoryLimitErrorEvents   = "Supportability/Go/MemoryLimit/Dropped/ErrorEvents"
+	supportMemoryLimitFailedMetrics = "Supportability/Go/MemoryLimit/Dropped/FailedMetrics"
+
 	// Runtime/System Metrics
 	memoryPhysical       = "Memory/Physical"
 	heapObjectsAllocated = "Memory/Heap/AllocatedObjects"
@@ -57,6 +126,11 @@ const (
 	gcPauseFraction      = "GC/System/Pause Fraction"
 	gcPauses             = "GC/System/Pauses"
 
+	// Runtime diagnostics metrics
+	schedLatencyMedian  = "Go/Runtime/Scheduler/Latency/50th Percentile"
+	schedLatencyP99     = "Go/Runtime/Scheduler/Latency/99th Percentile"
+	goroutineGrowthRate = "Go/Runtime/Goroutines/Growth Rate"
+
 	// Configurable event harvest supportability metrics
 	supportReportPeriod     = "Supportability/EventHarvest/ReportPeriod"
 	supportTxnEventLimit    = "Supportability/EventHarvest/AnalyticEventData/HarvestLimit"
@@ -96,6 +170,7 @@ type loggingConfig struct {
 	collectMetrics  bool // collection of log metric data is enabled
 	localEnrichment bool // local log enrichment is enabled
 	maxLogEvents    int  // maximum number of log events allowed to be collected
+	keepDropped     bool // log events sampled out are kept for Config.OnDataDropped
 }
 
 // Logging metrics that are generated at connect response
@@ -135,6 +210,15 @@ type distributedTracingSupport struct {
 	TraceContextStateNoNrEntry       bool // The traceparent header exists, and was accepted, but the tracestate header did not contain a trusted New Relic entry.
 	TraceContextCreateSuccess        bool // The agent successfully created the outbound payloads.
 	TraceContextCreateException      bool // A generic exception occurred while creating the outbound payloads.
+
+	// Header validation fields, only recorded in strict mode
+	MalformedTraceParent bool // The inbound traceparent header could not be parsed.
+	MalformedTraceState  bool // The inbound tracestate header contained an invalid New Relic entry.
+	MalformedNewRelic    bool // The inbound newrelic header could not be parsed.
+
+	// Debug header fields
+	DebugHeaderAccepted bool // The inbound debug trace header was valid.
+	DebugHeaderRejected bool // The inbound debug trace header was invalid or expired.
 }
 
 func (dts distributedTracingSupport) isEmpty() bool {
@@ -163,6 +247,27 @@ func (dts distributedTracingSupport) createMetrics(ms *metricTable) {
 	supportMetric(ms, dts.TraceContextCreateException, "Supportability/TraceContext/Create/Exception")
 	supportMetric(ms, dts.TraceContextStateInvalidNrEntry, "Supportability/TraceContext/TraceState/InvalidNrEntry")
 	supportMetric(ms, dts.TraceContextStateNoNrEntry, "Supportability/TraceContext/TraceState/NoNrEntry")
+
+	// Header Validation Supportability Metrics
+	supportMetric(ms, dts.MalformedTraceParent, "Supportability/DistributedTrace/MalformedHeader/TraceParent")
+	supportMetric(ms, dts.MalformedTraceState, "Supportability/DistributedTrace/MalformedHeader/TraceState")
+	supportMetric(ms, dts.MalformedNewRelic, "Supportability/DistributedTrace/MalformedHeader/NewRelic")
+
+	// Debug Header Supportability Metrics
+	supportMetric(ms, dts.DebugHeaderAccepted, "Supportability/DistributedTrace/DebugHeader/Accepted")
+	supportMetric(ms, dts.DebugHeaderRejected, "Supportability/DistributedTrace/DebugHeader/Rejected")
+}
+
+// crossAppTracingSupport is used to track the legacy cross application
+// tracing headers received while migrating to distributed tracing.
+type crossAppTracingSupport struct {
+	InboundReceived bool // The inbound request had CAT headers.
+	InboundIgnored  bool // The inbound request had CAT headers, but CAT is disabled.
+}
+
+func (cats crossAppTracingSupport) createMetrics(ms *metricTable) {
+	supportMetric(ms, cats.InboundReceived, "Supportability/CrossApplicationTracing/Inbound/Received")
+	supportMetric(ms, cats.InboundIgnored, "Supportability/CrossApplicationTracing/Inbound/Ignored")
 }
 
 type rollupMetric struct {
@@ -271,6 +376,12 @@ func datastoreOperationMetric(key datastoreMetricKey) string {
 		"/" + key.Operation
 }
 
+// Datastore/pipeline/{datastore}/{operation}
+func datastorePipelineMetric(key datastoreMetricKey) string {
+	return "Datastore/pipeline/" + key.Product +
+		"/" + key.Operation
+}
+
 // Datastore/statement/{datastore}/{table}/{operation}
 func datastoreStatementMetric(key datastoreMetricKey) string {
 	return "Datastore/statement/" + key.Product +
@@ -338,3 +449,40 @@ func errorsByCallerMetric(c payloadCaller) rollupMetric {
 func transportDurationMetric(c payloadCaller) rollupMetric {
 	return newRollupMetric("TransportDuration" + callerFields(c))
 }
+
+// Relationship/Inbound/{type}/{account}/{app}/{transport}
+func relationshipMetric(c payloadCaller) string {
+	return "Relationship/Inbound/" + c.Type +
+		"/" + c.Account +
+		"/" + c.App +
+		"/" + c.TransportType
+}
+
+// Breakdown/{txnName}/{metric}
+func breakdownHostMetric(txnName, metric string) string {
+	return "Breakdown/" + txnName + "/" + metric
+}
+
+// histogramMetric returns the duration histogram metric name for the
+// transaction and duration.  The buckets must be increasing.  The buckets are
+// not cumulative: the duration is only counted in the smallest bucket which
+// contains it.
+func histogramMetric(txnName string, buckets []time.Duration, d time.Duration) string {
+	bucket := histogramOverflow
+	for _, b := range buckets {
+		if d <= b {
+			bucket = strconv.FormatFloat(b.Seconds(), 'f', -1, 64)
+			break
+		}
+	}
+	return histogramPrefix + txnName + "/bucket/" + bucket
+}
+
+// statusClassMetric returns the status class rollup metric name for the
+// response code, or the empty string if the code is not a valid HTTP status.
+func statusClassMetric(code int) string {
+	if code < 100 || code > 599 {
+		return ""
+	}
+	return dispatcherStatusClassPrefix + strconv.Itoa(code/100) + "xx"
+}
@@ -50,11 +50,22 @@ type metric struct {
 	data   metricData
 }
 
+// failedMetricLimits limits the metrics of failed harvests merged into a
+// metricTable, see Config.Harvest.FailedMetrics.
+type failedMetricLimits struct {
+	maxMetrics int
+	maxAge     time.Duration
+}
+
 type metricTable struct {
 	metricPeriodStart time.Time
 	failedHarvests    int
 	maxTableSize      int // After this max is reached, only forced metrics are added
 	metrics           map[metricID]*metric
+	failedLimits      failedMetricLimits
+	// numFailedMetrics is the number of metrics added to the table by
+	// merging failed harvests.
+	numFailedMetrics int
 }
 
 func newMetricTable(maxTableSize int, now time.Time) *metricTable {
@@ -107,13 +118,31 @@ func (mt *metricTable) mergeMetric(id metricID, m metric) {
 func (mt *metricTable) mergeFailed(from *metricTable) {
 	fails := from.failedHarvests + 1
 	if fails >= failedMetricAttemptsLimit {
+		mt.addCount(supportFailedMetricsAttempts, float64(len(from.metrics)), forced)
+		return
+	}
+	if max := mt.failedLimits.maxAge; max > 0 && mt.metricPeriodStart.Sub(from.metricPeriodStart) > max {
+		mt.addCount(supportFailedMetricsAge, float64(len(from.metrics)), forced)
 		return
 	}
 	if from.metricPeriodStart.Before(mt.metricPeriodStart) {
 		mt.metricPeriodStart = from.metricPeriodStart
 	}
 	mt.failedHarvests = fails
-	mt.merge(from, "")
+	dropped := 0
+	for id, m := range from.metrics {
+		if _, ok := mt.metrics[id]; !ok {
+			if max := mt.failedLimits.maxMetrics; max > 0 && mt.numFailedMetrics >= max {
+				dropped++
+				continue
+			}
+			mt.numFailedMetrics++
+		}
+		mt.mergeMetric(id, *m)
+	}
+	if dropped > 0 {
+		mt.addCount(supportFailedMetricsCap, float64(dropped), forced)
+	}
 }
 
 func (mt *metricTable) merge(from *metricTable, newScope string) {
@@ -231,6 +260,12 @@ func (mt *metricTable) Data(agentRunID string, harvestStart time.Time) ([]byte,
 	return mt.CollectorJSON(agentRunID, harvestStart)
 }
 func (mt *metricTable) MergeIntoHarvest(h *harvest) {
+	// The metrics of a failed harvest are dropped if there is no room for
+	// them within Config.MemoryLimit.
+	if !h.fitMemoryLimit(estimatedMetricSize * len(mt.metrics)) {
+		h.Metrics.addCount(supportMemoryLimitFailedMetrics, float64(len(mt.metrics)), forced)
+		return
+	}
 	h.Metrics.mergeFailed(mt)
 }
 
@@ -243,6 +278,10 @@ func (mt *metricTable) ApplyRules(rules internal.MetricRules) *metricTable {
 	}
 
 	applied := newMetricTable(mt.maxTableSize, mt.metricPeriodStart)
+	// The number of failed harvests is kept so that the metrics are not
+	// retried indefinitely.
+	applied.failedHarvests = mt.failedHarvests
+	applied.failedLimits = mt.failedLimits
 	cache := make(map[string]string)
 
 	for id, m := range mt.metrics {
@@ -249,9 +249,69 @@ func TestMergeFailedLimitReached(t *testing.T) {
 	expectMetrics(t, dest, []internal.WantMetric{
 		{Name: "one", Scope: "", Forced: false, Data: []float64{1, 2, 1, 2, 2, 4}},
 		{Name: "two", Scope: "", Forced: false, Data: []float64{1, 2, 1, 2, 2, 4}},
+		{Name: supportFailedMetricsAttempts, Scope: "", Forced: true, Data: []float64{2, 0, 0, 0, 0, 0}},
 	})
 }
 
+func TestMergeFailedMaxAge(t *testing.T) {
+	src := newMetricTable(20, start)
+	dest := newMetricTable(20, start.Add(11*time.Minute))
+	dest.failedLimits.maxAge = 10 * time.Minute
+	dest.addDuration("one", "", 2*time.Second, 1*time.Second, unforced)
+	src.addDuration("two", "", 2*time.Second, 1*time.Second, unforced)
+	src.addDuration("three", "", 2*time.Second, 1*time.Second, forced)
+
+	dest.mergeFailed(src)
+
+	expectMetrics(t, dest, []internal.WantMetric{
+		{Name: "one", Scope: "", Forced: false, Data: []float64{1, 2, 1, 2, 2, 4}},
+		{Name: supportFailedMetricsAge, Scope: "", Forced: true, Data: []float64{2, 0, 0, 0, 0, 0}},
+	})
+	if 0 != dest.failedHarvests {
+		t.Error(dest.failedHarvests)
+	}
+}
+
+func TestMergeFailedMaxMetrics(t *testing.T) {
+	src := newMetricTable(20, start)
+	dest := newMetricTable(20, end)
+	dest.failedLimits.maxMetrics = 2
+	dest.addDuration("one", "", 2*time.Second, 1*time.Second, unforced)
+	src.addDuration("one", "", 2*time.Second, 1*time.Second, unforced)
+	src.addDuration("two", "", 2*time.Second, 1*time.Second, forced)
+	src.addDuration("three", "", 2*time.Second, 1*time.Second, unforced)
+	src.addDuration("four", "", 2*time.Second, 1*time.Second, unforced)
+
+	dest.mergeFailed(src)
+
+	// Metrics already in the table are always merged.
+	if m := dest.metrics[metricID{Name: "one"}]; nil == m || m.data.countSatisfied != 2 {
+		t.Error(m)
+	}
+	if len(dest.metrics) != 4 || dest.numFailedMetrics != 2 {
+		t.Error(len(dest.metrics), dest.numFailedMetrics)
+	}
+	if m := dest.metrics[metricID{Name: supportFailedMetricsCap}]; nil == m || m.data.countSatisfied != 1 {
+		t.Error(m)
+	}
+}
+
+func TestApplyRulesKeepsFailedHarvests(t *testing.T) {
+	js := `[{"match_expression":"one$","replacement":"uno","each_segment":false,"replace_all":false,"ignore":false,"eval_order":0,"terminate_chain":true}]`
+	var rules internal.MetricRules
+	if err := json.Unmarshal([]byte(js), &rules); nil != err {
+		t.Fatal(err)
+	}
+	mt := newMetricTable(20, start)
+	mt.failedHarvests = 2
+	mt.failedLimits.maxMetrics = 7
+	mt.addDuration("one", "", 2*time.Second, 1*time.Second, unforced)
+	applied := mt.ApplyRules(rules)
+	if applied == mt || applied.failedHarvests != 2 || applied.failedLimits.maxMetrics != 7 {
+		t.Error(applied.failedHarvests, applied.failedLimits)
+	}
+}
+
 func BenchmarkMetricTableCollectorJSON(b *testing.B) {
 	mt := newMetricTable(2000, time.Now())
 	md := metricData{
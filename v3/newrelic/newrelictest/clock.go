@@ -0,0 +1,102 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelictest
+
+import (
+	"sync"
+	"time"
+
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+// Clock is a newrelic.Clock whose time only changes when Advance is called.
+// Use it as the Config.Clock to control transaction and segment durations:
+//
+//	clock := newrelictest.NewClock(time.Now())
+//	app := newrelictest.NewApp(func(cfg *newrelic.Config) {
+//		cfg.Clock = clock
+//	})
+//	txn := app.StartTransaction("hello")
+//	clock.Advance(2 * time.Second)
+//	txn.End()
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*clockTicker
+}
+
+// NewClock creates a Clock set to the given time.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the Clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the Clock forward and delivers a tick to each Ticker whose
+// interval has elapsed.  As with time.Ticker, ticks are dropped if the
+// previous tick has not been received.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		t.advance(c.now)
+	}
+}
+
+// NewTicker returns a Ticker driven by Advance.
+func (c *Clock) NewTicker(d time.Duration) newrelic.Ticker {
+	if d <= 0 {
+		panic("non-positive interval for Clock.NewTicker")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &clockTicker{
+		clock:    c,
+		c:        make(chan time.Time, 1),
+		interval: d,
+		next:     c.now.Add(d),
+	}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+type clockTicker struct {
+	clock    *Clock
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time
+}
+
+func (t *clockTicker) advance(now time.Time) {
+	if now.Before(t.next) {
+		return
+	}
+	for !now.Before(t.next) {
+		t.next = t.next.Add(t.interval)
+	}
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+func (t *clockTicker) C() <-chan time.Time { return t.c }
+
+func (t *clockTicker) Stop() {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, other := range c.tickers {
+		if other == t {
+			c.tickers = append(c.tickers[:i], c.tickers[i+1:]...)
+			return
+		}
+	}
+}
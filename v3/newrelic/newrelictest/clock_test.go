@@ -0,0 +1,96 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelictest
+
+import (
+	"testing"
+	"time"
+
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+func TestClockTicker(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewClock(start)
+	ticker := clock.NewTicker(time.Second)
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case tm := <-ticker.C():
+		t.Fatal("unexpected tick", tm)
+	default:
+	}
+
+	clock.Advance(3 * time.Second)
+	select {
+	case tm := <-ticker.C():
+		if !tm.Equal(start.Add(3500 * time.Millisecond)) {
+			t.Error(tm)
+		}
+	default:
+		t.Fatal("missing tick")
+	}
+
+	ticker.Stop()
+	clock.Advance(time.Minute)
+	select {
+	case tm := <-ticker.C():
+		t.Fatal("tick after stop", tm)
+	default:
+	}
+}
+
+func TestClockTransactionDuration(t *testing.T) {
+	clock := NewClock(time.Unix(1000, 0))
+	app := NewApp(
+		newrelic.ConfigDistributedTracerEnabled(false),
+		func(cfg *newrelic.Config) { cfg.Clock = clock },
+	)
+	txn := app.StartTransaction("hello")
+	clock.Advance(2 * time.Second)
+	txn.End()
+
+	app.ExpectTxnEvents(t, []WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":      "OtherTransaction/Go/hello",
+			"timestamp": float64(1000 * 1000),
+			"duration":  float64(2),
+		},
+	}})
+}
+
+func TestClockSlowQueryThreshold(t *testing.T) {
+	clock := NewClock(time.Unix(1000, 0))
+	app := NewApp(func(cfg *newrelic.Config) {
+		cfg.Clock = clock
+		cfg.DatastoreTracer.SlowQuery.Threshold = 100 * time.Millisecond
+	})
+	txn := app.StartTransaction("hello")
+	fast := newrelic.DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            newrelic.DatastorePostgres,
+		Collection:         "users",
+		Operation:          "SELECT",
+		ParameterizedQuery: "SELECT * FROM users",
+	}
+	clock.Advance(99 * time.Millisecond)
+	fast.End()
+	slow := newrelic.DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            newrelic.DatastorePostgres,
+		Collection:         "users",
+		Operation:          "INSERT",
+		ParameterizedQuery: "INSERT INTO users (name) VALUES ($1)",
+	}
+	clock.Advance(101 * time.Millisecond)
+	slow.End()
+	txn.End()
+
+	app.ExpectSlowQueries(t, []WantSlowQuery{{
+		Count:      1,
+		MetricName: "Datastore/statement/Postgres/users/INSERT",
+		Query:      "INSERT INTO users (name) VALUES ($1)",
+		TxnName:    "OtherTransaction/Go/hello",
+	}})
+}
@@ -0,0 +1,238 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelictest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+// Collector protocol methods.  These are the values of CollectorRequest.Method
+// and the methods accepted by Collector.QueueResponse.
+const (
+	MethodPreconnect   = "preconnect"
+	MethodConnect      = "connect"
+	MethodMetrics      = "metric_data"
+	MethodCustomEvents = "custom_event_data"
+	MethodLogEvents    = "log_event_data"
+	MethodTxnEvents    = "analytic_event_data"
+	MethodErrorEvents  = "error_event_data"
+	MethodErrors       = "error_data"
+	MethodTxnTraces    = "transaction_sample_data"
+	MethodSlowQueries  = "sql_trace_data"
+	MethodSpanEvents   = "span_event_data"
+)
+
+// CollectorRequest is a request received by a Collector.
+type CollectorRequest struct {
+	Method string
+	RunID  string
+	// Body is the decompressed request payload.
+	Body []byte
+}
+
+type collectorResponse struct {
+	statusCode int
+	body       string
+}
+
+// Collector is an in-process server implementing enough of the New Relic
+// collector protocol to run an application end to end in tests.  By default
+// the preconnect, connect, and harvest endpoints all succeed, and each connect
+// is given a new run ID.  Use QueueResponse to simulate errors, such as the 409
+// restart and 410 disconnect responses.
+//
+//	collector := newrelictest.NewCollector()
+//	defer collector.Close()
+//	app, _ := newrelic.NewApplication(collector.ConfigOption())
+//	app.WaitForConnection(5 * time.Second)
+//	app.RecordCustomEvent("myEvent", nil)
+//	app.Shutdown(5 * time.Second)
+//	events := collector.Requests(newrelictest.MethodCustomEvents)
+type Collector struct {
+	server *httptest.Server
+
+	mu          sync.Mutex
+	connects    int
+	replyFields map[string]interface{}
+	responses   map[string][]collectorResponse
+	requests    []CollectorRequest
+}
+
+// NewCollector starts a Collector.  Call Close when done.
+func NewCollector() *Collector {
+	c := &Collector{
+		replyFields: map[string]interface{}{
+			"collect_analytics_events":          true,
+			"collect_custom_events":             true,
+			"collect_traces":                    true,
+			"collect_errors":                    true,
+			"collect_error_events":              true,
+			"collect_span_events":               true,
+			"sampling_target":                   1000 * 1000 * 1000,
+			"sampling_target_period_in_seconds": 1000 * 1000 * 1000,
+		},
+		responses: make(map[string][]collectorResponse),
+	}
+	c.server = httptest.NewTLSServer(http.HandlerFunc(c.serveHTTP))
+	return c
+}
+
+// Close shuts down the Collector.
+func (c *Collector) Close() {
+	c.server.Close()
+}
+
+// Host returns the host and port of the Collector.
+func (c *Collector) Host() string {
+	return c.server.Listener.Addr().String()
+}
+
+// ConfigOption configures an application to connect to the Collector.  It
+// enables the application, sets a placeholder license key and application
+// name, and disables cloud provider utilization detection.
+func (c *Collector) ConfigOption() newrelic.ConfigOption {
+	return func(cfg *newrelic.Config) {
+		cfg.Enabled = true
+		cfg.Host = c.Host()
+		cfg.Transport = c.server.Client().Transport
+		cfg.License = testLicenseKey
+		if "" == cfg.AppName {
+			cfg.AppName = AppName
+		}
+		cfg.Utilization.DetectAWS = false
+		cfg.Utilization.DetectAzure = false
+		cfg.Utilization.DetectGCP = false
+		cfg.Utilization.DetectPCF = false
+		cfg.Utilization.DetectDocker = false
+		cfg.Utilization.DetectKubernetes = false
+	}
+}
+
+// SetConnectReplyFields adds fields to the "return_value" object of
+// subsequent connect replies, such as "event_harvest_config" or
+// "agent_config".  The "agent_run_id" field is always set by the Collector.
+func (c *Collector) SetConnectReplyFields(fields map[string]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, v := range fields {
+		c.replyFields[k] = v
+	}
+}
+
+// QueueResponse sets the response to the next request for the method.
+// Responses queued for the same method are returned in order, after which
+// requests succeed again.
+func (c *Collector) QueueResponse(method string, statusCode int, body string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[method] = append(c.responses[method], collectorResponse{
+		statusCode: statusCode,
+		body:       body,
+	})
+}
+
+// Requests returns the requests received for the method.
+func (c *Collector) Requests(method string) []CollectorRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []CollectorRequest
+	for _, r := range c.requests {
+		if r.Method == method {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// WaitForRequests waits until at least n requests for the method have been
+// received or the timeout elapses, and returns the requests received.
+func (c *Collector) WaitForRequests(method string, n int, timeout time.Duration) []CollectorRequest {
+	deadline := time.Now().Add(timeout)
+	for {
+		reqs := c.Requests(method)
+		if len(reqs) >= n || time.Now().After(deadline) {
+			return reqs
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (c *Collector) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Query().Get("method")
+	body, err := readCollectorBody(r)
+	if nil != err {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	c.requests = append(c.requests, CollectorRequest{
+		Method: method,
+		RunID:  r.URL.Query().Get("run_id"),
+		Body:   body,
+	})
+	var resp collectorResponse
+	if queued := c.responses[method]; len(queued) > 0 {
+		resp = queued[0]
+		c.responses[method] = queued[1:]
+	} else {
+		resp = c.defaultResponse(method)
+	}
+	c.mu.Unlock()
+
+	w.WriteHeader(resp.statusCode)
+	w.Write([]byte(resp.body))
+}
+
+// defaultResponse must be called with the lock held.
+func (c *Collector) defaultResponse(method string) collectorResponse {
+	switch method {
+	case MethodPreconnect:
+		return collectorResponse{
+			statusCode: http.StatusOK,
+			body:       fmt.Sprintf(`{"return_value":{"redirect_host":%q}}`, c.Host()),
+		}
+	case MethodConnect:
+		c.connects++
+		reply := make(map[string]interface{}, len(c.replyFields)+1)
+		for k, v := range c.replyFields {
+			reply[k] = v
+		}
+		reply["agent_run_id"] = fmt.Sprintf("run-%d", c.connects)
+		js, err := json.Marshal(map[string]interface{}{"return_value": reply})
+		if nil != err {
+			return collectorResponse{statusCode: http.StatusInternalServerError, body: err.Error()}
+		}
+		return collectorResponse{statusCode: http.StatusOK, body: string(js)}
+	default:
+		return collectorResponse{statusCode: http.StatusOK, body: `{"return_value":null}`}
+	}
+}
+
+func readCollectorBody(r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+	raw, err := ioutil.ReadAll(r.Body)
+	if nil != err {
+		return nil, err
+	}
+	if "gzip" != r.Header.Get("Content-Encoding") {
+		return raw, nil
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if nil != err {
+		return nil, err
+	}
+	defer gz.Close()
+	return ioutil.ReadAll(gz)
+}
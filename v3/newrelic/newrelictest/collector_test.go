@@ -0,0 +1,232 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelictest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+const collectorTestTimeout = 5 * time.Second
+
+func newCollectorApp(t *testing.T, collector *Collector, options ...newrelic.ConfigOption) *newrelic.Application {
+	options = append([]newrelic.ConfigOption{collector.ConfigOption()}, options...)
+	app, err := newrelic.NewApplication(options...)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := app.WaitForConnection(collectorTestTimeout); nil != err {
+		t.Fatal(err)
+	}
+	return app
+}
+
+// advanceUntilRequest advances the clock past the harvest period until the
+// collector receives a request for the method.  The clock is advanced more than
+// once since recorded data reaches the harvest asynchronously.
+func advanceUntilRequest(t *testing.T, clock *Clock, collector *Collector, method string) {
+	deadline := time.Now().Add(collectorTestTimeout)
+	for 0 == len(collector.Requests(method)) {
+		if time.Now().After(deadline) {
+			t.Fatal("no request received for", method)
+		}
+		clock.Advance(61 * time.Second)
+		collector.WaitForRequests(method, 1, 50*time.Millisecond)
+	}
+}
+
+func TestCollectorWaitForConnectionCtx(t *testing.T) {
+	collector := NewCollector()
+	defer collector.Close()
+
+	app, err := newrelic.NewApplication(collector.ConfigOption())
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer app.Shutdown(collectorTestTimeout)
+
+	var attempts []newrelic.ConnectAttempt
+	ctx, cancel := context.WithTimeout(context.Background(), collectorTestTimeout)
+	defer cancel()
+	err = app.WaitForConnectionCtx(ctx, newrelic.WithConnectAttemptCallback(func(a newrelic.ConnectAttempt) {
+		attempts = append(attempts, a)
+	}))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if len(attempts) != 1 || attempts[0].Attempt != 1 || nil != attempts[0].Err {
+		t.Error(attempts)
+	}
+}
+
+func TestCollectorEndToEnd(t *testing.T) {
+	collector := NewCollector()
+	defer collector.Close()
+
+	app := newCollectorApp(t, collector)
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": "zap"})
+	app.Shutdown(collectorTestTimeout)
+
+	if reqs := collector.Requests(MethodPreconnect); len(reqs) != 1 {
+		t.Error(len(reqs))
+	}
+	if reqs := collector.Requests(MethodConnect); len(reqs) != 1 {
+		t.Error(len(reqs))
+	}
+	reqs := collector.Requests(MethodCustomEvents)
+	if len(reqs) != 1 {
+		t.Fatal(len(reqs))
+	}
+	if reqs[0].RunID != "run-1" {
+		t.Error(reqs[0].RunID)
+	}
+	if !bytes.Contains(reqs[0].Body, []byte(`"myEvent"`)) {
+		t.Error(string(reqs[0].Body))
+	}
+	if reqs := collector.Requests(MethodMetrics); len(reqs) != 1 {
+		t.Error(len(reqs))
+	}
+}
+
+func TestCollectorConnectReplyFields(t *testing.T) {
+	collector := NewCollector()
+	defer collector.Close()
+	collector.SetConnectReplyFields(map[string]interface{}{
+		"collect_custom_events": false,
+	})
+
+	app := newCollectorApp(t, collector)
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": "zap"})
+	app.Shutdown(collectorTestTimeout)
+
+	if reqs := collector.Requests(MethodCustomEvents); len(reqs) != 0 {
+		t.Error(len(reqs))
+	}
+}
+
+func TestCollectorRestart(t *testing.T) {
+	collector := NewCollector()
+	defer collector.Close()
+	collector.QueueResponse(MethodCustomEvents, http.StatusConflict, "")
+
+	clock := NewClock(time.Now())
+	app := newCollectorApp(t, collector, func(cfg *newrelic.Config) { cfg.Clock = clock })
+	defer app.Shutdown(collectorTestTimeout)
+
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": "zap"})
+	advanceUntilRequest(t, clock, collector, MethodCustomEvents)
+
+	reqs := collector.WaitForRequests(MethodConnect, 2, collectorTestTimeout)
+	if len(reqs) != 2 {
+		t.Fatal(len(reqs))
+	}
+	if err := app.WaitForConnection(collectorTestTimeout); nil != err {
+		t.Error(err)
+	}
+}
+
+func TestCollectorRestartKeepsData(t *testing.T) {
+	collector := NewCollector()
+	defer collector.Close()
+	collector.QueueResponse(MethodCustomEvents, http.StatusConflict, "")
+
+	clock := NewClock(time.Now())
+	app := newCollectorApp(t, collector, func(cfg *newrelic.Config) { cfg.Clock = clock })
+	defer app.Shutdown(collectorTestTimeout)
+
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": "zap"})
+	advanceUntilRequest(t, clock, collector, MethodCustomEvents)
+	if reqs := collector.WaitForRequests(MethodConnect, 2, collectorTestTimeout); len(reqs) != 2 {
+		t.Fatal(len(reqs))
+	}
+	if err := app.WaitForConnection(collectorTestTimeout); nil != err {
+		t.Fatal(err)
+	}
+
+	// The custom event and the metrics which were not sent because of the
+	// restart are sent for the new run.
+	deadline := time.Now().Add(collectorTestTimeout)
+	for len(collector.Requests(MethodCustomEvents)) < 2 || len(collector.Requests(MethodMetrics)) < 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("data not sent after restart")
+		}
+		clock.Advance(61 * time.Second)
+		collector.WaitForRequests(MethodMetrics, 1, 50*time.Millisecond)
+	}
+	events := collector.Requests(MethodCustomEvents)[1]
+	if events.RunID != "run-2" || !bytes.Contains(events.Body, []byte(`"myEvent"`)) {
+		t.Error(events.RunID, string(events.Body))
+	}
+	metrics := collector.Requests(MethodMetrics)[0]
+	if metrics.RunID != "run-2" {
+		t.Error(metrics.RunID)
+	}
+	for _, name := range []string{
+		"Supportability/Go/Collector/Restart",
+		"Supportability/Agent/Collector/HTTPError/409",
+	} {
+		if !bytes.Contains(metrics.Body, []byte(name)) {
+			t.Error("missing metric", name)
+		}
+	}
+}
+
+func TestCollectorHarvestTelemetry(t *testing.T) {
+	collector := NewCollector()
+	defer collector.Close()
+
+	clock := NewClock(time.Now())
+	app := newCollectorApp(t, collector, func(cfg *newrelic.Config) {
+		cfg.Clock = clock
+		cfg.HarvestTelemetry.Enabled = true
+	})
+	defer app.Shutdown(collectorTestTimeout)
+
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": "zap"})
+	advanceUntilRequest(t, clock, collector, MethodCustomEvents)
+
+	// The telemetry of a harvest is sent with the following harvest.
+	name := []byte("Supportability/Go/Harvest/custom_event_data/Response/")
+	deadline := time.Now().Add(collectorTestTimeout)
+	for {
+		reqs := collector.Requests(MethodMetrics)
+		if n := len(reqs); n > 0 && bytes.Contains(reqs[n-1].Body, name) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("harvest telemetry not sent")
+		}
+		clock.Advance(61 * time.Second)
+		collector.WaitForRequests(MethodMetrics, len(reqs)+1, 50*time.Millisecond)
+	}
+}
+
+func TestCollectorDisconnect(t *testing.T) {
+	collector := NewCollector()
+	defer collector.Close()
+	collector.QueueResponse(MethodCustomEvents, http.StatusGone, "")
+
+	clock := NewClock(time.Now())
+	app := newCollectorApp(t, collector, func(cfg *newrelic.Config) { cfg.Clock = clock })
+	defer app.Shutdown(collectorTestTimeout)
+
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": "zap"})
+	advanceUntilRequest(t, clock, collector, MethodCustomEvents)
+
+	deadline := time.Now().Add(collectorTestTimeout)
+	for nil == app.WaitForConnection(0) {
+		if time.Now().After(deadline) {
+			t.Fatal("application did not disconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if reqs := collector.Requests(MethodConnect); len(reqs) != 1 {
+		t.Error(len(reqs))
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package newrelictest helps applications assert in their own unit tests
+// which metrics, events, and attributes their instrumentation produced.
+//
+// An App never connects to New Relic.  Data recorded by the App's
+// transactions is kept in memory until it is checked with one of the Expect
+// methods:
+//
+//	app := newrelictest.NewApp()
+//	txn := app.StartTransaction("checkout")
+//	txn.AddAttribute("cart.size", 3)
+//	txn.End()
+//
+//	app.ExpectTxnEvents(t, []newrelictest.WantEvent{{
+//		Intrinsics: map[string]interface{}{
+//			"name": "OtherTransaction/Go/checkout",
+//		},
+//		UserAttributes: map[string]interface{}{
+//			"cart.size": 3,
+//		},
+//	}})
+//
+// Intrinsics are matched exactly: any intrinsic not listed with a value must
+// be given the MatchAnything placeholder.  Use ExpectMetricsPresent rather than
+// ExpectMetrics when only some of the recorded metrics are of interest.
+package newrelictest
+
+import (
+	"github.com/rainforestpay/go-agent/v3/internal"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+const (
+	testLicenseKey = "0123456789012345678901234567890123456789"
+	// AppName is the name given to applications created by NewApp.
+	AppName = "my app"
+)
+
+// MatchAnything may be used as an expected intrinsic or attribute value to
+// match any recorded value.
+var MatchAnything interface{} = internal.MatchAnything
+
+// Validator is implemented by *testing.T and *testing.B.
+type Validator interface {
+	Error(...interface{})
+}
+
+// WantMetric is a metric expectation.  If Data is nil, then any data values
+// are acceptable.  If Data has len 1, then only the metric count is
+// validated.  Forced may be true, false, or nil to match either.
+type WantMetric struct {
+	Name   string
+	Scope  string
+	Forced interface{}
+	Data   []float64
+}
+
+// WantEvent is a transaction, custom, error, or span event expectation.
+type WantEvent struct {
+	Intrinsics      map[string]interface{}
+	UserAttributes  map[string]interface{}
+	AgentAttributes map[string]interface{}
+}
+
+// WantError is a traced error expectation.
+type WantError struct {
+	TxnName         string
+	Msg             string
+	Klass           string
+	UserAttributes  map[string]interface{}
+	AgentAttributes map[string]interface{}
+}
+
+// WantSlowQuery is a slow query expectation.
+type WantSlowQuery struct {
+	Count        int32
+	MetricName   string
+	Query        string
+	TxnName      string
+	TxnURL       string
+	DatabaseName string
+	Host         string
+	PortPathOrID string
+	Params       map[string]interface{}
+}
+
+// App is an Application that stores the data it records for inspection
+// instead of sending it to New Relic.
+type App struct {
+	*newrelic.Application
+}
+
+// NewApp creates an App.  The options are applied before the settings
+// required for testing, so they may not enable the application or change its
+// name or license.  All transactions are sampled so that span events are
+// recorded.
+func NewApp(options ...newrelic.ConfigOption) *App {
+	options = append(options,
+		func(cfg *newrelic.Config) {
+			// Prevent spawning app goroutines in tests.
+			if !cfg.ServerlessMode.Enabled {
+				cfg.Enabled = false
+			}
+		},
+		newrelic.ConfigAppName(AppName),
+		newrelic.ConfigLicense(testLicenseKey),
+	)
+	app, err := newrelic.NewApplication(options...)
+	if nil != err {
+		panic(err)
+	}
+	internal.HarvestTesting(app.Private, func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	})
+	return &App{Application: app}
+}
+
+func (app *App) expect() internal.Expect {
+	return app.Private.(internal.Expect)
+}
+
+// Reset discards all data recorded by the App so far.
+func (app *App) Reset() {
+	internal.HarvestTesting(app.Private, nil)
+}
+
+func convertMetrics(want []WantMetric) []internal.WantMetric {
+	out := make([]internal.WantMetric, len(want))
+	for i, w := range want {
+		out[i] = internal.WantMetric(w)
+	}
+	return out
+}
+
+func convertEvents(want []WantEvent) []internal.WantEvent {
+	out := make([]internal.WantEvent, len(want))
+	for i, w := range want {
+		out[i] = internal.WantEvent(w)
+	}
+	return out
+}
+
+// ExpectMetrics checks that exactly the given metrics were recorded.
+func (app *App) ExpectMetrics(t Validator, want []WantMetric) {
+	app.expect().ExpectMetrics(t, convertMetrics(want))
+}
+
+// ExpectMetricsPresent checks that the given metrics were recorded, ignoring
+// any other metrics.
+func (app *App) ExpectMetricsPresent(t Validator, want []WantMetric) {
+	app.expect().ExpectMetricsPresent(t, convertMetrics(want))
+}
+
+// ExpectTxnEvents checks the recorded transaction events.
+func (app *App) ExpectTxnEvents(t Validator, want []WantEvent) {
+	app.expect().ExpectTxnEvents(t, convertEvents(want))
+}
+
+// ExpectCustomEvents checks the recorded custom events.
+func (app *App) ExpectCustomEvents(t Validator, want []WantEvent) {
+	app.expect().ExpectCustomEvents(t, convertEvents(want))
+}
+
+// ExpectErrorEvents checks the recorded error events.
+func (app *App) ExpectErrorEvents(t Validator, want []WantEvent) {
+	app.expect().ExpectErrorEvents(t, convertEvents(want))
+}
+
+// ExpectSpanEvents checks the recorded span events.  Distributed tracing
+// must be enabled for span events to be recorded.
+func (app *App) ExpectSpanEvents(t Validator, want []WantEvent) {
+	app.expect().ExpectSpanEvents(t, convertEvents(want))
+}
+
+// ExpectErrors checks the recorded traced errors.
+func (app *App) ExpectErrors(t Validator, want []WantError) {
+	out := make([]internal.WantError, len(want))
+	for i, w := range want {
+		out[i] = internal.WantError(w)
+	}
+	app.expect().ExpectErrors(t, out)
+}
+
+// ExpectSlowQueries checks the recorded slow queries.
+func (app *App) ExpectSlowQueries(t Validator, want []WantSlowQuery) {
+	out := make([]internal.WantSlowQuery, len(want))
+	for i, w := range want {
+		out[i] = internal.WantSlowQuery(w)
+	}
+	app.expect().ExpectSlowQueries(t, out)
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelictest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+func TestTxnEventsAndMetrics(t *testing.T) {
+	app := NewApp(newrelic.ConfigDistributedTracerEnabled(false))
+	txn := app.StartTransaction("hello")
+	txn.AddAttribute("zip", "zap")
+	txn.End()
+
+	app.ExpectTxnEvents(t, []WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		UserAttributes: map[string]interface{}{
+			"zip": "zap",
+		},
+	}})
+	app.ExpectMetricsPresent(t, []WantMetric{
+		{Name: "OtherTransaction/Go/hello", Scope: "", Forced: true, Data: []float64{1}},
+	})
+}
+
+func TestCustomEvents(t *testing.T) {
+	app := NewApp()
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": 1})
+
+	app.ExpectCustomEvents(t, []WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "myEvent",
+			"timestamp": MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"zip": 1,
+		},
+	}})
+}
+
+func TestErrors(t *testing.T) {
+	app := NewApp(newrelic.ConfigDistributedTracerEnabled(false))
+	txn := app.StartTransaction("hello")
+	txn.NoticeError(errors.New("my msg"))
+	txn.End()
+
+	app.ExpectErrors(t, []WantError{{
+		TxnName: "OtherTransaction/Go/hello",
+		Msg:     "my msg",
+		Klass:   "*errors.errorString",
+	}})
+	app.ExpectErrorEvents(t, []WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"error.class":     "*errors.errorString",
+			"error.message":   "my msg",
+			"transactionName": "OtherTransaction/Go/hello",
+		},
+	}})
+}
+
+func TestSpanEvents(t *testing.T) {
+	app := NewApp(newrelic.ConfigDistributedTracerEnabled(true))
+	txn := app.StartTransaction("hello")
+	txn.StartSegment("mySegment").End()
+	txn.End()
+
+	app.ExpectSpanEvents(t, []WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "Custom/mySegment",
+				"parentId": MatchAnything,
+				"category": "generic",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+		},
+	})
+}
+
+func TestSlowQueries(t *testing.T) {
+	app := NewApp(func(cfg *newrelic.Config) {
+		cfg.DatastoreTracer.SlowQuery.Threshold = 0
+	})
+	txn := app.StartTransaction("hello")
+	s := newrelic.DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            newrelic.DatastorePostgres,
+		Collection:         "users",
+		Operation:          "INSERT",
+		ParameterizedQuery: "INSERT INTO users (name, age) VALUES ($1, $2)",
+	}
+	time.Sleep(time.Millisecond)
+	s.End()
+	txn.End()
+
+	app.ExpectSlowQueries(t, []WantSlowQuery{{
+		Count:        1,
+		MetricName:   "Datastore/statement/Postgres/users/INSERT",
+		Query:        "INSERT INTO users (name, age) VALUES ($1, $2)",
+		TxnName:      "OtherTransaction/Go/hello",
+		DatabaseName: "",
+		Host:         "",
+		PortPathOrID: "",
+	}})
+}
+
+func TestReset(t *testing.T) {
+	app := NewApp()
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": 1})
+	app.Reset()
+
+	app.ExpectCustomEvents(t, []WantEvent{})
+	app.ExpectMetrics(t, []WantMetric{})
+}
+
+type recordingValidator struct {
+	errors int
+}
+
+func (v *recordingValidator) Error(...interface{}) { v.errors++ }
+
+func TestExpectationFailure(t *testing.T) {
+	app := NewApp()
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"zip": 1})
+
+	v := &recordingValidator{}
+	app.ExpectCustomEvents(v, []WantEvent{})
+	if 0 == v.errors {
+		t.Error("expected a validation error")
+	}
+}
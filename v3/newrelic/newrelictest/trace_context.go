@@ -0,0 +1,320 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelictest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+	newrelic "github.com/rainforestpay/go-agent/v3/newrelic"
+)
+
+// TraceContextFields are the expectations of a TraceContextCase on the
+// fields of an outbound payload or on the intrinsics of an event.
+type TraceContextFields struct {
+	Exact      map[string]interface{} `json:"exact,omitempty"`
+	Expected   []string               `json:"expected,omitempty"`
+	Unexpected []string               `json:"unexpected,omitempty"`
+	NotEqual   map[string]interface{} `json:"notequal,omitempty"`
+	Vendors    []string               `json:"vendors,omitempty"`
+}
+
+// TraceContextCase is a W3C trace context conformance test, in the format of
+// the cross agent tests' distributed_tracing/trace_context.json file.
+type TraceContextCase struct {
+	TestName          string               `json:"test_name"`
+	TrustedAccountKey string               `json:"trusted_account_key"`
+	AccountID         string               `json:"account_id"`
+	WebTransaction    bool                 `json:"web_transaction"`
+	RaisesException   bool                 `json:"raises_exception"`
+	ForceSampledTrue  bool                 `json:"force_sampled_true"`
+	SpanEventsEnabled bool                 `json:"span_events_enabled"`
+	TxnEventsEnabled  bool                 `json:"transaction_events_enabled"`
+	TransportType     string               `json:"transport_type"`
+	InboundHeaders    []map[string]string  `json:"inbound_headers"`
+	OutboundPayloads  []TraceContextFields `json:"outbound_payloads,omitempty"`
+	ExpectedMetrics   [][2]interface{}     `json:"expected_metrics"`
+	Intrinsics        struct {
+		TargetEvents     []string            `json:"target_events"`
+		Common           *TraceContextFields `json:"common,omitempty"`
+		Transaction      *TraceContextFields `json:"Transaction,omitempty"`
+		Span             *TraceContextFields `json:"Span,omitempty"`
+		TransactionError *TraceContextFields `json:"TransactionError,omitempty"`
+	} `json:"intrinsics"`
+}
+
+// ParseTraceContextCases parses the contents of a trace_context.json file.
+func ParseTraceContextCases(data []byte) ([]TraceContextCase, error) {
+	var cases []TraceContextCase
+	if err := json.Unmarshal(data, &cases); nil != err {
+		return nil, err
+	}
+	return cases, nil
+}
+
+// RunTraceContextCases runs each case as a subtest of t, checking that the
+// headers, metrics, and events created by the agent conform to the W3C trace
+// context specification.  The options are applied to the App of each case
+// before the settings required by the case.
+//
+//	data, _ := os.ReadFile("cross_agent_tests/distributed_tracing/trace_context.json")
+//	cases, err := newrelictest.ParseTraceContextCases(data)
+//	if nil != err {
+//		t.Fatal(err)
+//	}
+//	newrelictest.RunTraceContextCases(t, cases)
+func RunTraceContextCases(t *testing.T, cases []TraceContextCase, options ...newrelic.ConfigOption) {
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.TestName, func(t *testing.T) {
+			RunTraceContextCase(t, tc, options...)
+		})
+	}
+}
+
+// RunTraceContextCase runs a single case, see RunTraceContextCases.
+func RunTraceContextCase(t *testing.T, tc TraceContextCase, options ...newrelic.ConfigOption) {
+	options = append(options, func(cfg *newrelic.Config) {
+		cfg.CrossApplicationTracer.Enabled = false
+		cfg.DistributedTracer.Enabled = true
+		cfg.SpanEvents.Enabled = tc.SpanEventsEnabled
+		cfg.TransactionEvents.Enabled = tc.TxnEventsEnabled
+	})
+	app := NewApp(options...)
+	internal.HarvestTesting(app.Private, func(reply *internal.ConnectReply) {
+		reply.AccountID = tc.AccountID
+		reply.AppID = "456"
+		reply.PrimaryAppID = "456"
+		reply.TrustedAccountKey = tc.TrustedAccountKey
+		reply.SetSampleEverything()
+	})
+
+	txn := app.StartTransaction("hello")
+	if tc.WebTransaction {
+		txn.SetWebRequestHTTP(nil)
+	}
+	if tc.RaisesException {
+		txn.NoticeError(errors.New("my error message"))
+	}
+
+	// If there are no inbound headers, accept an empty set of headers
+	// before the case's headers.
+	transport := traceContextTransport(tc.TransportType)
+	if nil == tc.InboundHeaders {
+		txn.AcceptDistributedTraceHeaders(transport, nil)
+	}
+	txn.AcceptDistributedTraceHeaders(transport, traceContextHeaders(tc.InboundHeaders))
+
+	for _, expect := range tc.OutboundPayloads {
+		hdrs := http.Header{}
+		txn.InsertDistributedTraceHeaders(hdrs)
+		expectTraceContextHeaders(t, expect, hdrs)
+	}
+
+	txn.End()
+
+	var wantMetrics []WantMetric
+	for _, metric := range tc.ExpectedMetrics {
+		if name, ok := metric[0].(string); ok {
+			wantMetrics = append(wantMetrics, WantMetric{Name: name})
+		}
+	}
+	app.ExpectMetricsPresent(t, wantMetrics)
+
+	// Intrinsics are matched exactly, so the fields not listed by the case
+	// are added here.
+	extraTxnFields := &TraceContextFields{Expected: []string{"name"}}
+	if tc.WebTransaction {
+		extraTxnFields.Expected = append(extraTxnFields.Expected, "nr.apdexPerfZone")
+	}
+	extraSpanFields := &TraceContextFields{
+		Expected: []string{"name", "transaction.name", "category", "nr.entryPoint"},
+	}
+	extraErrorFields := &TraceContextFields{
+		Expected: []string{
+			"parent.type", "parent.account", "parent.app",
+			"parent.transportType", "error.message", "transactionName",
+			"parent.transportDuration", "error.class", "spanId",
+		},
+	}
+
+	for _, target := range tc.Intrinsics.TargetEvents {
+		switch target {
+		case "Transaction":
+			app.ExpectTxnEvents(t, traceContextEvents(tc.Intrinsics.Common, tc.Intrinsics.Transaction, extraTxnFields))
+		case "Span":
+			app.ExpectSpanEvents(t, traceContextEvents(tc.Intrinsics.Common, tc.Intrinsics.Span, extraSpanFields))
+		case "TransactionError":
+			app.ExpectErrorEvents(t, traceContextEvents(tc.Intrinsics.Common, tc.Intrinsics.TransactionError, extraErrorFields))
+		}
+	}
+}
+
+// traceContextTransport returns the transport type of a case, or
+// TransportUnknown if the agent does not support it.
+func traceContextTransport(transport string) newrelic.TransportType {
+	switch tt := newrelic.TransportType(transport); tt {
+	case newrelic.TransportHTTP, newrelic.TransportHTTPS, newrelic.TransportKafka,
+		newrelic.TransportJMS, newrelic.TransportIronMQ, newrelic.TransportAMQP,
+		newrelic.TransportQueue, newrelic.TransportOther:
+		return tt
+	default:
+		return newrelic.TransportUnknown
+	}
+}
+
+func traceContextHeaders(hdrs []map[string]string) http.Header {
+	h := http.Header{}
+	for _, entry := range hdrs {
+		for k, v := range entry {
+			h.Add(k, v)
+		}
+	}
+	return h
+}
+
+func traceContextEvents(fields ...*TraceContextFields) []WantEvent {
+	intrinsics := map[string]interface{}{}
+	for _, f := range fields {
+		if nil == f {
+			continue
+		}
+		for k, v := range f.Exact {
+			intrinsics[k] = v
+		}
+		for _, k := range f.Expected {
+			intrinsics[k] = MatchAnything
+		}
+	}
+	return []WantEvent{{Intrinsics: intrinsics}}
+}
+
+// traceContextPayload flattens the traceparent, tracestate, and newrelic
+// headers into the keys used by the outbound payload expectations.
+func traceContextPayload(t *testing.T, hdrs http.Header) map[string]string {
+	p := make(map[string]string)
+
+	parent := hdrs.Get("traceparent")
+	parentFields := strings.Split(parent, "-")
+	if len(parentFields) != 4 {
+		t.Error("incorrect traceparent header created", parent)
+		return nil
+	}
+	p["traceparent.version"] = parentFields[0]
+	p["traceparent.trace_id"] = parentFields[1]
+	p["traceparent.parent_id"] = parentFields[2]
+	p["traceparent.trace_flags"] = parentFields[3]
+
+	state := hdrs.Get("tracestate")
+	if stateFields := strings.Split(state, "-"); len(stateFields) >= 9 {
+		p["tracestate.tenant_id"] = strings.Split(state, "@")[0]
+		p["tracestate.version"] = strings.Split(stateFields[0], "=")[1]
+		p["tracestate.parent_type"] = stateFields[1]
+		p["tracestate.parent_account_id"] = stateFields[2]
+		p["tracestate.parent_application_id"] = stateFields[3]
+		p["tracestate.span_id"] = stateFields[4]
+		p["tracestate.transaction_id"] = stateFields[5]
+		p["tracestate.sampled"] = stateFields[6]
+		p["tracestate.priority"] = stateFields[7]
+		p["tracestate.timestamp"] = stateFields[8]
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(hdrs.Get("newrelic"))
+	if nil != err {
+		t.Error("unable to decode newrelic header:", err)
+		return p
+	}
+	var nr struct {
+		Version [2]int `json:"v"`
+		Data    struct {
+			Type          string      `json:"ty"`
+			App           string      `json:"ap"`
+			Account       string      `json:"ac"`
+			TransactionID string      `json:"tx"`
+			ID            string      `json:"id"`
+			TraceID       string      `json:"tr"`
+			Priority      float32     `json:"pr"`
+			Sampled       bool        `json:"sa"`
+			Timestamp     json.Number `json:"ti"`
+		} `json:"d"`
+	}
+	if err := json.Unmarshal(decoded, &nr); nil != err {
+		t.Error("unable to unmarshal newrelic header:", err)
+		return p
+	}
+	p["newrelic.v"] = fmt.Sprintf("%v", nr.Version)
+	p["newrelic.d.ac"] = nr.Data.Account
+	p["newrelic.d.ap"] = nr.Data.App
+	p["newrelic.d.id"] = nr.Data.ID
+	p["newrelic.d.pr"] = fmt.Sprintf("%v", nr.Data.Priority)
+	p["newrelic.d.ti"] = nr.Data.Timestamp.String()
+	p["newrelic.d.tr"] = nr.Data.TraceID
+	p["newrelic.d.tx"] = nr.Data.TransactionID
+	p["newrelic.d.ty"] = nr.Data.Type
+	p["newrelic.d.sa"] = "0"
+	if nr.Data.Sampled {
+		p["newrelic.d.sa"] = "1"
+	}
+	return p
+}
+
+func expectTraceContextHeaders(t *testing.T, expect TraceContextFields, hdrs http.Header) {
+	p := traceContextPayload(t, hdrs)
+	if nil == p {
+		return
+	}
+
+	for k, v := range expect.Exact {
+		var want string
+		switch val := v.(type) {
+		case bool:
+			want = "0"
+			if val {
+				want = "1"
+			}
+		case string:
+			want = val
+		default:
+			want = fmt.Sprintf("%v", val)
+		}
+		if got := p[k]; got != want {
+			t.Errorf("outbound payload has wrong value for key %s, expected=%s, actual=%s", k, want, got)
+		}
+	}
+	for _, k := range expect.Expected {
+		if p[k] == "" {
+			t.Errorf("outbound payload missing key %s", k)
+		}
+	}
+	for _, k := range expect.Unexpected {
+		if got := p[k]; got != "" {
+			t.Errorf("outbound payload has unexpected key %s, value=%s", k, got)
+		}
+	}
+	for k, v := range expect.NotEqual {
+		if got := p[k]; got == fmt.Sprintf("%v", v) {
+			t.Errorf("outbound payload has equal value for key %s, value=%s", k, got)
+		}
+	}
+
+	// The tracestate header must hold the New Relic entry followed by
+	// exactly the expected vendors.
+	state := hdrs.Get("tracestate")
+	for _, v := range expect.Vendors {
+		if !strings.Contains(state, v) {
+			t.Errorf("outbound tracestate does not contain vendor %s, tracestate=%s", v, state)
+		}
+	}
+	if state != "" {
+		if n := strings.Count(state, "="); n != len(expect.Vendors)+1 {
+			t.Errorf("outbound tracestate has wrong number of vendors, tracestate=%s", state)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelictest
+
+import (
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal/crossagent"
+)
+
+func TestCrossAgentW3CTraceContext(t *testing.T) {
+	data, err := crossagent.ReadFile("distributed_tracing/trace_context.json")
+	if nil != err {
+		t.Fatal(err)
+	}
+	cases, err := ParseTraceContextCases(data)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	var run []TraceContextCase
+	for _, tc := range cases {
+		// These cases were broken by a change to the specification.
+		if tc.TestName == "spans_disabled_in_child" || tc.TestName == "spans_disabled_root" {
+			continue
+		}
+		run = append(run, tc)
+	}
+	if len(run) == 0 {
+		t.Fatal("no trace context cases")
+	}
+	RunTraceContextCases(t, run)
+}
+
+func TestParseTraceContextCasesInvalid(t *testing.T) {
+	if _, err := ParseTraceContextCases([]byte(`{"test_name":`)); nil == err {
+		t.Error("invalid json accepted")
+	}
+}
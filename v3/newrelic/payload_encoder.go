@@ -0,0 +1,57 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// PayloadEncoder compresses harvest payloads using an algorithm other than
+// gzip.  It is set using Config.Compression.Encoder.  Encode may be called
+// concurrently.
+type PayloadEncoder interface {
+	// ContentEncoding returns the value of the Content-Encoding header of
+	// encoded payloads, eg. "zstd".
+	ContentEncoding() string
+	// Encode returns the compressed payload.
+	Encode(payload []byte) ([]byte, error)
+}
+
+// negotiatePayloadEncoder returns the encoder if New Relic advertises support
+// for its content encoding, and nil otherwise.
+func negotiatePayloadEncoder(encoder PayloadEncoder, supported []string) PayloadEncoder {
+	if nil == encoder {
+		return nil
+	}
+	encoding := encoder.ContentEncoding()
+	for _, s := range supported {
+		if strings.EqualFold(s, encoding) {
+			return encoder
+		}
+	}
+	return nil
+}
+
+// encodePayload compresses the command's data using its encoder, or gzip if
+// it has none.  The content encoding used is returned.
+func encodePayload(cmd rpmCmd, gzipWriterPool *sync.Pool) (*bytes.Buffer, string, error) {
+	if nil == cmd.Encoder {
+		compressed, err := compress(cmd.Data, gzipWriterPool)
+		return compressed, "gzip", err
+	}
+	encoded, err := cmd.Encoder.Encode(cmd.Data)
+	if nil != err {
+		return nil, "", err
+	}
+	return bytes.NewBuffer(encoded), cmd.Encoder.ContentEncoding(), nil
+}
+
+func payloadEncoderSetting(e PayloadEncoder) interface{} {
+	if nil == e {
+		return nil
+	}
+	return e.ContentEncoding()
+}
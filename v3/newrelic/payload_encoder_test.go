@@ -0,0 +1,97 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+	"github.com/rainforestpay/go-agent/v3/internal/logger"
+)
+
+// reverseEncoder is a PayloadEncoder which reverses the payload.
+type reverseEncoder struct{ err error }
+
+func (e reverseEncoder) ContentEncoding() string { return "reverse" }
+
+func (e reverseEncoder) Encode(payload []byte) ([]byte, error) {
+	if nil != e.err {
+		return nil, e.err
+	}
+	encoded := make([]byte, len(payload))
+	for i, b := range payload {
+		encoded[len(payload)-1-i] = b
+	}
+	return encoded, nil
+}
+
+func TestNegotiatePayloadEncoder(t *testing.T) {
+	encoder := reverseEncoder{}
+	if e := negotiatePayloadEncoder(nil, []string{"reverse"}); nil != e {
+		t.Error(e)
+	}
+	if e := negotiatePayloadEncoder(encoder, nil); nil != e {
+		t.Error(e)
+	}
+	if e := negotiatePayloadEncoder(encoder, []string{"zstd"}); nil != e {
+		t.Error(e)
+	}
+	if e := negotiatePayloadEncoder(encoder, []string{"zstd", "Reverse"}); e != encoder {
+		t.Error(e)
+	}
+}
+
+func TestAppRunPayloadEncoder(t *testing.T) {
+	cfg := config{Config: defaultConfig()}
+	cfg.Compression.Encoder = reverseEncoder{}
+	reply := internal.ConnectReplyDefaults()
+	if run := newAppRun(cfg, reply); nil != run.payloadEncoder {
+		t.Error(run.payloadEncoder)
+	}
+	reply.ContentEncodings = []string{"reverse"}
+	if run := newAppRun(cfg, reply); nil == run.payloadEncoder {
+		t.Error("encoder not used")
+	}
+}
+
+func TestCollectorRequestPayloadEncoder(t *testing.T) {
+	cmd := rpmCmd{
+		Name:           cmdMetrics,
+		Collector:      "collector.com",
+		RunID:          "run_id",
+		Data:           []byte("abc"),
+		MaxPayloadSize: internal.MaxPayloadSizeInBytes,
+		Encoder:        reverseEncoder{},
+	}
+	cs := rpmControls{
+		License: "the_license",
+		Client: &http.Client{
+			Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				if h := r.Header.Get("Content-Encoding"); h != "reverse" {
+					t.Error(h)
+				}
+				if body, _ := ioutil.ReadAll(r.Body); string(body) != "cba" {
+					t.Error(string(body))
+				}
+				return &http.Response{
+					StatusCode: 200,
+					Body:       ioutil.NopCloser(strings.NewReader("body")),
+				}, nil
+			}),
+		},
+		Logger: logger.ShimLogger{},
+	}
+	if resp := collectorRequest(cmd, cs); nil != resp.Err {
+		t.Error(resp.Err)
+	}
+
+	cmd.Encoder = reverseEncoder{err: errors.New("oops")}
+	if resp := collectorRequest(cmd, cs); nil == resp.Err {
+		t.Error("expected encoding error")
+	}
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+// remoteConfigTimeout limits each request to the Config.RemoteConfig.URL.
+const remoteConfigTimeout = 5 * time.Second
+
+// remoteConfigReader reads the Config.RemoteConfig source.  It is only used
+// by the goroutine polling the source.
+type remoteConfigReader struct {
+	file   string
+	url    string
+	client *http.Client
+	// etag and body are those of the last successful response.
+	etag string
+	body []byte
+}
+
+func newRemoteConfigReader(c config) *remoteConfigReader {
+	return &remoteConfigReader{
+		file:   c.RemoteConfig.File,
+		url:    c.RemoteConfig.URL,
+		client: &http.Client{Timeout: remoteConfigTimeout},
+	}
+}
+
+// read returns the contents of the source, or nil if it does not exist.
+func (r *remoteConfigReader) read() ([]byte, error) {
+	if "" != r.file {
+		data, err := os.ReadFile(r.file)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return data, err
+	}
+
+	req, err := http.NewRequest("GET", r.url, nil)
+	if nil != err {
+		return nil, err
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("User-Agent", userAgentPrefix+Version)
+	if "" != r.etag {
+		req.Header.Add("If-None-Match", r.etag)
+	}
+	resp, err := r.client.Do(req)
+	if nil != err {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return r.body, nil
+	case resp.StatusCode == http.StatusNotFound:
+		r.etag, r.body = "", nil
+		return nil, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("remote config response code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if nil != err {
+		return nil, err
+	}
+	r.etag, r.body = resp.Header.Get("ETag"), body
+	return body, nil
+}
+
+// parseRemoteConfig parses the contents of the Config.RemoteConfig source.
+// Empty contents return nil settings, so that the application's settings are
+// used.
+func parseRemoteConfig(data []byte) (*internal.ServerSideConfig, error) {
+	if 0 == len(bytes.TrimSpace(data)) {
+		return nil, nil
+	}
+	ssc := &internal.ServerSideConfig{}
+	if err := json.Unmarshal(data, ssc); nil != err {
+		return nil, err
+	}
+	if nil != ssc.ErrorCollectorIgnoreMessages {
+		if err := validateIgnoreMessages(ssc.ErrorCollectorIgnoreMessages); nil != err {
+			return nil, err
+		}
+	}
+	return ssc, nil
+}
+
+// remoteConfigRoutine polls the Config.RemoteConfig source, sending the
+// settings to the processor goroutine each time its contents change.
+func (app *app) remoteConfigRoutine() {
+	reader := newRemoteConfigReader(app.config)
+	ticker := app.config.Clock.NewTicker(app.config.RemoteConfig.PollPeriod)
+	defer ticker.Stop()
+
+	var last []byte
+	var lastErr string
+	for {
+		data, err := reader.read()
+		var ssc *internal.ServerSideConfig
+		if nil == err && !bytes.Equal(data, last) {
+			ssc, err = parseRemoteConfig(data)
+		}
+		if nil != err {
+			// Failures are logged once, rather than at each poll,
+			// until they change.
+			if err.Error() != lastErr {
+				app.Warn("unable to read remote config", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			lastErr = err.Error()
+		} else if lastErr = ""; !bytes.Equal(data, last) {
+			last = data
+			select {
+			case app.remoteConfigChan <- ssc:
+			case <-app.shutdownStarted:
+				return
+			}
+		}
+
+		select {
+		case <-ticker.C():
+		case <-app.shutdownStarted:
+			return
+		}
+	}
+}
+
+// runConfig returns the config used to create each appRun: the application's
+// config with the settings last read from the Config.RemoteConfig source.
+func (app *app) runConfig() config {
+	app.RLock()
+	defer app.RUnlock()
+
+	c := app.config
+	c.remoteConfig = app.remoteConfig
+	return c
+}
+
+// applyRemoteConfig replaces the run with one using the settings read from
+// the Config.RemoteConfig source.  The run's sampler and transaction name
+// cache are kept.  It returns the run to use, which is nil if the application
+// is not connected.
+func (app *app) applyRemoteConfig(run *appRun, ssc *internal.ServerSideConfig) *appRun {
+	app.Lock()
+	app.remoteConfig = ssc
+	app.Unlock()
+
+	app.Info("remote config applied", map[string]interface{}{
+		"app":      app.config.AppName,
+		"reverted": nil == ssc,
+	})
+	if nil == run {
+		return nil
+	}
+	next := newAppRun(app.runConfig(), run.Reply)
+	next.adaptiveSampler = run.adaptiveSampler
+	next.rulesCache = run.rulesCache
+	next.harvestConfig = run.harvestConfig
+	app.setState(next, nil)
+	return next
+}
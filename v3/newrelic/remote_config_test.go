@@ -0,0 +1,145 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestParseRemoteConfig(t *testing.T) {
+	if ssc, err := parseRemoteConfig([]byte(" \n")); nil != ssc || nil != err {
+		t.Error(ssc, err)
+	}
+	ssc, err := parseRemoteConfig([]byte(`{"span_events.enabled":false,"unknown":1}`))
+	if nil != err || nil == ssc.SpanEventsEnabled || *ssc.SpanEventsEnabled {
+		t.Error(ssc, err)
+	}
+	if _, err := parseRemoteConfig([]byte(`{"span_events.enabled":`)); nil == err {
+		t.Error("invalid json accepted")
+	}
+	if _, err := parseRemoteConfig([]byte(`{"error_collector.ignore_messages":{"class":["("]}}`)); nil == err {
+		t.Error("invalid ignore messages accepted")
+	}
+}
+
+func TestRemoteConfigReaderFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	r := &remoteConfigReader{file: file}
+	if data, err := r.read(); nil != data || nil != err {
+		t.Error(string(data), err)
+	}
+	if err := os.WriteFile(file, []byte(`{"slow_sql.enabled":false}`), 0644); nil != err {
+		t.Fatal(err)
+	}
+	if data, err := r.read(); string(data) != `{"slow_sql.enabled":false}` || nil != err {
+		t.Error(string(data), err)
+	}
+}
+
+type remoteConfigTransport struct {
+	status int
+	etag   string
+	body   string
+	// ifNoneMatch is the header of the last request.
+	ifNoneMatch string
+}
+
+func (rt *remoteConfigTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	rt.ifNoneMatch = r.Header.Get("If-None-Match")
+	header := http.Header{}
+	if "" != rt.etag {
+		header.Set("ETag", rt.etag)
+	}
+	if "" != rt.etag && rt.ifNoneMatch == rt.etag {
+		return &http.Response{StatusCode: http.StatusNotModified, Header: header, Body: io.NopCloser(&bytes.Buffer{})}, nil
+	}
+	return &http.Response{StatusCode: rt.status, Header: header, Body: io.NopCloser(bytes.NewBufferString(rt.body))}, nil
+}
+
+func TestRemoteConfigReaderURL(t *testing.T) {
+	rt := &remoteConfigTransport{status: 200, etag: `"v1"`, body: `{"slow_sql.enabled":false}`}
+	r := &remoteConfigReader{url: "http://localhost:51200/config", client: &http.Client{Transport: rt}}
+	for i := 0; i < 2; i++ {
+		if data, err := r.read(); string(data) != rt.body || nil != err {
+			t.Error(i, string(data), err)
+		}
+	}
+	if rt.ifNoneMatch != `"v1"` {
+		t.Error(rt.ifNoneMatch)
+	}
+
+	rt.status, rt.etag = 404, ""
+	if data, err := r.read(); nil != data || nil != err {
+		t.Error(string(data), err)
+	}
+	rt.status = 500
+	if _, err := r.read(); nil == err {
+		t.Error("error response accepted")
+	}
+}
+
+func TestRemoteConfigOverridesServerSideConfig(t *testing.T) {
+	enabled := true
+	cfg := config{Config: defaultConfig()}
+	cfg.remoteConfig, _ = parseRemoteConfig([]byte(`{"span_events.enabled":false}`))
+	reply := internal.ConnectReplyDefaults()
+	reply.ServerSideConfig.SpanEventsEnabled = &enabled
+	reply.ServerSideConfig.SlowSQLEnabled = &enabled
+	run := newAppRun(cfg, reply)
+	if run.Config.SpanEvents.Enabled {
+		t.Error("span events should be disabled by the remote config")
+	}
+	if !run.Config.DatastoreTracer.SlowQuery.Enabled {
+		t.Error("slow queries should be enabled by the server-side config")
+	}
+}
+
+func TestApplyRemoteConfig(t *testing.T) {
+	app := testApp(nil, nil, t)
+	run, _ := app.app.getState()
+	if !run.Config.TransactionTracer.Enabled {
+		t.Fatal("transaction tracer should be enabled by default")
+	}
+	ssc, _ := parseRemoteConfig([]byte(`{"transaction_tracer.enabled":false}`))
+	next := app.app.applyRemoteConfig(run, ssc)
+	if current, _ := app.app.getState(); current != next || next.Config.TransactionTracer.Enabled {
+		t.Error("remote config not applied")
+	}
+	if next.adaptiveSampler != run.adaptiveSampler || next.rulesCache != run.rulesCache {
+		t.Error("run state not kept")
+	}
+	if c := app.app.runConfig(); c.remoteConfig != ssc {
+		t.Error("remote config not used for new runs")
+	}
+
+	reverted := app.app.applyRemoteConfig(next, nil)
+	if !reverted.Config.TransactionTracer.Enabled {
+		t.Error("remote config not reverted")
+	}
+	if nil != app.app.applyRemoteConfig(nil, ssc) {
+		t.Error("run created while disconnected")
+	}
+}
+
+func TestRemoteConfigValidation(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.License = testLicenseKey
+	cfg.AppName = "my app"
+	cfg.RemoteConfig.Enabled = true
+	if err := cfg.validate(); err != errRemoteConfigSource {
+		t.Error(err)
+	}
+	cfg.RemoteConfig.File = "/etc/newrelic/config.json"
+	cfg.RemoteConfig.PollPeriod = 0
+	if err := cfg.validate(); err != errRemoteConfigPeriod {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "strings"
+
+// RPCNameGrouping controls how RPCTransactionName groups remote procedure
+// calls into transaction names.
+type RPCNameGrouping int
+
+const (
+	// RPCGroupByMethod names transactions after the service and method, eg.
+	// "pkg.Service/Method".  This is the default.
+	RPCGroupByMethod RPCNameGrouping = iota
+	// RPCGroupByService names transactions after the service only, eg.
+	// "pkg.Service".  Use it to limit the number of transaction names
+	// created by services with many methods.
+	RPCGroupByService
+)
+
+// RPCTransactionName returns the transaction name for a remote procedure call
+// given its full method string, eg. "/pkg.Service/Method".  This is the format
+// used by gRPC, Connect, and Twirp, so integrations and handlers for each
+// framework name transactions consistently.
+func RPCTransactionName(fullMethod string, grouping RPCNameGrouping) string {
+	service, method := splitRPCFullMethod(fullMethod)
+	if RPCGroupByService == grouping || "" == method {
+		return service
+	}
+	return service + "/" + method
+}
+
+// splitRPCFullMethod splits a full method string into its service and method.
+// The method is empty if the string contains no method.
+func splitRPCFullMethod(fullMethod string) (service, method string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if idx := strings.LastIndex(fullMethod, "/"); idx >= 0 {
+		return fullMethod[:idx], fullMethod[idx+1:]
+	}
+	return fullMethod, ""
+}
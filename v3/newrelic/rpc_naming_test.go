@@ -0,0 +1,27 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "testing"
+
+func TestRPCTransactionName(t *testing.T) {
+	testcases := []struct {
+		fullMethod string
+		grouping   RPCNameGrouping
+		expect     string
+	}{
+		{fullMethod: "/pkg.Service/Method", grouping: RPCGroupByMethod, expect: "pkg.Service/Method"},
+		{fullMethod: "/pkg.Service/Method", grouping: RPCGroupByService, expect: "pkg.Service"},
+		{fullMethod: "pkg.Service/Method", grouping: RPCGroupByMethod, expect: "pkg.Service/Method"},
+		{fullMethod: "/twirp/pkg.Service/Method", grouping: RPCGroupByService, expect: "twirp/pkg.Service"},
+		{fullMethod: "/pkg.Service", grouping: RPCGroupByMethod, expect: "pkg.Service"},
+		{fullMethod: "", grouping: RPCGroupByMethod, expect: ""},
+	}
+	for _, tc := range testcases {
+		if name := RPCTransactionName(tc.fullMethod, tc.grouping); name != tc.expect {
+			t.Errorf("fullMethod=%q grouping=%d: expected %q, got %q",
+				tc.fullMethod, tc.grouping, tc.expect, name)
+		}
+	}
+}
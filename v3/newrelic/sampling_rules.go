@@ -0,0 +1,68 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// SamplingRule overrides the adaptive sampler for the transactions whose names
+// match, see Config.DistributedTracer.SamplingRules.
+type SamplingRule struct {
+	// Pattern is the regular expression matched against the name of the
+	// transaction, using the syntax of the regexp package.  This is the
+	// name given to StartTransaction or SetName, eg. "GET /checkout",
+	// rather than the full metric name.
+	Pattern string
+	// SampleRate is the fraction of matching transactions which are
+	// sampled, between 0 and 1.  A SampleRate of 1 samples every matching
+	// transaction and a SampleRate of 0 samples none of them.
+	SampleRate float64
+}
+
+// samplingRule is a compiled SamplingRule.
+type samplingRule struct {
+	re   *regexp.Regexp
+	rate float32
+}
+
+func validateSamplingRules(rules []SamplingRule) error {
+	for _, rule := range rules {
+		if _, err := regexp.Compile(rule.Pattern); nil != err {
+			return fmt.Errorf("invalid sampling rule %q: %v", rule.Pattern, err)
+		}
+		if rule.SampleRate < 0 || rule.SampleRate > 1 {
+			return errSamplingRuleRate
+		}
+	}
+	return nil
+}
+
+// newSamplingRules compiles the rules, which must have been validated using
+// validateSamplingRules.
+func newSamplingRules(rules []SamplingRule) []samplingRule {
+	var compiled []samplingRule
+	for _, rule := range rules {
+		compiled = append(compiled, samplingRule{
+			re:   regexp.MustCompile(rule.Pattern),
+			rate: float32(rule.SampleRate),
+		})
+	}
+	return compiled
+}
+
+// computeSampledByRule returns whether a transaction with the given name and
+// priority is sampled according to the first matching rule.  ok is false if
+// no rule matches, in which case the adaptive sampler decides.  The priority
+// is uniformly distributed between 0 and 1, so comparing it to the rate
+// samples the expected fraction of transactions.
+func computeSampledByRule(rules []samplingRule, name string, p priority) (sampled bool, ok bool) {
+	for _, rule := range rules {
+		if rule.re.MatchString(name) {
+			return p.Float32() < rule.rate, true
+		}
+	}
+	return false, false
+}
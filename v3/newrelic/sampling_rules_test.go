@@ -0,0 +1,117 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+var testSamplingRules = []SamplingRule{
+	{Pattern: `^POST /checkout$`, SampleRate: 1},
+	{Pattern: `/healthz`, SampleRate: 0.01},
+	{Pattern: `^GET /`, SampleRate: 0.5},
+}
+
+func TestValidateSamplingRules(t *testing.T) {
+	if err := validateSamplingRules(testSamplingRules); nil != err {
+		t.Error(err)
+	}
+	if err := validateSamplingRules([]SamplingRule{{Pattern: "("}}); nil == err {
+		t.Error("invalid pattern accepted")
+	}
+	if err := validateSamplingRules([]SamplingRule{{Pattern: "x", SampleRate: 1.5}}); err != errSamplingRuleRate {
+		t.Error(err)
+	}
+	if err := validateSamplingRules([]SamplingRule{{Pattern: "x", SampleRate: -0.1}}); err != errSamplingRuleRate {
+		t.Error(err)
+	}
+}
+
+func TestComputeSampledByRule(t *testing.T) {
+	rules := newSamplingRules(testSamplingRules)
+	testcases := []struct {
+		name     string
+		priority priority
+		sampled  bool
+		ok       bool
+	}{
+		{name: "POST /checkout", priority: 0.999, sampled: true, ok: true},
+		{name: "GET /healthz", priority: 0.005, sampled: true, ok: true},
+		{name: "GET /healthz", priority: 0.02, sampled: false, ok: true},
+		{name: "GET /users", priority: 0.4, sampled: true, ok: true},
+		{name: "GET /users", priority: 0.6, sampled: false, ok: true},
+		{name: "POST /users", priority: 0.1, sampled: false, ok: false},
+	}
+	for _, tc := range testcases {
+		sampled, ok := computeSampledByRule(rules, tc.name, tc.priority)
+		if sampled != tc.sampled || ok != tc.ok {
+			t.Error(tc.name, tc.priority, sampled, ok)
+		}
+	}
+	if sampled, ok := computeSampledByRule(nil, "POST /checkout", 0.5); sampled || ok {
+		t.Error(sampled, ok)
+	}
+}
+
+func TestSamplingRulesOverrideAdaptiveSampler(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.DistributedTracer.SamplingRules = []SamplingRule{
+			{Pattern: `^POST /checkout$`, SampleRate: 1},
+			{Pattern: `/healthz`, SampleRate: 0},
+		}
+	}
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleNothing()
+	}
+	app := testApp(replyfn, cfgfn, t)
+
+	txn := app.StartTransaction("POST /checkout")
+	if !txn.IsSampled() {
+		t.Error("matching transaction not sampled")
+	}
+	txn.End()
+
+	txn = app.StartTransaction("GET /users")
+	if txn.IsSampled() {
+		t.Error("adaptive sampler not used")
+	}
+	txn.End()
+
+	app = testApp(distributedTracingReplyFields, cfgfn, t)
+	txn = app.StartTransaction("GET /healthz")
+	if txn.IsSampled() {
+		t.Error("matching transaction sampled")
+	}
+	txn.End()
+
+	txn = app.StartTransaction("GET /users")
+	if !txn.IsSampled() {
+		t.Error("adaptive sampler not used")
+	}
+	txn.End()
+}
+
+func TestSamplingRulesInboundPayloadPrecedence(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.DistributedTracer.SamplingRules = []SamplingRule{
+			{Pattern: `/healthz`, SampleRate: 0},
+		}
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	hdrs := http.Header{}
+	app.StartTransaction("hello").InsertDistributedTraceHeaders(hdrs)
+
+	txn := app.StartTransaction("GET /healthz")
+	txn.AcceptDistributedTraceHeaders(TransportHTTP, hdrs)
+	if !txn.IsSampled() {
+		t.Error("inbound sampling decision not used")
+	}
+	txn.End()
+}
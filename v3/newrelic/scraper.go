@@ -0,0 +1,89 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"expvar"
+	"fmt"
+	runtimemetrics "runtime/metrics"
+	"strconv"
+	"strings"
+)
+
+// validateRuntimeMetrics returns an error if a Config.Scraper.RuntimeMetrics
+// name is unknown or is a histogram.
+func validateRuntimeMetrics(names []string) error {
+	if 0 == len(names) {
+		return nil
+	}
+	kinds := make(map[string]runtimemetrics.ValueKind)
+	for _, d := range runtimemetrics.All() {
+		kinds[d.Name] = d.Kind
+	}
+	for _, name := range names {
+		switch kinds[name] {
+		case runtimemetrics.KindUint64, runtimemetrics.KindFloat64:
+		case runtimemetrics.KindFloat64Histogram:
+			return fmt.Errorf("Scraper.RuntimeMetrics: histogram metric %q is not supported", name)
+		default:
+			return fmt.Errorf("Scraper.RuntimeMetrics: unknown metric %q", name)
+		}
+	}
+	return nil
+}
+
+// expvarValue returns the numeric value of the expvar variable.  A key of an
+// expvar.Map is named "{map}.{key}".
+func expvarValue(name string) (float64, bool) {
+	v := expvar.Get(name)
+	if nil == v {
+		idx := strings.Index(name, ".")
+		if idx < 0 {
+			return 0, false
+		}
+		m, ok := expvar.Get(name[:idx]).(*expvar.Map)
+		if !ok {
+			return 0, false
+		}
+		v = m.Get(name[idx+1:])
+		if nil == v {
+			return 0, false
+		}
+	}
+	switch val := v.(type) {
+	case *expvar.Int:
+		return float64(val.Value()), true
+	case *expvar.Float:
+		return val.Value(), true
+	}
+	f, err := strconv.ParseFloat(v.String(), 64)
+	return f, nil == err
+}
+
+// createScrapedMetrics records the Config.Scraper metrics.  Expvar variables
+// which are missing or not numeric are skipped.
+func createScrapedMetrics(cfg Config, metrics *metricTable) {
+	prefix := cfg.Scraper.Prefix
+	for _, name := range cfg.Scraper.Expvars {
+		if val, ok := expvarValue(name); ok {
+			metrics.addValue(prefix+"Expvar/"+name, "", val, unforced)
+		}
+	}
+	if 0 == len(cfg.Scraper.RuntimeMetrics) {
+		return
+	}
+	samples := make([]runtimemetrics.Sample, len(cfg.Scraper.RuntimeMetrics))
+	for i, name := range cfg.Scraper.RuntimeMetrics {
+		samples[i].Name = name
+	}
+	runtimemetrics.Read(samples)
+	for _, s := range samples {
+		switch s.Value.Kind() {
+		case runtimemetrics.KindUint64:
+			metrics.addValue(prefix+"Runtime"+s.Name, "", float64(s.Value.Uint64()), unforced)
+		case runtimemetrics.KindFloat64:
+			metrics.addValue(prefix+"Runtime"+s.Name, "", s.Value.Float64(), unforced)
+		}
+	}
+}
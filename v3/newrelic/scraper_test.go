@@ -0,0 +1,56 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestCreateScrapedMetrics(t *testing.T) {
+	expvar.NewInt("scraperTestInt").Set(7)
+	expvar.NewFloat("scraperTestFloat").Set(1.5)
+	expvar.NewMap("scraperTestMap").Add("hits", 3)
+	expvar.NewString("scraperTestString").Set("not a number")
+	expvar.Publish("scraperTestFunc", expvar.Func(func() interface{} { return 42 }))
+
+	cfg := defaultConfig()
+	cfg.Scraper.Expvars = []string{
+		"scraperTestInt",
+		"scraperTestFloat",
+		"scraperTestMap.hits",
+		"scraperTestFunc",
+		"scraperTestString",
+		"scraperTestMissing",
+		"scraperTestMap.missing",
+	}
+	cfg.Scraper.RuntimeMetrics = []string{"/sched/goroutines:goroutines"}
+	mt := newMetricTable(100, time.Now())
+	createScrapedMetrics(cfg, mt)
+	expectMetrics(t, mt, []internal.WantMetric{
+		{Name: "Custom/Expvar/scraperTestInt", Scope: "", Forced: false, Data: []float64{1, 7, 7, 7, 7, 49}},
+		{Name: "Custom/Expvar/scraperTestFloat", Scope: "", Forced: false, Data: []float64{1, 1.5, 1.5, 1.5, 1.5, 2.25}},
+		{Name: "Custom/Expvar/scraperTestMap.hits", Scope: "", Forced: false, Data: []float64{1, 3, 3, 3, 3, 9}},
+		{Name: "Custom/Expvar/scraperTestFunc", Scope: "", Forced: false, Data: []float64{1, 42, 42, 42, 42, 1764}},
+		{Name: "Custom/Runtime/sched/goroutines:goroutines", Scope: "", Forced: false, Data: nil},
+	})
+}
+
+func TestValidateRuntimeMetrics(t *testing.T) {
+	if err := validateRuntimeMetrics(nil); nil != err {
+		t.Error(err)
+	}
+	if err := validateRuntimeMetrics([]string{"/gc/heap/objects:objects", "/sched/goroutines:goroutines"}); nil != err {
+		t.Error(err)
+	}
+	if err := validateRuntimeMetrics([]string{"/no/such/metric:things"}); nil == err {
+		t.Error("unknown metric accepted")
+	}
+	if err := validateRuntimeMetrics([]string{"/sched/latencies:seconds"}); nil == err {
+		t.Error("histogram metric accepted")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"regexp"
+	"sync/atomic"
+)
+
+// ScrubbingRule masks the text matched by a regular expression, see
+// Config.Scrubbing.
+type ScrubbingRule struct {
+	// Name identifies the rule in the supportability metric
+	// "Supportability/Go/Scrubbing/<Name>", which counts the values it
+	// modified.
+	Name string
+	// Pattern is the regular expression matched, using the syntax of the
+	// regexp package.
+	Pattern string
+	// Replacement replaces each match of Pattern.  It may refer to
+	// submatches, eg. "$1", as described by regexp.Regexp.Expand.
+	Replacement string
+}
+
+// scrubber applies the Config.Scrubbing rules.  A nil scrubber leaves all
+// values unchanged.
+type scrubber struct {
+	rules []*scrubbingRule
+}
+
+type scrubbingRule struct {
+	name        string
+	re          *regexp.Regexp
+	replacement string
+	// count is the number of values modified since the last harvest, and
+	// is accessed atomically.
+	count int64
+}
+
+// scrubbingMetricPrefix prefixes the name of each rule's supportability
+// metric.
+const scrubbingMetricPrefix = "Supportability/Go/Scrubbing/"
+
+func validateScrubbingRules(rules []ScrubbingRule) error {
+	names := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if "" == rule.Name {
+			return errScrubbingRuleName
+		}
+		if _, ok := names[rule.Name]; ok {
+			return fmt.Errorf("duplicate scrubbing rule name %q", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+		if _, err := regexp.Compile(rule.Pattern); nil != err {
+			return fmt.Errorf("invalid scrubbing rule %q: %v", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// newScrubber compiles the rules, which must have been validated using
+// validateScrubbingRules.  It returns nil if there are no rules.
+func newScrubber(rules []ScrubbingRule) *scrubber {
+	if 0 == len(rules) {
+		return nil
+	}
+	s := &scrubber{}
+	for _, rule := range rules {
+		s.rules = append(s.rules, &scrubbingRule{
+			name:        rule.Name,
+			re:          regexp.MustCompile(rule.Pattern),
+			replacement: rule.Replacement,
+		})
+	}
+	return s
+}
+
+// scrub applies each rule to the value in order.
+func (s *scrubber) scrub(val string) string {
+	if nil == s {
+		return val
+	}
+	for _, rule := range s.rules {
+		if rule.re.MatchString(val) {
+			val = rule.re.ReplaceAllString(val, rule.replacement)
+			atomic.AddInt64(&rule.count, 1)
+		}
+	}
+	return val
+}
+
+// scrubAttributes applies the rules to the string values of the attributes.
+func (s *scrubber) scrubAttributes(m spanAttributeMap) {
+	if nil == s {
+		return
+	}
+	for key, val := range m {
+		if str, ok := val.(stringJSONWriter); ok {
+			if scrubbed := s.scrub(string(str)); scrubbed != string(str) {
+				m[key] = stringJSONWriter(scrubbed)
+			}
+		}
+	}
+}
+
+// dumpSupportabilityMetrics returns the number of values modified by each
+// rule since the last call, and resets the counts.
+func (s *scrubber) dumpSupportabilityMetrics() map[string]float64 {
+	if nil == s {
+		return nil
+	}
+	metrics := make(map[string]float64, len(s.rules))
+	for _, rule := range s.rules {
+		if count := atomic.SwapInt64(&rule.count, 0); count > 0 {
+			metrics[scrubbingMetricPrefix+rule.name] = float64(count)
+		}
+	}
+	return metrics
+}
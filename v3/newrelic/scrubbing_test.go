@@ -0,0 +1,145 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+var testScrubbingRules = []ScrubbingRule{
+	{Name: "card", Pattern: `\b\d{12}(\d{4})\b`, Replacement: "************$1"},
+	{Name: "token", Pattern: `token=[^&\s]+`, Replacement: "token=[REDACTED]"},
+}
+
+func TestValidateScrubbingRules(t *testing.T) {
+	if err := validateScrubbingRules(testScrubbingRules); nil != err {
+		t.Error(err)
+	}
+	if err := validateScrubbingRules([]ScrubbingRule{{Pattern: "x"}}); err != errScrubbingRuleName {
+		t.Error(err)
+	}
+	if err := validateScrubbingRules([]ScrubbingRule{{Name: "a", Pattern: "("}}); nil == err {
+		t.Error("invalid pattern accepted")
+	}
+	if err := validateScrubbingRules([]ScrubbingRule{{Name: "a"}, {Name: "a"}}); nil == err {
+		t.Error("duplicate name accepted")
+	}
+}
+
+func TestScrubber(t *testing.T) {
+	var nilScrubber *scrubber
+	if out := nilScrubber.scrub("4111111111111111"); out != "4111111111111111" {
+		t.Error(out)
+	}
+	if newScrubber(nil) != nil {
+		t.Error("scrubber created without rules")
+	}
+
+	s := newScrubber(testScrubbingRules)
+	if out := s.scrub("card 4111111111111111 token=abc"); out != "card ************1111 token=[REDACTED]" {
+		t.Error(out)
+	}
+	if out := s.scrub("card 5500000000000004"); out != "card ************0004" {
+		t.Error(out)
+	}
+	if out := s.scrub("nothing to see"); out != "nothing to see" {
+		t.Error(out)
+	}
+	metrics := s.dumpSupportabilityMetrics()
+	if len(metrics) != 2 ||
+		metrics["Supportability/Go/Scrubbing/card"] != 2 ||
+		metrics["Supportability/Go/Scrubbing/token"] != 1 {
+		t.Error(metrics)
+	}
+	if metrics := s.dumpSupportabilityMetrics(); len(metrics) != 0 {
+		t.Error(metrics)
+	}
+}
+
+func TestScrubbingTransaction(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.Scrubbing.Rules = testScrubbingRules
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	req, _ := http.NewRequest("GET", "http://example.com/cards/4111111111111111?token=abc", nil)
+	txn.SetWebRequestHTTP(req)
+	txn.NoticeError(errors.New("declined: 4111111111111111"))
+	seg := txn.StartSegment("charge")
+	seg.AddAttribute("card", "4111111111111111")
+	seg.End()
+	txn.End()
+
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName: "WebTransaction/Go/hello",
+		Msg:     "declined: ************1111",
+		Klass:   "*errors.errorString",
+	}})
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/hello",
+			"guid":             internal.MatchAnything,
+			"traceId":          internal.MatchAnything,
+			"priority":         internal.MatchAnything,
+			"sampled":          internal.MatchAnything,
+			"nr.apdexPerfZone": internal.MatchAnything,
+		},
+		AgentAttributes: map[string]interface{}{
+			"request.uri":          "http://example.com/cards/************1111",
+			"request.method":       "GET",
+			"request.headers.host": "example.com",
+		},
+	}})
+	app.ExpectSpanEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":     "Custom/charge",
+			"parentId": internal.MatchAnything,
+			"category": "generic",
+		},
+		UserAttributes: map[string]interface{}{
+			"card": "************1111",
+		},
+		AgentAttributes: map[string]interface{}{},
+	}, {
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/hello",
+			"transaction.name": "WebTransaction/Go/hello",
+			"category":         "generic",
+			"nr.entryPoint":    true,
+		},
+		UserAttributes: map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{
+			"error.class":          "*errors.errorString",
+			"error.message":        "declined: ************1111",
+			"request.uri":          "http://example.com/cards/************1111",
+			"request.method":       "GET",
+			"request.headers.host": "example.com",
+		},
+	}})
+	metrics := app.app.config.scrubber.dumpSupportabilityMetrics()
+	if metrics["Supportability/Go/Scrubbing/card"] != 3 {
+		t.Error(metrics)
+	}
+}
+
+func TestScrubbingLogEvents(t *testing.T) {
+	app := newTestApp(sampleEverythingReplyFn, configTestAppLogFn, func(cfg *Config) {
+		cfg.Scrubbing.Rules = testScrubbingRules
+	})
+	app.Application.RecordLog(LogData{
+		Severity:  "Info",
+		Message:   "charging 4111111111111111",
+		Timestamp: 123,
+	})
+	app.ExpectLogEvents(t, []internal.WantLog{{
+		Severity:  "Info",
+		Message:   "charging ************1111",
+		Timestamp: 123,
+	}})
+}
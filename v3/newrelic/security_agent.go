@@ -0,0 +1,49 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "net/http"
+
+// SecurityAgent is notified of the activity of transactions, eg. so that an
+// interactive application security testing (IAST) module can inspect the
+// requests handled and the calls made by the application.  Assign it to
+// Config.SecurityAgent.
+//
+// Its methods are called synchronously by the goroutines using the
+// transactions, after the agent has recorded the activity, and so must be
+// safe for concurrent use and should not block.  They may use the
+// Transaction, eg. to add attributes, but must not end it.
+type SecurityAgent interface {
+	// TransactionStarted is called when Application.StartTransaction
+	// starts a transaction.
+	TransactionStarted(txn *Transaction)
+	// InboundRequest is called when Transaction.SetWebRequest or
+	// Transaction.SetWebRequestHTTP marks the transaction as a web
+	// transaction, with the request's method, URL, and headers.
+	InboundRequest(txn *Transaction, r WebRequest)
+	// OutboundHeaders is called when the distributed tracing headers of
+	// an outbound request are created by StartExternalSegment,
+	// NewRoundTripper, or Transaction.InsertDistributedTraceHeaders.  The
+	// headers may be modified, eg. to add the security agent's own
+	// headers.
+	OutboundHeaders(txn *Transaction, hdrs http.Header)
+	// ExternalSegmentEnded is called when an ExternalSegment ends.
+	ExternalSegmentEnded(txn *Transaction, s *ExternalSegment)
+	// DatastoreSegmentEnded is called when a DatastoreSegment ends.  Its
+	// QueryParameters have been removed if they may not be recorded, eg.
+	// when HighSecurity is enabled.
+	DatastoreSegmentEnded(txn *Transaction, s *DatastoreSegment)
+	// TransactionEnded is called when Transaction.End ends the
+	// transaction.
+	TransactionEnded(txn *Transaction)
+}
+
+// securityAgent returns the Config.SecurityAgent of the thread's transaction,
+// or nil.
+func (thd *thread) securityAgent() SecurityAgent {
+	if nil == thd || nil == thd.txn {
+		return nil
+	}
+	return thd.Config.SecurityAgent
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+type securityAgentRecorder struct {
+	calls []string
+}
+
+func (r *securityAgentRecorder) TransactionStarted(txn *Transaction) {
+	r.calls = append(r.calls, "start")
+}
+
+func (r *securityAgentRecorder) InboundRequest(txn *Transaction, req WebRequest) {
+	r.calls = append(r.calls, "inbound "+req.Method+" "+req.URL.Path)
+}
+
+func (r *securityAgentRecorder) OutboundHeaders(txn *Transaction, hdrs http.Header) {
+	r.calls = append(r.calls, "outbound")
+	hdrs.Set("X-Security-Agent", "1")
+}
+
+func (r *securityAgentRecorder) ExternalSegmentEnded(txn *Transaction, s *ExternalSegment) {
+	r.calls = append(r.calls, "external "+s.Request.URL.Host)
+}
+
+func (r *securityAgentRecorder) DatastoreSegmentEnded(txn *Transaction, s *DatastoreSegment) {
+	// The transaction may be used.
+	txn.AddAttribute("query", s.ParameterizedQuery)
+	r.calls = append(r.calls, "datastore "+s.ParameterizedQuery)
+}
+
+func (r *securityAgentRecorder) TransactionEnded(txn *Transaction) {
+	r.calls = append(r.calls, "end")
+}
+
+func TestSecurityAgentHooks(t *testing.T) {
+	r := &securityAgentRecorder{}
+	app := testApp(distributedTracingReplyFields, func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.SecurityAgent = r
+	}, t)
+	txn := app.StartTransaction("hello")
+	req, _ := http.NewRequest("GET", "http://example.com/orders", nil)
+	txn.SetWebRequestHTTP(req)
+
+	outbound, _ := http.NewRequest("GET", "http://api.example.com/", nil)
+	ext := StartExternalSegment(txn, outbound)
+	ext.End()
+	if outbound.Header.Get("X-Security-Agent") != "1" || outbound.Header.Get(DistributedTraceNewRelicHeader) == "" {
+		t.Error(outbound.Header)
+	}
+
+	ds := &DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            DatastorePostgres,
+		ParameterizedQuery: "SELECT * FROM orders WHERE id = $1",
+	}
+	ds.End()
+	txn.End()
+	txn.End()
+
+	expect := []string{
+		"start",
+		"inbound GET /orders",
+		"outbound",
+		"external api.example.com",
+		"datastore SELECT * FROM orders WHERE id = $1",
+		"end",
+	}
+	if !reflect.DeepEqual(r.calls, expect) {
+		t.Error(r.calls)
+	}
+}
+
+func TestSecurityAgentTransactionEndedAfterPanic(t *testing.T) {
+	r := &securityAgentRecorder{}
+	app := testApp(nil, func(cfg *Config) {
+		cfg.ErrorCollector.RecordPanics = true
+		cfg.SecurityAgent = r
+	}, t)
+	func() {
+		defer func() {
+			if recovered := recover(); nil == recovered {
+				t.Error("panic not re-raised")
+			}
+		}()
+		txn := app.StartTransaction("hello")
+		defer txn.End()
+		panic(errors.New("oops"))
+	}()
+	if !reflect.DeepEqual(r.calls, []string{"start", "end"}) {
+		t.Error(r.calls)
+	}
+}
+
+func TestSecurityAgentUnset(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	ds := &DatastoreSegment{StartTime: txn.StartSegmentNow(), Product: DatastorePostgres}
+	ds.End()
+	txn.End()
+	var nilThread *thread
+	if sa := nilThread.securityAgent(); nil != sa {
+		t.Error(sa)
+	}
+}
@@ -4,6 +4,8 @@
 package newrelic
 
 import (
+	"context"
+	"net"
 	"net/http"
 )
 
@@ -45,12 +47,24 @@ type DatastoreSegment struct {
 	// one of the fields primarily responsible for the grouping of Datastore
 	// metrics.
 	Operation string
+	// PipelineOperations may be set when the segment measures a batch of
+	// commands sent together, such as a Redis pipeline or MULTI/EXEC
+	// transaction, rather than creating a segment for each command.  It
+	// contains the operation of each command in the batch.  The number of
+	// commands is recorded in the db.commandCount attribute, and the
+	// number of each operation is recorded in a
+	// Datastore/pipeline/{product}/{operation} metric.  Operation defaults
+	// to "pipeline" when PipelineOperations is set.
+	PipelineOperations []string
 
 	// The following fields are used for extra metrics and added to instance
 	// data:
 	//
 	// ParameterizedQuery may be set to the query being performed.  It must
-	// not contain any raw parameters, only placeholders.
+	// not contain any raw parameters, only placeholders.  If Operation or
+	// Collection is empty and ParameterizedQuery is a SQL statement, they
+	// are derived from it when the segment ends, eg. "select" and "users"
+	// from "SELECT * FROM users WHERE id = ?".
 	ParameterizedQuery string
 	// QueryParameters may be used to provide query parameters.  Care should
 	// be taken to only provide parameters which are not sensitive.
@@ -97,12 +111,74 @@ type ExternalSegment struct {
 	// external metrics and the "component" span attribute.  It should be
 	// the framework making the external call.
 	Library string
+	// Outcome is an optional field describing how the call ended from the
+	// point of view of the caller's retry and circuit breaker logic.  Its
+	// values are added to the segment as agent attributes so that
+	// dependency health can be queried from span data.
+	Outcome ExternalOutcome
 
 	// statusCode is the status code for the response.  This value takes
 	// precedence over the status code set on the Response.
 	statusCode *int
+	// group is the logical external call of a request made using
+	// NewRoundTripper, see roundTripGroup.
+	group *roundTripGroup
 }
 
+// ExternalOutcome is used for the ExternalSegment.Outcome field.  Zero
+// valued fields are not recorded.
+type ExternalOutcome struct {
+	// RetryCount is the number of times the call was retried before this
+	// attempt.  It is recorded as the "external.retryCount" attribute.
+	RetryCount int
+	// RedirectCount is the number of redirects followed within this
+	// attempt before this request.  It is recorded as the
+	// "external.redirectCount" attribute.
+	RedirectCount int
+	// CircuitState is the state of the circuit breaker guarding the
+	// dependency when the call was made.  It is recorded as the
+	// "external.circuitState" attribute.
+	CircuitState ExternalCircuitState
+	// Failure is the reason the call failed, if it did.  It is recorded
+	// as the "external.failure" attribute.
+	Failure ExternalFailure
+	// HedgeID identifies the logical request when the call is one of
+	// several duplicates sent for it, eg. by a hedged-request strategy.
+	// Every duplicate shares the HedgeID, which is recorded as the
+	// "external.hedge.id" attribute, so that they can be analyzed
+	// together rather than looking like doubled traffic.
+	HedgeID string
+	// HedgeWinner marks the duplicate whose response was used.  It is
+	// recorded as the "external.hedge.winner" attribute when HedgeID is
+	// set.
+	HedgeWinner bool
+}
+
+// ExternalCircuitState is used for the ExternalOutcome.CircuitState field.
+type ExternalCircuitState string
+
+// These circuit state constants are used for the
+// ExternalOutcome.CircuitState field.
+const (
+	ExternalCircuitClosed   ExternalCircuitState = "closed"
+	ExternalCircuitOpen     ExternalCircuitState = "open"
+	ExternalCircuitHalfOpen ExternalCircuitState = "half_open"
+)
+
+// ExternalFailure is used for the ExternalOutcome.Failure field.
+type ExternalFailure string
+
+// These failure constants are used for the ExternalOutcome.Failure field.
+// Use ExternalFailureCircuitOpen when the call was rejected by the circuit
+// breaker without being made.
+const (
+	ExternalFailureTimeout     ExternalFailure = "timeout"
+	ExternalFailureConnection  ExternalFailure = "connection"
+	ExternalFailureServerError ExternalFailure = "server_error"
+	ExternalFailureClientError ExternalFailure = "client_error"
+	ExternalFailureCircuitOpen ExternalFailure = "circuit_open"
+)
+
 // MessageProducerSegment instruments calls to add messages to a queueing system.
 type MessageProducerSegment struct {
 	StartTime SegmentStartTime
@@ -136,7 +212,8 @@ const (
 // AddAttribute adds a key value pair to the current segment.
 //
 // The key must contain fewer than than 255 bytes.  The value must be a
-// number, string, or boolean.
+// number, string, boolean, slice, or map as described in
+// Transaction.AddAttribute.
 func (s *Segment) AddAttribute(key string, val interface{}) {
 	if nil == s {
 		return
@@ -159,7 +236,8 @@ func (s *Segment) End() {
 // AddAttribute adds a key value pair to the current DatastoreSegment.
 //
 // The key must contain fewer than than 255 bytes.  The value must be a
-// number, string, or boolean.
+// number, string, boolean, slice, or map as described in
+// Transaction.AddAttribute.
 func (s *DatastoreSegment) AddAttribute(key string, val interface{}) {
 	if nil == s {
 		return
@@ -178,13 +256,38 @@ func (s *DatastoreSegment) End() {
 			"collection": s.Collection,
 			"operation":  s.Operation,
 		})
+	} else if sa := s.StartTime.thread.securityAgent(); nil != sa {
+		sa.DatastoreSegmentEnded(newTransaction(s.StartTime.thread), s)
 	}
 }
 
+// SetInstance sets the Host and PortPathOrID of the segment from the network
+// and address used to connect to the datastore, as given to net.Dial, eg.
+// ("tcp", "db.example.com:5432").  Unix socket addresses set the Host to
+// "localhost" and the PortPathOrID to the socket's path.  Addresses without a
+// port set only the Host.
+func (s *DatastoreSegment) SetInstance(network, address string) {
+	if nil == s {
+		return
+	}
+	s.Host, s.PortPathOrID = datastoreInstance(network, address)
+}
+
+// SetInstanceFromAddr is like SetInstance, using an address such as the
+// RemoteAddr of the connection to the datastore.  It has no effect if the
+// address is nil.
+func (s *DatastoreSegment) SetInstanceFromAddr(addr net.Addr) {
+	if nil == s || nil == addr {
+		return
+	}
+	s.SetInstance(addr.Network(), addr.String())
+}
+
 // AddAttribute adds a key value pair to the current ExternalSegment.
 //
 // The key must contain fewer than than 255 bytes.  The value must be a
-// number, string, or boolean.
+// number, string, boolean, slice, or map as described in
+// Transaction.AddAttribute.
 func (s *ExternalSegment) AddAttribute(key string, val interface{}) {
 	if nil == s {
 		return
@@ -207,13 +310,16 @@ func (s *ExternalSegment) End() {
 			extraDetails["request.url"] = safeURL(s.Request.URL)
 		}
 		s.StartTime.thread.logAPIError(err, "end external segment", extraDetails)
+	} else if sa := s.StartTime.thread.securityAgent(); nil != sa {
+		sa.ExternalSegmentEnded(newTransaction(s.StartTime.thread), s)
 	}
 }
 
 // AddAttribute adds a key value pair to the current MessageProducerSegment.
 //
 // The key must contain fewer than than 255 bytes.  The value must be a
-// number, string, or boolean.
+// number, string, boolean, slice, or map as described in
+// Transaction.AddAttribute.
 func (s *MessageProducerSegment) AddAttribute(key string, val interface{}) {
 	if nil == s {
 		return
@@ -234,6 +340,17 @@ func (s *MessageProducerSegment) End() {
 	}
 }
 
+// SetHedge marks the ExternalSegment as one of several duplicate requests
+// sent for the same logical request, see ExternalOutcome.HedgeID.  Call it
+// before End with winner set for the duplicate whose response was used.
+func (s *ExternalSegment) SetHedge(id string, winner bool) {
+	if nil == s {
+		return
+	}
+	s.Outcome.HedgeID = id
+	s.Outcome.HedgeWinner = winner
+}
+
 // SetStatusCode sets the status code for the response of this ExternalSegment.
 // This status code will be included as an attribute on Span Events.  If status
 // code is not set using this method, then the status code found on the
@@ -271,6 +388,26 @@ func StartSegment(txn *Transaction, name string) *Segment {
 	}
 }
 
+// StartExternalSegmentFromContext is like StartExternalSegment, but looks for
+// the Transaction in ctx using FromContext rather than requiring it as a
+// parameter.  If ctx has no Transaction, the request's context is used.  Use
+// it in library code which is given a context but not a Transaction:
+//
+//	func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+//		req, err := http.NewRequest("GET", url, nil)
+//		if err != nil {
+//			return nil, err
+//		}
+//		seg := newrelic.StartExternalSegmentFromContext(ctx, req)
+//		resp, err := c.http.Do(req)
+//		seg.Response = resp
+//		seg.End()
+//		return resp, err
+//	}
+func StartExternalSegmentFromContext(ctx context.Context, request *http.Request) *ExternalSegment {
+	return StartExternalSegment(FromContext(ctx), request)
+}
+
 // StartExternalSegment starts the instrumentation of an external call and adds
 // distributed tracing headers to the request.  If the Transaction parameter is
 // nil then StartExternalSegment will look for a Transaction in the request's
@@ -303,8 +440,9 @@ func addSpanAttr(start SegmentStartTime, key string, val interface{}) {
 	if nil == start.thread {
 		return
 	}
-	validatedVal, err := validateUserAttribute(key, val)
+	validatedVal, err := start.thread.Config.attributeLimits().validateUserAttribute(key, val)
 	if nil != err {
+		start.thread.Config.attributeDropped(key, "span", err)
 		start.thread.logAPIError(err, "add segment attribute", map[string]interface{}{})
 		return
 	}
@@ -22,7 +22,7 @@ func serverlessGetenvShim(s string) string {
 func TestServerlessHarvest(t *testing.T) {
 	// Test the expected ServerlessHarvest use.
 	sh := newServerlessHarvest(logger.ShimLogger{}, serverlessGetenvShim)
-	event, err := createCustomEvent("myEvent", nil, time.Now())
+	event, err := createCustomEvent("myEvent", nil, time.Now(), defaultAttributeLimits)
 	if nil != err {
 		t.Fatal(err)
 	}
@@ -70,7 +70,7 @@ func TestServerlessHarvestNil(t *testing.T) {
 	// The public ServerlessHarvest methods should not panic if the
 	// receiver is nil.
 	var sh *serverlessHarvest
-	event, err := createCustomEvent("myEvent", nil, time.Now())
+	event, err := createCustomEvent("myEvent", nil, time.Now(), defaultAttributeLimits)
 	if nil != err {
 		t.Fatal(err)
 	}
@@ -94,7 +94,7 @@ func BenchmarkServerless(b *testing.B) {
 	// The JSON creation in ServerlessHarvest.Write has not been optimized.
 	// This benchmark would be useful for doing so.
 	sh := newServerlessHarvest(logger.ShimLogger{}, serverlessGetenvShim)
-	event, err := createCustomEvent("myEvent", nil, time.Now())
+	event, err := createCustomEvent("myEvent", nil, time.Now(), defaultAttributeLimits)
 	if nil != err {
 		b.Fatal(err)
 	}
@@ -14,7 +14,10 @@ import (
 
 type queryParameters map[string]interface{}
 
-func vetQueryParameters(params map[string]interface{}) (queryParameters, error) {
+// vetQueryParameters returns the valid query parameters, and the error of
+// the last invalid parameter.  If dropped is not nil, it is called with each
+// invalid parameter.
+func vetQueryParameters(params map[string]interface{}, dropped func(key string, err error)) (queryParameters, error) {
 	if nil == params {
 		return nil, nil
 	}
@@ -26,6 +29,9 @@ func vetQueryParameters(params map[string]interface{}) (queryParameters, error)
 		val, err := validateUserAttribute(key, val)
 		if nil != err {
 			retErr = err
+			if nil != dropped {
+				dropped(key, err)
+			}
 			continue
 		}
 		vetted[key] = val
@@ -72,12 +78,35 @@ type slowQuery struct {
 	// When Count > 1, slowQueryInstance contains values from the slowest
 	// observation.
 	slowQueryInstance
+
+	// key is the aggregation key, see slowQueries.key.
+	key string
+	// examples are transactions which made the query, recorded when
+	// aggregating by instance.
+	examples []slowQueryExample
+}
+
+// slowQueryExample identifies a transaction which made a slow query.
+type slowQueryExample struct {
+	TxnName string
+	GUID    string
 }
 
 type slowQueries struct {
 	priorityQueue []*slowQuery
-	// lookup maps query strings to indices in the priorityQueue
+	// lookup maps aggregation keys to indices in the priorityQueue
 	lookup map[string]int
+	// byInstance aggregates by query and datastore instance, see
+	// Config.DatastoreTracer.SlowQuery.AggregateByInstance.
+	byInstance bool
+}
+
+// key returns the aggregation key of the slow query.
+func (slows *slowQueries) key(slow *slowQuery) string {
+	if !slows.byInstance {
+		return slow.ParameterizedQuery
+	}
+	return slow.ParameterizedQuery + "\x00" + slow.Host + "\x00" + slow.PortPathOrID
 }
 
 func (slows *slowQueries) Len() int {
@@ -92,8 +121,8 @@ func (slows *slowQueries) Swap(i, j int) {
 	si := pq[i]
 	sj := pq[j]
 	pq[i], pq[j] = pq[j], pq[i]
-	slows.lookup[si.ParameterizedQuery] = j
-	slows.lookup[sj.ParameterizedQuery] = i
+	slows.lookup[si.key] = j
+	slows.lookup[sj.key] = i
 }
 
 // Push and Pop are unused: only heap.Init and heap.Fix are used.
@@ -107,19 +136,42 @@ func newSlowQueries(max int) *slowQueries {
 	}
 }
 
+// newHarvestSlowQueries returns the slow queries of a harvest.
+func newHarvestSlowQueries(byInstance bool) *slowQueries {
+	slows := newSlowQueries(maxHarvestSlowSQLs)
+	slows.byInstance = byInstance
+	return slows
+}
+
 // Merge is used to merge slow queries from the transaction into the harvest.
 func (slows *slowQueries) Merge(other *slowQueries, txnEvent txnEvent) {
 	for _, s := range other.priorityQueue {
 		cp := *s
 		cp.txnEvent = txnEvent
+		if slows.byInstance {
+			cp.examples = []slowQueryExample{{
+				TxnName: txnEvent.FinalName,
+				GUID:    txnEvent.BetterCAT.TxnID,
+			}}
+		}
 		slows.observe(cp)
 	}
 }
 
-// merge aggregates the observations from two slow queries with the same Query.
+// merge aggregates the observations from two slow queries with the same key.
 func (slow *slowQuery) merge(other slowQuery) {
 	slow.Count += other.Count
 	slow.Total += other.Total
+	examples := slow.examples
+	for _, e := range other.examples {
+		if len(examples) >= maxSlowQueryExamples {
+			break
+		}
+		if !hasSlowQueryExample(examples, e.TxnName) {
+			examples = append(examples, e)
+		}
+	}
+	slow.examples = examples
 
 	if other.Min < slow.Min {
 		slow.Min = other.Min
@@ -129,6 +181,15 @@ func (slow *slowQuery) merge(other slowQuery) {
 	}
 }
 
+func hasSlowQueryExample(examples []slowQueryExample, txnName string) bool {
+	for _, e := range examples {
+		if e.TxnName == txnName {
+			return true
+		}
+	}
+	return false
+}
+
 func (slows *slowQueries) observeInstance(slow slowQueryInstance) {
 	slows.observe(slowQuery{
 		Count:             1,
@@ -142,13 +203,14 @@ func (slows *slowQueries) insertAtIndex(slow slowQuery, idx int) {
 	cpy := new(slowQuery)
 	*cpy = slow
 	slows.priorityQueue[idx] = cpy
-	slows.lookup[slow.ParameterizedQuery] = idx
+	slows.lookup[slow.key] = idx
 	heap.Fix(slows, idx)
 }
 
 func (slows *slowQueries) observe(slow slowQuery) {
+	slow.key = slows.key(&slow)
 	// Has the query has previously been observed?
-	if idx, ok := slows.lookup[slow.ParameterizedQuery]; ok {
+	if idx, ok := slows.lookup[slow.key]; ok {
 		slows.priorityQueue[idx].merge(slow)
 		heap.Fix(slows, idx)
 		return
@@ -163,7 +225,7 @@ func (slows *slowQueries) observe(slow slowQuery) {
 	// Is this query slower than the existing fastest?
 	fastest := slows.priorityQueue[0]
 	if slow.Duration > fastest.Duration {
-		delete(slows.lookup, fastest.ParameterizedQuery)
+		delete(slows.lookup, fastest.key)
 		slows.insertAtIndex(slow, 0)
 		return
 	}
@@ -172,6 +234,8 @@ func (slows *slowQueries) observe(slow slowQuery) {
 // The third element of the slow query JSON should be a hash of the query
 // string.  This hash may be used by backend services to aggregate queries which
 // have the have the same query string.  It is unknown if this actually used.
+// When aggregating by instance the aggregation key is hashed instead, so that
+// the instances of a query are not combined.
 func makeSlowQueryID(query string) uint32 {
 	h := fnv.New32a()
 	h.Write([]byte(query))
@@ -189,7 +253,7 @@ func (slow *slowQuery) WriteJSON(buf *bytes.Buffer) {
 	uri, _ := slow.txnEvent.Attrs.GetAgentValue(AttributeRequestURI, destAll)
 	jsonx.AppendString(buf, uri)
 	buf.WriteByte(',')
-	jsonx.AppendInt(buf, int64(makeSlowQueryID(slow.ParameterizedQuery)))
+	jsonx.AppendInt(buf, int64(makeSlowQueryID(slow.key)))
 	buf.WriteByte(',')
 	jsonx.AppendString(buf, slow.ParameterizedQuery)
 	buf.WriteByte(',')
@@ -220,6 +284,9 @@ func (slow *slowQuery) WriteJSON(buf *bytes.Buffer) {
 	if nil != slow.QueryParameters {
 		w.writerField("query_parameters", slow.QueryParameters)
 	}
+	if len(slow.examples) > 0 {
+		w.writerField("example_transactions", slowQueryExamples(slow.examples))
+	}
 
 	sharedBetterCATIntrinsics(&slow.txnEvent, &w)
 
@@ -227,6 +294,25 @@ func (slow *slowQuery) WriteJSON(buf *bytes.Buffer) {
 	buf.WriteByte(']')
 }
 
+type slowQueryExamples []slowQueryExample
+
+func (examples slowQueryExamples) WriteJSON(buf *bytes.Buffer) {
+	buf.WriteByte('[')
+	for i, e := range examples {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		w := jsonFieldsWriter{buf: buf}
+		w.stringField("name", e.TxnName)
+		if "" != e.GUID {
+			w.stringField("guid", e.GUID)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
+}
+
 // WriteJSON marshals the collection of slow queries into JSON according to the
 // schema expected by the collector.
 //
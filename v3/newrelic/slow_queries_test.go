@@ -37,7 +37,7 @@ func TestSlowQueriesBasic(t *testing.T) {
 		strings.Repeat("X", attributeKeyLengthLimit+1): "invalid-key",
 		"invalid-value": struct{}{},
 		"valid":         123,
-	})
+	}, nil)
 	if nil == err {
 		t.Error("expected error")
 	}
@@ -102,7 +102,7 @@ func TestSlowQueriesExcludeURI(t *testing.T) {
 		strings.Repeat("X", attributeKeyLengthLimit+1): "invalid-key",
 		"invalid-value": struct{}{},
 		"valid":         123,
-	})
+	}, nil)
 	if nil == err {
 		t.Error("expected error")
 	}
@@ -203,6 +203,66 @@ func TestSlowQueriesAggregation(t *testing.T) {
 	}
 }
 
+func TestSlowQueriesAggregateByInstance(t *testing.T) {
+	harvestSlows := newHarvestSlowQueries(true)
+	observe := func(txnName, guid, host string, d time.Duration) {
+		txnSlows := newSlowQueries(maxTxnSlowQueries)
+		txnSlows.byInstance = true
+		txnSlows.observeInstance(slowQueryInstance{
+			Duration:           d,
+			DatastoreMetric:    "Datastore/statement/Postgres/users/SELECT",
+			ParameterizedQuery: "SELECT * FROM users",
+			Host:               host,
+			PortPathOrID:       "5432",
+		})
+		harvestSlows.Merge(txnSlows, txnEvent{
+			FinalName: txnName,
+			BetterCAT: betterCAT{TxnID: guid},
+		})
+	}
+	for i := 0; i < 7; i++ {
+		str := strconv.Itoa(i)
+		observe("WebTransaction/Go/endpoint"+str, "guid"+str, "db-1", 600*time.Millisecond)
+	}
+	// The same transaction is recorded as an example only once.
+	observe("WebTransaction/Go/endpoint0", "guid7", "db-1", 700*time.Millisecond)
+	observe("WebTransaction/Go/endpoint0", "guid8", "db-2", 500*time.Millisecond)
+
+	js, err := harvestSlows.Data("agentRunID", time.Now())
+	expect := compactJSONString(`[[
+	[
+		"WebTransaction/Go/endpoint0","",2636613162,"SELECT * FROM users",
+		"Datastore/statement/Postgres/users/SELECT",1,500,500,500,
+		{
+			"host":"db-2",
+			"port_path_or_id":"5432",
+			"example_transactions":[{"name":"WebTransaction/Go/endpoint0","guid":"guid8"}]
+		}
+	],
+	[
+		"WebTransaction/Go/endpoint0","",2279461189,"SELECT * FROM users",
+		"Datastore/statement/Postgres/users/SELECT",8,4900,600,700,
+		{
+			"host":"db-1",
+			"port_path_or_id":"5432",
+			"example_transactions":[
+				{"name":"WebTransaction/Go/endpoint0","guid":"guid0"},
+				{"name":"WebTransaction/Go/endpoint1","guid":"guid1"},
+				{"name":"WebTransaction/Go/endpoint2","guid":"guid2"},
+				{"name":"WebTransaction/Go/endpoint3","guid":"guid3"},
+				{"name":"WebTransaction/Go/endpoint4","guid":"guid4"}
+			]
+		}
+	]
+]]`)
+	if nil != err {
+		t.Error(err)
+	}
+	if string(js) != expect {
+		t.Error(string(js), expect)
+	}
+}
+
 func TestSlowQueriesBetterCAT(t *testing.T) {
 	acfg := createAttributeConfig(config{Config: defaultConfig()}, true)
 	attr := newAttributes(acfg)
@@ -236,7 +296,7 @@ func TestSlowQueriesBetterCAT(t *testing.T) {
 		strings.Repeat("X", attributeKeyLengthLimit+1): "invalid-key",
 		"invalid-value": struct{}{},
 		"valid":         123,
-	})
+	}, nil)
 	if nil == err {
 		t.Error("expected error")
 	}
@@ -0,0 +1,83 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "bytes"
+
+// spanAttributeOverflowValue replaces the values of custom span attributes
+// which have exceeded Config.SpanEvents.MaxAttributeValues.
+const spanAttributeOverflowValue = "[OVERFLOW]"
+
+// spanAttributeGuard tracks the distinct values of each custom span attribute
+// in a harvest, see Config.SpanEvents.MaxAttributeValues.  A nil
+// spanAttributeGuard does nothing.
+type spanAttributeGuard struct {
+	maxValues int
+	values    map[string]map[string]struct{}
+	buf       bytes.Buffer
+}
+
+// newSpanAttributeGuard returns nil if maxValues is not positive.
+func newSpanAttributeGuard(maxValues int) *spanAttributeGuard {
+	if maxValues <= 0 {
+		return nil
+	}
+	return &spanAttributeGuard{
+		maxValues: maxValues,
+		values:    make(map[string]map[string]struct{}),
+	}
+}
+
+func (g *spanAttributeGuard) reset() {
+	if nil == g {
+		return
+	}
+	g.values = make(map[string]map[string]struct{})
+}
+
+// allowed returns true if the value is one of the first maxValues distinct
+// values of the key.
+func (g *spanAttributeGuard) allowed(key string, val jsonWriter) bool {
+	g.buf.Reset()
+	val.WriteJSON(&g.buf)
+	seen, ok := g.values[key]
+	if !ok {
+		seen = make(map[string]struct{})
+		g.values[key] = seen
+	}
+	if _, ok := seen[g.buf.String()]; ok {
+		return true
+	}
+	if len(seen) >= g.maxValues {
+		return false
+	}
+	seen[g.buf.String()] = struct{}{}
+	return true
+}
+
+// apply replaces the custom attribute values of the span events which are
+// over the limit, and counts them in the overflow metric.
+func (g *spanAttributeGuard) apply(events []*spanEvent, metrics *metricTable) {
+	if nil == g {
+		return
+	}
+	for _, evt := range events {
+		var overflowed []string
+		for key, val := range evt.UserAttributes {
+			if !g.allowed(key, val) {
+				overflowed = append(overflowed, key)
+			}
+		}
+		if 0 == len(overflowed) {
+			continue
+		}
+		// The attributes may be shared with the transaction trace, so
+		// they are copied before being changed.
+		evt.UserAttributes = evt.UserAttributes.copy()
+		for _, key := range overflowed {
+			evt.UserAttributes.addString(key, spanAttributeOverflowValue)
+			metrics.addSingleCount(spanAttributeOverflow, forced)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestSpanAttributeGuardNil(t *testing.T) {
+	var g *spanAttributeGuard
+	if g = newSpanAttributeGuard(0); nil != g {
+		t.Fatal(g)
+	}
+	g.reset()
+	events := []*spanEvent{{UserAttributes: spanAttributeMap{"id": stringJSONWriter("1")}}}
+	g.apply(events, newMetricTable(100, time.Now()))
+	if events[0].UserAttributes["id"] != stringJSONWriter("1") {
+		t.Error(events[0].UserAttributes)
+	}
+}
+
+func TestSpanAttributeGuard(t *testing.T) {
+	g := newSpanAttributeGuard(2)
+	metrics := newMetricTable(100, time.Now())
+	shared := spanAttributeMap{"id": intJSONWriter(3), "ok": boolJSONWriter(true)}
+	events := []*spanEvent{
+		{UserAttributes: spanAttributeMap{"id": intJSONWriter(1), "ok": boolJSONWriter(true)}},
+		{UserAttributes: spanAttributeMap{"id": intJSONWriter(2), "ok": boolJSONWriter(true)}},
+		{UserAttributes: shared},
+		{UserAttributes: spanAttributeMap{"id": intJSONWriter(1)}},
+	}
+	g.apply(events, metrics)
+
+	expect := []spanAttributeMap{
+		{"id": intJSONWriter(1), "ok": boolJSONWriter(true)},
+		{"id": intJSONWriter(2), "ok": boolJSONWriter(true)},
+		{"id": stringJSONWriter(spanAttributeOverflowValue), "ok": boolJSONWriter(true)},
+		{"id": intJSONWriter(1)},
+	}
+	for i, evt := range events {
+		if len(evt.UserAttributes) != len(expect[i]) {
+			t.Fatal(i, evt.UserAttributes)
+		}
+		for key, val := range expect[i] {
+			if evt.UserAttributes[key] != val {
+				t.Error(i, key, evt.UserAttributes[key])
+			}
+		}
+	}
+	if shared["id"] != intJSONWriter(3) {
+		t.Error("shared attributes changed", shared)
+	}
+	expectMetrics(t, metrics, []internal.WantMetric{
+		{Name: "Supportability/SpanEvent/UserAttributes/Overflow", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+
+	g.reset()
+	events = []*spanEvent{{UserAttributes: spanAttributeMap{"id": intJSONWriter(3)}}}
+	g.apply(events, metrics)
+	if events[0].UserAttributes["id"] != intJSONWriter(3) {
+		t.Error(events[0].UserAttributes)
+	}
+}
+
+func TestSpanEventsMaxAttributeValues(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.SpanEvents.MaxAttributeValues = 1
+	}, t)
+	for _, id := range []string{"a", "b"} {
+		txn := app.StartTransaction("hello")
+		txn.AddAttribute("user", id)
+		txn.End()
+	}
+	app.ExpectSpanEvents(t, []internal.WantEvent{{
+		UserAttributes: map[string]interface{}{"user": "a"},
+	}, {
+		UserAttributes: map[string]interface{}{"user": "[OVERFLOW]"},
+	}})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Supportability/SpanEvent/UserAttributes/Overflow", Scope: "", Forced: true, Data: nil},
+	})
+}
@@ -5,6 +5,7 @@ package newrelic
 
 import (
 	"bytes"
+	"reflect"
 	"time"
 )
 
@@ -39,6 +40,56 @@ type spanEvent struct {
 	TracingVendors  string
 	AgentAttributes spanAttributeMap
 	UserAttributes  spanAttributeMap
+
+	// referenced is true if the GUID of the span may have been used by
+	// other spans, errors, or outbound payloads, in which case the span
+	// must not be compressed.
+	referenced bool
+	// compressedCount and compressedDuration are the number and total
+	// duration of the spans merged into this span by span compression.
+	compressedCount    int
+	compressedDuration time.Duration
+}
+
+// compressible returns true if the span may be merged with other spans by span
+// compression.  Spans which have already been merged are compressible since
+// each of the spans merged was.
+func (e *spanEvent) compressible(maxDuration time.Duration) bool {
+	return !e.referenced && (e.compressedCount > 0 || e.Duration <= maxDuration)
+}
+
+// mergeable returns true if the span other is identical to e and may be merged
+// into it by span compression.
+func (e *spanEvent) mergeable(other *spanEvent) bool {
+	return e.ParentID == other.ParentID &&
+		e.Name == other.Name &&
+		e.Category == other.Category &&
+		e.Component == other.Component &&
+		e.Kind == other.Kind &&
+		reflect.DeepEqual(e.AgentAttributes, other.AgentAttributes) &&
+		reflect.DeepEqual(e.UserAttributes, other.UserAttributes)
+}
+
+// merge merges the span other, which ended after e, into e.
+func (e *spanEvent) merge(other *spanEvent) {
+	if 0 == e.compressedCount {
+		e.compressedCount = 1
+		e.compressedDuration = e.Duration
+	}
+	e.compressedCount++
+	e.compressedDuration += other.Duration
+	if end := other.Timestamp.Add(other.Duration); end.After(e.Timestamp) {
+		e.Duration = end.Sub(e.Timestamp)
+	}
+}
+
+// addCompressionAttributes records the number and total duration of the spans
+// merged into e, once no more spans will be merged.
+func (e *spanEvent) addCompressionAttributes() {
+	if e.compressedCount > 0 {
+		e.AgentAttributes.addInt("nr.compressed.count", e.compressedCount)
+		e.AgentAttributes.addFloat("nr.compressed.duration", e.compressedDuration.Seconds())
+	}
 }
 
 // WriteJSON prepares JSON in the format expected by the collector.
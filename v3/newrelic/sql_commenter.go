@@ -0,0 +1,66 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/url"
+	"strings"
+)
+
+// sqlCommentField is a key value pair of a sqlcommenter comment.
+type sqlCommentField struct {
+	key   string
+	value string
+}
+
+// sqlComment formats the fields as a sqlcommenter comment, see
+// https://google.github.io/sqlcommenter/spec/.  The fields must be sorted by
+// key.
+func sqlComment(fields []sqlCommentField) string {
+	var b strings.Builder
+	b.WriteString("/*")
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(url.PathEscape(f.key))
+		b.WriteString("='")
+		b.WriteString(url.PathEscape(f.value))
+		b.WriteByte('\'')
+	}
+	b.WriteString("*/")
+	return b.String()
+}
+
+// commentQuery appends a sqlcommenter comment containing the application
+// name and the traceparent of the current span to the query if
+// DatastoreTracer.SQLCommenter is enabled.  Following the sqlcommenter
+// specification, queries which already contain a comment are not modified.
+func (txn *Transaction) commentQuery(query string) string {
+	if nil == txn || nil == txn.thread || nil == txn.thread.appRun {
+		return query
+	}
+	if !txn.thread.Config.DatastoreTracer.SQLCommenter.Enabled {
+		return query
+	}
+	if strings.Contains(query, "*/") || strings.Contains(query, "--") {
+		return query
+	}
+	var fields []sqlCommentField
+	if name := strings.Split(txn.thread.Config.AppName, ";")[0]; "" != name {
+		fields = append(fields, sqlCommentField{key: "application", value: name})
+	}
+	if tp := txn.thread.traceParent(); "" != tp {
+		fields = append(fields, sqlCommentField{key: "traceparent", value: tp})
+	}
+	if 0 == len(fields) {
+		return query
+	}
+	// The comment is placed before the terminating semicolon, if any.
+	query = strings.TrimRight(query, " \t\r\n")
+	if strings.HasSuffix(query, ";") {
+		return query[:len(query)-1] + " " + sqlComment(fields) + ";"
+	}
+	return query + " " + sqlComment(fields)
+}
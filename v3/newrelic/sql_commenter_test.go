@@ -0,0 +1,24 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "testing"
+
+func TestSQLComment(t *testing.T) {
+	comment := sqlComment([]sqlCommentField{
+		{key: "application", value: "it's my app"},
+		{key: "traceparent", value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+	})
+	expect := "/*application='it%27s%20my%20app',traceparent='00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01'*/"
+	if comment != expect {
+		t.Error(comment)
+	}
+}
+
+func TestCommentQueryNilTransaction(t *testing.T) {
+	var txn *Transaction
+	if q := txn.commentQuery("SELECT 1"); q != "SELECT 1" {
+		t.Error(q)
+	}
+}
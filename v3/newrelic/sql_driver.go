@@ -57,12 +57,12 @@ func (bld SQLDriverSegmentBuilder) useQuery(query string) SQLDriverSegmentBuilde
 }
 
 func (bld SQLDriverSegmentBuilder) startSegment(ctx context.Context) DatastoreSegment {
-	return bld.startSegmentAt(ctx, time.Now())
+	return bld.startSegmentAt(ctx, FromContext(ctx).now())
 }
 
 func (bld SQLDriverSegmentBuilder) startSegmentAt(ctx context.Context, at time.Time) DatastoreSegment {
 	segment := bld.BaseSegment
-	segment.StartTime = FromContext(ctx).startSegmentAt(at)
+	segment.StartTime = FromContext(ctx).startSegmentAt(at, "")
 	return segment
 }
 
@@ -168,8 +168,9 @@ func (w *wrapConn) Exec(query string, args []driver.Value) (driver.Result, error
 
 // ExecContext implements ExecerContext.
 func (w *wrapConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
-	startTime := time.Now()
-	result, err := w.original.(driver.ExecerContext).ExecContext(ctx, query, args)
+	txn := FromContext(ctx)
+	startTime := txn.now()
+	result, err := w.original.(driver.ExecerContext).ExecContext(ctx, txn.commentQuery(query), args)
 	if err != driver.ErrSkip {
 		seg := w.bld.useQuery(query).startSegmentAt(ctx, startTime)
 		seg.End()
@@ -193,8 +194,9 @@ func (w *wrapConn) Query(query string, args []driver.Value) (driver.Rows, error)
 
 // QueryContext implements QueryerContext.
 func (w *wrapConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
-	startTime := time.Now()
-	rows, err := w.original.(driver.QueryerContext).QueryContext(ctx, query, args)
+	txn := FromContext(ctx)
+	startTime := txn.now()
+	rows, err := w.original.(driver.QueryerContext).QueryContext(ctx, txn.commentQuery(query), args)
 	if err != driver.ErrSkip {
 		seg := w.bld.useQuery(query).startSegmentAt(ctx, startTime)
 		seg.End()
@@ -279,3 +279,73 @@ func TestSQLNoTxnNoCry(t *testing.T) {
 	conn, _ := connector.Connect(nil)
 	conn.(driver.QueryerContext).QueryContext(context.Background(), "myoperation,mycollection", nil)
 }
+
+type queryRecordingConn struct {
+	testConn
+	queries *[]string
+}
+
+func (c queryRecordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	*c.queries = append(*c.queries, query)
+	return nil, nil
+}
+
+func (c queryRecordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	*c.queries = append(*c.queries, query)
+	return nil, nil
+}
+
+type queryRecordingConnector struct {
+	testConnector
+	queries *[]string
+}
+
+func (c queryRecordingConnector) Connect(context.Context) (driver.Conn, error) {
+	return queryRecordingConn{queries: c.queries}, nil
+}
+
+func TestSQLCommenter(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.DatastoreTracer.SQLCommenter.Enabled = true
+	}, t)
+	var queries []string
+	conn, _ := InstrumentSQLConnector(queryRecordingConnector{queries: &queries}, testBuilder).Connect(nil)
+	txn := app.StartTransaction("hello")
+	ctx := NewContext(context.Background(), txn)
+	md := txn.GetTraceMetadata()
+	traceParent := "00-" + md.TraceID + "-" + md.SpanID + "-01"
+	conn.(driver.ExecerContext).ExecContext(ctx, "myoperation,mycollection", nil)
+	conn.(driver.QueryerContext).QueryContext(ctx, "myoperation,mycollection; ", nil)
+	conn.(driver.QueryerContext).QueryContext(ctx, "myoperation,mycollection /* mine */", nil)
+	conn.(driver.QueryerContext).QueryContext(context.Background(), "myoperation,mycollection", nil)
+	txn.End()
+
+	expect := []string{
+		"myoperation,mycollection /*application='my%20app',traceparent='" + traceParent + "'*/",
+		"myoperation,mycollection /*application='my%20app',traceparent='" + traceParent + "'*/;",
+		"myoperation,mycollection /* mine */",
+		"myoperation,mycollection",
+	}
+	if len(queries) != len(expect) {
+		t.Fatal(queries)
+	}
+	for i := range expect {
+		if queries[i] != expect[i] {
+			t.Errorf("got=%s expect=%s", queries[i], expect[i])
+		}
+	}
+}
+
+func TestSQLCommenterDisabled(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	var queries []string
+	conn, _ := InstrumentSQLConnector(queryRecordingConnector{queries: &queries}, testBuilder).Connect(nil)
+	txn := app.StartTransaction("hello")
+	ctx := NewContext(context.Background(), txn)
+	conn.(driver.ExecerContext).ExecContext(ctx, "myoperation,mycollection", nil)
+	txn.End()
+	if len(queries) != 1 || queries[0] != "myoperation,mycollection" {
+		t.Error(queries)
+	}
+}
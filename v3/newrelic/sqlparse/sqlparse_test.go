@@ -190,9 +190,10 @@ func TestExtractTable(t *testing.T) {
 		"\n[table]",
 		"\t    ( 'database'.`table`  ) ",
 	} {
-		table := extractTable(tc)
-		if table != "table" {
-			t.Error(idx, table)
+		var segment newrelic.DatastoreSegment
+		ParseQuery(&segment, "SELECT * FROM "+tc)
+		if segment.Collection != "table" {
+			t.Error(idx, segment.Collection)
 		}
 	}
 }
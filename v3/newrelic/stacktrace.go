@@ -36,12 +36,40 @@ func (f stacktraceFrame) formattedName() string {
 	return path.Base(f.Name)
 }
 
+// agentPackages are the packages whose frames are removed from the top of
+// stack traces.  Both this module's path and the upstream module's path are
+// listed since an application may contain either.
+var agentPackages = []string{
+	"github.com/rainforestpay/go-agent/v3/internal.",
+	"github.com/rainforestpay/go-agent/v3/newrelic.",
+	"github.com/newrelic/go-agent/v3/internal.",
+	"github.com/newrelic/go-agent/v3/newrelic.",
+}
+
 func (f stacktraceFrame) isAgent() bool {
 	// Note this is not a contains conditional rather than a prefix
 	// conditional to handle anonymous functions like:
 	// "go.(*struct { github.com/newrelic/go-agent.threadWithExtras }).NoticeError"
-	return strings.Contains(f.Name, "github.com/newrelic/go-agent/v3/internal.") ||
-		strings.Contains(f.Name, "github.com/newrelic/go-agent/v3/newrelic.")
+	for _, pkg := range agentPackages {
+		if strings.Contains(f.Name, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// isIgnored returns true if the frame belongs to the agent or to a function
+// matching one of the prefixes.
+func (f stacktraceFrame) isIgnored(ignoredPrefixes []string) bool {
+	if f.isAgent() {
+		return true
+	}
+	for _, prefix := range ignoredPrefixes {
+		if strings.HasPrefix(f.Name, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 func (f stacktraceFrame) WriteJSON(buf *bytes.Buffer) {
@@ -99,6 +127,47 @@ func (st stackTrace) frames() []stacktraceFrame {
 	return fs
 }
 
+// trim removes the leading frames which belong to the agent or match the
+// ignored prefixes, and then limits the stack trace to depth frames.  A
+// program counter may expand into several frames when functions have been
+// inlined: such a program counter is only removed if all of its frames are
+// ignored, and is not kept if its frames would exceed the depth unless it is
+// the first program counter kept.
+func (st stackTrace) trim(ignoredPrefixes []string, depth int) stackTrace {
+	start := 0
+	trimming := true
+	frames := 0
+	for idx := range st {
+		pcFrames := runtime.CallersFrames(st[idx : idx+1])
+		ignored := true
+		count := 0
+		for more := true; more; {
+			var frame runtime.Frame
+			frame, more = pcFrames.Next()
+			count++
+			f := stacktraceFrame{Name: frame.Function}
+			if !f.isIgnored(ignoredPrefixes) {
+				ignored = false
+			}
+		}
+		if trimming {
+			if ignored {
+				start = idx + 1
+				continue
+			}
+			trimming = false
+		}
+		if frames+count > depth && idx > start {
+			return st[start:idx]
+		}
+		frames += count
+		if frames >= depth {
+			return st[start : idx+1]
+		}
+	}
+	return st[start:]
+}
+
 // WriteJSON adds the stack trace to the buffer in the JSON form expected by the
 // collector.
 func (st stackTrace) WriteJSON(buf *bytes.Buffer) {
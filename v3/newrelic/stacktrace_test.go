@@ -228,3 +228,81 @@ func TestFramesCount(t *testing.T) {
 		t.Error("Invalid # of frames", len(st), len(frames))
 	}
 }
+
+func topFrameName(st stackTrace) string {
+	frames := st.frames()
+	if len(frames) == 0 {
+		return ""
+	}
+	return frames[0].Name
+}
+
+func TestStackTraceTrimDepth(t *testing.T) {
+	st := stacktracetest.CountedCall(20, func() []uintptr {
+		return getStackTrace()
+	})
+	trimmed := stackTrace(st).trim(nil, 5)
+	if len(trimmed.frames()) != 5 {
+		t.Error(len(trimmed.frames()))
+	}
+	if name := topFrameName(trimmed); name != "github.com/rainforestpay/go-agent/v3/internal/stacktracetest.CountedCall" {
+		t.Error(name)
+	}
+}
+
+func TestStackTraceTrimDepthFrames(t *testing.T) {
+	st := stacktracetest.CountedCall(20, func() []uintptr {
+		return getStackTrace()
+	})
+	// Inlined calls expand a program counter into several frames, which
+	// must not take the trimmed stack trace past the depth.
+	for depth := 1; depth < 30; depth++ {
+		trimmed := stackTrace(st).trim(nil, depth)
+		if n := len(trimmed.frames()); n > depth && len(trimmed) > 1 {
+			t.Error(depth, n)
+		}
+	}
+}
+
+func TestStackTraceTrimIgnoredPrefixes(t *testing.T) {
+	st := stacktracetest.CountedCall(5, func() []uintptr {
+		return getStackTrace()
+	})
+	// The agent frames at the top of the stack are removed, followed by
+	// the frames matching the prefix.  Agent frames below the top of the
+	// stack are not removed.
+	trimmed := stackTrace(st).trim([]string{"github.com/rainforestpay/go-agent/v3/internal/stacktracetest."}, maxStackTraceFrames)
+	if name := topFrameName(trimmed); name != "testing.tRunner" {
+		t.Error(name)
+	}
+}
+
+func TestStackTraceTrimAllIgnored(t *testing.T) {
+	st := getStackTrace()
+	trimmed := st.trim([]string{"testing.", "runtime."}, maxStackTraceFrames)
+	if len(trimmed) != 0 {
+		t.Error(len(trimmed))
+	}
+}
+
+func TestErrorStackTraceDepth(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.ErrorCollector.StackTraceDepth = 3
+	}, t)
+	txn := app.StartTransaction("hello")
+	stacktracetest.CountedCall(10, func() []uintptr {
+		txn.NoticeError(myError{})
+		return nil
+	})
+	errs := txn.thread.txn.Errors
+	if len(errs) != 1 {
+		t.Fatal(len(errs))
+	}
+	if n := len(errs[0].Stack.frames()); n != 3 {
+		t.Error(n)
+	}
+	if name := topFrameName(errs[0].Stack); name != "github.com/rainforestpay/go-agent/v3/internal/stacktracetest.CountedCall" {
+		t.Error(name)
+	}
+	txn.End()
+}
@@ -0,0 +1,169 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// statsDMaxPacketSize is the largest datagram read by the StatsD listener.
+const statsDMaxPacketSize = 65535
+
+var (
+	errStatsDLine = errors.New("invalid statsd line")
+	// errStatsDIgnored is returned for valid lines, such as sets, which are
+	// not recorded.
+	errStatsDIgnored = errors.New("statsd metric type not supported")
+)
+
+// statsDNetworkAddress splits the Config.StatsD.Address into the network
+// and address used by net.ListenPacket.
+func statsDNetworkAddress(address string) (string, string, error) {
+	idx := strings.Index(address, "://")
+	if idx < 0 {
+		return "", "", errStatsDAddress
+	}
+	network, addr := address[:idx], address[idx+3:]
+	if "" == addr || ("udp" != network && "unixgram" != network) {
+		return "", "", errStatsDAddress
+	}
+	return network, addr, nil
+}
+
+// statsDMetric is a metric parsed from a StatsD line.
+type statsDMetric struct {
+	Name  string
+	Value float64
+}
+
+// parseStatsDLine parses a line of the form
+// "<name>:<value>|<type>[|@<sample rate>][|#<tags>]".
+func parseStatsDLine(line string) (statsDMetric, error) {
+	if strings.HasPrefix(line, "_e{") || strings.HasPrefix(line, "_sc|") {
+		// DogStatsD events and service checks.
+		return statsDMetric{}, errStatsDIgnored
+	}
+	fields := strings.Split(line, "|")
+	if len(fields) < 2 {
+		return statsDMetric{}, errStatsDLine
+	}
+	colon := strings.LastIndex(fields[0], ":")
+	if colon <= 0 {
+		return statsDMetric{}, errStatsDLine
+	}
+	name := fields[0][:colon]
+	value, err := strconv.ParseFloat(fields[0][colon+1:], 64)
+	if nil != err {
+		return statsDMetric{}, errStatsDLine
+	}
+	rate := 1.0
+	for _, f := range fields[2:] {
+		if strings.HasPrefix(f, "@") {
+			rate, err = strconv.ParseFloat(f[1:], 64)
+			if nil != err || rate <= 0 || rate > 1 {
+				return statsDMetric{}, errStatsDLine
+			}
+		}
+	}
+	switch fields[1] {
+	case "c":
+		value = value / rate
+	case "ms":
+		value = value / 1000
+	case "g", "h", "d":
+	case "s":
+		return statsDMetric{}, errStatsDIgnored
+	default:
+		return statsDMetric{}, errStatsDLine
+	}
+	return statsDMetric{Name: name, Value: value}, nil
+}
+
+// recordStatsD records the metrics in a StatsD packet, which may contain
+// several newline separated lines.
+func (app *app) recordStatsD(packet []byte) {
+	for _, line := range strings.Split(string(packet), "\n") {
+		line = strings.TrimSpace(line)
+		if "" == line {
+			continue
+		}
+		m, err := parseStatsDLine(line)
+		if errStatsDIgnored == err {
+			continue
+		}
+		if nil == err {
+			err = app.RecordCustomMetric(m.Name, m.Value)
+		}
+		if nil != err && app.Logger.DebugEnabled() {
+			app.Debug("unable to record statsd metric", map[string]interface{}{
+				"line":   line,
+				"reason": err.Error(),
+			})
+		}
+	}
+}
+
+// startStatsD starts the Config.StatsD listener, which is closed when the
+// application shuts down.
+func (app *app) startStatsD() {
+	network, addr, _ := statsDNetworkAddress(app.config.StatsD.Address)
+	// Unlike the listeners of unix stream sockets, unixgram sockets are not
+	// removed when closed, so the file left by a previous process is
+	// removed before listening.  Linux abstract sockets have no file.
+	unixgramFile := "unixgram" == network && !strings.HasPrefix(addr, "@")
+	if unixgramFile {
+		removeStaleSocket(addr)
+	}
+	conn, err := net.ListenPacket(network, addr)
+	if nil != err {
+		app.Error("unable to start statsd listener", map[string]interface{}{
+			"address": app.config.StatsD.Address,
+			"reason":  err.Error(),
+		})
+		return
+	}
+	app.Info("statsd listener started", map[string]interface{}{
+		"address": conn.LocalAddr().String(),
+	})
+	go func() {
+		<-app.shutdownStarted
+		conn.Close()
+		if unixgramFile {
+			os.Remove(addr)
+		}
+	}()
+	go func() {
+		buf := make([]byte, statsDMaxPacketSize)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if nil != err {
+				return
+			}
+			app.recordStatsD(buf[:n])
+		}
+	}()
+}
+
+// removeStaleSocket removes the file at the path if it is a socket which no
+// process is listening on.  Other files, and the sockets of running
+// processes, are left in place so that listening fails.
+func removeStaleSocket(path string) {
+	info, err := os.Lstat(path)
+	if nil != err || 0 == info.Mode()&os.ModeSocket {
+		return
+	}
+	conn, err := net.Dial("unixgram", path)
+	if nil == err {
+		conn.Close()
+		return
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		os.Remove(path)
+	}
+}
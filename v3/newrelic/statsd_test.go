@@ -0,0 +1,152 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+func TestStatsDNetworkAddress(t *testing.T) {
+	testcases := []struct {
+		address, network, addr string
+		err                    error
+	}{
+		{address: "udp://127.0.0.1:8125", network: "udp", addr: "127.0.0.1:8125"},
+		{address: "unixgram:///var/run/statsd.sock", network: "unixgram", addr: "/var/run/statsd.sock"},
+		{address: "127.0.0.1:8125", err: errStatsDAddress},
+		{address: "tcp://127.0.0.1:8125", err: errStatsDAddress},
+		{address: "udp://", err: errStatsDAddress},
+	}
+	for _, tc := range testcases {
+		network, addr, err := statsDNetworkAddress(tc.address)
+		if network != tc.network || addr != tc.addr || err != tc.err {
+			t.Error(tc.address, network, addr, err)
+		}
+	}
+}
+
+func TestParseStatsDLine(t *testing.T) {
+	testcases := []struct {
+		line string
+		want statsDMetric
+		err  error
+	}{
+		{line: "page.views:1|c", want: statsDMetric{Name: "page.views", Value: 1}},
+		{line: "page.views:2|c|@0.5", want: statsDMetric{Name: "page.views", Value: 4}},
+		{line: "checkout.latency:320|ms|#env:prod", want: statsDMetric{Name: "checkout.latency", Value: 0.32}},
+		{line: "queue.depth:-3.5|g", want: statsDMetric{Name: "queue.depth", Value: -3.5}},
+		{line: "payload.size:512|h", want: statsDMetric{Name: "payload.size", Value: 512}},
+		{line: "payload.size:512|d", want: statsDMetric{Name: "payload.size", Value: 512}},
+		{line: "users.unique:42|s", err: errStatsDIgnored},
+		{line: "_e{5,4}:title|text", err: errStatsDIgnored},
+		{line: "_sc|redis.up|0", err: errStatsDIgnored},
+		{line: "page.views:1", err: errStatsDLine},
+		{line: ":1|c", err: errStatsDLine},
+		{line: "page.views:one|c", err: errStatsDLine},
+		{line: "page.views:1|x", err: errStatsDLine},
+		{line: "page.views:1|c|@0", err: errStatsDLine},
+	}
+	for _, tc := range testcases {
+		got, err := parseStatsDLine(tc.line)
+		if got != tc.want || err != tc.err {
+			t.Error(tc.line, got, err)
+		}
+	}
+}
+
+func TestRecordStatsD(t *testing.T) {
+	app := testApp(nil, nil, t)
+	app.app.recordStatsD([]byte("page.views:1|c\npage.views:3|c\n\ncheckout.latency:250|ms\nusers.unique:42|s\nnot a metric\nGo/reserved:1|g\n"))
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "Custom/page.views", Scope: "", Forced: false, Data: []float64{2, 4, 4, 1, 3, 10}},
+		{Name: "Custom/checkout.latency", Scope: "", Forced: false, Data: []float64{1, 0.25, 0.25, 0.25, 0.25, 0.0625}},
+		{Name: "Supportability/Go/CustomMetric/Rejected", Scope: "", Forced: true, Data: nil},
+	})
+}
+
+func TestStatsDConfigValidation(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Enabled = false
+	cfg.StatsD.Enabled = true
+	if err := cfg.validate(); nil != err {
+		t.Error(err)
+	}
+	cfg.StatsD.Address = "localhost:8125"
+	if err := cfg.validate(); err != errStatsDAddress {
+		t.Error(err)
+	}
+}
+
+func TestStatsDUnixgramSocketFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statsd.sock")
+	stale, err := net.ListenPacket("unixgram", path)
+	if nil != err {
+		t.Skip("unixgram sockets not supported:", err)
+	}
+	stale.Close()
+
+	app := testApp(nil, nil, t)
+	app.app.config.StatsD.Address = "unixgram://" + path
+	app.app.startStatsD()
+	app.expectNoLoggedErrors(t)
+
+	conn, err := net.Dial("unixgram", path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	close(app.app.shutdownStarted)
+	for i := 0; i < 100; i++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("socket file not removed on shutdown")
+}
+
+func TestRemoveStaleSocketKeepsLiveSockets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statsd.sock")
+	conn, err := net.ListenPacket("unixgram", path)
+	if nil != err {
+		t.Skip(err)
+	}
+	defer conn.Close()
+	removeStaleSocket(path)
+	if _, err := os.Lstat(path); nil != err {
+		t.Error(err)
+	}
+}
+
+func TestRemoveStaleSocketRemovesClosedSockets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statsd.sock")
+	conn, err := net.ListenPacket("unixgram", path)
+	if nil != err {
+		t.Skip(err)
+	}
+	conn.Close()
+	removeStaleSocket(path)
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Error(err)
+	}
+}
+
+func TestRemoveStaleSocketKeepsFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "statsd.sock")
+	if err := os.WriteFile(path, []byte("data"), 0600); nil != err {
+		t.Fatal(err)
+	}
+	removeStaleSocket(path)
+	if _, err := os.Lstat(path); nil != err {
+		t.Error(err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"io"
+	"net"
 	"strings"
 	"sync"
 	"time"
@@ -23,6 +24,8 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
 	"google.golang.org/grpc/status"
 
 	"github.com/rainforestpay/go-agent/v3/internal"
@@ -69,6 +72,13 @@ const (
 	// numCodes is the total number of grpc.Codes
 	numCodes = 17
 
+	// observerResolverScheme is the scheme of the gRPC target used to load
+	// balance span events across multiple trace observer hosts.
+	observerResolverScheme = "nrtraceobserver"
+	// observerServiceConfig load balances across the trace observer hosts,
+	// skipping those which cannot be reached.
+	observerServiceConfig = `{"loadBalancingConfig":[{"round_robin":{}}]}`
+
 	licenseMetadataKey = "license_key"
 	runIDMetadataKey   = "agent_run_token"
 
@@ -173,18 +183,41 @@ func newDialOptions(cfg observerConfig) []grpc.DialOption {
 		}),
 	}
 	if cfg.endpoint.secure {
-		do = append(do, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{})))
+		tlsConfig := cfg.endpoint.tlsConfig
+		if nil == tlsConfig {
+			tlsConfig = &tls.Config{}
+		}
+		do = append(do, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
 	} else {
 		do = append(do, grpc.WithInsecure())
 	}
 	if nil != cfg.dialer {
 		do = append(do, grpc.WithContextDialer(cfg.dialer))
 	}
+	if len(cfg.endpoint.hosts) > 1 {
+		addrs := make([]resolver.Address, 0, len(cfg.endpoint.hosts))
+		for _, h := range cfg.endpoint.hosts {
+			serverName, _, _ := net.SplitHostPort(h)
+			addrs = append(addrs, resolver.Address{Addr: h, ServerName: serverName})
+		}
+		r := manual.NewBuilderWithScheme(observerResolverScheme)
+		r.InitialState(resolver.State{Addresses: addrs})
+		do = append(do, grpc.WithResolvers(r), grpc.WithDefaultServiceConfig(observerServiceConfig))
+	}
 	return do
 }
 
+// dialTarget returns the gRPC target used to connect to the trace observer
+// hosts.
+func dialTarget(endpoint observerURL) string {
+	if len(endpoint.hosts) > 1 {
+		return observerResolverScheme + ":///" + endpoint.host
+	}
+	return endpoint.host
+}
+
 func (to *gRPCtraceObserver) connectToTraceObserver() {
-	conn, err := grpc.Dial(to.endpoint.host, to.dialOptions...)
+	conn, err := grpc.Dial(dialTarget(to.endpoint), to.dialOptions...)
 	if nil != err {
 		// this error is unrecoverable and will not be retried
 		to.log.Error("trace observer unable to dial grpc endpoint", map[string]interface{}{
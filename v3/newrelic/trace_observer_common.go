@@ -4,6 +4,7 @@
 package newrelic
 
 import (
+	"crypto/tls"
 	"errors"
 	"time"
 
@@ -51,6 +52,11 @@ type observerConfig struct {
 type observerURL struct {
 	host   string
 	secure bool
+	// hosts contains host followed by the additional hosts spans are load
+	// balanced across.  It is nil when there is only one host.
+	hosts []string
+	// tlsConfig is used for secure connections if non-nil.
+	tlsConfig *tls.Config
 }
 
 const (
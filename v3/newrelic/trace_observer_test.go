@@ -10,9 +10,19 @@ package newrelic
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
+	"io/ioutil"
+	"math/big"
 	"net"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -96,6 +106,160 @@ func TestValidateTraceObserverURL(t *testing.T) {
 	}
 }
 
+func TestValidateTraceObserverHosts(t *testing.T) {
+	c := defaultConfig()
+	c.InfiniteTracing.TraceObserver.Host = "testing.com"
+	c.InfiniteTracing.TraceObserver.Hosts = []string{"other.com", "1.2.3.4:8443"}
+	url, err := c.validateTraceObserverConfig()
+	if nil != err {
+		t.Fatal(err)
+	}
+	expect := &observerURL{
+		host:   "testing.com:443",
+		secure: true,
+		hosts:  []string{"testing.com:443", "other.com:443", "1.2.3.4:8443"},
+	}
+	if !reflect.DeepEqual(url, expect) {
+		t.Errorf("url is not as expected: actual=%#v expect=%#v", url, expect)
+	}
+}
+
+func TestValidateTraceObserverHostsWithoutHost(t *testing.T) {
+	c := defaultConfig()
+	c.InfiniteTracing.TraceObserver.Hosts = []string{"other.com", "1.2.3.4:8443"}
+	url, err := c.validateTraceObserverConfig()
+	if nil != err {
+		t.Fatal(err)
+	}
+	expect := &observerURL{
+		host:   "other.com:443",
+		secure: true,
+		hosts:  []string{"other.com:443", "1.2.3.4:8443"},
+	}
+	if !reflect.DeepEqual(url, expect) {
+		t.Errorf("url is not as expected: actual=%#v expect=%#v", url, expect)
+	}
+
+	c.InfiniteTracing.TraceObserver.Hosts = []string{"other.com"}
+	url, err = c.validateTraceObserverConfig()
+	if nil != err {
+		t.Fatal(err)
+	}
+	expect = &observerURL{host: "other.com:443", secure: true}
+	if !reflect.DeepEqual(url, expect) {
+		t.Errorf("url is not as expected: actual=%#v expect=%#v", url, expect)
+	}
+}
+
+// writeTestCertificate writes a self signed certificate and its key to PEM
+// files in dir and returns their paths.
+func writeTestCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if nil != err {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if nil != err {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if nil != err {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); nil != err {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); nil != err {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestValidateTraceObserverTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir)
+
+	c := defaultConfig()
+	c.InfiniteTracing.TraceObserver.Host = "localhost"
+	c.InfiniteTracing.TraceObserver.TLS.CertFile = certFile
+	c.InfiniteTracing.TraceObserver.TLS.KeyFile = keyFile
+	c.InfiniteTracing.TraceObserver.TLS.CAFile = certFile
+	url, err := c.validateTraceObserverConfig()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !url.secure || nil == url.tlsConfig {
+		t.Fatalf("expected a secure url with TLS config: %#v", url)
+	}
+	if len(url.tlsConfig.Certificates) != 1 || nil == url.tlsConfig.RootCAs {
+		t.Errorf("unexpected TLS config: %#v", url.tlsConfig)
+	}
+
+	c.InfiniteTracing.TraceObserver.TLS.KeyFile = ""
+	if _, err := c.validateTraceObserverConfig(); err != errTraceObserverClientCert {
+		t.Error(err)
+	}
+
+	c.InfiniteTracing.TraceObserver.TLS.CertFile = ""
+	c.InfiniteTracing.TraceObserver.TLS.CAFile = keyFile
+	if _, err := c.validateTraceObserverConfig(); err != errTraceObserverCAFile {
+		t.Error(err)
+	}
+}
+
+func TestTraceObserverMultipleHosts(t *testing.T) {
+	s := newTestObsServer(t, simpleRecordSpan)
+	defer s.Close()
+	var lock sync.Mutex
+	dialed := make(map[string]bool)
+	dialer := func(ctx context.Context, addr string) (net.Conn, error) {
+		lock.Lock()
+		dialed[addr] = true
+		lock.Unlock()
+		return s.dialer(ctx, addr)
+	}
+	hosts := []string{"localhost:1", "localhost:2"}
+	to, err := newTraceObserver(runToken, nil, observerConfig{
+		endpoint:    observerURL{host: hosts[0], hosts: hosts},
+		log:         logger.ShimLogger{},
+		license:     testLicenseKey,
+		queueSize:   20,
+		appShutdown: make(chan struct{}),
+		dialer:      dialer,
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+	waitForTrObs(t, to)
+	to.consumeSpan(&spanEvent{})
+	if !s.DidSpansArrive(t, 1, time.Second) {
+		t.Error("span did not arrive")
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		lock.Lock()
+		n := len(dialed)
+		lock.Unlock()
+		if n == len(hosts) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("not all hosts were dialed: %v", dialed)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	to.shutdown(time.Second)
+}
+
 func Test8TConfig(t *testing.T) {
 	testcases := []struct {
 		host         string
@@ -67,6 +67,16 @@ type txnData struct {
 	SlowQueriesEnabled bool
 	noticeErrors       bool // If errors are not expected or ignored, then true
 	expectedErrors     bool
+	responseCode       int  // Zero until a web response code is recorded.
+	clientCanceled     bool // The web request was canceled before the transaction ended.
+	// noErrorMetrics excludes the transaction's errors from the error
+	// metrics, see Config.Transaction.IgnoreSynthetics.
+	noErrorMetrics bool
+	// relationshipMetrics is set by
+	// Config.DistributedTracer.RelationshipMetrics.
+	relationshipMetrics bool
+	// breakdownHostMetrics is set by Config.BreakdownHostMetrics.Enabled.
+	breakdownHostMetrics bool
 
 	stamp           segmentStamp
 	threadIDCounter uint64
@@ -82,10 +92,27 @@ type txnData struct {
 	SlowQueryThreshold time.Duration
 
 	SlowQueries *slowQueries
+	// slowQueriesByInstance is set by
+	// Config.DatastoreTracer.SlowQuery.AggregateByInstance.
+	slowQueriesByInstance bool
+
+	// histogramBuckets are the duration histogram buckets, or nil if
+	// duration histograms are disabled.
+	histogramBuckets []time.Duration
+
+	// maxSegmentDuration is the maximum duration recorded for a segment, or
+	// zero if there is no maximum.
+	maxSegmentDuration time.Duration
+
+	// spanCompressionMaxDuration is the maximum duration of the spans
+	// merged by span compression, or zero if span compression is
+	// disabled.
+	spanCompressionMaxDuration time.Duration
 
 	// These better CAT supportability fields are left outside of
 	// TxnEvent.BetterCAT to minimize the size of transaction event memory.
 	DistributedTracingSupport distributedTracingSupport
+	CrossAppTracingSupport    crossAppTracingSupport
 
 	TraceIDGenerator        *internal.TraceIDGenerator
 	ShouldCollectSpanEvents func() bool
@@ -93,12 +120,25 @@ type txnData struct {
 	rootSpanErrData         *errorData
 	Errors                  txnErrors // Lazily initialized.
 	SpanEvents              []*spanEvent
-	logs                    logEventHeap
+	// roundTripGroups are the groups of requests made using
+	// NewRoundTripper which have more than one request.
+	roundTripGroups []*roundTripGroup
+	logs            logEventHeap
+	// logsSeen counts the logs recorded by the transaction, including
+	// those dropped once logs is full.
+	logsSeen int
+	// droppedLogs holds up to droppedDataLimit of the logs dropped once
+	// logs is full if Config.OnDataDropped is set.
+	droppedLogs []logEvent
 
 	customSegments    map[string]*metricData
 	datastoreSegments map[datastoreMetricKey]*metricData
 	externalSegments  map[externalMetricKey]*metricData
 	messageSegments   map[internal.MessageMetricKey]*metricData
+
+	// datastorePipelineCommands counts the commands of datastore
+	// pipeline segments by product and operation.
+	datastorePipelineCommands map[datastoreMetricKey]int
 }
 
 func (t *txnData) saveTraceSegment(end segmentEnd, name string, attrs spanAttributeMap, externalGUID string) {
@@ -111,6 +151,9 @@ func (t *txnData) saveTraceSegment(end segmentEnd, name string, attrs spanAttrib
 type tracingThread struct {
 	threadID uint64
 	stack    []segmentFrame
+	// roundTrip is the group of the last request made on this thread using
+	// NewRoundTripper without an ExternalCall.
+	roundTrip *roundTripGroup
 	// start and end are used to track the TotalTime this tracingThread was active.
 	start time.Time
 	end   time.Time
@@ -229,6 +272,42 @@ func (m spanAttributeMap) copy() spanAttributeMap {
 	return cpy
 }
 
+// redactable returns the attributes whose values can be represented as plain
+// values for Config.RedactAttributes.
+func (m spanAttributeMap) redactable() map[string]interface{} {
+	vals := make(map[string]interface{}, len(m))
+	for key, val := range m {
+		switch v := val.(type) {
+		case stringJSONWriter:
+			vals[key] = string(v)
+		case intJSONWriter:
+			vals[key] = int(v)
+		case floatJSONWriter:
+			vals[key] = float64(v)
+		case boolJSONWriter:
+			vals[key] = bool(v)
+		}
+	}
+	return vals
+}
+
+// redacted returns the attributes after the values returned by redactable
+// were modified by Config.RedactAttributes.
+func (m spanAttributeMap) redacted(vals map[string]interface{}) spanAttributeMap {
+	var out spanAttributeMap
+	for key, val := range m {
+		switch val.(type) {
+		case stringJSONWriter, intJSONWriter, floatJSONWriter, boolJSONWriter:
+		default:
+			out.add(key, val)
+		}
+	}
+	for key, val := range vals {
+		addAttr(&out, key, val)
+	}
+	return out
+}
+
 func (m *spanAttributeMap) addUserAttrs(attrs map[string]userAttribute) {
 	for key, val := range attrs {
 		if val.dests&destSpan > 0 {
@@ -279,6 +358,10 @@ func addAttr(m *spanAttributeMap, key string, val interface{}) {
 		m.addFloat(key, float64(v))
 	case float64:
 		m.addFloat(key, v)
+	case attributeMap:
+		for _, e := range v {
+			addAttr(m, key+"."+e.key, e.value)
+		}
 	default:
 		m.addString(key, fmt.Sprintf("%T", v))
 	}
@@ -286,13 +369,18 @@ func addAttr(m *spanAttributeMap, key string, val interface{}) {
 
 type segmentFrame struct {
 	segmentTime
-	children        time.Duration
-	spanID          string
+	children time.Duration
+	spanID   string
+	// name is the name given when the segment was started, if any.
+	name            string
 	agentAttributes spanAttributeMap
 	userAttributes  spanAttributeMap
 }
 
 type segmentEnd struct {
+	// referenced is true if the span identifier of the segment was used
+	// before the segment ended.
+	referenced      bool
 	start           segmentTime
 	stop            segmentTime
 	duration        time.Duration
@@ -316,12 +404,14 @@ func (end segmentEnd) spanEvent() *spanEvent {
 		AgentAttributes: end.agentAttributes,
 		UserAttributes:  end.userAttributes,
 		IsEntrypoint:    false,
+		referenced:      end.referenced,
 	}
 }
 
 const (
-	datastoreProductUnknown   = "Unknown"
-	datastoreOperationUnknown = "other"
+	datastoreProductUnknown    = "Unknown"
+	datastoreOperationUnknown  = "other"
+	datastoreOperationPipeline = "pipeline"
 )
 
 // NoticeErrors indicates whether the errors collected count towards error/ metrics
@@ -355,6 +445,17 @@ func (thread *tracingThread) AddAgentSpanAttribute(key string, val string) {
 	}
 }
 
+// addSegmentAgentAttributeInt adds an integer agent attribute to the segment
+// started at start if it has not ended.
+func (thread *tracingThread) addSegmentAgentAttributeInt(start segmentStartTime, key string, val int) {
+	if start.Depth < 0 || start.Depth >= len(thread.stack) {
+		return
+	}
+	if frame := &thread.stack[start.Depth]; frame.Stamp == start.Stamp {
+		frame.agentAttributes.addInt(key, val)
+	}
+}
+
 // AddUserSpanAttribute allows custom attributes to be added to spans.
 func (thread *tracingThread) AddUserSpanAttribute(key string, val interface{}) {
 	if len(thread.stack) > 0 {
@@ -413,6 +514,13 @@ func (t *txnData) CurrentSpanIdentifier(thread *tracingThread) string {
 
 func (t *txnData) saveSpanEvent(e *spanEvent) {
 	e.AgentAttributes = t.Attrs.filterSpanAttributes(e.AgentAttributes, destSpan)
+	if max := t.spanCompressionMaxDuration; max > 0 && len(t.SpanEvents) > 0 {
+		last := t.SpanEvents[len(t.SpanEvents)-1]
+		if e.compressible(max) && last.compressible(max) && last.mergeable(e) {
+			last.merge(e)
+			return
+		}
+	}
 	if len(t.SpanEvents) < defaultMaxSpanEvents {
 		t.SpanEvents = append(t.SpanEvents, e)
 	}
@@ -454,6 +562,11 @@ func endSegment(t *txnData, thread *tracingThread, start segmentStartTime, now t
 	if s.stop.Time.After(s.start.Time) {
 		s.duration = s.stop.Time.Sub(s.start.Time)
 	}
+	if max := t.maxSegmentDuration; max > 0 && s.duration > max {
+		s.duration = max
+		s.stop.Time = s.start.Time.Add(max)
+		s.agentAttributes.addBool(SpanAttributeDurationCapped, true)
+	}
 	if s.duration > children {
 		s.exclusive = s.duration - children
 	}
@@ -468,6 +581,7 @@ func endSegment(t *txnData, thread *tracingThread, start segmentStartTime, now t
 
 	thread.stack = thread.stack[0:start.Depth]
 
+	s.referenced = frame.spanID != ""
 	if fn := t.ShouldCreateSpanGUID; fn != nil && fn() {
 		s.SpanID = frame.spanID
 		if s.SpanID == "" {
@@ -537,6 +651,28 @@ type endExternalParams struct {
 	Library    string
 	Method     string
 	StatusCode *int
+	Outcome    ExternalOutcome
+	Group      *roundTripGroup
+}
+
+// addAttributes adds the non-zero outcome fields to the attributes.
+func (o ExternalOutcome) addAttributes(attrs *spanAttributeMap) {
+	if o.RetryCount != 0 {
+		attrs.addInt(SpanAttributeExternalRetryCount, o.RetryCount)
+	}
+	if o.RedirectCount != 0 {
+		attrs.addInt(SpanAttributeExternalRedirectCount, o.RedirectCount)
+	}
+	if o.CircuitState != "" {
+		attrs.addString(SpanAttributeExternalCircuitState, string(o.CircuitState))
+	}
+	if o.Failure != "" {
+		attrs.addString(SpanAttributeExternalFailure, string(o.Failure))
+	}
+	if o.HedgeID != "" {
+		attrs.addString(SpanAttributeExternalHedgeID, o.HedgeID)
+		attrs.addBool(SpanAttributeExternalHedgeWinner, o.HedgeWinner)
+	}
 }
 
 // endExternalSegment ends an external segment.
@@ -609,6 +745,7 @@ func endExternalSegment(p endExternalParams) error {
 		if p.Library == "http" {
 			attributes.addString(SpanAttributeHTTPURL, safeURL(p.URL))
 		}
+		p.Outcome.addAttributes(&attributes)
 		t.saveTraceSegment(end, key.scopedMetric(), attributes, transactionGUID)
 	}
 
@@ -626,6 +763,13 @@ func endExternalSegment(p endExternalParams) error {
 		} else if p.Response != nil {
 			evt.AgentAttributes.addInt(SpanAttributeHTTPStatusCode, p.Response.StatusCode)
 		}
+		p.Outcome.addAttributes(&evt.AgentAttributes)
+		if nil != p.Group {
+			// The span may be given a new parent when the
+			// transaction ends, so it must not be compressed.
+			evt.referenced = true
+			p.Group.spans = append(p.Group.spans, evt)
+		}
 		t.saveSpanEvent(evt)
 	}
 
@@ -698,11 +842,15 @@ type endDatastoreParams struct {
 	Collection         string
 	Operation          string
 	ParameterizedQuery string
+	PipelineOperations []string
 	QueryParameters    map[string]interface{}
-	Host               string
-	PortPathOrID       string
-	Database           string
-	ThisHost           string
+	// QueryParameterDropped, if not nil, is called with each invalid
+	// query parameter.
+	QueryParameterDropped func(key string, err error)
+	Host                  string
+	PortPathOrID          string
+	Database              string
+	ThisHost              string
 }
 
 const (
@@ -743,11 +891,21 @@ func endDatastoreSegment(p endDatastoreParams) error {
 		return err
 	}
 	if p.Operation == "" {
-		p.Operation = datastoreOperationUnknown
+		if len(p.PipelineOperations) > 0 {
+			p.Operation = datastoreOperationPipeline
+		} else {
+			p.Operation = datastoreOperationUnknown
+		}
 	}
 	if p.Product == "" {
 		p.Product = datastoreProductUnknown
 	}
+	if len(p.PipelineOperations) > 0 && p.TxnData.datastorePipelineCommands == nil {
+		p.TxnData.datastorePipelineCommands = make(map[datastoreMetricKey]int)
+	}
+	for _, op := range p.PipelineOperations {
+		p.TxnData.datastorePipelineCommands[datastoreMetricKey{Product: p.Product, Operation: op}]++
+	}
 	if p.Host == "" && p.PortPathOrID != "" {
 		p.Host = unknownDatastoreHost
 	}
@@ -795,7 +953,7 @@ func endDatastoreSegment(p endDatastoreParams) error {
 
 	scopedMetric := datastoreScopedMetric(key)
 	// errors in QueryParameters must not stop the recording of the segment
-	queryParams, err := vetQueryParameters(p.QueryParameters)
+	queryParams, err := vetQueryParameters(p.QueryParameters, p.QueryParameterDropped)
 
 	if p.TxnData.TxnTrace.considerNode(end) {
 		attributes := end.agentAttributes.copy()
@@ -803,6 +961,9 @@ func endDatastoreSegment(p endDatastoreParams) error {
 		attributes.addString(SpanAttributeDBInstance, p.Database)
 		attributes.addString(SpanAttributePeerAddress, datastoreSpanAddress(p.Host, p.PortPathOrID))
 		attributes.addString(SpanAttributePeerHostname, p.Host)
+		if n := len(p.PipelineOperations); n > 0 {
+			attributes.addInt(SpanAttributeDBCommandCount, n)
+		}
 		if len(queryParams) > 0 {
 			attributes.add(spanAttributeQueryParameters, queryParams)
 		}
@@ -812,6 +973,11 @@ func endDatastoreSegment(p endDatastoreParams) error {
 	if p.TxnData.slowQueryWorthy(end.duration) {
 		if nil == p.TxnData.SlowQueries {
 			p.TxnData.SlowQueries = newSlowQueries(maxTxnSlowQueries)
+			p.TxnData.SlowQueries.byInstance = p.TxnData.slowQueriesByInstance
+		}
+		var stack stackTrace
+		if !p.TxnData.TxnTrace.stackTracesDisabled {
+			stack = getStackTrace()
 		}
 		p.TxnData.SlowQueries.observeInstance(slowQueryInstance{
 			Duration:           end.duration,
@@ -821,7 +987,7 @@ func endDatastoreSegment(p endDatastoreParams) error {
 			Host:               p.Host,
 			PortPathOrID:       p.PortPathOrID,
 			DatabaseName:       p.Database,
-			StackTrace:         getStackTrace(),
+			StackTrace:         stack,
 		})
 	}
 
@@ -835,6 +1001,9 @@ func endDatastoreSegment(p endDatastoreParams) error {
 		evt.AgentAttributes.addString(SpanAttributePeerAddress, datastoreSpanAddress(p.Host, p.PortPathOrID))
 		evt.AgentAttributes.addString(SpanAttributePeerHostname, p.Host)
 		evt.AgentAttributes.addString(SpanAttributeDBCollection, p.Collection)
+		if n := len(p.PipelineOperations); n > 0 {
+			evt.AgentAttributes.addInt(SpanAttributeDBCommandCount, n)
+		}
 		p.TxnData.saveSpanEvent(evt)
 	}
 
@@ -861,6 +1030,9 @@ func mergeBreakdownMetrics(t *txnData, metrics *metricTable) {
 
 		hostMetric := externalHostMetric(key)
 		metrics.add(hostMetric, "", *data, unforced)
+		if t.breakdownHostMetrics {
+			metrics.add(breakdownHostMetric(scope, hostMetric), "", *data, unforced)
+		}
 		if key.ExternalCrossProcessID != "" && key.ExternalTransactionName != "" {
 			txnMetric := externalTransactionMetric(key)
 
@@ -885,6 +1057,9 @@ func mergeBreakdownMetrics(t *txnData, metrics *metricTable) {
 		if key.Host != "" && key.PortPathOrID != "" {
 			instance := datastoreInstanceMetric(key)
 			metrics.add(instance, "", *data, unforced)
+			if t.breakdownHostMetrics {
+				metrics.add(breakdownHostMetric(scope, instance), "", *data, unforced)
+			}
 		}
 
 		operation := datastoreOperationMetric(key)
@@ -899,6 +1074,9 @@ func mergeBreakdownMetrics(t *txnData, metrics *metricTable) {
 			metrics.add(operation, scope, *data, unforced)
 		}
 	}
+	for key, count := range t.datastorePipelineCommands {
+		metrics.addCount(datastorePipelineMetric(key), float64(count), unforced)
+	}
 	// Message Segment Metrics
 	for key, data := range t.messageSegments {
 		metric := key.Name()
@@ -4,6 +4,7 @@
 package newrelic
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -40,7 +41,18 @@ func (txn *Transaction) End() {
 		// not any nested call!
 		r = recover()
 	}
-	txn.thread.logAPIError(txn.thread.End(r), "end transaction", nil)
+	var err error
+	if sa := txn.thread.securityAgent(); nil != sa {
+		// Deferred so that the security agent is also notified when a
+		// recovered panic is re-raised.
+		defer func() {
+			if nil == err {
+				sa.TransactionEnded(txn)
+			}
+		}()
+	}
+	err = txn.thread.End(r)
+	txn.thread.logAPIError(err, "end transaction", nil)
 }
 
 // SetOption allows the setting of some transaction TraceOption parameters
@@ -67,6 +79,88 @@ func (txn *Transaction) Ignore() {
 	txn.thread.logAPIError(txn.thread.Ignore(), "ignore transaction", nil)
 }
 
+// ForceTrace ensures that a transaction trace is recorded for this
+// transaction, regardless of its duration and of the other transactions
+// competing for the slowest trace of the harvest.  Use it sparingly, for rare
+// and critical transactions: at most 20 forced traces are kept each harvest.
+// Traces are not recorded if the transaction tracer is disabled.  To force the
+// trace when starting the transaction, use the WithForcedTrace option.
+func (txn *Transaction) ForceTrace() {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.ForceTrace(), "force transaction trace", nil)
+}
+
+// WithForcedTrace is a TraceOption which ensures that a transaction trace is
+// recorded for the transaction.  See Transaction.ForceTrace.
+func WithForcedTrace() TraceOption {
+	return func(o *traceOptSet) {
+		o.ForceTrace = true
+	}
+}
+
+// DetailLevel controls the detail collected for a transaction, see
+// Transaction.SetDetailLevel.
+type DetailLevel int
+
+const (
+	// DetailLevelBasic collects no datastore query parameters, no stack
+	// traces for errors, slow queries, or transaction trace segments, and
+	// no code level metrics.
+	DetailLevelBasic DetailLevel = -1
+	// DetailLevelStandard collects the detail allowed by the Config.  It
+	// is the default.
+	DetailLevelStandard DetailLevel = 0
+	// DetailLevelVerbose collects datastore query parameters even when
+	// DatastoreTracer.QueryParameters is disabled, and a stack trace for
+	// every transaction trace segment regardless of
+	// TransactionTracer.Segments.StackTraceThreshold.
+	DetailLevelVerbose DetailLevel = 1
+)
+
+// SetDetailLevel sets the detail collected for this transaction from this
+// point onwards.  Use DetailLevelBasic to keep high volume endpoints lean and
+// DetailLevelVerbose for transactions being debugged, such as those of a
+// cohort of users.  Neither level overrides HighSecurity or security policies:
+// query parameters are never collected when they are disabled by either.
+func (txn *Transaction) SetDetailLevel(level DetailLevel) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.SetDetailLevel(level), "set detail level", nil)
+}
+
+// DisableSpanEvents prevents span events from being recorded for this
+// transaction, including those already created.  Its metrics, errors, events,
+// and trace are still recorded.  Use it for chatty internal transactions, such
+// as queue drainers creating thousands of segments, to save the span event
+// reservoir for other traffic.  To disable span events when starting the
+// transaction, use the WithoutSpanEvents option.
+func (txn *Transaction) DisableSpanEvents() {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.DisableSpanEvents(), "disable span events", nil)
+}
+
+// WithoutSpanEvents is a TraceOption which prevents span events from being
+// recorded for the transaction.  See Transaction.DisableSpanEvents.
+func WithoutSpanEvents() TraceOption {
+	return func(o *traceOptSet) {
+		o.NoSpanEvents = true
+	}
+}
+
 // SetName names the transaction.  Use a limited set of unique names to
 // ensure that Transactions are grouped usefully.
 func (txn *Transaction) SetName(name string) {
@@ -79,6 +173,27 @@ func (txn *Transaction) SetName(name string) {
 	txn.thread.logAPIError(txn.thread.SetName(name), "set transaction name", nil)
 }
 
+// SetNameFunc registers a function which names the transaction when it ends.
+// Use it when the name depends on the outcome of the transaction, eg. whether
+// a cache lookup hit or missed.  The function is given the name set using
+// StartTransaction or SetName, the response code, and the custom attributes,
+// and should return the name to use, or "" to keep the current name.  The
+// name returned has the same restrictions as SetName.
+//
+// The function is called while the transaction is ending, so it must not
+// call any Transaction methods.  It is not called if the name has already
+// been frozen, which happens when distributed tracing headers or cross
+// application tracing response headers are created.
+func (txn *Transaction) SetNameFunc(fn func(TransactionNameInfo) string) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.SetNameFunc(fn), "set transaction name function", nil)
+}
+
 // NoticeError records an error.  The Transaction saves the first five
 // errors.  For more control over the recorded error fields, see the
 // newrelic.Error type.
@@ -151,11 +266,39 @@ func (txn *Transaction) NoticeExpectedError(err error) {
 	txn.thread.logAPIError(txn.thread.NoticeError(err, true), "notice error", nil)
 }
 
+// RecordException records an error with a severity of ErrorSeverityWarning,
+// ErrorSeverityError, or ErrorSeverityCritical.  The severity is recorded as
+// the error.severity attribute.  The error is otherwise recorded just as it is
+// by NoticeError.
+//
+// The Transaction saves the first five errors, after which an error replaces
+// the first saved error of a lower severity.  Likewise, once the error limits
+// of the harvest cycle are reached, errors of a lower severity are discarded
+// first, so critical errors are never displaced by warnings.
+func (txn *Transaction) RecordException(err error, severity ErrorSeverity) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.RecordException(err, severity), "record exception", nil)
+}
+
 // AddAttribute adds a key value pair to the transaction event, errors,
 // and traces.
 //
 // The key must contain fewer than than 255 bytes.  The value must be a
-// number, string, or boolean.
+// number, string, or boolean, or one of the following:
+//
+//   - A []string, []int, []int64, []float64, or []bool, which is recorded as a
+//     JSON array string.  Elements are dropped from the end of the array to
+//     keep the string within 255 bytes.
+//   - A map[string]interface{} of these values, nested at most 4 levels deep,
+//     which is recorded as one attribute per entry.  The attribute key and
+//     the entry's path are joined with dots, so the value
+//     map[string]interface{}{"id": 1} added with the key "user" is recorded as
+//     the attribute "user.id".
 //
 // For more information, see:
 // https://docs.newrelic.com/docs/agents/manage-apm-agents/agent-metrics/collect-custom-attributes
@@ -169,6 +312,26 @@ func (txn *Transaction) AddAttribute(key string, value interface{}) {
 	txn.thread.logAPIError(txn.thread.AddAttribute(key, value), "add attribute", nil)
 }
 
+// AddTraceIntrinsic adds a key value pair to the intrinsics of the
+// transaction trace, if one is recorded for the transaction.  Unlike values
+// added using AddAttribute, these values are not added to the transaction
+// event or errors, and are not affected by the attributes configuration.  Use
+// it for values which tools reading transaction traces expect to find among the
+// intrinsics, eg. a tenant ID.
+//
+// The key and value have the same restrictions as AddAttribute, except that
+// maps are not allowed and the keys of the intrinsics recorded by the agent,
+// eg. "guid", are rejected.
+func (txn *Transaction) AddTraceIntrinsic(key string, value interface{}) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.AddTraceIntrinsic(key, value), "add trace intrinsic", nil)
+}
+
 // RecordLog records the data from a single log line.
 // This consumes a LogData object that should be configured
 // with data taken from a logging framework.
@@ -208,6 +371,7 @@ func (txn *Transaction) SetWebRequestHTTP(r *http.Request) {
 		Method:    r.Method,
 		Transport: transport(r),
 		Host:      r.Host,
+		Context:   r.Context(),
 	}
 	txn.SetWebRequest(wr)
 }
@@ -234,7 +398,11 @@ func (txn *Transaction) SetWebRequest(r WebRequest) {
 	if nil == txn.thread {
 		return
 	}
-	txn.thread.logAPIError(txn.thread.SetWebRequest(r), "set web request", nil)
+	err := txn.thread.SetWebRequest(r)
+	txn.thread.logAPIError(err, "set web request", nil)
+	if sa := txn.thread.securityAgent(); nil != sa && nil == err {
+		sa.InboundRequest(txn, r)
+	}
 }
 
 // SetWebResponse allows the Transaction to instrument response code and
@@ -266,17 +434,26 @@ func (txn *Transaction) SetWebResponse(w http.ResponseWriter) http.ResponseWrite
 // ExternalSegment.  The returned SegmentStartTime is safe to use even  when the
 // Transaction receiver is nil.  In this case, the segment will have no effect.
 func (txn *Transaction) StartSegmentNow() SegmentStartTime {
-	return txn.startSegmentAt(time.Now())
+	return txn.startSegmentAt(txn.now(), "")
 }
 
-func (txn *Transaction) startSegmentAt(at time.Time) SegmentStartTime {
+// now returns the current time according to the Config.Clock of the
+// transaction's application.
+func (txn *Transaction) now() time.Time {
+	if nil == txn || nil == txn.thread || nil == txn.thread.appRun {
+		return time.Now()
+	}
+	return txn.thread.Config.Clock.Now()
+}
+
+func (txn *Transaction) startSegmentAt(at time.Time, name string) SegmentStartTime {
 	if nil == txn {
 		return SegmentStartTime{}
 	}
 	if nil == txn.thread {
 		return SegmentStartTime{}
 	}
-	return txn.thread.startSegmentAt(at)
+	return txn.thread.startSegmentAt(at, name)
 }
 
 // StartSegment makes it easy to instrument segments.  To time a function, do
@@ -294,7 +471,7 @@ func (txn *Transaction) startSegmentAt(at time.Time) SegmentStartTime {
 //	segment.End()
 func (txn *Transaction) StartSegment(name string) *Segment {
 	return &Segment{
-		StartTime: txn.StartSegmentNow(),
+		StartTime: txn.startSegmentAt(txn.now(), name),
 		Name:      name,
 	}
 }
@@ -318,6 +495,9 @@ func (txn *Transaction) InsertDistributedTraceHeaders(hdrs http.Header) {
 		return
 	}
 	txn.thread.CreateDistributedTracePayload(hdrs)
+	if sa := txn.thread.securityAgent(); nil != sa {
+		sa.OutboundHeaders(txn, hdrs)
+	}
 }
 
 // AcceptDistributedTraceHeaders links transactions by accepting distributed
@@ -469,6 +649,29 @@ func (txn *Transaction) BrowserTimingHeader() *BrowserTimingHeader {
 	return b
 }
 
+// BrowserCorrelationToken returns a token identifying the transaction which
+// is shared with the Browser agent, so that backend transactions can be tied
+// to frontend events such as single page application route changes.  The
+// token is created by the first call and added to the transaction as the
+// AttributeBrowserCorrelationToken attribute.  If BrowserCorrelationToken is
+// called before BrowserTimingHeader, the header also sets the token as a
+// Browser agent custom attribute of the same name, which is then added to
+// page actions and browser interactions.  The token is also available from
+// BrowserTimingHeader.CorrelationToken for use in your own JavaScript.
+//
+// The empty string is returned if the transaction has ended.
+func (txn *Transaction) BrowserCorrelationToken() string {
+	if nil == txn {
+		return ""
+	}
+	if nil == txn.thread {
+		return ""
+	}
+	token, err := txn.thread.BrowserCorrelationToken()
+	txn.thread.logAPIError(err, "create browser correlation token", nil)
+	return token
+}
+
 // NewGoroutine allows you to use the Transaction in multiple
 // goroutines.
 //
@@ -530,6 +733,48 @@ func (txn *Transaction) IsSampled() bool {
 	return txn.thread.IsSampled()
 }
 
+// SetSampled overrides the decision of whether the Transaction is sampled,
+// which is otherwise made by the adaptive sampler, the
+// Config.DistributedTracer.SamplingRules, or the inbound distributed trace
+// headers.  It allows integrations to implement their own head sampling
+// heuristics, eg. sampling requests which are likely to be slow.
+//
+// SetSampled must be called before InsertDistributedTraceHeaders, or any
+// outbound call instrumented using it, since the decision is propagated to
+// the services called.  An error is logged if the decision has already been
+// propagated or if distributed tracing is disabled.
+func (txn *Transaction) SetSampled(sampled bool) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.SetSampled(sampled), "set sampled", nil)
+}
+
+// SetPriority overrides the priority of the Transaction, which must be
+// between 0 and 1.  When the Transaction is not sampled by SetSampled, the
+// adaptive sampler samples transactions with a higher priority in preference
+// to others.  The priority also decides which transaction, error, and span
+// events are retained when more are recorded than can be sent.  As is the
+// case for priorities chosen by the agent, 1 is added to the priority of
+// sampled transactions.
+//
+// Like SetSampled, SetPriority must be called before
+// InsertDistributedTraceHeaders.  An error is logged if the priority is out
+// of range, if the priority has already been propagated, or if distributed
+// tracing is disabled.
+func (txn *Transaction) SetPriority(priority float32) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.SetPriority(priority), "set priority", nil)
+}
+
 const (
 	// DistributedTraceNewRelicHeader is the header used by New Relic agents
 	// for automatic trace payload instrumentation.
@@ -586,6 +831,9 @@ type WebRequest struct {
 	// This is the value of the `Host` header. Go does not add it to the
 	// http.Header object and so must be passed separately.
 	Host string
+	// Context may be nil.  If it is canceled before the transaction ends,
+	// the transaction is recorded as canceled by the client.
+	Context context.Context
 }
 
 // LinkingMetadata is returned by Transaction.GetLinkingMetadata.  It contains
@@ -610,6 +858,20 @@ type LinkingMetadata struct {
 	Hostname string
 }
 
+// TransactionNameInfo is the information given to the function registered
+// using Transaction.SetNameFunc.
+type TransactionNameInfo struct {
+	// Name is the name set using StartTransaction or SetName.
+	Name string
+	// IsWeb is true if the transaction is a web transaction.
+	IsWeb bool
+	// StatusCode is the response code recorded using WriteHeader, or zero
+	// if none was recorded.
+	StatusCode int
+	// Attributes contains the custom attributes added using AddAttribute.
+	Attributes map[string]interface{}
+}
+
 // TraceMetadata is returned by Transaction.GetTraceMetadata.  It contains
 // distributed tracing identifiers.
 type TraceMetadata struct {
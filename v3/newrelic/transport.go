@@ -6,27 +6,38 @@
 package newrelic
 
 import (
+	"crypto/tls"
 	"net"
 	"net/http"
 	"time"
 )
 
-var (
-	// collectorDefaultTransport is the http.Transport to be used with
-	// communication to the collector backend if a Transport is not set on the
-	// Config.
-	collectorDefaultTransport = &http.Transport{
+// newCollectorTransport creates the http.Transport used to communicate with
+// the collector backend if a Transport is not set on the Config.
+func newCollectorTransport(c config) *http.Transport {
+	ct := c.CollectorTransport
+	t := &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
 			Timeout:   30 * time.Second,
 			KeepAlive: 30 * time.Second,
 			DualStack: true,
 		}).DialContext,
-		ForceAttemptHTTP2:     true, // added in go 1.13
-		MaxIdleConns:          100,
-		MaxIdleConnsPerHost:   100, // note: different from default global transport
-		IdleConnTimeout:       90 * time.Second,
+		ForceAttemptHTTP2:     !ct.DisableHTTP2, // added in go 1.13
+		MaxIdleConns:          ct.MaxIdleConns,
+		MaxIdleConnsPerHost:   ct.MaxIdleConnsPerHost, // note: different from default global transport
+		IdleConnTimeout:       ct.IdleConnTimeout,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
 	}
-)
+	if ct.TLSSessionCacheSize > 0 {
+		t.TLSClientConfig = &tls.Config{
+			ClientSessionCache: tls.NewLRUClientSessionCache(ct.TLSSessionCacheSize),
+		}
+	}
+	if ct.DisableHTTP2 {
+		// A non-nil empty map disables HTTP/2.
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	return t
+}
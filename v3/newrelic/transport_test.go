@@ -0,0 +1,41 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewCollectorTransportDefaults(t *testing.T) {
+	tr := newCollectorTransport(config{Config: defaultConfig()})
+	if tr.MaxIdleConns != 100 || tr.MaxIdleConnsPerHost != 100 || tr.IdleConnTimeout != 90*time.Second {
+		t.Error(tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.IdleConnTimeout)
+	}
+	if nil == tr.TLSClientConfig || nil == tr.TLSClientConfig.ClientSessionCache {
+		t.Error("missing TLS session cache")
+	}
+	if nil != tr.TLSNextProto {
+		t.Error("HTTP/2 should not be disabled")
+	}
+}
+
+func TestNewCollectorTransportTuning(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.CollectorTransport.MaxIdleConns = 5
+	cfg.CollectorTransport.MaxIdleConnsPerHost = 2
+	cfg.CollectorTransport.IdleConnTimeout = time.Minute
+	cfg.CollectorTransport.TLSSessionCacheSize = 0
+	cfg.CollectorTransport.DisableHTTP2 = true
+	tr := newCollectorTransport(config{Config: cfg})
+	if tr.MaxIdleConns != 5 || tr.MaxIdleConnsPerHost != 2 || tr.IdleConnTimeout != time.Minute {
+		t.Error(tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.IdleConnTimeout)
+	}
+	if nil != tr.TLSClientConfig {
+		t.Error("TLS session cache should be disabled")
+	}
+	if nil == tr.TLSNextProto || 0 != len(tr.TLSNextProto) {
+		t.Error("HTTP/2 should be disabled")
+	}
+}
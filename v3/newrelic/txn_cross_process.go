@@ -32,6 +32,10 @@ type txnCrossProcess struct {
 	// The user side switch controlling whether CAT is enabled or not.
 	Enabled bool
 
+	// ReadOnly is true if inbound CAT headers are accepted but outbound CAT
+	// headers are never created.
+	ReadOnly bool
+
 	// The user side switch controlling whether Distributed Tracing is enabled or not
 	// This is required by synthetics support.  If Distributed Tracing is enabled,
 	// any synthetics functionality that is triggered should not set nr.guid.
@@ -90,7 +94,7 @@ func (txp *txnCrossProcess) CreateCrossProcessMetadata(txnName, appName string)
 		metadata.Synthetics = txp.SyntheticsHeader
 	}
 
-	if txp.Enabled {
+	if txp.Enabled && !txp.ReadOnly {
 		txp.SetOutbound(true)
 		txp.requireTripID()
 
@@ -170,8 +174,8 @@ func (txp *txnCrossProcess) ParseAppData(encodedAppData string) (*cat.AppDataHea
 // CreateAppData creates the appData value that should be sent with a response
 // to ensure CAT operates as expected.
 func (txp *txnCrossProcess) CreateAppData(name string, queueTime, responseTime time.Duration, contentLength int64) (string, error) {
-	// If CAT is disabled, do nothing, successfully.
-	if !txp.Enabled {
+	// If CAT is disabled or read-only, do nothing, successfully.
+	if !txp.Enabled || txp.ReadOnly {
 		return "", nil
 	}
 
@@ -21,6 +21,8 @@ type traceNodeParams struct {
 	StackTrace              stackTrace
 	TransactionGUID         string
 	exclusiveDurationMillis *float64
+	// summary describes the children removed by harvestTrace.summarize.
+	summary traceNodeSummary
 }
 
 type traceNode struct {
@@ -47,6 +49,39 @@ type txnTrace struct {
 	StackTraceThreshold time.Duration
 	nodes               traceNodeHeap
 	maxNodes            int
+	// forced is set using Transaction.ForceTrace or WithForcedTrace.
+	forced bool
+	// stackTracesDisabled is set using DetailLevelBasic.
+	stackTracesDisabled bool
+	// intrinsics are added using Transaction.AddTraceIntrinsic.
+	intrinsics map[string]interface{}
+	// maxSize is TransactionTracer.MaxTraceSize.
+	maxSize int
+	// summary describes the top level segments removed by
+	// harvestTrace.summarize.
+	summary traceNodeSummary
+}
+
+// addIntrinsic adds a custom value to the trace's intrinsics.
+func (trace *txnTrace) addIntrinsic(key string, val interface{}, limits attributeLimits) error {
+	if limit := limits.keyLength(); len(key) > limit {
+		return invalidAttributeKeyErr{key: key, limit: limit}
+	}
+	if _, ok := reservedIntrinsics[key]; ok {
+		return reservedIntrinsicErr{key: key}
+	}
+	val, err := limits.validateAttributeValue(key, val)
+	if nil != err {
+		return err
+	}
+	if _, exists := trace.intrinsics[key]; !exists && len(trace.intrinsics) >= attributeUserLimit {
+		return userAttributeLimitErr{key: key}
+	}
+	if nil == trace.intrinsics {
+		trace.intrinsics = make(map[string]interface{})
+	}
+	trace.intrinsics[key] = val
+	return nil
 }
 
 // getMaxNodes allows the maximum number of nodes to be overwritten for unit
@@ -80,7 +115,7 @@ func (trace *txnTrace) witnessNode(end segmentEnd, name string, attrs spanAttrib
 	if trace.nodes == nil {
 		trace.nodes = make(traceNodeHeap, 0, startingTxnTraceNodes)
 	}
-	if end.exclusive >= trace.StackTraceThreshold {
+	if !trace.stackTracesDisabled && end.exclusive >= trace.StackTraceThreshold {
 		node.StackTrace = getStackTrace()
 	}
 	if max := trace.getMaxNodes(); len(trace.nodes) < max {
@@ -137,6 +172,10 @@ func printNodeStart(buf *bytes.Buffer, n nodeDetails) {
 	if "" != n.TransactionGUID {
 		w.stringField("transaction_guid", n.TransactionGUID)
 	}
+	if n.summary.segments > 0 {
+		w.intField("summarized_segments", int64(n.summary.segments))
+		w.floatField("summarized_duration_millis", n.summary.duration.Seconds()*1000.0)
+	}
 	for k, v := range n.attributes {
 		w.writerField(k, v)
 	}
@@ -257,6 +296,7 @@ func (trace *harvestTrace) writeJSON(buf *bytes.Buffer) {
 		relativeStop:  trace.Duration,
 	}
 	details.exclusiveDurationMillis = &exclusiveDurationMillis
+	details.summary = trace.Trace.summary
 	printNodeStart(buf, details)
 
 	for next := 0; next < len(nodes); {
@@ -283,7 +323,7 @@ func (trace *harvestTrace) writeJSON(buf *bytes.Buffer) {
 	userAttributesJSON(trace.Attrs, buf, destTxnTrace, nil)
 	buf.WriteByte(',')
 	buf.WriteString(`"intrinsics":`)
-	intrinsicsJSON(&trace.txnEvent, buf, false)
+	intrinsicsJSON(&trace.txnEvent, buf, false, "", trace.Trace.intrinsics)
 	buf.WriteByte('}')
 
 	// If the trace string pool is used, end another array here.
@@ -299,13 +339,17 @@ func (trace *harvestTrace) writeJSON(buf *bytes.Buffer) {
 	} else {
 		buf.WriteString(`""`)
 	}
-	buf.WriteByte(',')       //
-	buf.WriteString(`null`)  // reserved for future use
-	buf.WriteByte(',')       //
-	buf.WriteString(`false`) // ForcePersist is not yet supported
-	buf.WriteByte(',')       //
-	buf.WriteString(`null`)  // X-Ray sessions not supported
-	buf.WriteByte(',')       //
+	buf.WriteByte(',')      //
+	buf.WriteString(`null`) // reserved for future use
+	buf.WriteByte(',')      //
+	if trace.Trace.forced {
+		buf.WriteString(`true`)
+	} else {
+		buf.WriteString(`false`)
+	}
+	buf.WriteByte(',')      //
+	buf.WriteString(`null`) // X-Ray sessions not supported
+	buf.WriteByte(',')      //
 
 	// Synthetics are supported:
 	if trace.CrossProcess.IsSynthetics() {
@@ -368,28 +412,35 @@ func (h *txnTraceHeap) addTxnTrace(t *harvestTrace) {
 type harvestTraces struct {
 	regular    *txnTraceHeap
 	synthetics *txnTraceHeap
+	// forced holds the traces of transactions using Transaction.ForceTrace,
+	// so that they do not compete with the slowest regular trace.
+	forced *txnTraceHeap
 }
 
 func newHarvestTraces() *harvestTraces {
 	return &harvestTraces{
 		regular:    newTxnTraceHeap(maxRegularTraces),
 		synthetics: newTxnTraceHeap(maxSyntheticsTraces),
+		forced:     newTxnTraceHeap(maxForcedTraces),
 	}
 }
 
 func (traces *harvestTraces) Len() int {
-	return traces.regular.Len() + traces.synthetics.Len()
+	return traces.regular.Len() + traces.synthetics.Len() + traces.forced.Len()
 }
 
 func (traces *harvestTraces) Witness(trace harvestTrace) {
 	traceHeap := traces.regular
 	if trace.CrossProcess.IsSynthetics() {
 		traceHeap = traces.synthetics
+	} else if trace.Trace.forced {
+		traceHeap = traces.forced
 	}
 
 	if traceHeap.isKeeper(&trace) {
 		cpy := new(harvestTrace)
 		*cpy = trace
+		cpy.summarize()
 		traceHeap.addTxnTrace(cpy)
 	}
 }
@@ -409,6 +460,9 @@ func (traces *harvestTraces) Data(agentRunID string, harvestStart time.Time) ([]
 	for _, t := range *traces.synthetics {
 		estimate += 100 * t.Trace.nodes.Len()
 	}
+	for _, t := range *traces.forced {
+		estimate += 100 * t.Trace.nodes.Len()
+	}
 
 	buf := bytes.NewBuffer(make([]byte, 0, estimate))
 	buf.WriteByte('[')
@@ -434,6 +488,9 @@ func (traces *harvestTraces) Data(agentRunID string, harvestStart time.Time) ([]
 	for _, trace := range *traces.synthetics {
 		addTrace(trace)
 	}
+	for _, trace := range *traces.forced {
+		addTrace(trace)
+	}
 	buf.WriteByte(']')
 	buf.WriteByte(']')
 
@@ -444,6 +501,7 @@ func (traces *harvestTraces) slice() []*harvestTrace {
 	out := make([]*harvestTrace, 0, traces.Len())
 	out = append(out, (*traces.regular)...)
 	out = append(out, (*traces.synthetics)...)
+	out = append(out, (*traces.forced)...)
 
 	return out
 }
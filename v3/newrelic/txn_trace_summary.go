@@ -0,0 +1,190 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"sort"
+	"time"
+)
+
+// defaultMaxTxnTraceSize is the default TransactionTracer.MaxTraceSize.
+const defaultMaxTxnTraceSize = 256 * 1024
+
+// traceNodeSummary describes the segments removed from a trace by
+// harvestTrace.summarize.
+type traceNodeSummary struct {
+	// segments is the number of segments removed, including those
+	// removed beneath them.
+	segments int
+	// duration is the total duration of the removed segments, not
+	// counting those removed beneath them since their time is included.
+	duration time.Duration
+}
+
+func (s *traceNodeSummary) add(removed *traceNode) {
+	s.segments += 1 + removed.summary.segments
+	s.duration += removed.duration
+}
+
+// traceNodeSize returns the number of bytes the node occupies in the
+// serialized trace, not including its children.
+func traceNodeSize(buf *bytes.Buffer, traceStart time.Time, n *traceNode) int {
+	buf.Reset()
+	printNodeStart(buf, nodeDetails{
+		name:            n.name,
+		relativeStart:   n.start.Time.Sub(traceStart),
+		relativeStop:    n.stop.Time.Sub(traceStart),
+		traceNodeParams: n.traceNodeParams,
+	})
+	// The closing brackets.  The separating comma is not counted so that
+	// the size is not overestimated for only children.
+	return buf.Len() + 2
+}
+
+// traceTree is the structure of a trace's nodes as they are serialized by
+// harvestTrace.writeJSON.
+type traceTree struct {
+	nodes    sortedTraceNodes
+	parents  []int
+	children [][]int
+	depths   []int
+}
+
+func newTraceTree(nodes sortedTraceNodes) traceTree {
+	tree := traceTree{
+		nodes:    nodes,
+		parents:  make([]int, len(nodes)),
+		children: make([][]int, len(nodes)),
+		depths:   make([]int, len(nodes)),
+	}
+	// This mirrors printChildren: a node is the child of the most recent
+	// node of the same thread which has not stopped.
+	var open []int
+	for i, n := range nodes {
+		if i > 0 && nodes[i-1].threadID != n.threadID {
+			open = open[:0]
+		}
+		for len(open) > 0 && n.start.Stamp >= nodes[open[len(open)-1]].stop.Stamp {
+			open = open[:len(open)-1]
+		}
+		tree.parents[i] = -1
+		if len(open) > 0 {
+			parent := open[len(open)-1]
+			tree.parents[i] = parent
+			tree.children[parent] = append(tree.children[parent], i)
+			tree.depths[i] = tree.depths[parent] + 1
+		}
+		open = append(open, i)
+	}
+	return tree
+}
+
+// slowestPath returns the nodes found by starting from the slowest top level
+// node and descending to the slowest child of each node.
+func (tree traceTree) slowestPath() []bool {
+	onPath := make([]bool, len(tree.nodes))
+	next := -1
+	for i, parent := range tree.parents {
+		if parent < 0 && (next < 0 || tree.nodes[i].duration > tree.nodes[next].duration) {
+			next = i
+		}
+	}
+	for next >= 0 {
+		onPath[next] = true
+		slowest := -1
+		for _, child := range tree.children[next] {
+			if slowest < 0 || tree.nodes[child].duration > tree.nodes[slowest].duration {
+				slowest = child
+			}
+		}
+		next = slowest
+	}
+	return onPath
+}
+
+// summarize removes nodes from the trace until it fits within
+// Trace.maxSize.  Leaf nodes are removed, from the deepest to the shallowest
+// and the shortest to the longest, so that the deep subtrees are summarized
+// first.  The nodes of the slowest path are never removed.  The removed nodes
+// are recorded in the summary of their parent.
+func (trace *harvestTrace) summarize() {
+	max := trace.Trace.maxSize
+	if max <= 0 || 0 == len(trace.Trace.nodes) {
+		return
+	}
+	buf := &bytes.Buffer{}
+	trace.writeJSON(buf)
+	size := buf.Len()
+	if size <= max {
+		return
+	}
+
+	// The nodes are copied since the trace's slice is shared with the
+	// transaction.
+	copied := make([]traceNode, len(trace.Trace.nodes))
+	copy(copied, trace.Trace.nodes)
+	nodes := make(sortedTraceNodes, len(copied))
+	for i := range copied {
+		nodes[i] = &copied[i]
+	}
+	sort.Sort(nodes)
+
+	tree := newTraceTree(nodes)
+	keep := tree.slowestPath()
+	sizes := make([]int, len(nodes))
+	var order []int
+	for i, n := range nodes {
+		sizes[i] = traceNodeSize(buf, trace.Start, n)
+		if !keep[i] {
+			order = append(order, i)
+		}
+	}
+	// A node's children are deeper than it, and the parent of a kept node
+	// is kept, so removing the nodes in this order only ever removes
+	// leaves.
+	sort.SliceStable(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		if tree.depths[i] != tree.depths[j] {
+			return tree.depths[i] > tree.depths[j]
+		}
+		return nodes[i].duration < nodes[j].duration
+	})
+
+	// The transaction's root node grows by the size of its summary.
+	root := &traceNode{name: trace.FinalName}
+	root.exclusiveDurationMillis = new(float64)
+	root.summary = trace.Trace.summary
+	rootSize := traceNodeSize(buf, trace.Start, root)
+
+	removed := make([]bool, len(nodes))
+	for _, next := range order {
+		if size <= max {
+			break
+		}
+		removed[next] = true
+		size -= sizes[next]
+		if parent := tree.parents[next]; parent >= 0 {
+			nodes[parent].summary.add(nodes[next])
+			// The parent grows by the size of its summary.
+			parentSize := traceNodeSize(buf, trace.Start, nodes[parent])
+			size += parentSize - sizes[parent]
+			sizes[parent] = parentSize
+		} else {
+			trace.Trace.summary.add(nodes[next])
+			root.summary = trace.Trace.summary
+			newRootSize := traceNodeSize(buf, trace.Start, root)
+			size += newRootSize - rootSize
+			rootSize = newRootSize
+		}
+	}
+
+	kept := make(traceNodeHeap, 0, len(nodes))
+	for i, n := range nodes {
+		if !removed[i] {
+			kept = append(kept, *n)
+		}
+	}
+	trace.Trace.nodes = kept
+}
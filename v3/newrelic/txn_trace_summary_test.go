@@ -0,0 +1,253 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rainforestpay/go-agent/v3/internal"
+)
+
+// summaryTestPadding lengthens the segment names so that removing a segment
+// saves more than the size of the summary added to its parent.
+var summaryTestPadding = strings.Repeat("_", 100)
+
+// summaryTestName returns the name of a segment created by summaryTestTrace.
+func summaryTestName(name string) string {
+	return "Custom/" + name + summaryTestPadding
+}
+
+// summaryTestTrace creates a trace with the following segments, where the
+// slowest path is a, b, d:
+//
+//	a (0-10s)
+//	  b (0-8s)
+//	    c (0-1s)
+//	    d (1-3s)
+//	  e (8-9s)
+//	f (10-11s)
+func summaryTestTrace(start time.Time) *txnData {
+	txndata := &txnData{}
+	thread := &tracingThread{}
+	txndata.TxnTrace.Enabled = true
+	txndata.TxnTrace.StackTraceThreshold = 1 * time.Hour
+	txndata.TxnTrace.SegmentThreshold = 0
+
+	at := func(secs int) time.Time { return start.Add(time.Duration(secs) * time.Second) }
+	a := startSegment(txndata, thread, at(0))
+	b := startSegment(txndata, thread, at(0))
+	c := startSegment(txndata, thread, at(0))
+	endBasicSegment(txndata, thread, c, at(1), "c"+summaryTestPadding)
+	d := startSegment(txndata, thread, at(1))
+	endBasicSegment(txndata, thread, d, at(3), "d"+summaryTestPadding)
+	endBasicSegment(txndata, thread, b, at(8), "b"+summaryTestPadding)
+	e := startSegment(txndata, thread, at(8))
+	endBasicSegment(txndata, thread, e, at(9), "e"+summaryTestPadding)
+	endBasicSegment(txndata, thread, a, at(10), "a"+summaryTestPadding)
+	f := startSegment(txndata, thread, at(10))
+	endBasicSegment(txndata, thread, f, at(11), "f"+summaryTestPadding)
+	return txndata
+}
+
+func summaryTestHarvestTrace(start time.Time, txndata *txnData) harvestTrace {
+	acfg := createAttributeConfig(config{Config: defaultConfig()}, true)
+	return harvestTrace{
+		txnEvent: txnEvent{
+			Start:     start,
+			Duration:  12 * time.Second,
+			TotalTime: 12 * time.Second,
+			FinalName: "WebTransaction/Go/hello",
+			Attrs:     newAttributes(acfg),
+		},
+		Trace: txndata.TxnTrace,
+	}
+}
+
+func TestNewTraceTree(t *testing.T) {
+	start := time.Date(2014, time.November, 28, 1, 1, 0, 0, time.UTC)
+	trace := summaryTestTrace(start).TxnTrace
+	nodes := make(sortedTraceNodes, len(trace.nodes))
+	for i := range trace.nodes {
+		nodes[i] = &trace.nodes[i]
+	}
+	sort.Sort(nodes)
+	tree := newTraceTree(nodes)
+
+	parents := map[string]string{}
+	depths := map[string]int{}
+	for i, n := range tree.nodes {
+		if p := tree.parents[i]; p >= 0 {
+			parents[n.name] = tree.nodes[p].name
+		}
+		depths[n.name] = tree.depths[i]
+	}
+	expectParents := map[string]string{
+		summaryTestName("b"): summaryTestName("a"),
+		summaryTestName("c"): summaryTestName("b"),
+		summaryTestName("d"): summaryTestName("b"),
+		summaryTestName("e"): summaryTestName("a"),
+	}
+	for name, parent := range expectParents {
+		if parents[name] != parent {
+			t.Error(name, parents[name], parent)
+		}
+	}
+	if len(parents) != len(expectParents) {
+		t.Error(parents)
+	}
+	if depths[summaryTestName("a")] != 0 || depths[summaryTestName("f")] != 0 || depths[summaryTestName("b")] != 1 || depths[summaryTestName("d")] != 2 {
+		t.Error(depths)
+	}
+
+	var path []string
+	for i, onPath := range tree.slowestPath() {
+		if onPath {
+			path = append(path, tree.nodes[i].name)
+		}
+	}
+	if strings.Join(path, ",") != strings.Join([]string{summaryTestName("a"), summaryTestName("b"), summaryTestName("d")}, ",") {
+		t.Error(path)
+	}
+}
+
+func TestTxnTraceSummarizedUnderLimit(t *testing.T) {
+	start := time.Date(2014, time.November, 28, 1, 1, 0, 0, time.UTC)
+	txndata := summaryTestTrace(start)
+	txndata.TxnTrace.maxSize = defaultMaxTxnTraceSize
+	trace := summaryTestHarvestTrace(start, txndata)
+	trace.summarize()
+	if len(trace.Trace.nodes) != 6 || trace.Trace.summary.segments != 0 {
+		t.Error(len(trace.Trace.nodes), trace.Trace.summary)
+	}
+}
+
+func TestTxnTraceSummarizedDeepestFirst(t *testing.T) {
+	start := time.Date(2014, time.November, 28, 1, 1, 0, 0, time.UTC)
+	txndata := summaryTestTrace(start)
+	trace := summaryTestHarvestTrace(start, txndata)
+	js, _ := trace.MarshalJSON()
+	// Removing c, the shortest of the deepest segments, is enough.
+	trace.Trace.maxSize = len(js) - 1
+	ht := newHarvestTraces()
+	ht.Witness(trace)
+
+	if len(txndata.TxnTrace.nodes) != 6 {
+		t.Error("transaction's trace modified")
+	}
+	if js, _ := ht.slice()[0].MarshalJSON(); len(js) > trace.Trace.maxSize {
+		t.Error(len(js), trace.Trace.maxSize)
+	}
+	expectTxnTraces(t, ht, []internal.WantTxnTrace{{
+		MetricName: "WebTransaction/Go/hello",
+		Root: internal.WantTraceSegment{
+			SegmentName: "ROOT",
+			Attributes:  map[string]interface{}{},
+			Children: []internal.WantTraceSegment{{
+				SegmentName: "WebTransaction/Go/hello",
+				Attributes:  map[string]interface{}{"exclusive_duration_millis": 12000},
+				Children: []internal.WantTraceSegment{{
+					SegmentName: summaryTestName("a"),
+					Attributes:  map[string]interface{}{},
+					Children: []internal.WantTraceSegment{{
+						SegmentName: summaryTestName("b"),
+						Attributes: map[string]interface{}{
+							"summarized_segments":        1,
+							"summarized_duration_millis": 1000,
+						},
+						Children: []internal.WantTraceSegment{{
+							SegmentName: summaryTestName("d"),
+							Attributes:  map[string]interface{}{},
+							Children:    []internal.WantTraceSegment{},
+						}},
+					}, {
+						SegmentName: summaryTestName("e"),
+						Attributes:  map[string]interface{}{},
+						Children:    []internal.WantTraceSegment{},
+					}},
+				}, {
+					SegmentName: summaryTestName("f"),
+					Attributes:  map[string]interface{}{},
+					Children:    []internal.WantTraceSegment{},
+				}},
+			}},
+		},
+	}})
+}
+
+func TestTxnTraceSummarizedSlowestPathKept(t *testing.T) {
+	start := time.Date(2014, time.November, 28, 1, 1, 0, 0, time.UTC)
+	txndata := summaryTestTrace(start)
+	txndata.TxnTrace.maxSize = 1
+	ht := newHarvestTraces()
+	ht.Witness(summaryTestHarvestTrace(start, txndata))
+
+	expectTxnTraces(t, ht, []internal.WantTxnTrace{{
+		MetricName: "WebTransaction/Go/hello",
+		Root: internal.WantTraceSegment{
+			SegmentName: "ROOT",
+			Attributes:  map[string]interface{}{},
+			Children: []internal.WantTraceSegment{{
+				SegmentName: "WebTransaction/Go/hello",
+				Attributes: map[string]interface{}{
+					"exclusive_duration_millis":  12000,
+					"summarized_segments":        1,
+					"summarized_duration_millis": 1000,
+				},
+				Children: []internal.WantTraceSegment{{
+					SegmentName: summaryTestName("a"),
+					Attributes: map[string]interface{}{
+						"summarized_segments":        1,
+						"summarized_duration_millis": 1000,
+					},
+					Children: []internal.WantTraceSegment{{
+						SegmentName: summaryTestName("b"),
+						Attributes: map[string]interface{}{
+							"summarized_segments":        1,
+							"summarized_duration_millis": 1000,
+						},
+						Children: []internal.WantTraceSegment{{
+							SegmentName: summaryTestName("d"),
+							Attributes:  map[string]interface{}{},
+							Children:    []internal.WantTraceSegment{},
+						}},
+					}},
+				}},
+			}},
+		},
+	}})
+}
+
+func TestTxnTraceSummarizedSubtreeCounts(t *testing.T) {
+	start := time.Date(2014, time.November, 28, 1, 1, 0, 0, time.UTC)
+	txndata := &txnData{}
+	thread := &tracingThread{}
+	txndata.TxnTrace.Enabled = true
+	txndata.TxnTrace.StackTraceThreshold = 1 * time.Hour
+	txndata.TxnTrace.SegmentThreshold = 0
+	at := func(secs int) time.Time { return start.Add(time.Duration(secs) * time.Second) }
+
+	// The subtree of x is removed before its slower sibling y.
+	x := startSegment(txndata, thread, at(0))
+	x1 := startSegment(txndata, thread, at(0))
+	endBasicSegment(txndata, thread, x1, at(1), "x1")
+	x2 := startSegment(txndata, thread, at(1))
+	endBasicSegment(txndata, thread, x2, at(2), "x2")
+	endBasicSegment(txndata, thread, x, at(3), "x")
+	y := startSegment(txndata, thread, at(3))
+	endBasicSegment(txndata, thread, y, at(8), "y")
+
+	trace := summaryTestHarvestTrace(start, txndata)
+	trace.Trace.maxSize = 1
+	trace.summarize()
+
+	if len(trace.Trace.nodes) != 1 || trace.Trace.nodes[0].name != "Custom/y" {
+		t.Fatal(trace.Trace.nodes)
+	}
+	if s := trace.Trace.summary; s.segments != 3 || s.duration != 3*time.Second {
+		t.Error(s)
+	}
+}
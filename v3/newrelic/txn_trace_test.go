@@ -26,7 +26,7 @@ func TestTxnTrace(t *testing.T) {
 
 	t1 := startSegment(txndata, thread, start.Add(1*time.Second))
 	t2 := startSegment(txndata, thread, start.Add(2*time.Second))
-	qParams, err := vetQueryParameters(map[string]interface{}{"zip": 1})
+	qParams, err := vetQueryParameters(map[string]interface{}{"zip": 1}, nil)
 	if nil != err {
 		t.Error("error creating query params", err)
 	}